@@ -0,0 +1,235 @@
+// Package acl is a packet filter on top of a zart two-dimensional table.
+// Its rules match the source and destination prefix of a packet,
+// qualified by the IP protocol and ranges of source and destination
+// ports:
+//
+//	a := acl.New()
+//	defer a.Close()
+//	a.Add(acl.Rule{
+//		Src:      netip.MustParsePrefix("10.0.0.0/8"),
+//		Dst:      netip.MustParsePrefix("192.0.2.0/24"),
+//		Proto:    acl.TCP,
+//		DstPorts: acl.Port(443),
+//		Action:   acl.Permit,
+//	})
+//	r, ok := a.Match(src, dst, acl.TCP, sport, dport)
+//	if ok && r.Action == acl.Permit {
+//		// forward the packet
+//	}
+//
+// The best matching rule is the one with the most specific prefixes, as
+// for zart.Table2D.Lookup. Rules for the same pair of prefixes are
+// ranked by their qualifiers: a rule for a protocol before one for any
+// protocol, then by the narrower destination port range and the narrower
+// source port range. Rules that rank the same are tried in the order they
+// were added.
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrRule is returned, wrapped, for rules that cannot be added.
+var ErrRule = errors.New("acl: invalid rule")
+
+// IP protocol numbers of the protocols with ports.
+const (
+	TCP  uint8 = 6
+	UDP  uint8 = 17
+	SCTP uint8 = 132
+)
+
+// Action is what to do with a packet matching a rule.
+type Action int
+
+// The actions of a rule.
+const (
+	Deny Action = iota
+	Permit
+)
+
+func (a Action) String() string {
+	switch a {
+	case Deny:
+		return "deny"
+	case Permit:
+		return "permit"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// PortRange is the range of ports from First to Last inclusive. The zero
+// PortRange matches any port.
+type PortRange struct {
+	First, Last uint16
+}
+
+// Port returns the range of the single port p.
+func Port(p uint16) PortRange {
+	return PortRange{First: p, Last: p}
+}
+
+// Ports returns the range of the ports from first to last.
+func Ports(first, last uint16) PortRange {
+	return PortRange{First: first, Last: last}
+}
+
+// Any reports whether r matches every port.
+func (r PortRange) Any() bool {
+	return r == PortRange{} || r == PortRange{First: 0, Last: 0xffff}
+}
+
+// Contains reports whether port p is in r.
+func (r PortRange) Contains(p uint16) bool {
+	return r.Any() || r.First <= p && p <= r.Last
+}
+
+// size returns the number of ports in r.
+func (r PortRange) size() int {
+	if r.Any() {
+		return 1 << 16
+	}
+	return int(r.Last) - int(r.First) + 1
+}
+
+func (r PortRange) String() string {
+	switch {
+	case r.Any():
+		return "any"
+	case r.First == r.Last:
+		return fmt.Sprint(r.First)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Rule is an entry of an ACL. A Proto of 0 matches any protocol, the
+// port ranges are compared to the ports of the packet for every
+// protocol.
+type Rule struct {
+	Src, Dst           netip.Prefix
+	Proto              uint8
+	SrcPorts, DstPorts PortRange
+	Action             Action
+
+	// Name identifies the rule in the result of Match, it is not
+	// interpreted.
+	Name string
+}
+
+// matches reports whether the qualifiers of r match a packet.
+func (r *Rule) matches(proto uint8, sport, dport uint16) bool {
+	return (r.Proto == 0 || r.Proto == proto) && r.SrcPorts.Contains(sport) && r.DstPorts.Contains(dport)
+}
+
+// compare orders rules for the same prefixes by how specific their
+// qualifiers are, the most specific first.
+func compare(a, b *Rule) int {
+	if (a.Proto == 0) != (b.Proto == 0) {
+		if a.Proto != 0 {
+			return -1
+		}
+		return 1
+	}
+	if c := a.DstPorts.size() - b.DstPorts.size(); c != 0 {
+		return c
+	}
+	return a.SrcPorts.size() - b.SrcPorts.size()
+}
+
+// ACL is a set of rules. Like the tables it is built on, it is not safe
+// for concurrent modification.
+type ACL struct {
+	t *zart.Table2D[[]Rule]
+	n int
+}
+
+// New returns an ACL without rules, which matches no packet.
+func New() *ACL {
+	return &ACL{t: zart.NewTable2D[[]Rule]()}
+}
+
+// Close releases the tables of the ACL.
+func (a *ACL) Close() {
+	a.t.Close()
+}
+
+// Len returns the number of rules.
+func (a *ACL) Len() int {
+	return a.n
+}
+
+// Add adds r. It fails for invalid prefixes and port ranges with First
+// after Last. The prefixes are masked, a rule equal to one in the ACL is
+// ignored.
+func (a *ACL) Add(r Rule) error {
+	if !r.Src.IsValid() || !r.Dst.IsValid() {
+		return fmt.Errorf("%w: invalid prefix", ErrRule)
+	}
+	for _, pr := range []PortRange{r.SrcPorts, r.DstPorts} {
+		if pr.First > pr.Last {
+			return fmt.Errorf("%w: port range %d-%d", ErrRule, pr.First, pr.Last)
+		}
+	}
+	r.Src, r.Dst = r.Src.Masked(), r.Dst.Masked()
+	old, _ := a.t.Get(r.Src, r.Dst)
+	if slices.Contains(old, r) {
+		return nil
+	}
+	i, _ := slices.BinarySearchFunc(old, &r, func(q Rule, r *Rule) int {
+		if compare(&q, r) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	a.t.Insert(r.Src, r.Dst, slices.Insert(slices.Clip(old), i, r))
+	a.n++
+	return nil
+}
+
+// Delete removes the rule equal to r and reports whether there was one.
+func (a *ACL) Delete(r Rule) bool {
+	r.Src, r.Dst = r.Src.Masked(), r.Dst.Masked()
+	old, _ := a.t.Get(r.Src, r.Dst)
+	i := slices.Index(old, r)
+	if i < 0 {
+		return false
+	}
+	a.n--
+	if len(old) == 1 {
+		a.t.Delete(r.Src, r.Dst)
+		return true
+	}
+	a.t.Insert(r.Src, r.Dst, slices.Delete(slices.Clone(old), i, i+1))
+	return true
+}
+
+// Match returns the best rule matching a packet of protocol proto from
+// port sport of src to port dport of dst. The prefix pairs covering the
+// addresses are tried from the most specific one on, falling back to the
+// less specific ones when none of the rules of a pair matches the
+// protocol and ports.
+func (a *ACL) Match(src, dst netip.Addr, proto uint8, sport, dport uint16) (Rule, bool) {
+	for _, rules := range a.t.LookupAll(src, dst) {
+		for i := range rules {
+			if rules[i].matches(proto, sport, dport) {
+				return rules[i], true
+			}
+		}
+	}
+	return Rule{}, false
+}
+
+// Rules returns all rules, by pair of prefixes in the order of
+// zart.Table2D.All and for each pair in the order they are tried.
+func (a *ACL) Rules() []Rule {
+	var all []Rule
+	for _, rules := range a.t.All() {
+		all = append(all, rules...)
+	}
+	return all
+}
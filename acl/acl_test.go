@@ -0,0 +1,104 @@
+package acl
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func mpp(s string) netip.Prefix { return netip.MustParsePrefix(s) }
+func mpa(s string) netip.Addr   { return netip.MustParseAddr(s) }
+
+func TestMatch(t *testing.T) {
+	a := New()
+	defer a.Close()
+	for _, r := range []Rule{
+		{Src: mpp("0.0.0.0/0"), Dst: mpp("0.0.0.0/0"), Action: Deny, Name: "default"},
+		{Src: mpp("10.0.0.0/8"), Dst: mpp("192.0.2.0/24"), Proto: TCP, DstPorts: Port(443), Action: Permit, Name: "https"},
+		{Src: mpp("10.0.0.0/8"), Dst: mpp("192.0.2.0/24"), Proto: TCP, DstPorts: Ports(1, 1023), Action: Deny, Name: "low"},
+		{Src: mpp("10.0.0.0/8"), Dst: mpp("192.0.2.0/24"), Action: Permit, Name: "lan"},
+		{Src: mpp("10.1.0.0/16"), Dst: mpp("192.0.2.0/24"), Proto: UDP, SrcPorts: Port(53), Action: Permit, Name: "dns"},
+		{Src: mpp("10.9.0.0/16"), Dst: mpp("192.0.2.8/29"), Proto: TCP, Action: Deny, Name: "quarantine"},
+	} {
+		if err := a.Add(r); err != nil {
+			t.Fatalf("Add(%s): %v", r.Name, err)
+		}
+	}
+	if a.Len() != 6 {
+		t.Fatalf("Len = %d, want 6", a.Len())
+	}
+
+	tests := []struct {
+		src, dst     string
+		proto        uint8
+		sport, dport uint16
+		want         string
+	}{
+		{"10.0.0.1", "192.0.2.1", TCP, 40000, 443, "https"},
+		{"10.0.0.1", "192.0.2.1", TCP, 40000, 22, "low"},
+		{"10.0.0.1", "192.0.2.1", TCP, 40000, 8080, "lan"},
+		{"10.0.0.1", "192.0.2.1", UDP, 40000, 443, "lan"},
+		// the /16 source only has the DNS rule, the /8 rules apply
+		{"10.1.0.1", "192.0.2.1", UDP, 53, 40000, "dns"},
+		{"10.1.0.1", "192.0.2.1", UDP, 54, 443, "lan"},
+		{"10.9.0.1", "192.0.2.9", TCP, 1, 443, "quarantine"},
+		{"10.9.0.1", "192.0.2.9", UDP, 1, 443, "lan"},
+		{"172.16.0.1", "192.0.2.1", TCP, 40000, 443, "default"},
+	}
+	for _, tt := range tests {
+		r, ok := a.Match(mpa(tt.src), mpa(tt.dst), tt.proto, tt.sport, tt.dport)
+		if !ok || r.Name != tt.want {
+			t.Errorf("Match(%s, %s, %d, %d, %d) = %q, %v, want %q", tt.src, tt.dst, tt.proto, tt.sport, tt.dport, r.Name, ok, tt.want)
+		}
+	}
+	if _, ok := a.Match(mpa("2001:db8::1"), mpa("2001:db8::2"), TCP, 1, 2); ok {
+		t.Error("IPv6 packet matched an IPv4 rule")
+	}
+}
+
+func TestAddDelete(t *testing.T) {
+	a := New()
+	defer a.Close()
+	r := Rule{Src: mpp("10.0.0.1/8"), Dst: mpp("0.0.0.0/0"), Proto: TCP, Action: Permit}
+	if err := a.Add(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add(r); err != nil || a.Len() != 1 {
+		t.Fatalf("second Add = %v, Len = %d", err, a.Len())
+	}
+	if got := a.Rules(); len(got) != 1 || got[0].Src != mpp("10.0.0.0/8") {
+		t.Errorf("Rules = %v", got)
+	}
+	if err := a.Add(Rule{Src: r.Src, Dst: r.Dst, DstPorts: PortRange{First: 9, Last: 8}}); !errors.Is(err, ErrRule) {
+		t.Errorf("Add of a reversed port range = %v", err)
+	}
+	if err := a.Add(Rule{Dst: r.Dst}); !errors.Is(err, ErrRule) {
+		t.Errorf("Add without a source = %v", err)
+	}
+
+	if !a.Delete(r) || a.Delete(r) || a.Len() != 0 {
+		t.Errorf("Delete did not remove the rule once, Len = %d", a.Len())
+	}
+	if _, ok := a.Match(mpa("10.0.0.1"), mpa("1.1.1.1"), TCP, 1, 2); ok {
+		t.Error("deleted rule still matches")
+	}
+}
+
+func TestPortRange(t *testing.T) {
+	for _, tt := range []struct {
+		r    PortRange
+		p    uint16
+		want bool
+	}{
+		{PortRange{}, 0, true},
+		{PortRange{}, 65535, true},
+		{Port(80), 80, true},
+		{Port(80), 81, false},
+		{Ports(1000, 2000), 999, false},
+		{Ports(1000, 2000), 2000, true},
+	} {
+		if got := tt.r.Contains(tt.p); got != tt.want {
+			t.Errorf("%s.Contains(%d) = %v", tt.r, tt.p, got)
+		}
+	}
+}
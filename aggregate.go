@@ -0,0 +1,117 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+	"slices"
+)
+
+// AllAggregated returns an iterator over the smallest set of prefixes
+// that routes every address to the same value as the table, in CIDR
+// order. Two sibling prefixes with equal values are merged into their
+// parent, which wins over a value the parent itself may have, it is
+// unreachable below the two halves. A prefix whose value equals the value
+// of its closest covering prefix is dropped. Values are compared with
+// equal.
+//
+// The table is not modified, see Aggregate.
+func (t *Table[V]) AllAggregated(equal func(a, b V) bool) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		for _, e := range aggregate(t.All(), equal) {
+			if !yield(e.Prefix, e.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Aggregate replaces the contents of the table by AllAggregated and
+// returns by how many prefixes the table shrank. Lookups return the same
+// values as before, though LookupPrefix may return a shorter prefix.
+// Watchers see the deletes and inserts the rewrite takes.
+func (t *Table[V]) Aggregate(equal func(a, b V) bool) int {
+	return t.rewrite(aggregate(t.All(), equal), equal)
+}
+
+// rewrite replaces the contents of the table by the aggregated routes
+// agg and returns by how many prefixes it shrank.
+func (t *Table[V]) rewrite(agg []RouteEntry[V], equal func(a, b V) bool) int {
+	before := t.Size()
+	keep := make(map[netip.Prefix]V, len(agg))
+	for _, e := range agg {
+		keep[e.Prefix] = e.Value
+	}
+	for _, r := range t.routes() {
+		if _, ok := keep[r.prefix()]; !ok {
+			t.Delete(r.prefix())
+		}
+	}
+	for _, e := range agg {
+		if old, ok := t.Get(e.Prefix); !ok || !equal(old, e.Value) {
+			t.Insert(e.Prefix, e.Value)
+		}
+	}
+	return before - t.Size()
+}
+
+// aggregate merges equal siblings bottom up, longest prefixes first, so a
+// merged parent is paired with its own sibling further up. Then it drops
+// the prefixes repeating their cover, with a stack of covering prefixes
+// like Fprint. A prefix repeating a cover that is dropped itself repeats
+// the next cover up, comparing with the closest one is enough.
+func aggregate[V any](routes iter.Seq2[netip.Prefix, V], equal func(a, b V) bool) []RouteEntry[V] {
+	set := map[netip.Prefix]V{}
+	var byLen [129][]netip.Prefix
+	for pfx, val := range routes {
+		set[pfx] = val
+		byLen[pfx.Bits()] = append(byLen[pfx.Bits()], pfx)
+	}
+
+	for bits := len(byLen) - 1; bits > 0; bits-- {
+		for _, p := range byLen[bits] {
+			v, ok := set[p]
+			if !ok {
+				continue // merged with its sibling already
+			}
+			s := sibling(p)
+			if w, ok := set[s]; !ok || !equal(v, w) {
+				continue
+			}
+			delete(set, p)
+			delete(set, s)
+			parent, _ := p.Addr().Prefix(bits - 1)
+			if _, ok := set[parent]; !ok {
+				byLen[bits-1] = append(byLen[bits-1], parent)
+			}
+			set[parent] = v
+		}
+	}
+
+	sorted := make([]RouteEntry[V], 0, len(set))
+	for pfx, val := range set {
+		sorted = append(sorted, RouteEntry[V]{Prefix: pfx, Value: val})
+	}
+	slices.SortFunc(sorted, func(a, b RouteEntry[V]) int { return comparePrefix(a.Prefix, b.Prefix) })
+
+	out := sorted[:0]
+	var parents []RouteEntry[V]
+	for _, e := range sorted {
+		for len(parents) > 0 && !covers(parents[len(parents)-1].Prefix, e.Prefix) {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 || !equal(parents[len(parents)-1].Value, e.Value) {
+			out = append(out, e)
+		}
+		parents = append(parents, e)
+	}
+	return out
+}
+
+// sibling returns the other half of the parent of p, p must not be /0.
+func sibling(p netip.Prefix) netip.Prefix {
+	b := p.Addr().AsSlice()
+	i := p.Bits() - 1
+	b[i/8] ^= 0x80 >> (i % 8)
+	a, _ := netip.AddrFromSlice(b)
+	return netip.PrefixFrom(a, p.Bits())
+}
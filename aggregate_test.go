@@ -0,0 +1,76 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestAllAggregated(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), "a")
+	tbl.Insert(mpp("10.1.0.0/16"), "a") // repeats its cover
+	tbl.Insert(mpp("192.168.0.0/24"), "b")
+	tbl.Insert(mpp("192.168.1.0/24"), "b") // merges into /23
+	tbl.Insert(mpp("192.168.2.0/23"), "b") // merges with the /23 into a /22
+	tbl.Insert(mpp("192.168.0.0/22"), "c") // shadowed by its halves
+	tbl.Insert(mpp("172.16.0.0/25"), "d")
+	tbl.Insert(mpp("172.16.0.128/25"), "e")
+	tbl.Insert(mpp("2001:db8::/33"), "f")
+	tbl.Insert(mpp("2001:db8:8000::/33"), "f")
+
+	var got []string
+	for pfx, val := range tbl.AllAggregated(func(a, b string) bool { return a == b }) {
+		got = append(got, pfx.String()+"="+val)
+	}
+	want := []string{"10.0.0.0/8=a", "172.16.0.0/25=d", "172.16.0.128/25=e", "192.168.0.0/22=b", "2001:db8::/32=f"}
+	if !slices.Equal(got, want) {
+		t.Errorf("AllAggregated = %v, want %v", got, want)
+	}
+	if tbl.Size() != 10 {
+		t.Errorf("AllAggregated modified the table, size %d", tbl.Size())
+	}
+}
+
+func TestAggregateKeepsLookups(t *testing.T) {
+	prng := rand.New(rand.NewPCG(50, 50))
+	tbl := New[int]()
+	defer tbl.Close()
+	// dense prefixes in 10.0.0.0/16 with few values, so many merge
+	for range 400 {
+		a := netip.AddrFrom4([4]byte{10, 0, byte(prng.UintN(256)), byte(prng.UintN(256))})
+		pfx, _ := a.Prefix(16 + prng.IntN(9))
+		tbl.Insert(pfx, prng.IntN(2))
+	}
+
+	var want [1 << 16]int
+	for i := range want {
+		a := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+		if v, ok := tbl.Lookup(a); ok {
+			want[i] = v
+		} else {
+			want[i] = -1
+		}
+	}
+
+	before := tbl.Size()
+	shrunk := tbl.Aggregate(func(a, b int) bool { return a == b })
+	if shrunk <= 0 || tbl.Size() != before-shrunk {
+		t.Fatalf("Aggregate shrank %d prefixes to %d, reported %d", before, tbl.Size(), shrunk)
+	}
+	for i := range want {
+		a := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+		v, ok := tbl.Lookup(a)
+		if !ok {
+			v = -1
+		}
+		if v != want[i] {
+			t.Fatalf("Lookup(%s) = %d after Aggregate, want %d", a, v, want[i])
+		}
+	}
+	if again := tbl.Aggregate(func(a, b int) bool { return a == b }); again != 0 {
+		t.Errorf("second Aggregate shrank the table by %d", again)
+	}
+}
@@ -0,0 +1,73 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+)
+
+// All returns an iterator over all prefixes in the table with their
+// values, IPv4 before IPv6. Within an address family the order is the
+// trie order and not otherwise specified.
+//
+// The trie is walked in C, which hands the prefixes to Go in batches,
+// so iterating a large table takes no copy of it. The table must not be
+// modified until the iteration is finished.
+func (t *Table[V]) All() iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		t.trie.walk(func(batch []route) bool {
+			for i := range batch {
+				if !yield(batch[i].prefix(), t.vals.get(batch[i].val)) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// AllSorted is All in the canonical CIDR order of netip.Prefix: IPv4
+// before IPv6 and within a family by address and then by prefix length,
+// so a supernet comes before its subnets. The order comes from the walk
+// in C, the prefixes are not collected and sorted in Go, and two tables
+// with the same contents yield the same sequence.
+func (t *Table[V]) AllSorted() iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		t.trie.walkSorted(func(batch []route) bool {
+			for i := range batch {
+				if !yield(batch[i].prefix(), t.vals.get(batch[i].val)) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Walk calls fn for every prefix in the table, in the order of All, until
+// fn returns false.
+func (t *Table[V]) Walk(fn func(pfx netip.Prefix, val V) bool) {
+	for pfx, val := range t.All() {
+		if !fn(pfx, val) {
+			return
+		}
+	}
+}
+
+// routes dumps the trie, sized by the prefix counters so one call is
+// enough.
+func (t *Table[V]) routes() []route {
+	return fill(t.Size(), t.trie.dump)
+}
+
+// fill calls dump with a buffer of size hint and again with a larger one
+// until all routes fit, dump has the semantics of bart_dump.
+func fill[T any](hint int, dump func(out []T) int) []T {
+	buf := make([]T, hint)
+	for {
+		n := dump(buf)
+		if n <= len(buf) {
+			return buf[:n]
+		}
+		buf = make([]T, n)
+	}
+}
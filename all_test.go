@@ -0,0 +1,116 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	prng := rand.New(rand.NewPCG(9, 9))
+
+	tbl := New[int]()
+	defer tbl.Close()
+
+	want := map[netip.Prefix]int{}
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, i)
+		want[pfx] = i
+	}
+	for _, pfx := range []string{"0.0.0.0/0", "::/0", "10.1.2.3/32", "2001:db8::1/128"} {
+		tbl.Insert(mpp(pfx), -1)
+		want[mpp(pfx)] = -1
+	}
+
+	got := map[netip.Prefix]int{}
+	seen6 := false
+	for pfx, val := range tbl.All() {
+		if pfx.Addr().Is4() && seen6 {
+			t.Fatalf("All yielded IPv4 %s after IPv6", pfx)
+		}
+		seen6 = seen6 || pfx.Addr().Is6()
+		if _, dup := got[pfx]; dup {
+			t.Fatalf("All yielded %s twice", pfx)
+		}
+		got[pfx] = val
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("All yielded %d prefixes, want %d", len(got), len(want))
+	}
+	for pfx, val := range want {
+		if got[pfx] != val {
+			t.Errorf("All: %s = %d, want %d", pfx, got[pfx], val)
+		}
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range []string{"10.0.0.0/8", "10.1.0.0/16", "192.168.0.0/16", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), i)
+	}
+
+	calls := 0
+	tbl.Walk(func(netip.Prefix, int) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Errorf("Walk called fn %d times after it returned false, want 2", calls)
+	}
+}
+
+func TestAllBatches(t *testing.T) {
+	prng := rand.New(rand.NewPCG(82, 82))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 3*batchSize) {
+		tbl.Insert(pfx, i)
+	}
+
+	// stop in the second batch, reading the table from the loop
+	n := 0
+	for pfx, val := range tbl.All() {
+		if got, ok := tbl.Get(pfx); !ok || got != val {
+			t.Fatalf("Get(%s) during All = %d, %v, want %d", pfx, got, ok, val)
+		}
+		if n++; n == batchSize+10 {
+			break
+		}
+	}
+	if n != batchSize+10 {
+		t.Errorf("All yielded %d prefixes before the break", n)
+	}
+}
+
+func TestAllSorted(t *testing.T) {
+	prng := rand.New(rand.NewPCG(84, 84))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2*batchSize) {
+		tbl.Insert(pfx, i)
+	}
+	for _, pfx := range []string{"0.0.0.0/0", "10.0.0.0/7", "10.0.0.0/8", "10.0.0.0/9", "::/0", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), -1)
+	}
+
+	var want []netip.Prefix
+	for pfx := range tbl.All() {
+		want = append(want, pfx)
+	}
+	slices.SortFunc(want, comparePrefix)
+
+	var got []netip.Prefix
+	for pfx, val := range tbl.AllSorted() {
+		if v, _ := tbl.Get(pfx); v != val {
+			t.Fatalf("AllSorted: %s = %d, want %d", pfx, val, v)
+		}
+		got = append(got, pfx)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("AllSorted is not in CIDR order, first %v", got[:min(len(got), 8)])
+	}
+}
@@ -0,0 +1,129 @@
+package zart
+
+import (
+	"net/netip"
+	"sync/atomic"
+)
+
+// AtomicTable publishes a Table to lock-free readers. A control goroutine
+// builds a new table and publishes it with Swap, lookups see either the
+// old or the new table as a whole. The old table is closed as soon as
+// the last reader still using it is done.
+//
+// Readers take no lock, each lookup increments and decrements a
+// reference count of the published table. The published table must not
+// be modified, build a new one instead, e.g. from Clone or InsertPersist.
+type AtomicTable[V any] struct {
+	cur atomic.Pointer[version[V]]
+}
+
+// version is a published table with its reference count. The
+// publication holds one reference, each reader another. The table is
+// closed when the count drops to zero, after which it can't be acquired
+// again.
+type version[V any] struct {
+	t    *Table[V]
+	refs atomic.Int64
+}
+
+// NewAtomic returns an AtomicTable publishing t, which it takes over.
+func NewAtomic[V any](t *Table[V]) *AtomicTable[V] {
+	a := &AtomicTable[V]{}
+	a.cur.Store(newVersion(t))
+	return a
+}
+
+func newVersion[V any](t *Table[V]) *version[V] {
+	v := &version[V]{t: t}
+	v.refs.Store(1)
+	return v
+}
+
+// Swap publishes t and retires the previous table, which is closed once
+// the readers using it are done. The AtomicTable takes over t.
+func (a *AtomicTable[V]) Swap(t *Table[V]) {
+	if old := a.cur.Swap(newVersion(t)); old != nil {
+		old.release()
+	}
+}
+
+// Close retires the published table. Readers must not use the
+// AtomicTable afterwards.
+func (a *AtomicTable[V]) Close() {
+	if old := a.cur.Swap(nil); old != nil {
+		old.release()
+	}
+}
+
+// Read calls fn with the published table, which stays open until fn
+// returns even if it is swapped out meanwhile. fn must not modify the
+// table or keep it after returning.
+func (a *AtomicTable[V]) Read(fn func(t *Table[V])) {
+	v := a.acquire()
+	defer v.release()
+	fn(v.t)
+}
+
+// Lookup is like Table.Lookup on the published table.
+func (a *AtomicTable[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	v := a.acquire()
+	val, ok = v.t.Lookup(addr)
+	v.release()
+	return val, ok
+}
+
+// Contains is like Table.Contains on the published table.
+func (a *AtomicTable[V]) Contains(addr netip.Addr) bool {
+	v := a.acquire()
+	ok := v.t.Contains(addr)
+	v.release()
+	return ok
+}
+
+// LookupPrefix is like Table.LookupPrefix on the published table.
+func (a *AtomicTable[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	v := a.acquire()
+	pfx, val, ok = v.t.LookupPrefix(addr)
+	v.release()
+	return pfx, val, ok
+}
+
+// Get is like Table.Get on the published table.
+func (a *AtomicTable[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	v := a.acquire()
+	val, ok = v.t.Get(pfx)
+	v.release()
+	return val, ok
+}
+
+// LookupBatch is like Table.LookupBatch on the published table, all
+// addresses are resolved against the same table.
+func (a *AtomicTable[V]) LookupBatch(addrs []netip.Addr, results []Result[V]) {
+	v := a.acquire()
+	v.t.LookupBatch(addrs, results)
+	v.release()
+}
+
+// acquire returns the published version with a reference held. A version
+// whose count already dropped to zero is being closed, acquire then
+// retries with the version that replaced it.
+func (a *AtomicTable[V]) acquire() *version[V] {
+	for {
+		v := a.cur.Load()
+		if v == nil {
+			panic("zart: use of closed AtomicTable")
+		}
+		for n := v.refs.Load(); n > 0; n = v.refs.Load() {
+			if v.refs.CompareAndSwap(n, n+1) {
+				return v
+			}
+		}
+	}
+}
+
+// release drops a reference and closes the table with the last one.
+func (v *version[V]) release() {
+	if v.refs.Add(-1) == 0 {
+		v.t.Close()
+	}
+}
@@ -0,0 +1,73 @@
+package zart
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestAtomicTableSwap checks that readers always see a complete table
+// while tables are swapped underneath them, run it with -race.
+func TestAtomicTableSwap(t *testing.T) {
+	build := func(gen int) *Table[int] {
+		tbl := New[int]()
+		tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), gen)
+		tbl.Insert(netip.MustParsePrefix("10.1.0.0/16"), gen)
+		return tbl
+	}
+	a := NewAtomic(build(0))
+	defer a.Close()
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results := make([]Result[int], 2)
+			addrs := []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.1.0.1")}
+			for !stop.Load() {
+				if _, ok := a.Lookup(addrs[0]); !ok {
+					t.Error("Lookup missed")
+					return
+				}
+				a.LookupBatch(addrs, results)
+				if results[0] != results[1] {
+					t.Errorf("LookupBatch mixed two tables: %v", results)
+					return
+				}
+			}
+		}()
+	}
+	for gen := 1; gen <= 200; gen++ {
+		a.Swap(build(gen))
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if v, _ := a.Lookup(netip.MustParseAddr("10.0.0.1")); v != 200 {
+		t.Errorf("Lookup after the last Swap = %d, want 200", v)
+	}
+}
+
+func TestAtomicTableRetire(t *testing.T) {
+	old := New[string]()
+	old.Insert(netip.MustParsePrefix("0.0.0.0/0"), "old")
+	a := NewAtomic(old)
+	defer a.Close()
+
+	a.Read(func(tbl *Table[string]) {
+		a.Swap(New[string]())
+		// the swapped out table stays usable until Read returns
+		if v, ok := tbl.Lookup(netip.MustParseAddr("192.0.2.1")); !ok || v != "old" {
+			t.Errorf("Lookup in retired table = %q, %v", v, ok)
+		}
+	})
+	if old.vals.vals != nil {
+		t.Errorf("retired table not closed after the last reader")
+	}
+	if _, ok := a.Lookup(netip.MustParseAddr("192.0.2.1")); ok {
+		t.Errorf("Lookup found a route of the retired table")
+	}
+}
@@ -0,0 +1,63 @@
+package zart
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithAuditLogger makes the table write an audit trail of its changes to
+// logger, at level Info:
+//
+//   - a record "zart insert", "zart update" or "zart delete" for every
+//     prefix changed, whichever method changes it, with the attributes
+//     prefix, old and new as they apply and tags if the prefix has any;
+//   - a record "zart <op>" at the end of every bulk operation op, as
+//     named for Telemetry.Start, with the number of routes, its latency
+//     and its error if it failed. The commit of a transaction is one.
+//
+// The records are written by the goroutine changing the table before the
+// change returns. The prefix records go through the path of Watch events:
+// batch inserts on an audited table insert prefix by prefix. Tables made
+// from the table, like clones and persistent versions, write to logger
+// as well.
+func WithAuditLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.audit = logger }
+}
+
+// audited registers the audit hook of a table created with
+// WithAuditLogger, or with the options of one, and returns the table.
+func (t *Table[V]) audited() *Table[V] {
+	if t.opts.audit != nil {
+		t.hook(t.auditEvent)
+	}
+	return t
+}
+
+// auditEvent writes the audit record of one change.
+func (t *Table[V]) auditEvent(ev Event[V]) {
+	attrs := make([]slog.Attr, 1, 4)
+	attrs[0] = slog.String("prefix", ev.Prefix.String())
+	if ev.Kind != EventInsert {
+		attrs = append(attrs, slog.Any("old", ev.Old))
+	}
+	if ev.Kind != EventDelete {
+		attrs = append(attrs, slog.Any("new", ev.New))
+	}
+	if t.tags != nil {
+		if tags := t.tags.byPfx[ev.Prefix]; len(tags) > 0 {
+			attrs = append(attrs, slog.Any("tags", tags))
+		}
+	}
+	t.opts.audit.LogAttrs(context.Background(), slog.LevelInfo, "zart "+ev.Kind.String(), attrs...)
+}
+
+// auditOp writes the audit record of a bulk operation that started at
+// begin.
+func (t *Table[V]) auditOp(op string, begin time.Time, n int, err error) {
+	attrs := []slog.Attr{slog.Int("routes", n), slog.Duration("latency", time.Since(begin))}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	t.opts.audit.LogAttrs(context.Background(), slog.LevelInfo, "zart "+op, attrs...)
+}
@@ -0,0 +1,59 @@
+package zart
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestAuditLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	c := New[int](WithAuditLogger(logger)).WithLocking()
+	defer c.Close()
+
+	c.Insert(mpp("10.0.0.0/8"), 1)
+	tx := c.Begin()
+	tx.Insert(mpp("10.0.0.0/8"), 2)
+	tx.Delete(mpp("10.0.0.0/8"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	type record struct {
+		Msg     string
+		Prefix  string
+		Old     *int
+		New     *int
+		Routes  *int
+		Latency *int64
+	}
+	var got []record
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	msgs := []string{"zart insert", "zart update", "zart insert_batch", "zart delete", "zart commit"}
+	if len(got) != len(msgs) {
+		t.Fatalf("%d records, want %d: %+v", len(got), len(msgs), got)
+	}
+	for i, m := range msgs {
+		if got[i].Msg != m {
+			t.Errorf("record %d is %q, want %q", i, got[i].Msg, m)
+		}
+	}
+	if r := got[1]; r.Prefix != "10.0.0.0/8" || r.Old == nil || *r.Old != 1 || r.New == nil || *r.New != 2 {
+		t.Errorf("update record = %+v", r)
+	}
+	if r := got[3]; r.New != nil || r.Old == nil || *r.Old != 2 {
+		t.Errorf("delete record = %+v", r)
+	}
+	if r := got[4]; r.Routes == nil || *r.Routes != 2 || r.Latency == nil {
+		t.Errorf("commit record = %+v", r)
+	}
+}
@@ -0,0 +1,201 @@
+package zart
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	// batchSize is the number of entries marshalled per crossing into C.
+	batchSize = 1024
+
+	// lookupChunk is the number of addresses resolved per crossing into C
+	// by the LookupBatch functions. The scratch buffers for one chunk
+	// live on the stack.
+	lookupChunk = 256
+)
+
+// RouteEntry is a prefix with its payload, the element of the batch APIs.
+type RouteEntry[V any] struct {
+	Prefix netip.Prefix
+	Value  V
+}
+
+// route is the marshalled form of a RouteEntry, laid out exactly like
+// bart_route_t in include/bart.h. For IPv4 only addr[:4] is used.
+type route struct {
+	addr [16]byte
+	bits uint8
+	is4  uint8
+	_    [2]byte
+	val  uint32
+}
+
+func makeRoute(pfx netip.Prefix, val uint32) route {
+	r := route{bits: uint8(pfx.Bits()), val: val}
+	if addr := pfx.Addr(); addr.Is4() {
+		a4 := addr.As4()
+		copy(r.addr[:], a4[:])
+		r.is4 = 1
+	} else {
+		r.addr = addr.As16()
+	}
+	return r
+}
+
+// prefix converts r back into a canonical netip.Prefix, host bits in
+// addr are masked off.
+func (r *route) prefix() netip.Prefix {
+	if r.is4 != 0 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(r.addr[:4])), int(r.bits)).Masked()
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(r.addr), int(r.bits)).Masked()
+}
+
+// InsertBatch inserts all entries, in order, as if Insert was called for
+// each of them. The entries are marshalled into a contiguous buffer and
+// handed to the trie in chunks, so a full-table load costs one cgo call
+// per chunk instead of one per prefix. Invalid prefixes are skipped, as
+// are new ones once the table is over its memory limit.
+//
+// A watched table inserts the entries one by one, to report the values
+// they replace.
+func (t *Table[V]) InsertBatch(entries []RouteEntry[V]) {
+	t.mutable()
+	if len(entries) == 0 {
+		return
+	}
+	defer t.start("insert_batch")(len(entries), nil)
+	if t.watch != nil {
+		for _, e := range entries {
+			t.Insert(e.Prefix, e.Value)
+		}
+		return
+	}
+
+	t.changes++
+	inserted, updated := 0, 0
+	n := min(len(entries), batchSize)
+	buf := make([]route, 0, n)
+	replaced := make([]uint32, n)
+
+	for len(entries) > 0 {
+		chunk := entries[:min(len(entries), batchSize)]
+		entries = entries[len(chunk):]
+
+		buf = buf[:0]
+		full := t.full()
+		for _, e := range chunk {
+			if t.accepts(e.Prefix) && (!full || t.present(e.Prefix)) {
+				buf = append(buf, makeRoute(e.Prefix, t.vals.alloc(e.Value)))
+				if t.ttl != nil {
+					t.ttl.forget(e.Prefix.Masked())
+				}
+			}
+		}
+		k := t.trie.insertBulk(buf, replaced)
+		for _, slot := range replaced[:k] {
+			t.vals.release(slot)
+		}
+		inserted, updated = inserted+len(buf)-k, updated+k
+	}
+	t.count(EventInsert, inserted)
+	t.count(EventUpdate, updated)
+}
+
+// Result is the outcome of a single lookup in a batch, OK is false if no
+// prefix matched.
+type Result[V any] struct {
+	Value V
+	OK    bool
+}
+
+// LookupBatch performs a longest-prefix match for every address in addrs
+// and stores the outcome in the result with the same index. IPv4 and IPv6
+// addresses may be mixed, invalid addresses never match. It panics if
+// results is shorter than addrs.
+//
+// The addresses are resolved in chunks with one cgo call per chunk and
+// address family, which makes LookupBatch the fast path for dataplanes
+// that classify packets in bursts.
+func (t *Table[V]) LookupBatch(addrs []netip.Addr, results []Result[V]) {
+	results = results[:len(addrs)]
+
+	var (
+		addrs4     [lookupChunk]uint32
+		addrs6     [lookupChunk][16]byte
+		idx4, idx6 [lookupChunk]uint16
+		vals       [lookupChunk]uint32
+		found      [lookupChunk]uint8
+	)
+	for base := 0; base < len(addrs); base += lookupChunk {
+		chunk := addrs[base:min(base+lookupChunk, len(addrs))]
+
+		n4, n6 := 0, 0
+		for i, addr := range chunk {
+			addr = t.key(addr)
+			switch {
+			case addr.Is4():
+				a4 := addr.As4()
+				addrs4[n4], idx4[n4] = binary.BigEndian.Uint32(a4[:]), uint16(i)
+				n4++
+			case addr.IsValid():
+				addrs6[n6], idx6[n6] = addr.As16(), uint16(i)
+				n6++
+			default:
+				results[base+i] = Result[V]{}
+			}
+		}
+
+		t.trie.lookupBatch4(addrs4[:n4], vals[:n4], found[:n4])
+		for j, i := range idx4[:n4] {
+			results[base+int(i)] = t.result(vals[j], found[j])
+		}
+
+		t.trie.lookupBatch6(addrs6[:n6], vals[:n6], found[:n6])
+		for j, i := range idx6[:n6] {
+			results[base+int(i)] = t.result(vals[j], found[j])
+		}
+	}
+}
+
+// LookupBatch4 is like LookupBatch for IPv4 addresses given as host-order
+// integers, 10.0.0.1 is 0x0a000001. The addresses are passed to C as is,
+// without any conversion.
+func (t *Table[V]) LookupBatch4(addrs []uint32, results []Result[V]) {
+	results = results[:len(addrs)]
+
+	var vals [lookupChunk]uint32
+	var found [lookupChunk]uint8
+	for base := 0; base < len(addrs); base += lookupChunk {
+		chunk := addrs[base:min(base+lookupChunk, len(addrs))]
+		t.trie.lookupBatch4(chunk, vals[:len(chunk)], found[:len(chunk)])
+		for i := range chunk {
+			results[base+i] = t.result(vals[i], found[i])
+		}
+	}
+}
+
+// LookupBatch6 is like LookupBatch for IPv6 addresses given as 16 bytes
+// in network order.
+func (t *Table[V]) LookupBatch6(addrs [][16]byte, results []Result[V]) {
+	results = results[:len(addrs)]
+
+	var vals [lookupChunk]uint32
+	var found [lookupChunk]uint8
+	for base := 0; base < len(addrs); base += lookupChunk {
+		chunk := addrs[base:min(base+lookupChunk, len(addrs))]
+		t.trie.lookupBatch6(chunk, vals[:len(chunk)], found[:len(chunk)])
+		for i := range chunk {
+			results[base+i] = t.result(vals[i], found[i])
+		}
+	}
+}
+
+// result resolves a slot returned by a batch lookup into a Result.
+func (t *Table[V]) result(slot uint32, found uint8) Result[V] {
+	if found == 0 {
+		return Result[V]{}
+	}
+	return Result[V]{Value: t.payload(slot), OK: true}
+}
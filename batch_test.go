@@ -0,0 +1,127 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func randomPrefixes(prng *rand.Rand, n int) []netip.Prefix {
+	pfxs := make([]netip.Prefix, n)
+	for i := range pfxs {
+		if prng.IntN(4) == 0 {
+			var a [16]byte
+			a[0], a[1] = 0x20, 0x01
+			for j := 2; j < 8; j++ {
+				a[j] = byte(prng.UintN(8))
+			}
+			pfxs[i] = netip.PrefixFrom(netip.AddrFrom16(a), 16+prng.IntN(49)).Masked()
+			continue
+		}
+		a := [4]byte{byte(prng.UintN(16)), byte(prng.UintN(256)), byte(prng.UintN(256)), byte(prng.UintN(256))}
+		pfxs[i] = netip.PrefixFrom(netip.AddrFrom4(a), 8+prng.IntN(25)).Masked()
+	}
+	return pfxs
+}
+
+func TestInsertBatchMatchesInsert(t *testing.T) {
+	prng := rand.New(rand.NewPCG(7, 7))
+	pfxs := randomPrefixes(prng, 3*batchSize+17)
+
+	entries := make([]RouteEntry[int], 0, len(pfxs)+1)
+	for i, pfx := range pfxs {
+		entries = append(entries, RouteEntry[int]{pfx, i})
+	}
+	entries = append(entries, RouteEntry[int]{netip.Prefix{}, -1})
+
+	batch := New[int]()
+	defer batch.Close()
+	batch.InsertBatch(entries)
+
+	single := New[int]()
+	defer single.Close()
+	for _, e := range entries {
+		single.Insert(e.Prefix, e.Value)
+	}
+
+	for _, pfx := range randomPrefixes(prng, 5000) {
+		addr := pfx.Addr()
+		got, gotOK := batch.Lookup(addr)
+		want, wantOK := single.Lookup(addr)
+		if got != want || gotOK != wantOK {
+			t.Fatalf("Lookup(%s) = %d, %v, want %d, %v", addr, got, gotOK, want, wantOK)
+		}
+	}
+}
+
+func TestInsertBatchReleasesDuplicates(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	pfx := mpp("10.0.0.0/8")
+	tbl.InsertBatch([]RouteEntry[int]{{pfx, 1}, {pfx, 2}, {pfx, 3}})
+
+	if got, _ := tbl.Lookup(mpa("10.0.0.1")); got != 3 {
+		t.Errorf("Lookup = %d, want the last duplicate 3", got)
+	}
+	if live := len(tbl.vals.vals) - len(tbl.vals.free); live != 1 {
+		t.Errorf("%d live slots for a single prefix, want 1", live)
+	}
+}
+
+func TestLookupBatchMatchesLookup(t *testing.T) {
+	prng := rand.New(rand.NewPCG(8, 8))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, i)
+	}
+
+	var addrs []netip.Addr
+	for _, pfx := range randomPrefixes(prng, 3*lookupChunk+5) {
+		addrs = append(addrs, pfx.Addr())
+	}
+	addrs = append(addrs, netip.Addr{})
+
+	results := make([]Result[int], len(addrs))
+	for i := range results {
+		results[i] = Result[int]{Value: -1, OK: true}
+	}
+	tbl.LookupBatch(addrs, results)
+
+	var addrs4 []uint32
+	var addrs6 [][16]byte
+	var want4, want6 []Result[int]
+	for i, addr := range addrs {
+		val, ok := tbl.Lookup(addr)
+		if results[i] != (Result[int]{val, ok}) {
+			t.Fatalf("LookupBatch[%d] (%s) = %v, want {%d %v}", i, addr, results[i], val, ok)
+		}
+		switch {
+		case addr.Is4():
+			a4 := addr.As4()
+			addrs4 = append(addrs4, uint32(a4[0])<<24|uint32(a4[1])<<16|uint32(a4[2])<<8|uint32(a4[3]))
+			want4 = append(want4, results[i])
+		case addr.IsValid():
+			addrs6 = append(addrs6, addr.As16())
+			want6 = append(want6, results[i])
+		}
+	}
+
+	got4 := make([]Result[int], len(addrs4))
+	tbl.LookupBatch4(addrs4, got4)
+	for i := range got4 {
+		if got4[i] != want4[i] {
+			t.Fatalf("LookupBatch4[%d] = %v, want %v", i, got4[i], want4[i])
+		}
+	}
+
+	got6 := make([]Result[int], len(addrs6))
+	tbl.LookupBatch6(addrs6, got6)
+	for i := range got6 {
+		if got6[i] != want6[i] {
+			t.Fatalf("LookupBatch6[%d] = %v, want %v", i, got6[i], want6[i])
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package bench
+
+import (
+	"bufio"
+	"compress/gzip"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// fullTable reads the prefixes of the full table once, in file order.
+var fullTable = sync.OnceValues(func() ([]netip.Prefix, error) {
+	f, err := os.Open("../testdata/prefixes.txt.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var pfxs []netip.Prefix
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		pfx, err := netip.ParsePrefix(sc.Text())
+		if err != nil {
+			return nil, err
+		}
+		pfxs = append(pfxs, pfx.Masked())
+	}
+	return pfxs, sc.Err()
+})
+
+func prefixes(b *testing.B) []netip.Prefix {
+	b.Helper()
+	pfxs, err := fullTable()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return pfxs
+}
+
+// probes returns n addresses, within the prefixes of the table for hits
+// and outside all of them for misses. The same seed gives every
+// implementation the same addresses.
+func probes(pfxs []netip.Prefix, n int, hit bool) []netip.Addr {
+	ref := newMapTable()
+	for _, pfx := range pfxs {
+		ref.Insert(pfx, 0)
+	}
+	prng := rand.New(rand.NewPCG(1, 2))
+	addrs := make([]netip.Addr, 0, n)
+	for len(addrs) < n {
+		var addr netip.Addr
+		if hit {
+			addr = randomAddr(prng, pfxs[prng.IntN(len(pfxs))])
+		} else if prng.IntN(2) == 0 {
+			addr = netip.AddrFrom4([4]byte{byte(prng.Uint32()), byte(prng.Uint32()), byte(prng.Uint32()), byte(prng.Uint32())})
+		} else {
+			var a [16]byte
+			a[0], a[1] = 0x20|byte(prng.IntN(16)), byte(prng.Uint32())
+			for i := 2; i < 16; i++ {
+				a[i] = byte(prng.Uint32())
+			}
+			addr = netip.AddrFrom16(a)
+		}
+		if _, ok := ref.Lookup(addr); ok == hit {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// randomAddr returns a random address in pfx.
+func randomAddr(prng *rand.Rand, pfx netip.Prefix) netip.Addr {
+	a := pfx.Addr().As16()
+	off := 0
+	if pfx.Addr().Is4() {
+		off = 12
+	}
+	for i := off + (pfx.Bits()+7)/8; i < 16; i++ {
+		a[i] = byte(prng.Uint32())
+	}
+	if r := pfx.Bits() % 8; r != 0 {
+		i := off + pfx.Bits()/8
+		a[i] |= byte(prng.Uint32()) & (0xff >> r)
+	}
+	addr := netip.AddrFrom16(a)
+	if pfx.Addr().Is4() {
+		addr = addr.Unmap()
+	}
+	return addr
+}
+
+func load(im impl, pfxs []netip.Prefix) lpm {
+	t := im.new()
+	for i, pfx := range pfxs {
+		t.Insert(pfx, i)
+	}
+	return t
+}
+
+func BenchmarkInsert(b *testing.B) {
+	pfxs := prefixes(b)
+	for _, im := range impls {
+		b.Run(im.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				load(im, pfxs).Close()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(pfxs)), "ns/prefix")
+		})
+	}
+}
+
+func BenchmarkDelete(b *testing.B) {
+	pfxs := prefixes(b)
+	for _, im := range impls {
+		b.Run(im.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				t := load(im, pfxs)
+				b.StartTimer()
+				for _, pfx := range pfxs {
+					t.Delete(pfx)
+				}
+				b.StopTimer()
+				t.Close()
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(pfxs)), "ns/prefix")
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	pfxs := prefixes(b)
+	for _, kind := range []struct {
+		name string
+		hit  bool
+	}{{"hit", true}, {"miss", false}} {
+		addrs := probes(pfxs, 1<<16, kind.hit)
+		for _, im := range impls {
+			b.Run(kind.name+"/"+im.name, func(b *testing.B) {
+				t := load(im, pfxs)
+				defer t.Close()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					if _, ok := t.Lookup(addrs[n%len(addrs)]); ok != kind.hit {
+						b.Fatalf("Lookup(%s) = %v", addrs[n%len(addrs)], ok)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkMemory reports the memory of a full table per prefix. The Go
+// heap is measured for all implementations, the C trie of zart is added
+// from its statistics unless it is built with purego.
+func BenchmarkMemory(b *testing.B) {
+	pfxs := prefixes(b)
+	for _, im := range impls {
+		b.Run(im.name, func(b *testing.B) {
+			var bytes float64
+			for n := 0; n < b.N; n++ {
+				before := heap()
+				t := load(im, pfxs)
+				bytes = float64(heap() - before)
+				if z, ok := t.(zartTable); ok && cTrie {
+					bytes += float64(z.Stats().Bytes())
+				}
+				runtime.KeepAlive(t)
+				t.Close()
+			}
+			b.ReportMetric(bytes/float64(len(pfxs)), "bytes/prefix")
+		})
+	}
+}
+
+func heap() uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
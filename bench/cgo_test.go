@@ -0,0 +1,6 @@
+//go:build cgo && !purego
+
+package bench
+
+// cTrie is set when zart keeps its trie outside the Go heap.
+const cTrie = true
@@ -0,0 +1,42 @@
+//go:build critbitgo
+
+package bench
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/k-sone/critbitgo"
+)
+
+func init() {
+	impls = append(impls, impl{"critbitgo", func() lpm { return critbitTable{critbitgo.NewNet()} }})
+}
+
+// critbitTable adapts the net.IP based critbitgo.Net, the conversions
+// from net/netip are part of what the benchmarks measure.
+type critbitTable struct{ *critbitgo.Net }
+
+func ipNet(pfx netip.Prefix) *net.IPNet {
+	pfx = pfx.Masked()
+	addr := pfx.Addr()
+	return &net.IPNet{IP: addr.AsSlice(), Mask: net.CIDRMask(pfx.Bits(), addr.BitLen())}
+}
+
+func (t critbitTable) Insert(pfx netip.Prefix, val int) {
+	t.Net.Add(ipNet(pfx), val)
+}
+
+func (t critbitTable) Delete(pfx netip.Prefix) {
+	t.Net.Delete(ipNet(pfx))
+}
+
+func (t critbitTable) Lookup(addr netip.Addr) (int, bool) {
+	route, val := t.Net.MatchIP(addr.AsSlice())
+	if route == nil {
+		return 0, false
+	}
+	return val.(int), true
+}
+
+func (critbitTable) Close() {}
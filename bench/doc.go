@@ -0,0 +1,15 @@
+// Package bench compares zart with other longest-prefix-match tables on a
+// full Internet routing table, the prefixes in ../testdata/prefixes.txt.gz.
+//
+// The benchmarks insert, delete and look up the same prefixes and
+// addresses in every implementation:
+//
+//	go test -bench . -benchmem
+//	go test -bench 'Lookup/(zart|bart)' -count 10 | benchstat -
+//	go test -tags purego -bench .  # zart without cgo
+//
+// BenchmarkMemory reports the bytes per prefix of a full table, for zart
+// the C trie plus the Go side. The module is separate from zart so that
+// its dependencies stay out of zart's go.mod. critbitgo is only compared
+// with the critbitgo build tag, after go get github.com/k-sone/critbitgo.
+package bench
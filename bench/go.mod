@@ -0,0 +1,12 @@
+module github.com/gx14ac/zart/bench
+
+go 1.24.0
+
+require (
+	github.com/gaissmai/bart v0.30.0
+	github.com/gx14ac/zart v0.0.0
+)
+
+require golang.org/x/sys v0.30.0 // indirect
+
+replace github.com/gx14ac/zart => ../
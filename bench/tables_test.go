@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"net/netip"
+
+	"github.com/gaissmai/bart"
+	"github.com/gx14ac/zart"
+)
+
+// lpm is the part of a table the benchmarks need.
+type lpm interface {
+	Insert(pfx netip.Prefix, val int)
+	Delete(pfx netip.Prefix)
+	Lookup(addr netip.Addr) (int, bool)
+
+	// Close releases memory not managed by the Go garbage collector.
+	Close()
+}
+
+// impl is an implementation under test, impls lists all of them.
+type impl struct {
+	name string
+	new  func() lpm
+}
+
+var impls = []impl{
+	{"zart", func() lpm { return zartTable{zart.New[int]()} }},
+	{"zart-arena", func() lpm { return zartTable{zart.New[int](zart.WithArena(0))} }},
+	{"bart", func() lpm { return bartTable{new(bart.Table[int])} }},
+	{"netipmap", func() lpm { return newMapTable() }},
+}
+
+type zartTable struct{ *zart.Table[int] }
+
+func (t zartTable) Delete(pfx netip.Prefix) { t.Table.Delete(pfx) }
+
+type bartTable struct{ *bart.Table[int] }
+
+func (bartTable) Close() {}
+
+// mapTable is the straightforward net/netip table: one map per prefix
+// length, looked up from the longest length down.
+type mapTable struct {
+	byBits [2][129]map[netip.Prefix]int
+}
+
+func newMapTable() *mapTable {
+	return &mapTable{}
+}
+
+func family(addr netip.Addr) int {
+	if addr.Is4() {
+		return 0
+	}
+	return 1
+}
+
+func (t *mapTable) Insert(pfx netip.Prefix, val int) {
+	pfx = pfx.Masked()
+	m := &t.byBits[family(pfx.Addr())][pfx.Bits()]
+	if *m == nil {
+		*m = map[netip.Prefix]int{}
+	}
+	(*m)[pfx] = val
+}
+
+func (t *mapTable) Delete(pfx netip.Prefix) {
+	pfx = pfx.Masked()
+	delete(t.byBits[family(pfx.Addr())][pfx.Bits()], pfx)
+}
+
+func (t *mapTable) Lookup(addr netip.Addr) (int, bool) {
+	maps := &t.byBits[family(addr)]
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		if len(maps[bits]) == 0 {
+			continue
+		}
+		pfx, _ := addr.Prefix(bits)
+		if val, ok := maps[bits][pfx]; ok {
+			return val, true
+		}
+	}
+	return 0, false
+}
+
+func (*mapTable) Close() {}
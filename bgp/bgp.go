@@ -0,0 +1,79 @@
+// Package bgp applies live BGP announcements and withdrawals to a zart
+// table.
+//
+// A Source delivers the routing events of one BGP message at a time.
+// BMPReader is a Source for BGP Monitoring Protocol (RFC 7854) streams;
+// feeds with their own client library, such as the gobgp watch API, are
+// connected by converting their updates into Events in a small Source
+// implementation.
+//
+// The table holds one route per prefix, so a feed should carry a single
+// view, like the routes of one peer or the best paths of a route server.
+package bgp
+
+import (
+	"io"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// Route is a prefix with the attributes of its announcement.
+type Route struct {
+	Prefix netip.Prefix
+
+	// Peer and PeerAS identify the BGP peer that sent the route.
+	Peer   netip.Addr
+	PeerAS uint32
+
+	// Origin is the last AS number on the AS_PATH, 0 for an empty path.
+	Origin  uint32
+	NextHop netip.Addr
+}
+
+// Event announces a Route or, with Withdraw set, withdraws its Prefix.
+// The other fields of a withdrawn Route are only partially set.
+type Event struct {
+	Withdraw bool
+	Route
+}
+
+// Source is a feed of routing events. Next blocks until the next message
+// arrives and returns its events in order, at the end of the feed it
+// returns io.EOF. The slice is only valid until the next call.
+type Source interface {
+	Next() ([]Event, error)
+}
+
+// Apply reads src until it fails and applies every event to t, value
+// computes the payload of an announced route. It returns the number of
+// events applied and the error of src, which is nil at io.EOF.
+//
+// The events of a message are applied in order, runs of announcements
+// go into the table with InsertBatch. A message is fully applied before
+// the next one is read, so the table is current whenever src blocks.
+// Apply must be the only user of t while it runs.
+func Apply[V any](t *zart.Table[V], src Source, value func(Route) V) (n int, err error) {
+	var batch []zart.RouteEntry[V]
+	for {
+		events, err := src.Next()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+		for _, e := range events {
+			if e.Withdraw {
+				t.InsertBatch(batch)
+				batch = batch[:0]
+				t.Delete(e.Prefix)
+				continue
+			}
+			batch = append(batch, zart.RouteEntry[V]{Prefix: e.Prefix, Value: value(e.Route)})
+		}
+		t.InsertBatch(batch)
+		batch = batch[:0]
+		n += len(events)
+	}
+}
@@ -0,0 +1,121 @@
+package bgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+// bmpMessage returns a BMP message of type typ with body.
+func bmpMessage(typ byte, body []byte) []byte {
+	b := []byte{bmpVersion}
+	b = binary.BigEndian.AppendUint32(b, uint32(bmpCommonHeaderLen+len(body)))
+	b = append(b, typ)
+	return append(b, body...)
+}
+
+// routeMonitoring returns a Route Monitoring body for a BGP UPDATE from
+// the IPv4 peer 192.0.2.254 in AS 64496.
+func routeMonitoring(withdrawn, attrs, nlri []byte) []byte {
+	b := make([]byte, bmpPeerHeaderLen)
+	copy(b[22:], []byte{192, 0, 2, 254})
+	binary.BigEndian.PutUint32(b[26:], 64496)
+
+	var u []byte
+	u = binary.BigEndian.AppendUint16(u, uint16(len(withdrawn)))
+	u = append(u, withdrawn...)
+	u = binary.BigEndian.AppendUint16(u, uint16(len(attrs)))
+	u = append(u, attrs...)
+	u = append(u, nlri...)
+
+	msg := bytes.Repeat([]byte{0xff}, 16)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(bgpHeaderLen+len(u)))
+	msg = append(msg, bgpUpdate)
+	return append(append(b, msg...), u...)
+}
+
+func nlri(pfxs ...string) []byte {
+	var b []byte
+	for _, s := range pfxs {
+		pfx := netip.MustParsePrefix(s)
+		b = append(b, byte(pfx.Bits()))
+		b = append(b, pfx.Addr().AsSlice()[:(pfx.Bits()+7)/8]...)
+	}
+	return b
+}
+
+func attr(typ byte, val []byte) []byte {
+	return append([]byte{0x40, typ, byte(len(val))}, val...)
+}
+
+func asPath(asns ...uint32) []byte {
+	b := []byte{2, byte(len(asns))}
+	for _, asn := range asns {
+		b = binary.BigEndian.AppendUint32(b, asn)
+	}
+	return attr(attrASPath, b)
+}
+
+func TestApplyBMP(t *testing.T) {
+	var stream []byte
+	// peer up, skipped
+	stream = append(stream, bmpMessage(3, make([]byte, 20))...)
+
+	attrs := append(asPath(64496, 64500), attr(attrNextHop, []byte{192, 0, 2, 1})...)
+	stream = append(stream, bmpMessage(bmpRouteMonitoring,
+		routeMonitoring(nil, attrs, nlri("10.0.0.0/8", "198.51.100.0/24")))...)
+
+	mp := []byte{0, afiIPv6, safiUnicast, 16}
+	mp = append(mp, netip.MustParseAddr("2001:db8::1").AsSlice()...)
+	mp = append(mp, 0)
+	mp = append(mp, nlri("2001:db8:1::/48")...)
+	attrs = append(asPath(64496, 64501), attr(attrMPReach, mp)...)
+	stream = append(stream, bmpMessage(bmpRouteMonitoring, routeMonitoring(nil, attrs, nil))...)
+
+	// withdraw and re-announce with a new origin in one UPDATE
+	attrs = asPath(64502)
+	stream = append(stream, bmpMessage(bmpRouteMonitoring,
+		routeMonitoring(nlri("198.51.100.0/24", "10.0.0.0/8"), attrs, nlri("10.0.0.0/8")))...)
+
+	tbl := zart.New[Route]()
+	defer tbl.Close()
+	n, err := Apply(tbl, NewBMPReader(bytes.NewReader(stream)), func(r Route) Route { return r })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("Apply applied %d events, want 6", n)
+	}
+
+	r, ok := tbl.Lookup(netip.MustParseAddr("10.1.1.1"))
+	if !ok || r.Origin != 64502 || r.PeerAS != 64496 || r.Peer != netip.MustParseAddr("192.0.2.254") {
+		t.Errorf("10.0.0.0/8 = %+v, %v", r, ok)
+	}
+	if _, ok := tbl.Lookup(netip.MustParseAddr("198.51.100.1")); ok {
+		t.Errorf("withdrawn 198.51.100.0/24 still present")
+	}
+	r, ok = tbl.Lookup(netip.MustParseAddr("2001:db8:1::1"))
+	if !ok || r.Origin != 64501 || r.NextHop != netip.MustParseAddr("2001:db8::1") {
+		t.Errorf("2001:db8:1::/48 = %+v, %v", r, ok)
+	}
+}
+
+func TestBMPReaderErrors(t *testing.T) {
+	msg := bmpMessage(bmpRouteMonitoring, routeMonitoring(nil, asPath(64500), nlri("10.0.0.0/8")))
+
+	version := append([]byte(nil), msg...)
+	version[0] = 1
+	for name, b := range map[string][]byte{
+		"truncated": msg[:len(msg)-1],
+		"version":   version,
+		"nlri":      bmpMessage(bmpRouteMonitoring, routeMonitoring(nil, nil, []byte{33, 10, 0, 0, 0, 0})),
+	} {
+		if _, err := NewBMPReader(bytes.NewReader(b)).Next(); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: err = %v, want ErrFormat", name, err)
+		}
+	}
+}
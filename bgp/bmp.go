@@ -0,0 +1,286 @@
+package bgp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// BMP message types and per-peer header flags, RFC 7854.
+const (
+	bmpVersion         = 3
+	bmpRouteMonitoring = 0
+
+	bmpCommonHeaderLen = 6
+	bmpPeerHeaderLen   = 42
+
+	peerFlagIPv6     = 0x80
+	peerFlagLegacyAS = 0x20
+)
+
+// BGP message and path attribute types, RFC 4271 and RFC 4760.
+const (
+	bgpHeaderLen = 19
+	bgpUpdate    = 2
+
+	attrFlagExtendedLength = 0x10
+	attrASPath             = 2
+	attrNextHop            = 3
+	attrMPReach            = 14
+	attrMPUnreach          = 15
+
+	afiIPv4     = 1
+	afiIPv6     = 2
+	safiUnicast = 1
+)
+
+// maxMessage bounds a BMP message, BGP messages are at most 64k.
+const maxMessage = 1 << 20
+
+// ErrFormat is returned, wrapped, for malformed BMP or BGP data.
+var ErrFormat = errors.New("bgp: malformed message")
+
+// BMPReader is a Source reading a BMP stream, as sent by a router to its
+// monitoring station. Only Route Monitoring messages with unicast IPv4
+// and IPv6 routes produce events, all other messages are skipped.
+type BMPReader struct {
+	r      *bufio.Reader
+	buf    []byte
+	events []Event
+}
+
+// NewBMPReader returns a BMPReader for the stream r, typically the
+// accepted TCP connection of a router.
+func NewBMPReader(r io.Reader) *BMPReader {
+	return &BMPReader{r: bufio.NewReaderSize(r, 1<<16)}
+}
+
+// Next implements Source. Withdrawals come before announcements within
+// a message, as in the BGP UPDATE.
+func (r *BMPReader) Next() ([]Event, error) {
+	for {
+		var hdr [bmpCommonHeaderLen]byte
+		if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("%w: truncated BMP header", ErrFormat)
+			}
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(hdr[1:])
+		if hdr[0] != bmpVersion {
+			return nil, fmt.Errorf("%w: BMP version %d", ErrFormat, hdr[0])
+		}
+		if length < bmpCommonHeaderLen || length > maxMessage {
+			return nil, fmt.Errorf("%w: BMP message of %d bytes", ErrFormat, length)
+		}
+
+		n := int(length) - bmpCommonHeaderLen
+		if cap(r.buf) < n {
+			r.buf = make([]byte, n)
+		}
+		body := r.buf[:n]
+		if _, err := io.ReadFull(r.r, body); err != nil {
+			return nil, fmt.Errorf("%w: truncated BMP message", ErrFormat)
+		}
+		if hdr[5] != bmpRouteMonitoring {
+			continue
+		}
+
+		events, err := r.routeMonitoring(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			return events, nil
+		}
+	}
+}
+
+// routeMonitoring decodes the per-peer header and BGP UPDATE of a Route
+// Monitoring message.
+func (r *BMPReader) routeMonitoring(b []byte) ([]Event, error) {
+	if len(b) < bmpPeerHeaderLen+bgpHeaderLen {
+		return nil, fmt.Errorf("%w: short route monitoring message", ErrFormat)
+	}
+	flags := b[1]
+	var peer Route
+	if flags&peerFlagIPv6 != 0 {
+		peer.Peer = netip.AddrFrom16([16]byte(b[10:26]))
+	} else {
+		peer.Peer = netip.AddrFrom4([4]byte(b[22:26]))
+	}
+	peer.PeerAS = binary.BigEndian.Uint32(b[26:])
+	asSize := 4
+	if flags&peerFlagLegacyAS != 0 {
+		asSize = 2
+	}
+	b = b[bmpPeerHeaderLen:]
+
+	msgLen := int(binary.BigEndian.Uint16(b[16:]))
+	if b[18] != bgpUpdate {
+		return nil, nil
+	}
+	if msgLen < bgpHeaderLen || msgLen > len(b) {
+		return nil, fmt.Errorf("%w: BGP message length %d", ErrFormat, msgLen)
+	}
+	r.events = r.events[:0]
+	return r.update(b[bgpHeaderLen:msgLen], peer, asSize)
+}
+
+// update appends the events of a BGP UPDATE body to r.events.
+func (r *BMPReader) update(b []byte, peer Route, asSize int) ([]Event, error) {
+	withdrawn, b, err := cut16(b)
+	if err != nil {
+		return nil, err
+	}
+	attrs, nlri, err := cut16(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.prefixes(withdrawn, afiIPv4, Event{Withdraw: true, Route: peer}); err != nil {
+		return nil, err
+	}
+
+	// the announced routes share the attributes, MP_REACH_NLRI
+	// prefixes are added once the IPv6 next hop is known
+	ann := Event{Route: peer}
+	var mpReach []byte
+	for len(attrs) > 0 {
+		if len(attrs) < 3 {
+			return nil, fmt.Errorf("%w: short path attribute", ErrFormat)
+		}
+		flags, typ := attrs[0], attrs[1]
+		var n int
+		if flags&attrFlagExtendedLength != 0 {
+			if len(attrs) < 4 {
+				return nil, fmt.Errorf("%w: short path attribute", ErrFormat)
+			}
+			n, attrs = int(binary.BigEndian.Uint16(attrs[2:])), attrs[4:]
+		} else {
+			n, attrs = int(attrs[2]), attrs[3:]
+		}
+		if len(attrs) < n {
+			return nil, fmt.Errorf("%w: short path attribute", ErrFormat)
+		}
+		val := attrs[:n]
+		attrs = attrs[n:]
+
+		switch typ {
+		case attrASPath:
+			if ann.Origin, err = lastAS(val, asSize); err != nil {
+				return nil, err
+			}
+		case attrNextHop:
+			if n == 4 {
+				ann.NextHop = netip.AddrFrom4([4]byte(val))
+			}
+		case attrMPReach:
+			mpReach = val
+		case attrMPUnreach:
+			if len(val) < 3 {
+				return nil, fmt.Errorf("%w: short MP_UNREACH_NLRI", ErrFormat)
+			}
+			if val[2] == safiUnicast {
+				afi := binary.BigEndian.Uint16(val)
+				if err := r.prefixes(val[3:], afi, Event{Withdraw: true, Route: peer}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := r.prefixes(nlri, afiIPv4, ann); err != nil {
+		return nil, err
+	}
+	if mpReach != nil {
+		if len(mpReach) < 4 || len(mpReach) < 5+int(mpReach[3]) {
+			return nil, fmt.Errorf("%w: short MP_REACH_NLRI", ErrFormat)
+		}
+		afi, safi, nhLen := binary.BigEndian.Uint16(mpReach), mpReach[2], int(mpReach[3])
+		if safi == safiUnicast {
+			switch nh := mpReach[4 : 4+nhLen]; {
+			case afi == afiIPv6 && nhLen >= 16:
+				ann.NextHop = netip.AddrFrom16([16]byte(nh[:16]))
+			case afi == afiIPv4 && nhLen == 4:
+				ann.NextHop = netip.AddrFrom4([4]byte(nh))
+			}
+			// next hop, then one reserved octet
+			if err := r.prefixes(mpReach[5+nhLen:], afi, ann); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r.events, nil
+}
+
+// prefixes appends an event for every prefix of the NLRI encoding b,
+// a copy of e with the prefix set. Unknown address families are skipped.
+func (r *BMPReader) prefixes(b []byte, afi uint16, e Event) error {
+	size := 4
+	switch afi {
+	case afiIPv4:
+	case afiIPv6:
+		size = 16
+	default:
+		return nil
+	}
+	for len(b) > 0 {
+		bits := int(b[0])
+		n := (bits + 7) / 8
+		if bits > 8*size || len(b) < 1+n {
+			return fmt.Errorf("%w: bad NLRI prefix", ErrFormat)
+		}
+		var a [16]byte
+		copy(a[:], b[1:1+n])
+		b = b[1+n:]
+
+		addr := netip.AddrFrom16(a)
+		if size == 4 {
+			addr = netip.AddrFrom4([4]byte(a[:4]))
+		}
+		e.Prefix = netip.PrefixFrom(addr, bits).Masked()
+		r.events = append(r.events, e)
+	}
+	return nil
+}
+
+// lastAS returns the last AS number of an AS_PATH with AS numbers of
+// asSize bytes.
+func lastAS(b []byte, asSize int) (asn uint32, err error) {
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return 0, fmt.Errorf("%w: short AS_PATH segment", ErrFormat)
+		}
+		count := int(b[1])
+		b = b[2:]
+		if len(b) < asSize*count {
+			return 0, fmt.Errorf("%w: short AS_PATH segment", ErrFormat)
+		}
+		if count > 0 {
+			last := b[asSize*(count-1):]
+			if asSize == 2 {
+				asn = uint32(binary.BigEndian.Uint16(last))
+			} else {
+				asn = binary.BigEndian.Uint32(last)
+			}
+		}
+		b = b[asSize*count:]
+	}
+	return asn, nil
+}
+
+// cut16 splits a field with a 2-byte length prefix off b.
+func cut16(b []byte) (field, rest []byte, err error) {
+	if len(b) < 2 {
+		return nil, nil, fmt.Errorf("%w: short UPDATE", ErrFormat)
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return nil, nil, fmt.Errorf("%w: short UPDATE", ErrFormat)
+	}
+	return b[2 : 2+n], b[2+n:], nil
+}
@@ -0,0 +1,84 @@
+package zart
+
+import "net/netip"
+
+// BogonSet selects groups of the prefixes that have no business on the
+// public Internet, for InstallBogons. The sets may be or'ed together.
+type BogonSet uint
+
+const (
+	// Private is the private address space of RFC 1918 and the unique
+	// local IPv6 addresses of RFC 4193.
+	Private BogonSet = 1 << iota
+
+	// LinkLocal is 169.254.0.0/16 and fe80::/10.
+	LinkLocal
+
+	// Documentation is the space reserved for examples, RFC 5737 and
+	// RFC 3849 and RFC 9637 for IPv6.
+	Documentation
+
+	// Martians is the remaining special purpose space of RFC 6890 that is
+	// not globally routable: this network, loopback, shared address space
+	// for carrier grade NAT, benchmarking, multicast, the reserved class E
+	// and the deprecated IPv6 site local, 6bone and 6to4 ranges.
+	Martians
+
+	// AllBogons is the union of the sets above.
+	AllBogons = Private | LinkLocal | Documentation | Martians
+)
+
+var bogonPrefixes = [...]struct {
+	set  BogonSet
+	pfxs []string
+}{
+	{Private, []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}},
+	{LinkLocal, []string{"169.254.0.0/16", "fe80::/10"}},
+	{Documentation, []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32", "3fff::/20"}},
+	{Martians, []string{
+		"0.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "192.0.0.0/24", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+		"::/8", "100::/64", "2001:2::/48", "2001:10::/28", "2002::/16", "3ffe::/16", "fec0::/10", "ff00::/8",
+	}},
+}
+
+// Prefixes returns the prefixes of the sets in s, IPv4 before IPv6
+// within each set.
+func (s BogonSet) Prefixes() []netip.Prefix {
+	var pfxs []netip.Prefix
+	for _, b := range bogonPrefixes {
+		if s&b.set == 0 {
+			continue
+		}
+		for _, p := range b.pfxs {
+			pfxs = append(pfxs, netip.MustParsePrefix(p))
+		}
+	}
+	return pfxs
+}
+
+// BogonTag is the tag of the prefixes inserted by InstallBogons.
+const BogonTag Tag = "bogon"
+
+// InstallBogons inserts the bogon prefixes of sets into t with val,
+// for dropping or flagging traffic from and to them, and returns their
+// number. Without sets it inserts AllBogons. Prefixes already in t are
+// overwritten. The bogons are tagged with BogonTag, DeleteByTag removes
+// them again.
+//
+// The sets are the fixed special purpose space. The full bogons, which
+// include the space not yet allocated by the registries, change over time
+// and are fetched by package bogons.
+func InstallBogons[V any](t *Table[V], val V, sets ...BogonSet) int {
+	var s BogonSet
+	for _, set := range sets {
+		s |= set
+	}
+	if len(sets) == 0 {
+		s = AllBogons
+	}
+	pfxs := s.Prefixes()
+	for _, pfx := range pfxs {
+		t.InsertTagged(pfx, val, BogonTag)
+	}
+	return len(pfxs)
+}
@@ -0,0 +1,140 @@
+// Package bogons keeps the full bogons of Team Cymru in a zart table: the
+// special purpose space of zart.InstallBogons plus the space the regional
+// registries have not allocated yet, which shrinks as they hand it out.
+//
+//	feed := &bogons.Feed[bool]{Table: tbl, Value: true}
+//	if _, err := feed.Refresh(ctx); err != nil {
+//		return err
+//	}
+//	go feed.RefreshEvery(ctx, 4*time.Hour)
+package bogons
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gx14ac/zart"
+)
+
+// The full bogon lists, one prefix per line. Team Cymru updates them every
+// four hours.
+const (
+	FullBogonsIPv4 = "https://www.team-cymru.org/Services/Bogons/fullbogons-ipv4.txt"
+	FullBogonsIPv6 = "https://www.team-cymru.org/Services/Bogons/fullbogons-ipv6.txt"
+)
+
+// Tag is the tag of the prefixes inserted by a Feed.
+const Tag zart.Tag = "fullbogon"
+
+// Feed downloads bogon lists into a table.
+type Feed[V any] struct {
+	Table *zart.ConcurrentTable[V]
+
+	// Value is inserted for every bogon.
+	Value V
+
+	// URLs are the lists to fetch, FullBogonsIPv4 and FullBogonsIPv6 if
+	// empty.
+	URLs []string
+
+	// Client fetches the lists, http.DefaultClient if nil.
+	Client *http.Client
+
+	// OnError, if not nil, is called by RefreshEvery with the errors of
+	// the refreshes.
+	OnError func(error)
+}
+
+// Refresh fetches the lists and replaces the bogons in the table with
+// them in a single update and returns their number. The bogons are
+// tagged with Tag; the routes of the table not tagged with it are left
+// alone, unless a bogon overwrites them. If a list fails to download or
+// parse the table is not changed.
+func (f *Feed[V]) Refresh(ctx context.Context) (int, error) {
+	urls := f.URLs
+	if len(urls) == 0 {
+		urls = []string{FullBogonsIPv4, FullBogonsIPv6}
+	}
+	var pfxs []netip.Prefix
+	for _, url := range urls {
+		got, err := f.fetch(ctx, url)
+		if err != nil {
+			return 0, err
+		}
+		pfxs = append(pfxs, got...)
+	}
+
+	f.Table.Update(func(t *zart.Table[V]) {
+		t.DeleteByTag(Tag)
+		for _, pfx := range pfxs {
+			t.InsertTagged(pfx, f.Value, Tag)
+		}
+	})
+	return len(pfxs), nil
+}
+
+// RefreshEvery calls Refresh every interval until ctx is done, in the
+// calling goroutine; run it with go.
+func (f *Feed[V]) RefreshEvery(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			if _, err := f.Refresh(ctx); err != nil && f.OnError != nil {
+				f.OnError(err)
+			}
+		}
+	}
+}
+
+func (f *Feed[V]) fetch(ctx context.Context, url string) ([]netip.Prefix, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bogons: %s: %s", url, resp.Status)
+	}
+	pfxs, err := Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("bogons: %s: %w", url, err)
+	}
+	return pfxs, nil
+}
+
+// Parse reads a bogon list, one prefix per line. Empty lines and lines
+// starting with # are skipped.
+func Parse(r io.Reader) ([]netip.Prefix, error) {
+	var pfxs []netip.Prefix
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		pfx, err := netip.ParsePrefix(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pfxs = append(pfxs, pfx)
+	}
+	return pfxs, sc.Err()
+}
@@ -0,0 +1,67 @@
+package bogons
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestFeed(t *testing.T) {
+	lists := map[string]string{
+		"/v4": "# last updated 1700000000\n0.0.0.0/8\n10.0.0.0/8\n\n41.62.0.0/16\n",
+		"/v6": "# last updated 1700000000\n2001:db8::/32\n2c0f:f000::/20\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, ok := lists[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(list))
+	}))
+	defer srv.Close()
+
+	tbl := zart.NewConcurrent[string]()
+	tbl.Insert(netip.MustParsePrefix("192.0.2.0/24"), "static")
+	feed := &Feed[string]{Table: tbl, Value: "bogon", URLs: []string{srv.URL + "/v4", srv.URL + "/v6"}}
+
+	n, err := feed.Refresh(context.Background())
+	if err != nil || n != 5 {
+		t.Fatalf("Refresh = %d, %v", n, err)
+	}
+	if val, _ := tbl.Lookup(netip.MustParseAddr("41.62.1.1")); val != "bogon" {
+		t.Errorf("Lookup(41.62.1.1) = %q, want bogon", val)
+	}
+
+	// the registry allocates 41.62.0.0/16
+	lists["/v4"] = "0.0.0.0/8\n10.0.0.0/8\n"
+	if n, err := feed.Refresh(context.Background()); err != nil || n != 4 {
+		t.Fatalf("second Refresh = %d, %v", n, err)
+	}
+	if tbl.Contains(netip.MustParseAddr("41.62.1.1")) {
+		t.Errorf("41.62.0.0/16 kept after it left the list")
+	}
+	if val, _ := tbl.Lookup(netip.MustParseAddr("192.0.2.1")); val != "static" {
+		t.Errorf("Lookup(192.0.2.1) = %q, want the untagged static route", val)
+	}
+
+	// a failing list leaves the table alone
+	feed.URLs = append(feed.URLs, srv.URL+"/missing")
+	if _, err := feed.Refresh(context.Background()); err == nil {
+		t.Errorf("Refresh with a missing list succeeded")
+	}
+	if !tbl.Contains(netip.MustParseAddr("10.1.1.1")) {
+		t.Errorf("failed Refresh dropped the bogons")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(strings.NewReader("10.0.0.0/8\nnot a prefix\n")); err == nil {
+		t.Errorf("Parse accepted a malformed line")
+	}
+}
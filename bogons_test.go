@@ -0,0 +1,48 @@
+package zart
+
+import "testing"
+
+func TestInstallBogons(t *testing.T) {
+	tbl := New[int]()
+	tbl.Insert(mpp("8.8.8.0/24"), 1)
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+
+	n := InstallBogons(tbl, -1)
+	if n != len(AllBogons.Prefixes()) || tbl.Size() != n+1 {
+		t.Fatalf("InstallBogons = %d, size %d", n, tbl.Size())
+	}
+	for _, tc := range []struct {
+		addr  string
+		bogon bool
+	}{
+		{"10.1.2.3", true},
+		{"127.0.0.1", true},
+		{"100.64.1.1", true},
+		{"192.0.2.1", true},
+		{"255.255.255.255", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"2001:db8::1", true},
+		{"::1", true},
+		{"2606:4700::1111", false},
+	} {
+		val, ok := tbl.Lookup(mpa(tc.addr))
+		if got := ok && val == -1; got != tc.bogon {
+			t.Errorf("%s bogon = %v, want %v", tc.addr, got, tc.bogon)
+		}
+	}
+
+	if n := tbl.DeleteByTag(BogonTag); n != len(AllBogons.Prefixes()) || tbl.Size() != 1 {
+		t.Errorf("DeleteByTag = %d, size %d", n, tbl.Size())
+	}
+
+	tbl = New[int]()
+	if n := InstallBogons(tbl, 1, Private, LinkLocal); n != 6 {
+		t.Errorf("InstallBogons(Private, LinkLocal) = %d, want 6", n)
+	}
+	if tbl.Contains(mpa("127.0.0.1")) || !tbl.Contains(mpa("169.254.1.1")) {
+		t.Errorf("InstallBogons(Private, LinkLocal) installed the wrong sets")
+	}
+}
@@ -5,13 +5,16 @@ pub fn build(b: *std.Build) void {
     const target = b.standardTargetOptions(.{});
     const optimize = b.standardOptimizeOption(.{});
 
+    // libbart.a - C ABI (include/bart.h) consumed by the Go package via cgo
     const lib = b.addStaticLibrary(.{
         .name = "bart",
-        .root_source_file = b.path("src/main.zig"),
+        .root_source_file = b.path("src/c_api.zig"),
         .target = target,
         .optimize = optimize,
         .link_libc = true, 
     });
+    lib.bundle_compiler_rt = true;
+    lib.installHeader(b.path("include/bart.h"), "bart.h");
     b.installArtifact(lib);
 
     // Main executable
@@ -123,6 +126,17 @@ pub fn build(b: *std.Build) void {
     const test_unit_step = b.step("test", "Run unit tests");
     test_unit_step.dependOn(&run_lib_unit_tests.step);
 
+    // C ABI tests
+    const c_api_tests = b.addTest(.{
+        .root_source_file = b.path("src/c_api.zig"),
+        .target = target,
+        .optimize = optimize,
+        .link_libc = true,
+    });
+
+    const run_c_api_tests = b.addRunArtifact(c_api_tests);
+    test_unit_step.dependOn(&run_c_api_tests.step);
+
     // Bitset tests
     const bitset_tests = b.addTest(.{
         .root_source_file = b.path("src/bitset256.zig"),
@@ -165,6 +179,16 @@ pub fn build(b: *std.Build) void {
     const run_nodepool_tests = b.addRunArtifact(nodepool_tests);
     test_unit_step.dependOn(&run_nodepool_tests.step);
 
+    // Arena tests
+    const arena_tests = b.addTest(.{
+        .root_source_file = b.path("src/arena.zig"),
+        .target = target,
+        .optimize = optimize,
+    });
+
+    const run_arena_tests = b.addRunArtifact(arena_tests);
+    test_unit_step.dependOn(&run_arena_tests.step);
+
     // NodePool usage test
     const nodepool_usage_test = b.addExecutable(.{
         .name = "nodepool_test",
@@ -0,0 +1,141 @@
+package zart
+
+import "errors"
+
+// VersionID identifies a checkpoint of a table taken by Checkpoint.
+type VersionID uint64
+
+// ErrNoCheckpoint is returned by Rollback for a VersionID that is not a
+// checkpoint of the table or was released.
+var ErrNoCheckpoint = errors.New("zart: no such checkpoint")
+
+// checkpoint is the state of a table kept by Checkpoint: a persistent
+// version of its trie, the payload registry its slots point into and a
+// copy of its tags. The registry is shared with the table until Compact,
+// Freeze or Union give the table one of its own.
+type checkpoint[V any] struct {
+	trie *trie
+	vals *registry[V]
+	tags *tagSet
+}
+
+// checkpoints are the checkpoints of a table by id.
+type checkpoints[V any] struct {
+	last VersionID
+	byID map[VersionID]checkpoint[V]
+}
+
+// Checkpoint records the current contents of the table and returns the
+// id to restore them with Rollback. Like InsertPersist it costs no copy:
+// the checkpoint shares the trie nodes and payloads of the table, and
+// the nodes on the paths of later changes are copied on write.
+//
+// While a table has checkpoints the slots of overwritten and deleted
+// payloads are not reused, they are released with the table; release
+// checkpoints no longer needed with ReleaseCheckpoint.
+func (t *Table[V]) Checkpoint() VersionID {
+	if t.cps == nil {
+		t.cps = &checkpoints[V]{byID: map[VersionID]checkpoint[V]{}}
+	}
+	t.cps.last++
+	t.vals.shared++
+	t.cps.byID[t.cps.last] = checkpoint[V]{trie: t.trie.persist(), vals: t.vals, tags: t.tags.clone()}
+	return t.cps.last
+}
+
+// Rollback restores the contents and tags of the table recorded by
+// Checkpoint id. The checkpoint is kept, the table can be rolled back to
+// it again. Watchers see the changes undone as events, found by a walk
+// of both tries that skips the subtrees they still share; TTLs of the
+// changed prefixes are dropped.
+func (t *Table[V]) Rollback(id VersionID) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	cp, ok := t.cps.get(id)
+	if !ok {
+		return ErrNoCheckpoint
+	}
+	if t.watch != nil || t.ttl != nil {
+		v := &Table[V]{trie: cp.trie, vals: cp.vals}
+		for _, d := range t.diffItems(v) {
+			ev, _ := t.diffEvent(v, &d, func(V, V) bool { return false })
+			if t.watch != nil {
+				t.watch.notify(ev)
+			}
+			if t.ttl != nil {
+				t.ttl.forget(ev.Prefix)
+			}
+		}
+	}
+	old := t.trie
+	t.trie = cp.trie.persist()
+	old.close()
+	cp.vals.shared++
+	t.vals.reset()
+	t.vals = cp.vals
+	t.tags = cp.tags.clone()
+	t.changes++
+	return nil
+}
+
+// ReleaseCheckpoint drops checkpoint id, it reports whether the table
+// had it.
+func (t *Table[V]) ReleaseCheckpoint(id VersionID) bool {
+	cp, ok := t.cps.get(id)
+	if !ok {
+		return false
+	}
+	delete(t.cps.byID, id)
+	cp.trie.close()
+	cp.vals.reset()
+	return true
+}
+
+// Checkpoints returns the number of checkpoints the table keeps.
+func (t *Table[V]) Checkpoints() int {
+	if t.cps == nil {
+		return 0
+	}
+	return len(t.cps.byID)
+}
+
+func (c *checkpoints[V]) get(id VersionID) (checkpoint[V], bool) {
+	if c == nil {
+		return checkpoint[V]{}, false
+	}
+	cp, ok := c.byID[id]
+	return cp, ok
+}
+
+// releaseCheckpoints drops all checkpoints of the table.
+func (t *Table[V]) releaseCheckpoints() {
+	if t.cps == nil {
+		return
+	}
+	for id := range t.cps.byID {
+		t.ReleaseCheckpoint(id)
+	}
+}
+
+// Checkpoint is like Table.Checkpoint.
+func (c *ConcurrentTable[V]) Checkpoint() VersionID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Checkpoint()
+}
+
+// Rollback is like Table.Rollback, readers see the table either before
+// or after it.
+func (c *ConcurrentTable[V]) Rollback(id VersionID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Rollback(id)
+}
+
+// ReleaseCheckpoint is like Table.ReleaseCheckpoint.
+func (c *ConcurrentTable[V]) ReleaseCheckpoint(id VersionID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.ReleaseCheckpoint(id)
+}
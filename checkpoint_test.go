@@ -0,0 +1,142 @@
+package zart
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckpointRollback(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("2001:db8::/32"), 2)
+	tbl.SetTags(mpp("10.0.0.0/8"), "core")
+
+	id := tbl.Checkpoint()
+	tbl.Insert(mpp("10.0.0.0/8"), 3)
+	tbl.Insert(mpp("192.168.0.0/16"), 4)
+	tbl.Delete(mpp("2001:db8::/32"))
+	tbl.SetTags(mpp("10.0.0.0/8"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := tbl.Watch(ctx)
+	if err := tbl.Rollback(id); err != nil {
+		t.Fatal(err)
+	}
+	if tbl.Size() != 2 {
+		t.Errorf("size after Rollback = %d, want 2", tbl.Size())
+	}
+	if v, _ := tbl.Get(mpp("10.0.0.0/8")); v != 1 {
+		t.Errorf("10.0.0.0/8 = %d after Rollback, want 1", v)
+	}
+	if _, ok := tbl.Get(mpp("2001:db8::/32")); !ok {
+		t.Error("deleted prefix not restored")
+	}
+	if tags := tbl.Tags(mpp("10.0.0.0/8")); len(tags) != 1 || tags[0] != "core" {
+		t.Error("tag not restored")
+	}
+	if n := len(events); n != 3 {
+		t.Errorf("%d events for the rollback, want 3", n)
+	}
+
+	// the checkpoint survives the rollback
+	tbl.Insert(mpp("172.16.0.0/12"), 5)
+	if err := tbl.Rollback(id); err != nil || tbl.Size() != 2 {
+		t.Errorf("second Rollback = %v, size %d", err, tbl.Size())
+	}
+	if !tbl.ReleaseCheckpoint(id) || tbl.Checkpoints() != 0 {
+		t.Error("ReleaseCheckpoint failed")
+	}
+	if err := tbl.Rollback(id); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("Rollback of a released checkpoint = %v", err)
+	}
+	tbl.Insert(mpp("172.16.0.0/12"), 5)
+	if tbl.Size() != 3 {
+		t.Errorf("size after release = %d, want 3", tbl.Size())
+	}
+}
+
+func TestCheckpointRandom(t *testing.T) {
+	prng := rand.New(rand.NewPCG(100, 100))
+	tbl := New[int]()
+	defer tbl.Close()
+	pfxs := randomPrefixes(prng, 2000)
+	for i, pfx := range pfxs[:1000] {
+		tbl.Insert(pfx, i)
+	}
+	want := tbl.Clone()
+	defer want.Close()
+
+	id := tbl.Checkpoint()
+	for i, pfx := range pfxs {
+		if i%3 == 0 {
+			tbl.Delete(pfx)
+		} else {
+			tbl.Insert(pfx, -i)
+		}
+	}
+	later := tbl.Checkpoint()
+	if err := tbl.Rollback(id); err != nil {
+		t.Fatal(err)
+	}
+	eq := func(a, b int) bool { return a == b }
+	if !tbl.Equal(want, eq) {
+		t.Fatalf("table after Rollback differs in %d prefixes", len(tbl.Diff(want, eq)))
+	}
+	if err := tbl.Rollback(later); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := tbl.Get(pfxs[1]); !ok || v != -1 {
+		t.Errorf("Rollback to the later checkpoint: %v = %d, %v", pfxs[1], v, ok)
+	}
+	if tbl.Checkpoints() != 2 {
+		t.Errorf("%d checkpoints, want 2", tbl.Checkpoints())
+	}
+}
+
+func TestCheckpointRebuilt(t *testing.T) {
+	for name, change := range map[string]func(tbl *Table[int]){
+		"Compact": func(tbl *Table[int]) { tbl.Compact() },
+		"Freeze":  func(tbl *Table[int]) { tbl.Freeze() },
+		"Union": func(tbl *Table[int]) {
+			o := New[int]()
+			defer o.Close()
+			o.Insert(mpp("10.0.0.0/8"), 7)
+			tbl.Union(o, func(_ netip.Prefix, a, b int) int { return a + b })
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tbl := New[int]()
+			defer tbl.Close()
+			tbl.Insert(mpp("192.0.2.0/24"), 1)
+			tbl.Delete(mpp("192.0.2.0/24")) // a free slot, gone with a rebuild
+			tbl.Insert(mpp("10.0.0.0/8"), 2)
+			tbl.Insert(mpp("2001:db8::/32"), 3)
+
+			id := tbl.Checkpoint()
+			tbl.Insert(mpp("2001:db8::/32"), 4)
+			change(tbl)
+			if !tbl.Frozen() {
+				if err := tbl.Rollback(id); err != nil {
+					t.Fatal(err)
+				}
+				if v, _ := tbl.Get(mpp("10.0.0.0/8")); v != 2 {
+					t.Errorf("10.0.0.0/8 = %d after Rollback, want 2", v)
+				}
+				if v, _ := tbl.Get(mpp("2001:db8::/32")); v != 3 {
+					t.Errorf("2001:db8::/32 = %d after Rollback, want 3", v)
+				}
+			}
+			want := maps.Collect(tbl.All())
+			tbl.ReleaseCheckpoint(id)
+			if got := maps.Collect(tbl.All()); !maps.Equal(got, want) {
+				t.Errorf("ReleaseCheckpoint changed the contents to %v, want %v", got, want)
+			}
+		})
+	}
+}
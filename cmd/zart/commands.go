@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gx14ac/zart/pcap"
+)
+
+// load reads route files and reports what they contain, with -o it
+// writes them to a single file, by default a snapshot that loads faster
+// than the sources.
+func load(args []string, stdout io.Writer) error {
+	fs := newFlagSet("load")
+	out := fs.String("o", "", "write the loaded routes to `file` (.csv, .json or snapshot)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t, err := readFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	n4, n6 := t.Size4(), t.Size6()
+	fmt.Fprintf(stdout, "loaded %d routes (%d IPv4, %d IPv6)\n", n4+n6, n4, n6)
+	if *out == "" {
+		return nil
+	}
+	return saveFile(t, *out)
+}
+
+// lookup prints the longest matching route of each address, or
+// "not found".
+func lookup(args []string, stdout io.Writer) error {
+	fs := newFlagSet("lookup")
+	files := routeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	addrs := make([]netip.Addr, fs.NArg())
+	for i, arg := range fs.Args() {
+		addr, err := netip.ParseAddr(arg)
+		if err != nil {
+			return err
+		}
+		addrs[i] = addr
+	}
+	t, err := readFiles(files())
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	for _, addr := range addrs {
+		switch pfx, val, ok := t.LookupPrefix(addr); {
+		case ok && val != "":
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", addr, pfx, val)
+		case ok:
+			fmt.Fprintf(tw, "%s\t%s\n", addr, pfx)
+		default:
+			fmt.Fprintf(tw, "%s\tnot found\n", addr)
+		}
+	}
+	return tw.Flush()
+}
+
+// dump prints all routes.
+func dump(args []string, stdout io.Writer) error {
+	fs := newFlagSet("dump")
+	files := routeFlags(fs)
+	format := fs.String("format", "text", "output `format`: text, csv or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t, err := readFiles(files())
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	switch *format {
+	case "text":
+		bw := bufio.NewWriter(stdout)
+		for pfx, val := range t.All() {
+			printRoute(bw, pfx, val)
+		}
+		return bw.Flush()
+	case "csv":
+		return t.ExportCSV(stdout)
+	case "json":
+		return t.ExportJSON(stdout)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
+
+// stats prints the number of routes per family and prefix length, and
+// the size of the trie.
+func stats(args []string, stdout io.Writer) error {
+	fs := newFlagSet("stats")
+	files := routeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t, err := readFiles(files())
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	var bits4 [33]int
+	var bits6 [129]int
+	for pfx := range t.All() {
+		if pfx.Addr().Is4() {
+			bits4[pfx.Bits()]++
+		} else {
+			bits6[pfx.Bits()]++
+		}
+	}
+	n4, n6 := sum(bits4[:]), sum(bits6[:])
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "routes\t%d\t\n", n4+n6)
+	fmt.Fprintf(tw, "IPv4\t%d\t\n", n4)
+	fmt.Fprintf(tw, "IPv6\t%d\t\n", n6)
+	for bits, n := range bits4 {
+		if n > 0 {
+			fmt.Fprintf(tw, "IPv4 /%d\t%d\t\n", bits, n)
+		}
+	}
+	for bits, n := range bits6 {
+		if n > 0 {
+			fmt.Fprintf(tw, "IPv6 /%d\t%d\t\n", bits, n)
+		}
+	}
+	st := t.Stats()
+	fmt.Fprintf(tw, "nodes\t%d\t\n", st.Nodes())
+	fmt.Fprintf(tw, "trie bytes\t%d\t\n", st.Bytes())
+	return tw.Flush()
+}
+
+func sum(counts []int) (n int) {
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+// classify prints the traffic of a pcap capture by route, the busiest
+// routes first.
+func classify(args []string, stdout io.Writer) error {
+	fs := newFlagSet("pcap")
+	files := routeFlags(fs)
+	top := fs.Int("n", 20, "print the busiest `count` routes, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	t, err := readFiles(files())
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rep, err := pcap.Classify(t, f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+
+	sorted := rep.Sorted()
+	if *top > 0 && len(sorted) > *top {
+		sorted = sorted[:*top]
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "prefix\tvalue\tsrc packets\tsrc bytes\tdst packets\tdst bytes\n")
+	for _, s := range sorted {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", s.Prefix, s.Value, s.Src.Packets, s.Src.Bytes, s.Dst.Packets, s.Dst.Bytes)
+	}
+	u := rep.Unrouted
+	fmt.Fprintf(tw, "unrouted\t\t%d\t%d\t%d\t%d\n", u.Src.Packets, u.Src.Bytes, u.Dst.Packets, u.Dst.Bytes)
+	return tw.Flush()
+}
@@ -0,0 +1,119 @@
+// Command zart loads route files into a zart table and queries it.
+//
+// Usage:
+//
+//	zart load [-o file] file...
+//	zart lookup [-r file]... addr...
+//	zart dump [-r file]... [-format text|csv|json]
+//	zart stats [-r file]...
+//	zart shell [-r file]...
+//	zart pcap [-r file]... [-n count] capture
+//
+// Route files are read by extension: .csv and .json in the formats of
+// Table.ExportCSV and Table.ExportJSON, .mrt for MRT RIB dumps (.gz and
+// .bz2 compressed files are unpacked), .zart for snapshots written by
+// "zart load -o" or the save command of the shell. Any other file is
+// read as text, one prefix per line optionally followed by a value;
+// lines starting with # are comments. Values are kept as strings, the
+// origin AS of MRT routes as "AS64496".
+//
+// shell starts an interactive session to insert, delete and look up
+// routes; type help for its commands.
+//
+// pcap classifies the packets of a pcap capture by the routes matching
+// their source and destination addresses and prints the traffic of the
+// busiest routes.
+//
+// lookup, dump, stats, shell and pcap also read the files named by the
+// ZART_ROUTES environment variable, a list separated by the OS path list
+// separator, if no -r flag is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `usage:
+	zart load [-o file] file...
+	zart lookup [-r file]... addr...
+	zart dump [-r file]... [-format text|csv|json]
+	zart stats [-r file]...
+	zart shell [-r file]...
+	zart pcap [-r file]... [-n count] capture
+`
+
+// commands maps subcommand names to their implementations. Each gets its
+// arguments without the subcommand name.
+var commands = map[string]func(args []string, stdout io.Writer) error{
+	"load":   load,
+	"lookup": lookup,
+	"dump":   dump,
+	"stats":  stats,
+	"shell":  shell,
+	"pcap":   classify,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "zart: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	if err := cmd(args[1:], stdout); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "zart %s: %v\n", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// routeFlags adds the -r flag to fs, the returned function yields the
+// route files to read, falling back to $ZART_ROUTES.
+func routeFlags(fs *flag.FlagSet) func() []string {
+	var files []string
+	fs.Func("r", "read routes from `file` (repeatable)", func(s string) error {
+		files = append(files, s)
+		return nil
+	})
+	return func() []string {
+		if len(files) == 0 {
+			if env := os.Getenv("ZART_ROUTES"); env != "" {
+				return filepath.SplitList(env)
+			}
+		}
+		return files
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("zart "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s\n", synopsis(name))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// synopsis returns the usage line of command name.
+func synopsis(name string) string {
+	for _, line := range strings.Split(usage, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "zart "+name+" ") {
+			return line
+		}
+	}
+	return "zart " + name
+}
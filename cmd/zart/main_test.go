@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runZart(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	if code != 0 {
+		return stderr.String(), code
+	}
+	return stdout.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	t.Setenv("ZART_ROUTES", "")
+	text := writeFile(t, "routes.txt", `# static routes
+10.0.0.0/8	via 192.0.2.1
+10.1.0.0/16 via 192.0.2.2
+2001:db8::/32
+`)
+	csvFile := writeFile(t, "override.csv", "10.1.0.0/16,via 192.0.2.3\n")
+	snap := filepath.Join(t.TempDir(), "all.zart")
+
+	if out, code := runZart(t, "load", "-o", snap, text, csvFile); code != 0 || out != "loaded 3 routes (2 IPv4, 1 IPv6)\n" {
+		t.Fatalf("load = %q, %d", out, code)
+	}
+
+	out, code := runZart(t, "lookup", "-r", snap, "10.1.2.3", "2001:db8::1", "192.168.1.1")
+	want := "10.1.2.3     10.1.0.0/16  via 192.0.2.3\n" +
+		"2001:db8::1  2001:db8::/32\n" +
+		"192.168.1.1  not found\n"
+	if code != 0 || out != want {
+		t.Errorf("lookup = %d\n%s\nwant\n%s", code, out, want)
+	}
+
+	t.Setenv("ZART_ROUTES", text)
+	out, code = runZart(t, "dump")
+	want = "10.0.0.0/8 via 192.0.2.1\n10.1.0.0/16 via 192.0.2.2\n2001:db8::/32\n"
+	if code != 0 || out != want {
+		t.Errorf("dump = %d\n%s\nwant\n%s", code, out, want)
+	}
+	if out, code = runZart(t, "dump", "-format", "json"); code != 0 || !strings.HasPrefix(out, `[{"prefix":"10.0.0.0/8"`) {
+		t.Errorf("dump -format json = %d\n%s", code, out)
+	}
+
+	out, code = runZart(t, "stats")
+	if code != 0 {
+		t.Fatalf("stats = %d %s", code, out)
+	}
+	stats := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Fields(line)
+		stats[strings.Join(f[:len(f)-1], " ")] = f[len(f)-1]
+	}
+	for label, want := range map[string]string{"routes": "3", "IPv4": "2", "IPv4 /16": "1", "IPv6 /32": "1"} {
+		if stats[label] != want {
+			t.Errorf("stats %s = %q, want %q:\n%s", label, stats[label], want, out)
+		}
+	}
+	if stats["nodes"] == "" || stats["trie bytes"] == "" {
+		t.Errorf("stats lacks the trie size:\n%s", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Setenv("ZART_ROUTES", "")
+	bad := writeFile(t, "bad.txt", "10.0.0.0/8\n10.0.0.0/33\n")
+	for _, tc := range []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{nil, 2, "usage:"},
+		{[]string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+		{[]string{"stats"}, 1, "no route files"},
+		{[]string{"load", bad}, 1, "bad.txt: line 2:"},
+		{[]string{"lookup", "-r", bad, "nope"}, 1, "nope"},
+	} {
+		if out, code := runZart(t, tc.args...); code != tc.code || !strings.Contains(out, tc.msg) {
+			t.Errorf("zart %v = %d %q, want %d and %q", tc.args, code, out, tc.code, tc.msg)
+		}
+	}
+}
+
+func TestPcap(t *testing.T) {
+	t.Setenv("ZART_ROUTES", "")
+	routes := writeFile(t, "routes.txt", "10.0.0.0/8 internal\n192.0.2.0/24 transit\n")
+
+	// a raw IP capture of two packets from 10.0.0.1 to 192.0.2.1 and
+	// 198.51.100.1
+	capture := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0, 0, 101, 0, 0, 0}
+	for _, dst := range [][4]byte{{192, 0, 2, 1}, {198, 51, 100, 1}} {
+		capture = append(capture, 0, 0, 0, 0, 0, 0, 0, 0, 20, 0, 0, 0, 100, 0, 0, 0)
+		capture = append(capture, 0x45, 0, 0, 100, 0, 0, 0, 0, 64, 17, 0, 0, 10, 0, 0, 1)
+		capture = append(capture, dst[:]...)
+	}
+	file := writeFile(t, "traffic.pcap", string(capture))
+
+	out, code := runZart(t, "pcap", "-r", routes, file)
+	want := "prefix        value     src packets  src bytes  dst packets  dst bytes\n" +
+		"10.0.0.0/8    internal  2            200        0            0\n" +
+		"192.0.2.0/24  transit   0            0          1            100\n" +
+		"unrouted                0            0          1            100\n"
+	if code != 0 || out != want {
+		t.Errorf("pcap = %d\n%s\nwant\n%s", code, out, want)
+	}
+}
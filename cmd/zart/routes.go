@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/gx14ac/zart"
+	"github.com/gx14ac/zart/mrt"
+)
+
+// batchSize is the number of routes read before they are inserted.
+const batchSize = 4096
+
+// readFiles returns a table with the routes of all files, later files
+// override the values of earlier ones.
+func readFiles(files []string) (*zart.Table[string], error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no route files, use -r or set ZART_ROUTES")
+	}
+	t := zart.New[string]()
+	for _, name := range files {
+		if err := readFile(t, name); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// readFile inserts the routes of the named file into t, picking the
+// format by extension.
+func readFile(t *zart.Table[string], name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	ext := filepath.Ext(name)
+	switch ext {
+	case ".gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		r, ext = zr, filepath.Ext(strings.TrimSuffix(name, ext))
+	case ".bz2":
+		r, ext = bzip2.NewReader(f), filepath.Ext(strings.TrimSuffix(name, ext))
+	}
+
+	switch ext {
+	case ".csv":
+		err = t.ImportCSV(r)
+	case ".json":
+		err = t.ImportJSON(r)
+	case ".mrt":
+		err = readMRT(t, r)
+	case ".zart":
+		err = readSnapshot(t, r)
+	default:
+		err = readText(t, r)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func readMRT(t *zart.Table[string], r io.Reader) error {
+	mr := mrt.NewReader(r)
+	var batch []zart.RouteEntry[string]
+	for {
+		rt, err := mr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, zart.RouteEntry[string]{Prefix: rt.Prefix, Value: fmt.Sprintf("AS%d", rt.Origin)})
+		if len(batch) == batchSize {
+			t.InsertBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	t.InsertBatch(batch)
+	return nil
+}
+
+// readSnapshot inserts the routes of a MarshalBinary snapshot.
+func readSnapshot(t *zart.Table[string], r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var snap zart.Table[string]
+	if err := snap.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	defer snap.Close()
+	var batch []zart.RouteEntry[string]
+	for pfx, val := range snap.All() {
+		batch = append(batch, zart.RouteEntry[string]{Prefix: pfx, Value: val})
+	}
+	t.InsertBatch(batch)
+	return nil
+}
+
+// readText reads lines of a prefix and an optional value, separated by
+// white space.
+func readText(t *zart.Table[string], r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	var batch []zart.RouteEntry[string]
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		field, val := text, ""
+		if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+			field, val = text[:i], strings.TrimSpace(text[i:])
+		}
+		pfx, err := netip.ParsePrefix(field)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		batch = append(batch, zart.RouteEntry[string]{Prefix: pfx, Value: val})
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	t.InsertBatch(batch)
+	return nil
+}
+
+// saveFile writes the routes of t to the named file, as CSV or JSON by
+// extension and as a snapshot otherwise.
+func saveFile(t *zart.Table[string], name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	switch filepath.Ext(name) {
+	case ".csv":
+		err = t.ExportCSV(f)
+	case ".json":
+		err = t.ExportJSON(f)
+	default:
+		var data []byte
+		if data, err = t.MarshalBinary(); err == nil {
+			_, err = f.Write(data)
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/gx14ac/zart"
+)
+
+const shellHelp = `commands:
+	insert prefix [value]   add or replace a route
+	delete prefix           remove a route
+	lookup addr             longest prefix match
+	show [prefix]           print all routes, or those within prefix
+	load file               read routes from a file
+	save file               write the routes as .csv, .json or snapshot
+	stats                   route counts
+	help                    this text
+	quit                    leave the shell
+`
+
+// shellCommands are the names completed in the first word of a line.
+var shellCommands = []string{"delete", "help", "insert", "load", "lookup", "quit", "save", "show", "stats"}
+
+// shell runs an interactive session on a table loaded from the -r files,
+// or an empty one. On a terminal lines can be edited, the arrow keys walk
+// the history of the session and tab completes commands, prefixes of the
+// table and file names. Otherwise commands are read line by line, so a
+// script can be piped in.
+func shell(args []string, stdout io.Writer) error {
+	fs := newFlagSet("shell")
+	files := routeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t := zart.New[string]()
+	defer t.Close()
+	for _, name := range files() {
+		if err := readFile(t, name); err != nil {
+			return err
+		}
+	}
+	s := &session{t: t}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		s.out = stdout
+		sc := bufio.NewScanner(os.Stdin)
+		return s.run(func() (string, error) {
+			if sc.Scan() {
+				return sc.Text(), nil
+			}
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		})
+	}
+
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, old)
+	tty := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, stdout}, "zart> ")
+	tty.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return s.complete(line, pos)
+	}
+	s.out = tty
+	return s.run(tty.ReadLine)
+}
+
+// session is the state of a shell.
+type session struct {
+	t   *zart.Table[string]
+	out io.Writer
+}
+
+// run executes the lines returned by readLine until it fails or quit is
+// entered. Errors of single commands are printed, they don't end the
+// session.
+func (s *session) run(readLine func() (string, error)) error {
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		quit, err := s.exec(line)
+		if err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// exec runs one command line.
+func (s *session) exec(line string) (quit bool, err error) {
+	words := strings.Fields(line)
+	if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+		return false, nil
+	}
+	cmd, args := words[0], words[1:]
+	arg := func(what string) (string, error) {
+		if len(args) == 0 {
+			return "", fmt.Errorf("%s: missing %s", cmd, what)
+		}
+		return args[0], nil
+	}
+
+	switch cmd {
+	case "insert":
+		a, err := arg("prefix")
+		if err != nil {
+			return false, err
+		}
+		pfx, err := netip.ParsePrefix(a)
+		if err != nil {
+			return false, err
+		}
+		s.t.Insert(pfx, strings.Join(args[1:], " "))
+	case "delete":
+		a, err := arg("prefix")
+		if err != nil {
+			return false, err
+		}
+		pfx, err := netip.ParsePrefix(a)
+		if err != nil {
+			return false, err
+		}
+		if !s.t.Delete(pfx.Masked()) {
+			return false, fmt.Errorf("%s not in table", pfx.Masked())
+		}
+	case "lookup":
+		a, err := arg("address")
+		if err != nil {
+			return false, err
+		}
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return false, err
+		}
+		if pfx, val, ok := s.t.LookupPrefix(addr); ok {
+			printRoute(s.out, pfx, val)
+		} else {
+			fmt.Fprintln(s.out, "not found")
+		}
+	case "show":
+		routes := s.t.All()
+		if len(args) > 0 {
+			pfx, err := netip.ParsePrefix(args[0])
+			if err != nil {
+				return false, err
+			}
+			routes = s.t.Subnets(pfx)
+		}
+		for pfx, val := range routes {
+			printRoute(s.out, pfx, val)
+		}
+	case "load":
+		a, err := arg("file")
+		if err != nil {
+			return false, err
+		}
+		return false, readFile(s.t, a)
+	case "save":
+		a, err := arg("file")
+		if err != nil {
+			return false, err
+		}
+		return false, saveFile(s.t, a)
+	case "stats":
+		n4, n6 := s.t.Size4(), s.t.Size6()
+		fmt.Fprintf(s.out, "%d routes (%d IPv4, %d IPv6)\n", n4+n6, n4, n6)
+	case "help", "?":
+		fmt.Fprint(s.out, shellHelp)
+	case "quit", "exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", cmd)
+	}
+	return false, nil
+}
+
+// complete extends the word before the cursor to the longest common
+// prefix of its candidates: the commands for the first word, file names
+// after load and save, and the prefixes of the table otherwise.
+func (s *session) complete(line string, pos int) (string, int, bool) {
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	word := line[start:pos]
+
+	var candidates []string
+	switch cmd, _, _ := strings.Cut(strings.TrimSpace(line[:start]), " "); cmd {
+	case "":
+		for _, c := range shellCommands {
+			if strings.HasPrefix(c, word) {
+				candidates = append(candidates, c+" ")
+			}
+		}
+	case "load", "save":
+		matches, _ := filepath.Glob(word + "*")
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+				m += string(filepath.Separator)
+			}
+			candidates = append(candidates, m)
+		}
+	case "insert", "delete", "show":
+		for pfx := range s.t.All() {
+			if p := pfx.String(); strings.HasPrefix(p, word) {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	common := slices.Min(candidates)
+	for _, c := range candidates {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) <= len(word) {
+		return "", 0, false
+	}
+	return line[:start] + common + line[pos:], start + len(common), true
+}
+
+func printRoute(w io.Writer, pfx netip.Prefix, val string) {
+	if val == "" {
+		fmt.Fprintln(w, pfx)
+	} else {
+		fmt.Fprintln(w, pfx, val)
+	}
+}
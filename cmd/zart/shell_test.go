@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func script(lines ...string) func() (string, error) {
+	return func() (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	}
+}
+
+func TestSession(t *testing.T) {
+	tbl := zart.New[string]()
+	defer tbl.Close()
+	var out bytes.Buffer
+	s := &session{t: tbl, out: &out}
+	saved := filepath.Join(t.TempDir(), "saved.csv")
+
+	err := s.run(script(
+		"insert 10.0.0.0/8 via 192.0.2.1",
+		"insert 10.1.0.0/16",
+		"# comment",
+		"lookup 10.1.2.3",
+		"show 10.0.0.0/8",
+		"delete 10.1.0.0/16",
+		"delete 10.1.0.0/16",
+		"lookup 192.168.0.1",
+		"save "+saved,
+		"delete 10.0.0.0/8",
+		"load "+saved,
+		"stats",
+		"frob",
+		"quit",
+		"stats",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `10.1.0.0/16
+10.0.0.0/8 via 192.0.2.1
+10.1.0.0/16
+error: 10.1.0.0/16 not in table
+not found
+1 routes (1 IPv4, 0 IPv6)
+error: unknown command "frob", try help
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tbl := zart.New[string]()
+	defer tbl.Close()
+	for _, p := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"} {
+		tbl.Insert(netip.MustParsePrefix(p), "")
+	}
+	s := &session{t: tbl}
+
+	for _, tc := range []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{"ins", "insert ", true},
+		{"lo", "lo", false}, // load and lookup
+		{"delete 10.1", "delete 10.1.", true},
+		{"delete 10.1.2", "delete 10.1.2.0/24", true},
+		{"show 192", "show 192", false},
+		{"lookup 10.", "lookup 10.", false},
+	} {
+		line, pos, ok := s.complete(tc.line, len(tc.line))
+		if !ok {
+			line, pos = tc.line, len(tc.line)
+		}
+		if ok != tc.ok || line != tc.want || pos != len(tc.want) {
+			t.Errorf("complete(%q) = %q, %d, %v, want %q", tc.line, line, pos, ok, tc.want)
+		}
+	}
+
+	// completion in the middle of a line keeps the rest
+	line, pos, ok := s.complete("sh 10.0.0.0/8", 2)
+	if !ok || line != "show  10.0.0.0/8" || pos != 5 {
+		t.Errorf("complete in mid-line = %q, %d, %v", line, pos, ok)
+	}
+}
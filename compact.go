@@ -0,0 +1,51 @@
+package zart
+
+// Compact rebuilds the table in fresh memory, for long-running processes
+// after mass deletions. Neither the trie nor the payload registry shrinks
+// when prefixes are deleted: emptied node arrays keep their capacity and
+// the slots of deleted payloads are only recycled by later inserts.
+// Compact copies the remaining routes into a new trie of exactly their
+// size with dense slots and frees the old memory, or with persistent
+// versions leaves it to them. The contents, tags, TTLs and hit counts are
+// unchanged and watchers see no events. It takes time linear in the size
+// of the table. A frozen table was compacted by Freeze and is left alone.
+func (t *Table[V]) Compact() {
+	if t.closed || t.Frozen() {
+		return
+	}
+	t.rebuild(t.opts)
+}
+
+// rebuild copies the routes into a new trie made with o, see Compact.
+func (t *Table[V]) rebuild(o options) {
+	routes := make([]route, t.Size())
+	routes = routes[:t.trie.dump(routes)]
+
+	vals := &registry[V]{vals: make([]V, len(routes))}
+	if t.vals.hits != nil {
+		vals.hits = make([]uint64, len(routes))
+	}
+	for i := range routes {
+		r := &routes[i]
+		vals.vals[i] = t.vals.get(r.val)
+		if vals.hits != nil {
+			vals.hits[i] = t.vals.hitCount(r.val)
+		}
+		r.val = uint32(i)
+	}
+
+	// the dump has no duplicates, nothing is replaced
+	trie := newTrie(o)
+	trie.insertBulk(routes, make([]uint32, len(routes)))
+	t.trie.close()
+	t.vals.reset()
+	t.trie, t.vals = trie, vals
+	t.changes++
+}
+
+// Compact is like Table.Compact.
+func (c *ConcurrentTable[V]) Compact() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.Compact()
+}
@@ -0,0 +1,51 @@
+package zart
+
+import (
+	"maps"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	prng := rand.New(rand.NewPCG(71, 71))
+	pfxs := randomPrefixes(prng, 5000)
+
+	tbl := New[int](WithArena(0))
+	defer tbl.Close()
+	tbl.CountHits()
+	for i, pfx := range pfxs {
+		tbl.Insert(pfx, i)
+	}
+	for _, pfx := range pfxs[100:] {
+		tbl.Delete(pfx)
+	}
+	keep := pfxs[:100]
+	tbl.Lookup(keep[0].Addr())
+	want := maps.Collect(tbl.All())
+	hits := maps.Collect(tbl.Counters())
+	v1 := tbl.InsertPersist(pfxs[4999], -1)
+	defer v1.Close()
+
+	tbl.Compact()
+	if got := maps.Collect(tbl.All()); !maps.Equal(got, want) {
+		t.Fatalf("Compact changed the contents: %d routes, want %d", len(got), len(want))
+	}
+	if n := len(tbl.vals.vals); n != len(want) {
+		t.Errorf("registry has %d slots after Compact, want %d", n, len(want))
+	}
+	if got := maps.Collect(tbl.Counters()); !maps.Equal(got, hits) || len(hits) == 0 {
+		t.Errorf("Counters after Compact = %v, want %v", got, hits)
+	}
+
+	// the persistent version keeps the old trie and payloads
+	if v, ok := v1.Get(pfxs[4999]); !ok || v != -1 {
+		t.Errorf("persistent version Get = %d, %v", v, ok)
+	}
+	if v1.Size() != len(want)+1 {
+		t.Errorf("persistent version has %d routes, want %d", v1.Size(), len(want)+1)
+	}
+	tbl.Insert(pfxs[4998], 1)
+	if _, ok := v1.Get(pfxs[4998]); ok {
+		t.Errorf("insert after Compact is visible in the persistent version")
+	}
+}
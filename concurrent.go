@@ -0,0 +1,244 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+	"sync"
+)
+
+// ConcurrentTable is a Table that is safe for concurrent use. Writers
+// are serialized, readers run in parallel with each other and wait for
+// writers.
+//
+// Iterators work on a copy of the matching routes taken under the read
+// lock, the table may be modified while they run. Read and Update give
+// direct access to the table for compound operations.
+type ConcurrentTable[V any] struct {
+	mu sync.RWMutex
+	t  *Table[V]
+}
+
+// NewConcurrent returns an empty ConcurrentTable.
+func NewConcurrent[V any]() *ConcurrentTable[V] {
+	return &ConcurrentTable[V]{t: New[V]()}
+}
+
+// WithLocking returns a ConcurrentTable that takes over t, typically
+// after a bulk load. t must not be used directly afterwards.
+func (t *Table[V]) WithLocking() *ConcurrentTable[V] {
+	return &ConcurrentTable[V]{t: t}
+}
+
+// Close releases the table, it must not be used afterwards and no other
+// goroutine may be using it.
+func (c *ConcurrentTable[V]) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.Close()
+}
+
+// Read calls fn with the table under the read lock. fn must not modify
+// the table or keep it after returning.
+func (c *ConcurrentTable[V]) Read(fn func(t *Table[V])) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn(c.t)
+}
+
+// Update calls fn with the table under the write lock, the changes of fn
+// become visible to readers together. fn must not keep the table after
+// returning.
+func (c *ConcurrentTable[V]) Update(fn func(t *Table[V])) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c.t)
+}
+
+// Insert is like Table.Insert.
+func (c *ConcurrentTable[V]) Insert(pfx netip.Prefix, val V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.Insert(pfx, val)
+}
+
+// InsertBatch is like Table.InsertBatch. Readers see either none or all
+// of the entries.
+func (c *ConcurrentTable[V]) InsertBatch(entries []RouteEntry[V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.InsertBatch(entries)
+}
+
+// Delete is like Table.Delete.
+func (c *ConcurrentTable[V]) Delete(pfx netip.Prefix) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Delete(pfx)
+}
+
+// DeleteSubtree is like Table.DeleteSubtree.
+func (c *ConcurrentTable[V]) DeleteSubtree(pfx netip.Prefix) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.DeleteSubtree(pfx)
+}
+
+// DeleteIf is like Table.DeleteIf, fn runs with the table locked.
+func (c *ConcurrentTable[V]) DeleteIf(fn func(pfx netip.Prefix, val V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.DeleteIf(fn)
+}
+
+// InsertCovering is like Table.InsertCovering. Readers see either the
+// more specific prefixes or pfx replacing them, never a mix.
+func (c *ConcurrentTable[V]) InsertCovering(pfx netip.Prefix, val V) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.InsertCovering(pfx, val)
+}
+
+// Contains is like Table.Contains.
+func (c *ConcurrentTable[V]) Contains(addr netip.Addr) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Contains(addr)
+}
+
+// Lookup is like Table.Lookup.
+func (c *ConcurrentTable[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Lookup(addr)
+}
+
+// LookupPrefix is like Table.LookupPrefix.
+func (c *ConcurrentTable[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LookupPrefix(addr)
+}
+
+// LookupShortest is like Table.LookupShortest.
+func (c *ConcurrentTable[V]) LookupShortest(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LookupShortest(addr)
+}
+
+// Lookup4Raw is like Table.Lookup4Raw.
+func (c *ConcurrentTable[V]) Lookup4Raw(addr uint32) (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Lookup4Raw(addr)
+}
+
+// Lookup6Raw is like Table.Lookup6Raw.
+func (c *ConcurrentTable[V]) Lookup6Raw(addr *[16]byte) (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Lookup6Raw(addr)
+}
+
+// Modify is like Table.Modify, fn runs with the table locked and must
+// not use c.
+func (c *ConcurrentTable[V]) Modify(pfx netip.Prefix, fn func(old V, existed bool) (val V, del bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.Modify(pfx, fn)
+}
+
+// Get is like Table.Get.
+func (c *ConcurrentTable[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Get(pfx)
+}
+
+// LookupBatch is like Table.LookupBatch, all addresses are resolved
+// against the same state of the table.
+func (c *ConcurrentTable[V]) LookupBatch(addrs []netip.Addr, results []Result[V]) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.t.LookupBatch(addrs, results)
+}
+
+// LookupBatch4 is like Table.LookupBatch4.
+func (c *ConcurrentTable[V]) LookupBatch4(addrs []uint32, results []Result[V]) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.t.LookupBatch4(addrs, results)
+}
+
+// LookupBatch6 is like Table.LookupBatch6.
+func (c *ConcurrentTable[V]) LookupBatch6(addrs [][16]byte, results []Result[V]) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.t.LookupBatch6(addrs, results)
+}
+
+// OverlapsPrefix is like Table.OverlapsPrefix.
+func (c *ConcurrentTable[V]) OverlapsPrefix(pfx netip.Prefix) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.OverlapsPrefix(pfx)
+}
+
+// All is like Table.All.
+func (c *ConcurrentTable[V]) All() iter.Seq2[netip.Prefix, V] {
+	return c.collect(func(t *Table[V]) iter.Seq2[netip.Prefix, V] { return t.All() })
+}
+
+// AllSorted is like Table.AllSorted.
+func (c *ConcurrentTable[V]) AllSorted() iter.Seq2[netip.Prefix, V] {
+	return c.collect(func(t *Table[V]) iter.Seq2[netip.Prefix, V] { return t.AllSorted() })
+}
+
+// Supernets is like Table.Supernets.
+func (c *ConcurrentTable[V]) Supernets(pfx netip.Prefix) iter.Seq2[netip.Prefix, V] {
+	return c.collect(func(t *Table[V]) iter.Seq2[netip.Prefix, V] { return t.Supernets(pfx) })
+}
+
+// LookupAll is like Table.LookupAll.
+func (c *ConcurrentTable[V]) LookupAll(addr netip.Addr) iter.Seq2[netip.Prefix, V] {
+	return c.collect(func(t *Table[V]) iter.Seq2[netip.Prefix, V] { return t.LookupAll(addr) })
+}
+
+// Subnets is like Table.Subnets.
+func (c *ConcurrentTable[V]) Subnets(pfx netip.Prefix) iter.Seq2[netip.Prefix, V] {
+	return c.collect(func(t *Table[V]) iter.Seq2[netip.Prefix, V] { return t.Subnets(pfx) })
+}
+
+// CountSubnets is like Table.CountSubnets.
+func (c *ConcurrentTable[V]) CountSubnets(pfx netip.Prefix) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.CountSubnets(pfx)
+}
+
+// Clone returns a copy of the table as a plain Table owned by the
+// caller.
+func (c *ConcurrentTable[V]) Clone() *Table[V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Clone()
+}
+
+// collect returns an iterator over a copy of the routes yielded by seq,
+// taken under the read lock when the iteration starts.
+func (c *ConcurrentTable[V]) collect(seq func(t *Table[V]) iter.Seq2[netip.Prefix, V]) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		var entries []RouteEntry[V]
+		c.mu.RLock()
+		for pfx, val := range seq(c.t) {
+			entries = append(entries, RouteEntry[V]{pfx, val})
+		}
+		c.mu.RUnlock()
+
+		for _, e := range entries {
+			if !yield(e.Prefix, e.Value) {
+				return
+			}
+		}
+	}
+}
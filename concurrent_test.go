@@ -0,0 +1,88 @@
+package zart
+
+import (
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentTable runs writers, readers and iterators in parallel,
+// it is meant to be run with -race.
+func TestConcurrentTable(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+	c.Insert(netip.MustParsePrefix("0.0.0.0/0"), -1)
+
+	const n = 500
+	var wg sync.WaitGroup
+	for w := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				pfx := netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(w), byte(i >> 8), byte(i)}), 32)
+				c.Insert(pfx, i)
+				if i%3 == 0 {
+					c.Delete(pfx)
+				}
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results := make([]Result[int], 2)
+			for i := range n {
+				addr := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+				if _, ok := c.Lookup(addr); !ok {
+					t.Errorf("Lookup(%s) missed the default route", addr)
+					return
+				}
+				c.LookupBatch([]netip.Addr{addr, netip.MustParseAddr("192.0.2.1")}, results)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			for pfx := range c.All() {
+				// modifying the table while iterating must not deadlock
+				if pfx.Bits() == 32 && pfx.Addr().As4()[3] == 7 {
+					c.Delete(pfx)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+
+	c.Read(func(tbl *Table[int]) {
+		for pfx, val := range tbl.All() {
+			if pfx.Bits() == 32 && val%3 == 0 {
+				t.Errorf("deleted prefix %s still present", pfx)
+			}
+		}
+	})
+}
+
+func TestConcurrentTableUpdate(t *testing.T) {
+	tbl := New[string]()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	c := tbl.WithLocking()
+	defer c.Close()
+
+	c.Update(func(tbl *Table[string]) {
+		tbl.Delete(netip.MustParsePrefix("10.0.0.0/8"))
+		tbl.Insert(netip.MustParsePrefix("10.0.0.0/16"), "b")
+	})
+	if v, ok := c.Lookup(netip.MustParseAddr("10.0.1.1")); !ok || v != "b" {
+		t.Errorf("Lookup = %q, %v, want b", v, ok)
+	}
+	clone := c.Clone()
+	defer clone.Close()
+	c.Insert(netip.MustParsePrefix("10.0.0.0/8"), "c")
+	if _, ok := clone.Lookup(netip.MustParseAddr("10.1.1.1")); ok {
+		t.Errorf("Clone is not independent of the table")
+	}
+}
@@ -0,0 +1,80 @@
+package zart
+
+import (
+	"context"
+	"iter"
+	"net/netip"
+	"slices"
+)
+
+// The Context variants of the long-running methods check ctx once per
+// batch of routes coming out of the trie and return ctx.Err() when it is
+// done, so that a dump for a caller that went away stops early.
+
+// WalkContext is Walk checking ctx between batches of routes, it returns
+// ctx.Err() if the walk was cut short by it and nil otherwise.
+func (t *Table[V]) WalkContext(ctx context.Context, fn func(pfx netip.Prefix, val V) bool) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	var err error
+	for pfx, val := range t.allContext(ctx, &err) {
+		if !fn(pfx, val) {
+			break
+		}
+	}
+	return err
+}
+
+// allContext is All ending early once ctx is done, with ctx.Err() in
+// *err.
+func (t *Table[V]) allContext(ctx context.Context, err *error) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		t.trie.walk(func(batch []route) bool {
+			if *err = ctx.Err(); *err != nil {
+				return false
+			}
+			for i := range batch {
+				if !yield(batch[i].prefix(), t.vals.get(batch[i].val)) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// DiffContext is Diff checking ctx. The side by side walk of the tries
+// is a single call into the trie that ctx can't interrupt; ctx is checked
+// before it and while the differences are turned into events.
+func (t *Table[V]) DiffContext(ctx context.Context, other *Table[V], equal func(a, b V) bool) ([]Event[V], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	items := t.diffItems(other)
+	events := make([]Event[V], 0, len(items))
+	for i := range items {
+		if i%batchSize == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if ev, ok := t.diffEvent(other, &items[i], equal); ok {
+			events = append(events, ev)
+		}
+	}
+	slices.SortFunc(events, func(a, b Event[V]) int { return comparePrefix(a.Prefix, b.Prefix) })
+	return events, nil
+}
+
+// AggregateContext is Aggregate checking ctx while the aggregated routes
+// are computed. Once the table is being rewritten the rewrite runs to the
+// end, a cancelled call leaves the table as it was.
+func (t *Table[V]) AggregateContext(ctx context.Context, equal func(a, b V) bool) (int, error) {
+	var err error
+	agg := aggregate(t.allContext(ctx, &err), equal)
+	if err != nil {
+		return 0, err
+	}
+	return t.rewrite(agg, equal), nil
+}
@@ -0,0 +1,69 @@
+package zart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	prng := rand.New(rand.NewPCG(83, 83))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 3*batchSize) {
+		tbl.Insert(pfx, i%7)
+	}
+	eq := func(a, b int) bool { return a == b }
+	other := tbl.Clone()
+	defer other.Close()
+	other.Insert(mpp("192.0.2.0/24"), 1)
+
+	// a live context changes nothing
+	ctx := context.Background()
+	var want, got bytes.Buffer
+	tbl.ExportJSON(&want)
+	if err := tbl.ExportJSONContext(ctx, &got); err != nil || !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("ExportJSONContext = %v, output differs: %v", err, !bytes.Equal(got.Bytes(), want.Bytes()))
+	}
+	if events, err := tbl.DiffContext(ctx, other, eq); err != nil || len(events) != 1 {
+		t.Errorf("DiffContext = %d events, %v", len(events), err)
+	}
+	b1, _ := tbl.MarshalBinary()
+	if b2, err := tbl.MarshalBinaryContext(ctx); err != nil || !bytes.Equal(b1, b2) {
+		t.Errorf("MarshalBinaryContext = %v, output differs: %v", err, !bytes.Equal(b1, b2))
+	}
+
+	// cancelling in the middle of a walk stops it at the next batch
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	err := tbl.WalkContext(ctx, func(netip.Prefix, int) bool {
+		if n++; n == 10 {
+			cancel()
+		}
+		return true
+	})
+	if !errors.Is(err, context.Canceled) || n != batchSize {
+		t.Errorf("WalkContext = %v after %d routes, want Canceled after %d", err, n, batchSize)
+	}
+
+	size := tbl.Size()
+	if _, err := tbl.AggregateContext(ctx, eq); !errors.Is(err, context.Canceled) || tbl.Size() != size {
+		t.Errorf("AggregateContext = %v, size %d -> %d", err, size, tbl.Size())
+	}
+	if _, err := tbl.DiffContext(ctx, other, eq); !errors.Is(err, context.Canceled) {
+		t.Errorf("DiffContext = %v", err)
+	}
+	if err := tbl.ExportCSVContext(ctx, &got); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExportCSVContext = %v", err)
+	}
+	if _, err := tbl.MarshalBinaryContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("MarshalBinaryContext = %v", err)
+	}
+
+	if shrunk, err := tbl.AggregateContext(context.Background(), eq); err != nil || shrunk != size-tbl.Size() {
+		t.Errorf("AggregateContext = %d, %v", shrunk, err)
+	}
+}
@@ -0,0 +1,100 @@
+package zart
+
+import (
+	"math"
+	"net/netip"
+	"slices"
+)
+
+// Coverage describes the address space routed by a table, see
+// Table.CoveredAddresses.
+type Coverage struct {
+	// IPv4 is the number of IPv4 addresses covered by any prefix.
+	IPv4 uint64
+
+	// IPv6 is the covered IPv6 space in /64 networks, prefixes longer
+	// than /64 count as the fraction of a /64 they span.
+	IPv6 float64
+
+	// Blocks holds the coverage of the /8 blocks of both families that
+	// are covered at least in part, in CIDR order.
+	Blocks []BlockCoverage
+}
+
+// BlockCoverage is the coverage of one /8 block.
+type BlockCoverage struct {
+	Prefix netip.Prefix
+
+	// Share is the covered fraction of the block, from 0 to 1.
+	Share float64
+}
+
+// IPv4Share returns the covered fraction of the IPv4 address space.
+func (c Coverage) IPv4Share() float64 {
+	return float64(c.IPv4) / (1 << 32)
+}
+
+// IPv6Share returns the covered fraction of the IPv6 address space.
+func (c Coverage) IPv6Share() float64 {
+	return c.IPv6 / (1 << 64)
+}
+
+// CoveredAddresses sums the address space covered by the prefixes of the
+// table. Space covered by nested prefixes is counted once. It takes time
+// linear in the size of the table.
+func (t *Table[V]) CoveredAddresses() Coverage {
+	var c Coverage
+	for _, p := range t.outermost() {
+		c.add(p)
+	}
+	return c
+}
+
+// outermost returns the prefixes not covered by another one in CIDR
+// order, they are disjoint and span the routed address space.
+func (t *Table[V]) outermost() []netip.Prefix {
+	routes := t.routes()
+	pfxs := make([]netip.Prefix, len(routes))
+	for i := range routes {
+		pfxs[i] = routes[i].prefix()
+	}
+	slices.SortFunc(pfxs, comparePrefix)
+
+	out := pfxs[:0]
+	for _, p := range pfxs {
+		if n := len(out); n > 0 && out[n-1].Overlaps(p) {
+			continue // nested in the last outermost prefix
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// add counts the outermost prefix p, in CIDR order after the prefixes
+// counted before.
+func (c *Coverage) add(p netip.Prefix) {
+	hostBits := p.Addr().BitLen() - p.Bits()
+	if p.Addr().Is4() {
+		c.IPv4 += 1 << hostBits
+	} else {
+		c.IPv6 += math.Ldexp(1, hostBits-64)
+	}
+
+	if p.Bits() < 8 {
+		// all the /8 blocks in p are covered in full
+		b, _ := p.Addr().Prefix(8)
+		for range 1 << (8 - p.Bits()) {
+			c.Blocks = append(c.Blocks, BlockCoverage{Prefix: b, Share: 1})
+			a := b.Addr().AsSlice()
+			a[0]++
+			next, _ := netip.AddrFromSlice(a)
+			b = netip.PrefixFrom(next, 8)
+		}
+		return
+	}
+	b, _ := p.Addr().Prefix(8)
+	if n := len(c.Blocks); n == 0 || c.Blocks[n-1].Prefix != b {
+		c.Blocks = append(c.Blocks, BlockCoverage{Prefix: b})
+	}
+	c.Blocks[len(c.Blocks)-1].Share += math.Ldexp(1, 8-p.Bits())
+}
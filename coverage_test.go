@@ -0,0 +1,48 @@
+package zart
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestCoveredAddresses(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("10.1.0.0/16"), 2) // nested, counted once
+	tbl.Insert(mpp("192.168.0.0/24"), 3)
+	tbl.Insert(mpp("192.168.1.0/25"), 4)
+	tbl.Insert(mpp("2.0.0.0/7"), 5)
+	tbl.Insert(mpp("2001:db8::/32"), 6)
+	tbl.Insert(mpp("2001:db8:1::/48"), 7)
+	tbl.Insert(mpp("fd00::/128"), 8)
+
+	c := tbl.CoveredAddresses()
+	if want := uint64(1<<24 + 256 + 128 + 2<<24); c.IPv4 != want {
+		t.Errorf("IPv4 = %d, want %d", c.IPv4, want)
+	}
+	if want := float64(1<<32) + 1.0/(1<<64); c.IPv6 != want {
+		t.Errorf("IPv6 = %v /64s, want %v", c.IPv6, want)
+	}
+	if got, want := c.IPv4Share(), 3.0/256+384.0/(1<<32); got != want {
+		t.Errorf("IPv4Share = %v, want %v", got, want)
+	}
+
+	want := []BlockCoverage{
+		{mpp("2.0.0.0/8"), 1},
+		{mpp("3.0.0.0/8"), 1},
+		{mpp("10.0.0.0/8"), 1},
+		{mpp("192.0.0.0/8"), 384.0 / (1 << 24)},
+		{mpp("2000::/8"), 1.0 / (1 << 24)},
+		{mpp("fd00::/8"), 1.0 / (1 << 120)},
+	}
+	if !slices.Equal(c.Blocks, want) {
+		t.Errorf("Blocks = %v, want %v", c.Blocks, want)
+	}
+
+	tbl.Insert(netip.PrefixFrom(netip.IPv4Unspecified(), 0), 0)
+	if c := tbl.CoveredAddresses(); c.IPv4 != 1<<32 || c.IPv4Share() != 1 || len(c.Blocks) != 258 {
+		t.Errorf("with a default route IPv4 = %d, %d blocks", c.IPv4, len(c.Blocks))
+	}
+}
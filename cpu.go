@@ -0,0 +1,101 @@
+package zart
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/cpu"
+)
+
+// CPU features, BART_CPU_* in include/bart.h.
+const (
+	cpuPOPCNT = 1 << iota
+	cpuBMI2
+	cpuNEON
+)
+
+// CPUInfo reports the CPU features the trie code could use and which of
+// them it does, see CPUFeatures.
+type CPUInfo struct {
+	// POPCNT, BMI2 and NEON are the features of the CPU.
+	POPCNT, BMI2, NEON bool
+
+	// Used names the features the bitset operations of the trie use,
+	// built into the C library for its target or selected at run
+	// time.
+	Used []string
+
+	// Generic is set if run-time selection is turned off, by
+	// ZART_CPU=generic in the environment or SetGenericCPU.
+	Generic bool
+}
+
+var cpuState struct {
+	sync.Mutex
+	generic bool
+	used    uint32
+}
+
+func init() {
+	cpuState.generic = strings.EqualFold(os.Getenv("ZART_CPU"), "generic")
+	selectCPU()
+}
+
+// detectCPU returns the features of the CPU.
+func detectCPU() uint32 {
+	var f uint32
+	if cpu.X86.HasPOPCNT {
+		f |= cpuPOPCNT
+	}
+	if cpu.X86.HasBMI2 {
+		f |= cpuBMI2
+	}
+	if cpu.ARM64.HasASIMD {
+		f |= cpuNEON
+	}
+	return f
+}
+
+// selectCPU passes the detected features to the trie, or none if
+// selection is turned off.
+func selectCPU() {
+	f := detectCPU()
+	if cpuState.generic {
+		f = 0
+	}
+	cpuState.used = setCPUFeatures(f)
+}
+
+// CPUFeatures reports the CPU features found at start up and those the
+// trie code uses. The C library is built for a baseline target unless
+// configured otherwise; on x86-64 it then counts the bits of its
+// bitsets, the rank behind every lookup step, with POPCNT once the CPU
+// is known to have it. BMI2 and NEON are reported only: nothing on the
+// lookup path gains from BMI2, and NEON is part of the arm64 baseline.
+func CPUFeatures() CPUInfo {
+	cpuState.Lock()
+	defer cpuState.Unlock()
+	f := detectCPU()
+	info := CPUInfo{POPCNT: f&cpuPOPCNT != 0, BMI2: f&cpuBMI2 != 0, NEON: f&cpuNEON != 0, Generic: cpuState.generic}
+	for _, n := range []struct {
+		bit  uint32
+		name string
+	}{{cpuPOPCNT, "popcnt"}, {cpuBMI2, "bmi2"}, {cpuNEON, "neon"}} {
+		if cpuState.used&n.bit != 0 {
+			info.Used = append(info.Used, n.name)
+		}
+	}
+	return info
+}
+
+// SetGenericCPU turns run-time selection of CPU features off, or on again,
+// like ZART_CPU=generic in the environment. It is meant for comparing the
+// code paths and ruling them out when debugging, and must not be called
+// while any table is in use.
+func SetGenericCPU(generic bool) {
+	cpuState.Lock()
+	defer cpuState.Unlock()
+	cpuState.generic = generic
+	selectCPU()
+}
@@ -0,0 +1,50 @@
+package zart
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestCPUFeatures(t *testing.T) {
+	defer SetGenericCPU(CPUFeatures().Generic)
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i := range 256 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i), 0, 0}), 16), i)
+	}
+	lookups := func(mode string) {
+		t.Helper()
+		for i := range 256 {
+			if v, ok := tbl.Lookup(netip.AddrFrom4([4]byte{10, byte(i), 1, 1})); !ok || v != i {
+				t.Fatalf("%s Lookup of 10.%d.1.1 = %d, %v", mode, i, v, ok)
+			}
+		}
+	}
+
+	SetGenericCPU(false)
+	info := CPUFeatures()
+	if info.Generic {
+		t.Error("Generic after SetGenericCPU(false)")
+	}
+	for _, name := range info.Used {
+		if (name == "popcnt" && !info.POPCNT) || (name == "bmi2" && !info.BMI2) || (name == "neon" && !info.NEON) {
+			t.Errorf("uses %s the CPU lacks: %+v", name, info)
+		}
+	}
+	lookups("selected")
+
+	// the generic code keeps only the features built into the library
+	SetGenericCPU(true)
+	g := CPUFeatures()
+	if !g.Generic {
+		t.Error("no Generic after SetGenericCPU(true)")
+	}
+	for _, name := range g.Used {
+		if !slices.Contains(info.Used, name) {
+			t.Errorf("generic code uses %s", name)
+		}
+	}
+	lookups("generic")
+}
@@ -0,0 +1,191 @@
+package zart
+
+import (
+	"math"
+	"net/netip"
+	"time"
+)
+
+// DampeningConfig are the parameters of route flap dampening as in RFC
+// 2439, zero fields take the defaults of common BGP implementations.
+type DampeningConfig struct {
+	// HalfLife is the time after which a penalty is halved, 15 minutes.
+	HalfLife time.Duration
+
+	// Suppress is the penalty above which a prefix is suppressed, 2000.
+	Suppress float64
+
+	// Reuse is the penalty below which a suppressed prefix is installed
+	// again, 750.
+	Reuse float64
+
+	// MaxSuppress is the longest time a prefix stays suppressed after its
+	// last flap, 60 minutes. It caps the penalty at Reuse times 2 to the
+	// power of MaxSuppress/HalfLife.
+	MaxSuppress time.Duration
+
+	// Withdrawal is the penalty of a withdrawal, 1000.
+	Withdrawal float64
+
+	// Change is the penalty of an announcement replacing the value of a
+	// reachable prefix, 500.
+	Change float64
+}
+
+func (c DampeningConfig) withDefaults() DampeningConfig {
+	if c.HalfLife <= 0 {
+		c.HalfLife = 15 * time.Minute
+	}
+	if c.Suppress <= 0 {
+		c.Suppress = 2000
+	}
+	if c.Reuse <= 0 {
+		c.Reuse = 750
+	}
+	if c.MaxSuppress <= 0 {
+		c.MaxSuppress = time.Hour
+	}
+	if c.Withdrawal <= 0 {
+		c.Withdrawal = 1000
+	}
+	if c.Change <= 0 {
+		c.Change = 500
+	}
+	return c
+}
+
+// flap is the dampening state of a prefix with a penalty.
+type flap[V any] struct {
+	penalty    float64
+	at         time.Time // when penalty was last updated
+	suppressed bool
+	reachable  bool // the last update was an announcement, of val
+	val        V
+}
+
+// Dampener feeds a table with announcements and withdrawals of prefixes,
+// keeping the prefixes that flap out of it. Every withdrawal and change
+// adds to the penalty of a prefix, which decays exponentially; a prefix
+// whose penalty exceeds the suppress threshold is removed from the table
+// and its announcements are held back until the penalty has decayed below
+// the reuse threshold. Reuse installs the prefixes that became usable
+// again and must be called periodically.
+//
+// The time of every update is passed in, so that recorded updates can be
+// replayed at their own pace. The table must not be modified otherwise
+// for the prefixes fed through the Dampener. Like Table, a Dampener is not
+// safe for concurrent use.
+type Dampener[V any] struct {
+	t     *Table[V]
+	cfg   DampeningConfig
+	limit float64
+	flaps map[netip.Prefix]*flap[V]
+}
+
+// NewDampener returns a Dampener feeding t with the parameters of cfg.
+func NewDampener[V any](t *Table[V], cfg DampeningConfig) *Dampener[V] {
+	cfg = cfg.withDefaults()
+	limit := cfg.Reuse * math.Exp2(float64(cfg.MaxSuppress)/float64(cfg.HalfLife))
+	return &Dampener[V]{t: t, cfg: cfg, limit: limit, flaps: map[netip.Prefix]*flap[V]{}}
+}
+
+// decay brings the penalty of f to now.
+func (d *Dampener[V]) decay(f *flap[V], now time.Time) {
+	if dt := now.Sub(f.at); dt > 0 {
+		f.penalty *= math.Exp2(-float64(dt) / float64(d.cfg.HalfLife))
+	}
+	f.at = now
+}
+
+// penalize adds p to the penalty of pfx and suppresses it above the
+// threshold.
+func (d *Dampener[V]) penalize(pfx netip.Prefix, p float64, now time.Time) *flap[V] {
+	f := d.flaps[pfx]
+	if f == nil {
+		f = &flap[V]{at: now}
+		d.flaps[pfx] = f
+	}
+	d.decay(f, now)
+	f.penalty = min(f.penalty+p, d.limit)
+	if !f.suppressed && f.penalty > d.cfg.Suppress {
+		f.suppressed = true
+		d.t.Delete(pfx)
+	}
+	return f
+}
+
+// Announce records an announcement of pfx with val at now and installs
+// it in the table unless pfx is suppressed. It reports whether pfx was
+// installed.
+func (d *Dampener[V]) Announce(pfx netip.Prefix, val V, now time.Time) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	pfx = pfx.Masked()
+	f := d.flaps[pfx]
+	if _, installed := d.t.Get(pfx); installed || f != nil && f.reachable {
+		f = d.penalize(pfx, d.cfg.Change, now)
+	}
+	if f != nil {
+		f.reachable, f.val = true, val
+		if f.suppressed {
+			return false
+		}
+	}
+	d.t.Insert(pfx, val)
+	return true
+}
+
+// Withdraw records a withdrawal of pfx at now and removes it from the
+// table.
+func (d *Dampener[V]) Withdraw(pfx netip.Prefix, now time.Time) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked()
+	f := d.penalize(pfx, d.cfg.Withdrawal, now)
+	f.reachable = false
+	var zero V
+	f.val = zero
+	d.t.Delete(pfx)
+}
+
+// Reuse installs the suppressed prefixes whose penalty has decayed below
+// the reuse threshold at now, if their last update was an announcement,
+// and returns their number. It forgets the prefixes whose penalty is
+// below half the threshold.
+func (d *Dampener[V]) Reuse(now time.Time) int {
+	n := 0
+	for pfx, f := range d.flaps {
+		d.decay(f, now)
+		if f.suppressed && f.penalty < d.cfg.Reuse {
+			f.suppressed = false
+			if f.reachable {
+				d.t.Insert(pfx, f.val)
+				n++
+			}
+		}
+		if !f.suppressed && f.penalty < d.cfg.Reuse/2 {
+			delete(d.flaps, pfx)
+		}
+	}
+	return n
+}
+
+// Penalty returns the penalty of pfx at now.
+func (d *Dampener[V]) Penalty(pfx netip.Prefix, now time.Time) float64 {
+	f := d.flaps[pfx.Masked()]
+	if f == nil {
+		return 0
+	}
+	if dt := now.Sub(f.at); dt > 0 {
+		return f.penalty * math.Exp2(-float64(dt)/float64(d.cfg.HalfLife))
+	}
+	return f.penalty
+}
+
+// Suppressed reports whether pfx is suppressed.
+func (d *Dampener[V]) Suppressed(pfx netip.Prefix) bool {
+	f := d.flaps[pfx.Masked()]
+	return f != nil && f.suppressed
+}
@@ -0,0 +1,104 @@
+package zart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDampener(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	d := NewDampener(tbl, DampeningConfig{})
+	pfx := mpp("192.0.2.0/24")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !d.Announce(pfx, 1, now) {
+		t.Fatal("first announcement not installed")
+	}
+	// two flaps a minute apart stay below the suppress threshold
+	for i := range 2 {
+		now = now.Add(30 * time.Second)
+		d.Withdraw(pfx, now)
+		now = now.Add(30 * time.Second)
+		if !d.Announce(pfx, 2+i, now) {
+			t.Fatalf("flap %d suppressed with penalty %.0f", i+1, d.Penalty(pfx, now))
+		}
+	}
+	// the third one crosses it
+	now = now.Add(30 * time.Second)
+	d.Withdraw(pfx, now)
+	if !d.Suppressed(pfx) {
+		t.Fatalf("not suppressed with penalty %.0f", d.Penalty(pfx, now))
+	}
+	now = now.Add(30 * time.Second)
+	if d.Announce(pfx, 9, now) {
+		t.Fatal("suppressed announcement installed")
+	}
+	if _, ok := tbl.Get(pfx); ok {
+		t.Fatal("suppressed prefix in the table")
+	}
+
+	// decayed to half after a half-life
+	p := d.Penalty(pfx, now)
+	if half := d.Penalty(pfx, now.Add(15*time.Minute)); math.Abs(half-p/2) > 1e-6 {
+		t.Errorf("penalty %.1f after a half-life, want %.1f", half, p/2)
+	}
+
+	if n := d.Reuse(now.Add(time.Minute)); n != 0 {
+		t.Errorf("Reuse installed %d prefixes too early", n)
+	}
+	// below 750 once the penalty has decayed by a factor of 4 or so
+	now = now.Add(30 * time.Minute)
+	if n := d.Reuse(now); n != 1 || d.Suppressed(pfx) {
+		t.Fatalf("Reuse = %d with penalty %.0f", n, d.Penalty(pfx, now))
+	}
+	if v, ok := tbl.Get(pfx); !ok || v != 9 {
+		t.Errorf("reused prefix = %d, %v, want the last announcement", v, ok)
+	}
+
+	// forgotten once the penalty is low
+	d.Reuse(now.Add(2 * time.Hour))
+	if len(d.flaps) != 0 {
+		t.Errorf("%d prefixes still tracked", len(d.flaps))
+	}
+}
+
+func TestDampenerMaxSuppress(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	d := NewDampener(tbl, DampeningConfig{})
+	pfx := mpp("2001:db8::/32")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for range 100 {
+		d.Announce(pfx, 1, now)
+		d.Withdraw(pfx, now)
+		now = now.Add(time.Second)
+	}
+	d.Announce(pfx, 1, now)
+	// the ceiling is reached, after MaxSuppress the prefix is usable
+	if d.Reuse(now.Add(59*time.Minute)) != 0 {
+		t.Error("reused before MaxSuppress")
+	}
+	if d.Reuse(now.Add(61*time.Minute)) != 1 {
+		t.Errorf("not reused after MaxSuppress, penalty %.0f", d.Penalty(pfx, now.Add(61*time.Minute)))
+	}
+}
+
+func TestDampenerChange(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	d := NewDampener(tbl, DampeningConfig{})
+	pfx := mpp("10.0.0.0/8")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d.Announce(pfx, 1, now)
+	d.Announce(pfx, 2, now)
+	if p := d.Penalty(pfx, now); p != 500 {
+		t.Errorf("penalty of a change = %.0f, want 500", p)
+	}
+	if v, _ := tbl.Get(pfx); v != 2 {
+		t.Errorf("changed value = %d", v)
+	}
+}
@@ -0,0 +1,100 @@
+package zart
+
+import "net/netip"
+
+// The default routes of both families, 0.0.0.0/0 and ::/0. They are
+// ordinary prefixes of the table, stored in the root nodes of the tries,
+// and match every address of their family that no longer prefix covers.
+var (
+	default4 = netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+	default6 = netip.PrefixFrom(netip.IPv6Unspecified(), 0)
+)
+
+// SetDefault4 sets the IPv4 default route 0.0.0.0/0 to val.
+func (t *Table[V]) SetDefault4(val V) {
+	t.Insert(default4, val)
+}
+
+// SetDefault6 sets the IPv6 default route ::/0 to val.
+func (t *Table[V]) SetDefault6(val V) {
+	t.Insert(default6, val)
+}
+
+// Default4 returns the value of the IPv4 default route.
+func (t *Table[V]) Default4() (val V, ok bool) {
+	return t.Get(default4)
+}
+
+// Default6 returns the value of the IPv6 default route.
+func (t *Table[V]) Default6() (val V, ok bool) {
+	return t.Get(default6)
+}
+
+// ClearDefault4 deletes the IPv4 default route and reports whether there
+// was one.
+func (t *Table[V]) ClearDefault4() bool {
+	return t.Delete(default4)
+}
+
+// ClearDefault6 deletes the IPv6 default route and reports whether there
+// was one.
+func (t *Table[V]) ClearDefault6() bool {
+	return t.Delete(default6)
+}
+
+// LookupDefault is Lookup also reporting whether addr matched only the
+// default route of its family, which lookups that must not fall back on
+// it, like the check whether a next hop is reachable, treat as no match.
+func (t *Table[V]) LookupDefault(addr netip.Addr) (val V, ok, isDefault bool) {
+	pfx, val, ok := t.LookupPrefix(addr)
+	return val, ok, ok && pfx.Bits() == 0
+}
+
+// SetDefault4 is like Table.SetDefault4.
+func (c *ConcurrentTable[V]) SetDefault4(val V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.SetDefault4(val)
+}
+
+// SetDefault6 is like Table.SetDefault6.
+func (c *ConcurrentTable[V]) SetDefault6(val V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.SetDefault6(val)
+}
+
+// Default4 is like Table.Default4.
+func (c *ConcurrentTable[V]) Default4() (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Default4()
+}
+
+// Default6 is like Table.Default6.
+func (c *ConcurrentTable[V]) Default6() (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Default6()
+}
+
+// ClearDefault4 is like Table.ClearDefault4.
+func (c *ConcurrentTable[V]) ClearDefault4() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.ClearDefault4()
+}
+
+// ClearDefault6 is like Table.ClearDefault6.
+func (c *ConcurrentTable[V]) ClearDefault6() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.ClearDefault6()
+}
+
+// LookupDefault is like Table.LookupDefault.
+func (c *ConcurrentTable[V]) LookupDefault(addr netip.Addr) (val V, ok, isDefault bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LookupDefault(addr)
+}
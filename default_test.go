@@ -0,0 +1,78 @@
+package zart
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestDefaultRoute(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	if _, ok := tbl.Default4(); ok {
+		t.Fatal("empty table has a default route")
+	}
+	tbl.Insert(mpp("0.0.0.0/1"), "low half")
+	tbl.Insert(mpp("10.0.0.0/8"), "ten")
+	tbl.SetDefault4("gw4")
+	tbl.SetDefault6("gw6")
+
+	if v, ok := tbl.Default4(); !ok || v != "gw4" {
+		t.Errorf("Default4 = %q, %v", v, ok)
+	}
+	if v, ok := tbl.Get(mpp("0.0.0.0/0")); !ok || v != "gw4" {
+		t.Errorf("Get(0.0.0.0/0) = %q, %v", v, ok)
+	}
+
+	tests := []struct {
+		addr      string
+		want      string
+		isDefault bool
+	}{
+		{"0.0.0.0", "low half", false},
+		{"10.1.1.1", "ten", false},
+		{"127.255.255.255", "low half", false},
+		{"128.0.0.0", "gw4", true},
+		{"255.255.255.255", "gw4", true},
+		{"::", "gw6", true},
+		{"2001:db8::1", "gw6", true},
+		// a mapped address is IPv6 and matches ::/0
+		{"::ffff:10.1.1.1", "gw6", true},
+	}
+	for _, tt := range tests {
+		v, ok, isDefault := tbl.LookupDefault(mpa(tt.addr))
+		if !ok || v != tt.want || isDefault != tt.isDefault {
+			t.Errorf("LookupDefault(%s) = %q, %v, %v, want %q, %v", tt.addr, v, ok, isDefault, tt.want, tt.isDefault)
+		}
+	}
+
+	tbl.SetDefault4("gw4b")
+	if v, _ := tbl.Default4(); v != "gw4b" || tbl.Size() != 4 {
+		t.Errorf("replaced default = %q, size %d", v, tbl.Size())
+	}
+	if !tbl.ClearDefault4() || tbl.ClearDefault4() {
+		t.Error("ClearDefault4 did not report the route once")
+	}
+	if _, ok, _ := tbl.LookupDefault(mpa("200.0.0.1")); ok {
+		t.Error("lookup matched a cleared default route")
+	}
+	if v, ok := tbl.Default6(); !ok || v != "gw6" {
+		t.Errorf("ClearDefault4 touched the IPv6 default: %q, %v", v, ok)
+	}
+	if _, ok, _ := tbl.LookupDefault(netip.Addr{}); ok {
+		t.Error("the zero address matched")
+	}
+}
+
+func TestDefaultRouteMapped(t *testing.T) {
+	tbl := New[string](WithMapped(MappedAsIPv4))
+	defer tbl.Close()
+	tbl.SetDefault4("gw4")
+	tbl.SetDefault6("gw6")
+	if v, _, isDefault := tbl.LookupDefault(mpa("::ffff:10.1.1.1")); v != "gw4" || !isDefault {
+		t.Errorf("mapped lookup = %q, %v, want the IPv4 default", v, isDefault)
+	}
+	tbl.ClearDefault6()
+	if tbl.Size() != 1 {
+		t.Errorf("Size = %d after ClearDefault6", tbl.Size())
+	}
+}
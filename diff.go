@@ -0,0 +1,95 @@
+package zart
+
+import "slices"
+
+// diffItem is laid out like bart_diff_t in include/bart.h.
+type diffItem struct {
+	route  route
+	theirs uint32
+	kind   uint8
+	_      [3]byte
+}
+
+// Kinds of a diffItem, BART_DIFF_* in include/bart.h.
+const (
+	diffRemoved = iota
+	diffAdded
+	diffChanged
+)
+
+// Diff returns the changes that turn t into other, in CIDR order: an
+// EventDelete for every prefix only in t, an EventInsert for every prefix
+// only in other and an EventUpdate for every prefix whose values differ
+// by equal. A nil equal compares the prefixes alone, as for Subtract,
+// and reports no updates.
+//
+// Both tries are walked side by side in a single call into the trie,
+// without dumping either table. Persistent versions of one table, as made
+// by InsertPersist, share their payloads and most of their nodes; for
+// them the walk skips the shared subtrees and the cost is proportional to
+// the changes between the versions rather than to the table size.
+func (t *Table[V]) Diff(other *Table[V], equal func(a, b V) bool) []Event[V] {
+	items := t.diffItems(other)
+	events := make([]Event[V], 0, len(items))
+	for i := range items {
+		if ev, ok := t.diffEvent(other, &items[i], equal); ok {
+			events = append(events, ev)
+		}
+	}
+	slices.SortFunc(events, func(a, b Event[V]) int { return comparePrefix(a.Prefix, b.Prefix) })
+	return events
+}
+
+// diffEvent turns a difference found by the walk into its event, ok is
+// false for a common prefix with equal values.
+func (t *Table[V]) diffEvent(other *Table[V], d *diffItem, equal func(a, b V) bool) (ev Event[V], ok bool) {
+	pfx := d.route.prefix()
+	switch d.kind {
+	case diffRemoved:
+		return Event[V]{Kind: EventDelete, Prefix: pfx, Old: t.vals.get(d.route.val)}, true
+	case diffAdded:
+		return Event[V]{Kind: EventInsert, Prefix: pfx, New: other.vals.get(d.theirs)}, true
+	}
+	if equal == nil {
+		return ev, false
+	}
+	old, val := t.vals.get(d.route.val), other.vals.get(d.theirs)
+	if equal(old, val) {
+		return ev, false
+	}
+	return Event[V]{Kind: EventUpdate, Prefix: pfx, Old: old, New: val}, true
+}
+
+// Equal reports whether t and other hold the same prefixes with values
+// that are equal by equal. The prefixes are compared first, in a walk of
+// both tries that stops at the first difference, the values only if all
+// prefixes match and equal is not nil.
+func (t *Table[V]) Equal(other *Table[V], equal func(a, b V) bool) bool {
+	if t == other {
+		return true
+	}
+	if !t.trie.samePrefixes(other.trie) {
+		return false
+	}
+	if equal == nil {
+		return true
+	}
+	for _, d := range t.diffItems(other) {
+		if !equal(t.vals.get(d.route.val), other.vals.get(d.theirs)) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffItems runs the side by side walk of Diff in as few calls as
+// possible.
+func (t *Table[V]) diffItems(other *Table[V]) []diffItem {
+	shared := t.vals == other.vals
+	hint := 64
+	if !shared {
+		// every common prefix comes back for comparing the values
+		hint = max(t.Size(), other.Size())
+	}
+	return fill(hint, func(out []diffItem) int { return t.trie.diff(other.trie, shared, out) })
+}
@@ -0,0 +1,144 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// applyEvents replays the events of a Diff on t.
+func applyEvents[V any](t *Table[V], events []Event[V]) {
+	for _, e := range events {
+		if e.Kind == EventDelete {
+			t.Delete(e.Prefix)
+		} else {
+			t.Insert(e.Prefix, e.New)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, b := New[string](), New[string]()
+	defer a.Close()
+	defer b.Close()
+	a.Insert(mpp("10.0.0.0/8"), "x")
+	a.Insert(mpp("10.1.0.0/16"), "y")
+	a.Insert(mpp("2001:db8::/32"), "z")
+	b.Insert(mpp("10.0.0.0/8"), "x")
+	b.Insert(mpp("10.1.0.0/16"), "w")
+	b.Insert(mpp("192.168.0.0/24"), "v")
+
+	want := []Event[string]{
+		{Kind: EventUpdate, Prefix: mpp("10.1.0.0/16"), Old: "y", New: "w"},
+		{Kind: EventInsert, Prefix: mpp("192.168.0.0/24"), New: "v"},
+		{Kind: EventDelete, Prefix: mpp("2001:db8::/32"), Old: "z"},
+	}
+	got := a.Diff(b, func(x, y string) bool { return x == y })
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diff[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if d := a.Diff(a, func(x, y string) bool { return x == y }); len(d) != 0 {
+		t.Errorf("Diff of a table with itself = %v", d)
+	}
+
+	// without equal only the prefixes are compared
+	got = a.Diff(b, nil)
+	if len(got) != 2 || got[0] != want[1] || got[1] != want[2] {
+		t.Errorf("Diff with a nil equal = %v, want %v", got, want[1:])
+	}
+}
+
+func TestDiffRandom(t *testing.T) {
+	prng := rand.New(rand.NewPCG(53, 53))
+	eq := func(x, y int) bool { return x == y }
+
+	base := New[int]()
+	defer base.Close()
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		base.Insert(pfx, prng.IntN(4))
+	}
+
+	// a persistent version sharing payloads and a clone sharing nothing
+	cur := base.InsertPersist(mpp("0.0.0.0/0"), 9)
+	defer cur.Close()
+	for i, pfx := range randomPrefixes(prng, 300) {
+		switch i % 3 {
+		case 0:
+			cur.Delete(pfx)
+		default:
+			cur.Insert(pfx, prng.IntN(4))
+		}
+	}
+	clone := cur.Clone()
+	defer clone.Close()
+
+	for _, other := range []*Table[int]{cur, clone} {
+		events := base.Diff(other, eq)
+		for _, e := range events {
+			old, inBase := base.Get(e.Prefix)
+			val, inOther := other.Get(e.Prefix)
+			switch {
+			case e.Kind == EventDelete && (!inBase || inOther || e.Old != old),
+				e.Kind == EventInsert && (inBase || !inOther || e.New != val),
+				e.Kind == EventUpdate && (old == val || e.Old != old || e.New != val):
+				t.Fatalf("wrong event %v: base %d, %v, other %d, %v", e, old, inBase, val, inOther)
+			}
+		}
+
+		replay := base.Clone()
+		applyEvents(replay, events)
+		if d := replay.Diff(other, eq); len(d) != 0 {
+			t.Errorf("replaying %d events leaves %d differences, first %v", len(events), len(d), d[0])
+		}
+		replay.Close()
+	}
+}
+
+func TestEqual(t *testing.T) {
+	prng := rand.New(rand.NewPCG(54, 54))
+	eq := func(x, y int) bool { return x == y }
+
+	a, b := New[int](), New[int]()
+	defer a.Close()
+	defer b.Close()
+	pfxs := randomPrefixes(prng, 1000)
+	for i, pfx := range pfxs {
+		a.Insert(pfx, i)
+	}
+	for i := len(pfxs) - 1; i >= 0; i-- {
+		if v, ok := a.Get(pfxs[i]); ok && v == i {
+			b.Insert(pfxs[i], i)
+		}
+	}
+	if !a.Equal(b, eq) || !b.Equal(a, eq) {
+		t.Fatal("tables with the same routes inserted in different order are not Equal")
+	}
+
+	b.Insert(pfxs[0], -1)
+	if a.Equal(b, eq) {
+		t.Error("Equal with a changed value")
+	}
+	if !a.Equal(b, func(x, y int) bool { return true }) || !a.Equal(b, nil) {
+		t.Error("not Equal with matching prefixes and equal ignoring values")
+	}
+	b.Delete(pfxs[0])
+	b.Insert(mpp("203.0.113.0/24"), 0)
+	if a.Equal(b, eq) {
+		t.Error("Equal with a different prefix")
+	}
+
+	p := a.InsertPersist(mpp("198.51.100.0/24"), 1)
+	defer p.Close()
+	if a.Equal(p, eq) {
+		t.Error("Equal to a persistent version with one more prefix")
+	}
+	q := p.DeletePersist(mpp("198.51.100.0/24"))
+	defer q.Close()
+	if !a.Equal(q, eq) {
+		t.Error("not Equal to a persistent version with the same routes")
+	}
+}
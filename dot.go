@@ -0,0 +1,81 @@
+package zart
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kinds of trie items, the values of BART_ITEM_* in include/bart.h.
+const (
+	itemNode = iota
+	itemPrefix
+	itemLeaf
+	itemFringe
+)
+
+// trieItem mirrors bart_trie_item_t, an element of the structural dump
+// of the trie.
+type trieItem struct {
+	node, parent       uint32
+	kind, depth, octet uint8
+	route              route
+}
+
+// DumpDOT writes the structure of the trie as a Graphviz digraph to w,
+// for rendering with e.g. dot -Tsvg. Every trie node is a box listing
+// the prefixes stored in it with their values, edges are labeled with
+// the octet of the child slot. Path compressed leaves and fringes are
+// drawn as ellipses, fringes dashed. Values are formatted with %v.
+func (t *Table[V]) DumpDOT(w io.Writer) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	items := fill(t.Size()+16, t.trie.items)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph zart {\n\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	// a node's label collects its prefixes, which follow it
+	var label strings.Builder
+	var node trieItem
+	flush := func() {
+		if node.node != 0 {
+			fmt.Fprintf(bw, "\tn%d [label=\"%s\"];\n", node.node, label.String())
+			if node.parent != 0 {
+				fmt.Fprintf(bw, "\tn%d -> n%d [label=\"%d\"];\n", node.parent, node.node, node.octet)
+			}
+		}
+		label.Reset()
+	}
+	for i, it := range items {
+		pfx := it.route.prefix()
+		switch it.kind {
+		case itemNode:
+			flush()
+			node = it
+			family := "IPv6"
+			if it.route.is4 != 0 {
+				family = "IPv4"
+			}
+			fmt.Fprintf(&label, "%s depth %d\\n%s\\l", family, it.depth, pfx)
+		case itemPrefix:
+			fmt.Fprintf(&label, "%s = %s\\l", pfx, dotEscape(fmt.Sprint(t.vals.get(it.route.val))))
+		case itemLeaf, itemFringe:
+			style := ""
+			if it.kind == itemFringe {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(bw, "\tp%d [shape=ellipse%s, label=\"%s\\n%s\"];\n", i, style, pfx,
+				dotEscape(fmt.Sprint(t.vals.get(it.route.val))))
+			fmt.Fprintf(bw, "\tn%d -> p%d [label=\"%d\"];\n", it.node, i, it.octet)
+		}
+	}
+	flush()
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotEscape escapes s for a double quoted DOT string.
+var dotEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
@@ -0,0 +1,41 @@
+package zart
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDumpDOT(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), `ten "quoted"`)
+	tbl.Insert(mpp("10.1.0.0/16"), "ten-one")
+	tbl.Insert(mpp("10.1.2.0/24"), "ten-one-two")
+	tbl.Insert(mpp("2001:db8::/32"), "doc")
+
+	var sb strings.Builder
+	if err := tbl.DumpDOT(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	if !strings.HasPrefix(out, "digraph zart {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Fatalf("not a digraph:\n%s", out)
+	}
+	for _, want := range []string{`n1 [label="IPv4 depth 0`, `n2 [label="IPv6 depth 0`, `ten \"quoted\"`, "ten-one-two", "2001:db8::/32", "doc"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// every edge must connect declared nodes
+	declared := map[string]bool{}
+	for _, m := range regexp.MustCompile(`(?m)^\t([np]\d+) \[`).FindAllStringSubmatch(out, -1) {
+		declared[m[1]] = true
+	}
+	for _, m := range regexp.MustCompile(`(?m)^\t(n\d+) -> ([np]\d+) `).FindAllStringSubmatch(out, -1) {
+		if !declared[m[1]] || !declared[m[2]] {
+			t.Errorf("edge %s -> %s to an undeclared node", m[1], m[2])
+		}
+	}
+}
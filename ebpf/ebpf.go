@@ -0,0 +1,52 @@
+// Package ebpf programs eBPF LPM_TRIE maps from zart tables, so that XDP
+// and tc programs match against the same routes as the Go side.
+//
+// An LPM_TRIE map holds one address family, its keys use the layout of
+// struct bpf_lpm_trie_key: the prefix length as host-order uint32,
+// followed by the address in network order. Entries produces the keys of
+// a table in this layout, Export writes them into a map on Linux.
+package ebpf
+
+import (
+	"encoding/binary"
+	"iter"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// Key sizes of IPv4 and IPv6 LPM_TRIE maps.
+const (
+	KeySize4 = 4 + 4
+	KeySize6 = 4 + 16
+)
+
+// AppendKey appends the bpf_lpm_trie_key encoding of pfx to b, pfx is
+// masked first.
+func AppendKey(b []byte, pfx netip.Prefix) []byte {
+	pfx = pfx.Masked()
+	b = binary.NativeEndian.AppendUint32(b, uint32(pfx.Bits()))
+	if addr := pfx.Addr(); addr.Is4() {
+		a4 := addr.As4()
+		return append(b, a4[:]...)
+	}
+	a16 := pfx.Addr().As16()
+	return append(b, a16[:]...)
+}
+
+// Entries returns an iterator over the IPv4 or, with ipv6 set, the IPv6
+// routes of t as LPM_TRIE keys with their values. The key is only valid
+// until the next iteration.
+func Entries[V any](t *zart.Table[V], ipv6 bool) iter.Seq2[[]byte, V] {
+	return func(yield func([]byte, V) bool) {
+		key := make([]byte, 0, KeySize6)
+		for pfx, val := range t.All() {
+			if pfx.Addr().Is6() != ipv6 {
+				continue
+			}
+			if !yield(AppendKey(key[:0], pfx), val) {
+				return
+			}
+		}
+	}
+}
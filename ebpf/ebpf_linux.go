@@ -0,0 +1,84 @@
+package ebpf
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/gx14ac/zart"
+)
+
+// mapElemAttr is the BPF_MAP_*_ELEM variant of union bpf_attr.
+type mapElemAttr struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+// mapCreateAttr is the BPF_MAP_CREATE variant of union bpf_attr.
+type mapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (uintptr, error) {
+	r, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return 0, errno
+	}
+	return r, nil
+}
+
+// CreateMap creates an LPM_TRIE map for IPv4 or, with ipv6 set, IPv6
+// keys and returns its file descriptor. Creating maps needs CAP_BPF.
+func CreateMap(ipv6 bool, valueSize, maxEntries int) (fd int, err error) {
+	attr := mapCreateAttr{
+		mapType:    unix.BPF_MAP_TYPE_LPM_TRIE,
+		keySize:    KeySize4,
+		valueSize:  uint32(valueSize),
+		maxEntries: uint32(maxEntries),
+		mapFlags:   unix.BPF_F_NO_PREALLOC,
+	}
+	if ipv6 {
+		attr.keySize = KeySize6
+	}
+	r, err := bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, fmt.Errorf("ebpf: create map: %w", err)
+	}
+	return int(r), nil
+}
+
+// Export writes the IPv4 or, with ipv6 set, the IPv6 routes of t into
+// the LPM_TRIE map mapFD, existing keys are overwritten. value encodes a
+// payload into the map value, it must return exactly the value size of
+// the map. Keys in the map that are not in t are left alone. Export
+// returns the number of routes written before the first error.
+func Export[V any](t *zart.Table[V], mapFD int, ipv6 bool, value func(V) []byte) (n int, err error) {
+	for key, val := range Entries(t, ipv6) {
+		v := value(val)
+		attr := mapElemAttr{
+			mapFD: uint32(mapFD),
+			key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+			flags: unix.BPF_ANY,
+		}
+		if len(v) > 0 {
+			attr.value = uint64(uintptr(unsafe.Pointer(&v[0])))
+		}
+		_, err := bpf(unix.BPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+		runtime.KeepAlive(key)
+		runtime.KeepAlive(v)
+		if err != nil {
+			return n, fmt.Errorf("ebpf: update map: %w", err)
+		}
+		n++
+	}
+	return n, nil
+}
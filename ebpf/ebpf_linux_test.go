@@ -0,0 +1,32 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestExport(t *testing.T) {
+	fd, err := CreateMap(false, 4, 16)
+	if err != nil {
+		t.Skipf("cannot create LPM_TRIE map: %v", err)
+	}
+	defer unix.Close(fd)
+
+	tbl := zart.New[uint32]()
+	defer tbl.Close()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	tbl.Insert(netip.MustParsePrefix("10.1.0.0/16"), 2)
+	tbl.Insert(netip.MustParsePrefix("2001:db8::/32"), 3)
+
+	n, err := Export(tbl, fd, false, func(v uint32) []byte {
+		return binary.NativeEndian.AppendUint32(nil, v)
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("Export = %d, %v, want 2 routes", n, err)
+	}
+}
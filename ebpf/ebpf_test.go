@@ -0,0 +1,49 @@
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestEntries(t *testing.T) {
+	tbl := zart.New[uint32]()
+	defer tbl.Close()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	tbl.Insert(netip.MustParsePrefix("192.168.1.0/24"), 2)
+	tbl.Insert(netip.MustParsePrefix("2001:db8::/32"), 3)
+
+	var keys4 [][]byte
+	for key, val := range Entries(tbl, false) {
+		if len(key) != KeySize4 {
+			t.Fatalf("IPv4 key of %d bytes", len(key))
+		}
+		keys4 = append(keys4, append([]byte(nil), key...))
+		if val == 3 {
+			t.Errorf("IPv6 route in IPv4 entries")
+		}
+	}
+	want := binary.NativeEndian.AppendUint32(nil, 24)
+	want = append(want, 192, 168, 1, 0)
+	if len(keys4) != 2 || !bytes.Equal(keys4[1], want) {
+		t.Errorf("IPv4 keys = %x, want second key %x", keys4, want)
+	}
+
+	n := 0
+	for key, val := range Entries(tbl, true) {
+		n++
+		if len(key) != KeySize6 || val != 3 || binary.NativeEndian.Uint32(key) != 32 {
+			t.Errorf("IPv6 entry %x = %d", key, val)
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d IPv6 entries, want 1", n)
+	}
+
+	if got := AppendKey(nil, netip.MustParsePrefix("10.1.2.3/8")); !bytes.Equal(got[4:], []byte{10, 0, 0, 0}) {
+		t.Errorf("AppendKey does not mask, got %x", got)
+	}
+}
@@ -0,0 +1,124 @@
+package zart
+
+import (
+	"math/bits"
+	"net/netip"
+	"slices"
+)
+
+// MultiTable is a routing table holding a set of values per prefix, the
+// equal-cost next hops of a route. It is a Table of value slices: a
+// lookup still costs a single longest-prefix match, the set is selected
+// from on the Go side.
+//
+// The sets are never modified in place, Add and Remove store a new slice.
+// Slices returned by LookupMulti stay valid and must not be modified.
+// Like Table, a MultiTable is not safe for concurrent use.
+type MultiTable[V comparable] struct {
+	t *Table[[]V]
+}
+
+// NewMulti returns an empty MultiTable.
+func NewMulti[V comparable]() *MultiTable[V] {
+	return &MultiTable[V]{t: New[[]V]()}
+}
+
+// Close releases the table, see Table.Close.
+func (m *MultiTable[V]) Close() {
+	m.t.Close()
+}
+
+// Table returns the underlying table, for the queries MultiTable does not
+// wrap. Its values must not be modified.
+func (m *MultiTable[V]) Table() *Table[[]V] {
+	return m.t
+}
+
+// Size returns the number of prefixes in the table.
+func (m *MultiTable[V]) Size() int {
+	return m.t.Size()
+}
+
+// Add adds val to the set of pfx and reports whether it was added, false
+// means it was already present. Values keep the order they were added in.
+func (m *MultiTable[V]) Add(pfx netip.Prefix, val V) bool {
+	// a value already present changes nothing: no event, no log record
+	// and no invalidated caches
+	if slices.Contains(m.Get(pfx), val) {
+		return false
+	}
+	added := false
+	m.t.Modify(pfx, func(old []V, _ bool) ([]V, bool) {
+		added = true
+		return append(slices.Clip(old), val), false
+	})
+	return added
+}
+
+// Remove removes val from the set of pfx and reports whether it was
+// present. The prefix is deleted with its last value.
+func (m *MultiTable[V]) Remove(pfx netip.Prefix, val V) bool {
+	// like Add, a missing value is not a change
+	old := m.Get(pfx)
+	i := slices.Index(old, val)
+	if i < 0 {
+		return false
+	}
+	if len(old) == 1 {
+		return m.t.Delete(pfx)
+	}
+	m.t.Insert(pfx, slices.Delete(slices.Clone(old), i, i+1))
+	return true
+}
+
+// Set replaces the set of pfx with vals, duplicates are dropped. An empty
+// vals deletes pfx.
+func (m *MultiTable[V]) Set(pfx netip.Prefix, vals []V) {
+	if len(vals) == 0 {
+		m.t.Delete(pfx)
+		return
+	}
+	set := make([]V, 0, len(vals))
+	for _, v := range vals {
+		if !slices.Contains(set, v) {
+			set = append(set, v)
+		}
+	}
+	m.t.Insert(pfx, set)
+}
+
+// Delete removes pfx with all its values and reports whether it was
+// present.
+func (m *MultiTable[V]) Delete(pfx netip.Prefix) bool {
+	return m.t.Delete(pfx)
+}
+
+// Get returns the set of exactly pfx, see Table.Get.
+func (m *MultiTable[V]) Get(pfx netip.Prefix) []V {
+	vals, _ := m.t.Get(pfx)
+	return vals
+}
+
+// LookupMulti performs a longest-prefix match for addr and returns all
+// values of the matching prefix, nil if no prefix matched.
+func (m *MultiTable[V]) LookupMulti(addr netip.Addr) []V {
+	vals, _ := m.t.Lookup(addr)
+	return vals
+}
+
+// LookupECMP performs a longest-prefix match for addr and selects one
+// value of the matching prefix by flowHash, so all packets of a flow take
+// the same next hop. flowHash should be uniformly distributed over all 64
+// bits, e.g. a hash of the flow's 5-tuple.
+//
+// The selection is hash-threshold as in RFC 2992: the hash space is split
+// into one contiguous region per value, so adding or removing a next hop
+// moves fewer flows than a modulo selection would.
+func (m *MultiTable[V]) LookupECMP(addr netip.Addr, flowHash uint64) (val V, ok bool) {
+	vals, _ := m.t.Lookup(addr)
+	if len(vals) == 0 {
+		return val, false
+	}
+	i, _ := bits.Mul64(flowHash, uint64(len(vals)))
+	return vals[i], true
+}
@@ -0,0 +1,93 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestMultiTable(t *testing.T) {
+	m := NewMulti[string]()
+	defer m.Close()
+
+	def, site := mpp("0.0.0.0/0"), mpp("10.1.0.0/16")
+	for _, nh := range []string{"a", "b", "c", "b"} {
+		m.Add(site, nh)
+	}
+	m.Set(def, []string{"x", "x", "y"})
+
+	if got := m.LookupMulti(mpa("10.1.2.3")); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("LookupMulti = %v, want [a b c]", got)
+	}
+	if got := m.Get(def); !slices.Equal(got, []string{"x", "y"}) {
+		t.Errorf("Set did not drop duplicates: %v", got)
+	}
+
+	before := m.LookupMulti(mpa("10.1.2.3"))
+	if !m.Remove(site, "b") || m.Remove(site, "b") || m.Remove(mpp("10.2.0.0/16"), "b") {
+		t.Errorf("Remove reported the wrong presence")
+	}
+	if !slices.Equal(before, []string{"a", "b", "c"}) {
+		t.Errorf("Remove modified a returned set: %v", before)
+	}
+	m.Remove(site, "a")
+	m.Remove(site, "c")
+	if m.Size() != 1 {
+		t.Errorf("removing the last value kept the prefix, size %d", m.Size())
+	}
+	if got := m.LookupMulti(mpa("10.1.2.3")); !slices.Equal(got, []string{"x", "y"}) {
+		t.Errorf("LookupMulti after removal = %v", got)
+	}
+	if got := m.LookupMulti(mpa("2001:db8::1")); got != nil {
+		t.Errorf("LookupMulti of an unrouted address = %v", got)
+	}
+}
+
+func TestMultiTableNoOp(t *testing.T) {
+	m := NewMulti[string]()
+	defer m.Close()
+	site := mpp("10.1.0.0/16")
+	m.Add(site, "a")
+
+	events := 0
+	m.t.hook(func(Event[[]string]) { events++ })
+	changes := m.t.changes
+	if m.Add(site, "a") || m.Remove(site, "b") || m.Remove(mpp("10.2.0.0/16"), "a") {
+		t.Errorf("no-op Add or Remove reported a change")
+	}
+	if events != 0 || m.t.changes != changes || m.Size() != 1 {
+		t.Errorf("no-op Add and Remove sent %d events, changes %d to %d", events, changes, m.t.changes)
+	}
+}
+
+func TestLookupECMP(t *testing.T) {
+	m := NewMulti[int]()
+	defer m.Close()
+	pfx := mpp("10.0.0.0/8")
+	for nh := range 4 {
+		m.Add(pfx, nh)
+	}
+
+	prng := rand.New(rand.NewPCG(43, 43))
+	var hits [4]int
+	for range 4000 {
+		h := prng.Uint64()
+		nh, ok := m.LookupECMP(mpa("10.9.9.9"), h)
+		if !ok {
+			t.Fatal("LookupECMP found no route")
+		}
+		if again, _ := m.LookupECMP(mpa("10.1.1.1"), h); again != nh {
+			t.Fatalf("flow hash %x selected %d and %d", h, nh, again)
+		}
+		hits[nh]++
+	}
+	for nh, n := range hits {
+		if n < 800 || n > 1200 {
+			t.Errorf("next hop %d selected %d of 4000 times", nh, n)
+		}
+	}
+
+	if _, ok := m.LookupECMP(mpa("192.0.2.1"), 1); ok {
+		t.Errorf("LookupECMP matched an unrouted address")
+	}
+}
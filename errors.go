@@ -0,0 +1,131 @@
+package zart
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// Errors of the Try methods, wrapped in a *PrefixError where a prefix is
+// at fault.
+var (
+	ErrInvalidPrefix = errors.New("zart: invalid prefix")
+	ErrHostBits      = errors.New("zart: prefix has host bits set")
+	ErrNilTable      = errors.New("zart: nil table")
+	ErrClosed        = errors.New("zart: table is closed")
+	ErrMemoryLimit   = errors.New("zart: table memory limit exceeded")
+	ErrFrozen        = errors.New("zart: table is frozen")
+)
+
+// ErrClosed is also returned by all other methods with an error result
+// once a table is closed. The methods without one panic with ErrClosed
+// instead of passing the released trie to C, so a recovered value can be
+// tested with errors.Is.
+
+// PrefixError reports a prefix rejected by a Try method.
+type PrefixError struct {
+	Addr netip.Addr
+	Bits int
+	Err  error // ErrInvalidPrefix or ErrHostBits
+}
+
+func (e *PrefixError) Error() string {
+	if !e.Addr.IsValid() {
+		return e.Err.Error() + ": invalid address"
+	}
+	return fmt.Sprintf("%v: %s/%d", e.Err, e.Addr, e.Bits)
+}
+
+func (e *PrefixError) Unwrap() error {
+	return e.Err
+}
+
+// MakePrefix returns addr/bits, or a *PrefixError if addr is invalid or
+// bits is out of range for its family. Unlike netip.PrefixFrom it tells
+// why the prefix is invalid.
+func MakePrefix(addr netip.Addr, bits int) (netip.Prefix, error) {
+	if !addr.IsValid() || bits < 0 || bits > addr.BitLen() {
+		return netip.Prefix{}, &PrefixError{Addr: addr, Bits: bits, Err: ErrInvalidPrefix}
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
+
+// Check returns the error a Try method would return for pfx: ErrNilTable,
+// ErrClosed, or a *PrefixError for an invalid prefix or, on a strict
+// table, one with host bits set.
+func (t *Table[V]) Check(pfx netip.Prefix) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	switch {
+	case !pfx.IsValid():
+		return &PrefixError{Addr: pfx.Addr(), Bits: pfx.Bits(), Err: ErrInvalidPrefix}
+	case t.opts.strict && pfx != pfx.Masked():
+		return &PrefixError{Addr: pfx.Addr(), Bits: pfx.Bits(), Err: ErrHostBits}
+	}
+	return nil
+}
+
+// usable returns ErrNilTable or ErrClosed for a table that can't be used.
+func (t *Table[V]) usable() error {
+	switch {
+	case t == nil:
+		return ErrNilTable
+	case t.closed:
+		return ErrClosed
+	}
+	return nil
+}
+
+// TryInsert is Insert returning an error instead of ignoring pfx, see
+// Check, ErrFrozen, or ErrMemoryLimit for a new prefix over the limit
+// set with WithMaxMemory.
+func (t *Table[V]) TryInsert(pfx netip.Prefix, val V) error {
+	if err := t.Check(pfx); err != nil {
+		return err
+	}
+	if t.Frozen() {
+		return ErrFrozen
+	}
+	if !t.room(pfx) {
+		return ErrMemoryLimit
+	}
+	t.Insert(pfx, val)
+	return nil
+}
+
+// TryInsertAddr is TryInsert for addr/bits, bits out of range for the
+// family of addr are reported as for MakePrefix.
+func (t *Table[V]) TryInsertAddr(addr netip.Addr, bits int, val V) error {
+	pfx, err := MakePrefix(addr, bits)
+	if err != nil {
+		return err
+	}
+	return t.TryInsert(pfx, val)
+}
+
+// TryDelete is Delete returning an error for a prefix it would ignore,
+// or ErrFrozen.
+func (t *Table[V]) TryDelete(pfx netip.Prefix) (bool, error) {
+	if err := t.Check(pfx); err != nil {
+		return false, err
+	}
+	if t.Frozen() {
+		return false, ErrFrozen
+	}
+	return t.Delete(pfx), nil
+}
+
+// TryGet is Get returning an error for a prefix it would ignore.
+func (t *Table[V]) TryGet(pfx netip.Prefix) (val V, ok bool, err error) {
+	if err := t.Check(pfx); err != nil {
+		return val, false, err
+	}
+	val, ok = t.Get(pfx)
+	return val, ok, nil
+}
+
+// accepts reports whether the inserts without an error take pfx.
+func (t *Table[V]) accepts(pfx netip.Prefix) bool {
+	return pfx.IsValid() && !(t.opts.strict && pfx != pfx.Masked())
+}
@@ -0,0 +1,104 @@
+package zart
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestTryInsert(t *testing.T) {
+	tbl := New[int]()
+	if err := tbl.TryInsertAddr(mpa("10.0.0.0"), 40, 1); !errors.Is(err, ErrInvalidPrefix) {
+		t.Errorf("TryInsertAddr with /40 = %v", err)
+	} else if got := err.Error(); got != "zart: invalid prefix: 10.0.0.0/40" {
+		t.Errorf("error text %q", got)
+	}
+	if err := tbl.TryInsert(netip.Prefix{}, 1); !errors.Is(err, ErrInvalidPrefix) {
+		t.Errorf("TryInsert of the zero prefix = %v", err)
+	}
+	if err := tbl.TryInsert(mpp("192.168.1.55/24"), 1); err != nil {
+		t.Errorf("TryInsert with host bits on a lax table = %v", err)
+	}
+	if _, ok, err := tbl.TryGet(mpp("192.168.1.0/24")); !ok || err != nil {
+		t.Errorf("TryGet = %v, %v", ok, err)
+	}
+
+	tbl.Close()
+	if err := tbl.TryInsert(mpp("10.0.0.0/8"), 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("TryInsert after Close = %v", err)
+	}
+	var nilTable *Table[int]
+	if _, err := nilTable.TryDelete(mpp("10.0.0.0/8")); !errors.Is(err, ErrNilTable) {
+		t.Errorf("TryDelete on a nil table = %v", err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tbl := New[int](WithStrict())
+	defer tbl.Close()
+
+	var perr *PrefixError
+	if err := tbl.TryInsert(mpp("192.168.1.55/24"), 1); !errors.Is(err, ErrHostBits) || !errors.As(err, &perr) || perr.Bits != 24 {
+		t.Errorf("TryInsert with host bits on a strict table = %v", err)
+	}
+	tbl.Insert(mpp("10.1.2.3/8"), 2)
+	tbl.InsertBatch([]RouteEntry[int]{{mpp("10.1.2.3/16"), 3}, {mpp("10.2.0.0/16"), 4}})
+	tbl.Modify(mpp("10.3.0.1/16"), func(int, bool) (int, bool) { return 5, false })
+	if tbl.Size() != 1 {
+		t.Errorf("strict table took prefixes with host bits, size %d", tbl.Size())
+	}
+	c := tbl.Clone()
+	defer c.Close()
+	if c.TryInsert(mpp("10.1.2.3/8"), 1) == nil {
+		t.Error("clone of a strict table is not strict")
+	}
+}
+
+func TestUseAfterClose(t *testing.T) {
+	tbl := New[int]()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	v1 := tbl.InsertPersist(mpp("10.1.0.0/16"), 2)
+	defer v1.Close()
+
+	tbl.Close()
+	tbl.Close()
+	if v, ok := v1.Get(mpp("10.0.0.0/8")); !ok || v != 1 {
+		t.Errorf("version after closing its table twice: Get = %d, %v", v, ok)
+	}
+
+	var buf bytes.Buffer
+	if err := tbl.ExportJSON(&buf); !errors.Is(err, ErrClosed) {
+		t.Errorf("ExportJSON after Close = %v", err)
+	}
+	if _, err := tbl.MarshalBinary(); !errors.Is(err, ErrClosed) {
+		t.Errorf("MarshalBinary after Close = %v", err)
+	}
+	snap, _ := v1.MarshalBinary()
+	if err := tbl.UnmarshalBinary(snap); !errors.Is(err, ErrClosed) {
+		t.Errorf("UnmarshalBinary after Close = %v", err)
+	}
+	if _, _, err := tbl.LookupString("10.1.2.3"); !errors.Is(err, ErrClosed) {
+		t.Errorf("LookupString after Close = %v", err)
+	}
+
+	for name, call := range map[string]func(){
+		"Lookup": func() { tbl.Lookup(mpa("10.1.2.3")) },
+		"Insert": func() { tbl.Insert(mpp("10.0.0.0/8"), 1) },
+		"Size":   func() { tbl.Size() },
+		"Clone":  func() { tbl.Clone() },
+		"All": func() {
+			for range tbl.All() {
+			}
+		},
+	} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); !errors.Is(err, ErrClosed) {
+					t.Errorf("%s after Close panics with %v, want ErrClosed", name, err)
+				}
+			}()
+			call()
+		}()
+	}
+}
@@ -0,0 +1,53 @@
+package zart_test
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/gx14ac/zart"
+)
+
+func Example() {
+	tbl := zart.New[string]()
+	defer tbl.Close()
+
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), "core")
+	tbl.Insert(netip.MustParsePrefix("10.1.0.0/16"), "site-1")
+
+	nh, ok := tbl.Lookup(netip.MustParseAddr("10.1.2.3"))
+	fmt.Println(nh, ok)
+
+	tbl.Delete(netip.MustParsePrefix("10.1.0.0/16"))
+	nh, ok = tbl.Lookup(netip.MustParseAddr("10.1.2.3"))
+	fmt.Println(nh, ok)
+
+	// Output:
+	// site-1 true
+	// core true
+}
+
+func ExampleTable_Fprint() {
+	tbl := zart.New[string]()
+	defer tbl.Close()
+
+	for pfx, nh := range map[string]string{
+		"10.0.0.0/8":    "core",
+		"10.1.0.0/16":   "site-1",
+		"10.1.2.0/24":   "lab",
+		"10.2.0.0/16":   "site-2",
+		"192.0.2.0/24":  "test-net",
+		"2001:db8::/32": "doc",
+	} {
+		tbl.Insert(netip.MustParsePrefix(pfx), nh)
+	}
+	tbl.Fprint(os.Stdout)
+
+	// Output:
+	// 10.0.0.0/8       core
+	//   10.1.0.0/16    site-1
+	//     10.1.2.0/24  lab
+	//   10.2.0.0/16    site-2
+	// 192.0.2.0/24     test-net
+	// 2001:db8::/32    doc
+}
@@ -0,0 +1,158 @@
+package zart
+
+import (
+	"bufio"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// jsonRoute is the record written by ExportJSON.
+type jsonRoute[V any] struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Value  V            `json:"value"`
+}
+
+// ExportJSON writes all routes as a JSON array of
+// {"prefix": ..., "value": ...} objects in the order of All, one object
+// per line. Values are encoded with encoding/json.
+func (t *Table[V]) ExportJSON(w io.Writer) error {
+	return t.ExportJSONContext(context.Background(), w)
+}
+
+// ExportJSONContext is ExportJSON checking ctx between batches of
+// routes. A cancelled export returns ctx.Err() and leaves an incomplete
+// array in w.
+func (t *Table[V]) ExportJSONContext(ctx context.Context, w io.Writer) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	var cerr error
+	sep := "["
+	for pfx, val := range t.allContext(ctx, &cerr) {
+		bw.WriteString(sep)
+		if err := enc.Encode(jsonRoute[V]{pfx, val}); err != nil {
+			return err
+		}
+		sep = ","
+	}
+	if cerr != nil {
+		bw.Flush()
+		return cerr
+	}
+	if sep == "[" {
+		bw.WriteString(sep)
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// ImportJSON inserts the routes of a JSON array in the format of
+// ExportJSON. The array is decoded as a stream, routes are inserted in
+// batches as they are read. On error the table may hold part of the
+// input.
+func (t *Table[V]) ImportJSON(r io.Reader) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('[') {
+		return errors.New("zart: JSON routes must be an array")
+	}
+
+	batch := make([]RouteEntry[V], 0, batchSize)
+	for i := 1; dec.More(); i++ {
+		var e jsonRoute[V]
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("zart: route %d: %w", i, err)
+		}
+		if !e.Prefix.IsValid() {
+			return fmt.Errorf("zart: route %d: missing prefix", i)
+		}
+		if batch = append(batch, RouteEntry[V](e)); len(batch) == cap(batch) {
+			t.InsertBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	t.InsertBatch(batch)
+	_, err := dec.Token()
+	return err
+}
+
+// ExportCSV writes all routes as prefix,value records in the order of
+// All, without a header. Values are written as text: strings as they
+// are, encoding.TextMarshaler payloads with MarshalText and all others
+// as JSON.
+func (t *Table[V]) ExportCSV(w io.Writer) error {
+	return t.ExportCSVContext(context.Background(), w)
+}
+
+// ExportCSVContext is ExportCSV checking ctx between batches of routes,
+// a cancelled export returns ctx.Err() after the records written so far.
+func (t *Table[V]) ExportCSVContext(ctx context.Context, w io.Writer) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	var cerr error
+	cw := csv.NewWriter(w)
+	for pfx, val := range t.allContext(ctx, &cerr) {
+		text, err := marshalText(val)
+		if err != nil {
+			return err
+		}
+		if err := cw.Write([]string{pfx.String(), text}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if cerr != nil {
+		return cerr
+	}
+	return cw.Error()
+}
+
+// ImportCSV inserts the prefix,value records read from r, the value text
+// is parsed the way ExportCSV writes it. Empty lines and lines starting
+// with # are skipped. On error the table may hold part of the input.
+func (t *Table[V]) ImportCSV(r io.Reader) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	_, err := t.Load(r, CSV[V](), nil)
+	return err
+}
+
+// marshalText returns the CSV text for v.
+func marshalText[V any](v V) (string, error) {
+	switch p := any(&v).(type) {
+	case *string:
+		return *p, nil
+	case encoding.TextMarshaler:
+		b, err := p.MarshalText()
+		return string(b), err
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// unmarshalText parses text written by marshalText into v.
+func unmarshalText[V any](text string, v *V) error {
+	switch p := any(v).(type) {
+	case *string:
+		*p = text
+		return nil
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText([]byte(text))
+	}
+	return json.Unmarshal([]byte(text), v)
+}
@@ -0,0 +1,94 @@
+package zart
+
+import (
+	"bytes"
+	"maps"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestExportImportJSON(t *testing.T) {
+	type hop struct {
+		Via    netip.Addr `json:"via"`
+		Metric int        `json:"metric"`
+	}
+	tbl := New[hop]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), hop{mpa("192.0.2.1"), 10})
+	tbl.Insert(mpp("2001:db8::/32"), hop{mpa("fe80::1"), 20})
+
+	var buf bytes.Buffer
+	if err := tbl.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"prefix":"10.0.0.0/8","value":{"via":"192.0.2.1","metric":10}}
+,{"prefix":"2001:db8::/32","value":{"via":"fe80::1","metric":20}}
+]
+`
+	if buf.String() != want {
+		t.Errorf("ExportJSON =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	got := New[hop]()
+	defer got.Close()
+	if err := got.ImportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(collectAll(got), collectAll(tbl)) {
+		t.Errorf("JSON round trip: got %v, want %v", collectAll(got), collectAll(tbl))
+	}
+
+	empty := New[hop]()
+	defer empty.Close()
+	buf.Reset()
+	if err := empty.ExportJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("ExportJSON of empty table = %q, %v", buf.String(), err)
+	}
+
+	for _, in := range []string{`{}`, `[{"value":{}}]`, `[{"prefix":"10.0.0.0/33"}]`} {
+		if err := got.ImportJSON(strings.NewReader(in)); err == nil {
+			t.Errorf("ImportJSON(%s) succeeded", in)
+		}
+	}
+}
+
+func TestExportImportCSV(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), "ten, or so")
+	tbl.Insert(mpp("2001:db8::/32"), "doc")
+
+	var buf bytes.Buffer
+	if err := tbl.ExportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "10.0.0.0/8,\"ten, or so\"\n2001:db8::/32,doc\n"; buf.String() != want {
+		t.Errorf("ExportCSV = %q, want %q", buf.String(), want)
+	}
+	got := New[string]()
+	defer got.Close()
+	if err := got.ImportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(collectAll(got), collectAll(tbl)) {
+		t.Errorf("CSV round trip: got %v, want %v", collectAll(got), collectAll(tbl))
+	}
+
+	// non-string values are parsed as text or JSON
+	hops := New[netip.Addr]()
+	defer hops.Close()
+	in := "# prefix,next hop\n10.0.0.0/8,192.0.2.1\n\n::/0,fe80::1\n"
+	if err := hops.ImportCSV(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := hops.Lookup(mpa("2001:db8::1")); v != mpa("fe80::1") {
+		t.Errorf("Lookup = %s, want fe80::1", v)
+	}
+	metrics := New[int]()
+	defer metrics.Close()
+	err := metrics.ImportCSV(strings.NewReader("10.0.0.0/8,1\n10.0.0.0/33,2\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ImportCSV with bad prefix: err = %v, want line 2", err)
+	}
+}
@@ -0,0 +1,59 @@
+package zart
+
+import "net/netip"
+
+// Extract returns a new table with pfx and every prefix covered by it,
+// with their tags, configured like t; t is left as it is. The routes are
+// collected and inserted in single calls into the tries. Payloads that
+// implement Cloner[V] are cloned as by Clone, so both tables can be
+// modified separately.
+func (t *Table[V]) Extract(pfx netip.Prefix) *Table[V] {
+	return t.extract(pfx, true)
+}
+
+// Detach is Extract moving the prefixes into the new table, and their
+// payloads without cloning them: they are removed from t as by
+// DeleteSubtree.
+func (t *Table[V]) Detach(pfx netip.Prefix) *Table[V] {
+	x := t.extract(pfx, false)
+	t.DeleteSubtree(pfx)
+	return x
+}
+
+func (t *Table[V]) extract(pfx netip.Prefix, clone bool) *Table[V] {
+	x := (&Table[V]{trie: newTrie(t.opts), vals: new(registry[V]), opts: t.opts}).audited()
+	if !pfx.IsValid() {
+		return x
+	}
+	q := makeRoute(pfx.Masked(), 0)
+	routes := fill(64, func(out []route) int { return t.trie.subnets(&q, out) })
+	for i := range routes {
+		val := t.vals.get(routes[i].val)
+		if cl, ok := any(val).(Cloner[V]); ok && clone {
+			val = cl.Clone()
+		}
+		routes[i].val = x.vals.alloc(val)
+		if t.tags != nil {
+			if tags := t.tags.byPfx[routes[i].prefix()]; len(tags) > 0 {
+				x.tagSet().set(routes[i].prefix(), tags)
+			}
+		}
+	}
+	x.trie.insertBulk(routes, make([]uint32, len(routes)))
+	return x
+}
+
+// Extract is like Table.Extract, the new table is a plain Table owned by
+// the caller.
+func (c *ConcurrentTable[V]) Extract(pfx netip.Prefix) *Table[V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Extract(pfx)
+}
+
+// Detach is like Table.Detach.
+func (c *ConcurrentTable[V]) Detach(pfx netip.Prefix) *Table[V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Detach(pfx)
+}
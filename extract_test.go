@@ -0,0 +1,76 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	prng := rand.New(rand.NewPCG(95, 95))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, i)
+	}
+	tbl.InsertTagged(mpp("10.1.0.0/16"), -1, "customer")
+	tbl.Insert(mpp("10.0.0.0/8"), -2)
+	tbl.Insert(mpp("0.0.0.0/0"), -3)
+
+	block := mpp("10.0.0.0/8")
+	want := map[string]int{}
+	for pfx, val := range tbl.Subnets(block) {
+		want[pfx.String()] = val
+	}
+	size := tbl.Size()
+
+	x := tbl.Extract(block)
+	defer x.Close()
+	if x.Size() != len(want) || tbl.Size() != size {
+		t.Fatalf("Extract: %d prefixes, want %d; table has %d, want %d", x.Size(), len(want), tbl.Size(), size)
+	}
+	for pfx, val := range x.All() {
+		if w, ok := want[pfx.String()]; !ok || w != val {
+			t.Errorf("extracted %s = %d, want %d, %v", pfx, val, w, ok)
+		}
+	}
+	if tags := x.Tags(mpp("10.1.0.0/16")); len(tags) != 1 || tags[0] != "customer" {
+		t.Errorf("extracted tags = %v", tags)
+	}
+	x.Insert(mpp("10.9.0.0/16"), 9)
+	if _, ok := tbl.Get(mpp("10.9.0.0/16")); ok {
+		t.Error("insert into the extract is visible in the table")
+	}
+
+	d := tbl.Detach(block)
+	defer d.Close()
+	if d.Size() != len(want) || tbl.Size() != size-len(want) {
+		t.Errorf("Detach: %d prefixes, table has %d", d.Size(), tbl.Size())
+	}
+	if v, ok := tbl.Lookup(mpa("10.1.1.1")); !ok || v != -3 {
+		t.Errorf("after Detach the block resolves to %d, %v, want the default route", v, ok)
+	}
+	if tags := tbl.Tags(mpp("10.1.0.0/16")); tags != nil {
+		t.Errorf("detached tags stayed: %v", tags)
+	}
+
+	empty := tbl.Extract(mpp("10.0.0.0/8"))
+	defer empty.Close()
+	if empty.Size() != 0 {
+		t.Errorf("Extract of a detached block has %d prefixes", empty.Size())
+	}
+}
+
+func TestExtractClones(t *testing.T) {
+	tbl := New[clonedPayload]()
+	defer tbl.Close()
+	one := 1
+	tbl.Insert(mpp("10.0.0.0/8"), clonedPayload{&one})
+
+	x := tbl.Extract(mpp("10.0.0.0/7"))
+	defer x.Close()
+	v, _ := x.Get(mpp("10.0.0.0/8"))
+	*v.n = 2
+	if one != 1 {
+		t.Error("Extract did not clone the payload")
+	}
+}
@@ -0,0 +1,192 @@
+package zart
+
+import "net/netip"
+
+// Table4 is a table of IPv4 prefixes only, for callers that never handle
+// the other family. Lookups take the address as the trie does, a uint32
+// with 10.0.0.1 as 0x0a000001, and go straight to the IPv4 trie without
+// the conversions and the family dispatch of the netip.Addr methods of
+// Table. Inserts drop IPv6 prefixes.
+//
+// The nodes are those of Table, only the empty root of the other family
+// is left over. Table returns the table underneath for everything else;
+// like Table, a Table4 is not safe for concurrent use.
+type Table4[V any] struct {
+	t *Table[V]
+}
+
+// New4 returns an empty Table4 configured by opts as for New.
+func New4[V any](opts ...Option) *Table4[V] {
+	return &Table4[V]{t: New[V](opts...)}
+}
+
+// Table returns the table holding the prefixes. IPv6 prefixes inserted
+// into it are visible to its methods only.
+func (t *Table4[V]) Table() *Table[V] { return t.t }
+
+// Close releases the table, see Table.Close.
+func (t *Table4[V]) Close() { t.t.Close() }
+
+// Size returns the number of prefixes in the table.
+func (t *Table4[V]) Size() int { return t.t.Size4() }
+
+// Insert is Table.Insert for IPv4 prefixes, others are ignored.
+func (t *Table4[V]) Insert(pfx netip.Prefix, val V) {
+	if pfx.Addr().Is4() {
+		t.t.Insert(pfx, val)
+	}
+}
+
+// Delete is Table.Delete for IPv4 prefixes, others are never present.
+func (t *Table4[V]) Delete(pfx netip.Prefix) bool {
+	return pfx.Addr().Is4() && t.t.Delete(pfx)
+}
+
+// Get is Table.Get for IPv4 prefixes, others are never present.
+func (t *Table4[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	if !pfx.Addr().Is4() {
+		return val, false
+	}
+	return t.t.Get(pfx)
+}
+
+// Lookup performs a longest-prefix match for addr like Table.Lookup.
+func (t *Table4[V]) Lookup(addr uint32) (val V, ok bool) {
+	if lt, on := t.t.timer(); on {
+		val, ok = t.lookup(addr)
+		lt.stop(true, ok)
+		return val, ok
+	}
+	return t.lookup(addr)
+}
+
+func (t *Table4[V]) lookup(addr uint32) (val V, ok bool) {
+	slot, ok := t.t.trie.lookup4(addr)
+	if !ok {
+		return val, false
+	}
+	return t.t.payload(slot), true
+}
+
+// LookupPrefix is like Lookup and also returns the matching prefix.
+func (t *Table4[V]) LookupPrefix(addr uint32) (pfx netip.Prefix, val V, ok bool) {
+	if lt, on := t.t.timer(); on {
+		pfx, val, ok = t.lookupPrefix(addr)
+		lt.stop(true, ok)
+		return pfx, val, ok
+	}
+	return t.lookupPrefix(addr)
+}
+
+func (t *Table4[V]) lookupPrefix(addr uint32) (pfx netip.Prefix, val V, ok bool) {
+	slot, bits, ok := t.t.trie.lookupPrefix4(addr)
+	if !ok {
+		return pfx, val, false
+	}
+	pfx, _ = addr4(addr).Prefix(int(bits))
+	return pfx, t.t.payload(slot), true
+}
+
+// Contains reports whether any prefix in the table covers addr.
+func (t *Table4[V]) Contains(addr uint32) bool {
+	return t.t.trie.contains4(addr)
+}
+
+// LookupBatch is Table.LookupBatch4.
+func (t *Table4[V]) LookupBatch(addrs []uint32, results []Result[V]) {
+	t.t.LookupBatch4(addrs, results)
+}
+
+// addr4 returns the netip.Addr of the IPv4 address a.
+func addr4(a uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)})
+}
+
+// Table6 is Table4 for IPv6: lookups take the address as its 16 bytes in
+// network order, inserts drop IPv4 prefixes. An IPv4-mapped address is
+// an IPv6 address to it, whatever WithMapped says.
+type Table6[V any] struct {
+	t *Table[V]
+}
+
+// New6 returns an empty Table6 configured by opts as for New.
+func New6[V any](opts ...Option) *Table6[V] {
+	return &Table6[V]{t: New[V](opts...)}
+}
+
+// Table returns the table holding the prefixes. IPv4 prefixes inserted
+// into it are visible to its methods only.
+func (t *Table6[V]) Table() *Table[V] { return t.t }
+
+// Close releases the table, see Table.Close.
+func (t *Table6[V]) Close() { t.t.Close() }
+
+// Size returns the number of prefixes in the table.
+func (t *Table6[V]) Size() int { return t.t.Size6() }
+
+// Insert is Table.Insert for IPv6 prefixes, others are ignored.
+func (t *Table6[V]) Insert(pfx netip.Prefix, val V) {
+	if pfx.Addr().Is6() {
+		t.t.Insert(pfx, val)
+	}
+}
+
+// Delete is Table.Delete for IPv6 prefixes, others are never present.
+func (t *Table6[V]) Delete(pfx netip.Prefix) bool {
+	return pfx.Addr().Is6() && t.t.Delete(pfx)
+}
+
+// Get is Table.Get for IPv6 prefixes, others are never present.
+func (t *Table6[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	if !pfx.Addr().Is6() {
+		return val, false
+	}
+	return t.t.Get(pfx)
+}
+
+// Lookup performs a longest-prefix match for addr like Table.Lookup.
+func (t *Table6[V]) Lookup(addr [16]byte) (val V, ok bool) {
+	if lt, on := t.t.timer(); on {
+		val, ok = t.lookup(&addr)
+		lt.stop(false, ok)
+		return val, ok
+	}
+	return t.lookup(&addr)
+}
+
+func (t *Table6[V]) lookup(addr *[16]byte) (val V, ok bool) {
+	slot, ok := t.t.trie.lookup6(addr)
+	if !ok {
+		return val, false
+	}
+	return t.t.payload(slot), true
+}
+
+// LookupPrefix is like Lookup and also returns the matching prefix.
+func (t *Table6[V]) LookupPrefix(addr [16]byte) (pfx netip.Prefix, val V, ok bool) {
+	if lt, on := t.t.timer(); on {
+		pfx, val, ok = t.lookupPrefix(&addr)
+		lt.stop(false, ok)
+		return pfx, val, ok
+	}
+	return t.lookupPrefix(&addr)
+}
+
+func (t *Table6[V]) lookupPrefix(addr *[16]byte) (pfx netip.Prefix, val V, ok bool) {
+	slot, bits, ok := t.t.trie.lookupPrefix6(addr)
+	if !ok {
+		return pfx, val, false
+	}
+	pfx, _ = netip.AddrFrom16(*addr).Prefix(int(bits))
+	return pfx, t.t.payload(slot), true
+}
+
+// Contains reports whether any prefix in the table covers addr.
+func (t *Table6[V]) Contains(addr [16]byte) bool {
+	return t.t.trie.contains6(&addr)
+}
+
+// LookupBatch is Table.LookupBatch6.
+func (t *Table6[V]) LookupBatch(addrs [][16]byte, results []Result[V]) {
+	t.t.LookupBatch6(addrs, results)
+}
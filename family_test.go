@@ -0,0 +1,86 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestTable4Table6(t *testing.T) {
+	prng := rand.New(rand.NewPCG(111, 111))
+	ref := New[int]()
+	defer ref.Close()
+	t4, t6 := New4[int](), New6[int]()
+	defer t4.Close()
+	defer t6.Close()
+	for i, pfx := range randomPrefixes(prng, 5000) {
+		ref.Insert(pfx, i)
+		t4.Insert(pfx, i)
+		t6.Insert(pfx, i)
+	}
+	if t4.Size() != ref.Size4() || t6.Size() != ref.Size6() {
+		t.Fatalf("sizes %d/%d, want %d/%d", t4.Size(), t6.Size(), ref.Size4(), ref.Size6())
+	}
+	if t4.Table().Size6() != 0 || t6.Table().Size4() != 0 {
+		t.Fatalf("prefixes of the other family inserted")
+	}
+
+	addrs4 := make([]uint32, 2000)
+	addrs6 := make([][16]byte, 2000)
+	for i := range addrs4 {
+		addrs4[i] = prng.Uint32()
+		for j := range addrs6[i] {
+			addrs6[i][j] = byte(prng.Uint32())
+		}
+		if i%2 == 0 {
+			// stay close to the prefixes for hits
+			addrs6[i][0] = 0x20
+		}
+	}
+
+	res := make([]Result[int], len(addrs4))
+	t4.LookupBatch(addrs4, res)
+	for i, a := range addrs4 {
+		addr := addr4(a)
+		want, wantOK := ref.Lookup(addr)
+		if got, ok := t4.Lookup(a); got != want || ok != wantOK {
+			t.Fatalf("Table4.Lookup(%s) = %d, %v, want %d, %v", addr, got, ok, want, wantOK)
+		}
+		wantPfx, _, _ := ref.LookupPrefix(addr)
+		if pfx, _, _ := t4.LookupPrefix(a); pfx != wantPfx {
+			t.Fatalf("Table4.LookupPrefix(%s) = %s, want %s", addr, pfx, wantPfx)
+		}
+		if t4.Contains(a) != wantOK || res[i] != (Result[int]{want, wantOK}) {
+			t.Fatalf("Table4.Contains or LookupBatch of %s disagree with Lookup", addr)
+		}
+	}
+
+	t6.LookupBatch(addrs6, res)
+	for i, a := range addrs6 {
+		addr := netip.AddrFrom16(a)
+		want, wantOK := ref.Lookup(addr)
+		if got, ok := t6.Lookup(a); got != want || ok != wantOK {
+			t.Fatalf("Table6.Lookup(%s) = %d, %v, want %d, %v", addr, got, ok, want, wantOK)
+		}
+		wantPfx, _, _ := ref.LookupPrefix(addr)
+		if pfx, _, _ := t6.LookupPrefix(a); pfx != wantPfx {
+			t.Fatalf("Table6.LookupPrefix(%s) = %s, want %s", addr, pfx, wantPfx)
+		}
+		if t6.Contains(a) != wantOK || res[i] != (Result[int]{want, wantOK}) {
+			t.Fatalf("Table6.Contains or LookupBatch of %s disagree with Lookup", addr)
+		}
+	}
+
+	v4, v6 := mpp("10.0.0.0/8"), mpp("2001:db8::/32")
+	t4.Insert(v4, 1)
+	t6.Insert(v6, 1)
+	if _, ok := t4.Get(v6); ok || t4.Delete(v6) {
+		t.Errorf("Table4 holds an IPv6 prefix")
+	}
+	if _, ok := t6.Get(v4); ok || t6.Delete(v4) {
+		t.Errorf("Table6 holds an IPv4 prefix")
+	}
+	if !t4.Delete(v4) || !t6.Delete(v6) {
+		t.Errorf("Delete missed a prefix of the own family")
+	}
+}
@@ -0,0 +1,62 @@
+package zart
+
+// Freeze makes the table read-only, for tables that are loaded once and
+// then only looked up. The routes are copied as by Compact into a trie
+// built for lookups: the C trie allocates its nodes from an arena with a
+// block the size of the whole trie, so they lie side by side in memory,
+// and the pure-Go trie is path compressed as with WithPathCompression.
+// TTLs are dropped, the memory limit no longer applies and the channels
+// of Watch are closed, as no change follows.
+//
+// Afterwards the methods modifying the table panic with ErrFrozen, or
+// return it if they have an error result; Compact does nothing. Lookups,
+// walks, Clone and the persistent versions are unaffected. A clone is not
+// frozen, it is the way to modify the routes again. Freezing a frozen
+// table does nothing.
+func (t *Table[V]) Freeze() {
+	if t.closed || t.Frozen() {
+		return
+	}
+	v4, v6 := t.trie.stats()
+	o := t.opts
+	o.arena = v4.Bytes + v6.Bytes + 1
+	o.compress = true
+	t.rebuild(o)
+	t.trie.frozen = true
+	t.ttl, t.mem = nil, nil
+	if t.watch != nil {
+		t.watch.closeAll()
+	}
+}
+
+// Frozen reports whether Freeze was called on the table.
+func (t *Table[V]) Frozen() bool {
+	return t.trie != nil && t.trie.frozen
+}
+
+// mutable panics with ErrFrozen if the table is frozen. The trie rejects
+// changes on its own; mutable is for the modifications that touch the
+// payloads or tags before they reach it.
+func (t *Table[V]) mutable() {
+	if t.Frozen() {
+		panic(ErrFrozen)
+	}
+}
+
+// writable is usable for the modifications with an error result.
+func (t *Table[V]) writable() error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	if t.Frozen() {
+		return ErrFrozen
+	}
+	return nil
+}
+
+// Freeze is like Table.Freeze.
+func (c *ConcurrentTable[V]) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.Freeze()
+}
@@ -0,0 +1,94 @@
+package zart
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+	prng := rand.New(rand.NewPCG(114, 114))
+	pfxs := randomPrefixes(prng, 5000)
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range pfxs {
+		tbl.Insert(pfx, i)
+	}
+	tbl.InsertWithTTL(mpp("192.0.2.0/24"), -1, time.Hour)
+	want := maps.Collect(tbl.All())
+	ch := tbl.Watch(context.Background())
+
+	tbl.Freeze()
+	if !tbl.Frozen() {
+		t.Fatalf("Frozen = false after Freeze")
+	}
+	if got := maps.Collect(tbl.All()); !maps.Equal(got, want) {
+		t.Fatalf("Freeze changed the contents: %d routes, want %d", len(got), len(want))
+	}
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		addr := pfx.Addr()
+		p, v, ok := tbl.LookupPrefix(addr)
+		if w, wok := want[p]; ok != wok || ok && w != v {
+			t.Fatalf("LookupPrefix(%s) = %s, %d, %v after Freeze", addr, p, v, ok)
+		}
+	}
+	if err := tbl.Fsck(); err != nil {
+		t.Errorf("Fsck after Freeze: %v", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("Watch channel still open after Freeze")
+	}
+	if n := tbl.Expire(time.Now().Add(2 * time.Hour)); n != 0 {
+		t.Errorf("Expire after Freeze removed %d prefixes", n)
+	}
+
+	for name, call := range map[string]func(){
+		"Insert":        func() { tbl.Insert(mpp("10.0.0.0/8"), 1) },
+		"Delete":        func() { tbl.Delete(pfxs[0]) },
+		"DeleteSubtree": func() { tbl.DeleteSubtree(mpp("::/0")) },
+		"InsertBatch":   func() { tbl.InsertBatch([]RouteEntry[int]{{Prefix: pfxs[1], Value: 1}}) },
+		"Modify":        func() { tbl.Modify(pfxs[2], func(int, bool) (int, bool) { return 0, false }) },
+		"SetTags":       func() { tbl.SetTags(pfxs[3], "feed") },
+	} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); !errors.Is(err, ErrFrozen) {
+					t.Errorf("%s on a frozen table panics with %v, want ErrFrozen", name, err)
+				}
+			}()
+			call()
+		}()
+	}
+	tbl.Compact()
+	if !tbl.Frozen() {
+		t.Errorf("Compact unfroze the table")
+	}
+	if err := tbl.TryInsert(mpp("10.0.0.0/8"), 1); !errors.Is(err, ErrFrozen) {
+		t.Errorf("TryInsert = %v, want ErrFrozen", err)
+	}
+	if _, err := tbl.TryDelete(pfxs[0]); !errors.Is(err, ErrFrozen) {
+		t.Errorf("TryDelete = %v, want ErrFrozen", err)
+	}
+	if got := maps.Collect(tbl.All()); !maps.Equal(got, want) {
+		t.Errorf("rejected modifications changed the contents")
+	}
+
+	c := tbl.Clone()
+	defer c.Close()
+	if c.Frozen() {
+		t.Fatalf("Clone of a frozen table is frozen")
+	}
+	c.Insert(mpp("10.0.0.0/8"), -3)
+	if v, _ := c.Get(mpp("10.0.0.0/8")); v != -3 {
+		t.Errorf("Insert into the clone stored %d, want -3", v)
+	}
+	v := tbl.InsertPersist(mpp("198.51.100.0/24"), 2)
+	defer v.Close()
+	if _, ok := v.Get(mpp("198.51.100.0/24")); !ok {
+		t.Errorf("InsertPersist on a frozen table lost the prefix")
+	}
+}
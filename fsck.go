@@ -0,0 +1,130 @@
+package zart
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// fsckProblem mirrors bart_fsck_problem_t, an inconsistency of the trie.
+type fsckProblem struct {
+	path             [16]byte
+	depth, is4, code uint8
+	_                [5]byte
+	have, want       uint64
+}
+
+// Codes of a fsckProblem, BART_FSCK_* in include/bart.h.
+const (
+	fsckPrefixCount = iota + 1
+	fsckChildCount
+	fsckPrefixIndex
+	fsckDepth
+	fsckEmptyNode
+	fsckLeaf
+	fsckRefs
+	fsckSize
+)
+
+// FsckError is the report of Fsck, one line per problem found.
+type FsckError struct {
+	Problems []string
+}
+
+func (e *FsckError) Error() string {
+	return fmt.Sprintf("zart: fsck: %d problems:\n\t%s", len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+// Fsck checks the internal consistency of the table and returns a
+// *FsckError listing every problem, or nil. It walks the trie validating
+// the bitsets of the nodes against their arrays, the depth of nodes and
+// path compressed leaves, the reference counts and the size counters,
+// and checks that every route refers to its own payload slot in use and
+// that the tags belong to prefixes in the table. A healthy table never
+// fails; Fsck is for debugging suspected corruption and for fuzzing.
+// It takes time linear in the size of the table.
+func (t *Table[V]) Fsck() error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	var problems []string
+	for _, p := range fill(16, t.trie.fsck) {
+		problems = append(problems, p.String())
+	}
+
+	free := make(map[uint32]bool, len(t.vals.free))
+	for _, slot := range t.vals.free {
+		if free[slot] {
+			problems = append(problems, fmt.Sprintf("payload slot %d is free twice", slot))
+		}
+		free[slot] = true
+	}
+	if t.vals.hits != nil && len(t.vals.hits) != len(t.vals.vals) {
+		problems = append(problems, fmt.Sprintf("%d hit counters for %d payload slots", len(t.vals.hits), len(t.vals.vals)))
+	}
+	owner := map[uint32]netip.Prefix{}
+	for _, r := range t.routes() {
+		pfx := r.prefix()
+		switch other, dup := owner[r.val]; {
+		case int(r.val) >= len(t.vals.vals):
+			problems = append(problems, fmt.Sprintf("%s: payload slot %d out of %d", pfx, r.val, len(t.vals.vals)))
+		case free[r.val]:
+			problems = append(problems, fmt.Sprintf("%s: payload slot %d is free", pfx, r.val))
+		case dup:
+			problems = append(problems, fmt.Sprintf("%s: payload slot %d is also used by %s", pfx, r.val, other))
+		}
+		owner[r.val] = pfx
+	}
+
+	if t.tags != nil {
+		for pfx, tags := range t.tags.byPfx {
+			if _, ok := t.slot(pfx); !ok {
+				problems = append(problems, fmt.Sprintf("%s: tagged %v but not in the table", pfx, tags))
+			}
+			for _, tag := range tags {
+				if _, ok := t.tags.byTag[tag][pfx]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: tag %q missing from the tag index", pfx, tag))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &FsckError{Problems: problems}
+	}
+	return nil
+}
+
+// String describes p with the position of its node as a prefix.
+func (p *fsckProblem) String() string {
+	var addr netip.Addr
+	if p.is4 != 0 {
+		addr = netip.AddrFrom4([4]byte(p.path[:4]))
+	} else {
+		addr = netip.AddrFrom16(p.path)
+	}
+	at := fmt.Sprintf("node %s", netip.PrefixFrom(addr, min(8*int(p.depth), addr.BitLen())))
+	switch p.code {
+	case fsckPrefixCount:
+		return fmt.Sprintf("%s: prefix bitset has %d bits for %d prefixes", at, p.have, p.want)
+	case fsckChildCount:
+		return fmt.Sprintf("%s: child bitset has %d bits for %d children", at, p.have, p.want)
+	case fsckPrefixIndex:
+		return fmt.Sprintf("%s: prefix at invalid index %d", at, p.have)
+	case fsckDepth:
+		return fmt.Sprintf("%s: at depth %d, the deepest is %d", at, p.have, p.want)
+	case fsckEmptyNode:
+		return fmt.Sprintf("%s: empty node", at)
+	case fsckLeaf:
+		return fmt.Sprintf("%s: leaf with /%d in a slot for more than /%d", at, p.have, p.want)
+	case fsckRefs:
+		return fmt.Sprintf("%s: reference count %d", at, p.have)
+	case fsckSize:
+		family := "IPv6"
+		if p.is4 != 0 {
+			family = "IPv4"
+		}
+		return fmt.Sprintf("%s trie has %d prefixes, its size counter %d", family, p.have, p.want)
+	}
+	return fmt.Sprintf("%s: problem %d (%d, %d)", at, p.code, p.have, p.want)
+}
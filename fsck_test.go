@@ -0,0 +1,48 @@
+package zart
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestFsck(t *testing.T) {
+	prng := rand.New(rand.NewPCG(76, 76))
+	pfxs := randomPrefixes(prng, 3000)
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range pfxs {
+		tbl.InsertTagged(pfx, i, "x")
+	}
+	for _, pfx := range pfxs[:1000] {
+		tbl.Delete(pfx)
+	}
+	v1 := tbl.InsertPersist(pfxs[0], 0)
+	defer v1.Close()
+	if err := tbl.Fsck(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.Fsck(); err != nil {
+		t.Fatal(err)
+	}
+
+	// free the slot of a route and tag a missing prefix behind the
+	// table's back
+	c := tbl.Clone()
+	defer c.Close()
+	slot, _ := c.slot(pfxs[2000])
+	c.vals.free = append(c.vals.free, slot)
+	c.tags.byPfx[pfxs[1]] = []Tag{"y"}
+
+	var ferr *FsckError
+	if err := c.Fsck(); !errors.As(err, &ferr) || len(ferr.Problems) != 3 {
+		t.Fatalf("Fsck of a corrupted table = %v", err)
+	}
+	for _, want := range []string{"is free", "not in the table", "missing from the tag index"} {
+		if !strings.Contains(ferr.Error(), want) {
+			t.Errorf("report %q lacks %q", ferr.Error(), want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+)
+
+// LoadCSV builds a DB from the GeoLite2 CSV files: the locations file of
+// one language, like GeoLite2-Country-Locations-en.csv, and the blocks
+// files, GeoLite2-Country-Blocks-IPv4.csv and -IPv6.csv. column names the
+// column of the locations file the networks map to, like
+// "country_iso_code", or "subdivision_1_iso_code" for the city files.
+//
+// Networks without a location of their own get that of their registered
+// country. Networks whose location has an empty column are left out.
+func LoadCSV(locations io.Reader, column string, blocks ...io.Reader) (*DB, error) {
+	db := newDB()
+	codes := map[string]Code{}
+	err := readCSV(locations, []string{"geoname_id", column}, func(rec []string) error {
+		if rec[1] != "" {
+			codes[rec[0]] = db.Names.Intern(rec[1])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	in := &inserter{db: db}
+	for _, r := range blocks {
+		err := readCSV(r, []string{"network", "geoname_id", "registered_country_geoname_id"}, func(rec []string) error {
+			pfx, err := netip.ParsePrefix(rec[0])
+			if err != nil {
+				return fmt.Errorf("network %q", rec[0])
+			}
+			id := rec[1]
+			if id == "" {
+				id = rec[2]
+			}
+			if c, ok := codes[id]; ok {
+				in.add(pfx, c)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	in.flush()
+	return db, nil
+}
+
+// readCSV calls fn with the columns of every record of r picked by the
+// names in its header line.
+func readCSV(r io.Reader, names []string, fn func(rec []string) error) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	cols := make([]int, len(names))
+	for i, name := range names {
+		if cols[i] = slices.Index(header, name); cols[i] < 0 {
+			return fmt.Errorf("%w: no column %q", ErrFormat, name)
+		}
+	}
+	picked := make([]string, len(names))
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrFormat, err)
+		}
+		for i, col := range cols {
+			picked[i] = rec[col]
+		}
+		if err := fn(picked); err != nil {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("%w: line %d: %w", ErrFormat, line, err)
+		}
+	}
+}
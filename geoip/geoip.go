@@ -0,0 +1,100 @@
+// Package geoip builds zart tables for geolocation lookups from MaxMind
+// databases, the binary MMDB files as well as the GeoLite2 CSV downloads.
+//
+//	db, err := geoip.LoadMMDB(data, "country", "iso_code")
+//	if err != nil {
+//		return err
+//	}
+//	country, ok := db.Lookup(addr) // "DE"
+//
+// The tables map the networks of the database to small codes interned by
+// a Names, every distinct string, like a country code, is stored once
+// however many networks carry it.
+package geoip
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrFormat is returned, wrapped, for databases that do not parse.
+var ErrFormat = errors.New("geoip: malformed database")
+
+// Code is an interned string, see Names.
+type Code uint32
+
+// Names interns strings as Codes, numbered densely from 0 in the order
+// of their first use. The zero Names is ready to use.
+type Names struct {
+	codes map[string]Code
+	names []string
+}
+
+// Intern returns the code of s, assigning the next one if s is new.
+func (n *Names) Intern(s string) Code {
+	if c, ok := n.codes[s]; ok {
+		return c
+	}
+	if n.codes == nil {
+		n.codes = map[string]Code{}
+	}
+	c := Code(len(n.names))
+	n.codes[s] = c
+	n.names = append(n.names, s)
+	return c
+}
+
+// Name returns the string interned as c, "" for an unknown code.
+func (n *Names) Name(c Code) string {
+	if int(c) >= len(n.names) {
+		return ""
+	}
+	return n.names[c]
+}
+
+// Len returns the number of distinct strings.
+func (n *Names) Len() int {
+	return len(n.names)
+}
+
+// DB is a geolocation table. Its Table looks up IPv4-mapped IPv6
+// addresses as IPv4, like the MMDB readers do. It is safe for concurrent
+// lookups once loaded.
+type DB struct {
+	Table *zart.Table[Code]
+	Names *Names
+}
+
+func newDB() *DB {
+	return &DB{Table: zart.New[Code](zart.WithMapped(zart.MappedAsIPv4)), Names: &Names{}}
+}
+
+// Lookup returns the string of the most specific network containing
+// addr.
+func (db *DB) Lookup(addr netip.Addr) (string, bool) {
+	c, ok := db.Table.Lookup(addr)
+	if !ok {
+		return "", false
+	}
+	return db.Names.Name(c), true
+}
+
+// inserter buffers the routes for InsertBatch.
+type inserter struct {
+	db    *DB
+	batch []zart.RouteEntry[Code]
+}
+
+func (in *inserter) add(pfx netip.Prefix, c Code) {
+	in.batch = append(in.batch, zart.RouteEntry[Code]{Prefix: pfx, Value: c})
+	if len(in.batch) == 4096 {
+		in.flush()
+	}
+}
+
+func (in *inserter) flush() {
+	in.db.Table.InsertBatch(in.batch)
+	in.batch = in.batch[:0]
+}
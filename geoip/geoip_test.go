@@ -0,0 +1,214 @@
+package geoip
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// mmdbWriter builds MMDB files for the tests.
+type mmdbWriter struct {
+	ipVersion  int
+	recordSize int
+	nodes      [][2]uint // records, node numbers or data offsets + dataRef
+	data       []byte
+}
+
+// dataRef marks a record as a data offset until the node count is known.
+const dataRef = 1 << 40
+
+func newWriter(ipVersion, recordSize int) *mmdbWriter {
+	return &mmdbWriter{ipVersion: ipVersion, recordSize: recordSize, nodes: [][2]uint{{0, 0}}}
+}
+
+func ctrl(b []byte, typ, size int) []byte {
+	if typ > 7 {
+		return append(b, byte(size), byte(typ-7))
+	}
+	return append(b, byte(typ<<5|size))
+}
+
+func encString(b []byte, s string) []byte { return append(ctrl(b, typeString, len(s)), s...) }
+
+func encUint(b []byte, typ int, u uint32) []byte {
+	return append(ctrl(b, typ, 4), byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+}
+
+// record appends {key0: {key1: val}} to the data section and returns its
+// offset.
+func (w *mmdbWriter) record(key0, key1, val string) uint {
+	off := uint(len(w.data))
+	w.data = ctrl(w.data, typeMap, 1)
+	w.data = encString(w.data, key0)
+	w.data = ctrl(w.data, typeMap, 1)
+	w.data = encString(w.data, key1)
+	w.data = encString(w.data, val)
+	return off
+}
+
+// pointer appends a pointer to off, which must be below 2048, and
+// returns its own offset.
+func (w *mmdbWriter) pointer(off uint) uint {
+	at := uint(len(w.data))
+	w.data = append(w.data, byte(typePointer<<5|off>>8&7), byte(off))
+	return at
+}
+
+// path returns the node at bits-1 of addr, creating the nodes on the way.
+func (w *mmdbWriter) path(addr []byte, bits int) (node uint, side int) {
+	for d := 0; ; d++ {
+		side = int(addr[d/8] >> (7 - d%8) & 1)
+		if d == bits-1 {
+			return node, side
+		}
+		next := w.nodes[node][side]
+		if next == 0 {
+			next = uint(len(w.nodes))
+			w.nodes = append(w.nodes, [2]uint{})
+			w.nodes[node][side] = next
+		}
+		node = next
+	}
+}
+
+func (w *mmdbWriter) insert(pfx netip.Prefix, off uint) {
+	addr := pfx.Addr().AsSlice()
+	bits := pfx.Bits()
+	if w.ipVersion == 6 && pfx.Addr().Is4() {
+		addr = netip.AddrFrom16(pfx.Addr().As16()).AsSlice()
+		clear(addr[10:12]) // ::a.b.c.d, not mapped
+		bits += 96
+	}
+	node, side := w.path(addr, bits)
+	w.nodes[node][side] = dataRef + off
+}
+
+// alias links the node of pfx to that of target, which must exist.
+func (w *mmdbWriter) alias(pfx, target netip.Prefix) {
+	tn, ts := w.path(target.Addr().AsSlice(), target.Bits())
+	node, side := w.path(pfx.Addr().AsSlice(), pfx.Bits())
+	w.nodes[node][side] = w.nodes[tn][ts]
+}
+
+func (w *mmdbWriter) bytes() []byte {
+	n := uint(len(w.nodes))
+	rec := func(r uint) uint {
+		switch {
+		case r >= dataRef:
+			return r - dataRef + n + 16
+		case r == 0:
+			return n // no node points back to the root
+		}
+		return r
+	}
+	var b []byte
+	for _, nd := range w.nodes {
+		l, r := rec(nd[0]), rec(nd[1])
+		switch w.recordSize {
+		case 24:
+			b = append(b, byte(l>>16), byte(l>>8), byte(l), byte(r>>16), byte(r>>8), byte(r))
+		case 28:
+			b = append(b, byte(l>>16), byte(l>>8), byte(l), byte(l>>20&0xf0|r>>24&0x0f), byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+	b = append(b, make([]byte, 16)...)
+	b = append(b, w.data...)
+	b = append(b, metadataStart...)
+	b = ctrl(b, typeMap, 4)
+	b = encString(b, "node_count")
+	b = encUint(b, typeUint32, uint32(n))
+	b = encString(b, "record_size")
+	b = encUint(b, typeUint16, uint32(w.recordSize))
+	b = encString(b, "ip_version")
+	b = encUint(b, typeUint16, uint32(w.ipVersion))
+	b = encString(b, "database_type")
+	return encString(b, "Test-Country")
+}
+
+func check(t *testing.T, db *DB, want map[string]string) {
+	t.Helper()
+	for addr, name := range want {
+		got, ok := db.Lookup(netip.MustParseAddr(addr))
+		if got != name || ok != (name != "") {
+			t.Errorf("Lookup(%s) = %q, %v, want %q", addr, got, ok, name)
+		}
+	}
+}
+
+func TestLoadMMDB(t *testing.T) {
+	for _, size := range []int{24, 28} {
+		w := newWriter(6, size)
+		de := w.record("country", "iso_code", "DE")
+		us := w.record("country", "iso_code", "US")
+		w.insert(netip.MustParsePrefix("1.2.0.0/16"), de)
+		w.insert(netip.MustParsePrefix("1.3.0.0/16"), w.pointer(de))
+		w.insert(netip.MustParsePrefix("8.8.8.0/24"), us)
+		w.insert(netip.MustParsePrefix("2001:db8::/32"), w.pointer(us))
+		w.insert(netip.MustParsePrefix("2001:db9::/32"), w.record("continent", "code", "EU"))
+		w.alias(netip.MustParsePrefix("::ffff:0:0/96"), netip.MustParsePrefix("::/96"))
+		w.alias(netip.MustParsePrefix("2002::/16"), netip.MustParsePrefix("::/96"))
+
+		db, err := LoadMMDB(w.bytes(), "country", "iso_code")
+		if err != nil {
+			t.Fatalf("record size %d: %v", size, err)
+		}
+		if got := db.Table.Size(); got != 4 {
+			t.Errorf("record size %d: Size = %d, want 4 without the aliases", size, got)
+		}
+		if db.Names.Len() != 2 {
+			t.Errorf("record size %d: %d names, want DE and US", size, db.Names.Len())
+		}
+		check(t, db, map[string]string{
+			"1.2.3.4":        "DE",
+			"1.3.3.4":        "DE",
+			"8.8.8.8":        "US",
+			"9.9.9.9":        "",
+			"2001:db8::1":    "US",
+			"2001:db9::1":    "", // no country
+			"2001:db10::1":   "",
+			"::ffff:1.2.3.4": "DE", // mapped, found as 1.2.3.4 in the IPv4 table
+		})
+	}
+
+	w := newWriter(4, 24)
+	w.insert(netip.MustParsePrefix("10.0.0.0/8"), w.record("country", "iso_code", "ZZ"))
+	db, err := LoadMMDB(w.bytes(), "country", "iso_code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, db, map[string]string{"10.1.1.1": "ZZ", "11.1.1.1": ""})
+
+	if _, err := LoadMMDB([]byte("no database"), "country", "iso_code"); !errors.Is(err, ErrFormat) {
+		t.Errorf("LoadMMDB of garbage = %v, want ErrFormat", err)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	const locations = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union
+2921044,en,EU,Europe,DE,Germany,1
+6252001,en,NA,"North America",US,"United States",0
+6255148,en,EU,Europe,,Europe,0
+`
+	const blocks4 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.2.0.0/16,2921044,2921044,,0,0
+8.8.8.0/24,,6252001,,0,0
+9.9.9.0/24,6255148,6255148,,0,0
+`
+	const blocks6 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+2001:db8::/32,6252001,6252001,,0,0
+`
+	db, err := LoadCSV(strings.NewReader(locations), "country_iso_code", strings.NewReader(blocks4), strings.NewReader(blocks6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, db, map[string]string{"1.2.3.4": "DE", "8.8.8.8": "US", "9.9.9.9": "", "2001:db8::1": "US"})
+
+	_, err = LoadCSV(strings.NewReader(locations), "country_iso_code", strings.NewReader("network,geoname_id,registered_country_geoname_id\nbogus,1,1\n"))
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("LoadCSV with a bad network = %v, want ErrFormat", err)
+	}
+	if _, err := LoadCSV(strings.NewReader(locations), "no_such_column"); !errors.Is(err, ErrFormat) {
+		t.Errorf("LoadCSV with a missing column = %v, want ErrFormat", err)
+	}
+}
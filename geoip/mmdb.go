@@ -0,0 +1,358 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+)
+
+// metadataStart marks the metadata at the end of an MMDB file.
+var metadataStart = []byte("\xab\xcd\xefMaxMind.com")
+
+// LoadMMDB builds a DB from the MaxMind DB file in data, mapping every
+// network to the string found at path in its record, like "country",
+// "iso_code" for the country databases or "subdivisions", "0", "iso_code"
+// for the region of the city databases; numeric elements index arrays.
+// Networks whose record has no string at path are left out.
+//
+// The IPv4 networks of IPv6 databases are loaded once, as IPv4, without
+// their aliases under ::ffff:0:0/96 and 2002::/16.
+func LoadMMDB(data []byte, path ...string) (*DB, error) {
+	m, err := openMMDB(data)
+	if err != nil {
+		return nil, err
+	}
+	db := newDB()
+	w := walker{mmdb: m, path: path, in: &inserter{db: db}, codes: map[uint]code{}}
+	if m.ipVersion == 6 {
+		for range 96 {
+			if w.ipv4 >= m.nodeCount {
+				break
+			}
+			w.ipv4, _ = m.node(w.ipv4)
+		}
+	}
+	var addr [16]byte
+	if err := w.walk(0, &addr, 0); err != nil {
+		return nil, err
+	}
+	w.in.flush()
+	return db, nil
+}
+
+// mmdb is the search tree of an MMDB file, rooted at node 0.
+type mmdb struct {
+	tree       []byte
+	data       decoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  int
+}
+
+func openMMDB(data []byte) (*mmdb, error) {
+	i := bytes.LastIndex(data, metadataStart)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no metadata", ErrFormat)
+	}
+	md, _, err := decoder(data[i+len(metadataStart):]).decode(0)
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := md.(map[string]any)
+	nodeCount, _ := meta["node_count"].(uint64)
+	recordSize, _ := meta["record_size"].(uint64)
+	ipVersion, _ := meta["ip_version"].(uint64)
+	if recordSize != 24 && recordSize != 28 && recordSize != 32 || ipVersion != 4 && ipVersion != 6 {
+		return nil, fmt.Errorf("%w: record size %d, IP version %d", ErrFormat, recordSize, ipVersion)
+	}
+	treeSize := nodeCount * recordSize / 4
+	if treeSize+16 > uint64(i) {
+		return nil, fmt.Errorf("%w: search tree of %d nodes truncated", ErrFormat, nodeCount)
+	}
+	return &mmdb{
+		tree:       data[:treeSize],
+		data:       decoder(data[treeSize+16 : i]),
+		nodeCount:  uint(nodeCount),
+		recordSize: uint(recordSize),
+		ipVersion:  int(ipVersion),
+	}, nil
+}
+
+// node returns the records of node n, the child nodes for the bits 0
+// and 1.
+func (m *mmdb) node(n uint) (left, right uint) {
+	b := m.tree[n*m.recordSize/4:]
+	switch m.recordSize {
+	case 24:
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		left = uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		right = uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+		return left, right
+	}
+	return uint(binary.BigEndian.Uint32(b)), uint(binary.BigEndian.Uint32(b[4:]))
+}
+
+// code caches the lookup of a path in a data record.
+type code struct {
+	c  Code
+	ok bool
+}
+
+type walker struct {
+	*mmdb
+	path  []string
+	in    *inserter
+	ipv4  uint
+	codes map[uint]code
+}
+
+// walk visits the subtree of record rec at addr/depth.
+func (w *walker) walk(rec uint, addr *[16]byte, depth int) error {
+	switch {
+	case rec == w.nodeCount:
+		return nil
+	case rec > w.nodeCount:
+		return w.leaf(rec-w.nodeCount-16, addr, depth)
+	}
+	if w.ipVersion == 6 && rec == w.ipv4 && isIPv4Alias(addr, depth) {
+		return nil
+	}
+	bits := 32
+	if w.ipVersion == 6 {
+		bits = 128
+	}
+	if depth >= bits || rec*w.recordSize/4 >= uint(len(w.tree)) {
+		return fmt.Errorf("%w: bad search tree", ErrFormat)
+	}
+	left, right := w.node(rec)
+	if err := w.walk(left, addr, depth+1); err != nil {
+		return err
+	}
+	addr[depth/8] |= 0x80 >> (depth % 8)
+	err := w.walk(right, addr, depth+1)
+	addr[depth/8] &^= 0x80 >> (depth % 8)
+	return err
+}
+
+// isIPv4Alias reports whether addr/depth is one of the aliases of the
+// IPv4 subtree, ::ffff:0:0/96 for mapped and 2002::/16 for 6to4
+// addresses.
+func isIPv4Alias(addr *[16]byte, depth int) bool {
+	switch depth {
+	case 16:
+		return addr[0] == 0x20 && addr[1] == 0x02
+	case 96:
+		return *(*[10]byte)(addr[:10]) == [10]byte{} && addr[10] == 0xff && addr[11] == 0xff
+	}
+	return false
+}
+
+func (w *walker) leaf(off uint, addr *[16]byte, depth int) error {
+	var pfx netip.Prefix
+	switch {
+	case w.ipVersion == 4:
+		pfx = netip.PrefixFrom(netip.AddrFrom4(*(*[4]byte)(addr[:4])), depth)
+	case depth >= 96 && *(*[12]byte)(addr[:12]) == [12]byte{}:
+		pfx = netip.PrefixFrom(netip.AddrFrom4(*(*[4]byte)(addr[12:])), depth-96)
+	default:
+		pfx = netip.PrefixFrom(netip.AddrFrom16(*addr), depth)
+	}
+
+	c, cached := w.codes[off]
+	if !cached {
+		rec, _, err := w.data.decode(off)
+		if err != nil {
+			return err
+		}
+		if s, ok := lookupPath(rec, w.path); ok {
+			c = code{w.in.db.Names.Intern(s), true}
+		}
+		w.codes[off] = c
+	}
+	if c.ok {
+		w.in.add(pfx, c.c)
+	}
+	return nil
+}
+
+// lookupPath returns the string at path in a decoded record.
+func lookupPath(v any, path []string) (string, bool) {
+	for _, key := range path {
+		switch x := v.(type) {
+		case map[string]any:
+			v = x[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(x) {
+				return "", false
+			}
+			v = x[i]
+		default:
+			return "", false
+		}
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// decoder decodes the values of the data section of an MMDB file, which
+// pointers are relative to.
+type decoder []byte
+
+// Types of the MMDB data section.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decode decodes the value at off and returns it with the offset after
+// it. Maps decode to map[string]any, arrays to []any, the unsigned types
+// to uint64 except for uint128, which stays []byte, and int32 to int64.
+func (d decoder) decode(off uint) (v any, next uint, err error) {
+	typ, size, off, err := d.control(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == typePointer {
+		ptr, next, err := d.pointer(size, off)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr)
+		return v, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			var key, val any
+			if key, off, err = d.decode(off); err != nil {
+				return nil, 0, err
+			}
+			if val, off, err = d.decode(off); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key of type %T", ErrFormat, key)
+			}
+			m[k] = val
+		}
+		return m, off, nil
+	case typeArray:
+		a := make([]any, size)
+		for i := range a {
+			if a[i], off, err = d.decode(off); err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, off, nil
+	case typeBool:
+		return size != 0, off, nil
+	}
+
+	if off+size > uint(len(d)) {
+		return nil, 0, fmt.Errorf("%w: value at %d overruns the data section", ErrFormat, off)
+	}
+	b := d[off : off+size]
+	next = off + size
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return b, next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: double of %d bytes", ErrFormat, size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: float of %d bytes", ErrFormat, size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, next, nil
+	case typeInt32:
+		var u uint32
+		for _, c := range b {
+			u = u<<8 | uint32(c)
+		}
+		return int64(int32(u)), next, nil
+	}
+	return nil, 0, fmt.Errorf("%w: type %d at %d", ErrFormat, typ, off)
+}
+
+// control decodes the control byte at off into the type and the size of
+// the value, and the offset of its payload.
+func (d decoder) control(off uint) (typ, size, next uint, err error) {
+	if off >= uint(len(d)) {
+		return 0, 0, 0, fmt.Errorf("%w: offset %d out of the data section", ErrFormat, off)
+	}
+	b := d[off]
+	off++
+	typ = uint(b >> 5)
+	if typ == typeExtended {
+		if off >= uint(len(d)) {
+			return 0, 0, 0, fmt.Errorf("%w: truncated type at %d", ErrFormat, off)
+		}
+		typ = 7 + uint(d[off])
+		off++
+	}
+	size = uint(b & 0x1f)
+	if typ == typePointer {
+		return typ, size, off, nil
+	}
+	if size >= 29 {
+		n := size - 28
+		if off+n > uint(len(d)) {
+			return 0, 0, 0, fmt.Errorf("%w: truncated size at %d", ErrFormat, off)
+		}
+		var ext uint
+		for _, c := range d[off : off+n] {
+			ext = ext<<8 | uint(c)
+		}
+		size = [...]uint{29, 285, 65821}[n-1] + ext
+		off += n
+	}
+	return typ, size, off, nil
+}
+
+// pointer decodes a pointer with the bits of the control byte in size.
+func (d decoder) pointer(size, off uint) (ptr, next uint, err error) {
+	n := size>>3&3 + 1
+	if off+n > uint(len(d)) {
+		return 0, 0, fmt.Errorf("%w: truncated pointer at %d", ErrFormat, off)
+	}
+	if n < 4 {
+		ptr = size & 7
+	}
+	for _, c := range d[off : off+n] {
+		ptr = ptr<<8 | uint(c)
+	}
+	ptr += [...]uint{0, 2048, 526336, 0}[n-1]
+	return ptr, off + n, nil
+}
@@ -0,0 +1,109 @@
+//go:build cgo
+
+package zart
+
+import (
+	"iter"
+	"net/netip"
+	"runtime/cgo"
+)
+
+// HandleTable is a RawTable whose values are cgo.Handles of Go values of
+// type V. The trie stores the handle of every payload, so C code reading
+// the values holds no Go pointers and never breaks the cgo pointer rules;
+// a handle it gets back from the trie resolves to the payload with
+// cgo.Handle.Value.
+//
+// The table owns the handles: the handle of a value is deleted when the
+// value is overwritten or deleted and when the table is closed. Handles
+// returned by LookupHandle are only valid until then. The cleanup of a
+// table that is never closed frees the trie but not the handles, which
+// keep their values reachable.
+//
+// cgo.Handle needs the cgo runtime, HandleTable is missing from builds
+// with CGO_ENABLED=0. It is there with the purego tag as long as cgo is
+// enabled.
+type HandleTable[V any] struct {
+	raw *RawTable
+}
+
+// NewHandleTable returns an empty HandleTable, opts are as for NewRaw.
+func NewHandleTable[V any](opts ...Option) *HandleTable[V] {
+	return &HandleTable[V]{raw: NewRaw(opts...)}
+}
+
+// Close deletes the handles of all values and releases the trie. Closing
+// a table again does nothing.
+func (t *HandleTable[V]) Close() {
+	if t.raw.closed {
+		return
+	}
+	for _, h := range t.raw.All() {
+		cgo.Handle(h).Delete()
+	}
+	t.raw.Close()
+}
+
+// Size returns the number of prefixes in the table.
+func (t *HandleTable[V]) Size() int {
+	return t.raw.Size()
+}
+
+// Insert adds pfx with a new handle of val, the handle of a value it
+// overwrites is deleted. Invalid prefixes are ignored.
+func (t *HandleTable[V]) Insert(pfx netip.Prefix, val V) {
+	if !pfx.IsValid() {
+		return
+	}
+	if old, replaced := t.raw.Insert(pfx, uint64(cgo.NewHandle(val))); replaced {
+		cgo.Handle(old).Delete()
+	}
+}
+
+// Delete removes pfx and deletes the handle of its value, it reports
+// whether pfx was present.
+func (t *HandleTable[V]) Delete(pfx netip.Prefix) bool {
+	old, ok := t.raw.Delete(pfx)
+	if ok {
+		cgo.Handle(old).Delete()
+	}
+	return ok
+}
+
+// Get returns the value stored for exactly pfx.
+func (t *HandleTable[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	h, ok := t.raw.Get(pfx)
+	if !ok {
+		return val, false
+	}
+	return cgo.Handle(h).Value().(V), true
+}
+
+// Lookup performs a longest-prefix match for addr like RawTable.Lookup.
+func (t *HandleTable[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	h, ok := t.LookupHandle(addr)
+	if !ok {
+		return val, false
+	}
+	return h.Value().(V), true
+}
+
+// LookupHandle is Lookup returning the handle of the value, for passing
+// it on to C. The table keeps owning the handle, it must not be deleted.
+func (t *HandleTable[V]) LookupHandle(addr netip.Addr) (cgo.Handle, bool) {
+	h, ok := t.raw.Lookup(addr)
+	return cgo.Handle(h), ok
+}
+
+// All returns an iterator over all prefixes with their values, in the
+// order of Table.All. The table must not be modified during the
+// iteration.
+func (t *HandleTable[V]) All() iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		for pfx, h := range t.raw.All() {
+			if !yield(pfx, cgo.Handle(h).Value().(V)) {
+				return
+			}
+		}
+	}
+}
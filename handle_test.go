@@ -0,0 +1,70 @@
+//go:build cgo
+
+package zart
+
+import (
+	"runtime/cgo"
+	"testing"
+)
+
+type link struct {
+	ifName string
+	mtu    int
+}
+
+// deleted reports whether h was deleted, Value panics for those.
+func deleted(h cgo.Handle) (gone bool) {
+	defer func() { gone = recover() != nil }()
+	h.Value()
+	return false
+}
+
+func TestHandleTable(t *testing.T) {
+	tbl := NewHandleTable[*link]()
+	eth0 := &link{"eth0", 1500}
+	tbl.Insert(mpp("10.0.0.0/8"), eth0)
+	tbl.Insert(mpp("2001:db8::/32"), &link{"eth1", 9000})
+
+	if nh, ok := tbl.Lookup(mpa("10.1.2.3")); !ok || nh != eth0 {
+		t.Errorf("Lookup = %v, %v", nh, ok)
+	}
+	h, ok := tbl.LookupHandle(mpa("10.1.2.3"))
+	if !ok || h.Value().(*link) != eth0 {
+		t.Fatalf("LookupHandle = %v, %v", h, ok)
+	}
+
+	// overwriting deletes the old handle
+	tbl.Insert(mpp("10.0.0.0/8"), &link{"eth2", 1500})
+	if !deleted(h) {
+		t.Errorf("the handle of the overwritten value is alive")
+	}
+	if nh, _ := tbl.Get(mpp("10.0.0.0/8")); nh.ifName != "eth2" {
+		t.Errorf("Get = %v, want eth2", nh)
+	}
+
+	h, _ = tbl.LookupHandle(mpa("10.1.2.3"))
+	if !tbl.Delete(mpp("10.0.0.0/8")) || !deleted(h) {
+		t.Errorf("Delete left the handle alive")
+	}
+	if tbl.Delete(mpp("10.0.0.0/8")) {
+		t.Errorf("second Delete found the prefix")
+	}
+
+	n := 0
+	for _, nh := range tbl.All() {
+		if nh.mtu != 9000 {
+			t.Errorf("All yields %v", nh)
+		}
+		n++
+	}
+	if n != 1 || tbl.Size() != 1 {
+		t.Errorf("%d values in All, Size %d, want 1", n, tbl.Size())
+	}
+
+	h, _ = tbl.LookupHandle(mpa("2001:db8::1"))
+	tbl.Close()
+	tbl.Close()
+	if !deleted(h) {
+		t.Errorf("Close left a handle alive")
+	}
+}
@@ -0,0 +1,56 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+	"sync/atomic"
+)
+
+// CountHits enables per-prefix hit counters: from now on every Lookup,
+// LookupPrefix and LookupBatch that matches a prefix increments its
+// counter with an atomic add, so lookups under the read lock of a
+// ConcurrentTable keep counting correctly. Contains does not count.
+//
+// Replacing a value with Insert or Modify keeps the count of the prefix,
+// InsertBatch restarts it. Clones keep counting with counters of their
+// own, persistent versions share the counters of unchanged prefixes.
+func (t *Table[V]) CountHits() {
+	t.vals.countHits()
+}
+
+// Hits returns the number of lookups that matched exactly pfx, 0 if pfx
+// is not in the table or counting is off.
+func (t *Table[V]) Hits(pfx netip.Prefix) uint64 {
+	if t.vals.hits == nil {
+		return 0
+	}
+	slot, ok := t.slot(pfx)
+	if !ok {
+		return 0
+	}
+	return t.vals.hitCount(slot)
+}
+
+// Counters returns an iterator over all prefixes in the table with their
+// hit counts, in the order of All. Prefixes that were never matched are
+// included with 0, to find unused routes and rules. Counters yields
+// nothing if counting is off.
+func (t *Table[V]) Counters() iter.Seq2[netip.Prefix, uint64] {
+	return func(yield func(netip.Prefix, uint64) bool) {
+		if t.vals.hits == nil {
+			return
+		}
+		for _, r := range t.routes() {
+			if !yield(r.prefix(), t.vals.hitCount(r.val)) {
+				return
+			}
+		}
+	}
+}
+
+// ResetCounters sets all hit counters to zero.
+func (t *Table[V]) ResetCounters() {
+	for i := range t.vals.hits {
+		atomic.StoreUint64(&t.vals.hits[i], 0)
+	}
+}
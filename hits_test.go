@@ -0,0 +1,82 @@
+package zart
+
+import (
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+func TestHitCounters(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Lookup(mpa("10.1.1.1")) // not counted yet
+
+	tbl.CountHits()
+	tbl.Insert(mpp("10.1.0.0/16"), 2)
+	tbl.Insert(mpp("192.0.2.0/24"), 3)
+
+	for range 3 {
+		tbl.Lookup(mpa("10.1.1.1"))
+	}
+	tbl.LookupPrefix(mpa("10.2.2.2"))
+	tbl.LookupBatch([]netip.Addr{mpa("10.1.1.1"), mpa("10.3.3.3"), mpa("198.51.100.1")}, make([]Result[int], 3))
+	tbl.Contains(mpa("10.1.1.1"))
+
+	want := map[netip.Prefix]uint64{mpp("10.0.0.0/8"): 2, mpp("10.1.0.0/16"): 4, mpp("192.0.2.0/24"): 0}
+	got := map[netip.Prefix]uint64{}
+	for pfx, n := range tbl.Counters() {
+		got[pfx] = n
+	}
+	if len(got) != len(want) {
+		t.Errorf("Counters = %v, want %v", got, want)
+	}
+	for pfx, n := range want {
+		if got[pfx] != n || tbl.Hits(pfx) != n {
+			t.Errorf("hits of %s = %d (Hits %d), want %d", pfx, got[pfx], tbl.Hits(pfx), n)
+		}
+	}
+
+	// an overwritten value keeps the count, a reinserted prefix does not
+	tbl.Insert(mpp("10.1.0.0/16"), 20)
+	if n := tbl.Hits(mpp("10.1.0.0/16")); n != 4 {
+		t.Errorf("after overwrite Hits = %d, want 4", n)
+	}
+	tbl.Delete(mpp("10.1.0.0/16"))
+	tbl.Insert(mpp("10.1.0.0/16"), 2)
+	if n := tbl.Hits(mpp("10.1.0.0/16")); n != 0 {
+		t.Errorf("reinserted prefix starts at %d", n)
+	}
+
+	tbl.ResetCounters()
+	if n := tbl.Hits(mpp("10.0.0.0/8")); n != 0 {
+		t.Errorf("after ResetCounters Hits = %d", n)
+	}
+}
+
+func TestHitCountersConcurrent(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+	c.Update(func(t *Table[int]) {
+		t.CountHits()
+		t.Insert(mpp("10.0.0.0/8"), 1)
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Lookup(mpa("10.1.1.1"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Read(func(tbl *Table[int]) {
+		if n := tbl.Hits(mpp("10.0.0.0/8")); n != 8000 {
+			t.Errorf("Hits = %d, want 8000", n)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+// Package httpapi exposes a zart table over a small JSON HTTP API:
+//
+//	GET    /lookup?ip=ADDR   longest-prefix match for ADDR
+//	PUT    /routes           insert a JSON array of {"prefix", "value"}
+//	DELETE /routes/PREFIX    delete PREFIX, e.g. /routes/10.0.0.0/8
+//	GET    /dump             all routes in the format of PUT /routes
+//
+// Values are encoded with encoding/json. The Handler can be mounted into
+// an existing mux, under a path prefix with http.StripPrefix.
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/gx14ac/zart"
+)
+
+// maxBody limits the size of a PUT /routes request body.
+const maxBody = 64 << 20
+
+// route is the JSON form of a route, as written by Table.ExportJSON.
+type route[V any] struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Value  V            `json:"value"`
+}
+
+// Handler serves the API for a Table. It serializes access to the table,
+// which must not be used directly while the handler is in use.
+type Handler[V any] struct {
+	mu  sync.RWMutex
+	t   *zart.Table[V]
+	mux *http.ServeMux
+}
+
+// New returns a Handler for t.
+func New[V any](t *zart.Table[V]) *Handler[V] {
+	h := &Handler[V]{t: t, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /lookup", h.lookup)
+	h.mux.HandleFunc("PUT /routes", h.insert)
+	h.mux.HandleFunc("DELETE /routes/{prefix...}", h.delete)
+	h.mux.HandleFunc("GET /dump", h.dump)
+	return h
+}
+
+func (h *Handler[V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler[V]) lookup(w http.ResponseWriter, r *http.Request) {
+	addr, err := netip.ParseAddr(r.URL.Query().Get("ip"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.mu.RLock()
+	pfx, val, ok := h.t.LookupPrefix(addr)
+	h.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route to %s", addr))
+		return
+	}
+	writeJSON(w, http.StatusOK, route[V]{pfx, val})
+}
+
+// insert adds all routes of the body, or none if any of them is invalid.
+func (h *Handler[V]) insert(w http.ResponseWriter, r *http.Request) {
+	var routes []route[V]
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&routes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries := make([]zart.RouteEntry[V], len(routes))
+	for i, rt := range routes {
+		if !rt.Prefix.IsValid() {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("route %d: missing prefix", i+1))
+			return
+		}
+		entries[i] = zart.RouteEntry[V]{Prefix: rt.Prefix, Value: rt.Value}
+	}
+	h.mu.Lock()
+	h.t.InsertBatch(entries)
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler[V]) delete(w http.ResponseWriter, r *http.Request) {
+	pfx, err := netip.ParsePrefix(r.PathValue("prefix"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.mu.Lock()
+	ok := h.t.Delete(pfx)
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route %s", pfx))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dump renders the table into memory first, so the lock is not held
+// while a slow client reads the response.
+func (h *Handler[V]) dump(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	h.mu.RLock()
+	err := h.t.ExportJSON(&buf)
+	h.mu.RUnlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
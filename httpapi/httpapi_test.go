@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	b, _ := io.ReadAll(rec.Result().Body)
+	return rec.Code, string(b)
+}
+
+func TestHandler(t *testing.T) {
+	tbl := zart.New[string]()
+	defer tbl.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", New(tbl)))
+
+	code, _ := do(t, mux, "PUT", "/api/routes", `[{"prefix":"10.0.0.0/8","value":"ten"},{"prefix":"10.1.0.0/16","value":"ten-one"}]`)
+	if code != http.StatusNoContent {
+		t.Fatalf("PUT /routes = %d", code)
+	}
+
+	code, body := do(t, mux, "GET", "/api/lookup?ip=10.1.2.3", "")
+	if want := `{"prefix":"10.1.0.0/16","value":"ten-one"}` + "\n"; code != http.StatusOK || body != want {
+		t.Errorf("GET /lookup = %d %q, want %q", code, body, want)
+	}
+	if code, _ := do(t, mux, "GET", "/api/lookup?ip=192.168.1.1", ""); code != http.StatusNotFound {
+		t.Errorf("GET /lookup of unrouted address = %d", code)
+	}
+	if code, _ := do(t, mux, "GET", "/api/lookup?ip=nope", ""); code != http.StatusBadRequest {
+		t.Errorf("GET /lookup with bad address = %d", code)
+	}
+
+	if code, _ := do(t, mux, "DELETE", "/api/routes/10.1.0.0/16", ""); code != http.StatusNoContent {
+		t.Errorf("DELETE /routes/10.1.0.0/16 = %d", code)
+	}
+	if code, _ := do(t, mux, "DELETE", "/api/routes/10.1.0.0/16", ""); code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d", code)
+	}
+
+	code, body = do(t, mux, "GET", "/api/dump", "")
+	if want := `[{"prefix":"10.0.0.0/8","value":"ten"}` + "\n]\n"; code != http.StatusOK || body != want {
+		t.Errorf("GET /dump = %d %q, want %q", code, body, want)
+	}
+
+	// a bad route rejects the whole request
+	code, _ = do(t, mux, "PUT", "/api/routes", `[{"prefix":"192.168.0.0/16","value":"lan"},{"value":"x"}]`)
+	if code != http.StatusBadRequest {
+		t.Errorf("PUT with missing prefix = %d", code)
+	}
+	if _, ok := tbl.Lookup(netip.MustParseAddr("192.168.1.1")); ok {
+		t.Errorf("rejected PUT modified the table")
+	}
+}
@@ -0,0 +1,567 @@
+/*
+ * bart.h - C ABI of the ZART routing table.
+ *
+ * The implementation lives in src/c_api.zig and is compiled into
+ * libbart.a by `zig build`. The Go package github.com/gx14ac/zart
+ * links against it; other C callers can use it directly.
+ *
+ * IPv4 addresses are passed as host-order integers, so 10.0.0.0 is
+ * 0x0a000000. IPv6 addresses are passed as 16 bytes in network order.
+ *
+ * Values are 64 bits wide. The functions with uint32_t values predate
+ * that and are kept for compatibility: they store their values zero
+ * extended and return the low 32 bits of the stored ones. The _64
+ * variants pass the whole value, for next-hop ids or pointers.
+ */
+#ifndef BART_H
+#define BART_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* bart_table_t is an opaque handle to an IPv4/IPv6 routing table. */
+typedef struct bart_table bart_table_t;
+
+/*
+ * bart_route_t is a prefix with its value, the element of the bulk APIs.
+ * For IPv4 (is4 != 0) only the first 4 bytes of addr are used.
+ */
+typedef struct {
+    uint8_t addr[16];
+    uint8_t bits;
+    uint8_t is4;
+    uint32_t value;
+} bart_route_t;
+
+/* bart_route64_t is bart_route_t with the whole 64-bit value. */
+typedef struct {
+    uint8_t addr[16];
+    uint8_t bits;
+    uint8_t is4;
+    uint64_t value;
+} bart_route64_t;
+
+/* bart_create returns a new empty table, or NULL if out of memory. */
+bart_table_t *bart_create(void);
+
+/*
+ * bart_create_arena is bart_create for a table allocating its nodes from
+ * an arena of block_size byte blocks, 0 for the default of 256 KiB. The
+ * persistent versions of the table share the arena, bart_clone gives the
+ * copy its own. Destroying the last version frees the blocks at once
+ * instead of the nodes one by one.
+ */
+bart_table_t *bart_create_arena(size_t block_size);
+
+/*
+ * bart_clone returns a deep copy of tbl that shares no memory with it,
+ * or NULL if out of memory. The copy must be released with bart_destroy.
+ */
+bart_table_t *bart_clone(const bart_table_t *tbl);
+
+/*
+ * bart_persist returns a new version of tbl that shares all trie nodes
+ * with it, in constant time. Both tables stay fully usable: a write to
+ * either one copies the shared nodes on the path of the prefix first, so
+ * the versions never see each other's changes. Each version is released
+ * with bart_destroy, in any order.
+ */
+bart_table_t *bart_persist(bart_table_t *tbl);
+
+/* bart_destroy releases the table and all of its nodes. NULL is a no-op. */
+void bart_destroy(bart_table_t *tbl);
+
+/*
+ * bart_size4/6 return the number of IPv4/IPv6 prefixes in tbl. The
+ * counters are kept up to date by every insert and delete, the call
+ * takes constant time.
+ */
+size_t bart_size4(const bart_table_t *tbl);
+size_t bart_size6(const bart_table_t *tbl);
+
+/*
+ * bart_family_stats_t describes the trie of one address family. Prefixes
+ * are stored in nodes, or path compressed as leaves (with their prefix)
+ * and fringes (prefix implied by the position) in place of a child node.
+ * bytes counts the nodes and their sparse arrays as allocated, without
+ * the overhead of the allocator; nodes shared with persistent versions
+ * are counted in every version. nodes_per_level[d] is the number of nodes
+ * at depth d, the stride of depth d covers address bits 8d to 8d+7.
+ */
+typedef struct {
+    uint64_t prefixes;
+    uint64_t nodes;
+    uint64_t leaves;
+    uint64_t fringes;
+    uint64_t bytes;
+    uint64_t nodes_per_level[16];
+} bart_family_stats_t;
+
+typedef struct {
+    bart_family_stats_t v4;
+    bart_family_stats_t v6;
+} bart_stats_t;
+
+/*
+ * bart_stats walks the trie and stores its statistics in *out. It takes
+ * time linear in the number of nodes.
+ */
+void bart_stats(const bart_table_t *tbl, bart_stats_t *out);
+
+/*
+ * bart_level_shape_t sums the nodes at one depth of a trie: their stored
+ * prefixes (of at most 255 slots) and children (of 256 slots), and how
+ * many of the children are path compressed leaves and fringes.
+ */
+typedef struct {
+    uint64_t nodes;
+    uint64_t prefixes;
+    uint64_t children;
+    uint64_t leaves;
+    uint64_t fringes;
+} bart_level_shape_t;
+
+/*
+ * bart_shape_t describes the trie of one address family. prefix_fill and
+ * child_fill are histograms of the nodes by the number n of their
+ * prefixes or children: bucket 0 counts nodes with n == 0, bucket b > 0
+ * those with 2^(b-1) <= n < 2^b.
+ */
+typedef struct {
+    bart_level_shape_t levels[16];
+    uint64_t prefix_fill[9];
+    uint64_t child_fill[10];
+} bart_shape_t;
+
+/*
+ * bart_shape walks the trie like bart_stats and stores the shape of the
+ * IPv4 trie in *v4 and of the IPv6 trie in *v6.
+ */
+void bart_shape(const bart_table_t *tbl, bart_shape_t *v4, bart_shape_t *v6);
+
+/* Codes of bart_fsck_problem_t. */
+enum {
+    BART_FSCK_PREFIX_COUNT = 1, /* prefix bitset bits (have) != items (want) */
+    BART_FSCK_CHILD_COUNT,      /* child bitset bits (have) != items (want) */
+    BART_FSCK_PREFIX_INDEX,     /* prefix at the unused base index 0 */
+    BART_FSCK_DEPTH,            /* node at depth have, deeper than want */
+    BART_FSCK_EMPTY_NODE,       /* non-root node without prefixes or children */
+    BART_FSCK_LEAF,             /* leaf /have outside its slot, want is the depth in bits */
+    BART_FSCK_REFS,             /* node with a reference count of 0 */
+    BART_FSCK_SIZE,             /* have prefixes in the trie, the counter says want */
+};
+
+/*
+ * bart_fsck_problem_t is an inconsistency found by bart_fsck in the node
+ * at depth whose position is the first depth octets of path.
+ */
+typedef struct {
+    uint8_t path[16];
+    uint8_t depth;
+    uint8_t is4;
+    uint8_t code;
+    uint8_t _pad[5];
+    uint64_t have;
+    uint64_t want;
+} bart_fsck_problem_t;
+
+/*
+ * bart_fsck walks both tries checking their invariants: the bitsets of the
+ * sparse arrays against their items, the depth of nodes and leaves, the
+ * reference counts and the size counters. It stores up to cap problems in
+ * out, which may be NULL for cap 0, and returns the number found.
+ */
+size_t bart_fsck(const bart_table_t *tbl, bart_fsck_problem_t *out, size_t cap);
+
+/*
+ * bart_trie_item_t is an element of the structural dump of a trie. A
+ * BART_ITEM_NODE item is a trie node: node is its number, parent the
+ * number of its parent (0 for the IPv4 and IPv6 roots), octet the child
+ * slot it occupies in the parent, and route its address path with
+ * depth*8 bits. The other kinds are prefixes with their values, stored
+ * in node node: BART_ITEM_PREFIX in the node's prefix slots, BART_ITEM_LEAF
+ * and BART_ITEM_FRINGE path compressed in its child slot octet.
+ */
+enum {
+    BART_ITEM_NODE = 0,
+    BART_ITEM_PREFIX = 1,
+    BART_ITEM_LEAF = 2,
+    BART_ITEM_FRINGE = 3,
+};
+
+typedef struct {
+    uint32_t node;
+    uint32_t parent;
+    uint8_t kind;
+    uint8_t depth;
+    uint8_t octet;
+    bart_route_t route;
+} bart_trie_item_t;
+
+/*
+ * bart_trie_items stores up to cap items of the trie structure in out,
+ * depth first, the IPv4 trie before the IPv6 trie. Every node comes
+ * before its prefixes and children. The IPv4 root is node 1 and the IPv6
+ * root node 2, the other nodes are numbered from 3 in walk order. The
+ * total number of items is returned as for bart_dump.
+ */
+size_t bart_trie_items(const bart_table_t *tbl, bart_trie_item_t *out, size_t cap);
+
+/*
+ * bart_lookup_trace4/6 store up to cap items describing the lookup of
+ * addr in out, for explaining why a prefix was selected. Every node on
+ * the lookup path is a BART_ITEM_NODE item, numbered from 1 with parent
+ * the previous node and octet the address octet at its depth; it is
+ * followed by the BART_ITEM_PREFIX items of its prefixes covering addr,
+ * most specific first. A leaf or fringe in the child slot of addr ends
+ * the path, a leaf need not cover addr. The total number of items is
+ * returned as for bart_dump.
+ */
+size_t bart_lookup_trace4(const bart_table_t *tbl, uint32_t addr, bart_trie_item_t *out, size_t cap);
+size_t bart_lookup_trace6(const bart_table_t *tbl, const uint8_t addr[16], bart_trie_item_t *out, size_t cap);
+
+/*
+ * bart_insert4/6 add a prefix with the given value. An existing value
+ * for the same prefix is overwritten; in that case 1 is returned and the
+ * previous value is stored in *old (if old is not NULL), otherwise 0 is
+ * returned. Invalid prefix lengths are ignored and return 0.
+ */
+int bart_insert4(bart_table_t *tbl, uint32_t addr, uint8_t bits, uint32_t value, uint32_t *old);
+int bart_insert6(bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, uint32_t value, uint32_t *old);
+int bart_insert4_64(bart_table_t *tbl, uint32_t addr, uint8_t bits, uint64_t value, uint64_t *old);
+int bart_insert6_64(bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, uint64_t value, uint64_t *old);
+
+/*
+ * bart_insert6_hl_64 is bart_insert6_64 for an address given as its high
+ * and low 64 bits, hi holding the first 8 bytes in network order.
+ */
+int bart_insert6_hl_64(bart_table_t *tbl, uint64_t hi, uint64_t lo, uint8_t bits, uint64_t value, uint64_t *old);
+
+/*
+ * bart_get_or_insert4/6 insert a prefix with the given value unless it is
+ * already present. For a present prefix 1 is returned, its value is
+ * stored in *old (if old is not NULL) and the trie is left unchanged;
+ * otherwise the prefix is inserted and 0 is returned.
+ */
+int bart_get_or_insert4(bart_table_t *tbl, uint32_t addr, uint8_t bits, uint32_t value, uint32_t *old);
+int bart_get_or_insert6(bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, uint32_t value, uint32_t *old);
+
+/*
+ * bart_insert_bulk inserts n routes in order. The previous values of
+ * overwritten prefixes are stored in replaced (if not NULL, room for n
+ * values), the number of overwritten prefixes is returned.
+ */
+size_t bart_insert_bulk(bart_table_t *tbl, const bart_route_t *routes, size_t n, uint32_t *replaced);
+
+/*
+ * bart_delete4/6 remove a prefix. If it was present, 1 is returned and its
+ * value is stored in *old (if old is not NULL), otherwise 0 is returned.
+ * Trie nodes left empty by the removal are freed.
+ */
+int bart_delete4(bart_table_t *tbl, uint32_t addr, uint8_t bits, uint32_t *old);
+int bart_delete6(bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, uint32_t *old);
+int bart_delete4_64(bart_table_t *tbl, uint32_t addr, uint8_t bits, uint64_t *old);
+int bart_delete6_64(bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, uint64_t *old);
+
+/*
+ * bart_get4/6 return the value stored for exactly the given prefix, host
+ * bits are ignored. *found is set to 1 if the prefix is present and 0
+ * otherwise; the return value is 0 on a miss.
+ */
+uint32_t bart_get4(const bart_table_t *tbl, uint32_t addr, uint8_t bits, int *found);
+uint32_t bart_get6(const bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, int *found);
+uint64_t bart_get4_64(const bart_table_t *tbl, uint32_t addr, uint8_t bits, int *found);
+uint64_t bart_get6_64(const bart_table_t *tbl, const uint8_t addr[16], uint8_t bits, int *found);
+
+/*
+ * bart_lookup4/6 perform a longest-prefix match. *found is set to 1 on a
+ * match and 0 otherwise; the return value is undefined (0) on a miss.
+ */
+uint32_t bart_lookup4(const bart_table_t *tbl, uint32_t addr, int *found);
+uint32_t bart_lookup6(const bart_table_t *tbl, const uint8_t addr[16], int *found);
+uint64_t bart_lookup4_64(const bart_table_t *tbl, uint32_t addr, int *found);
+uint64_t bart_lookup6_64(const bart_table_t *tbl, const uint8_t addr[16], int *found);
+
+/*
+ * bart_lookup6_hl/_hl_64 are bart_lookup6/_64 for an address given as
+ * its high and low 64 bits like bart_insert6_hl_64, for callers holding
+ * it in two registers rather than in memory.
+ */
+uint32_t bart_lookup6_hl(const bart_table_t *tbl, uint64_t hi, uint64_t lo, int *found);
+uint64_t bart_lookup6_hl_64(const bart_table_t *tbl, uint64_t hi, uint64_t lo, int *found);
+
+/*
+ * bart_lookup_prefix4/6 are like bart_lookup4/6 and additionally store
+ * the length of the matching prefix in *bits; the prefix itself is the
+ * address masked to that length.
+ */
+uint32_t bart_lookup_prefix4(const bart_table_t *tbl, uint32_t addr, uint8_t *bits, int *found);
+uint32_t bart_lookup_prefix6(const bart_table_t *tbl, const uint8_t addr[16], uint8_t *bits, int *found);
+
+/*
+ * bart_lookup_shortest4/6 are bart_lookup_prefix4/6 for the least
+ * specific prefix covering addr instead of the most specific one.
+ */
+uint32_t bart_lookup_shortest4(const bart_table_t *tbl, uint32_t addr, uint8_t *bits, int *found);
+uint32_t bart_lookup_shortest6(const bart_table_t *tbl, const uint8_t addr[16], uint8_t *bits, int *found);
+
+/*
+ * bart_contains4/6 return 1 if any prefix covers addr and 0 otherwise.
+ * They stop at the first covering prefix instead of looking for the
+ * longest one.
+ */
+int bart_contains4(const bart_table_t *tbl, uint32_t addr);
+int bart_contains6(const bart_table_t *tbl, const uint8_t addr[16]);
+
+/*
+ * bart_lookup_batch4/6 perform n longest-prefix matches in one call.
+ * For every addrs[i] the matched value is stored in values[i] and found[i]
+ * is set to 1, or values[i] and found[i] are set to 0 on a miss.
+ */
+void bart_lookup_batch4(const bart_table_t *tbl, const uint32_t *addrs, size_t n, uint32_t *values, uint8_t *found);
+void bart_lookup_batch6(const bart_table_t *tbl, const uint8_t (*addrs)[16], size_t n, uint32_t *values, uint8_t *found);
+
+/*
+ * bart_dump stores up to cap routes of the table in out, IPv4 before
+ * IPv6, in trie order. The total number of routes is returned; if it is
+ * larger than cap, the call must be repeated with a larger buffer.
+ * out may be NULL with cap 0 to query the required capacity.
+ */
+size_t bart_dump(const bart_table_t *tbl, bart_route_t *out, size_t cap);
+
+/*
+ * bart_walk_fn receives a batch of n routes from bart_walk, it returns 0
+ * to stop the walk and nonzero to go on. The routes are only valid
+ * during the call.
+ */
+typedef int (*bart_walk_fn)(void *ctx, const bart_route_t *routes, size_t n);
+
+/*
+ * bart_walk passes the routes of tbl to fn in the order of bart_dump, in
+ * batches of up to cap routes collected in buf, without a copy of the
+ * whole table. ctx is passed on to fn. It returns the number of routes
+ * passed to fn; 0 for a cap of 0. fn must not modify tbl.
+ */
+size_t bart_walk(const bart_table_t *tbl, bart_route_t *buf, size_t cap, bart_walk_fn fn, void *ctx);
+
+/*
+ * bart_walk_sorted is bart_walk in CIDR order: IPv4 before IPv6, and
+ * within a family by address and then by prefix length.
+ */
+size_t bart_walk_sorted(const bart_table_t *tbl, bart_route_t *buf, size_t cap, bart_walk_fn fn, void *ctx);
+
+/*
+ * bart_page stores up to cap routes of tbl following *after in the order
+ * of bart_walk_sorted in out, from the first route if after is NULL, and
+ * returns their number. The value of *after is ignored and it need not be
+ * in tbl; the walk skips everything before it.
+ */
+size_t bart_page(const bart_table_t *tbl, const bart_route_t *after, bart_route_t *out, size_t cap);
+
+/* bart_dump64 is bart_dump with the whole values. */
+size_t bart_dump64(const bart_table_t *tbl, bart_route64_t *out, size_t cap);
+
+/*
+ * bart_supernets stores the routes covering *pfx, including pfx itself,
+ * in out, from the most to the least specific one. The value of *pfx is
+ * ignored. At most 129 routes can cover a prefix, the total number is
+ * returned as for bart_dump.
+ */
+size_t bart_supernets(const bart_table_t *tbl, const bart_route_t *pfx, bart_route_t *out, size_t cap);
+
+/*
+ * bart_subnets stores the routes covered by *pfx, including pfx itself,
+ * in out, in trie order. The value of *pfx is ignored. The total number
+ * of routes is returned as for bart_dump.
+ */
+size_t bart_subnets(const bart_table_t *tbl, const bart_route_t *pfx, bart_route_t *out, size_t cap);
+
+/*
+ * bart_delete_subtree removes all routes covered by *pfx, including pfx
+ * itself, and stores them with their values in out. If they do not fit
+ * into cap routes nothing is removed; the total number of covered routes
+ * is returned in both cases, so the caller can retry with a larger out.
+ */
+size_t bart_delete_subtree(bart_table_t *tbl, const bart_route_t *pfx, bart_route_t *out, size_t cap);
+
+/*
+ * bart_delete_bulk removes n routes in order, their values are ignored.
+ * The number of routes that were present and removed is returned.
+ */
+size_t bart_delete_bulk(bart_table_t *tbl, const bart_route_t *routes, size_t n);
+
+/*
+ * bart_overlaps_prefix returns 1 if any route of the table overlaps *pfx,
+ * i.e. covers it or is covered by it, and 0 otherwise.
+ * bart_overlaps returns 1 if any route of a overlaps any route of b.
+ * Both run as trie walks without enumerating the routes.
+ */
+int bart_overlaps_prefix(const bart_table_t *tbl, const bart_route_t *pfx);
+int bart_overlaps(const bart_table_t *a, const bart_table_t *b);
+
+/*
+ * bart_union inserts all routes of other into tbl in one call, adding
+ * base to their values. Prefixes present in both tables keep the value in
+ * tbl; for each of them the route with tbl's value is stored in
+ * conflicts and the value from other in theirs, both need room for cap
+ * entries. The number of conflicts is returned; unlike bart_dump the
+ * union is not repeatable, cap must be at least the size of other.
+ */
+size_t bart_union(bart_table_t *tbl, const bart_table_t *other, uint32_t base, bart_route_t *conflicts, uint32_t *theirs, size_t cap);
+
+/*
+ * bart_graft moves the subtries below the roots of sub into tbl and
+ * leaves sub empty, in time independent of their size. It is the merge
+ * step of a parallel load, which builds tables of disjoint first octets
+ * concurrently. It returns 0 and changes nothing if the tables have a
+ * child at the same first octet, sub has prefixes shorter than /8, either
+ * table is shared with a persistent version at its root, or either
+ * allocates from an arena.
+ */
+int bart_graft(bart_table_t *tbl, bart_table_t *sub);
+
+/*
+ * bart_diff_t is a prefix whose presence or value differs between two
+ * tables. route holds the prefix with its value in the first table, theirs
+ * the value in the second; the value of the missing side is 0.
+ */
+enum {
+    BART_DIFF_REMOVED = 0, /* only in the first table */
+    BART_DIFF_ADDED = 1,   /* only in the second table */
+    BART_DIFF_CHANGED = 2, /* in both, with different values */
+};
+
+typedef struct {
+    bart_route_t route;
+    uint32_t theirs;
+    uint8_t kind;
+} bart_diff_t;
+
+/*
+ * bart_diff stores up to cap differences between a and b in out and
+ * returns their total number, as for bart_dump. Both tries are walked side
+ * by side. If shared is nonzero the tables share their values, like
+ * persistent versions of one table: prefixes with equal values in both are
+ * not reported and subtrees shared by both tries are skipped without
+ * walking them. Otherwise every prefix present in both is reported as
+ * BART_DIFF_CHANGED, for the caller to compare the values.
+ */
+size_t bart_diff(const bart_table_t *a, const bart_table_t *b, int shared, bart_diff_t *out, size_t cap);
+
+/*
+ * bart_common stores up to cap prefixes present in both a and b in out
+ * and returns their total number. Each is reported as BART_DIFF_CHANGED
+ * with the value of a in route and the value of b in theirs. The walk is
+ * the one of bart_diff, subtrees present on one side only are skipped.
+ */
+size_t bart_common(const bart_table_t *a, const bart_table_t *b, bart_diff_t *out, size_t cap);
+
+/*
+ * bart_same_prefixes returns 1 if a and b hold the same set of prefixes,
+ * regardless of their values, and 0 otherwise. Like bart_diff it walks
+ * both tries side by side; it stops at the first difference and skips
+ * subtrees shared by both tries.
+ */
+int bart_same_prefixes(const bart_table_t *a, const bart_table_t *b);
+
+/*
+ * bart_pset_t is an opaque handle to a set of IPv4/IPv6 prefixes, a table
+ * without values. Its nodes have no value slots, so it takes less memory
+ * than a bart_table_t holding the same prefixes.
+ */
+typedef struct bart_pset bart_pset_t;
+
+/* bart_pset_create returns a new empty set, or NULL if out of memory. */
+bart_pset_t *bart_pset_create(void);
+
+/* bart_pset_create_arena is bart_create_arena for a set. */
+bart_pset_t *bart_pset_create_arena(size_t block_size);
+
+/* bart_pset_clone returns a copy of set, or NULL if out of memory. */
+bart_pset_t *bart_pset_clone(const bart_pset_t *set);
+
+/* bart_pset_destroy frees set, NULL is ignored. */
+void bart_pset_destroy(bart_pset_t *set);
+
+size_t bart_pset_size4(const bart_pset_t *set);
+size_t bart_pset_size6(const bart_pset_t *set);
+
+/*
+ * bart_pset_add4/6 add a prefix to set and return 1 if it was missing, 0
+ * if it was present or is invalid. bart_pset_remove4/6 remove it and
+ * return 1 if it was present.
+ */
+int bart_pset_add4(bart_pset_t *set, uint32_t addr, uint8_t bits);
+int bart_pset_add6(bart_pset_t *set, const uint8_t addr[16], uint8_t bits);
+int bart_pset_remove4(bart_pset_t *set, uint32_t addr, uint8_t bits);
+int bart_pset_remove6(bart_pset_t *set, const uint8_t addr[16], uint8_t bits);
+
+/* bart_pset_has4/6 return 1 if set holds exactly the prefix. */
+int bart_pset_has4(const bart_pset_t *set, uint32_t addr, uint8_t bits);
+int bart_pset_has6(const bart_pset_t *set, const uint8_t addr[16], uint8_t bits);
+
+/* bart_pset_contains4/6 return 1 if a prefix of set covers addr. */
+int bart_pset_contains4(const bart_pset_t *set, uint32_t addr);
+int bart_pset_contains6(const bart_pset_t *set, const uint8_t addr[16]);
+
+/*
+ * bart_pset_add_bulk and bart_pset_remove_bulk add or remove the n
+ * prefixes of routes, ignoring their values, and return how many were
+ * added or removed.
+ */
+size_t bart_pset_add_bulk(bart_pset_t *set, const bart_route_t *routes, size_t n);
+size_t bart_pset_remove_bulk(bart_pset_t *set, const bart_route_t *routes, size_t n);
+
+/*
+ * bart_pset_walk_sorted is bart_walk_sorted for a set, the routes have
+ * value 0.
+ */
+size_t bart_pset_walk_sorted(const bart_pset_t *set, bart_route_t *buf, size_t cap, bart_walk_fn fn, void *ctx);
+
+/* bart_pset_union adds the prefixes of other to set. */
+void bart_pset_union(bart_pset_t *set, const bart_pset_t *other);
+
+/*
+ * bart_pset_common stores up to cap prefixes present in both a and b in
+ * out, with value 0, and returns their total number. out may be NULL to
+ * only count them.
+ */
+size_t bart_pset_common(const bart_pset_t *a, const bart_pset_t *b, bart_route_t *out, size_t cap);
+
+/* bart_pset_stats is bart_stats for a set. */
+void bart_pset_stats(const bart_pset_t *set, bart_stats_t *out);
+
+/*
+ * CPU features of the bitset operations behind every lookup and insert.
+ */
+enum {
+    BART_CPU_POPCNT = 1, /* x86-64 POPCNT */
+    BART_CPU_BMI2 = 2,   /* x86-64 BMI2 */
+    BART_CPU_NEON = 4,   /* arm64 Advanced SIMD */
+};
+
+/*
+ * bart_cpu_features returns the BART_CPU_* features the library was built
+ * to use unconditionally, those of its compilation target.
+ */
+uint32_t bart_cpu_features(void);
+
+/*
+ * bart_set_cpu_features tells the library the BART_CPU_* features of the
+ * CPU, as detected by the caller, and returns those it uses from now on:
+ * the built in ones plus those it selects at run time. A library built
+ * for a baseline x86-64 target selects POPCNT for rank and popcount; 0
+ * goes back to the generic code. It must not be called concurrently with
+ * other calls into the library.
+ */
+uint32_t bart_set_cpu_features(uint32_t features);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* BART_H */
@@ -0,0 +1,95 @@
+package bart
+
+import "math/bits"
+
+// bitset256 is a fixed size bitset for the 256 slots of a trie node,
+// exactly four machine words.
+type bitset256 [4]uint64
+
+func (b *bitset256) test(i uint8) bool {
+	return b[i>>6]&(1<<(i&63)) != 0
+}
+
+func (b *bitset256) set(i uint8) {
+	b[i>>6] |= 1 << (i & 63)
+}
+
+func (b *bitset256) clear(i uint8) {
+	b[i>>6] &^= 1 << (i & 63)
+}
+
+// rank returns the number of set bits in [0..i].
+func (b *bitset256) rank(i uint8) int {
+	w := int(i >> 6)
+	n := bits.OnesCount64(b[w] & (^uint64(0) >> (63 - (i & 63))))
+	for j := 0; j < w; j++ {
+		n += bits.OnesCount64(b[j])
+	}
+	return n
+}
+
+// next returns the first set bit >= i.
+func (b *bitset256) next(i uint) (uint8, bool) {
+	for w := i >> 6; w < 4; w++ {
+		word := b[w]
+		if w == i>>6 {
+			word &= ^uint64(0) << (i & 63)
+		}
+		if word != 0 {
+			return uint8(w<<6 + uint(bits.TrailingZeros64(word))), true
+		}
+	}
+	return 0, false
+}
+
+// sparse is a popcount compressed array with up to 256 items,
+// only the slots set in the bitset are backed by an item.
+type sparse[T any] struct {
+	bitset256
+	items []T
+}
+
+func (s *sparse[T]) len() int {
+	return len(s.items)
+}
+
+func (s *sparse[T]) get(i uint8) (T, bool) {
+	if !s.test(i) {
+		var zero T
+		return zero, false
+	}
+	return s.items[s.rank(i)-1], true
+}
+
+// insertAt sets slot i to v, it returns the overwritten item if any.
+func (s *sparse[T]) insertAt(i uint8, v T) (old T, existed bool) {
+	if s.test(i) {
+		j := s.rank(i) - 1
+		old, s.items[j] = s.items[j], v
+		return old, true
+	}
+	s.set(i)
+	j := s.rank(i) - 1
+
+	var zero T
+	s.items = append(s.items, zero)
+	copy(s.items[j+1:], s.items[j:])
+	s.items[j] = v
+	return old, false
+}
+
+// deleteAt clears slot i, it returns the removed item if any.
+func (s *sparse[T]) deleteAt(i uint8) (old T, ok bool) {
+	if !s.test(i) {
+		return old, false
+	}
+	j := s.rank(i) - 1
+	old = s.items[j]
+
+	var zero T
+	copy(s.items[j:], s.items[j+1:])
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	s.clear(i)
+	return old, true
+}
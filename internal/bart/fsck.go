@@ -0,0 +1,104 @@
+package bart
+
+import "math/bits"
+
+// Codes of the problems found by Fsck, numbered like BART_FSCK_* of the
+// C trie. The Go trie has no reference counts.
+const (
+	FsckPrefixCount = iota + 1
+	FsckChildCount
+	FsckPrefixIndex
+	FsckDepth
+	FsckEmptyNode
+	FsckLeaf
+	FsckRefs
+	FsckSize
+)
+
+// Problem is an inconsistency in the node at Depth whose position is the
+// first Depth octets of Path. Have and Want depend on the code.
+type Problem struct {
+	Code       int
+	Path       [16]byte
+	Depth      int
+	Have, Want int
+}
+
+// Fsck checks the IPv4 or IPv6 trie and calls fn for every problem.
+func (t *Trie[V]) Fsck(is4 bool, fn func(Problem)) {
+	last := maxDepth
+	if is4 {
+		last = 4
+	}
+	var path [16]byte
+	count := t.root(is4).fsck(&path, 0, last, fn)
+	size := t.size6
+	if is4 {
+		size = t.size4
+	}
+	if count != size {
+		fn(Problem{Code: FsckSize, Have: count, Want: size})
+	}
+}
+
+// fsck checks n and returns the number of prefixes stored below it. The
+// nodes at depth last, one per host route, are the deepest.
+func (n *node[V]) fsck(path *[16]byte, depth, last int, fn func(Problem)) int {
+	report := func(code, have, want int) {
+		fn(Problem{Code: code, Path: *path, Depth: depth, Have: have, Want: want})
+	}
+	if depth > last {
+		report(FsckDepth, depth, last)
+		return 0
+	}
+	if depth > 0 && n.isEmpty() {
+		report(FsckEmptyNode, 0, 0)
+	}
+
+	if c := n.prefixes.count(); c != n.prefixes.len() {
+		report(FsckPrefixCount, c, n.prefixes.len())
+	}
+	if n.prefixes.test(0) {
+		report(FsckPrefixIndex, 0, 1)
+	}
+	// a node below the last octet holds the host route only
+	if idx, ok := n.prefixes.next(2); depth == last && ok {
+		report(FsckPrefixIndex, int(idx), 1)
+	}
+	count := min(n.prefixes.count(), n.prefixes.len())
+
+	if c := n.children.count(); c != n.children.len() {
+		report(FsckChildCount, c, n.children.len())
+		return count
+	}
+	k := 0
+	for c, ok := n.children.next(0); ok; c, ok = n.children.next(uint(c) + 1) {
+		kid := n.children.items[k]
+		k++
+		if depth == last {
+			report(FsckDepth, depth+1, last)
+			continue
+		}
+		path[depth] = c
+		clear(path[depth+1:])
+		if kid == nil {
+			fn(Problem{Code: FsckEmptyNode, Path: *path, Depth: depth + 1})
+			continue
+		}
+		if l := kid.leaf; l != nil {
+			// a leaf stands for a prefix below its slot
+			if l.bits < 8*depth+8 || l.bits > 8*last || !within(l.octets[:], path[:], 8*depth+8) {
+				fn(Problem{Code: FsckLeaf, Path: *path, Depth: depth + 1, Have: l.bits, Want: 8*depth + 8})
+			}
+			count++
+			continue
+		}
+		count += kid.fsck(path, depth+1, last, fn)
+	}
+	return count
+}
+
+// count returns the number of bits set.
+func (b *bitset256) count() int {
+	return bits.OnesCount64(b[0]) + bits.OnesCount64(b[1]) + bits.OnesCount64(b[2]) + bits.OnesCount64(b[3])
+}
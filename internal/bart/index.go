@@ -0,0 +1,27 @@
+package bart
+
+// The prefixes of a stride are mapped onto a complete binary tree with
+// the baseIndex function of the ART algorithm: the prefix octet/bits with
+// bits in [0..7] gets the index (octet >> (8-bits)) + (1 << bits), so /0
+// is index 1 and every parent of index i is i>>1.
+//
+// Prefixes with bits%8 == 0 are stored as /0 (index 1) of the node one
+// level further down, a host route of a stride never needs index 256..511.
+
+// pfxToIdx maps octet/bits, bits in [0..7], to its base index.
+func pfxToIdx(octet, bits uint8) uint8 {
+	return octet>>(8-bits) + 1<<bits
+}
+
+// hostIdx returns the base index of octet/8, the starting point of the
+// backtracking during a longest-prefix match.
+func hostIdx(octet uint8) uint {
+	return uint(octet) + 256
+}
+
+// idxToPfx is the inverse of pfxToIdx.
+func idxToPfx(idx uint8) (octet, bits uint8) {
+	for bits = 7; idx>>bits == 0; bits-- {
+	}
+	return (idx - 1<<bits) << (8 - bits), bits
+}
@@ -0,0 +1,118 @@
+package bart
+
+// Item is an element of the structural dump of a trie, see Items. Items
+// with IsNode describe a node: Node is its number, Parent the number of
+// its parent or 0 for a root, Octet the child slot it occupies, Octets
+// and Bits its address path. The other items are the prefixes stored in
+// node Node with their values, Leaf ones path compressed in its child
+// slot Octet. Fringe tells the leaves ending on the next stride boundary
+// apart.
+type Item[V any] struct {
+	Node, Parent int
+	IsNode       bool
+	Leaf, Fringe bool
+	Depth        int
+	Octet        uint8
+
+	// Octets is only valid during the call to fn.
+	Octets []byte
+	Bits   int
+	Val    V
+}
+
+// Items calls fn for every node and prefix, depth first and IPv4 before
+// IPv6, every node before its prefixes and children. The IPv4 root is
+// node 1, the IPv6 root node 2, the other nodes are numbered from 3 in
+// walk order.
+func (t *Trie[V]) Items(fn func(Item[V])) {
+	next := 3
+	t.root4.items(1, 0, 0, 0, make([]byte, 4), &next, fn)
+	t.root6.items(2, 0, 0, 0, make([]byte, 16), &next, fn)
+}
+
+// items walks n, number id, whose position is the first depth octets of
+// path.
+func (n *node[V]) items(id, parent, depth int, octet uint8, path []byte, next *int, fn func(Item[V])) {
+	fn(Item[V]{Node: id, Parent: parent, IsNode: true, Depth: depth, Octet: octet, Octets: path, Bits: depth * 8})
+
+	pfx := make([]byte, len(path))
+	j := 0
+	for idx, ok := n.prefixes.next(0); ok; idx, ok = n.prefixes.next(uint(idx) + 1) {
+		o, bits := idxToPfx(idx)
+		copy(pfx, path[:depth])
+		pfx[depth] = o
+		fn(Item[V]{Node: id, Parent: parent, Depth: depth, Octets: pfx, Bits: depth*8 + int(bits), Val: n.prefixes.items[j]})
+		j++
+	}
+
+	k := 0
+	for c, ok := n.children.next(0); ok; c, ok = n.children.next(uint(c) + 1) {
+		kid := n.children.items[k]
+		k++
+		if kid.leaf != nil {
+			fn(kid.leaf.item(id, parent, depth, c, len(path)))
+			continue
+		}
+		path[depth] = c
+		clear(path[depth+1:])
+		*next++
+		kid.items(*next-1, id, depth+1, c, path, next, fn)
+	}
+}
+
+// item returns the item of l in child slot octet of node id at depth, in
+// a trie of size octet addresses.
+func (l *leaf[V]) item(id, parent, depth int, octet uint8, size int) Item[V] {
+	return Item[V]{
+		Node: id, Parent: parent, Leaf: true, Fringe: l.fringe(depth),
+		Depth: depth, Octet: octet, Octets: l.octets[:size], Bits: l.bits, Val: l.val,
+	}
+}
+
+// Trace calls fn for the nodes on the lookup path of octets, numbered
+// from 1 with Parent the previous node and Octet the address octet at
+// their depth. Every node is followed by its prefixes covering the
+// address, most specific first. The walk ends with the leaf in the child
+// slot of the address if there is one, whether it covers it or not.
+func (t *Trie[V]) Trace(octets []byte, fn func(Item[V])) {
+	if len(octets) != 4 && len(octets) != 16 {
+		return
+	}
+	n := t.root(len(octets) == 4)
+	path := make([]byte, len(octets))
+	pfx := make([]byte, len(octets))
+	for depth := 0; ; depth++ {
+		var octet uint8
+		if depth < len(octets) {
+			octet = octets[depth]
+		}
+		fn(Item[V]{Node: depth + 1, Parent: depth, IsNode: true, Depth: depth, Octet: octet, Octets: path, Bits: depth * 8})
+
+		// a node below the last octet only holds the host route as /0
+		if depth == len(octets) {
+			if val, ok := n.prefixes.get(1); ok {
+				fn(Item[V]{Node: depth + 1, Parent: depth, Depth: depth, Octets: path, Bits: depth * 8, Val: val})
+			}
+			return
+		}
+		for idx := hostIdx(octet) >> 1; idx > 0; idx >>= 1 {
+			if val, ok := n.prefixes.get(uint8(idx)); ok {
+				o, bits := idxToPfx(uint8(idx))
+				copy(pfx, path[:depth])
+				pfx[depth] = o
+				fn(Item[V]{Node: depth + 1, Parent: depth, Depth: depth, Octet: octet, Octets: pfx, Bits: depth*8 + int(bits), Val: val})
+			}
+		}
+
+		c, ok := n.children.get(octet)
+		if !ok {
+			return
+		}
+		if c.leaf != nil {
+			fn(c.leaf.item(depth+1, depth, depth, octet, len(octets)))
+			return
+		}
+		path[depth] = octet
+		n = c
+	}
+}
@@ -0,0 +1,105 @@
+package bart
+
+// leaf is the prefix of a path compressed child, see Trie.Compress. It
+// takes the place of the chain of nodes that would hold the prefix
+// alone. Leaves are never modified, a changed leaf is a new one, so they
+// are shared freely between persistent versions.
+//
+// The C trie stores the prefixes ending on a stride boundary below their
+// slot, the fringes, without the prefix. The Go trie stores the prefix of
+// every leaf and only tells fringes apart in the statistics.
+type leaf[V any] struct {
+	octets [maxDepth]byte // masked to bits
+	bits   int
+	val    V
+}
+
+// newLeaf returns the child node standing for octets/bits with val.
+func (t *Trie[V]) newLeaf(octets []byte, bits int, val V) *node[V] {
+	l := &leaf[V]{bits: bits, val: val}
+	copy(l.octets[:], octets)
+	mask(l.octets[:len(octets)], bits)
+	return &node[V]{leaf: l, gen: t.gen}
+}
+
+// mask clears the host bits of octets/bits.
+func mask(octets []byte, bits int) {
+	for i := range octets {
+		if rest := bits - 8*i; rest < 8 {
+			octets[i] &^= 0xff >> max(rest, 0)
+		}
+	}
+}
+
+// within reports whether octets is covered by the prefix p/bits.
+func within(p, octets []byte, bits int) bool {
+	full := bits / 8
+	if string(p[:full]) != string(octets[:full]) {
+		return false
+	}
+	if rest := bits % 8; rest != 0 {
+		m := byte(0xff) << (8 - rest)
+		return p[full]&m == octets[full]&m
+	}
+	return true
+}
+
+// is reports whether l holds exactly the prefix octets/bits.
+func (l *leaf[V]) is(octets []byte, bits int) bool {
+	return l.bits == bits && within(l.octets[:], octets, bits)
+}
+
+// covers reports whether l covers the prefix octets/bits.
+func (l *leaf[V]) covers(octets []byte, bits int) bool {
+	return l.bits <= bits && within(l.octets[:], octets, l.bits)
+}
+
+// yield passes the prefix of l to fn in path.
+func (l *leaf[V]) yield(path []byte, fn func([]byte, int, V) bool) bool {
+	copy(path, l.octets[:len(path)])
+	return fn(path, l.bits, l.val)
+}
+
+// fringe reports whether l, in a child slot of a node at depth, ends on
+// the boundary of the stride below, as a C fringe would.
+func (l *leaf[V]) fringe(depth int) bool {
+	return l.bits == 8*depth+8
+}
+
+// expand returns the uncompressed nodes a leaf child at depth stands for,
+// other nodes are returned as they are.
+func (n *node[V]) expand(depth int) *node[V] {
+	if n == nil || n.leaf == nil {
+		return n
+	}
+	l := n.leaf
+	top := &node[V]{}
+	c := top
+	for d := depth; d < l.bits/8; d++ {
+		kid := &node[V]{}
+		c.children.insertAt(l.octets[d], kid)
+		c = kid
+	}
+	c.prefixes.insertAt(pfxToIdx(octetAt(l.octets[:], l.bits/8), uint8(l.bits%8)), l.val)
+	return top
+}
+
+// compressed returns the leaf that can take the place of n, a node at
+// depth whose position is the first depth octets of path, or nil if n
+// holds more than a single prefix.
+func (t *Trie[V]) compressed(n *node[V], path []byte, depth int) *node[V] {
+	switch {
+	case n.prefixes.len() == 1 && n.children.len() == 0:
+		idx, _ := n.prefixes.next(0)
+		octet, bits := idxToPfx(idx)
+		l := &leaf[V]{bits: 8*depth + int(bits), val: n.prefixes.items[0]}
+		copy(l.octets[:], path[:depth])
+		if depth < maxDepth {
+			l.octets[depth] = octet
+		}
+		return &node[V]{leaf: l, gen: t.gen}
+	case n.prefixes.len() == 0 && n.children.len() == 1 && n.children.items[0].leaf != nil:
+		return n.children.items[0]
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package bart
+
+import (
+	"bytes"
+	"slices"
+)
+
+// node is a level of the multibit trie with a stride of 8 bits.
+//
+// The prefixes of the stride are indexed by their base index, the
+// children by the next octet of the address. Both are popcount
+// compressed sparse arrays.
+//
+// A child with leaf set is a path compressed prefix instead, with no
+// prefixes or children of its own.
+type node[V any] struct {
+	prefixes sparse[V]
+	children sparse[*node[V]]
+	leaf     *leaf[V]
+
+	// gen is the generation of the trie owning the node, nodes of other
+	// generations are shared with persistent versions and copied on write.
+	gen uint64
+}
+
+func (n *node[V]) isEmpty() bool {
+	return n.leaf == nil && n.prefixes.len() == 0 && n.children.len() == 0
+}
+
+// lpm returns the base index and value of the longest prefix covering idx
+// in this node.
+func (n *node[V]) lpm(idx uint) (top uint8, val V, ok bool) {
+	for ; idx > 0; idx >>= 1 {
+		if idx < 256 && n.prefixes.test(uint8(idx)) {
+			val, ok = n.prefixes.get(uint8(idx))
+			return uint8(idx), val, ok
+		}
+	}
+	return 0, val, false
+}
+
+// cowCopy returns a copy of n owned by generation gen. The children are
+// shared with n.
+func (n *node[V]) cowCopy(gen uint64) *node[V] {
+	return &node[V]{
+		prefixes: sparse[V]{bitset256: n.prefixes.bitset256, items: slices.Clone(n.prefixes.items)},
+		children: sparse[*node[V]]{bitset256: n.children.bitset256, items: slices.Clone(n.children.items)},
+		gen:      gen,
+	}
+}
+
+// clone returns a deep copy of n and its descendants.
+func (n *node[V]) clone() *node[V] {
+	if n.leaf != nil {
+		l := *n.leaf
+		return &node[V]{leaf: &l}
+	}
+	c := &node[V]{
+		prefixes: sparse[V]{bitset256: n.prefixes.bitset256, items: slices.Clone(n.prefixes.items)},
+		children: sparse[*node[V]]{bitset256: n.children.bitset256, items: make([]*node[V], len(n.children.items))},
+	}
+	for i, kid := range n.children.items {
+		c.children.items[i] = kid.clone()
+	}
+	return c
+}
+
+// walk calls fn for the prefixes of n and its descendants in trie order,
+// path holds the octets of n's position above depth.
+func (n *node[V]) walk(path []byte, depth int, fn func([]byte, int, V) bool) bool {
+	return n.walkCovered(path, depth, 0, 0, fn)
+}
+
+// walkCovered is walk restricted to the prefixes and children of n covered
+// by octet/lastBits, the last stride of a subnets query. octet/0 covers
+// the whole node.
+func (n *node[V]) walkCovered(path []byte, depth int, octet, lastBits uint8, fn func([]byte, int, V) bool) bool {
+	start := pfxToIdx(octet, lastBits)
+
+	j := 0
+	for idx, ok := n.prefixes.next(0); ok; idx, ok = n.prefixes.next(uint(idx) + 1) {
+		if covers(start, idx) {
+			pfxOctet, bits := idxToPfx(idx)
+			if depth < len(path) {
+				path[depth] = pfxOctet
+				clear(path[depth+1:])
+			}
+			if !fn(path, depth*8+int(bits), n.prefixes.items[j]) {
+				return false
+			}
+		}
+		j++
+	}
+
+	first := octet &^ (0xff >> lastBits)
+	last := first | 0xff>>lastBits
+	for c, ok := n.children.next(uint(first)); ok && c <= last; c, ok = n.children.next(uint(c) + 1) {
+		kid := n.children.items[n.children.rank(c)-1]
+		if kid.leaf != nil {
+			if !kid.leaf.yield(path, fn) {
+				return false
+			}
+			continue
+		}
+		path[depth] = c
+		if !kid.walk(path, depth+1, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// walkSorted is walk in CIDR order. The prefixes of n are sorted by
+// address and length and merged with the children, a child comes before
+// the prefixes starting at a higher octet.
+//
+// With after set only the prefixes following after/bits are passed to
+// fn, path then holds the first depth octets of after: the children below
+// its octet at depth are skipped and only the one at it is descended with
+// after, the others follow it entirely.
+func (n *node[V]) walkSorted(path []byte, depth int, after []byte, bits int, fn func([]byte, int, V) bool) bool {
+	var buf [256]uint8
+	idxs := buf[:0]
+	for idx, ok := n.prefixes.next(0); ok; idx, ok = n.prefixes.next(uint(idx) + 1) {
+		idxs = append(idxs, idx)
+	}
+	slices.SortFunc(idxs, func(a, b uint8) int {
+		ao, ab := idxToPfx(a)
+		bo, bb := idxToPfx(b)
+		if ao != bo {
+			return int(ao) - int(bo)
+		}
+		return int(ab) - int(bb)
+	})
+
+	// a node at the end of the address has no children
+	c, more := n.children.next(0)
+	if after != nil && depth < len(after) {
+		c, more = n.children.next(uint(after[depth]))
+	}
+	kid := func() bool {
+		path[depth] = c
+		var below []byte
+		if after != nil && depth < len(after) && after[depth] == c {
+			below = after
+		}
+		ok := true
+		switch k := n.children.items[n.children.rank(c)-1]; {
+		case k.leaf == nil:
+			ok = k.walkSorted(path, depth+1, below, bits, fn)
+		case below == nil || follows(k.leaf.octets[:len(path)], k.leaf.bits, after, bits):
+			ok = k.leaf.yield(path, fn)
+		}
+		c, more = n.children.next(uint(c) + 1)
+		return ok
+	}
+	for _, idx := range idxs {
+		octet, pfxBits := idxToPfx(idx)
+		for more && c < octet {
+			if !kid() {
+				return false
+			}
+		}
+		if depth < len(path) {
+			path[depth] = octet
+			clear(path[depth+1:])
+		}
+		if after != nil && !follows(path, depth*8+int(pfxBits), after, bits) {
+			continue
+		}
+		val, _ := n.prefixes.get(idx)
+		if !fn(path, depth*8+int(pfxBits), val) {
+			return false
+		}
+	}
+	for more {
+		if !kid() {
+			return false
+		}
+	}
+	return true
+}
+
+// follows reports whether octets/bits comes after the prefix after/abits
+// in CIDR order, both of the same address family.
+func follows(octets []byte, bits int, after []byte, abits int) bool {
+	if c := bytes.Compare(octets, after); c != 0 {
+		return c > 0
+	}
+	return bits > abits
+}
+
+// covers reports whether the base index start is idx or one of its
+// ancestors in the complete binary tree of a stride.
+func covers(start, idx uint8) bool {
+	for idx > start {
+		idx >>= 1
+	}
+	return idx == start
+}
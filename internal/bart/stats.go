@@ -0,0 +1,94 @@
+package bart
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+// Stats describes the trie of one address family. Leaves and Fringes
+// count the path compressed prefixes, which are included in Prefixes.
+type Stats struct {
+	Prefixes int
+	Nodes    int
+	Leaves   int
+	Fringes  int
+	Bytes    int // nodes, leaves and their sparse arrays, by capacity
+
+	// Levels holds the number of nodes per depth, /128 routes live at
+	// depth 16.
+	Levels [maxDepth + 1]int
+}
+
+// Stats walks the IPv4 or IPv6 trie. Nodes shared with persistent
+// versions are counted in every version.
+func (t *Trie[V]) Stats(is4 bool) Stats {
+	var s Stats
+	t.root(is4).stats(0, &s)
+	return s
+}
+
+func (n *node[V]) stats(depth int, s *Stats) {
+	var zero V
+	s.Nodes++
+	s.Levels[depth]++
+	s.Bytes += int(unsafe.Sizeof(*n)) +
+		cap(n.prefixes.items)*int(unsafe.Sizeof(zero)) +
+		cap(n.children.items)*int(unsafe.Sizeof(n))
+	s.Prefixes += n.prefixes.len()
+	for _, kid := range n.children.items {
+		if kid.leaf == nil {
+			kid.stats(depth+1, s)
+			continue
+		}
+		if kid.leaf.fringe(depth) {
+			s.Fringes++
+		} else {
+			s.Leaves++
+		}
+		s.Prefixes++
+		s.Bytes += int(unsafe.Sizeof(*kid) + unsafe.Sizeof(*kid.leaf))
+	}
+}
+
+// Level sums the nodes at one depth of a trie. Leaves and Fringes are
+// the path compressed children among Children.
+type Level struct {
+	Nodes, Prefixes, Children int
+	Leaves, Fringes           int
+}
+
+// Shape describes the trie of one address family. PrefixFill and
+// ChildFill are histograms of the nodes by the bit length of their
+// number of prefixes or children.
+type Shape struct {
+	Levels     [maxDepth + 1]Level
+	PrefixFill [9]int
+	ChildFill  [10]int
+}
+
+// Shape walks the IPv4 or IPv6 trie.
+func (t *Trie[V]) Shape(is4 bool) Shape {
+	var s Shape
+	t.root(is4).shape(0, &s)
+	return s
+}
+
+func (n *node[V]) shape(depth int, s *Shape) {
+	pfxs, kids := n.prefixes.len(), n.children.len()
+	l := &s.Levels[depth]
+	l.Nodes++
+	l.Prefixes += pfxs
+	l.Children += kids
+	s.PrefixFill[bits.Len(uint(pfxs))]++
+	s.ChildFill[bits.Len(uint(kids))]++
+	for _, kid := range n.children.items {
+		switch {
+		case kid.leaf == nil:
+			kid.shape(depth+1, s)
+		case kid.leaf.fringe(depth):
+			l.Fringes++
+		default:
+			l.Leaves++
+		}
+	}
+}
@@ -0,0 +1,640 @@
+// Package bart is the pure-Go implementation of the BART multibit trie,
+// the counterpart of the Zig core in src/ for builds without cgo.
+//
+// Addresses are handled as octet slices, 4 bytes for IPv4 and 16 bytes
+// for IPv6, so the same code serves both families.
+package bart
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// maxDepth is the number of strides of an IPv6 address.
+const maxDepth = 16
+
+// Trie is an IPv4 and IPv6 routing table with payload V.
+// The zero value is ready to use.
+type Trie[V any] struct {
+	root4 node[V]
+	root6 node[V]
+	size4 int
+	size6 int
+
+	// Compress makes inserts store a prefix that would be alone in the
+	// nodes below its path as a leaf in the child slot where it leaves
+	// the trie, and deletes fold nodes left with a single prefix into a
+	// leaf. It applies to the changes made after it is set, leaves
+	// already in the trie are read either way.
+	Compress bool
+
+	// gen identifies the nodes owned by this trie, see Persist.
+	gen uint64
+}
+
+// generation hands out the generations of persistent tries.
+var generation atomic.Uint64
+
+// own returns n if it belongs to t, else a copy that does. Writers call
+// it for every node on their path before modifying it.
+func (t *Trie[V]) own(n *node[V]) *node[V] {
+	if n.gen == t.gen {
+		return n
+	}
+	return n.cowCopy(t.gen)
+}
+
+// ownRoot is own for the root node of a family, which is embedded in t.
+func (t *Trie[V]) ownRoot(is4 bool) *node[V] {
+	r := t.root(is4)
+	if r.gen != t.gen {
+		*r = *r.cowCopy(t.gen)
+	}
+	return r
+}
+
+// ownChild returns the child of n at octet, copied into t if needed.
+func (t *Trie[V]) ownChild(n *node[V], octet uint8) (*node[V], bool) {
+	c, ok := n.children.get(octet)
+	if !ok || c.gen == t.gen {
+		return c, ok
+	}
+	c = t.own(c)
+	n.children.insertAt(octet, c)
+	return c, true
+}
+
+// Persist returns a new version of t that shares all nodes with t. Both
+// tries stay writable; since both get a new generation, the first write
+// to any shared node copies it, so the versions are independent.
+func (t *Trie[V]) Persist() *Trie[V] {
+	p := *t
+	t.gen = generation.Add(1)
+	p.gen = generation.Add(1)
+	return &p
+}
+
+func (t *Trie[V]) root(is4 bool) *node[V] {
+	if is4 {
+		return &t.root4
+	}
+	return &t.root6
+}
+
+func (t *Trie[V]) sizeUpdate(is4 bool, delta int) {
+	if is4 {
+		t.size4 += delta
+		return
+	}
+	t.size6 += delta
+}
+
+// validPrefix reports whether octets/bits is a well-formed key.
+func validPrefix(octets []byte, bits int) bool {
+	return (len(octets) == 4 || len(octets) == 16) && bits >= 0 && bits <= 8*len(octets)
+}
+
+// Insert adds octets/bits with val, overwriting an existing value.
+// Host bits are ignored, invalid prefixes are dropped.
+func (t *Trie[V]) Insert(octets []byte, bits int, val V) (old V, existed bool) {
+	if !validPrefix(octets, bits) {
+		return old, false
+	}
+	is4 := len(octets) == 4
+	depth, lastBits := bits/8, uint8(bits%8)
+
+	n := t.ownRoot(is4)
+	for d := 0; d < depth; d++ {
+		c, ok := n.children.get(octets[d])
+		switch {
+		case !ok && t.Compress:
+			n.children.insertAt(octets[d], t.newLeaf(octets, bits, val))
+			t.sizeUpdate(is4, 1)
+			return old, false
+		case !ok:
+			c = &node[V]{gen: t.gen}
+			n.children.insertAt(octets[d], c)
+		case c.leaf != nil && c.leaf.is(octets, bits):
+			n.children.insertAt(octets[d], t.newLeaf(octets, bits, val))
+			return c.leaf.val, true
+		case c.leaf != nil:
+			// push the leaf down a level, it may meet the new prefix
+			// again in the next slot
+			l := c
+			c = &node[V]{gen: t.gen}
+			c.push(l, d+1)
+			n.children.insertAt(octets[d], c)
+		default:
+			c, _ = t.ownChild(n, octets[d])
+		}
+		n = c
+	}
+
+	old, existed = n.prefixes.insertAt(pfxToIdx(octetAt(octets, depth), lastBits), val)
+	if !existed {
+		t.sizeUpdate(is4, 1)
+	}
+	return old, existed
+}
+
+// Get returns the value stored for exactly octets/bits, host bits are
+// ignored.
+func (t *Trie[V]) Get(octets []byte, bits int) (val V, ok bool) {
+	if !validPrefix(octets, bits) {
+		return val, false
+	}
+	depth, lastBits := bits/8, uint8(bits%8)
+
+	n := t.root(len(octets) == 4)
+	for d := 0; d < depth; d++ {
+		if n, ok = n.children.get(octets[d]); !ok {
+			return val, false
+		}
+		if n.leaf != nil {
+			if n.leaf.is(octets, bits) {
+				return n.leaf.val, true
+			}
+			return val, false
+		}
+	}
+	return n.prefixes.get(pfxToIdx(octetAt(octets, depth), lastBits))
+}
+
+// push stores the leaf l in n, a new node at depth on its path.
+func (n *node[V]) push(l *node[V], depth int) {
+	if l.leaf.bits/8 == depth {
+		n.prefixes.insertAt(pfxToIdx(octetAt(l.leaf.octets[:], depth), uint8(l.leaf.bits%8)), l.leaf.val)
+		return
+	}
+	n.children.insertAt(l.leaf.octets[depth], l)
+}
+
+// Delete removes octets/bits and returns its value. Nodes left empty
+// are unlinked, so deleted routes release their memory, and with
+// Compress nodes left with a single prefix are folded into a leaf.
+func (t *Trie[V]) Delete(octets []byte, bits int) (old V, ok bool) {
+	if !validPrefix(octets, bits) {
+		return old, false
+	}
+	is4 := len(octets) == 4
+	depth, lastBits := bits/8, uint8(bits%8)
+
+	var stack [maxDepth]*node[V]
+	n := t.ownRoot(is4)
+	d := 0
+	for ; d < depth; d++ {
+		c, found := n.children.get(octets[d])
+		if !found {
+			return old, false
+		}
+		if c.leaf != nil {
+			if !c.leaf.is(octets, bits) {
+				return old, false
+			}
+			n.children.deleteAt(octets[d])
+			old, ok = c.leaf.val, true
+			break
+		}
+		stack[d] = n
+		n, _ = t.ownChild(n, octets[d])
+	}
+
+	if !ok {
+		if old, ok = n.prefixes.deleteAt(pfxToIdx(octetAt(octets, depth), lastBits)); !ok {
+			return old, false
+		}
+	}
+	t.sizeUpdate(is4, -1)
+
+	// purge empty nodes bottom-up, n is at depth d
+	for d--; d >= 0; d-- {
+		switch {
+		case n.isEmpty():
+			stack[d].children.deleteAt(octets[d])
+		case !t.Compress:
+			return old, true
+		default:
+			l := t.compressed(n, octets, d+1)
+			if l == nil {
+				return old, true
+			}
+			stack[d].children.insertAt(octets[d], l)
+		}
+		n = stack[d]
+	}
+	return old, true
+}
+
+// Contains reports whether any prefix covers the address in octets. It
+// returns at the first covering prefix on the way down, without the
+// backtracking of Lookup.
+func (t *Trie[V]) Contains(octets []byte) bool {
+	if len(octets) != 4 && len(octets) != 16 {
+		return false
+	}
+	n := t.root(len(octets) == 4)
+	for _, octet := range octets {
+		if n.prefixes.len() != 0 {
+			if _, _, ok := n.lpm(hostIdx(octet)); ok {
+				return true
+			}
+		}
+		c, ok := n.children.get(octet)
+		if !ok {
+			return false
+		}
+		if c.leaf != nil {
+			return c.leaf.covers(octets, 8*len(octets))
+		}
+		n = c
+	}
+	// the host route of a full path lives below the last octet
+	return n.prefixes.test(1)
+}
+
+// Lookup performs a longest-prefix match for the address in octets.
+func (t *Trie[V]) Lookup(octets []byte) (val V, ok bool) {
+	_, val, ok = t.LookupPrefix(octets)
+	return val, ok
+}
+
+// LookupPrefix is like Lookup and also returns the length of the matching
+// prefix; the prefix itself is the address masked to bits.
+func (t *Trie[V]) LookupPrefix(octets []byte) (bits int, val V, ok bool) {
+	if len(octets) != 4 && len(octets) != 16 {
+		return 0, val, false
+	}
+
+	// descend as deep as possible, remember the path for backtracking
+	var stack [maxDepth + 1]*node[V]
+	n := t.root(len(octets) == 4)
+	depth := 0
+	for ; depth < len(octets); depth++ {
+		stack[depth] = n
+		c, found := n.children.get(octets[depth])
+		if !found {
+			break
+		}
+		if c.leaf != nil {
+			// a leaf is more specific than any prefix above it
+			if c.leaf.covers(octets, 8*len(octets)) {
+				return c.leaf.bits, c.leaf.val, true
+			}
+			break
+		}
+		n = c
+	}
+
+	// a node below the last octet only holds the host route as /0
+	if depth == len(octets) {
+		if val, ok = n.prefixes.get(1); ok {
+			return 8 * depth, val, true
+		}
+		depth--
+	}
+
+	for ; depth >= 0; depth-- {
+		n = stack[depth]
+		if n.prefixes.len() == 0 {
+			continue
+		}
+		if top, val, ok := n.lpm(hostIdx(octets[depth])); ok {
+			_, pfxLen := idxToPfx(top)
+			return 8*depth + int(pfxLen), val, true
+		}
+	}
+	return 0, val, false
+}
+
+// LookupShortest is LookupPrefix for the least specific prefix covering
+// octets, the first one on the way down.
+func (t *Trie[V]) LookupShortest(octets []byte) (bits int, val V, ok bool) {
+	if len(octets) != 4 && len(octets) != 16 {
+		return 0, val, false
+	}
+	n := t.root(len(octets) == 4)
+	for depth := 0; ; depth++ {
+		if n.prefixes.len() != 0 {
+			octet := octetAt(octets, depth)
+			for b := range uint8(8) {
+				if val, ok = n.prefixes.get(pfxToIdx(octet, b)); ok {
+					return 8*depth + int(b), val, true
+				}
+			}
+		}
+		if depth == len(octets) {
+			return 0, val, false
+		}
+		c, found := n.children.get(octets[depth])
+		if !found {
+			return 0, val, false
+		}
+		if c.leaf != nil {
+			if c.leaf.covers(octets, 8*len(octets)) {
+				return c.leaf.bits, c.leaf.val, true
+			}
+			return 0, val, false
+		}
+		n = c
+	}
+}
+
+// Supernets calls fn for every prefix covering octets/bits, including
+// octets/bits itself, from the most to the least specific one, until fn
+// returns false. The prefixes are identified by their length alone.
+func (t *Trie[V]) Supernets(octets []byte, bits int, fn func(bits int, val V) bool) {
+	if !validPrefix(octets, bits) {
+		return
+	}
+	depth := bits / 8
+
+	// collect the nodes on the path, the search runs bottom-up
+	var stack [maxDepth + 1]*node[V]
+	n := t.root(len(octets) == 4)
+	d := 0
+	for ; ; d++ {
+		stack[d] = n
+		if d == depth {
+			break
+		}
+		c, found := n.children.get(octets[d])
+		if !found {
+			break
+		}
+		if c.leaf != nil {
+			if c.leaf.covers(octets, bits) && !fn(c.leaf.bits, c.leaf.val) {
+				return
+			}
+			break
+		}
+		n = c
+	}
+
+	for ; d >= 0; d-- {
+		n = stack[d]
+		if n.prefixes.len() == 0 {
+			continue
+		}
+		// the deepest possible cover in this node: pfx itself at its own
+		// depth, else the /7 of the stride, /8 lives one node further down
+		idx := pfxToIdx(octetAt(octets, d), 7)
+		if d == depth {
+			idx = pfxToIdx(octetAt(octets, d), uint8(bits%8))
+		}
+		for ; idx > 0; idx >>= 1 {
+			if val, ok := n.prefixes.get(idx); ok {
+				_, pfxLen := idxToPfx(idx)
+				if !fn(8*d+int(pfxLen), val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Subnets calls fn for every prefix covered by octets/bits, including
+// octets/bits itself, in trie order until fn returns false. The octets
+// passed to fn are only valid during the call.
+func (t *Trie[V]) Subnets(octets []byte, bits int, fn func(octets []byte, bits int, val V) bool) {
+	if !validPrefix(octets, bits) {
+		return
+	}
+	depth := bits / 8
+
+	var buf [maxDepth]byte
+	path := buf[:len(octets)]
+	copy(path, octets[:depth])
+
+	n := t.root(len(octets) == 4)
+	for d := 0; d < depth; d++ {
+		c, found := n.children.get(octets[d])
+		if !found {
+			return
+		}
+		if c.leaf != nil {
+			if c.leaf.bits >= bits && within(c.leaf.octets[:], octets, bits) {
+				c.leaf.yield(path, fn)
+			}
+			return
+		}
+		n = c
+	}
+	n.walkCovered(path, depth, octetAt(octets, depth), uint8(bits%8), fn)
+}
+
+// OverlapsPrefix reports whether any prefix in the trie covers
+// octets/bits or is covered by it.
+func (t *Trie[V]) OverlapsPrefix(octets []byte, bits int) bool {
+	found := false
+	t.Supernets(octets, bits, func(int, V) bool {
+		found = true
+		return false
+	})
+	if !found {
+		t.Subnets(octets, bits, func([]byte, int, V) bool {
+			found = true
+			return false
+		})
+	}
+	return found
+}
+
+// Overlaps reports whether any prefix in t overlaps any prefix in o.
+// The smaller trie is walked and every prefix tested against the other.
+func (t *Trie[V]) Overlaps(o *Trie[V]) bool {
+	small, large := t, o
+	if small.size4+small.size6 > large.size4+large.size6 {
+		small, large = large, small
+	}
+	found := false
+	small.Walk(func(octets []byte, bits int, _ V) bool {
+		found = large.OverlapsPrefix(octets, bits)
+		return !found
+	})
+	return found
+}
+
+// Clone returns a deep copy of the trie, values are copied by assignment.
+// Unlike Persist, the copy shares nothing with t.
+func (t *Trie[V]) Clone() *Trie[V] {
+	return &Trie[V]{
+		root4:    *t.root4.clone(),
+		root6:    *t.root6.clone(),
+		size4:    t.size4,
+		size6:    t.size6,
+		Compress: t.Compress,
+	}
+}
+
+// Walk calls fn for every prefix in the trie, IPv4 before IPv6, until fn
+// returns false. The octets passed to fn are masked to bits and only
+// valid during the call.
+func (t *Trie[V]) Walk(fn func(octets []byte, bits int, val V) bool) {
+	var path [maxDepth]byte
+	_ = t.root4.walk(path[:4], 0, fn) && t.root6.walk(path[:], 0, fn)
+}
+
+// WalkSorted is Walk in CIDR order, within an address family by address
+// and then by prefix length.
+func (t *Trie[V]) WalkSorted(fn func(octets []byte, bits int, val V) bool) {
+	var path [maxDepth]byte
+	_ = t.root4.walkSorted(path[:4], 0, nil, 0, fn) && t.root6.walkSorted(path[:], 0, nil, 0, fn)
+}
+
+// WalkSortedAfter is WalkSorted starting after octets/bits, which need not
+// be in the trie. The subtrees before it are skipped.
+func (t *Trie[V]) WalkSortedAfter(octets []byte, bits int, fn func(octets []byte, bits int, val V) bool) {
+	if !validPrefix(octets, bits) {
+		return
+	}
+	var path [maxDepth]byte
+	after := slices.Clone(octets)
+	mask(after, bits)
+	if len(after) == 4 {
+		_ = t.root4.walkSorted(path[:4], 0, after, bits, fn) && t.root6.walkSorted(path[:], 0, nil, 0, fn)
+		return
+	}
+	t.root6.walkSorted(path[:], 0, after, bits, fn)
+}
+
+// Size4 returns the number of IPv4 prefixes.
+func (t *Trie[V]) Size4() int { return t.size4 }
+
+// Size6 returns the number of IPv6 prefixes.
+func (t *Trie[V]) Size6() int { return t.size6 }
+
+// octetAt returns octets[d], or 0 below the last octet.
+func octetAt(octets []byte, d int) uint8 {
+	if d < len(octets) {
+		return octets[d]
+	}
+	return 0
+}
+
+// Diff calls fn for every prefix that is present in only one of t and o
+// or in both, walking the tries side by side. inT and inO tell where the
+// prefix is present, the missing value is zero. With shared, as for
+// persistent versions of one trie, prefixes with equal values are not
+// reported and nodes shared by both tries are skipped. The walk stops
+// once fn returns false.
+func Diff[V comparable](t, o *Trie[V], shared bool, fn func(octets []byte, bits int, a, b V, inT, inO bool) bool) {
+	var path [maxDepth]byte
+	_ = diffNodes(&t.root4, &o.root4, path[:4], 0, shared, fn) &&
+		diffNodes(&t.root6, &o.root6, path[:], 0, shared, fn)
+}
+
+// SamePrefixes reports whether t and o hold the same set of prefixes,
+// regardless of their values. Nodes shared by both tries are skipped.
+func SamePrefixes[V comparable](t, o *Trie[V]) bool {
+	if t.size4 != o.size4 || t.size6 != o.size6 {
+		return false
+	}
+	same := true
+	Diff(t, o, true, func(_ []byte, _ int, _, _ V, inT, inO bool) bool {
+		same = inT && inO
+		return same
+	})
+	return same
+}
+
+// diffNodes compares the nodes at the same position of two tries, either
+// may be nil.
+func diffNodes[V comparable](a, b *node[V], path []byte, depth int, shared bool, fn func([]byte, int, V, V, bool, bool) bool) bool {
+	if a == b && (shared || a == nil) {
+		return true
+	}
+	a, b = a.expand(depth), b.expand(depth)
+	for i := uint(1); i < 256; i++ {
+		idx := uint8(i)
+		va, inA := a.prefixAt(idx)
+		vb, inB := b.prefixAt(idx)
+		if !inA && !inB || inA && inB && shared && va == vb {
+			continue
+		}
+		octet, bits := idxToPfx(idx)
+		if depth < len(path) {
+			path[depth] = octet
+			clear(path[depth+1:])
+		}
+		if !fn(path, depth*8+int(bits), va, vb, inA, inB) {
+			return false
+		}
+	}
+	for i := range uint(256) {
+		c := uint8(i)
+		ca, cb := a.childAt(c), b.childAt(c)
+		if ca == nil && cb == nil {
+			continue
+		}
+		path[depth] = c
+		if !diffNodes(ca, cb, path, depth+1, shared, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixAt and childAt are prefixes.get and children.get of a node that
+// may be nil.
+func (n *node[V]) prefixAt(idx uint8) (val V, ok bool) {
+	if n == nil {
+		return val, false
+	}
+	return n.prefixes.get(idx)
+}
+
+func (n *node[V]) childAt(octet uint8) *node[V] {
+	if n == nil {
+		return nil
+	}
+	c, _ := n.children.get(octet)
+	return c
+}
+
+// Graft moves the subtries below the roots of o into t and leaves o
+// empty, the merge step of a parallel load. It reports false and changes
+// nothing if t has a child at any of the first octets of o or o has
+// prefixes shorter than /8.
+func (t *Trie[V]) Graft(o *Trie[V]) bool {
+	for _, is4 := range []bool{true, false} {
+		r, or := t.root(is4), o.root(is4)
+		if or.prefixes.len() > 0 {
+			return false
+		}
+		for w := range r.children.bitset256 {
+			if r.children.bitset256[w]&or.children.bitset256[w] != 0 {
+				return false
+			}
+		}
+	}
+	for _, is4 := range []bool{true, false} {
+		r, or := t.ownRoot(is4), o.root(is4)
+		k := 0
+		for c, ok := or.children.next(0); ok; c, ok = or.children.next(uint(c) + 1) {
+			kid := or.children.items[k]
+			k++
+			if kid.gen != t.gen {
+				kid.adopt(o.gen, t.gen)
+			}
+			r.children.insertAt(c, kid)
+		}
+		*or = node[V]{gen: o.gen}
+	}
+	t.size4 += o.size4
+	t.size6 += o.size6
+	o.size4, o.size6 = 0, 0
+	return true
+}
+
+// adopt hands the nodes of generation from below n over to generation to,
+// for subtries moved between tries. Nodes of other generations are shared
+// and keep theirs.
+func (n *node[V]) adopt(from, to uint64) {
+	if n.gen != from {
+		return
+	}
+	n.gen = to
+	for _, kid := range n.children.items {
+		kid.adopt(from, to)
+	}
+}
@@ -0,0 +1,359 @@
+package bart
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// route is the reference model entry for the brute-force comparisons.
+type route struct {
+	pfx netip.Prefix
+	val int
+}
+
+func randomPrefix(prng *rand.Rand, is4 bool) netip.Prefix {
+	if is4 {
+		var a [4]byte
+		for i := range a {
+			a[i] = byte(prng.UintN(256))
+		}
+		return netip.PrefixFrom(netip.AddrFrom4(a), prng.IntN(33)).Masked()
+	}
+	var a [16]byte
+	// keep the IPv6 space dense enough for overlaps
+	a[0], a[1] = 0x20, 0x01
+	for i := 2; i < 16; i++ {
+		a[i] = byte(prng.UintN(4))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(a), prng.IntN(129)).Masked()
+}
+
+func octets(a netip.Addr) []byte {
+	return a.AsSlice()
+}
+
+// lpm is the brute-force longest-prefix match over routes, it returns the
+// value and the length of the matching prefix.
+func lpm(routes []route, addr netip.Addr) (int, int, bool) {
+	best, val := -1, 0
+	for _, r := range routes {
+		if r.pfx.Contains(addr) && r.pfx.Bits() > best {
+			best, val = r.pfx.Bits(), r.val
+		}
+	}
+	return val, best, best >= 0
+}
+
+func TestTrieLookupMatchesBruteForce(t *testing.T) {
+	prng := rand.New(rand.NewPCG(42, 42))
+	for _, is4 := range []bool{true, false} {
+		var tr Trie[int]
+		var routes []route
+		seen := map[netip.Prefix]int{}
+
+		for i := 0; i < 2000; i++ {
+			pfx := randomPrefix(prng, is4)
+			if j, ok := seen[pfx]; ok {
+				routes[j].val = i
+			} else {
+				seen[pfx] = len(routes)
+				routes = append(routes, route{pfx, i})
+			}
+			tr.Insert(octets(pfx.Addr()), pfx.Bits(), i)
+		}
+
+		for i := 0; i < 5000; i++ {
+			addr := randomPrefix(prng, is4).Addr()
+			want, wantBits, wantOK := lpm(routes, addr)
+			got, gotOK := tr.Lookup(octets(addr))
+			if got != want || gotOK != wantOK {
+				t.Fatalf("Lookup(%s) = %d, %v, want %d, %v", addr, got, gotOK, want, wantOK)
+			}
+			if bits, _, _ := tr.LookupPrefix(octets(addr)); wantOK && bits != wantBits {
+				t.Fatalf("LookupPrefix(%s) bits = %d, want %d", addr, bits, wantBits)
+			}
+		}
+
+		size := tr.Size4()
+		if !is4 {
+			size = tr.Size6()
+		}
+		if size != len(routes) {
+			t.Errorf("size = %d, want %d", size, len(routes))
+		}
+	}
+}
+
+func TestTrieInsertOverwrite(t *testing.T) {
+	var tr Trie[string]
+	a := []byte{10, 0, 0, 0}
+
+	if _, existed := tr.Insert(a, 8, "first"); existed {
+		t.Fatal("Insert into empty trie reported an existing prefix")
+	}
+	old, existed := tr.Insert([]byte{10, 1, 2, 3}, 8, "second")
+	if !existed || old != "first" {
+		t.Fatalf("Insert = %q, %v, want %q, true", old, existed, "first")
+	}
+	if tr.Size4() != 1 {
+		t.Errorf("Size4 = %d, want 1", tr.Size4())
+	}
+}
+
+func TestTrieDeletePurgesNodes(t *testing.T) {
+	prng := rand.New(rand.NewPCG(1, 2))
+	for _, is4 := range []bool{true, false} {
+		var tr Trie[int]
+		var pfxs []netip.Prefix
+		for i := 0; i < 1000; i++ {
+			pfx := randomPrefix(prng, is4)
+			if _, existed := tr.Insert(octets(pfx.Addr()), pfx.Bits(), i); !existed {
+				pfxs = append(pfxs, pfx)
+			}
+		}
+
+		for _, pfx := range pfxs {
+			if _, ok := tr.Delete(octets(pfx.Addr()), pfx.Bits()); !ok {
+				t.Fatalf("Delete(%s) = false, want true", pfx)
+			}
+			if _, ok := tr.Delete(octets(pfx.Addr()), pfx.Bits()); ok {
+				t.Fatalf("second Delete(%s) = true, want false", pfx)
+			}
+		}
+
+		if !tr.root4.isEmpty() || !tr.root6.isEmpty() {
+			t.Error("root nodes not empty after deleting every prefix")
+		}
+		if tr.Size4() != 0 || tr.Size6() != 0 {
+			t.Errorf("sizes = %d, %d, want 0, 0", tr.Size4(), tr.Size6())
+		}
+	}
+}
+
+func TestTrieInvalidPrefix(t *testing.T) {
+	var tr Trie[int]
+	tr.Insert([]byte{10, 0, 0, 0}, 33, 1)
+	tr.Insert([]byte{10, 0, 0}, 8, 1)
+	if tr.Size4() != 0 {
+		t.Errorf("Size4 = %d after invalid inserts, want 0", tr.Size4())
+	}
+	if _, ok := tr.Lookup([]byte{10, 0, 0}); ok {
+		t.Error("Lookup with a 3 byte address matched")
+	}
+}
+
+func TestIdxToPfx(t *testing.T) {
+	for bits := uint8(0); bits < 8; bits++ {
+		for o := 0; o < 256; o++ {
+			octet := uint8(o) & ^uint8(0xff>>bits)
+			gotOctet, gotBits := idxToPfx(pfxToIdx(octet, bits))
+			if gotOctet != octet || gotBits != bits {
+				t.Fatalf("idxToPfx(pfxToIdx(%d, %d)) = %d, %d", octet, bits, gotOctet, gotBits)
+			}
+		}
+	}
+}
+
+func TestTrieFsck(t *testing.T) {
+	prng := rand.New(rand.NewPCG(76, 76))
+	for _, is4 := range []bool{true, false} {
+		var tr Trie[int]
+		var pfxs []netip.Prefix
+		for i := 0; i < 2000; i++ {
+			pfx := randomPrefix(prng, is4)
+			pfxs = append(pfxs, pfx)
+			tr.Insert(octets(pfx.Addr()), pfx.Bits(), i)
+		}
+		for _, pfx := range pfxs[:1000] {
+			tr.Delete(octets(pfx.Addr()), pfx.Bits())
+		}
+		var problems []Problem
+		tr.Fsck(is4, func(p Problem) { problems = append(problems, p) })
+		if len(problems) != 0 {
+			t.Fatalf("is4 %v: healthy trie has problems %+v", is4, problems)
+		}
+
+		// break the coupling of a bitset and its items, and the size
+		root := tr.root(is4)
+		root.prefixes.items = append(root.prefixes.items, 0)
+		tr.sizeUpdate(is4, 1)
+		tr.Fsck(is4, func(p Problem) { problems = append(problems, p) })
+		if len(problems) != 2 || problems[0].Code != FsckPrefixCount || problems[1].Code != FsckSize {
+			t.Errorf("is4 %v: corrupted trie has problems %+v", is4, problems)
+		}
+	}
+}
+
+func TestGraft(t *testing.T) {
+	var a, b Trie[int]
+	a.Insert([]byte{10, 0, 0, 0}, 8, 1)
+	a.Insert([]byte{0, 0, 0, 0}, 0, 2)
+	b.Insert([]byte{11, 1, 0, 0}, 16, 3)
+	b.Insert([]byte{0x20, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 32, 4)
+	v1 := a.Persist()
+
+	if !a.Graft(&b) {
+		t.Fatal("Graft of disjoint tries failed")
+	}
+	if a.Size4() != 3 || a.Size6() != 1 || b.Size4()+b.Size6() != 0 {
+		t.Errorf("sizes after Graft = %d+%d, %d+%d", a.Size4(), a.Size6(), b.Size4(), b.Size6())
+	}
+	if v, ok := a.Lookup([]byte{11, 1, 2, 3}); !ok || v != 3 {
+		t.Errorf("Lookup = %d, %v, want 3", v, ok)
+	}
+	if v, _ := v1.Lookup([]byte{11, 1, 2, 3}); v != 2 {
+		t.Errorf("the persistent version sees the grafted routes")
+	}
+	a.Insert([]byte{11, 1, 2, 0}, 24, 5)
+	if v, _ := a.Lookup([]byte{11, 1, 2, 3}); v != 5 {
+		t.Errorf("Lookup after insert = %d, want 5", v)
+	}
+	var n int
+	a.Fsck(true, func(Problem) { n++ })
+	a.Fsck(false, func(Problem) { n++ })
+	if n != 0 {
+		t.Errorf("Fsck found %d problems", n)
+	}
+
+	// overlapping first octets and short prefixes refuse the graft
+	var c, d Trie[int]
+	c.Insert([]byte{10, 1, 0, 0}, 16, 1)
+	d.Insert([]byte{10, 2, 0, 0}, 16, 2)
+	if c.Graft(&d) {
+		t.Errorf("Graft with a shared first octet succeeded")
+	}
+	d = Trie[int]{}
+	d.Insert([]byte{0, 0, 0, 0}, 4, 2)
+	if c.Graft(&d) || d.Size4() != 1 {
+		t.Errorf("Graft with a short prefix succeeded")
+	}
+}
+
+func TestTrieCompressMatchesUncompressed(t *testing.T) {
+	prng := rand.New(rand.NewPCG(108, 108))
+	for _, is4 := range []bool{true, false} {
+		var plain Trie[int]
+		comp := Trie[int]{Compress: true}
+		var pfxs []netip.Prefix
+		for i := 0; i < 3000; i++ {
+			pfx := randomPrefix(prng, is4)
+			pfxs = append(pfxs, pfx)
+			_, e1 := plain.Insert(octets(pfx.Addr()), pfx.Bits(), i)
+			_, e2 := comp.Insert(octets(pfx.Addr()), pfx.Bits(), i)
+			if e1 != e2 {
+				t.Fatalf("Insert(%s) existed = %v, want %v", pfx, e2, e1)
+			}
+		}
+		old := comp.Persist()
+		for _, pfx := range pfxs[:1500] {
+			v1, ok1 := plain.Delete(octets(pfx.Addr()), pfx.Bits())
+			v2, ok2 := comp.Delete(octets(pfx.Addr()), pfx.Bits())
+			if v1 != v2 || ok1 != ok2 {
+				t.Fatalf("Delete(%s) = %d, %v, want %d, %v", pfx, v2, ok2, v1, ok1)
+			}
+		}
+
+		var problems []Problem
+		comp.Fsck(is4, func(p Problem) { problems = append(problems, p) })
+		old.Fsck(is4, func(p Problem) { problems = append(problems, p) })
+		if len(problems) != 0 {
+			t.Fatalf("is4 %v: compressed trie has problems %+v", is4, problems)
+		}
+		ps, cs := plain.Stats(is4), comp.Stats(is4)
+		if cs.Prefixes != ps.Prefixes || cs.Leaves+cs.Fringes == 0 || cs.Nodes >= ps.Nodes {
+			t.Errorf("is4 %v: stats %+v, uncompressed %+v", is4, cs, ps)
+		}
+
+		same := true
+		Diff(&plain, &comp, false, func(_ []byte, _ int, a, b int, inT, inO bool) bool {
+			same = inT && inO && a == b
+			return same
+		})
+		if !same {
+			t.Fatalf("is4 %v: Diff found differences", is4)
+		}
+
+		for range 2000 {
+			probe := randomPrefix(prng, is4)
+			o, bits := octets(probe.Addr()), probe.Bits()
+			b1, v1, ok1 := plain.LookupPrefix(o)
+			b2, v2, ok2 := comp.LookupPrefix(o)
+			if b1 != b2 || v1 != v2 || ok1 != ok2 {
+				t.Fatalf("LookupPrefix(%s) = /%d %d %v, want /%d %d %v", probe.Addr(), b2, v2, ok2, b1, v1, ok1)
+			}
+			if comp.Contains(o) != ok1 {
+				t.Fatalf("Contains(%s) != %v", probe.Addr(), ok1)
+			}
+			b1, v1, ok1 = plain.LookupShortest(o)
+			b2, v2, ok2 = comp.LookupShortest(o)
+			if b1 != b2 || v1 != v2 || ok1 != ok2 {
+				t.Fatalf("LookupShortest(%s) = /%d %d %v, want /%d %d %v", probe.Addr(), b2, v2, ok2, b1, v1, ok1)
+			}
+			v1, ok1 = plain.Get(o, bits)
+			v2, ok2 = comp.Get(o, bits)
+			if v1 != v2 || ok1 != ok2 {
+				t.Fatalf("Get(%s) = %d %v, want %d %v", probe, v2, ok2, v1, ok1)
+			}
+			var sup1, sup2 []int
+			plain.Supernets(o, bits, func(b, _ int) bool { sup1 = append(sup1, b); return true })
+			comp.Supernets(o, bits, func(b, _ int) bool { sup2 = append(sup2, b); return true })
+			if !slices.Equal(sup1, sup2) {
+				t.Fatalf("Supernets(%s) = %v, want %v", probe, sup2, sup1)
+			}
+			if got, want := collect(comp.Subnets, o, bits), collect(plain.Subnets, o, bits); !slices.Equal(got, want) {
+				t.Fatalf("Subnets(%s) = %v, want %v", probe, got, want)
+			}
+		}
+
+		var sorted1, sorted2 []string
+		plain.WalkSorted(func(o []byte, bits int, _ int) bool {
+			sorted1 = append(sorted1, fmt.Sprint(o, bits))
+			return true
+		})
+		comp.WalkSorted(func(o []byte, bits int, _ int) bool {
+			sorted2 = append(sorted2, fmt.Sprint(o, bits))
+			return true
+		})
+		if !slices.Equal(sorted1, sorted2) {
+			t.Fatalf("is4 %v: WalkSorted differs", is4)
+		}
+		for range 200 {
+			probe := randomPrefix(prng, is4)
+			after := func(tr *Trie[int]) func([]byte, int, func([]byte, int, int) bool) {
+				return func(o []byte, bits int, fn func([]byte, int, int) bool) { tr.WalkSortedAfter(o, bits, fn) }
+			}
+			o := octets(probe.Addr())
+			if got, want := collect(after(&comp), o, probe.Bits()), collect(after(&plain), o, probe.Bits()); !slices.Equal(got, want) {
+				t.Fatalf("WalkSortedAfter(%s) = %v, want %v", probe, got, want)
+			}
+		}
+
+		// the version persisted before the deletes still has all routes
+		for _, pfx := range pfxs[:1500] {
+			if _, ok := old.Get(octets(pfx.Addr()), pfx.Bits()); !ok {
+				t.Fatalf("persisted version lost %s", pfx)
+			}
+		}
+
+		for _, pfx := range pfxs {
+			comp.Delete(octets(pfx.Addr()), pfx.Bits())
+		}
+		if !comp.root(is4).isEmpty() || comp.Size4()+comp.Size6() != 0 {
+			t.Errorf("is4 %v: trie not empty after deleting every prefix", is4)
+		}
+	}
+}
+
+// collect returns the prefixes a walk like Subnets passes to fn, as
+// strings in the order seen.
+func collect(subnets func([]byte, int, func([]byte, int, int) bool), o []byte, bits int) []string {
+	var out []string
+	subnets(o, bits, func(o []byte, bits int, _ int) bool {
+		out = append(out, fmt.Sprint(o, bits))
+		return true
+	})
+	return out
+}
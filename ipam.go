@@ -0,0 +1,93 @@
+package zart
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// FindFreeBlock returns the unused /bits subnet of within with the lowest
+// address, for address management on top of the table: the prefixes in
+// the table are the allocations, a block is free if it neither covers nor
+// is covered by one of them. Prefixes covering within, the pool itself
+// among them, are not allocations, so pools and their allocations can
+// share a table. ok is false if within has no free block of that size,
+// is invalid, or bits is out of range for it.
+//
+// The pool is halved towards the free space, looking only at the
+// allocations inside it: the search takes time proportional to their
+// number times the depth of the block below within.
+func (t *Table[V]) FindFreeBlock(within netip.Prefix, bits int) (blk netip.Prefix, ok bool) {
+	if !within.IsValid() || bits < within.Bits() || bits > within.Addr().BitLen() {
+		return blk, false
+	}
+	within = within.Masked()
+	var used []netip.Prefix
+	for pfx := range t.Subnets(within) {
+		if pfx != within {
+			used = append(used, pfx)
+		}
+	}
+	slices.SortFunc(used, comparePrefix)
+	return freeBlock(within, bits, used)
+}
+
+// freeBlock is FindFreeBlock in p for the allocations used, which are
+// covered by p or p itself, in CIDR order.
+func freeBlock(p netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, bool) {
+	switch {
+	case len(used) == 0:
+		return netip.PrefixFrom(p.Addr(), bits), true
+	case used[0] == p || p.Bits() == bits:
+		return netip.Prefix{}, false
+	}
+	lo := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+	hi := netip.PrefixFrom(setBit(p.Addr(), p.Bits()), p.Bits()+1)
+	i := 0
+	for i < len(used) && lo.Contains(used[i].Addr()) {
+		i++
+	}
+	if blk, ok := freeBlock(lo, bits, used[:i]); ok {
+		return blk, true
+	}
+	return freeBlock(hi, bits, used[i:])
+}
+
+// setBit returns addr with bit i, counted from the most significant one,
+// set.
+func setBit(addr netip.Addr, i int) netip.Addr {
+	if addr.Is4() {
+		a := addr.As4()
+		a[i/8] |= 0x80 >> (i % 8)
+		return netip.AddrFrom4(a)
+	}
+	a := addr.As16()
+	a[i/8] |= 0x80 >> (i % 8)
+	return netip.AddrFrom16(a)
+}
+
+// ReserveNext allocates the block FindFreeBlock returns by inserting it
+// with val, and returns it. ok is false, and the table unchanged, if there
+// is no free block or the table is over the limit of WithMaxMemory.
+func (t *Table[V]) ReserveNext(within netip.Prefix, bits int, val V) (blk netip.Prefix, ok bool) {
+	blk, ok = t.FindFreeBlock(within, bits)
+	if !ok || !t.room(blk) {
+		return netip.Prefix{}, false
+	}
+	t.Insert(blk, val)
+	return blk, true
+}
+
+// FindFreeBlock is like Table.FindFreeBlock.
+func (c *ConcurrentTable[V]) FindFreeBlock(within netip.Prefix, bits int) (netip.Prefix, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.FindFreeBlock(within, bits)
+}
+
+// ReserveNext is like Table.ReserveNext. The block is found and inserted
+// under one lock, concurrent reservations never return the same block.
+func (c *ConcurrentTable[V]) ReserveNext(within netip.Prefix, bits int, val V) (netip.Prefix, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.ReserveNext(within, bits, val)
+}
@@ -0,0 +1,126 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestFindFreeBlock(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), "supernet")
+	tbl.Insert(mpp("10.0.0.0/16"), "pool")
+	tbl.Insert(mpp("10.0.0.0/24"), "node-a")
+	tbl.Insert(mpp("10.0.1.0/25"), "node-b")
+	tbl.Insert(mpp("10.0.2.0/23"), "node-c")
+	tbl.Insert(mpp("10.1.0.0/24"), "other pool")
+
+	for _, tc := range []struct {
+		within string
+		bits   int
+		want   string
+	}{
+		{"10.0.0.0/16", 24, "10.0.4.0/24"},
+		{"10.0.0.0/16", 25, "10.0.1.128/25"},
+		{"10.0.0.0/16", 32, "10.0.1.128/32"},
+		{"10.0.0.0/16", 22, "10.0.4.0/22"},
+		{"10.0.0.0/16", 16, ""}, // allocations inside
+		{"10.0.0.0/16", 15, ""}, // larger than the pool
+		{"10.0.0.0/16", 33, ""},
+		{"10.0.1.0/24", 25, "10.0.1.128/25"},
+		{"10.0.0.77/16", 24, "10.0.4.0/24"}, // masked
+		{"10.0.0.0/8", 16, "10.2.0.0/16"},
+		{"192.168.0.0/24", 24, "192.168.0.0/24"},
+	} {
+		got, ok := tbl.FindFreeBlock(mpp(tc.within), tc.bits)
+		if ok != (tc.want != "") || ok && got != mpp(tc.want) {
+			t.Errorf("FindFreeBlock(%s, %d) = %v, %v, want %q", tc.within, tc.bits, got, ok, tc.want)
+		}
+	}
+	if _, ok := tbl.FindFreeBlock(netip.Prefix{}, 24); ok {
+		t.Errorf("FindFreeBlock of an invalid prefix found a block")
+	}
+}
+
+func TestFindFreeBlockRandom(t *testing.T) {
+	prng := rand.New(rand.NewPCG(117, 117))
+	pool := mpp("2001:db8::/118")
+	for range 50 {
+		tbl := New[int]()
+		for i := range 40 {
+			bits := 118 + prng.IntN(11)
+			addr := pool.Addr().As16()
+			addr[14], addr[15] = byte(prng.IntN(4)), byte(prng.Uint32())
+			tbl.Insert(netip.PrefixFrom(netip.AddrFrom16(addr), bits).Masked(), i)
+		}
+		for bits := 118; bits <= 128; bits++ {
+			got, ok := tbl.FindFreeBlock(pool, bits)
+			want, wantOK := scanFreeBlock(tbl, pool, bits)
+			if ok != wantOK || got != want {
+				t.Fatalf("FindFreeBlock(%s, %d) = %v, %v, want %v, %v", pool, bits, got, ok, want, wantOK)
+			}
+		}
+		tbl.Close()
+	}
+}
+
+// scanFreeBlock is FindFreeBlock trying every block in turn.
+func scanFreeBlock(tbl *Table[int], within netip.Prefix, bits int) (netip.Prefix, bool) {
+	step := uint64(1) << (128 - bits)
+	base := within.Addr().As16()
+	for off := uint64(0); off < 1<<(128-within.Bits()); off += step {
+		addr := base
+		addr[14], addr[15] = byte(off>>8), byte(off)
+		blk := netip.PrefixFrom(netip.AddrFrom16(addr), bits)
+		free := true
+		for pfx := range tbl.All() {
+			if pfx != within && pfx.Overlaps(blk) && !covers(pfx, within) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return blk, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+func TestReserveNext(t *testing.T) {
+	c := NewConcurrent[int]()
+	pool := mpp("192.168.10.0/28")
+	c.Insert(pool, -1)
+	var got []netip.Prefix
+	for i := 0; ; i++ {
+		blk, ok := c.ReserveNext(pool, 30, i)
+		if !ok {
+			break
+		}
+		if v, _ := c.Get(blk); v != i {
+			t.Fatalf("%s reserved with %d, holds %d", blk, i, v)
+		}
+		got = append(got, blk)
+	}
+	want := []netip.Prefix{
+		mpp("192.168.10.0/30"), mpp("192.168.10.4/30"),
+		mpp("192.168.10.8/30"), mpp("192.168.10.12/30"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("reserved %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("reserved %v, want %v", got, want)
+		}
+	}
+
+	// a released block is handed out again first
+	c.Delete(mpp("192.168.10.4/30"))
+	if blk, ok := c.ReserveNext(pool, 30, 9); !ok || blk != mpp("192.168.10.4/30") {
+		t.Fatalf("ReserveNext after release = %v, %v", blk, ok)
+	}
+	if _, ok := c.ReserveNext(pool, 30, 10); ok {
+		t.Fatalf("ReserveNext in a full pool found a block")
+	}
+}
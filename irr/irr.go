@@ -0,0 +1,180 @@
+// Package irr loads the route and route6 objects of Internet Routing
+// Registries into zart tables, from the RPSL dumps of IRRd and the
+// registries' bulk whois files, like radb.db.gz or ripe.db.route.gz.
+//
+// A route object registers the origin AS that may announce a prefix:
+//
+//	route:   192.0.2.0/24
+//	descr:   Example
+//	origin:  AS64500
+//	mnt-by:  MAINT-EXAMPLE
+//	source:  RADB
+//
+// Objects are separated by blank lines, the other object classes and the
+// attributes other than route, route6, origin and source are skipped.
+// Dumps are usually compressed, wrap the reader with compress/gzip as
+// needed.
+package irr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrFormat is returned, wrapped, for route objects that do not parse.
+var ErrFormat = errors.New("irr: malformed route object")
+
+// Route is a route or route6 object.
+type Route struct {
+	Prefix netip.Prefix
+	Origin uint32
+	Source string // the registry, like "RADB", in upper case
+}
+
+// Reader reads the route objects of an RPSL dump one by one.
+type Reader struct {
+	sc      *bufio.Scanner
+	line    int
+	sources []string
+}
+
+// NewReader returns a Reader for the RPSL dump in r. With sources, only
+// the objects of these registries are read, matched case insensitively.
+func NewReader(r io.Reader, sources ...string) *Reader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	rd := &Reader{sc: sc}
+	for _, s := range sources {
+		rd.sources = append(rd.sources, strings.ToUpper(s))
+	}
+	return rd
+}
+
+// Next returns the next route object. At the end of the input it
+// returns io.EOF.
+func (r *Reader) Next() (Route, error) {
+	for {
+		obj, start, err := r.object()
+		if err != nil {
+			return Route{}, err
+		}
+		rt, ok, err := parse(obj)
+		if err != nil {
+			return Route{}, fmt.Errorf("%w: line %d: %w", ErrFormat, start, err)
+		}
+		if ok && (len(r.sources) == 0 || slices.Contains(r.sources, rt.Source)) {
+			return rt, nil
+		}
+	}
+}
+
+// object returns the attributes of the next object, continuation lines
+// folded into their attribute, and its first line.
+func (r *Reader) object() (attrs []string, start int, err error) {
+	for r.sc.Scan() {
+		r.line++
+		line := r.sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			if attrs != nil {
+				return attrs, start, nil
+			}
+		case line[0] == '%' || line[0] == '#':
+			// whois comments and remarks of the dump
+		case line[0] == ' ' || line[0] == '\t' || line[0] == '+':
+			if attrs != nil {
+				attrs[len(attrs)-1] += " " + strings.TrimSpace(line[1:])
+			}
+		default:
+			if attrs == nil {
+				start = r.line
+			}
+			attrs = append(attrs, line)
+		}
+	}
+	if err := r.sc.Err(); err != nil {
+		return nil, 0, err
+	}
+	if attrs != nil {
+		return attrs, start, nil
+	}
+	return nil, 0, io.EOF
+}
+
+// parse returns the route of an object, ok is false for the objects of
+// other classes.
+func parse(attrs []string) (rt Route, ok bool, err error) {
+	class, _, _ := strings.Cut(attrs[0], ":")
+	if class = strings.ToLower(class); class != "route" && class != "route6" {
+		return rt, false, nil
+	}
+	var origin string
+	for _, a := range attrs {
+		key, val, _ := strings.Cut(a, ":")
+		val, _, _ = strings.Cut(val, "#")
+		val = strings.TrimSpace(val)
+		switch strings.ToLower(key) {
+		case "route", "route6":
+			if rt.Prefix, err = netip.ParsePrefix(val); err != nil {
+				return rt, false, fmt.Errorf("prefix %q", val)
+			}
+		case "origin":
+			origin = val
+		case "source":
+			rt.Source = strings.ToUpper(val)
+		}
+	}
+	asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(origin), "AS"), 10, 32)
+	if err != nil {
+		return rt, false, fmt.Errorf("%s %s: origin %q", class, rt.Prefix, origin)
+	}
+	rt.Origin = uint32(asn)
+	return rt, true, nil
+}
+
+// Load inserts the prefixes of the route objects in r into t, mapping
+// each to all origins registered for it, in ascending order, and returns
+// the number of objects read. With sources only the objects of these
+// registries are loaded. On error the objects read before it are in the
+// table.
+func Load(t *zart.Table[[]uint32], r io.Reader, sources ...string) (n int, err error) {
+	rd := NewReader(r, sources...)
+	for {
+		rt, err := rd.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		t.Modify(rt.Prefix, func(origins []uint32, _ bool) ([]uint32, bool) {
+			if i, found := slices.BinarySearch(origins, rt.Origin); !found {
+				origins = slices.Insert(origins, i, rt.Origin)
+			}
+			return origins, false
+		})
+		n++
+	}
+}
+
+// Format is the RPSL format for Table.Load, the values are the route
+// objects. Of several objects for a prefix the last one read is kept;
+// use Load to keep all origins.
+func Format(sources ...string) zart.Format[Route] {
+	return func(r io.Reader) zart.Decoder[Route] { return decoder{NewReader(r, sources...)} }
+}
+
+type decoder struct{ rd *Reader }
+
+func (d decoder) Next() (zart.RouteEntry[Route], error) {
+	rt, err := d.rd.Next()
+	return zart.RouteEntry[Route]{Prefix: rt.Prefix, Value: rt}, err
+}
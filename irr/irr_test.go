@@ -0,0 +1,89 @@
+package irr
+
+import (
+	"errors"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+const dump = `% This is the RIPE Database query service.
+% Note: this output has been filtered.
+
+route:          192.0.2.0/24
+descr:          Example
+origin:         AS64500
+mnt-by:         MAINT-EXAMPLE
+source:         RADB
+
+aut-num:        AS64500
+as-name:        EXAMPLE
+source:         RADB
+
+route:          192.0.2.0/24
+descr:          Example, announced
+                by a second origin
+origin:         as64501 # the backup
+source:         RIPE
+
+route6:         2001:db8::/32
+origin:         AS64502
+remarks:        multi
++               line
+source:         radb
+`
+
+func TestReader(t *testing.T) {
+	rd := NewReader(strings.NewReader(dump))
+	var got []Route
+	for {
+		rt, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rt)
+	}
+	want := []Route{
+		{netip.MustParsePrefix("192.0.2.0/24"), 64500, "RADB"},
+		{netip.MustParsePrefix("192.0.2.0/24"), 64501, "RIPE"},
+		{netip.MustParsePrefix("2001:db8::/32"), 64502, "RADB"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("routes = %v, want %v", got, want)
+	}
+
+	bad := "route: 192.0.2.0/24\norigin: ASx\nsource: RADB\n"
+	if _, err := NewReader(strings.NewReader(bad)).Next(); !errors.Is(err, ErrFormat) {
+		t.Errorf("Next of a bad origin = %v, want ErrFormat", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tbl := zart.New[[]uint32]()
+	if n, err := Load(tbl, strings.NewReader(dump)); err != nil || n != 3 {
+		t.Fatalf("Load = %d, %v", n, err)
+	}
+	if got, _ := tbl.Lookup(netip.MustParseAddr("192.0.2.1")); !slices.Equal(got, []uint32{64500, 64501}) {
+		t.Errorf("origins of 192.0.2.0/24 = %v", got)
+	}
+
+	tbl = zart.New[[]uint32]()
+	if n, err := Load(tbl, strings.NewReader(dump), "ripe"); err != nil || n != 1 {
+		t.Fatalf("Load(ripe) = %d, %v", n, err)
+	}
+	if got, _ := tbl.Lookup(netip.MustParseAddr("192.0.2.1")); !slices.Equal(got, []uint32{64501}) || tbl.Size() != 1 {
+		t.Errorf("Load(ripe) = %v, size %d", got, tbl.Size())
+	}
+
+	routes := zart.New[Route]()
+	if n, err := routes.Load(strings.NewReader(dump), Format("RADB"), nil); err != nil || n != 2 {
+		t.Fatalf("Table.Load(Format(RADB)) = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,260 @@
+package zart
+
+import (
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Lookup latencies are recorded in log-linear buckets, as HDR histograms
+// do: a bucket per nanosecond below 2*latencySub, then latencySub buckets
+// per power of two, so a bucket is at most 1/32 of its lower bound wide.
+// The last bucket takes everything from about 68s.
+const (
+	latencySubBits = 5
+	latencySub     = 1 << latencySubBits
+	latencyBuckets = 2*latencySub + 30*latencySub
+)
+
+// WithLatencySampling makes the table time about one in every lookups by
+// Lookup and LookupPrefix, and record the latency in histograms by
+// address family and hit or miss, see LatencyStats. An every of 1 or less
+// times every lookup. Lookups that are not sampled don't read the clock.
+// Tables made from the table share its histograms.
+func WithLatencySampling(every int) Option {
+	return func(o *options) { o.lat = &latencies{every: uint32(max(every, 1))} }
+}
+
+// latencies holds the histograms of a table, recorded into concurrently
+// by the lookups of a ConcurrentTable.
+type latencies struct {
+	every uint32
+	hists [4]histogram // by family and hit, see slot
+}
+
+func (l *latencies) slot(is4, hit bool) *histogram {
+	i := 0
+	if !is4 {
+		i = 2
+	}
+	if !hit {
+		i++
+	}
+	return &l.hists[i]
+}
+
+// sample reports whether the next lookup is timed.
+func (l *latencies) sample() bool {
+	return l.every == 1 || rand.Uint32N(l.every) == 0
+}
+
+type histogram struct {
+	counts [latencyBuckets]atomic.Uint64
+	sum    atomic.Uint64
+	min    atomic.Uint64 // +1, 0 while empty
+	max    atomic.Uint64
+}
+
+func (h *histogram) record(d time.Duration) {
+	ns := uint64(max(d, 0))
+	h.counts[latencyBucket(ns)].Add(1)
+	h.sum.Add(ns)
+	for cur := h.min.Load(); cur == 0 || ns+1 < cur; cur = h.min.Load() {
+		if h.min.CompareAndSwap(cur, ns+1) {
+			break
+		}
+	}
+	for cur := h.max.Load(); ns > cur; cur = h.max.Load() {
+		if h.max.CompareAndSwap(cur, ns) {
+			break
+		}
+	}
+}
+
+func (h *histogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.sum.Store(0)
+	h.min.Store(0)
+	h.max.Store(0)
+}
+
+func (h *histogram) snapshot() LatencyHistogram {
+	s := LatencyHistogram{counts: make([]uint64, latencyBuckets)}
+	for i := range h.counts {
+		s.counts[i] = h.counts[i].Load()
+		s.Count += s.counts[i]
+	}
+	s.Sum = time.Duration(h.sum.Load())
+	if m := h.min.Load(); m > 0 {
+		s.Min = time.Duration(m - 1)
+	}
+	s.Max = time.Duration(h.max.Load())
+	return s
+}
+
+// latencyBucket returns the bucket of ns nanoseconds.
+func latencyBucket(ns uint64) int {
+	if ns < 2*latencySub {
+		return int(ns)
+	}
+	shift := bits.Len64(ns) - latencySubBits - 1
+	i := latencySub*shift + int(ns>>shift)
+	return min(i, latencyBuckets-1)
+}
+
+// latencyBound returns the largest duration falling into bucket i.
+func latencyBound(i int) time.Duration {
+	if i < 2*latencySub {
+		return time.Duration(i)
+	}
+	if i == latencyBuckets-1 {
+		return math.MaxInt64
+	}
+	shift := i/latencySub - 1
+	m := uint64(i%latencySub + latencySub)
+	return time.Duration((m+1)<<shift - 1)
+}
+
+// LatencyStats holds the sampled lookup latencies of a table by address
+// family and by whether the lookup found a prefix. Addresses looked up
+// as IPv4 after WithMapped count as IPv4.
+type LatencyStats struct {
+	IPv4Hit, IPv4Miss LatencyHistogram
+	IPv6Hit, IPv6Miss LatencyHistogram
+}
+
+// All returns the histogram of all sampled lookups.
+func (s LatencyStats) All() LatencyHistogram {
+	return s.IPv4Hit.Merge(s.IPv4Miss).Merge(s.IPv6Hit.Merge(s.IPv6Miss))
+}
+
+// LatencyHistogram is a histogram of lookup latencies, Count lookups
+// taking Sum in total, between Min and Max.
+type LatencyHistogram struct {
+	Count    uint64
+	Sum      time.Duration
+	Min, Max time.Duration
+
+	counts []uint64
+}
+
+// Mean returns the average latency, 0 for an empty histogram.
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the latency that the share q of the lookups did not
+// exceed, Quantile(0.999) is the p999. The result is the upper bound of
+// a bucket, at most 1/32 above the true quantile, and never above Max.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(min(max(q, 0), 1) * float64(h.Count)))
+	var seen uint64
+	for i, n := range h.counts {
+		if seen += n; seen >= max(rank, 1) {
+			return min(latencyBound(i), h.Max)
+		}
+	}
+	return h.Max
+}
+
+// Merge returns the histogram of the lookups of both h and o.
+func (h LatencyHistogram) Merge(o LatencyHistogram) LatencyHistogram {
+	switch {
+	case o.Count == 0:
+		return h
+	case h.Count == 0:
+		return o
+	}
+	m := LatencyHistogram{
+		Count:  h.Count + o.Count,
+		Sum:    h.Sum + o.Sum,
+		Min:    min(h.Min, o.Min),
+		Max:    max(h.Max, o.Max),
+		counts: make([]uint64, latencyBuckets),
+	}
+	for i := range m.counts {
+		m.counts[i] = h.counts[i] + o.counts[i]
+	}
+	return m
+}
+
+// LatencyStats returns the lookup latencies sampled since the table was
+// created or the histograms were reset, the zero value without
+// WithLatencySampling.
+func (t *Table[V]) LatencyStats() LatencyStats {
+	l := t.opts.lat
+	if l == nil {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		IPv4Hit: l.hists[0].snapshot(), IPv4Miss: l.hists[1].snapshot(),
+		IPv6Hit: l.hists[2].snapshot(), IPv6Miss: l.hists[3].snapshot(),
+	}
+}
+
+// ResetLatencyStats empties the latency histograms, for measuring the
+// next interval on its own.
+func (t *Table[V]) ResetLatencyStats() {
+	if l := t.opts.lat; l != nil {
+		for i := range l.hists {
+			l.hists[i].reset()
+		}
+	}
+}
+
+// lookupTimer times a lookup for the telemetry and the latency
+// histograms of a table.
+type lookupTimer struct {
+	tel   Telemetry
+	lat   *latencies // nil unless the lookup is sampled
+	start time.Time
+}
+
+// timer starts timing a lookup, it reports false if nobody measures it.
+func (t *Table[V]) timer() (lookupTimer, bool) {
+	lt := lookupTimer{tel: t.opts.tel}
+	if l := t.opts.lat; l != nil && l.sample() {
+		lt.lat = l
+	}
+	if lt.tel == nil && lt.lat == nil {
+		return lt, false
+	}
+	lt.start = time.Now()
+	return lt, true
+}
+
+// stop records a lookup of an IPv4 or IPv6 address, as the table looked
+// it up.
+func (lt lookupTimer) stop(is4, hit bool) {
+	d := time.Since(lt.start)
+	if lt.tel != nil {
+		lt.tel.Lookup(d, hit)
+	}
+	if lt.lat != nil {
+		lt.lat.slot(is4, hit).record(d)
+	}
+}
+
+// LatencyStats is like Table.LatencyStats.
+func (c *ConcurrentTable[V]) LatencyStats() LatencyStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LatencyStats()
+}
+
+// ResetLatencyStats is like Table.ResetLatencyStats.
+func (c *ConcurrentTable[V]) ResetLatencyStats() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.t.ResetLatencyStats()
+}
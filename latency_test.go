@@ -0,0 +1,101 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	prev := -1
+	for _, ns := range []uint64{0, 1, 63, 64, 65, 100, 1000, 12345, 1 << 20, 1<<36 - 1, 1 << 40} {
+		i := latencyBucket(ns)
+		if i < prev || i >= latencyBuckets {
+			t.Errorf("bucket of %d = %d after %d", ns, i, prev)
+		}
+		prev = i
+		if i == latencyBuckets-1 {
+			continue // open ended
+		}
+		if hi := latencyBound(i); uint64(hi) < ns || float64(uint64(hi)-ns) > float64(ns)/32+1 {
+			t.Errorf("bucket %d of %d ends at %d", i, ns, hi)
+		}
+		if i > 0 && uint64(latencyBound(i-1)) >= ns {
+			t.Errorf("bucket %d of %d starts after %d", i, ns, latencyBound(i-1))
+		}
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	tbl := New[int](WithLatencySampling(1))
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("2001:db8::/32"), 2)
+
+	for range 100 {
+		tbl.Lookup(mpa("10.1.2.3"))
+		tbl.Lookup(mpa("11.1.2.3"))
+	}
+	for range 50 {
+		tbl.LookupPrefix(mpa("2001:db8::1"))
+	}
+	s := tbl.LatencyStats()
+	if s.IPv4Hit.Count != 100 || s.IPv4Miss.Count != 100 || s.IPv6Hit.Count != 50 || s.IPv6Miss.Count != 0 {
+		t.Fatalf("counts %d %d %d %d", s.IPv4Hit.Count, s.IPv4Miss.Count, s.IPv6Hit.Count, s.IPv6Miss.Count)
+	}
+	all := s.All()
+	if all.Count != 250 || all.Min > all.Max || all.Mean() < all.Min || all.Mean() > all.Max {
+		t.Errorf("all: count %d, min %v, mean %v, max %v", all.Count, all.Min, all.Mean(), all.Max)
+	}
+	if p50, p999 := all.Quantile(0.5), all.Quantile(0.999); p50 > p999 || p999 > all.Max || p50 < all.Min {
+		t.Errorf("p50 %v, p999 %v, min %v, max %v", p50, p999, all.Min, all.Max)
+	}
+
+	tbl.ResetLatencyStats()
+	if n := tbl.LatencyStats().All().Count; n != 0 {
+		t.Errorf("%d lookups after reset", n)
+	}
+	plain := New[int]()
+	defer plain.Close()
+	plain.Lookup(mpa("10.1.2.3"))
+	if h := plain.LatencyStats().All(); h.Count != 0 || h.Quantile(0.5) != 0 {
+		t.Error("table without sampling has latencies")
+	}
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	var h histogram
+	prng := rand.New(rand.NewPCG(110, 110))
+	for range 10000 {
+		h.record(time.Duration(1000 + prng.IntN(1000)))
+	}
+	h.record(time.Millisecond)
+	s := h.snapshot()
+	if p := s.Quantile(0.5); p < 1400*time.Nanosecond || p > 1600*time.Nanosecond {
+		t.Errorf("p50 = %v, want about 1.5µs", p)
+	}
+	if s.Quantile(1) != time.Millisecond || s.Max != time.Millisecond || s.Min < 1000 {
+		t.Errorf("p100 %v, max %v, min %v", s.Quantile(1), s.Max, s.Min)
+	}
+}
+
+func TestLatencySamplingConcurrent(t *testing.T) {
+	c := New[int](WithLatencySampling(4)).WithLocking()
+	defer c.Close()
+	c.Insert(mpp("10.0.0.0/8"), 1)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10000 {
+				c.Lookup(mpa("10.1.2.3"))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := c.LatencyStats().IPv4Hit.Count; n < 5000 || n > 15000 {
+		t.Errorf("%d of 40000 lookups sampled, want about 10000", n)
+	}
+}
@@ -0,0 +1,42 @@
+package zart
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// leakLog is the function set by TrackLeaks, nil while tracking is off.
+var leakLog atomic.Pointer[func(format string, args ...any)]
+
+// TrackLeaks turns on the reporting of tables that are garbage collected
+// without having been closed. Every table created from then on records
+// the stack that created it, and logf is called with it when the garbage
+// collector finds the table unreachable, e.g. TrackLeaks(log.Printf) in a
+// test or a debug build. A nil logf turns tracking off again.
+//
+// The memory of leaked tables is released without tracking as well, but
+// only once the garbage collector gets to them, which may be much later
+// than Close would.
+func TrackLeaks(logf func(format string, args ...any)) {
+	if logf == nil {
+		leakLog.Store(nil)
+		return
+	}
+	leakLog.Store(&logf)
+}
+
+// allocStack returns the stack creating a table for the leak report, or
+// "" when tracking is off.
+func allocStack() string {
+	if leakLog.Load() == nil {
+		return ""
+	}
+	return string(debug.Stack())
+}
+
+// reportLeak logs a table created at stack that was never closed.
+func reportLeak(stack string) {
+	if logf := leakLog.Load(); logf != nil && stack != "" {
+		(*logf)("zart: table garbage collected without Close, created at:\n%s", stack)
+	}
+}
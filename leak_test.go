@@ -0,0 +1,52 @@
+package zart
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackLeaks(t *testing.T) {
+	leaks := make(chan string, 10)
+	TrackLeaks(func(format string, args ...any) {
+		leaks <- args[0].(string)
+	})
+	defer TrackLeaks(nil)
+
+	closed := New[int]()
+	closed.Insert(mpp("10.0.0.0/8"), 1)
+	closed.Close()
+	leakTable()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case stack := <-leaks:
+			if !strings.Contains(stack, "leakTable") {
+				t.Errorf("leak report without the creating function:\n%s", stack)
+			}
+			// the closed table and the version must not be reported
+			time.Sleep(10 * time.Millisecond)
+			runtime.GC()
+			select {
+			case stack := <-leaks:
+				t.Errorf("second leak report:\n%s", stack)
+			case <-time.After(50 * time.Millisecond):
+			}
+			return
+		case <-deadline:
+			t.Fatal("leaked table not reported")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+//go:noinline
+func leakTable() {
+	tbl := New[int]()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	v1 := tbl.InsertPersist(mpp("10.1.0.0/16"), 2)
+	v1.Close()
+}
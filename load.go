@@ -0,0 +1,129 @@
+package zart
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// Decoder reads the routes of a route file one by one. Next returns io.EOF
+// after the last route.
+type Decoder[V any] interface {
+	Next() (RouteEntry[V], error)
+}
+
+// Format returns a Decoder for one file format reading from r, see Lines
+// and CSV. Packages for other formats provide their own, like mrt.Format.
+type Format[V any] func(r io.Reader) Decoder[V]
+
+// Load streams the routes read from r in format into the table and
+// returns their number. The routes are inserted in batches as they are
+// decoded, the file is never held in memory as a whole. progress, if not
+// nil, is called with the number of routes loaded so far after every
+// batch. On error the routes read before it are in the table.
+func (t *Table[V]) Load(r io.Reader, format Format[V], progress func(n int)) (n int, err error) {
+	if err := t.writable(); err != nil {
+		return 0, err
+	}
+	end := t.start("load")
+	defer func() { end(n, err) }()
+	dec := format(r)
+	batch := make([]RouteEntry[V], 0, batchSize)
+	flush := func() {
+		t.InsertBatch(batch)
+		n += len(batch)
+		batch = batch[:0]
+		if progress != nil {
+			progress(n)
+		}
+	}
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
+			flush()
+			return n, nil
+		}
+		if err != nil {
+			flush()
+			return n, err
+		}
+		if batch = append(batch, e); len(batch) == cap(batch) {
+			flush()
+		}
+	}
+}
+
+// Lines is the format of one route per line: a prefix in CIDR notation or
+// a bare address for a host route, optionally followed by white space
+// and the value as text, parsed as by ImportCSV. Routes without a value
+// get the zero value. Empty lines and lines starting with # are skipped.
+func Lines[V any]() Format[V] {
+	return func(r io.Reader) Decoder[V] {
+		return &linesDecoder[V]{sc: bufio.NewScanner(r)}
+	}
+}
+
+type linesDecoder[V any] struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (d *linesDecoder[V]) Next() (e RouteEntry[V], err error) {
+	for d.sc.Scan() {
+		d.line++
+		text := strings.TrimSpace(d.sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		pfxText, valText, hasVal := strings.Cut(text, " ")
+		if !hasVal {
+			pfxText, valText, hasVal = strings.Cut(text, "\t")
+		}
+		if e.Prefix, err = parsePrefix(pfxText); err != nil {
+			return e, fmt.Errorf("zart: line %d: %w", d.line, err)
+		}
+		if hasVal {
+			if err := unmarshalText(strings.TrimSpace(valText), &e.Value); err != nil {
+				return e, fmt.Errorf("zart: line %d: %w", d.line, err)
+			}
+		}
+		return e, nil
+	}
+	if err := d.sc.Err(); err != nil {
+		return e, err
+	}
+	return e, io.EOF
+}
+
+// CSV is the format of ImportCSV and ExportCSV.
+func CSV[V any]() Format[V] {
+	return func(r io.Reader) Decoder[V] {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = 2
+		cr.Comment = '#'
+		cr.ReuseRecord = true
+		return &csvDecoder[V]{cr: cr}
+	}
+}
+
+type csvDecoder[V any] struct {
+	cr *csv.Reader
+}
+
+func (d *csvDecoder[V]) Next() (e RouteEntry[V], err error) {
+	rec, err := d.cr.Read()
+	if err != nil {
+		return e, err
+	}
+	line, _ := d.cr.FieldPos(0)
+	if e.Prefix, err = netip.ParsePrefix(rec[0]); err != nil {
+		return e, fmt.Errorf("zart: line %d: %w", line, err)
+	}
+	if err := unmarshalText(rec[1], &e.Value); err != nil {
+		return e, fmt.Errorf("zart: line %d: %w", line, err)
+	}
+	return e, nil
+}
@@ -0,0 +1,50 @@
+package zart
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadLines(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("# generated\n\n")
+	for i := range 2*batchSize + 10 {
+		fmt.Fprintf(&sb, "10.%d.%d.0/24 %d\n", i>>8, i&0xff, i)
+	}
+	sb.WriteString("2001:db8::/32\n192.0.2.1\t7\n")
+
+	tbl := New[int]()
+	defer tbl.Close()
+	var calls []int
+	n, err := tbl.Load(strings.NewReader(sb.String()), Lines[int](), func(n int) { calls = append(calls, n) })
+	if err != nil || n != 2*batchSize+12 || tbl.Size() != n {
+		t.Fatalf("Load = %d, %v with %d prefixes", n, err, tbl.Size())
+	}
+	if len(calls) != 3 || calls[0] != batchSize || calls[2] != n {
+		t.Errorf("progress calls %v", calls)
+	}
+	if v, ok := tbl.Lookup(mpa("10.1.2.3")); !ok || v != 258 {
+		t.Errorf("Lookup = %d, %v", v, ok)
+	}
+	if v, ok := tbl.Get(mpp("192.0.2.1/32")); !ok || v != 7 {
+		t.Errorf("host route = %d, %v", v, ok)
+	}
+
+	_, err = tbl.Load(strings.NewReader("10.0.0.0/8 1\nbogus 2\n"), Lines[int](), nil)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Load of a bad line = %v", err)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	n, err := tbl.Load(strings.NewReader("10.0.0.0/8,a\n2001:db8::/32,b\n"), CSV[string](), nil)
+	if n != 2 || err != nil {
+		t.Fatalf("Load = %d, %v", n, err)
+	}
+	if v, _ := tbl.Get(mpp("2001:db8::/32")); v != "b" {
+		t.Errorf("Get = %q", v)
+	}
+}
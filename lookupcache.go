@@ -0,0 +1,91 @@
+package zart
+
+import (
+	"container/list"
+	"net/netip"
+)
+
+// LookupCache serves repeated lookups of the same addresses from a
+// Go-side LRU cache, without a call into the trie. With a few heavy
+// hitter flows dominating the traffic most lookups never reach the trie.
+//
+// Every modification of the table empties the cache, results are never
+// stale. The cache pays off on tables that change rarely compared to the
+// lookup rate. Lookups served from the cache are not seen by the hit
+// counters of the table. A LookupCache must not be used concurrently,
+// use one per goroutine or lock it together with the table.
+type LookupCache[V any] struct {
+	t       *Table[V]
+	size    int
+	changes uint64 // t.changes when the entries were filled
+
+	entries map[netip.Addr]*list.Element
+	lru     list.List // of *cacheEntry[V], most recently used first
+
+	hits, misses uint64
+}
+
+type cacheEntry[V any] struct {
+	addr netip.Addr
+	pfx  netip.Prefix
+	val  V
+	ok   bool
+}
+
+// NewLookupCache returns a cache of up to size results in front of t.
+func NewLookupCache[V any](t *Table[V], size int) *LookupCache[V] {
+	return &LookupCache[V]{t: t, size: max(size, 1), changes: t.changes, entries: map[netip.Addr]*list.Element{}}
+}
+
+// Lookup is like Table.Lookup.
+func (c *LookupCache[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	_, val, ok = c.LookupPrefix(addr)
+	return val, ok
+}
+
+// LookupPrefix is like Table.LookupPrefix. Misses are cached as well.
+func (c *LookupCache[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	if c.changes != c.t.changes {
+		c.Reset()
+	}
+	if el, found := c.entries[addr]; found {
+		c.hits++
+		c.lru.MoveToFront(el)
+		e := el.Value.(*cacheEntry[V])
+		return e.pfx, e.val, e.ok
+	}
+
+	c.misses++
+	pfx, val, ok = c.t.LookupPrefix(addr)
+	var e *cacheEntry[V]
+	if c.lru.Len() >= c.size {
+		// recycle the least recently used entry
+		el := c.lru.Back()
+		e = el.Value.(*cacheEntry[V])
+		delete(c.entries, e.addr)
+		c.lru.Remove(el)
+	} else {
+		e = new(cacheEntry[V])
+	}
+	*e = cacheEntry[V]{addr: addr, pfx: pfx, val: val, ok: ok}
+	c.entries[addr] = c.lru.PushFront(e)
+	return pfx, val, ok
+}
+
+// Reset empties the cache.
+func (c *LookupCache[V]) Reset() {
+	clear(c.entries)
+	c.lru.Init()
+	c.changes = c.t.changes
+}
+
+// Len returns the number of cached results.
+func (c *LookupCache[V]) Len() int {
+	return c.lru.Len()
+}
+
+// Stats returns the number of lookups served from the cache and of those
+// that went to the table.
+func (c *LookupCache[V]) Stats() (hits, misses uint64) {
+	return c.hits, c.misses
+}
@@ -0,0 +1,48 @@
+package zart
+
+import "testing"
+
+func TestLookupCache(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	c := NewLookupCache(tbl, 2)
+
+	for range 3 {
+		if v, ok := c.Lookup(mpa("10.1.1.1")); !ok || v != 1 {
+			t.Fatalf("Lookup = %d, %v", v, ok)
+		}
+	}
+	if _, ok := c.Lookup(mpa("192.0.2.1")); ok {
+		t.Fatal("unrouted address matched")
+	}
+	c.Lookup(mpa("192.0.2.1"))
+	if hits, misses := c.Stats(); hits != 3 || misses != 2 {
+		t.Errorf("Stats = %d hits, %d misses, want 3, 2", hits, misses)
+	}
+
+	// the least recently used entry goes first
+	c.Lookup(mpa("10.1.1.1"))
+	c.Lookup(mpa("10.2.2.2"))
+	if _, found := c.entries[mpa("192.0.2.1")]; found || c.Len() != 2 {
+		t.Errorf("LRU entry not evicted, %d entries", c.Len())
+	}
+
+	// every modification invalidates
+	tbl.Insert(mpp("10.1.0.0/16"), 2)
+	if v, _ := c.Lookup(mpa("10.1.1.1")); v != 2 {
+		t.Errorf("stale result %d after Insert", v)
+	}
+	tbl.Modify(mpp("10.1.0.0/16"), func(int, bool) (int, bool) { return 3, false })
+	if v, _ := c.Lookup(mpa("10.1.1.1")); v != 3 {
+		t.Errorf("stale result %d after Modify", v)
+	}
+	tbl.Delete(mpp("10.1.0.0/16"))
+	if v, _ := c.Lookup(mpa("10.1.1.1")); v != 1 {
+		t.Errorf("stale result %d after Delete", v)
+	}
+	tbl.InsertBatch([]RouteEntry[int]{{mpp("192.0.2.0/24"), 4}})
+	if v, ok := c.Lookup(mpa("192.0.2.1")); !ok || v != 4 {
+		t.Errorf("stale miss after InsertBatch: %d, %v", v, ok)
+	}
+}
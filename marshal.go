@@ -0,0 +1,270 @@
+package zart
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// Snapshot format, all integers big endian:
+//
+//	magic    "ZART"
+//	version  uint16
+//	count    uvarint, number of routes
+//	routes   count times: family (4 or 6), bits, the (bits+7)/8 leading
+//	         address octets, then the payload
+//	checksum uint32, CRC-32C of everything before it
+//
+// Payloads of fixed size are stored with encoding/binary in little
+// endian, int and uint as varints, strings, byte slices and
+// encoding.BinaryMarshaler payloads with a uvarint length prefix.
+const (
+	snapshotMagic   = "ZART"
+	snapshotVersion = 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Tables are encoded by encoding/gob and other packages that look for
+// these interfaces, without glue code in the containing types.
+var (
+	_ encoding.BinaryMarshaler   = (*Table[int])(nil)
+	_ encoding.BinaryUnmarshaler = (*Table[int])(nil)
+)
+
+// RegisterGob registers *Table[V] with encoding/gob, which is only needed
+// to send tables as the dynamic value of an interface. Table fields of
+// concrete type are encoded through MarshalBinary without registration.
+func RegisterGob[V any]() {
+	gob.Register(&Table[V]{})
+}
+
+// errCorrupt is returned by UnmarshalBinary for truncated or malformed
+// snapshots.
+var errCorrupt = errors.New("zart: corrupt snapshot")
+
+// MarshalBinary encodes all prefixes and payloads of the table into a
+// versioned snapshot for UnmarshalBinary.
+//
+// Payloads must be of a fixed-size type as understood by
+// encoding/binary, an int, uint, string or []byte, or implement
+// encoding.BinaryMarshaler; other payload types return an error.
+func (t *Table[V]) MarshalBinary() ([]byte, error) {
+	return t.MarshalBinaryContext(context.Background())
+}
+
+// MarshalBinaryContext is MarshalBinary checking ctx between batches of
+// routes, a cancelled call returns ctx.Err().
+func (t *Table[V]) MarshalBinaryContext(ctx context.Context) ([]byte, error) {
+	if err := t.usable(); err != nil {
+		return nil, err
+	}
+	routes := t.routes()
+
+	b := make([]byte, 0, 16+len(routes)*12)
+	b = append(b, snapshotMagic...)
+	b = binary.BigEndian.AppendUint16(b, snapshotVersion)
+	b = binary.AppendUvarint(b, uint64(len(routes)))
+
+	var err error
+	for i := range routes {
+		if i%batchSize == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		r := &routes[i]
+		b = appendRoute(b, r)
+		if b, err = appendPayload(b, t.vals.get(r.val)); err != nil {
+			return nil, err
+		}
+	}
+	return binary.BigEndian.AppendUint32(b, crc32.Checksum(b, castagnoli)), nil
+}
+
+// UnmarshalBinary replaces the contents of the table with a snapshot
+// written by MarshalBinary. It can be called on a zero Table, which then
+// needs to be closed like one returned by New.
+//
+// The snapshot is verified before the table is touched. The routes go
+// into the trie in bulk, which is much faster than inserting them one by
+// one.
+func (t *Table[V]) UnmarshalBinary(data []byte) (err error) {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	var loaded int
+	end := t.start("unmarshal")
+	defer func() { end(loaded, err) }()
+	n := len(data) - 4
+	if n < len(snapshotMagic)+2 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return errors.New("zart: not a snapshot")
+	}
+	if binary.BigEndian.Uint32(data[n:]) != crc32.Checksum(data[:n], castagnoli) {
+		return errors.New("zart: snapshot checksum mismatch")
+	}
+	b := data[len(snapshotMagic):n]
+	if v := binary.BigEndian.Uint16(b); v != snapshotVersion {
+		return fmt.Errorf("zart: unsupported snapshot version %d", v)
+	}
+	b = b[2:]
+
+	count, k := binary.Uvarint(b)
+	if k <= 0 || count > uint64(len(b)) {
+		return errCorrupt
+	}
+	b = b[k:]
+
+	routes := make([]route, count)
+	vals := make([]V, count)
+	for i := range routes {
+		var err error
+		if b, err = decodeRoute(b, &routes[i]); err != nil {
+			return err
+		}
+		routes[i].val = uint32(i)
+		if b, err = decodePayload(b, &vals[i]); err != nil {
+			return err
+		}
+	}
+	if len(b) != 0 {
+		return errCorrupt
+	}
+
+	if t.trie != nil {
+		t.release()
+	}
+	t.trie, t.vals = newTrie(t.opts), &registry[V]{vals: vals}
+	t.changes++
+
+	// a valid snapshot has no duplicates, but a crafted one may
+	replaced := make([]uint32, len(routes))
+	for _, slot := range replaced[:t.trie.insertBulk(routes, replaced)] {
+		t.vals.release(slot)
+	}
+	loaded = len(routes)
+	return nil
+}
+
+// appendRoute appends the family, length and leading address octets of r
+// to b, the value of r is not encoded.
+func appendRoute(b []byte, r *route) []byte {
+	family := byte(6)
+	if r.is4 != 0 {
+		family = 4
+	}
+	b = append(b, family, r.bits)
+	return append(b, r.addr[:(int(r.bits)+7)/8]...)
+}
+
+// decodeRoute decodes a prefix written by appendRoute from the start of b
+// into r, with host bits masked off, and returns the rest of b.
+func decodeRoute(b []byte, r *route) ([]byte, error) {
+	if len(b) < 2 {
+		return nil, errCorrupt
+	}
+	family, bits := b[0], int(b[1])
+	b = b[2:]
+
+	*r = route{}
+	switch {
+	case family == 4 && bits <= 32:
+		r.is4 = 1
+	case family == 6 && bits <= 128:
+	default:
+		return nil, errCorrupt
+	}
+	r.bits = uint8(bits)
+
+	octets := (bits + 7) / 8
+	if len(b) < octets {
+		return nil, errCorrupt
+	}
+	copy(r.addr[:], b[:octets])
+	if bits%8 != 0 {
+		r.addr[octets-1] &= ^byte(0xff >> (bits % 8))
+	}
+	return b[octets:], nil
+}
+
+// appendPayload appends the encoding of v to b.
+// The method set of *V is checked so that it mirrors decodePayload.
+func appendPayload[V any](b []byte, v V) ([]byte, error) {
+	switch p := any(&v).(type) {
+	case *string:
+		b = binary.AppendUvarint(b, uint64(len(*p)))
+		return append(b, *p...), nil
+	case *[]byte:
+		b = binary.AppendUvarint(b, uint64(len(*p)))
+		return append(b, *p...), nil
+	case *int:
+		return binary.AppendVarint(b, int64(*p)), nil
+	case *uint:
+		return binary.AppendUvarint(b, uint64(*p)), nil
+	case encoding.BinaryMarshaler:
+		data, err := p.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = binary.AppendUvarint(b, uint64(len(data)))
+		return append(b, data...), nil
+	}
+	b, err := binary.Append(b, binary.LittleEndian, v)
+	if err != nil {
+		return nil, fmt.Errorf("zart: cannot marshal payload of type %T", v)
+	}
+	return b, nil
+}
+
+// decodePayload decodes a payload written by appendPayload from the
+// start of b into v and returns the rest of b.
+func decodePayload[V any](b []byte, v *V) ([]byte, error) {
+	switch p := any(v).(type) {
+	case *string, *[]byte, encoding.BinaryUnmarshaler:
+		n, k := binary.Uvarint(b)
+		if k <= 0 || n > uint64(len(b)-k) {
+			return nil, errCorrupt
+		}
+		data := b[k : k+int(n)]
+		switch p := p.(type) {
+		case *string:
+			*p = string(data)
+		case *[]byte:
+			*p = append([]byte(nil), data...)
+		case encoding.BinaryUnmarshaler:
+			if err := p.UnmarshalBinary(data); err != nil {
+				return nil, err
+			}
+		}
+		return b[k+int(n):], nil
+	case *int:
+		n, k := binary.Varint(b)
+		if k <= 0 {
+			return nil, errCorrupt
+		}
+		*p = int(n)
+		return b[k:], nil
+	case *uint:
+		n, k := binary.Uvarint(b)
+		if k <= 0 {
+			return nil, errCorrupt
+		}
+		*p = uint(n)
+		return b[k:], nil
+	}
+	size := binary.Size(v)
+	if size < 0 {
+		return nil, fmt.Errorf("zart: cannot unmarshal payload of type %T", *v)
+	}
+	if len(b) < size {
+		return nil, errCorrupt
+	}
+	if _, err := binary.Decode(b, binary.LittleEndian, v); err != nil {
+		return nil, err
+	}
+	return b[size:], nil
+}
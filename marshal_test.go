@@ -0,0 +1,208 @@
+package zart
+
+import (
+	"bytes"
+	"encoding/gob"
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func collectAll[V any](tbl *Table[V]) map[netip.Prefix]V {
+	m := map[netip.Prefix]V{}
+	for pfx, val := range tbl.All() {
+		m[pfx] = val
+	}
+	return m
+}
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+	prng := rand.New(rand.NewPCG(16, 16))
+
+	tbl := New[int64]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, int64(i)-1000)
+	}
+	tbl.Insert(mpp("0.0.0.0/0"), 4)
+	tbl.Insert(mpp("::/0"), 6)
+
+	data, err := tbl.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Table[int64]
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+
+	if !maps.Equal(collectAll(&got), collectAll(tbl)) {
+		t.Fatalf("round trip changed the routes")
+	}
+	if v, ok := got.Lookup(mpa("192.0.2.1")); !ok || v != 4 {
+		t.Errorf("Lookup after UnmarshalBinary = %d, %v, want 4, true", v, ok)
+	}
+}
+
+func TestUnmarshalBinaryReplaces(t *testing.T) {
+	src := New[int]()
+	defer src.Close()
+	src.Insert(mpp("10.0.0.0/8"), 1)
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.InsertWithTTL(mpp("10.0.0.0/8"), 2, time.Hour)
+	tbl.SetTags(mpp("10.0.0.0/8"), "feed")
+	if err := tbl.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tbl.TTL(mpp("10.0.0.0/8")); ok {
+		t.Errorf("restored prefix has the TTL of the replaced route")
+	}
+	if tags := tbl.Tags(mpp("10.0.0.0/8")); len(tags) != 0 {
+		t.Errorf("restored prefix has the tags %v of the replaced route", tags)
+	}
+	if n := tbl.Expire(time.Now().Add(2 * time.Hour)); n != 0 {
+		t.Errorf("Expire removed %d restored prefixes", n)
+	}
+	if v, ok := tbl.Get(mpp("10.0.0.0/8")); !ok || v != 1 {
+		t.Errorf("Get = %d, %v, want 1, true", v, ok)
+	}
+}
+
+func TestMarshalBinaryPayloads(t *testing.T) {
+	strs := New[string]()
+	defer strs.Close()
+	strs.Insert(mpp("10.0.0.0/8"), "ten")
+	strs.Insert(mpp("10.1.2.0/23"), "")
+	strs.Insert(mpp("2001:db8::/32"), "doc")
+
+	data, err := strs.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unmarshal replaces existing contents
+	got := New[string]()
+	defer got.Close()
+	got.Insert(mpp("192.168.0.0/16"), "stale")
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(collectAll(got), collectAll(strs)) {
+		t.Errorf("string payloads: got %v, want %v", collectAll(got), collectAll(strs))
+	}
+
+	// netip.Addr implements encoding.BinaryMarshaler
+	hops := New[netip.Addr]()
+	defer hops.Close()
+	hops.Insert(mpp("10.0.0.0/8"), mpa("192.0.2.1"))
+	hops.Insert(mpp("2001:db8::/32"), mpa("fe80::1"))
+	if data, err = hops.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	var hops2 Table[netip.Addr]
+	if err := hops2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	defer hops2.Close()
+	if !maps.Equal(collectAll(&hops2), collectAll(hops)) {
+		t.Errorf("BinaryMarshaler payloads: got %v, want %v", collectAll(&hops2), collectAll(hops))
+	}
+
+	bad := New[map[string]int]()
+	defer bad.Close()
+	bad.Insert(mpp("10.0.0.0/8"), nil)
+	if _, err := bad.MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary with map payload succeeded")
+	}
+}
+
+func TestUnmarshalBinaryRejects(t *testing.T) {
+	tbl := New[uint32]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("2001:db8::/32"), 2)
+
+	data, err := tbl.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)/2] ^= 1
+	version := append([]byte(nil), data...)
+	version[5] = 9
+
+	for name, b := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("TRAZ"), data[4:]...),
+		"checksum":  flipped,
+		"version":   version,
+		"truncated": data[:len(data)-5],
+	} {
+		got := New[uint32]()
+		got.Insert(mpp("192.168.0.0/16"), 3)
+		if err := got.UnmarshalBinary(b); err == nil {
+			t.Errorf("%s: UnmarshalBinary succeeded", name)
+		}
+		if v, ok := got.Lookup(mpa("192.168.1.1")); !ok || v != 3 {
+			t.Errorf("%s: failed UnmarshalBinary modified the table", name)
+		}
+		got.Close()
+	}
+}
+
+func TestTableGob(t *testing.T) {
+	type config struct {
+		Name   string
+		Routes *Table[string]
+		Hops   Table[uint16]
+		Any    any
+	}
+	RegisterGob[int]()
+
+	in := config{Name: "edge", Routes: New[string]()}
+	defer in.Routes.Close()
+	in.Routes.Insert(mpp("10.0.0.0/8"), "ten")
+	in.Hops = *New[uint16]()
+	defer in.Hops.Close()
+	in.Hops.Insert(mpp("2001:db8::/32"), 7)
+	anyTbl := New[int]()
+	defer anyTbl.Close()
+	anyTbl.Insert(mpp("192.168.0.0/16"), 16)
+	in.Any = anyTbl
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out config
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	defer out.Routes.Close()
+	defer out.Hops.Close()
+
+	if v, _ := out.Routes.Lookup(mpa("10.1.1.1")); v != "ten" || out.Name != "edge" {
+		t.Errorf("pointer field: Lookup = %q", v)
+	}
+	if v, _ := out.Hops.Lookup(mpa("2001:db8::1")); v != 7 {
+		t.Errorf("value field: Lookup = %d", v)
+	}
+	tbl, ok := out.Any.(*Table[int])
+	if !ok {
+		t.Fatalf("interface field decoded as %T", out.Any)
+	}
+	defer tbl.Close()
+	if v, _ := tbl.Lookup(mpa("192.168.1.1")); v != 16 {
+		t.Errorf("interface field: Lookup = %d", v)
+	}
+}
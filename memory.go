@@ -0,0 +1,65 @@
+package zart
+
+import "net/netip"
+
+// WithMaxMemory limits the memory held by the trie of the table, as
+// Stats reports it in Bytes, to about limit bytes. Once the trie holds
+// more, Insert, Modify and InsertBatch drop new prefixes and TryInsert
+// returns ErrMemoryLimit for them. Values of prefixes already present
+// are still updated, and deletes make room for new prefixes again. A
+// limit of 0 or less is no limit.
+//
+// The trie is measured with a walk like Stats. In between, the table
+// estimates its growth from the bytes per prefix of the last walk. It
+// measures again once the number of prefixes has moved by a 64th, and by
+// 64 at least, or by a 1024th while the estimate is over the limit.
+// InsertBatch checks the limit once per batch of 1024 routes. Union,
+// UnmarshalBinary and the tables made by set operations are not limited.
+func WithMaxMemory(limit int) Option {
+	return func(o *options) { o.maxMem = max(limit, 0) }
+}
+
+// memBudget is the last measurement of the trie of a table with a
+// memory limit.
+type memBudget struct {
+	bytes int
+	size  int // prefixes in the trie when it was measured
+}
+
+// full reports whether the trie of t is over the memory limit.
+func (t *Table[V]) full() bool {
+	limit := t.opts.maxMem
+	if limit == 0 {
+		return false
+	}
+	size := t.Size()
+	if t.mem == nil {
+		t.mem = &memBudget{size: -1}
+	}
+	m := t.mem
+	drift := size - m.size
+	if drift < 0 {
+		drift = -drift
+	}
+	estimate := m.bytes
+	if m.size > 0 {
+		estimate += (size - m.size) * m.bytes / m.size
+	}
+	if m.size < 0 || drift > max(m.size/64, 64) || estimate > limit && drift > m.size/1024 {
+		v4, v6 := t.trie.stats()
+		m.bytes, m.size = v4.Bytes+v6.Bytes, size
+		estimate = m.bytes
+	}
+	return estimate > limit
+}
+
+// room reports whether the memory limit lets an insert of pfx through:
+// the trie is within it or pfx is present and only gets a new value.
+func (t *Table[V]) room(pfx netip.Prefix) bool {
+	return !t.full() || t.present(pfx)
+}
+
+func (t *Table[V]) present(pfx netip.Prefix) bool {
+	_, ok := t.Get(pfx)
+	return ok
+}
@@ -0,0 +1,84 @@
+package zart
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestMaxMemory(t *testing.T) {
+	pfxs := make([]netip.Prefix, 4000)
+	for i := range pfxs {
+		pfxs[i] = netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24)
+	}
+	ref := New[int]()
+	defer ref.Close()
+	for _, pfx := range pfxs[:1000] {
+		ref.Insert(pfx, 0)
+	}
+	limit := ref.Stats().Bytes()
+
+	tbl := New[int](WithMaxMemory(limit))
+	defer tbl.Close()
+	for i, pfx := range pfxs {
+		tbl.Insert(pfx, i)
+	}
+	if n := tbl.Size(); n < 990 || n > 1100 {
+		t.Errorf("%d prefixes inserted under a limit of %d bytes, want about 1000", n, limit)
+	}
+	if b := tbl.Stats().Bytes(); b > limit+limit/10 {
+		t.Errorf("trie holds %d bytes, limit %d", b, limit)
+	}
+
+	if err := tbl.TryInsert(pfxs[3999], 1); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("TryInsert of a new prefix = %v, want ErrMemoryLimit", err)
+	}
+	if err := tbl.TryInsert(pfxs[0], -1); err != nil {
+		t.Errorf("TryInsert of a present prefix = %v", err)
+	}
+	if v, _ := tbl.Get(pfxs[0]); v != -1 {
+		t.Errorf("value of a present prefix not updated: %d", v)
+	}
+	tbl.InsertBatch([]RouteEntry[int]{{Prefix: pfxs[1], Value: -2}, {Prefix: pfxs[3998], Value: 0}})
+	if v, _ := tbl.Get(pfxs[1]); v != -2 || tbl.present(pfxs[3998]) {
+		t.Errorf("InsertBatch over the limit: present prefix %d, new one inserted %v", v, tbl.present(pfxs[3998]))
+	}
+
+	// deletes make room again
+	for _, pfx := range pfxs[:200] {
+		tbl.Delete(pfx)
+	}
+	if err := tbl.TryInsert(pfxs[3999], 1); err != nil {
+		t.Errorf("TryInsert after deletes = %v", err)
+	}
+}
+
+func TestMaxMemorySmall(t *testing.T) {
+	tbl := New[int](WithMaxMemory(1 << 30))
+	defer tbl.Close()
+	for i := range 40 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i), 0, 0}), 16), i)
+	}
+	// a small table is not walked again for every insert
+	if tbl.mem.size != 0 {
+		t.Errorf("trie measured again at %d prefixes", tbl.mem.size)
+	}
+}
+
+func TestMaxMemoryBatch(t *testing.T) {
+	prng := rand.New(rand.NewPCG(109, 109))
+	var entries []RouteEntry[int]
+	for i, pfx := range randomPrefixes(prng, 20000) {
+		entries = append(entries, RouteEntry[int]{Prefix: pfx, Value: i})
+	}
+	tbl := New[int](WithMaxMemory(1 << 20))
+	defer tbl.Close()
+	tbl.InsertBatch(entries)
+	if b := tbl.Stats().Bytes(); b > 1<<20+1<<18 {
+		t.Errorf("trie holds %d bytes after InsertBatch, limit %d", b, 1<<20)
+	}
+	if n := tbl.Size(); n == 0 || n > 15000 {
+		t.Errorf("%d prefixes inserted, want the limit to stop the load", n)
+	}
+}
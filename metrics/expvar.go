@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/gx14ac/zart"
+)
+
+// vars is the value of the expvar variable of a Table.
+type vars struct {
+	Prefixes4  int    `json:"prefixes_ipv4"`
+	Prefixes6  int    `json:"prefixes_ipv6"`
+	Nodes      int    `json:"nodes"`
+	Bytes      int    `json:"trie_bytes"`
+	LookupHits uint64 `json:"lookup_hits"`
+	LookupMiss uint64 `json:"lookup_misses"`
+	Inserts    uint64 `json:"inserts"`
+	Deletes    uint64 `json:"deletes"`
+}
+
+// Var returns an expvar.Var reporting the prefix counts, trie nodes and
+// bytes of trie memory of t together with its operation counters. Like
+// a Collector it reads the trie statistics on every read of the
+// variable, with locker held if it is not nil.
+func Var[V any](t *Table[V], locker sync.Locker) expvar.Var {
+	return expvar.Func(func() any {
+		st := stats(t, locker)
+		return vars{
+			Prefixes4:  st.IPv4.Prefixes,
+			Prefixes6:  st.IPv6.Prefixes,
+			Nodes:      st.Nodes(),
+			Bytes:      st.Bytes(),
+			LookupHits: t.hits.Load(),
+			LookupMiss: t.misses.Load(),
+			Inserts:    t.inserts.Load(),
+			Deletes:    t.deletes.Load(),
+		}
+	})
+}
+
+// Publish publishes Var(t, locker) under name, to be served with the
+// other variables at /debug/vars. Like expvar.Publish it panics if name
+// is already taken.
+func Publish[V any](name string, t *Table[V], locker sync.Locker) {
+	expvar.Publish(name, Var(t, locker))
+}
+
+// DebugHandler returns an http.Handler writing the trie statistics of t
+// as text, next to /debug/pprof, e.g. under /debug/zart: per address
+// family the prefixes, nodes, leaves, fringes, bytes and nodes per
+// depth, then the operation counters. locker is held while the trie is
+// walked if it is not nil.
+func DebugHandler[V any](t *Table[V], locker sync.Locker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		st := stats(t, locker)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+		for _, f := range []struct {
+			family string
+			s      zart.FamilyStats
+		}{{"ipv4", st.IPv4}, {"ipv6", st.IPv6}} {
+			levels := make([]string, len(f.s.NodesPerLevel))
+			for i, n := range f.s.NodesPerLevel {
+				levels[i] = fmt.Sprint(n)
+			}
+			fmt.Fprintf(tw, "%s prefixes\t%d\n", f.family, f.s.Prefixes)
+			fmt.Fprintf(tw, "%s nodes\t%d\n", f.family, f.s.Nodes)
+			fmt.Fprintf(tw, "%s leaves\t%d\n", f.family, f.s.Leaves)
+			fmt.Fprintf(tw, "%s fringes\t%d\n", f.family, f.s.Fringes)
+			fmt.Fprintf(tw, "%s bytes\t%d\n", f.family, f.s.Bytes)
+			fmt.Fprintf(tw, "%s nodes per depth\t%s\n", f.family, strings.Join(levels, " "))
+		}
+		fmt.Fprintf(tw, "lookup hits\t%d\n", t.hits.Load())
+		fmt.Fprintf(tw, "lookup misses\t%d\n", t.misses.Load())
+		fmt.Fprintf(tw, "inserts\t%d\n", t.inserts.Load())
+		fmt.Fprintf(tw, "deletes\t%d\n", t.deletes.Load())
+		tw.Flush()
+	})
+}
+
+// stats reads the trie statistics of t with locker held.
+func stats[V any](t *Table[V], locker sync.Locker) zart.Stats {
+	if locker != nil {
+		locker.Lock()
+		defer locker.Unlock()
+	}
+	return t.Stats()
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestVar(t *testing.T) {
+	tbl := Wrap(zart.New[int]())
+	defer tbl.Close()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	tbl.Insert(netip.MustParsePrefix("2001:db8::/32"), 2)
+	tbl.Lookup(netip.MustParseAddr("10.1.1.1"))
+	tbl.Lookup(netip.MustParseAddr("192.0.2.1"))
+
+	var got vars
+	if err := json.Unmarshal([]byte(Var(tbl, nil).String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Prefixes4 != 1 || got.Prefixes6 != 1 || got.Inserts != 2 || got.LookupHits != 1 || got.LookupMiss != 1 {
+		t.Errorf("Var = %+v", got)
+	}
+	if got.Nodes == 0 || got.Bytes == 0 {
+		t.Errorf("Var reports no trie memory: %+v", got)
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	tbl := Wrap(zart.New[int]())
+	defer tbl.Close()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+
+	rec := httptest.NewRecorder()
+	DebugHandler(tbl, nil).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/zart", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"ipv4 prefixes 1", "ipv6 prefixes 0", "inserts 1"} {
+		if !strings.Contains(strings.Join(strings.Fields(body), " "), strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("debug output misses %q:\n%s", want, body)
+		}
+	}
+}
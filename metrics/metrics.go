@@ -0,0 +1,165 @@
+// Package metrics exports Prometheus metrics for a zart table.
+//
+// Table wraps a zart.Table and counts lookups, hits, misses, inserts and
+// deletes with atomic counters; the rates follow from the counters in
+// PromQL, e.g. rate(zart_lookups_total[1m]). A Collector reports these
+// counters together with the number of prefixes, trie nodes and bytes
+// of trie memory per address family. Var and DebugHandler report the same
+// through expvar and a debug HTTP handler.
+package metrics
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gx14ac/zart"
+)
+
+// Table is a zart.Table that counts its operations. Methods not listed
+// here are passed through uncounted.
+type Table[V any] struct {
+	*zart.Table[V]
+
+	hits, misses     atomic.Uint64
+	inserts, deletes atomic.Uint64
+}
+
+// Wrap returns a counting Table for t.
+func Wrap[V any](t *zart.Table[V]) *Table[V] {
+	return &Table[V]{Table: t}
+}
+
+func (t *Table[V]) Insert(pfx netip.Prefix, val V) {
+	t.Table.Insert(pfx, val)
+	t.inserts.Add(1)
+}
+
+func (t *Table[V]) InsertBatch(entries []zart.RouteEntry[V]) {
+	t.Table.InsertBatch(entries)
+	t.inserts.Add(uint64(len(entries)))
+}
+
+func (t *Table[V]) Delete(pfx netip.Prefix) bool {
+	ok := t.Table.Delete(pfx)
+	t.deletes.Add(1)
+	return ok
+}
+
+func (t *Table[V]) Lookup(addr netip.Addr) (V, bool) {
+	val, ok := t.Table.Lookup(addr)
+	t.count(ok)
+	return val, ok
+}
+
+func (t *Table[V]) LookupPrefix(addr netip.Addr) (netip.Prefix, V, bool) {
+	pfx, val, ok := t.Table.LookupPrefix(addr)
+	t.count(ok)
+	return pfx, val, ok
+}
+
+func (t *Table[V]) LookupBatch(addrs []netip.Addr, results []zart.Result[V]) {
+	t.Table.LookupBatch(addrs, results)
+	t.countBatch(results[:len(addrs)])
+}
+
+func (t *Table[V]) LookupBatch4(addrs []uint32, results []zart.Result[V]) {
+	t.Table.LookupBatch4(addrs, results)
+	t.countBatch(results[:len(addrs)])
+}
+
+func (t *Table[V]) LookupBatch6(addrs [][16]byte, results []zart.Result[V]) {
+	t.Table.LookupBatch6(addrs, results)
+	t.countBatch(results[:len(addrs)])
+}
+
+func (t *Table[V]) count(hit bool) {
+	if hit {
+		t.hits.Add(1)
+	} else {
+		t.misses.Add(1)
+	}
+}
+
+func (t *Table[V]) countBatch(results []zart.Result[V]) {
+	hits := 0
+	for i := range results {
+		if results[i].OK {
+			hits++
+		}
+	}
+	t.hits.Add(uint64(hits))
+	t.misses.Add(uint64(len(results) - hits))
+}
+
+// Collector is a prometheus.Collector for a Table.
+type Collector[V any] struct {
+	// Locker, if set, is held while the collector reads the table, so
+	// scrapes can run alongside writers that hold it as well.
+	Locker sync.Locker
+
+	t *Table[V]
+
+	prefixes *prometheus.Desc
+	nodes    *prometheus.Desc
+	memory   *prometheus.Desc
+	lookups  *prometheus.Desc
+	inserts  *prometheus.Desc
+	deletes  *prometheus.Desc
+}
+
+// NewCollector returns a Collector for t. The metric names start with
+// namespace_ if namespace is not empty, constLabels are added to all of
+// them, e.g. to tell several tables apart.
+func NewCollector[V any](t *Table[V], namespace string, constLabels prometheus.Labels) *Collector[V] {
+	name := func(n string) string { return prometheus.BuildFQName(namespace, "zart", n) }
+	return &Collector[V]{
+		t: t,
+		prefixes: prometheus.NewDesc(name("prefixes"),
+			"Number of prefixes in the table.", []string{"family"}, constLabels),
+		nodes: prometheus.NewDesc(name("nodes"),
+			"Number of trie nodes.", []string{"family"}, constLabels),
+		memory: prometheus.NewDesc(name("memory_bytes"),
+			"Memory held by the trie nodes, without payloads.", []string{"family"}, constLabels),
+		lookups: prometheus.NewDesc(name("lookups_total"),
+			"Longest-prefix match lookups by result.", []string{"result"}, constLabels),
+		inserts: prometheus.NewDesc(name("inserts_total"),
+			"Prefixes inserted, including overwrites.", nil, constLabels),
+		deletes: prometheus.NewDesc(name("deletes_total"),
+			"Prefix deletions, including those of absent prefixes.", nil, constLabels),
+	}
+}
+
+func (c *Collector[V]) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.prefixes
+	ch <- c.nodes
+	ch <- c.memory
+	ch <- c.lookups
+	ch <- c.inserts
+	ch <- c.deletes
+}
+
+func (c *Collector[V]) Collect(ch chan<- prometheus.Metric) {
+	st := c.stats()
+	for _, f := range []struct {
+		family string
+		s      zart.FamilyStats
+	}{{"ipv4", st.IPv4}, {"ipv6", st.IPv6}} {
+		ch <- prometheus.MustNewConstMetric(c.prefixes, prometheus.GaugeValue, float64(f.s.Prefixes), f.family)
+		ch <- prometheus.MustNewConstMetric(c.nodes, prometheus.GaugeValue, float64(f.s.Nodes), f.family)
+		ch <- prometheus.MustNewConstMetric(c.memory, prometheus.GaugeValue, float64(f.s.Bytes), f.family)
+	}
+
+	t := c.t
+	ch <- prometheus.MustNewConstMetric(c.lookups, prometheus.CounterValue, float64(t.hits.Load()), "hit")
+	ch <- prometheus.MustNewConstMetric(c.lookups, prometheus.CounterValue, float64(t.misses.Load()), "miss")
+	ch <- prometheus.MustNewConstMetric(c.inserts, prometheus.CounterValue, float64(t.inserts.Load()))
+	ch <- prometheus.MustNewConstMetric(c.deletes, prometheus.CounterValue, float64(t.deletes.Load()))
+}
+
+// stats reads the trie statistics, a walk over all nodes of the table.
+func (c *Collector[V]) stats() zart.Stats {
+	return stats(c.t, c.Locker)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestCollector(t *testing.T) {
+	tbl := Wrap(zart.New[int]())
+	defer tbl.Close()
+
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	tbl.InsertBatch([]zart.RouteEntry[int]{
+		{Prefix: netip.MustParsePrefix("192.168.0.0/16"), Value: 2},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Value: 3},
+	})
+	tbl.Delete(netip.MustParsePrefix("172.16.0.0/12"))
+	tbl.Lookup(netip.MustParseAddr("10.1.1.1"))
+	tbl.LookupPrefix(netip.MustParseAddr("203.0.113.1"))
+	results := make([]zart.Result[int], 3)
+	tbl.LookupBatch([]netip.Addr{
+		netip.MustParseAddr("192.168.1.1"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("::1"),
+	}, results)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(tbl, "", prometheus.Labels{"table": "main"}))
+
+	want := `
+# HELP zart_deletes_total Prefix deletions, including those of absent prefixes.
+# TYPE zart_deletes_total counter
+zart_deletes_total{table="main"} 1
+# HELP zart_inserts_total Prefixes inserted, including overwrites.
+# TYPE zart_inserts_total counter
+zart_inserts_total{table="main"} 3
+# HELP zart_lookups_total Longest-prefix match lookups by result.
+# TYPE zart_lookups_total counter
+zart_lookups_total{result="hit",table="main"} 3
+zart_lookups_total{result="miss",table="main"} 2
+# HELP zart_prefixes Number of prefixes in the table.
+# TYPE zart_prefixes gauge
+zart_prefixes{family="ipv4",table="main"} 2
+zart_prefixes{family="ipv6",table="main"} 1
+`
+	names := []string{"zart_deletes_total", "zart_inserts_total", "zart_lookups_total", "zart_prefixes"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+
+	// node and memory counts depend on the backend, they just have to
+	// be there
+	if n, err := testutil.GatherAndCount(reg, "zart_nodes", "zart_memory_bytes"); err != nil || n != 4 {
+		t.Errorf("GatherAndCount(zart_nodes, zart_memory_bytes) = %d, %v, want 4", n, err)
+	}
+}
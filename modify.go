@@ -0,0 +1,76 @@
+package zart
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Modify updates the value of pfx in place. fn is called with the current
+// value and whether pfx is present and returns the value to store, or del
+// to remove pfx. Returning del for a missing prefix leaves the table
+// unchanged. Host bits of pfx are masked off, invalid prefixes and new
+// ones over the memory limit are ignored.
+//
+// A new payload slot is reserved and offered to the trie before fn is
+// called, so both the lookup and an insert cost a single call into the
+// trie. Only removing pfx takes a second one.
+func (t *Table[V]) Modify(pfx netip.Prefix, fn func(old V, existed bool) (val V, del bool)) {
+	t.mutable()
+	if !t.accepts(pfx) || !t.room(pfx) {
+		return
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	var zero V
+	slot := t.vals.alloc(zero)
+
+	var cur uint32
+	var existed bool
+	var a16 [16]byte
+	if addr.Is4() {
+		a4 := addr.As4()
+		cur, existed = t.trie.getOrInsert4(binary.BigEndian.Uint32(a4[:]), bits, slot)
+	} else {
+		a16 = addr.As16()
+		cur, existed = t.trie.getOrInsert6(&a16, bits, slot)
+	}
+
+	if !existed {
+		val, del := fn(zero, false)
+		if del {
+			// take back the reserved slot, nobody has seen it
+			if addr.Is4() {
+				a4 := addr.As4()
+				t.trie.delete4(binary.BigEndian.Uint32(a4[:]), bits)
+			} else {
+				t.trie.delete6(&a16, bits)
+			}
+			t.vals.release(slot)
+			return
+		}
+		t.vals.set(slot, val)
+		t.changes++
+		t.count(EventInsert, 1)
+		if t.watch != nil {
+			t.watch.notify(Event[V]{Kind: EventInsert, Prefix: pfx.Masked(), New: val})
+		}
+		return
+	}
+
+	t.vals.release(slot)
+	old := t.vals.get(cur)
+	val, del := fn(old, true)
+	switch {
+	case del:
+		t.Delete(pfx)
+	case t.vals.shared > 0:
+		// other versions still refer to the slot, store a new one
+		t.Insert(pfx, val)
+	default:
+		t.vals.set(cur, val)
+		t.changes++
+		t.count(EventUpdate, 1)
+		if t.watch != nil {
+			t.watch.notify(Event[V]{Kind: EventUpdate, Prefix: pfx.Masked(), Old: old, New: val})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package zart
+
+import "testing"
+
+func TestModify(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	incr := func(old int, existed bool) (int, bool) { return old + 1, false }
+	for range 3 {
+		tbl.Modify(mpp("10.0.0.0/8"), incr)
+	}
+	tbl.Modify(mpp("2001:db8::/32"), incr)
+	if v, ok := tbl.Get(mpp("10.0.0.0/8")); v != 3 || !ok {
+		t.Errorf("after 3 increments Get = %d, %v", v, ok)
+	}
+	if v, ok := tbl.Get(mpp("2001:db8::/32")); v != 1 || !ok {
+		t.Errorf("IPv6 Get = %d, %v", v, ok)
+	}
+
+	tbl.Modify(mpp("10.1.0.0/16"), func(old int, existed bool) (int, bool) {
+		if existed {
+			t.Errorf("missing prefix reported as existing with %d", old)
+		}
+		return 0, true
+	})
+	if _, ok := tbl.Get(mpp("10.1.0.0/16")); ok || tbl.Size() != 2 || tbl.vals.len() != 2 {
+		t.Errorf("deleting a missing prefix changed the table: size %d, %d slots", tbl.Size(), tbl.vals.len())
+	}
+
+	tbl.Modify(mpp("10.0.0.0/8"), func(old int, _ bool) (int, bool) { return old, old == 3 })
+	if _, ok := tbl.Get(mpp("10.0.0.0/8")); ok || tbl.Size() != 1 || tbl.vals.len() != 1 {
+		t.Errorf("Modify did not delete: size %d, %d slots", tbl.Size(), tbl.vals.len())
+	}
+}
+
+func TestModifyPersist(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+
+	p := tbl.InsertPersist(mpp("10.1.0.0/16"), 2)
+	defer p.Close()
+	p.Modify(mpp("10.0.0.0/8"), func(old int, _ bool) (int, bool) { return old + 10, false })
+
+	if v, _ := tbl.Get(mpp("10.0.0.0/8")); v != 1 {
+		t.Errorf("Modify of a persistent version changed the original to %d", v)
+	}
+	if v, _ := p.Get(mpp("10.0.0.0/8")); v != 11 {
+		t.Errorf("modified version has %d, want 11", v)
+	}
+}
@@ -0,0 +1,240 @@
+// Package mrt loads MRT routing information base dumps (RFC 6396) as
+// published by RouteViews and RIPE RIS into a zart table.
+//
+// Only TABLE_DUMP_V2 unicast RIB records are used, including their
+// ADD-PATH variants (RFC 8050); all other record types are skipped. The
+// value of each prefix is its origin AS, the last AS number on the
+// AS_PATH of the first RIB entry. Archives are usually compressed, wrap
+// the reader with compress/gzip or compress/bzip2 as needed.
+package mrt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// MRT record types and TABLE_DUMP_V2 subtypes.
+const (
+	typeTableDumpV2 = 13
+
+	subtypeRIBIPv4Unicast        = 2
+	subtypeRIBIPv6Unicast        = 4
+	subtypeRIBIPv4UnicastAddPath = 8
+	subtypeRIBIPv6UnicastAddPath = 10
+)
+
+// BGP path attribute types and AS_PATH segment types.
+const (
+	attrFlagExtendedLength = 0x10
+	attrASPath             = 2
+
+	segmentASSet      = 1
+	segmentASSequence = 2
+)
+
+// maxRecord bounds the length of a single record, real RIB records
+// are far smaller.
+const maxRecord = 1 << 24
+
+// ErrFormat is returned, wrapped, for malformed MRT data.
+var ErrFormat = errors.New("mrt: malformed record")
+
+// Route is a prefix with the origin AS number of its first RIB entry.
+type Route struct {
+	Prefix netip.Prefix
+	Origin uint32
+}
+
+// Reader reads the routes of an MRT dump one by one.
+type Reader struct {
+	r   *bufio.Reader
+	hdr [12]byte
+	buf []byte
+}
+
+// NewReader returns a Reader for the MRT dump in r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 1<<16)}
+}
+
+// Next returns the next route of the dump. At the end of the input it
+// returns io.EOF. Prefixes without entries or without an AS_PATH are
+// skipped.
+func (r *Reader) Next() (Route, error) {
+	for {
+		if _, err := io.ReadFull(r.r, r.hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("%w: truncated header", ErrFormat)
+			}
+			return Route{}, err
+		}
+		typ := binary.BigEndian.Uint16(r.hdr[4:])
+		subtype := binary.BigEndian.Uint16(r.hdr[6:])
+		length := binary.BigEndian.Uint32(r.hdr[8:])
+		if length > maxRecord {
+			return Route{}, fmt.Errorf("%w: record of %d bytes", ErrFormat, length)
+		}
+
+		if cap(r.buf) < int(length) {
+			r.buf = make([]byte, length)
+		}
+		body := r.buf[:length]
+		if _, err := io.ReadFull(r.r, body); err != nil {
+			return Route{}, fmt.Errorf("%w: truncated record", ErrFormat)
+		}
+		if typ != typeTableDumpV2 {
+			continue
+		}
+
+		var is4, addPath bool
+		switch subtype {
+		case subtypeRIBIPv4Unicast:
+			is4 = true
+		case subtypeRIBIPv4UnicastAddPath:
+			is4, addPath = true, true
+		case subtypeRIBIPv6Unicast:
+		case subtypeRIBIPv6UnicastAddPath:
+			addPath = true
+		default:
+			continue
+		}
+
+		rt, ok, err := parseRIB(body, is4, addPath)
+		if err != nil {
+			return Route{}, err
+		}
+		if ok {
+			return rt, nil
+		}
+	}
+}
+
+// parseRIB decodes a RIB_IPV4_UNICAST or RIB_IPV6_UNICAST record body,
+// ok is false if it has no entry with an origin AS.
+func parseRIB(b []byte, is4, addPath bool) (rt Route, ok bool, err error) {
+	// sequence number, prefix length
+	if len(b) < 5 {
+		return rt, false, fmt.Errorf("%w: short RIB record", ErrFormat)
+	}
+	bits := int(b[4])
+	b = b[5:]
+
+	var a [16]byte
+	maxBits := 128
+	if is4 {
+		maxBits = 32
+	}
+	n := (bits + 7) / 8
+	if bits > maxBits || len(b) < n+2 {
+		return rt, false, fmt.Errorf("%w: bad prefix", ErrFormat)
+	}
+	copy(a[:], b[:n])
+	b = b[n:]
+
+	addr := netip.AddrFrom16(a)
+	if is4 {
+		addr = netip.AddrFrom4([4]byte(a[:4]))
+	}
+	rt.Prefix = netip.PrefixFrom(addr, bits).Masked()
+
+	entries := binary.BigEndian.Uint16(b)
+	b = b[2:]
+
+	// peer index, originated time, path identifier with ADD-PATH
+	hdr := 6
+	if addPath {
+		hdr += 4
+	}
+	for range entries {
+		if len(b) < hdr+2 {
+			return rt, false, fmt.Errorf("%w: short RIB entry", ErrFormat)
+		}
+		attrLen := int(binary.BigEndian.Uint16(b[hdr:]))
+		b = b[hdr+2:]
+		if len(b) < attrLen {
+			return rt, false, fmt.Errorf("%w: short attributes", ErrFormat)
+		}
+		origin, found, err := originAS(b[:attrLen])
+		if err != nil || found {
+			rt.Origin = origin
+			return rt, found, err
+		}
+		b = b[attrLen:]
+	}
+	return rt, false, nil
+}
+
+// originAS returns the last AS number of the AS_PATH in the path
+// attributes b. AS_PATH is always encoded with 4-byte AS numbers in
+// TABLE_DUMP_V2.
+func originAS(b []byte) (asn uint32, found bool, err error) {
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return 0, false, fmt.Errorf("%w: short attribute", ErrFormat)
+		}
+		flags, typ := b[0], b[1]
+		var n int
+		if flags&attrFlagExtendedLength != 0 {
+			if len(b) < 4 {
+				return 0, false, fmt.Errorf("%w: short attribute", ErrFormat)
+			}
+			n, b = int(binary.BigEndian.Uint16(b[2:])), b[4:]
+		} else {
+			n, b = int(b[2]), b[3:]
+		}
+		if len(b) < n {
+			return 0, false, fmt.Errorf("%w: short attribute", ErrFormat)
+		}
+		if typ == attrASPath {
+			return lastAS(b[:n])
+		}
+		b = b[n:]
+	}
+	return 0, false, nil
+}
+
+// lastAS returns the last AS number of the segments of an AS_PATH.
+func lastAS(b []byte) (asn uint32, found bool, err error) {
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return 0, false, fmt.Errorf("%w: short AS_PATH segment", ErrFormat)
+		}
+		typ, count := b[0], int(b[1])
+		b = b[2:]
+		if typ != segmentASSet && typ != segmentASSequence || len(b) < 4*count {
+			return 0, false, fmt.Errorf("%w: bad AS_PATH segment", ErrFormat)
+		}
+		if count > 0 {
+			asn, found = binary.BigEndian.Uint32(b[4*(count-1):]), true
+		}
+		b = b[4*count:]
+	}
+	return asn, found, nil
+}
+
+// Load inserts all routes of the MRT dump in r into t, mapping each
+// prefix to its origin AS, and returns the number of routes read. The
+// routes are inserted in batches; on error the routes read before it
+// are in the table.
+func Load(t *zart.Table[uint32], r io.Reader) (n int, err error) {
+	return t.Load(r, Format(), nil)
+}
+
+// Format is the MRT format for Table.Load, the values are the origin AS
+// numbers as for Load.
+func Format() zart.Format[uint32] {
+	return func(r io.Reader) zart.Decoder[uint32] { return decoder{NewReader(r)} }
+}
+
+type decoder struct{ rd *Reader }
+
+func (d decoder) Next() (zart.RouteEntry[uint32], error) {
+	rt, err := d.rd.Next()
+	return zart.RouteEntry[uint32]{Prefix: rt.Prefix, Value: rt.Origin}, err
+}
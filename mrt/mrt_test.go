@@ -0,0 +1,117 @@
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+// record appends an MRT record with the given type, subtype and body.
+func record(b []byte, typ, subtype uint16, body []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, 1700000000)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, subtype)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+// rib returns a RIB record body for pfx with one entry per AS path.
+func rib(pfx netip.Prefix, addPath bool, paths ...[]uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, 42)
+	b = append(b, byte(pfx.Bits()))
+	b = append(b, pfx.Addr().AsSlice()[:(pfx.Bits()+7)/8]...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(paths)))
+	for i, path := range paths {
+		b = binary.BigEndian.AppendUint16(b, uint16(i))
+		b = binary.BigEndian.AppendUint32(b, 1700000000)
+		if addPath {
+			b = binary.BigEndian.AppendUint32(b, 7)
+		}
+
+		// ORIGIN, then AS_PATH with extended length
+		attrs := []byte{0x40, 1, 1, 0}
+		var seg []byte
+		if len(path) > 0 {
+			seg = append(seg, segmentASSequence, byte(len(path)))
+			for _, asn := range path {
+				seg = binary.BigEndian.AppendUint32(seg, asn)
+			}
+		}
+		attrs = append(attrs, 0x50, attrASPath)
+		attrs = binary.BigEndian.AppendUint16(attrs, uint16(len(seg)))
+		attrs = append(attrs, seg...)
+
+		b = binary.BigEndian.AppendUint16(b, uint16(len(attrs)))
+		b = append(b, attrs...)
+	}
+	return b
+}
+
+func TestLoad(t *testing.T) {
+	var dump []byte
+	dump = record(dump, typeTableDumpV2, 1, []byte("peer index table, skipped"))
+	dump = record(dump, typeTableDumpV2, subtypeRIBIPv4Unicast,
+		rib(netip.MustParsePrefix("10.0.0.0/8"), false, []uint32{3356, 64500}, []uint32{174, 64501}))
+	dump = record(dump, typeTableDumpV2, subtypeRIBIPv4Unicast,
+		rib(netip.MustParsePrefix("192.0.2.0/24"), false, nil, []uint32{2914, 64502}))
+	dump = record(dump, 12, 2, []byte("TABLE_DUMP v1, skipped"))
+	dump = record(dump, typeTableDumpV2, subtypeRIBIPv6Unicast,
+		rib(netip.MustParsePrefix("2001:db8::/33"), false, []uint32{6939, 64503}))
+	dump = record(dump, typeTableDumpV2, subtypeRIBIPv6UnicastAddPath,
+		rib(netip.MustParsePrefix("2001:db8:8000::/33"), true, []uint32{64504}))
+	dump = record(dump, typeTableDumpV2, subtypeRIBIPv4Unicast,
+		rib(netip.MustParsePrefix("198.51.100.0/24"), false, nil))
+
+	tbl := zart.New[uint32]()
+	defer tbl.Close()
+	n, err := Load(tbl, bytes.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("Load returned %d routes, want 4", n)
+	}
+
+	for addr, want := range map[string]uint32{
+		"10.1.2.3":         64500,
+		"192.0.2.1":        64502,
+		"2001:db8::1":      64503,
+		"2001:db8:8000::1": 64504,
+	} {
+		if got, ok := tbl.Lookup(netip.MustParseAddr(addr)); !ok || got != want {
+			t.Errorf("Lookup(%s) = %d, %v, want %d", addr, got, ok, want)
+		}
+	}
+	if _, ok := tbl.Lookup(netip.MustParseAddr("198.51.100.1")); ok {
+		t.Errorf("prefix without AS_PATH was loaded")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	good := record(nil, typeTableDumpV2, subtypeRIBIPv4Unicast,
+		rib(netip.MustParsePrefix("10.0.0.0/8"), false, []uint32{64500}))
+
+	r := NewReader(bytes.NewReader(good[:len(good)-3]))
+	if _, err := r.Next(); !errors.Is(err, ErrFormat) {
+		t.Errorf("truncated record: err = %v, want ErrFormat", err)
+	}
+
+	bad := record(nil, typeTableDumpV2, subtypeRIBIPv4Unicast, []byte{0, 0, 0, 1, 33, 10, 0, 0, 0, 0, 0})
+	r = NewReader(bytes.NewReader(bad))
+	if _, err := r.Next(); !errors.Is(err, ErrFormat) {
+		t.Errorf("IPv4 /33: err = %v, want ErrFormat", err)
+	}
+
+	r = NewReader(bytes.NewReader(good))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at end = %v, want io.EOF", err)
+	}
+}
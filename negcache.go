@@ -0,0 +1,145 @@
+package zart
+
+import (
+	"net/netip"
+
+	"github.com/gx14ac/zart/internal/bart"
+)
+
+// MissCache puts a cache of unrouted address space in front of a Table,
+// for workloads where most lookups miss, like filtering bogons.
+//
+// For every miss the cache remembers the largest block around the address
+// that no prefix in the table overlaps, the gap between the routes. A
+// later lookup in a remembered block is answered from a small Go-side
+// trie of those blocks, without a call into the table. Hits and the
+// first miss in a block cost a cache probe more than a plain lookup.
+//
+// Inserts through the cache drop the blocks they overlap, deletes only
+// grow the unrouted space and leave the cache valid. The table must only
+// be modified through the cache. Like Table, a MissCache is not safe for
+// concurrent use.
+type MissCache[V any] struct {
+	t      *Table[V]
+	gaps   bart.Trie[struct{}]
+	max    int
+	misses uint64 // lookups answered from the cache
+}
+
+// NewMissCache returns a cache in front of t remembering up to maxGaps
+// unrouted blocks, it starts over once the limit is reached.
+func NewMissCache[V any](t *Table[V], maxGaps int) *MissCache[V] {
+	return &MissCache[V]{t: t, max: maxGaps}
+}
+
+// Table returns the cached table, for queries the cache does not wrap.
+func (c *MissCache[V]) Table() *Table[V] {
+	return c.t
+}
+
+// CachedMisses returns the number of lookups answered from the cache.
+func (c *MissCache[V]) CachedMisses() uint64 {
+	return c.misses
+}
+
+// Gaps returns the number of unrouted blocks in the cache.
+func (c *MissCache[V]) Gaps() int {
+	return c.gaps.Size4() + c.gaps.Size6()
+}
+
+// Reset empties the cache.
+func (c *MissCache[V]) Reset() {
+	c.gaps = bart.Trie[struct{}]{}
+}
+
+// Lookup is like Table.Lookup.
+func (c *MissCache[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	_, val, ok = c.LookupPrefix(addr)
+	return val, ok
+}
+
+// LookupPrefix is like Table.LookupPrefix.
+func (c *MissCache[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	if !addr.IsValid() {
+		return pfx, val, false
+	}
+	if c.gaps.Contains(addr.AsSlice()) {
+		c.misses++
+		return pfx, val, false
+	}
+	pfx, val, ok = c.t.LookupPrefix(addr)
+	if !ok {
+		c.remember(addr)
+	}
+	return pfx, val, ok
+}
+
+// Contains is like Table.Contains.
+func (c *MissCache[V]) Contains(addr netip.Addr) bool {
+	_, _, ok := c.LookupPrefix(addr)
+	return ok
+}
+
+// Insert is like Table.Insert, it drops the cached blocks overlapping pfx.
+func (c *MissCache[V]) Insert(pfx netip.Prefix, val V) {
+	c.t.Insert(pfx, val)
+	c.forget(pfx)
+}
+
+// InsertBatch is like Table.InsertBatch.
+func (c *MissCache[V]) InsertBatch(entries []RouteEntry[V]) {
+	c.t.InsertBatch(entries)
+	for _, e := range entries {
+		c.forget(e.Prefix)
+	}
+}
+
+// Delete is like Table.Delete.
+func (c *MissCache[V]) Delete(pfx netip.Prefix) bool {
+	return c.t.Delete(pfx)
+}
+
+// remember caches the largest block around addr that overlaps no prefix.
+// Overlapping is monotone in the prefix length, the shortest free length
+// is found by bisection. addr itself missed, so its host route is free.
+func (c *MissCache[V]) remember(addr netip.Addr) {
+	if c.Gaps() >= c.max {
+		c.Reset()
+	}
+	lo, hi := 0, addr.BitLen()
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if p, _ := addr.Prefix(mid); c.t.OverlapsPrefix(p) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	gap, _ := addr.Prefix(lo)
+	c.gaps.Insert(gap.Addr().AsSlice(), lo, struct{}{})
+}
+
+// forget drops the cached blocks overlapping pfx, those covering it and
+// those covered by it.
+func (c *MissCache[V]) forget(pfx netip.Prefix) {
+	if !pfx.IsValid() || c.Gaps() == 0 {
+		return
+	}
+	pfx = pfx.Masked()
+	octets := pfx.Addr().AsSlice()
+
+	var stale []netip.Prefix
+	c.gaps.Supernets(octets, pfx.Bits(), func(bits int, _ struct{}) bool {
+		p, _ := pfx.Addr().Prefix(bits)
+		stale = append(stale, p)
+		return true
+	})
+	c.gaps.Subnets(octets, pfx.Bits(), func(o []byte, bits int, _ struct{}) bool {
+		a, _ := netip.AddrFromSlice(o)
+		stale = append(stale, netip.PrefixFrom(a, bits))
+		return true
+	})
+	for _, p := range stale {
+		c.gaps.Delete(p.Addr().AsSlice(), p.Bits())
+	}
+}
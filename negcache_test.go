@@ -0,0 +1,69 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestMissCache(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	c := NewMissCache(tbl, 1000)
+
+	c.Insert(mpp("10.0.0.0/8"), 1)
+	c.Insert(mpp("10.1.0.0/16"), 2)
+
+	if _, ok := c.Lookup(mpa("192.0.2.1")); ok {
+		t.Fatal("unrouted address matched")
+	}
+	if c.Gaps() != 1 {
+		t.Fatalf("%d gaps after a miss, want 1", c.Gaps())
+	}
+	for _, a := range []string{"192.0.2.2", "200.1.2.3", "128.0.0.1"} {
+		if _, ok := c.Lookup(mpa(a)); ok {
+			t.Errorf("%s matched", a)
+		}
+	}
+	if c.CachedMisses() != 3 {
+		t.Errorf("CachedMisses = %d, want 3: the gap of 192.0.2.1 is 128.0.0.0/1", c.CachedMisses())
+	}
+
+	// a new route inside a gap must be found
+	c.Insert(mpp("192.0.2.0/24"), 3)
+	if v, ok := c.Lookup(mpa("192.0.2.1")); !ok || v != 3 {
+		t.Errorf("route inserted into a cached gap: %d, %v", v, ok)
+	}
+	if v, ok := c.Lookup(mpa("10.1.2.3")); !ok || v != 2 {
+		t.Errorf("Lookup(10.1.2.3) = %d, %v", v, ok)
+	}
+}
+
+func TestMissCacheMatchesTable(t *testing.T) {
+	prng := rand.New(rand.NewPCG(48, 48))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	c := NewMissCache(tbl, 64)
+
+	pfxs := randomPrefixes(prng, 400)
+	for i, pfx := range pfxs[:200] {
+		c.Insert(pfx, i)
+	}
+	for round := range 4 {
+		for _, pfx := range randomPrefixes(prng, 500) {
+			addr := pfx.Addr()
+			got, gotOK := c.Lookup(addr)
+			want, wantOK := tbl.Lookup(addr)
+			if got != want || gotOK != wantOK {
+				t.Fatalf("round %d: cached Lookup(%s) = %d, %v, table %d, %v", round, addr, got, gotOK, want, wantOK)
+			}
+		}
+		for i, pfx := range pfxs[200+50*round : 250+50*round] {
+			c.Insert(pfx, 1000+i)
+			c.Delete(pfxs[prng.IntN(200)])
+		}
+	}
+	if c.CachedMisses() == 0 {
+		t.Errorf("no lookup was answered from the cache")
+	}
+}
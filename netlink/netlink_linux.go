@@ -0,0 +1,346 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/gx14ac/zart"
+)
+
+// RTNETLINK constants missing from package syscall.
+const (
+	rtmgrpIPv4Route = 0x40
+	rtmgrpIPv6Route = 0x400
+
+	rtaTable = 15
+
+	rtprotStatic = 4
+	rtnUnicast   = 1
+	scopeLink    = 253
+
+	rtMsgLen    = syscall.SizeofRtMsg
+	nlmsgHdrLen = syscall.SizeofNlMsghdr
+)
+
+// Dump returns the routes of the kernel routing table number table.
+func Dump(table int) ([]Route, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETROUTE, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("netlink: dump routes: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("netlink: dump routes: %w", err)
+	}
+	var routes []Route
+	for i := range msgs {
+		if r, ok := parseRoute(&msgs[i]); ok && r.Table == table {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// parseRoute decodes an RTM_NEWROUTE or RTM_DELROUTE message, ok is false
+// for other messages and routes that are not IPv4 or IPv6.
+func parseRoute(m *syscall.NetlinkMessage) (r Route, ok bool) {
+	typ := m.Header.Type
+	if typ != syscall.RTM_NEWROUTE && typ != syscall.RTM_DELROUTE || len(m.Data) < rtMsgLen {
+		return r, false
+	}
+	// struct rtmsg: family, dst_len, src_len, tos, table, protocol,
+	// scope, type, flags
+	family, bits := m.Data[0], int(m.Data[1])
+	if family != syscall.AF_INET && family != syscall.AF_INET6 {
+		return r, false
+	}
+	attrs, err := syscall.ParseNetlinkRouteAttr(m)
+	if err != nil {
+		return r, false
+	}
+
+	r.Table, r.Protocol, r.Type = int(m.Data[4]), m.Data[5], m.Data[7]
+	dst := netip.IPv4Unspecified()
+	if family == syscall.AF_INET6 {
+		dst = netip.IPv6Unspecified()
+	}
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case syscall.RTA_DST:
+			if addr, ok := netip.AddrFromSlice(a.Value); ok {
+				dst = addr
+			}
+		case syscall.RTA_GATEWAY:
+			r.Gateway, _ = netip.AddrFromSlice(a.Value)
+		case syscall.RTA_OIF:
+			if len(a.Value) == 4 {
+				r.OIF = int(binary.NativeEndian.Uint32(a.Value))
+			}
+		case syscall.RTA_PRIORITY:
+			if len(a.Value) == 4 {
+				r.Priority = binary.NativeEndian.Uint32(a.Value)
+			}
+		case rtaTable:
+			if len(a.Value) == 4 {
+				r.Table = int(binary.NativeEndian.Uint32(a.Value))
+			}
+		}
+	}
+	pfx, err := dst.Prefix(bits)
+	if err != nil {
+		return r, false
+	}
+	r.Dst = pfx
+	return r, true
+}
+
+// routeMessage encodes r as a netlink message of type typ.
+func routeMessage(typ, flags uint16, seq uint32, r Route) []byte {
+	family := uint8(syscall.AF_INET6)
+	if r.Dst.Addr().Is4() {
+		family = syscall.AF_INET
+	}
+	table, protocol, rtype := uint8(r.Table), r.Protocol, r.Type
+	if r.Table > 255 {
+		table = syscall.RT_TABLE_UNSPEC
+	}
+	if protocol == 0 {
+		protocol = rtprotStatic
+	}
+	if rtype == 0 {
+		rtype = rtnUnicast
+	}
+	scope := uint8(syscall.RT_SCOPE_UNIVERSE)
+	if !r.Gateway.IsValid() {
+		scope = scopeLink
+	}
+
+	b := make([]byte, nlmsgHdrLen, 128)
+	b = append(b, family, uint8(r.Dst.Bits()), 0, 0, table, protocol, scope, rtype, 0, 0, 0, 0)
+	b = appendAttr(b, syscall.RTA_DST, r.Dst.Addr().AsSlice())
+	if r.Gateway.IsValid() {
+		b = appendAttr(b, syscall.RTA_GATEWAY, r.Gateway.AsSlice())
+	}
+	if r.OIF != 0 {
+		b = appendAttr(b, syscall.RTA_OIF, binary.NativeEndian.AppendUint32(nil, uint32(r.OIF)))
+	}
+	if r.Priority != 0 {
+		b = appendAttr(b, syscall.RTA_PRIORITY, binary.NativeEndian.AppendUint32(nil, r.Priority))
+	}
+	b = appendAttr(b, rtaTable, binary.NativeEndian.AppendUint32(nil, uint32(r.Table)))
+
+	binary.NativeEndian.PutUint32(b[0:], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:], typ)
+	binary.NativeEndian.PutUint16(b[6:], flags)
+	binary.NativeEndian.PutUint32(b[8:], seq)
+	return b
+}
+
+// appendAttr appends a route attribute, padded to 4 bytes.
+func appendAttr(b []byte, typ uint16, val []byte) []byte {
+	b = binary.NativeEndian.AppendUint16(b, uint16(syscall.SizeofRtAttr+len(val)))
+	b = binary.NativeEndian.AppendUint16(b, typ)
+	b = append(b, val...)
+	for len(b)%syscall.NLMSG_ALIGNTO != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// socket opens a route netlink socket subscribed to groups.
+func socket(groups uint32) (int, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return -1, fmt.Errorf("netlink: socket: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}); err != nil {
+		syscall.Close(fd)
+		return -1, fmt.Errorf("netlink: bind: %w", err)
+	}
+	return fd, nil
+}
+
+// Push installs every route of t into the kernel routing table number
+// table, replacing existing routes to the same destination. route maps
+// a table entry to the kernel route, its Dst and Table are set by Push.
+// It returns the number of routes installed before the first error.
+func Push[V any](t *zart.Table[V], table int, route func(pfx netip.Prefix, val V) Route) (n int, err error) {
+	fd, err := socket(0)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Close(fd)
+
+	const flags = syscall.NLM_F_REQUEST | syscall.NLM_F_ACK | syscall.NLM_F_CREATE | syscall.NLM_F_REPLACE
+	buf := make([]byte, os.Getpagesize())
+	seq := uint32(0)
+	for pfx, val := range t.All() {
+		r := route(pfx, val)
+		r.Dst, r.Table = pfx, table
+
+		seq++
+		msg := routeMessage(syscall.RTM_NEWROUTE, flags, seq, r)
+		if err := syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+			return n, fmt.Errorf("netlink: push %s: %w", pfx, err)
+		}
+		if err := readAck(fd, buf, seq); err != nil {
+			return n, fmt.Errorf("netlink: push %s: %w", pfx, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// readAck waits for the NLMSG_ERROR reply to request seq.
+func readAck(fd int, buf []byte, seq uint32) error {
+	for {
+		k, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:k])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != seq || m.Header.Type != syscall.NLMSG_ERROR || len(m.Data) < 4 {
+				continue
+			}
+			if errno := int32(binary.NativeEndian.Uint32(m.Data)); errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			return nil
+		}
+	}
+}
+
+// Mirror keeps a Table equal to a kernel routing table.
+type Mirror struct {
+	// Locker, if set, is held while Run modifies the table, so readers
+	// holding it see consistent contents. Set it before calling Run.
+	Locker sync.Locker
+
+	t     *zart.Table[Route]
+	table int
+	f     *os.File
+}
+
+// NewMirror subscribes to route notifications and loads the kernel
+// routing table number table into t. Call Run to apply the changes
+// that follow, and Close to stop.
+func NewMirror(t *zart.Table[Route], table int) (*Mirror, error) {
+	// subscribe before the dump, so no change in between is lost
+	fd, err := socket(rtmgrpIPv4Route | rtmgrpIPv6Route)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink: %w", err)
+	}
+	m := &Mirror{t: t, table: table, f: os.NewFile(uintptr(fd), "rtnetlink")}
+	if err := m.resync(); err != nil {
+		m.f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run applies route notifications to the table until Close is called,
+// it then returns nil. If the kernel drops notifications because the
+// socket buffer overflowed, the table is reloaded with a full dump.
+func (m *Mirror) Run() error {
+	buf := make([]byte, 1<<16)
+	for {
+		n, err := m.f.Read(buf)
+		switch {
+		case errors.Is(err, os.ErrClosed):
+			return nil
+		case errors.Is(err, syscall.ENOBUFS):
+			if err := m.resync(); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return fmt.Errorf("netlink: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("netlink: %w", err)
+		}
+		m.lock()
+		for i := range msgs {
+			m.apply(&msgs[i])
+		}
+		m.unlock()
+	}
+}
+
+// Close stops Run and releases the netlink socket, the table is left as
+// it is.
+func (m *Mirror) Close() error {
+	return m.f.Close()
+}
+
+// apply updates the table for one notification. A deletion only removes
+// the stored route if it has the same metric, so withdrawing a backup
+// route keeps the primary one.
+func (m *Mirror) apply(msg *syscall.NetlinkMessage) {
+	r, ok := parseRoute(msg)
+	if !ok || r.Table != m.table {
+		return
+	}
+	if msg.Header.Type == syscall.RTM_NEWROUTE {
+		m.t.Insert(r.Dst, r)
+		return
+	}
+	if old, ok := m.t.Get(r.Dst); ok && old.Priority == r.Priority {
+		m.t.Delete(r.Dst)
+	}
+}
+
+// resync replaces the contents of the table with a fresh dump.
+func (m *Mirror) resync() error {
+	routes, err := Dump(m.table)
+	if err != nil {
+		return err
+	}
+	entries := make([]zart.RouteEntry[Route], len(routes))
+	keep := make(map[netip.Prefix]bool, len(routes))
+	for i, r := range routes {
+		entries[i] = zart.RouteEntry[Route]{Prefix: r.Dst, Value: r}
+		keep[r.Dst] = true
+	}
+
+	m.lock()
+	defer m.unlock()
+	var stale []netip.Prefix
+	for pfx := range m.t.All() {
+		if !keep[pfx] {
+			stale = append(stale, pfx)
+		}
+	}
+	for _, pfx := range stale {
+		m.t.Delete(pfx)
+	}
+	m.t.InsertBatch(entries)
+	return nil
+}
+
+func (m *Mirror) lock() {
+	if m.Locker != nil {
+		m.Locker.Lock()
+	}
+}
+
+func (m *Mirror) unlock() {
+	if m.Locker != nil {
+		m.Locker.Unlock()
+	}
+}
@@ -0,0 +1,78 @@
+package netlink
+
+import (
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func message(t *testing.T, typ uint16, r Route) *syscall.NetlinkMessage {
+	t.Helper()
+	msgs, err := syscall.ParseNetlinkMessage(routeMessage(typ, 0, 1, r))
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ParseNetlinkMessage = %d messages, %v", len(msgs), err)
+	}
+	return &msgs[0]
+}
+
+func TestRouteMessageRoundTrip(t *testing.T) {
+	for _, want := range []Route{
+		{
+			Dst: netip.MustParsePrefix("10.0.0.0/8"), Gateway: netip.MustParseAddr("192.0.2.1"),
+			OIF: 2, Table: TableMain, Priority: 100, Protocol: rtprotStatic, Type: rtnUnicast,
+		},
+		{
+			Dst: netip.MustParsePrefix("2001:db8::/32"), OIF: 3,
+			Table: 1000, Protocol: 186, Type: rtnUnicast,
+		},
+		{
+			Dst: netip.MustParsePrefix("0.0.0.0/0"), Gateway: netip.MustParseAddr("192.0.2.254"),
+			Table: TableMain, Protocol: rtprotStatic, Type: rtnUnicast,
+		},
+	} {
+		got, ok := parseRoute(message(t, syscall.RTM_NEWROUTE, want))
+		if !ok || got != want {
+			t.Errorf("parseRoute(routeMessage(%+v)) = %+v, %v", want, got, ok)
+		}
+	}
+}
+
+func TestMirrorApply(t *testing.T) {
+	tbl := zart.New[Route]()
+	defer tbl.Close()
+	m := &Mirror{t: tbl, table: TableMain}
+
+	ten := Route{Dst: netip.MustParsePrefix("10.0.0.0/8"), Table: TableMain, Priority: 10}
+	backup := ten
+	backup.Priority = 20
+	other := Route{Dst: netip.MustParsePrefix("10.0.0.0/8"), Table: 100}
+
+	m.apply(message(t, syscall.RTM_NEWROUTE, ten))
+	m.apply(message(t, syscall.RTM_NEWROUTE, other))
+	if r, ok := tbl.Lookup(netip.MustParseAddr("10.1.1.1")); !ok || r.Table != TableMain {
+		t.Fatalf("Lookup = %+v, %v, want the route of the main table", r, ok)
+	}
+
+	m.apply(message(t, syscall.RTM_DELROUTE, backup))
+	if _, ok := tbl.Lookup(netip.MustParseAddr("10.1.1.1")); !ok {
+		t.Errorf("deleting a route with another metric removed the stored one")
+	}
+	m.apply(message(t, syscall.RTM_DELROUTE, ten))
+	if _, ok := tbl.Lookup(netip.MustParseAddr("10.1.1.1")); ok {
+		t.Errorf("route still present after RTM_DELROUTE")
+	}
+}
+
+func TestDump(t *testing.T) {
+	routes, err := Dump(TableLocal)
+	if err != nil {
+		t.Skipf("no route netlink: %v", err)
+	}
+	for _, r := range routes {
+		if !r.Dst.IsValid() || r.Table != TableLocal {
+			t.Errorf("bad route %+v", r)
+		}
+	}
+}
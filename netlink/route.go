@@ -0,0 +1,32 @@
+// Package netlink synchronizes zart tables with Linux kernel routing
+// tables over RTNETLINK.
+//
+// A Mirror copies a kernel routing table into a Table and keeps it
+// current from route notifications, Push installs the routes of a Table
+// into a kernel routing table. Both work on Linux only, installing
+// routes needs CAP_NET_ADMIN.
+//
+// Kernel routes are keyed by destination prefix only: of several routes
+// to the same prefix with different metrics or TOS the table keeps the
+// last one reported.
+package netlink
+
+import "net/netip"
+
+// Well-known kernel routing table numbers.
+const (
+	TableMain  = 254
+	TableLocal = 255
+)
+
+// Route is a kernel route.
+type Route struct {
+	Dst     netip.Prefix
+	Gateway netip.Addr // invalid for directly connected routes
+	OIF     int        // output interface index, 0 if none
+
+	Table    int
+	Priority uint32 // route metric
+	Protocol uint8  // RTPROT_*, who installed the route
+	Type     uint8  // RTN_*, 1 for unicast routes
+}
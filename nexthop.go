@@ -0,0 +1,116 @@
+package zart
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"strings"
+	"unique"
+)
+
+// nextHop is the interned value of a NextHop. The labels are packed into
+// a string of big-endian uint32s to keep it comparable.
+type nextHop struct {
+	gateway netip.Addr
+	ifIndex int32
+	weight  uint32
+	labels  string
+}
+
+// NextHop is a structured next hop: a gateway address, the index of the
+// outgoing interface, an MPLS label stack to push and a weight among the
+// equal-cost next hops of a route. NextHops are interned, equal next
+// hops share one copy and a NextHop is a single pointer, so a
+// Table[NextHop] or MultiTable[NextHop] of a full table holding a few
+// thousand distinct next hops stores them once. NextHops are comparable,
+// equal next hops are ==.
+//
+// The zero NextHop has no gateway, interface, labels or weight.
+type NextHop struct {
+	h unique.Handle[nextHop]
+}
+
+// MakeNextHop returns the next hop via gateway out of the interface with
+// index ifIndex, pushing labels, the top of the stack first. Either the
+// gateway or the interface may be unset, with the zero Addr and 0. Labels
+// are 20 bits, higher bits are dropped.
+func MakeNextHop(gateway netip.Addr, ifIndex int, weight uint32, labels ...uint32) NextHop {
+	buf := make([]byte, 0, 4*len(labels))
+	for _, l := range labels {
+		buf = binary.BigEndian.AppendUint32(buf, l&0xfffff)
+	}
+	return NextHop{unique.Make(nextHop{gateway: gateway, ifIndex: int32(ifIndex), weight: weight, labels: string(buf)})}
+}
+
+func (nh NextHop) get() nextHop {
+	if nh.IsZero() {
+		return nextHop{}
+	}
+	return nh.h.Value()
+}
+
+// IsZero reports whether nh is the zero NextHop.
+func (nh NextHop) IsZero() bool {
+	return nh == NextHop{}
+}
+
+// Gateway returns the gateway address, the zero Addr for a next hop that
+// is an interface only.
+func (nh NextHop) Gateway() netip.Addr {
+	return nh.get().gateway
+}
+
+// IfIndex returns the interface index, 0 if unset.
+func (nh NextHop) IfIndex() int {
+	return int(nh.get().ifIndex)
+}
+
+// Weight returns the weight of the next hop.
+func (nh NextHop) Weight() uint32 {
+	return nh.get().weight
+}
+
+// Labels returns a copy of the MPLS label stack, the top first.
+func (nh NextHop) Labels() []uint32 {
+	s := nh.get().labels
+	if s == "" {
+		return nil
+	}
+	labels := make([]uint32, len(s)/4)
+	for i := range labels {
+		labels[i] = binary.BigEndian.Uint32([]byte(s[4*i:]))
+	}
+	return labels
+}
+
+// String formats nh like ip route does: "via 192.0.2.1 dev 3 encap mpls
+// 100/200 weight 1", leaving out the parts that are unset.
+func (nh NextHop) String() string {
+	v := nh.get()
+	var b strings.Builder
+	add := func(s ...string) {
+		for _, s := range s {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(s)
+		}
+	}
+	if v.gateway.IsValid() {
+		add("via", v.gateway.String())
+	}
+	if v.ifIndex != 0 {
+		add("dev", strconv.Itoa(int(v.ifIndex)))
+	}
+	if labels := nh.Labels(); labels != nil {
+		strs := make([]string, len(labels))
+		for i, l := range labels {
+			strs[i] = strconv.FormatUint(uint64(l), 10)
+		}
+		add("encap", "mpls", strings.Join(strs, "/"))
+	}
+	if v.weight != 0 {
+		add("weight", strconv.FormatUint(uint64(v.weight), 10))
+	}
+	return b.String()
+}
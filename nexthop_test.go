@@ -0,0 +1,61 @@
+package zart
+
+import (
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestNextHop(t *testing.T) {
+	a := MakeNextHop(mpa("192.0.2.1"), 3, 1, 100, 200)
+	b := MakeNextHop(mpa("192.0.2.1"), 3, 1, 100, 200)
+	if a != b {
+		t.Error("equal next hops are not ==")
+	}
+	if a == MakeNextHop(mpa("192.0.2.1"), 3, 1, 100) {
+		t.Error("next hops with different label stacks are ==")
+	}
+	if got := a.Labels(); !slices.Equal(got, []uint32{100, 200}) {
+		t.Errorf("Labels = %v", got)
+	}
+	if a.Gateway() != mpa("192.0.2.1") || a.IfIndex() != 3 || a.Weight() != 1 {
+		t.Errorf("fields of %s", a)
+	}
+	if got := MakeNextHop(mpa("192.0.2.1"), 0, 0, 1<<20|7).Labels(); got[0] != 7 {
+		t.Errorf("label beyond 20 bits = %d", got[0])
+	}
+
+	for _, tt := range []struct {
+		nh   NextHop
+		want string
+	}{
+		{a, "via 192.0.2.1 dev 3 encap mpls 100/200 weight 1"},
+		{MakeNextHop(mpa("2001:db8::1"), 0, 0), "via 2001:db8::1"},
+		{MakeNextHop(mpa("0.0.0.0"), 7, 0), "via 0.0.0.0 dev 7"},
+		{NextHop{}, ""},
+	} {
+		if got := tt.nh.String(); got != tt.want {
+			t.Errorf("String = %q, want %q", got, tt.want)
+		}
+	}
+
+	var zero NextHop
+	if !zero.IsZero() || zero.Gateway().IsValid() || zero.Labels() != nil {
+		t.Error("zero NextHop is not empty")
+	}
+	if unsafe.Sizeof(zero) != unsafe.Sizeof(uintptr(0)) {
+		t.Errorf("NextHop is %d bytes", unsafe.Sizeof(zero))
+	}
+}
+
+func TestNextHopTable(t *testing.T) {
+	tbl := New[NextHop]()
+	defer tbl.Close()
+	for i, pfx := range []string{"10.0.0.0/8", "10.1.0.0/16", "192.168.0.0/16"} {
+		tbl.Insert(mpp(pfx), MakeNextHop(mpa("192.0.2.1"), 2, 0, uint32(16+i%2)))
+	}
+	got, _ := tbl.Lookup(mpa("192.168.1.1"))
+	if got != MakeNextHop(mpa("192.0.2.1"), 2, 0, 16) {
+		t.Errorf("Lookup = %s", got)
+	}
+}
@@ -0,0 +1,93 @@
+package zart
+
+import "log/slog"
+
+// Option configures a table created by New.
+type Option func(*options)
+
+type options struct {
+	strict   bool
+	mapped   MappedPolicy
+	arena    int
+	compress bool
+	maxMem   int
+	resolve  int
+	tel      Telemetry
+	lat      *latencies
+	audit    *slog.Logger
+}
+
+// WithMasking makes the table mask the host bits of the prefixes it is
+// given, so 192.168.1.55/24 is stored, found and deleted as
+// 192.168.1.0/24, and every prefix the table returns or reports is
+// masked. This is the default; the option states it explicitly and
+// overrides an earlier WithStrict.
+func WithMasking() Option {
+	return func(o *options) { o.strict = false }
+}
+
+// WithStrict makes the table reject prefixes with host bits set instead
+// of masking them. The Try methods return ErrHostBits for them, the other
+// inserts ignore them like invalid prefixes. It overrides an earlier
+// WithMasking.
+func WithStrict() Option {
+	return func(o *options) { o.strict = true }
+}
+
+// MappedPolicy selects how lookups treat IPv4-mapped IPv6 addresses like
+// ::ffff:192.0.2.1, as handed out by dual-stack sockets.
+type MappedPolicy uint8
+
+const (
+	// MappedAsIPv6 looks mapped addresses up as native IPv6 addresses,
+	// they only match IPv6 prefixes like ::ffff:0:0/96. The default.
+	MappedAsIPv6 MappedPolicy = iota
+
+	// MappedAsIPv4 unmaps the addresses and looks them up among the IPv4
+	// prefixes, so ::ffff:192.0.2.1 matches 192.0.2.0/24.
+	MappedAsIPv4
+)
+
+// WithMapped sets the policy for IPv4-mapped IPv6 addresses. It applies to
+// the lookups by address: Lookup, LookupPrefix, Contains, LookupBatch,
+// LookupAll and LookupTrace. With MappedAsIPv4 the prefixes they return
+// are IPv4 prefixes. Inserts and the queries by prefix are not affected.
+func WithMapped(p MappedPolicy) Option {
+	return func(o *options) { o.mapped = p }
+}
+
+// WithArena makes the C table allocate its nodes from an arena of
+// blockSize byte blocks instead of one malloc per node, 0 for the default
+// block size of 256 KiB. Bulk loads get faster and closing the table
+// frees a few blocks instead of walking the trie; freed nodes are reused
+// by the table but not returned to the system before the table is
+// closed. Versions made with InsertPersist share the arena of their
+// table, clones get one of their own. The option has no effect on the
+// pure-Go build.
+func WithArena(blockSize int) Option {
+	return func(o *options) { o.arena = max(blockSize, 0) + 1 }
+}
+
+// WithPathCompression makes the pure-Go trie store a prefix whose path
+// leaves the trie as a leaf in the free child slot, instead of a chain
+// of nodes holding it alone, as the C trie always does. Deletes fold the
+// nodes left with a single prefix back into a leaf. Tables of scattered
+// long prefixes, like IPv6 /48s and /64s, get by with a fraction of the
+// nodes; inserting below a leaf pushes it down a level first. Stats and
+// Shape count the leaves and fringes. The option has no effect on the
+// cgo build.
+func WithPathCompression() Option {
+	return func(o *options) { o.compress = true }
+}
+
+// defaultResolveDepth is the number of recursive lookups LookupResolved
+// makes without WithResolveDepth, enough for BGP next hops resolved over
+// an IGP and a tunnel.
+const defaultResolveDepth = 4
+
+// WithResolveDepth sets the number of recursive lookups LookupResolved
+// makes at most after the first one, 4 by default. Values below 1 are
+// taken as 1.
+func WithResolveDepth(n int) Option {
+	return func(o *options) { o.resolve = max(n, 1) }
+}
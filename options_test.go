@@ -0,0 +1,94 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestMasking(t *testing.T) {
+	tbl := New[int](WithStrict(), WithMasking())
+	defer tbl.Close()
+
+	if err := tbl.TryInsert(mpp("192.168.1.55/24"), 1); err != nil {
+		t.Fatalf("TryInsert with host bits after WithMasking = %v", err)
+	}
+	tbl.InsertTagged(mpp("10.1.2.3/8"), 2, "x")
+	for pfx := range tbl.All() {
+		if pfx != pfx.Masked() {
+			t.Errorf("All returns the unmasked %s", pfx)
+		}
+	}
+	if v, ok := tbl.Get(mpp("192.168.1.200/24")); !ok || v != 1 {
+		t.Errorf("Get with other host bits = %d, %v", v, ok)
+	}
+	if tags := tbl.Tags(mpp("10.9.9.9/8")); len(tags) != 1 {
+		t.Errorf("Tags with other host bits = %v", tags)
+	}
+	if !tbl.Delete(mpp("192.168.1.1/24")) || tbl.Size() != 1 {
+		t.Errorf("Delete with other host bits failed, size %d", tbl.Size())
+	}
+}
+
+func TestMappedPolicy(t *testing.T) {
+	mapped := mpa("::ffff:192.0.2.1")
+	for _, tc := range []struct {
+		policy MappedPolicy
+		want   int
+		pfx    netip.Prefix
+	}{
+		{MappedAsIPv6, 6, mpp("::ffff:0:0/96")},
+		{MappedAsIPv4, 4, mpp("192.0.2.0/24")},
+	} {
+		tbl := New[int](WithMapped(tc.policy))
+		tbl.Insert(mpp("192.0.2.0/24"), 4)
+		tbl.Insert(mpp("::ffff:0:0/96"), 6)
+
+		if v, ok := tbl.Lookup(mapped); !ok || v != tc.want {
+			t.Errorf("policy %d: Lookup = %d, %v, want %d", tc.policy, v, ok, tc.want)
+		}
+		if pfx, _, _ := tbl.LookupPrefix(mapped); pfx != tc.pfx {
+			t.Errorf("policy %d: LookupPrefix = %s, want %s", tc.policy, pfx, tc.pfx)
+		}
+		res := make([]Result[int], 1)
+		tbl.LookupBatch([]netip.Addr{mapped}, res)
+		if res[0].Value != tc.want {
+			t.Errorf("policy %d: LookupBatch = %v", tc.policy, res[0])
+		}
+		c := tbl.Clone()
+		if v, _ := c.Lookup(mapped); v != tc.want {
+			t.Errorf("policy %d: clone looks up %d", tc.policy, v)
+		}
+		c.Close()
+		tbl.Close()
+	}
+}
+
+func TestArena(t *testing.T) {
+	prng := rand.New(rand.NewPCG(70, 70))
+	pfxs := randomPrefixes(prng, 5000)
+
+	tbl := New[int](WithArena(4096))
+	for i, pfx := range pfxs {
+		tbl.Insert(pfx, i)
+	}
+	v1 := tbl.DeletePersist(pfxs[0])
+	c := v1.Clone()
+	want := tbl.Size()
+
+	// the versions share the arena and can be closed in any order
+	tbl.Close()
+	if v1.Size() != want-1 {
+		t.Errorf("persistent version has %d prefixes, want %d", v1.Size(), want-1)
+	}
+	if _, ok := v1.Get(pfxs[0]); ok {
+		t.Errorf("persistent version still has %s", pfxs[0])
+	}
+	v1.Close()
+	for _, pfx := range pfxs[1:] {
+		if _, ok := c.Get(pfx); !ok {
+			t.Fatalf("clone lost %s", pfx)
+		}
+	}
+	c.Close()
+}
@@ -0,0 +1,113 @@
+// Package otel reports the telemetry of zart tables to OpenTelemetry.
+//
+// A Telemetry set on a table with zart.WithTelemetry records a span for
+// every bulk load, batch insert and transaction commit, the latency of
+// every Lookup and LookupPrefix in the histogram zart.lookup.duration by
+// result, and the changes of the table in the counter zart.changes by
+// kind; the rates follow from the counter in the metrics backend.
+package otel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/gx14ac/zart"
+)
+
+// scope is the instrumentation scope of the tracer and meter.
+const scope = "github.com/gx14ac/zart"
+
+// Telemetry is a zart.Telemetry recording to an OpenTelemetry tracer and
+// meter.
+type Telemetry struct {
+	tracer  trace.Tracer
+	lookups metric.Float64Histogram
+	changes metric.Int64Counter
+	attrs   []attribute.KeyValue
+
+	// the options of every measurement, built once so recording
+	// allocates nothing
+	hit, miss []metric.RecordOption
+	kinds     map[zart.EventKind][]metric.AddOption
+}
+
+var _ zart.Telemetry = (*Telemetry)(nil)
+
+// New returns a Telemetry recording spans with tp and metrics with mp,
+// a nil provider records nothing. attrs are added to all spans and
+// measurements, e.g. to tell several tables apart.
+func New(tp trace.TracerProvider, mp metric.MeterProvider, attrs ...attribute.KeyValue) (*Telemetry, error) {
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	if mp == nil {
+		mp = metricnoop.NewMeterProvider()
+	}
+	meter := mp.Meter(scope)
+	lookups, err := meter.Float64Histogram("zart.lookup.duration",
+		metric.WithDescription("Duration of longest-prefix match lookups."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(25e-9, 50e-9, 100e-9, 250e-9, 500e-9, 1e-6, 2.5e-6, 10e-6, 100e-6))
+	if err != nil {
+		return nil, err
+	}
+	changes, err := meter.Int64Counter("zart.changes",
+		metric.WithDescription("Prefixes inserted, updated or deleted."),
+		metric.WithUnit("{prefix}"))
+	if err != nil {
+		return nil, err
+	}
+
+	set := func(kv ...attribute.KeyValue) attribute.Set {
+		return attribute.NewSet(append(kv, attrs...)...)
+	}
+	t := &Telemetry{
+		tracer:  tp.Tracer(scope),
+		lookups: lookups,
+		changes: changes,
+		attrs:   attrs,
+		hit:     []metric.RecordOption{metric.WithAttributeSet(set(attribute.String("result", "hit")))},
+		miss:    []metric.RecordOption{metric.WithAttributeSet(set(attribute.String("result", "miss")))},
+		kinds:   map[zart.EventKind][]metric.AddOption{},
+	}
+	for _, k := range []zart.EventKind{zart.EventInsert, zart.EventUpdate, zart.EventDelete} {
+		t.kinds[k] = []metric.AddOption{metric.WithAttributeSet(set(attribute.String("kind", k.String())))}
+	}
+	return t, nil
+}
+
+// Lookup records the duration of a lookup.
+func (t *Telemetry) Lookup(d time.Duration, hit bool) {
+	opts := t.miss
+	if hit {
+		opts = t.hit
+	}
+	t.lookups.Record(context.Background(), d.Seconds(), opts...)
+}
+
+// Change counts n changes of kind.
+func (t *Telemetry) Change(kind zart.EventKind, n int) {
+	t.changes.Add(context.Background(), int64(n), t.kinds[kind]...)
+}
+
+// Start starts the span zart.<op> of a bulk operation. The span ends
+// with the number of routes in the attribute zart.routes and, on
+// failure, the error.
+func (t *Telemetry) Start(op string) func(n int, err error) {
+	_, span := t.tracer.Start(context.Background(), "zart."+op, trace.WithAttributes(t.attrs...))
+	return func(n int, err error) {
+		span.SetAttributes(attribute.Int("zart.routes", n))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,73 @@
+package otel
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/gx14ac/zart"
+)
+
+func TestTelemetry(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	tel, err := New(tp, mp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := zart.New[int](zart.WithTelemetry(tel))
+	defer tbl.Close()
+
+	if _, err := tbl.Load(strings.NewReader("10.0.0.0/8 1\n192.168.0.0/16 2\n"), zart.Lines[int](), nil); err != nil {
+		t.Fatal(err)
+	}
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 3)
+	tbl.Delete(netip.MustParsePrefix("192.168.0.0/16"))
+	tbl.Lookup(netip.MustParseAddr("10.1.1.1"))
+	tbl.LookupPrefix(netip.MustParseAddr("192.0.2.1"))
+
+	var names []string
+	for _, s := range spans.Ended() {
+		names = append(names, s.Name())
+	}
+	if got := strings.Join(names, " "); got != "zart.insert_batch zart.load" {
+		t.Errorf("spans = %s", got)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range d.DataPoints {
+					kind, _ := p.Attributes.Value("kind")
+					got["changes "+kind.AsString()] += p.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range d.DataPoints {
+					result, _ := p.Attributes.Value("result")
+					got["lookups "+result.AsString()] += int64(p.Count)
+				}
+			}
+		}
+	}
+	want := map[string]int64{"changes insert": 2, "changes update": 1, "changes delete": 1, "lookups hit": 1, "lookups miss": 1}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("%s = %d, want %d", k, got[k], n)
+		}
+	}
+}
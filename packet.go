@@ -0,0 +1,70 @@
+package zart
+
+import "encoding/binary"
+
+// Offsets of the addresses in the IP headers.
+const (
+	ipv4Src = 12
+	ipv4Dst = 16
+	ipv6Src = 8
+	ipv6Dst = 24
+)
+
+// LookupPacketIPv4 is Lookup4Raw for the IPv4 address at pkt[off:], for
+// dataplanes reading frames from AF_XDP or similar rings. The address is
+// read in place, the destination of an IPv4 header at ip is at ip+16.
+// It reports false if pkt ends before the address.
+func (t *Table[V]) LookupPacketIPv4(pkt []byte, off int) (val V, ok bool) {
+	if off < 0 || len(pkt)-off < 4 {
+		return val, false
+	}
+	return t.Lookup4Raw(binary.BigEndian.Uint32(pkt[off:]))
+}
+
+// LookupPacketIPv6 is Lookup6Raw for the IPv6 address at pkt[off:],
+// which is passed on without being copied. The destination of an IPv6
+// header at ip is at ip+24. It reports false if pkt ends before the
+// address.
+func (t *Table[V]) LookupPacketIPv6(pkt []byte, off int) (val V, ok bool) {
+	if off < 0 || len(pkt)-off < 16 {
+		return val, false
+	}
+	return t.Lookup6Raw((*[16]byte)(pkt[off:]))
+}
+
+// LookupPacket looks up the source and destination address of the IP
+// header at pkt[ip:], of either version as told by its first nibble.
+// Both results are empty for a truncated header or another version. The
+// IPv4 addresses are resolved in a single crossing into C.
+func (t *Table[V]) LookupPacket(pkt []byte, ip int) (src, dst Result[V]) {
+	if ip < 0 || ip >= len(pkt) {
+		return src, dst
+	}
+	hdr := pkt[ip:]
+	switch hdr[0] >> 4 {
+	case 4:
+		if len(hdr) < ipv4Dst+4 {
+			return src, dst
+		}
+		addrs := [2]uint32{binary.BigEndian.Uint32(hdr[ipv4Src:]), binary.BigEndian.Uint32(hdr[ipv4Dst:])}
+		var vals [2]uint32
+		var found [2]uint8
+		t.trie.lookupBatch4(addrs[:], vals[:], found[:])
+		return t.result(vals[0], found[0]), t.result(vals[1], found[1])
+	case 6:
+		if len(hdr) < ipv6Dst+16 {
+			return src, dst
+		}
+		src.Value, src.OK = t.Lookup6Raw((*[16]byte)(hdr[ipv6Src:]))
+		dst.Value, dst.OK = t.Lookup6Raw((*[16]byte)(hdr[ipv6Dst:]))
+	}
+	return src, dst
+}
+
+// LookupPacket is like Table.LookupPacket, both addresses are resolved
+// against the same state of the table.
+func (c *ConcurrentTable[V]) LookupPacket(pkt []byte, ip int) (src, dst Result[V]) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LookupPacket(pkt, ip)
+}
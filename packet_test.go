@@ -0,0 +1,56 @@
+package zart
+
+import "testing"
+
+func TestLookupPacket(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("192.0.2.0/24"), 2)
+	tbl.Insert(mpp("2001:db8::/32"), 3)
+
+	// Ethernet frames, the IP headers start at 14
+	v4 := make([]byte, 14+20)
+	v4[14] = 0x45
+	copy(v4[14+12:], []byte{10, 1, 2, 3})
+	copy(v4[14+16:], []byte{192, 0, 2, 9})
+	v6 := make([]byte, 14+40)
+	v6[14] = 0x60
+	copy(v6[14+8:], mpa("2001:db8::1").AsSlice())
+	copy(v6[14+24:], mpa("2001:db9::1").AsSlice())
+
+	if v, ok := tbl.LookupPacketIPv4(v4, 14+16); !ok || v != 2 {
+		t.Errorf("LookupPacketIPv4 = %d, %v, want 2", v, ok)
+	}
+	if v, ok := tbl.LookupPacketIPv6(v6, 14+8); !ok || v != 3 {
+		t.Errorf("LookupPacketIPv6 = %d, %v, want 3", v, ok)
+	}
+	if src, dst := tbl.LookupPacket(v4, 14); src != (Result[int]{1, true}) || dst != (Result[int]{2, true}) {
+		t.Errorf("LookupPacket IPv4 = %v, %v", src, dst)
+	}
+	if src, dst := tbl.LookupPacket(v6, 14); src != (Result[int]{3, true}) || dst.OK {
+		t.Errorf("LookupPacket IPv6 = %v, %v", src, dst)
+	}
+
+	// truncated packets and bad offsets never match
+	if _, ok := tbl.LookupPacketIPv4(v4, len(v4)-3); ok {
+		t.Errorf("LookupPacketIPv4 read past the end")
+	}
+	if _, ok := tbl.LookupPacketIPv6(v6, -1); ok {
+		t.Errorf("LookupPacketIPv6 with a negative offset matched")
+	}
+	if src, dst := tbl.LookupPacket(v4[:30], 14); src.OK || dst.OK {
+		t.Errorf("LookupPacket of a truncated header matched")
+	}
+	if src, dst := tbl.LookupPacket(v4, len(v4)); src.OK || dst.OK {
+		t.Errorf("LookupPacket past the end matched")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tbl.LookupPacket(v4, 14)
+		tbl.LookupPacket(v6, 14)
+	})
+	if allocs != 0 {
+		t.Errorf("LookupPacket allocates %v times", allocs)
+	}
+}
@@ -0,0 +1,43 @@
+package zart
+
+import "net/netip"
+
+// Page returns up to limit entries following after in the order of
+// AllSorted, together with the cursor to pass as after for the next page.
+// The zero Prefix as after starts at the first entry, next is the zero
+// Prefix when there are no entries left. A limit of 0 or less returns no
+// entries and after as the cursor.
+//
+// Every page is a walk of the trie resuming at after, which skips the
+// subtrees before it, and holds no more than limit routes, so a large
+// table can be streamed to clients page by page. The cursor is a prefix
+// and need not stay in the table: the table may change between pages,
+// each page shows it as it is then.
+func (t *Table[V]) Page(after netip.Prefix, limit int) (entries []RouteEntry[V], next netip.Prefix) {
+	if limit <= 0 {
+		return nil, after
+	}
+	var from *route
+	if after.IsValid() {
+		r := makeRoute(after.Masked(), 0)
+		from = &r
+	}
+	// one route more than asked tells whether there is another page
+	buf := make([]route, limit+1)
+	n := t.trie.page(from, buf)
+	entries = make([]RouteEntry[V], min(n, limit))
+	for i := range entries {
+		entries[i] = RouteEntry[V]{Prefix: buf[i].prefix(), Value: t.vals.get(buf[i].val)}
+	}
+	if n > limit {
+		next = entries[limit-1].Prefix
+	}
+	return entries, next
+}
+
+// Page is like Table.Page.
+func (c *ConcurrentTable[V]) Page(after netip.Prefix, limit int) (entries []RouteEntry[V], next netip.Prefix) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.Page(after, limit)
+}
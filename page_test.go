@@ -0,0 +1,90 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	prng := rand.New(rand.NewPCG(85, 85))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 1000) {
+		tbl.Insert(pfx, i)
+	}
+	tbl.Insert(mpp("0.0.0.0/0"), -1)
+	tbl.Insert(mpp("::/0"), -1)
+
+	var want []RouteEntry[int]
+	for pfx, val := range tbl.AllSorted() {
+		want = append(want, RouteEntry[int]{Prefix: pfx, Value: val})
+	}
+
+	for _, limit := range []int{1, 7, 100, len(want), len(want) + 1} {
+		var got []RouteEntry[int]
+		var cursor netip.Prefix
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatalf("limit %d: paging does not end", limit)
+			}
+			page, next := tbl.Page(cursor, limit)
+			if len(page) > limit {
+				t.Fatalf("limit %d: page of %d entries", limit, len(page))
+			}
+			got = append(got, page...)
+			if !next.IsValid() {
+				break
+			}
+			cursor = next
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("limit %d: pages differ from AllSorted, %d entries, want %d", limit, len(got), len(want))
+		}
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range []string{"10.0.0.0/8", "10.0.0.0/16", "10.1.0.0/16", "10.1.2.0/24", "192.168.0.0/16", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), i)
+	}
+
+	tests := []struct {
+		after string
+		want  []string
+	}{
+		{"10.0.0.0/12", []string{"10.0.0.0/16", "10.1.0.0/16"}},
+		{"10.1.0.0/16", []string{"10.1.2.0/24", "192.168.0.0/16"}},
+		{"10.1.2.0/23", []string{"10.1.2.0/24", "192.168.0.0/16"}},
+		{"192.168.255.0/24", []string{"2001:db8::/32"}},
+		{"2001:db8::/32", nil},
+		{"::/0", []string{"2001:db8::/32"}},
+	}
+	for _, tt := range tests {
+		page, _ := tbl.Page(mpp(tt.after), 2)
+		var got []string
+		for _, e := range page {
+			got = append(got, e.Prefix.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Page(%s, 2) = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+
+	// the cursor survives the deletion of the prefix it names
+	page, next := tbl.Page(netip.Prefix{}, 2)
+	if len(page) != 2 || next != mpp("10.0.0.0/16") {
+		t.Fatalf("first page %v, next %s", page, next)
+	}
+	tbl.Delete(next)
+	if page, _ := tbl.Page(next, 1); len(page) != 1 || page[0].Prefix != mpp("10.1.0.0/16") {
+		t.Errorf("Page after a deleted cursor = %v", page)
+	}
+
+	if page, next := tbl.Page(mpp("10.0.0.0/8"), 0); page != nil || next != mpp("10.0.0.0/8") {
+		t.Errorf("Page with limit 0 = %v, %s", page, next)
+	}
+}
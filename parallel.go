@@ -0,0 +1,129 @@
+package zart
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMin is the number of entries below which LoadParallel inserts
+// serially, starting the workers costs more than it saves.
+const parallelMin = 4 * batchSize
+
+// LoadParallel inserts all entries like InsertBatch, building the trie
+// on up to workers goroutines. The entries are sharded by address family
+// and first octet, every worker inserts its shards into a trie of its
+// own, and the subtries are grafted into the table when all are done.
+// Workers of 0 or less use GOMAXPROCS.
+//
+// It is meant for the cold start of large tables: a table that is not
+// empty, is watched, uses an arena or has a memory limit, and inputs too
+// small to be worth it, are loaded with InsertBatch. The shards are only
+// balanced by their number of routes, so tables concentrated in a few
+// first octets gain little.
+func (t *Table[V]) LoadParallel(entries []RouteEntry[V], workers int) {
+	t.mutable()
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || len(entries) < parallelMin || t.watch != nil || t.opts.arena > 0 || t.opts.maxMem > 0 || t.Size() > 0 {
+		t.InsertBatch(entries)
+		return
+	}
+	defer t.start("load_parallel")(len(entries), nil)
+	t.changes++
+
+	// The slots are allocated here, in entry order, so duplicates keep
+	// the value of the last entry as with InsertBatch. Prefixes shorter
+	// than /8 live in the root nodes and go straight into the table.
+	var short []route
+	var shards [512][]route
+	accepted := 0
+	for _, e := range entries {
+		if !t.accepts(e.Prefix) {
+			continue
+		}
+		accepted++
+		r := makeRoute(e.Prefix, t.vals.alloc(e.Value))
+		if t.ttl != nil {
+			t.ttl.forget(e.Prefix.Masked())
+		}
+		if r.bits < 8 {
+			short = append(short, r)
+			continue
+		}
+		k := int(r.addr[0])
+		if r.is4 == 0 {
+			k += 256
+		}
+		shards[k] = append(shards[k], r)
+	}
+
+	// cut the shards into runs of about the same number of routes
+	var runs [][][]route
+	want := (len(entries) + workers - 1) / workers
+	var run [][]route
+	n := 0
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		run = append(run, shard)
+		if n += len(shard); n >= want {
+			runs = append(runs, run)
+			run, n = nil, 0
+		}
+	}
+	if run != nil {
+		runs = append(runs, run)
+	}
+
+	subs := make([]*trie, len(runs))
+	replaced := make([][]uint32, len(runs))
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub := newTrie(t.opts)
+			var buf []uint32
+			for _, shard := range run {
+				buf = append(buf, make([]uint32, len(shard))...)
+				k := len(buf) - len(shard)
+				buf = buf[:k+sub.insertBulk(shard, buf[k:])]
+			}
+			subs[i], replaced[i] = sub, buf
+		}()
+	}
+	wg.Wait()
+
+	// the table was empty, the updates are the duplicates of the input
+	updated := 0
+	for _, slots := range replaced {
+		for _, slot := range slots {
+			t.vals.release(slot)
+		}
+		updated += len(slots)
+	}
+	buf := make([]uint32, len(short))
+	k := t.trie.insertBulk(short, buf)
+	for _, slot := range buf[:k] {
+		t.vals.release(slot)
+	}
+	updated += k
+	for _, sub := range subs {
+		// the shards are disjoint, the union has no conflicts
+		if !t.trie.graft(sub) {
+			t.trie.union(sub, 0, nil, nil)
+		}
+		sub.close()
+	}
+	t.count(EventInsert, accepted-updated)
+	t.count(EventUpdate, updated)
+}
+
+// LoadParallel is like Table.LoadParallel.
+func (c *ConcurrentTable[V]) LoadParallel(entries []RouteEntry[V], workers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.LoadParallel(entries, workers)
+}
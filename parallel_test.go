@@ -0,0 +1,76 @@
+package zart
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestLoadParallel(t *testing.T) {
+	prng := rand.New(rand.NewPCG(77, 77))
+	pfxs := randomPrefixes(prng, 20000)
+	entries := make([]RouteEntry[int], 0, len(pfxs)+100)
+	for i, pfx := range pfxs {
+		entries = append(entries, RouteEntry[int]{pfx, i})
+	}
+	// duplicates across the input, short prefixes and an invalid one
+	for i := range 50 {
+		entries = append(entries, RouteEntry[int]{pfxs[i*7], -i})
+	}
+	entries = append(entries,
+		RouteEntry[int]{netip.MustParsePrefix("0.0.0.0/0"), 1},
+		RouteEntry[int]{netip.MustParsePrefix("10.0.0.0/7"), 2},
+		RouteEntry[int]{netip.MustParsePrefix("::/0"), 3},
+		RouteEntry[int]{netip.MustParsePrefix("2000::/3"), 4},
+		RouteEntry[int]{},
+	)
+
+	wantTel := newFakeTelemetry()
+	want := New[int](WithTelemetry(wantTel))
+	defer want.Close()
+	want.InsertBatch(entries)
+
+	for _, workers := range []int{0, 3, 64} {
+		tel := newFakeTelemetry()
+		tbl := New[int](WithTelemetry(tel))
+		tbl.LoadParallel(entries, workers)
+		if !maps.Equal(tel.changes, wantTel.changes) {
+			t.Errorf("workers %d: counted %v, want %v", workers, tel.changes, wantTel.changes)
+		}
+		if got, w := maps.Collect(tbl.All()), maps.Collect(want.All()); !maps.Equal(got, w) {
+			t.Errorf("workers %d: %d routes, want %d", workers, len(got), len(w))
+		}
+		if tbl.Size() != want.Size() {
+			t.Errorf("workers %d: Size = %d, want %d", workers, tbl.Size(), want.Size())
+		}
+		if err := tbl.Fsck(); err != nil {
+			t.Errorf("workers %d: %v", workers, err)
+		}
+		tbl.Close()
+	}
+}
+
+func TestLoadParallelFallback(t *testing.T) {
+	prng := rand.New(rand.NewPCG(77, 77))
+	entries := make([]RouteEntry[int], 0, parallelMin)
+	for i, pfx := range randomPrefixes(prng, parallelMin) {
+		entries = append(entries, RouteEntry[int]{pfx, i})
+	}
+
+	// a table with routes already in it
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(entries[0].Prefix, -1)
+	tbl.Insert(netip.MustParsePrefix("192.0.2.0/24"), -2)
+	tbl.LoadParallel(entries, 4)
+	if v, _ := tbl.Get(entries[0].Prefix); v != 0 {
+		t.Errorf("Get = %d, want the loaded value", v)
+	}
+	if v, _ := tbl.Get(netip.MustParsePrefix("192.0.2.0/24")); v != -2 {
+		t.Errorf("Get = %d, want the value from before", v)
+	}
+	if err := tbl.Fsck(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,52 @@
+package zart
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// parsePrefix parses a prefix in CIDR notation, a bare address is taken as
+// its host route.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(s)
+}
+
+// InsertString is TryInsert for a prefix in CIDR notation like
+// "10.0.0.0/8" or "2001:db8::/32". A bare address is inserted as a host
+// route. Syntax errors are returned as from netip.ParsePrefix.
+func (t *Table[V]) InsertString(s string, val V) error {
+	pfx, err := parsePrefix(s)
+	if err != nil {
+		return err
+	}
+	return t.TryInsert(pfx, val)
+}
+
+// DeleteString is TryDelete for a prefix given as to InsertString.
+func (t *Table[V]) DeleteString(s string) (bool, error) {
+	pfx, err := parsePrefix(s)
+	if err != nil {
+		return false, err
+	}
+	return t.TryDelete(pfx)
+}
+
+// LookupString is Lookup for an address like "10.1.2.3" or "2001:db8::1".
+func (t *Table[V]) LookupString(s string) (val V, ok bool, err error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return val, false, err
+	}
+	if err := t.usable(); err != nil {
+		return val, false, err
+	}
+	val, ok = t.Lookup(addr)
+	return val, ok, nil
+}
@@ -0,0 +1,49 @@
+package zart
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStringAPI(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+
+	for _, s := range []string{"10.0.0.0/8", "2001:db8::/32", "192.0.2.1"} {
+		if err := tbl.InsertString(s, s); err != nil {
+			t.Errorf("InsertString(%q) = %v", s, err)
+		}
+	}
+	if err := tbl.InsertString("10.0.0.0/40", "x"); err == nil {
+		t.Error("InsertString accepted a /40")
+	}
+	if err := tbl.InsertString("not a prefix", "x"); err == nil {
+		t.Error("InsertString accepted garbage")
+	}
+
+	for addr, want := range map[string]string{
+		"10.1.2.3":    "10.0.0.0/8",
+		"2001:db8::1": "2001:db8::/32",
+		"192.0.2.1":   "192.0.2.1",
+	} {
+		if v, ok, err := tbl.LookupString(addr); v != want || !ok || err != nil {
+			t.Errorf("LookupString(%q) = %q, %v, %v, want %q", addr, v, ok, err, want)
+		}
+	}
+	if _, ok, err := tbl.LookupString("192.0.2.2"); ok || err != nil {
+		t.Errorf("LookupString of an unrouted address = %v, %v", ok, err)
+	}
+	if _, _, err := tbl.LookupString("10.0.0.0/8"); err == nil {
+		t.Error("LookupString accepted a prefix")
+	}
+
+	if ok, err := tbl.DeleteString("192.0.2.1/32"); !ok || err != nil {
+		t.Errorf("DeleteString = %v, %v", ok, err)
+	}
+
+	strict := New[string](WithStrict())
+	defer strict.Close()
+	if err := strict.InsertString("10.1.0.0/8", "x"); !errors.Is(err, ErrHostBits) {
+		t.Errorf("InsertString with host bits on a strict table = %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+// Package pcap classifies captured traffic by the routes of a zart
+// table: which prefixes the packets of a capture come from and go to,
+// and how much of it each carries.
+//
+// Captures are read in the classic libpcap file format, as written by
+// tcpdump -w, with Ethernet, Linux cooked (SLL and SLL2) or raw IP link
+// layers. pcapng files must be converted first, editcap -F pcap will do.
+package pcap
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrFormat is returned, wrapped, for files that are not pcap captures.
+var ErrFormat = errors.New("pcap: malformed capture")
+
+// Link layer types of the captures read.
+const (
+	linkEthernet = 1
+	linkRaw      = 101
+	linkSLL      = 113
+	linkIPv4     = 228
+	linkIPv6     = 229
+	linkSLL2     = 276
+)
+
+// maxSnap bounds the captured length of a packet.
+const maxSnap = 1 << 18
+
+// Packet is an IP packet of a capture.
+type Packet struct {
+	Time     time.Time
+	Src, Dst netip.Addr
+
+	// Length is the length of the packet on the wire, including its link
+	// layer header, not the part captured.
+	Length int
+}
+
+// Reader reads the IP packets of a capture one by one, skipping the
+// packets of other protocols.
+type Reader struct {
+	r       *bufio.Reader
+	order   binary.ByteOrder
+	nano    bool
+	link    uint32
+	hdr     [16]byte
+	buf     []byte
+	skipped int
+}
+
+// NewReader reads the file header of the capture in r and returns a
+// Reader for its packets.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReaderSize(r, 1<<16)}
+	var hdr [24]byte
+	if _, err := io.ReadFull(rd.r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("%w: file header: %w", ErrFormat, err)
+	}
+	switch binary.LittleEndian.Uint32(hdr[:]) {
+	case 0xa1b2c3d4:
+		rd.order = binary.LittleEndian
+	case 0xa1b23c4d:
+		rd.order, rd.nano = binary.LittleEndian, true
+	case 0xd4c3b2a1:
+		rd.order = binary.BigEndian
+	case 0x4d3cb2a1:
+		rd.order, rd.nano = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("%w: not a pcap file", ErrFormat)
+	}
+	rd.link = rd.order.Uint32(hdr[20:]) & 0x0fffffff
+	switch rd.link {
+	case linkEthernet, linkRaw, linkSLL, linkIPv4, linkIPv6, linkSLL2:
+	default:
+		return nil, fmt.Errorf("%w: unsupported link type %d", ErrFormat, rd.link)
+	}
+	return rd, nil
+}
+
+// Skipped returns the number of packets read that were not IP.
+func (rd *Reader) Skipped() int {
+	return rd.skipped
+}
+
+// Next returns the next IP packet. At the end of the capture it returns
+// io.EOF.
+func (rd *Reader) Next() (Packet, error) {
+	for {
+		if _, err := io.ReadFull(rd.r, rd.hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("%w: truncated packet header", ErrFormat)
+			}
+			return Packet{}, err
+		}
+		sec, frac := rd.order.Uint32(rd.hdr[0:]), rd.order.Uint32(rd.hdr[4:])
+		capLen, wireLen := rd.order.Uint32(rd.hdr[8:]), rd.order.Uint32(rd.hdr[12:])
+		if capLen > maxSnap {
+			return Packet{}, fmt.Errorf("%w: packet of %d bytes", ErrFormat, capLen)
+		}
+		if cap(rd.buf) < int(capLen) {
+			rd.buf = make([]byte, capLen)
+		}
+		data := rd.buf[:capLen]
+		if _, err := io.ReadFull(rd.r, data); err != nil {
+			return Packet{}, fmt.Errorf("%w: truncated packet: %w", ErrFormat, err)
+		}
+
+		src, dst, ok := rd.addrs(data)
+		if !ok {
+			rd.skipped++
+			continue
+		}
+		if !rd.nano {
+			frac *= 1000
+		}
+		return Packet{Time: time.Unix(int64(sec), int64(frac)), Src: src, Dst: dst, Length: int(wireLen)}, nil
+	}
+}
+
+// addrs returns the addresses of the IP packet in the link layer frame
+// b, ok is false for other protocols and truncated packets.
+func (rd *Reader) addrs(b []byte) (src, dst netip.Addr, ok bool) {
+	var etherType uint16
+	switch rd.link {
+	case linkEthernet:
+		if len(b) < 14 {
+			return src, dst, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b[12:]), b[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(b) >= 4 {
+			etherType, b = binary.BigEndian.Uint16(b[2:]), b[4:] // VLAN tags
+		}
+	case linkSLL:
+		if len(b) < 16 {
+			return src, dst, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b[14:]), b[16:]
+	case linkSLL2:
+		if len(b) < 20 {
+			return src, dst, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b[0:]), b[20:]
+	default:
+		// raw IP, the version tells
+	}
+
+	switch {
+	case (etherType == 0x0800 || etherType == 0) && len(b) >= 20 && b[0]>>4 == 4:
+		return netip.AddrFrom4([4]byte(b[12:16])), netip.AddrFrom4([4]byte(b[16:20])), true
+	case (etherType == 0x86dd || etherType == 0) && len(b) >= 40 && b[0]>>4 == 6:
+		return netip.AddrFrom16([16]byte(b[8:24])), netip.AddrFrom16([16]byte(b[24:40])), true
+	}
+	return src, dst, false
+}
+
+// Counter counts packets and their bytes on the wire.
+type Counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+func (c *Counter) add(p *Packet) {
+	c.Packets++
+	c.Bytes += uint64(p.Length)
+}
+
+// PrefixStats is the traffic of a route, from and to the addresses it
+// routes.
+type PrefixStats[V any] struct {
+	Prefix   netip.Prefix
+	Value    V
+	Src, Dst Counter
+}
+
+// Report is the traffic of a capture by route.
+type Report[V any] struct {
+	Prefixes map[netip.Prefix]*PrefixStats[V]
+
+	// Unrouted counts the packets from and to addresses without a route.
+	Unrouted struct{ Src, Dst Counter }
+
+	// Total counts all IP packets, OtherPackets those of other protocols.
+	Total        Counter
+	OtherPackets int
+}
+
+// Sorted returns the routes of the report by their bytes, sources and
+// destinations together, the busiest first; routes with equal traffic
+// are in CIDR order.
+func (r *Report[V]) Sorted() []*PrefixStats[V] {
+	stats := slices.Collect(maps.Values(r.Prefixes))
+	slices.SortFunc(stats, func(a, b *PrefixStats[V]) int {
+		if c := cmp.Compare(b.Src.Bytes+b.Dst.Bytes, a.Src.Bytes+a.Dst.Bytes); c != 0 {
+			return c
+		}
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Prefix.Bits(), b.Prefix.Bits())
+	})
+	return stats
+}
+
+// Classify reads the capture in r and counts the packets of every route
+// of t their source and their destination address match by longest-
+// prefix match. On error the report covers the packets read before it.
+func Classify[V any](t *zart.Table[V], r io.Reader) (*Report[V], error) {
+	rep := &Report[V]{Prefixes: map[netip.Prefix]*PrefixStats[V]{}}
+	rd, err := NewReader(r)
+	if err != nil {
+		return rep, err
+	}
+	for {
+		p, err := rd.Next()
+		rep.OtherPackets = rd.Skipped()
+		if err == io.EOF {
+			return rep, nil
+		}
+		if err != nil {
+			return rep, err
+		}
+		rep.Total.add(&p)
+		if s := rep.match(t, p.Src); s != nil {
+			s.Src.add(&p)
+		} else {
+			rep.Unrouted.Src.add(&p)
+		}
+		if s := rep.match(t, p.Dst); s != nil {
+			s.Dst.add(&p)
+		} else {
+			rep.Unrouted.Dst.add(&p)
+		}
+	}
+}
+
+// match returns the stats of the route of addr, nil if there is none.
+func (r *Report[V]) match(t *zart.Table[V], addr netip.Addr) *PrefixStats[V] {
+	pfx, val, ok := t.LookupPrefix(addr)
+	if !ok {
+		return nil
+	}
+	s := r.Prefixes[pfx]
+	if s == nil {
+		s = &PrefixStats[V]{Prefix: pfx, Value: val}
+		r.Prefixes[pfx] = s
+	}
+	return s
+}
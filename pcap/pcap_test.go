@@ -0,0 +1,133 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gx14ac/zart"
+)
+
+// capture builds a little endian pcap file of Ethernet frames.
+type capture struct{ bytes.Buffer }
+
+func newCapture(link uint32) *capture {
+	c := &capture{}
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:], link)
+	c.Write(hdr)
+	return c
+}
+
+// frame appends a frame with an Ethernet header of etherType, unless 0,
+// and the IP header of src and dst, padded to wire bytes.
+func (c *capture) frame(etherType uint16, src, dst string, wire int) {
+	var b []byte
+	if etherType != 0 {
+		b = make([]byte, 14)
+		binary.BigEndian.PutUint16(b[12:], etherType)
+	}
+	s, d := netip.MustParseAddr(src), netip.MustParseAddr(dst)
+	if s.Is4() {
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		copy(ip[12:], s.AsSlice())
+		copy(ip[16:], d.AsSlice())
+		b = append(b, ip...)
+	} else {
+		ip := make([]byte, 40)
+		ip[0] = 0x60
+		copy(ip[8:], s.AsSlice())
+		copy(ip[24:], d.AsSlice())
+		b = append(b, ip...)
+	}
+	c.record(b, wire)
+}
+
+func (c *capture) record(b []byte, wire int) {
+	hdr := make([]byte, 16)
+	binary.LittleEndian.PutUint32(hdr[0:], 1700000000)
+	binary.LittleEndian.PutUint32(hdr[4:], 250000)
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(wire))
+	c.Write(hdr)
+	c.Write(b)
+}
+
+func TestReader(t *testing.T) {
+	c := newCapture(linkEthernet)
+	c.frame(0x0800, "10.0.0.1", "192.0.2.1", 100)
+	c.record(make([]byte, 60), 60) // an ARP frame
+	c.frame(0x86dd, "2001:db8::1", "2001:db8::2", 200)
+
+	rd, err := NewReader(&c.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := rd.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Packet{Time: time.Unix(1700000000, 250000000), Src: netip.MustParseAddr("10.0.0.1"), Dst: netip.MustParseAddr("192.0.2.1"), Length: 100}
+	if p != want {
+		t.Errorf("Next = %+v, want %+v", p, want)
+	}
+	if p, err = rd.Next(); err != nil || p.Dst != netip.MustParseAddr("2001:db8::2") {
+		t.Errorf("Next = %+v, %v", p, err)
+	}
+	if _, err := rd.Next(); err != io.EOF || rd.Skipped() != 1 {
+		t.Errorf("Next at the end = %v, skipped %d", err, rd.Skipped())
+	}
+
+	raw := newCapture(linkRaw)
+	raw.frame(0, "10.0.0.1", "10.0.0.2", 20)
+	if rd, err := NewReader(&raw.Buffer); err != nil {
+		t.Error(err)
+	} else if p, err := rd.Next(); err != nil || p.Src != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("raw IP Next = %+v, %v", p, err)
+	}
+
+	if _, err := NewReader(bytes.NewReader(make([]byte, 24))); !errors.Is(err, ErrFormat) {
+		t.Errorf("NewReader of zeros = %v, want ErrFormat", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tbl := zart.New[string]()
+	tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), "internal")
+	tbl.Insert(netip.MustParsePrefix("192.0.2.0/24"), "transit")
+	tbl.Insert(netip.MustParsePrefix("192.0.2.128/25"), "peer")
+
+	c := newCapture(linkEthernet)
+	c.frame(0x0800, "10.0.0.1", "192.0.2.1", 1000)
+	c.frame(0x0800, "10.0.0.2", "192.0.2.200", 500)
+	c.frame(0x0800, "192.0.2.200", "10.0.0.2", 100)
+	c.frame(0x0800, "10.0.0.1", "8.8.8.8", 50)
+
+	rep, err := Classify(tbl, &c.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Total != (Counter{4, 1650}) || rep.Unrouted.Dst != (Counter{1, 50}) || rep.Unrouted.Src != (Counter{}) {
+		t.Errorf("Total %+v, Unrouted %+v", rep.Total, rep.Unrouted)
+	}
+	sorted := rep.Sorted()
+	if len(sorted) != 3 {
+		t.Fatalf("Sorted = %d routes, want 3", len(sorted))
+	}
+	in := sorted[0]
+	if in.Value != "internal" || in.Src != (Counter{3, 1550}) || in.Dst != (Counter{1, 100}) {
+		t.Errorf("busiest = %+v", in)
+	}
+	if sorted[1].Value != "transit" || sorted[2].Value != "peer" || sorted[2].Src != (Counter{1, 100}) {
+		t.Errorf("Sorted = %+v, %+v", sorted[1], sorted[2])
+	}
+}
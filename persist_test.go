@@ -0,0 +1,95 @@
+package zart
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestPersistVersions(t *testing.T) {
+	prng := rand.New(rand.NewPCG(15, 15))
+	pfxs := randomPrefixes(prng, 400)
+
+	base := New[int]()
+	model := map[netip.Prefix]int{}
+	for i, pfx := range pfxs[:200] {
+		base.Insert(pfx, i)
+		model[pfx] = i
+	}
+
+	versions := []*Table[int]{base}
+	models := []map[netip.Prefix]int{model}
+	for i := range 300 {
+		j := prng.IntN(len(versions))
+		pfx := pfxs[prng.IntN(len(pfxs))]
+		m := maps.Clone(models[j])
+
+		var v *Table[int]
+		if prng.IntN(3) == 0 {
+			v = versions[j].DeletePersist(pfx)
+			delete(m, pfx)
+		} else {
+			v = versions[j].InsertPersist(pfx, 1000+i)
+			m[pfx] = 1000 + i
+		}
+		versions = append(versions, v)
+		models = append(models, m)
+
+		// in-place writes to a version must not leak into the others
+		if prng.IntN(10) == 0 {
+			k := prng.IntN(len(versions))
+			versions[k].Insert(pfx, -i)
+			models[k][pfx] = -i
+		}
+	}
+
+	for i, v := range versions {
+		got := map[netip.Prefix]int{}
+		for pfx, val := range v.All() {
+			got[pfx] = val
+		}
+		if !maps.Equal(got, models[i]) {
+			t.Fatalf("version %d has %d routes, want %d", i, len(got), len(models[i]))
+		}
+		for _, pfx := range pfxs {
+			want, wantOK := lpmModel(models[i], pfx.Addr())
+			val, ok := v.Lookup(pfx.Addr())
+			if val != want || ok != wantOK {
+				t.Fatalf("version %d: Lookup(%s) = %d, %v, want %d, %v", i, pfx.Addr(), val, ok, want, wantOK)
+			}
+		}
+	}
+
+	for _, v := range versions {
+		v.Close()
+	}
+}
+
+func TestPersistCloseOrder(t *testing.T) {
+	tbl := New[string]()
+	tbl.Insert(mpp("10.0.0.0/8"), "ten")
+
+	next := tbl.DeletePersist(mpp("10.0.0.0/8"))
+	defer next.Close()
+	tbl.Close()
+
+	if _, ok := next.Lookup(mpa("10.1.1.1")); ok {
+		t.Errorf("deleted prefix found in persisted version")
+	}
+	next.Insert(mpp("10.0.0.0/8"), "again")
+	if v, _ := next.Lookup(mpa("10.1.1.1")); v != "again" {
+		t.Errorf("Lookup after closing the original = %q, want %q", v, "again")
+	}
+}
+
+// lpmModel is a longest-prefix match over a map of prefixes.
+func lpmModel(m map[netip.Prefix]int, addr netip.Addr) (val int, ok bool) {
+	best := -1
+	for pfx, v := range m {
+		if pfx.Contains(addr) && pfx.Bits() > best {
+			best, val, ok = pfx.Bits(), v, true
+		}
+	}
+	return val, ok
+}
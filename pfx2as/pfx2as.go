@@ -0,0 +1,193 @@
+// Package pfx2as builds IP to AS number tables from the CAIDA RouteViews
+// prefix-to-AS datasets, the origin ASes seen in BGP for every routed
+// prefix:
+//
+//	db, err := pfx2as.Fetch(ctx, nil, "") // the latest IPv4 dataset
+//	if err != nil {
+//		return err
+//	}
+//	asn, ok := db.LookupASN(addr)
+//
+// The datasets are text files with one route per line, the prefix
+// address, its length and the origin AS, separated by tabs:
+//
+//	1.0.0.0	24	13335
+//	1.0.4.0	22	38803_56203
+//
+// Prefixes announced by several origins (MOAS) list them joined by "_",
+// AS sets join their members by ",". The tables keep the first AS number
+// of either.
+package pfx2as
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrFormat is returned, wrapped, for datasets that do not parse.
+var ErrFormat = errors.New("pfx2as: malformed dataset")
+
+// The RouteViews prefix-to-AS datasets for IPv4 and IPv6. Each directory
+// holds a pfx2as-creation.log listing the files, the newest last.
+const (
+	RouteViews4 = "https://publicdata.caida.org/datasets/routing/routeviews-prefix2as/"
+	RouteViews6 = "https://publicdata.caida.org/datasets/routing/routeviews6-prefix2as/"
+)
+
+// DB is a table mapping prefixes to their origin AS.
+type DB struct {
+	*zart.Table[uint32]
+}
+
+// LookupASN returns the origin AS of the most specific prefix routing
+// addr.
+func (db DB) LookupASN(addr netip.Addr) (asn uint32, ok bool) {
+	return db.Lookup(addr)
+}
+
+// Load inserts the routes of the dataset in r into t and returns their
+// number. r must be decompressed, see Fetch for gzipped datasets. On
+// error the routes read before it are in the table.
+func Load(t *zart.Table[uint32], r io.Reader) (n int, err error) {
+	return t.Load(r, Format(), nil)
+}
+
+// Format is the dataset format for Table.Load, the values are the origin
+// AS numbers as for Load.
+func Format() zart.Format[uint32] {
+	return func(r io.Reader) zart.Decoder[uint32] { return &decoder{sc: bufio.NewScanner(r)} }
+}
+
+type decoder struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (d *decoder) Next() (e zart.RouteEntry[uint32], err error) {
+	for d.sc.Scan() {
+		d.line++
+		text := d.sc.Text()
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return e, fmt.Errorf("%w: line %d: %d fields", ErrFormat, d.line, len(fields))
+		}
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return e, fmt.Errorf("%w: line %d: address %q", ErrFormat, d.line, fields[0])
+		}
+		bits, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return e, fmt.Errorf("%w: line %d: length %q", ErrFormat, d.line, fields[1])
+		}
+		if e.Prefix, err = zart.MakePrefix(addr, bits); err != nil {
+			return e, fmt.Errorf("%w: line %d: %w", ErrFormat, d.line, err)
+		}
+		first, _, _ := strings.Cut(fields[2], "_")
+		first, _, _ = strings.Cut(first, ",")
+		asn, err := strconv.ParseUint(first, 10, 32)
+		if err != nil {
+			return e, fmt.Errorf("%w: line %d: AS %q", ErrFormat, d.line, fields[2])
+		}
+		e.Value = uint32(asn)
+		return e, nil
+	}
+	if err := d.sc.Err(); err != nil {
+		return e, err
+	}
+	return e, io.EOF
+}
+
+// Fetch downloads a dataset into a new DB, gunzipping it if needed. url
+// is the dataset file or one of the dataset directories RouteViews4 and
+// RouteViews6, for which the newest file is fetched; "" is RouteViews4.
+// client may be nil for http.DefaultClient.
+func Fetch(ctx context.Context, client *http.Client, url string) (DB, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if url == "" {
+		url = RouteViews4
+	}
+	if strings.HasSuffix(url, "/") {
+		latest, err := latest(ctx, client, url)
+		if err != nil {
+			return DB{}, err
+		}
+		url += latest
+	}
+
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return DB{}, err
+	}
+	defer body.Close()
+	br := bufio.NewReader(body)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return DB{}, fmt.Errorf("pfx2as: %s: %w", url, err)
+		}
+		r = zr
+	}
+	db := DB{zart.New[uint32]()}
+	if _, err := Load(db.Table, r); err != nil {
+		return DB{}, fmt.Errorf("pfx2as: %s: %w", url, err)
+	}
+	return db, nil
+}
+
+// latest returns the path of the newest file in the creation log of the
+// dataset directory dir.
+func latest(ctx context.Context, client *http.Client, dir string) (string, error) {
+	body, err := get(ctx, client, dir+"pfx2as-creation.log")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	var path string
+	sc := bufio.NewScanner(body)
+	for sc.Scan() {
+		// sequence number, creation time, path
+		if fields := strings.Fields(sc.Text()); len(fields) == 3 && fields[0][0] != '#' {
+			path = fields[2]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("%w: no files in %spfx2as-creation.log", ErrFormat, dir)
+	}
+	return path, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("pfx2as: %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
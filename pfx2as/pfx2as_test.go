@@ -0,0 +1,70 @@
+package pfx2as
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+const dataset = "1.0.0.0\t24\t13335\n1.0.4.0\t22\t38803_56203\n1.0.4.0\t24\t64500,64501\n2001:db8::\t32\t64502\n"
+
+func TestLoad(t *testing.T) {
+	tbl := zart.New[uint32]()
+	if n, err := Load(tbl, strings.NewReader(dataset)); err != nil || n != 4 {
+		t.Fatalf("Load = %d, %v", n, err)
+	}
+	db := DB{tbl}
+	for addr, want := range map[string]uint32{"1.0.0.1": 13335, "1.0.5.1": 38803, "1.0.4.1": 64500, "2001:db8::1": 64502, "8.8.8.8": 0} {
+		if asn, ok := db.LookupASN(netip.MustParseAddr(addr)); asn != want || ok != (want != 0) {
+			t.Errorf("LookupASN(%s) = %d, %v, want %d", addr, asn, ok, want)
+		}
+	}
+
+	for _, bad := range []string{"1.0.0.0\t24\n", "1.0.0.0\t33\t1\n", "1.0.0.0\t24\tASx\n"} {
+		if _, err := Load(zart.New[uint32](), strings.NewReader(bad)); !errors.Is(err, ErrFormat) {
+			t.Errorf("Load(%q) = %v, want ErrFormat", bad, err)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(dataset))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/routeviews-prefix2as/pfx2as-creation.log":
+			w.Write([]byte("# seqnum\ttimestamp\tpath\n1\t1700000000\t2023/11/old.pfx2as.gz\n2\t1700086400\t2023/11/new.pfx2as.gz\n"))
+		case "/routeviews-prefix2as/2023/11/new.pfx2as.gz":
+			w.Write(gz.Bytes())
+		case "/plain.pfx2as":
+			w.Write([]byte(dataset))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, url := range []string{srv.URL + "/routeviews-prefix2as/", srv.URL + "/plain.pfx2as"} {
+		db, err := Fetch(context.Background(), srv.Client(), url)
+		if err != nil {
+			t.Fatalf("Fetch(%s): %v", url, err)
+		}
+		if db.Size() != 4 {
+			t.Errorf("Fetch(%s) loaded %d routes, want 4", url, db.Size())
+		}
+	}
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/missing/"); err == nil {
+		t.Errorf("Fetch of a missing dataset succeeded")
+	}
+}
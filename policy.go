@@ -0,0 +1,102 @@
+package zart
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Precedence selects how a Policy decides between matching permit and
+// deny rules.
+type Precedence int
+
+const (
+	// MostSpecific lets the longest matching prefix decide, of either
+	// kind. A prefix both permitted and denied is denied.
+	MostSpecific Precedence = iota
+
+	// DenyOverrides denies an address covered by any deny rule, however
+	// specific the permit rules covering it are.
+	DenyOverrides
+)
+
+func (p Precedence) String() string {
+	switch p {
+	case MostSpecific:
+		return "most-specific"
+	case DenyOverrides:
+		return "deny-overrides"
+	}
+	return fmt.Sprintf("Precedence(%d)", int(p))
+}
+
+// Decision is the result of Policy.Evaluate. Prefix and Rule are those of
+// the rule that decided, if Matched; an address matching no rule is not
+// allowed.
+type Decision[V any] struct {
+	Allowed bool
+	Matched bool
+	Prefix  netip.Prefix
+	Rule    V
+}
+
+// Policy is an allow/deny list of prefixes: a table of permit and a table
+// of deny rules, each with a value that identifies it in the decisions,
+// like a rule name or the line of a config file. Like Table, a Policy is
+// not safe for concurrent use.
+type Policy[V any] struct {
+	prec   Precedence
+	permit *Table[V]
+	deny   *Table[V]
+}
+
+// NewPolicy returns an empty Policy deciding with precedence p.
+func NewPolicy[V any](p Precedence) *Policy[V] {
+	return &Policy[V]{prec: p, permit: New[V](), deny: New[V]()}
+}
+
+// Close releases both tables.
+func (p *Policy[V]) Close() {
+	p.permit.Close()
+	p.deny.Close()
+}
+
+// Permit adds a rule allowing the addresses in pfx, replacing the value
+// of an earlier permit rule for pfx.
+func (p *Policy[V]) Permit(pfx netip.Prefix, rule V) {
+	p.permit.Insert(pfx, rule)
+}
+
+// Deny adds a rule denying the addresses in pfx, replacing the value of
+// an earlier deny rule for pfx.
+func (p *Policy[V]) Deny(pfx netip.Prefix, rule V) {
+	p.deny.Insert(pfx, rule)
+}
+
+// Permits returns the table of permit rules, for loading or removing
+// rules in bulk.
+func (p *Policy[V]) Permits() *Table[V] {
+	return p.permit
+}
+
+// Denies returns the table of deny rules.
+func (p *Policy[V]) Denies() *Table[V] {
+	return p.deny
+}
+
+// Evaluate decides whether addr is allowed. The matching rule of either
+// kind is the longest-prefix match of addr in its table; which of the
+// two decides depends on the precedence of the policy.
+func (p *Policy[V]) Evaluate(addr netip.Addr) Decision[V] {
+	dpfx, dval, denied := p.deny.LookupPrefix(addr)
+	if denied && p.prec == DenyOverrides {
+		return Decision[V]{Matched: true, Prefix: dpfx, Rule: dval}
+	}
+	ppfx, pval, permitted := p.permit.LookupPrefix(addr)
+	switch {
+	case permitted && (!denied || ppfx.Bits() > dpfx.Bits()):
+		return Decision[V]{Allowed: true, Matched: true, Prefix: ppfx, Rule: pval}
+	case denied:
+		return Decision[V]{Matched: true, Prefix: dpfx, Rule: dval}
+	}
+	return Decision[V]{}
+}
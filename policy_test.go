@@ -0,0 +1,48 @@
+package zart
+
+import "testing"
+
+func TestPolicy(t *testing.T) {
+	rules := func(prec Precedence) *Policy[string] {
+		p := NewPolicy[string](prec)
+		p.Permit(mpp("10.0.0.0/8"), "corp")
+		p.Deny(mpp("10.66.0.0/16"), "quarantine")
+		p.Permit(mpp("10.66.1.0/24"), "remediation")
+		p.Permit(mpp("192.0.2.0/24"), "web")
+		p.Deny(mpp("192.0.2.0/24"), "web-block")
+		p.Deny(mpp("::/0"), "no-v6")
+		p.Permit(mpp("2001:db8::/32"), "lab")
+		return p
+	}
+
+	tests := []struct {
+		addr    string
+		prec    Precedence
+		allowed bool
+		rule    string
+	}{
+		{"10.1.1.1", MostSpecific, true, "corp"},
+		{"10.66.0.1", MostSpecific, false, "quarantine"},
+		{"10.66.1.1", MostSpecific, true, "remediation"},
+		{"10.66.1.1", DenyOverrides, false, "quarantine"},
+		{"10.1.1.1", DenyOverrides, true, "corp"},
+		// the same prefix permitted and denied
+		{"192.0.2.1", MostSpecific, false, "web-block"},
+		{"2001:db8::1", MostSpecific, true, "lab"},
+		{"2001:db8::1", DenyOverrides, false, "no-v6"},
+		{"172.16.0.1", MostSpecific, false, ""},
+	}
+	for _, tt := range tests {
+		p := rules(tt.prec)
+		d := p.Evaluate(mpa(tt.addr))
+		if d.Allowed != tt.allowed || d.Rule != tt.rule || d.Matched != (tt.rule != "") {
+			t.Errorf("%s: Evaluate(%s) = %+v, want allowed %v by %q", tt.prec, tt.addr, d, tt.allowed, tt.rule)
+		}
+		if d.Matched {
+			if got, _ := p.Permits().Get(d.Prefix); d.Allowed && got != d.Rule {
+				t.Errorf("%s: Evaluate(%s) names prefix %s of another rule", tt.prec, tt.addr, d.Prefix)
+			}
+		}
+		p.Close()
+	}
+}
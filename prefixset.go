@@ -0,0 +1,251 @@
+package zart
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"iter"
+	"net/netip"
+)
+
+// PrefixSet is a set of IPv4 and IPv6 prefixes without values, for
+// blocklists, bogon filters and other tables that only answer whether an
+// address or prefix is listed. Unlike a Table[struct{}] it keeps nothing
+// in Go: there is no payload registry, the trie holds the prefixes alone
+// and Contains is a single call into it.
+//
+// The trie has a node format of its own, without the value slots of the
+// prefixes, so a set takes less memory than a table of the same
+// prefixes, see Stats.
+//
+// A PrefixSet must be created with NewPrefixSet and released with Close,
+// like a Table. It is not safe for concurrent use.
+type PrefixSet struct {
+	trie   *setTrie
+	opts   options
+	closed bool
+}
+
+// Sets are encoded by encoding/gob and others like tables.
+var (
+	_ encoding.BinaryMarshaler   = (*PrefixSet)(nil)
+	_ encoding.BinaryUnmarshaler = (*PrefixSet)(nil)
+)
+
+// NewPrefixSet returns an empty set. Of the options only WithArena and
+// WithPathCompression apply.
+func NewPrefixSet(opts ...Option) *PrefixSet {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &PrefixSet{trie: newSetTrie(o), opts: o}
+}
+
+// Close releases the trie. Closing a set again does nothing.
+func (s *PrefixSet) Close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.trie.close()
+}
+
+// Clone returns an independent copy of the set.
+func (s *PrefixSet) Clone() *PrefixSet {
+	return &PrefixSet{trie: s.trie.clone(), opts: s.opts}
+}
+
+// Size returns the number of prefixes in the set.
+func (s *PrefixSet) Size() int {
+	return s.trie.size4() + s.trie.size6()
+}
+
+// Add adds pfx to the set and reports whether it was not in it yet. Host
+// bits are masked off, invalid prefixes are ignored.
+func (s *PrefixSet) Add(pfx netip.Prefix) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return s.trie.add4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return s.trie.add6(&a16, bits)
+}
+
+// Remove removes pfx from the set and reports whether it was in it.
+func (s *PrefixSet) Remove(pfx netip.Prefix) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return s.trie.remove4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return s.trie.remove6(&a16, bits)
+}
+
+// Has reports whether exactly pfx is in the set.
+func (s *PrefixSet) Has(pfx netip.Prefix) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return s.trie.has4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return s.trie.has6(&a16, bits)
+}
+
+// Contains reports whether any prefix in the set covers addr. IPv4-mapped
+// addresses are looked up as IPv6.
+func (s *PrefixSet) Contains(addr netip.Addr) bool {
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		return s.trie.contains4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		return s.trie.contains6(&a16)
+	}
+	return false
+}
+
+// All returns an iterator over the prefixes of the set in the CIDR order
+// of Table.AllSorted. The set must not be modified during the iteration.
+func (s *PrefixSet) All() iter.Seq[netip.Prefix] {
+	return func(yield func(netip.Prefix) bool) {
+		s.trie.walkSorted(func(batch []route) bool {
+			for i := range batch {
+				if !yield(batch[i].prefix()) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Union adds all prefixes of o to s in a single call into the trie, o is
+// not modified.
+func (s *PrefixSet) Union(o *PrefixSet) {
+	if o == s {
+		return
+	}
+	s.trie.union(o.trie)
+}
+
+// Intersect returns a new set, configured like s, of the prefixes in
+// both s and o. The common prefixes come from one walk of the smaller
+// set, looked up in the larger one.
+func (s *PrefixSet) Intersect(o *PrefixSet) *PrefixSet {
+	x := &PrefixSet{trie: newSetTrie(s.opts), opts: s.opts}
+	x.trie.insertBulk(s.common(o))
+	return x
+}
+
+// Subtract removes from s every prefix that is also in o and returns how
+// many were removed, o is not modified.
+func (s *PrefixSet) Subtract(o *PrefixSet) int {
+	return s.trie.deleteBulk(s.common(o))
+}
+
+// common returns the prefixes in both s and o.
+func (s *PrefixSet) common(o *PrefixSet) []route {
+	return fill(min(s.Size(), o.Size()), func(out []route) int { return s.trie.common(o.trie, out) })
+}
+
+// Stats walks the trie of the set and reports its statistics like
+// Table.Stats. The bytes of a set are those of its nodes alone, the
+// prefixes take no value slots.
+func (s *PrefixSet) Stats() Stats {
+	v4, v6 := s.trie.stats()
+	return Stats{IPv4: v4, IPv6: v6}
+}
+
+// Set snapshot format, as the table snapshot of MarshalBinary without
+// the payloads:
+//
+//	magic    "ZSET"
+//	version  uint16
+//	count    uvarint, number of prefixes
+//	prefixes count times: family (4 or 6), bits, the (bits+7)/8 leading
+//	         address octets
+//	checksum uint32, CRC-32C of everything before it
+const (
+	setMagic   = "ZSET"
+	setVersion = 1
+)
+
+// MarshalBinary encodes the prefixes of the set for UnmarshalBinary, in
+// CIDR order.
+func (s *PrefixSet) MarshalBinary() ([]byte, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	b := make([]byte, 0, 16+s.Size()*6)
+	b = append(b, setMagic...)
+	b = binary.BigEndian.AppendUint16(b, setVersion)
+	b = binary.AppendUvarint(b, uint64(s.Size()))
+	s.trie.walkSorted(func(batch []route) bool {
+		for i := range batch {
+			b = appendRoute(b, &batch[i])
+		}
+		return true
+	})
+	return binary.BigEndian.AppendUint32(b, crc32.Checksum(b, castagnoli)), nil
+}
+
+// UnmarshalBinary replaces the contents of the set with a snapshot
+// written by MarshalBinary. It can be called on a zero PrefixSet, which
+// then needs to be closed like one returned by NewPrefixSet. The snapshot
+// is verified before the set is touched.
+func (s *PrefixSet) UnmarshalBinary(data []byte) error {
+	if s.closed {
+		return ErrClosed
+	}
+	n := len(data) - 4
+	if n < len(setMagic)+2 || string(data[:len(setMagic)]) != setMagic {
+		return errors.New("zart: not a prefix set snapshot")
+	}
+	if binary.BigEndian.Uint32(data[n:]) != crc32.Checksum(data[:n], castagnoli) {
+		return errors.New("zart: snapshot checksum mismatch")
+	}
+	b := data[len(setMagic):n]
+	if v := binary.BigEndian.Uint16(b); v != setVersion {
+		return fmt.Errorf("zart: unsupported prefix set version %d", v)
+	}
+	b = b[2:]
+	count, k := binary.Uvarint(b)
+	if k <= 0 || count > uint64(len(b)) {
+		return errCorrupt
+	}
+	b = b[k:]
+
+	routes := make([]route, count)
+	for i := range routes {
+		var err error
+		if b, err = decodeRoute(b, &routes[i]); err != nil {
+			return err
+		}
+	}
+	if len(b) != 0 {
+		return errCorrupt
+	}
+
+	if s.trie != nil {
+		s.trie.close()
+	}
+	s.trie = newSetTrie(s.opts)
+	s.trie.insertBulk(routes)
+	return nil
+}
@@ -0,0 +1,140 @@
+package zart
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestPrefixSet(t *testing.T) {
+	s := NewPrefixSet()
+	defer s.Close()
+	if !s.Add(mpp("10.1.2.3/8")) || s.Add(mpp("10.0.0.0/8")) {
+		t.Errorf("Add reports the wrong prefixes as new")
+	}
+	s.Add(mpp("2001:db8::/32"))
+	s.Add(netip.Prefix{})
+
+	if !s.Has(mpp("10.0.0.0/8")) || s.Has(mpp("10.0.0.0/9")) {
+		t.Errorf("Has matched by more than the exact prefix")
+	}
+	if !s.Contains(mpa("10.9.9.9")) || !s.Contains(mpa("2001:db8::1")) || s.Contains(mpa("11.0.0.1")) {
+		t.Errorf("Contains disagrees with the prefixes")
+	}
+	want := []netip.Prefix{mpp("10.0.0.0/8"), mpp("2001:db8::/32")}
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) || s.Size() != 2 {
+		t.Errorf("All = %v, want %v", got, want)
+	}
+	if !s.Remove(mpp("10.0.0.0/8")) || s.Remove(mpp("10.0.0.0/8")) || s.Size() != 1 {
+		t.Errorf("Remove reports the wrong prefixes as present")
+	}
+}
+
+func TestPrefixSetAlgebra(t *testing.T) {
+	prng := rand.New(rand.NewPCG(116, 116))
+	pfxs := randomPrefixes(prng, 4000)
+
+	a, b := NewPrefixSet(), NewPrefixSet()
+	defer a.Close()
+	defer b.Close()
+	inA, inB := map[netip.Prefix]bool{}, map[netip.Prefix]bool{}
+	for _, pfx := range pfxs[:3000] {
+		a.Add(pfx)
+		inA[pfx.Masked()] = true
+	}
+	for _, pfx := range pfxs[2000:] {
+		b.Add(pfx)
+		inB[pfx.Masked()] = true
+	}
+
+	collect := func(s *PrefixSet) map[netip.Prefix]bool {
+		m := map[netip.Prefix]bool{}
+		for pfx := range s.All() {
+			m[pfx] = true
+		}
+		return m
+	}
+	both, union, diff := map[netip.Prefix]bool{}, maps.Clone(inA), map[netip.Prefix]bool{}
+	for pfx := range inA {
+		if inB[pfx] {
+			both[pfx] = true
+		} else {
+			diff[pfx] = true
+		}
+	}
+	maps.Copy(union, inB)
+
+	x := a.Intersect(b)
+	defer x.Close()
+	if got := collect(x); !maps.Equal(got, both) {
+		t.Errorf("Intersect has %d prefixes, want %d", len(got), len(both))
+	}
+	u := a.Clone()
+	defer u.Close()
+	u.Union(b)
+	if got := collect(u); !maps.Equal(got, union) {
+		t.Errorf("Union has %d prefixes, want %d", len(got), len(union))
+	}
+	if n := a.Subtract(b); n != len(both) {
+		t.Errorf("Subtract removed %d prefixes, want %d", n, len(both))
+	}
+	if got := collect(a); !maps.Equal(got, diff) {
+		t.Errorf("Subtract left %d prefixes, want %d", len(got), len(diff))
+	}
+	if got := collect(b); !maps.Equal(got, inB) {
+		t.Errorf("set operations changed their argument")
+	}
+}
+
+func TestPrefixSetMarshal(t *testing.T) {
+	prng := rand.New(rand.NewPCG(1160, 1160))
+	s := NewPrefixSet()
+	defer s.Close()
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		s.Add(pfx)
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c PrefixSet
+	defer c.Close()
+	if err := c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := slices.Collect(c.All()), slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Errorf("round trip has %d prefixes, want %d", len(got), len(want))
+	}
+
+	data[len(data)/2] ^= 1
+	if err := c.UnmarshalBinary(data); err == nil {
+		t.Errorf("UnmarshalBinary accepted a corrupt snapshot")
+	}
+	if c.Size() != s.Size() {
+		t.Errorf("failed UnmarshalBinary changed the set")
+	}
+}
+
+func TestPrefixSetStats(t *testing.T) {
+	prng := rand.New(rand.NewPCG(1161, 1161))
+	s := NewPrefixSet(WithPathCompression())
+	defer s.Close()
+	tbl := New[struct{}](WithPathCompression())
+	defer tbl.Close()
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		s.Add(pfx)
+		tbl.Insert(pfx, struct{}{})
+	}
+
+	// the same trie, without the value slots
+	ss, ts := s.Stats(), tbl.Stats()
+	if ss.Nodes() != ts.Nodes() || ss.IPv4.Prefixes+ss.IPv6.Prefixes != s.Size() {
+		t.Errorf("set has %d nodes for %d prefixes, table %d nodes", ss.Nodes(), s.Size(), ts.Nodes())
+	}
+	if ss.Bytes() >= ts.Bytes() {
+		t.Errorf("set takes %d bytes, table %d", ss.Bytes(), ts.Bytes())
+	}
+}
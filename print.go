@@ -0,0 +1,53 @@
+package zart
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Fprint writes the table to w in the style of a router's route listing:
+// one prefix per line with its value formatted with %v, sorted in CIDR
+// order, IPv4 before IPv6, by address and shorter prefixes first. Every
+// prefix is indented two spaces further than the closest prefix covering
+// it, the values are aligned in a column.
+//
+// The output only depends on the contents of the table, not on the order
+// of inserts, and is suitable for comparing snapshots in tests.
+func (t *Table[V]) Fprint(w io.Writer) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	routes := t.routes()
+	slices.SortFunc(routes, func(a, b route) int { return comparePrefix(a.prefix(), b.prefix()) })
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	var parents []netip.Prefix
+	for i := range routes {
+		pfx := routes[i].prefix()
+		for len(parents) > 0 && !covers(parents[len(parents)-1], pfx) {
+			parents = parents[:len(parents)-1]
+		}
+		fmt.Fprintf(tw, "%s%s\t%v\n", strings.Repeat("  ", len(parents)), pfx, t.vals.get(routes[i].val))
+		parents = append(parents, pfx)
+	}
+	return tw.Flush()
+}
+
+// comparePrefix orders prefixes in CIDR order: IPv4 before IPv6, by
+// address and shorter prefixes first.
+func comparePrefix(p, q netip.Prefix) int {
+	if c := p.Addr().Compare(q.Addr()); c != 0 {
+		return c
+	}
+	return cmp.Compare(p.Bits(), q.Bits())
+}
+
+// covers reports whether p covers q, q being more specific.
+func covers(p, q netip.Prefix) bool {
+	return p.Bits() < q.Bits() && p.Contains(q.Addr())
+}
@@ -0,0 +1,118 @@
+package zart
+
+import (
+	"encoding/binary"
+	"iter"
+	"net/netip"
+)
+
+// maxSupernets is the largest number of prefixes that can cover a single
+// prefix, /0 to /128.
+const maxSupernets = 129
+
+// Supernets returns an iterator over all prefixes in the table covering
+// pfx, including pfx itself, ordered from the most to the least specific
+// prefix. The first prefix yielded for a host route is the longest-prefix
+// match of the address.
+//
+// The covering routes are collected in a single call when the iteration
+// starts, the table must not be modified until it is finished.
+func (t *Table[V]) Supernets(pfx netip.Prefix) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		if !pfx.IsValid() {
+			return
+		}
+		q := makeRoute(pfx.Masked(), 0)
+
+		var buf [maxSupernets]route
+		n := t.trie.supernets(&q, buf[:])
+		for i := range buf[:n] {
+			if !yield(buf[i].prefix(), t.vals.get(buf[i].val)) {
+				return
+			}
+		}
+	}
+}
+
+// LookupAll returns an iterator over all prefixes in the table covering
+// addr, from the most to the least specific one, for combining the
+// attributes of nested scopes. The first prefix yielded is the
+// longest-prefix match of addr. LookupAll is Supernets of the host route
+// of addr.
+func (t *Table[V]) LookupAll(addr netip.Addr) iter.Seq2[netip.Prefix, V] {
+	addr = t.key(addr)
+	return t.Supernets(netip.PrefixFrom(addr, addr.BitLen()))
+}
+
+// LookupShortest is LookupPrefix for the least specific prefix covering
+// addr instead of the most specific one, the allocation addr belongs to
+// in a table of allocations and their assignments. It is the last prefix
+// of LookupAll, found by walking down the trie without collecting the
+// others.
+func (t *Table[V]) LookupShortest(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	addr = t.key(addr)
+	var slot uint32
+	var bits uint8
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		slot, bits, ok = t.trie.lookupShortest4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		slot, bits, ok = t.trie.lookupShortest6(&a16)
+	}
+	if !ok {
+		return pfx, val, false
+	}
+	pfx, _ = addr.Prefix(int(bits))
+	return pfx, t.payload(slot), true
+}
+
+// Subnets returns an iterator over all prefixes in the table covered by
+// pfx, including pfx itself, in the order of All. Subnets of 0.0.0.0/0
+// are all IPv4 prefixes in the table.
+//
+// As with All, the routes are collected when the iteration starts and the
+// table must not be modified until it is finished.
+func (t *Table[V]) Subnets(pfx netip.Prefix) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		if !pfx.IsValid() {
+			return
+		}
+		q := makeRoute(pfx.Masked(), 0)
+
+		routes := fill(16, func(out []route) int { return t.trie.subnets(&q, out) })
+		for i := range routes {
+			if !yield(routes[i].prefix(), t.vals.get(routes[i].val)) {
+				return
+			}
+		}
+	}
+}
+
+// CountSubnets returns the number of prefixes in the table covered by
+// pfx, including pfx itself, the length of Subnets. They are counted by a
+// walk of the subtree in C, which hands no routes to Go.
+func (t *Table[V]) CountSubnets(pfx netip.Prefix) int {
+	if !pfx.IsValid() {
+		return 0
+	}
+	q := makeRoute(pfx.Masked(), 0)
+	return t.trie.subnets(&q, nil)
+}
+
+// OverlapsPrefix reports whether any prefix in the table overlaps pfx,
+// that is covers pfx or is covered by it.
+func (t *Table[V]) OverlapsPrefix(pfx netip.Prefix) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	q := makeRoute(pfx.Masked(), 0)
+	return t.trie.overlapsPrefix(&q)
+}
+
+// Overlaps reports whether any prefix in t overlaps any prefix in o.
+// Firewall managers use it to detect conflicting rule sets up front.
+func (t *Table[V]) Overlaps(o *Table[V]) bool {
+	return t.trie.overlaps(o.trie)
+}
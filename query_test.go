@@ -0,0 +1,239 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestSupernets(t *testing.T) {
+	prng := rand.New(rand.NewPCG(10, 10))
+
+	tbl := New[int]()
+	defer tbl.Close()
+
+	stored := map[netip.Prefix]int{}
+	for i, pfx := range append(randomPrefixes(prng, 2000), mpp("0.0.0.0/0"), mpp("::/0")) {
+		tbl.Insert(pfx, i)
+		stored[pfx] = i
+	}
+
+	for _, query := range randomPrefixes(prng, 1000) {
+		var want []netip.Prefix
+		for bits := query.Bits(); bits >= 0; bits-- {
+			cover, _ := query.Addr().Prefix(bits)
+			if _, ok := stored[cover]; ok {
+				want = append(want, cover)
+			}
+		}
+
+		var got []netip.Prefix
+		for pfx, val := range tbl.Supernets(query) {
+			if val != stored[pfx] {
+				t.Fatalf("Supernets(%s): %s = %d, want %d", query, pfx, val, stored[pfx])
+			}
+			got = append(got, pfx)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("Supernets(%s) = %v, want %v", query, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("Supernets(%s) = %v, want %v", query, got, want)
+			}
+		}
+	}
+}
+
+func TestSupernetsLPMFirst(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"} {
+		tbl.Insert(mpp(pfx), i)
+	}
+
+	for pfx := range tbl.Supernets(mpp("10.1.2.3/32")) {
+		if want := mpp("10.1.2.0/24"); pfx != want {
+			t.Errorf("first supernet of 10.1.2.3/32 = %s, want %s", pfx, want)
+		}
+		break
+	}
+}
+
+func TestLookupAll(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	for _, pfx := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.2.3/32", "10.2.0.0/16", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), pfx)
+	}
+
+	tests := []struct {
+		addr string
+		want []string
+	}{
+		{"10.1.2.3", []string{"10.1.2.3/32", "10.1.2.0/24", "10.1.0.0/16", "10.0.0.0/8"}},
+		{"10.1.9.9", []string{"10.1.0.0/16", "10.0.0.0/8"}},
+		{"2001:db8::1", []string{"2001:db8::/32"}},
+		{"192.0.2.1", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for pfx, val := range tbl.LookupAll(mpa(tt.addr)) {
+			if val != pfx.String() {
+				t.Errorf("LookupAll(%s) yielded %s with value %s", tt.addr, pfx, val)
+			}
+			got = append(got, val)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LookupAll(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	for range tbl.LookupAll(netip.Addr{}) {
+		t.Errorf("LookupAll of the zero Addr yielded a prefix")
+	}
+}
+
+func TestLookupShortest(t *testing.T) {
+	prng := rand.New(rand.NewPCG(93, 93))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, i)
+	}
+	for _, pfx := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.2.3/32", "2001:db8::/32", "2001:db8::1/128"} {
+		tbl.Insert(mpp(pfx), -1)
+	}
+
+	addrs := []netip.Addr{mpa("10.1.2.3"), mpa("10.9.9.9"), mpa("2001:db8::1"), mpa("8.8.8.8")}
+	for range 1000 {
+		pfx := randomPrefixes(prng, 1)[0]
+		addrs = append(addrs, pfx.Addr())
+	}
+	for _, addr := range addrs {
+		var want netip.Prefix
+		var wantVal int
+		for pfx, val := range tbl.LookupAll(addr) {
+			want, wantVal = pfx, val
+		}
+		pfx, val, ok := tbl.LookupShortest(addr)
+		if ok != want.IsValid() || pfx != want || val != wantVal {
+			t.Fatalf("LookupShortest(%s) = %s, %d, %v, want %s, %d", addr, pfx, val, ok, want, wantVal)
+		}
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	prng := rand.New(rand.NewPCG(11, 11))
+
+	tbl := New[int]()
+	defer tbl.Close()
+
+	stored := map[netip.Prefix]int{}
+	for i, pfx := range append(randomPrefixes(prng, 2000), mpp("0.0.0.0/0"), mpp("10.1.2.3/32")) {
+		tbl.Insert(pfx, i)
+		stored[pfx] = i
+	}
+
+	queries := append(randomPrefixes(prng, 300), mpp("0.0.0.0/0"), mpp("::/0"), mpp("10.0.0.0/8"), mpp("10.1.2.3/32"))
+	for _, query := range queries {
+		want := 0
+		for pfx := range stored {
+			if query.Bits() <= pfx.Bits() && query.Contains(pfx.Addr()) {
+				want++
+			}
+		}
+
+		got := 0
+		for pfx, val := range tbl.Subnets(query) {
+			if !query.Contains(pfx.Addr()) || pfx.Bits() < query.Bits() {
+				t.Fatalf("Subnets(%s) yielded %s", query, pfx)
+			}
+			if stored[pfx] != val {
+				t.Fatalf("Subnets(%s): %s = %d, want %d", query, pfx, val, stored[pfx])
+			}
+			got++
+		}
+		if got != want {
+			t.Fatalf("Subnets(%s) yielded %d prefixes, want %d", query, got, want)
+		}
+	}
+}
+
+func TestCountSubnets(t *testing.T) {
+	prng := rand.New(rand.NewPCG(94, 94))
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 3000) {
+		tbl.Insert(pfx, i)
+	}
+	queries := []netip.Prefix{mpp("0.0.0.0/0"), mpp("::/0"), mpp("10.0.0.0/8"), mpp("10.1.2.3/32"), {}}
+	queries = append(queries, randomPrefixes(prng, 200)...)
+	for _, q := range queries {
+		want := 0
+		for range tbl.Subnets(q) {
+			want++
+		}
+		if got := tbl.CountSubnets(q); got != want {
+			t.Errorf("CountSubnets(%s) = %d, want %d", q, got, want)
+		}
+	}
+	if got := tbl.CountSubnets(mpp("0.0.0.0/0")) + tbl.CountSubnets(mpp("::/0")); got != tbl.Size() {
+		t.Errorf("CountSubnets of both /0 = %d, want Size %d", got, tbl.Size())
+	}
+}
+
+func TestOverlapsPrefix(t *testing.T) {
+	prng := rand.New(rand.NewPCG(12, 12))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	stored := randomPrefixes(prng, 200)
+	for i, pfx := range stored {
+		tbl.Insert(pfx, i)
+	}
+
+	for _, query := range randomPrefixes(prng, 2000) {
+		want := false
+		for _, pfx := range stored {
+			if pfx.Overlaps(query) {
+				want = true
+				break
+			}
+		}
+		if got := tbl.OverlapsPrefix(query); got != want {
+			t.Fatalf("OverlapsPrefix(%s) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	prng := rand.New(rand.NewPCG(13, 13))
+
+	for i := 0; i < 200; i++ {
+		a, b := New[int](), New[int]()
+		pa, pb := randomPrefixes(prng, 1+prng.IntN(10)), randomPrefixes(prng, 1+prng.IntN(10))
+		for _, pfx := range pa {
+			a.Insert(pfx, 0)
+		}
+		for _, pfx := range pb {
+			b.Insert(pfx, 0)
+		}
+
+		want := false
+		for _, x := range pa {
+			for _, y := range pb {
+				want = want || x.Overlaps(y)
+			}
+		}
+		if got := a.Overlaps(b); got != want {
+			t.Fatalf("%v.Overlaps(%v) = %v, want %v", pa, pb, got, want)
+		}
+		if got := b.Overlaps(a); got != want {
+			t.Fatalf("%v.Overlaps(%v) = %v, want %v", pb, pa, got, want)
+		}
+		a.Close()
+		b.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package zart
+
+import (
+	"encoding/binary"
+	"iter"
+	"net/netip"
+)
+
+// route64 is route with a 64-bit value, laid out like bart_route64_t.
+type route64 struct {
+	addr [16]byte
+	bits uint8
+	is4  uint8
+	_    [6]byte
+	val  uint64
+}
+
+func (r *route64) prefix() netip.Prefix {
+	rt := route{addr: r.addr, bits: r.bits, is4: r.is4}
+	return rt.prefix()
+}
+
+// RawTable is a routing table of uint64 values stored in the trie
+// itself, next-hop ids, indexes into the caller's own arrays or other
+// handles. Unlike Table it keeps no payloads in Go: a lookup returns the
+// value the trie holds, without a second indirection, and the values are
+// exactly those of the C API.
+//
+// A RawTable must be created with NewRaw and released with Close, like
+// a Table. It is not safe for concurrent use.
+type RawTable struct {
+	trie   *trie
+	closed bool
+}
+
+// NewRaw returns an empty RawTable. Of the options only WithArena and
+// WithPathCompression apply.
+func NewRaw(opts ...Option) *RawTable {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &RawTable{trie: newTrie(o)}
+}
+
+// Close releases the trie. Closing a table again does nothing.
+func (t *RawTable) Close() {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	t.trie.close()
+}
+
+// Size returns the number of prefixes in the table.
+func (t *RawTable) Size() int {
+	return t.trie.size4() + t.trie.size6()
+}
+
+// Insert adds pfx with value val and returns the value it replaced, if
+// pfx was in the table. Host bits are masked off, invalid prefixes are
+// ignored.
+func (t *RawTable) Insert(pfx netip.Prefix, val uint64) (old uint64, replaced bool) {
+	if !pfx.IsValid() {
+		return 0, false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return t.trie.insertWide4(binary.BigEndian.Uint32(a4[:]), bits, val)
+	}
+	a16 := addr.As16()
+	return t.trie.insertWide6(&a16, bits, val)
+}
+
+// Delete removes pfx and returns its value, ok is false if it was not in
+// the table.
+func (t *RawTable) Delete(pfx netip.Prefix) (old uint64, ok bool) {
+	if !pfx.IsValid() {
+		return 0, false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return t.trie.deleteWide4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return t.trie.deleteWide6(&a16, bits)
+}
+
+// Get returns the value stored for exactly pfx.
+func (t *RawTable) Get(pfx netip.Prefix) (val uint64, ok bool) {
+	if !pfx.IsValid() {
+		return 0, false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return t.trie.getWide4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return t.trie.getWide6(&a16, bits)
+}
+
+// Lookup performs a longest-prefix match for addr. IPv4-mapped addresses
+// are looked up as IPv6.
+func (t *RawTable) Lookup(addr netip.Addr) (val uint64, ok bool) {
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		return t.trie.lookupWide4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		return t.trie.lookupWide6(&a16)
+	}
+	return 0, false
+}
+
+// Lookup4Raw is Lookup for an IPv4 address given as an integer, see
+// Table.Lookup4Raw.
+func (t *RawTable) Lookup4Raw(addr uint32) (val uint64, ok bool) {
+	return t.trie.lookupWide4(addr)
+}
+
+// Lookup6Raw is Lookup for an IPv6 address given as 16 bytes in network
+// order, see Table.Lookup6Raw.
+func (t *RawTable) Lookup6Raw(addr *[16]byte) (val uint64, ok bool) {
+	return t.trie.lookupWide6(addr)
+}
+
+// Lookup6HiLo is Lookup for an IPv6 address given as its two halves, see
+// Table.Lookup6HiLo.
+func (t *RawTable) Lookup6HiLo(hi, lo uint64) (val uint64, ok bool) {
+	return t.trie.lookupWide6HiLo(hi, lo)
+}
+
+// Insert6HiLo is Insert for the IPv6 prefix with the address halves hi,
+// lo and bits, the address taking no detour through memory. Lengths
+// outside 0 to 128 are ignored.
+func (t *RawTable) Insert6HiLo(hi, lo uint64, bits int, val uint64) (old uint64, replaced bool) {
+	if bits < 0 || bits > 128 {
+		return 0, false
+	}
+	return t.trie.insertWide6HiLo(hi, lo, uint8(bits), val)
+}
+
+// All returns an iterator over all prefixes with their values, in the
+// order of Table.All. The table must not be modified during the
+// iteration.
+func (t *RawTable) All() iter.Seq2[netip.Prefix, uint64] {
+	return func(yield func(netip.Prefix, uint64) bool) {
+		for _, r := range fill(t.Size(), t.trie.dumpWide) {
+			if !yield(r.prefix(), r.val) {
+				return
+			}
+		}
+	}
+}
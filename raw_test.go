@@ -0,0 +1,80 @@
+package zart
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestRawTable(t *testing.T) {
+	tbl := NewRaw()
+	defer tbl.Close()
+
+	const big = 0xfedc_ba98_7654_3210
+	if _, replaced := tbl.Insert(mpp("10.0.0.0/8"), big); replaced {
+		t.Errorf("first Insert replaced a value")
+	}
+	if old, replaced := tbl.Insert(mpp("10.1.2.3/8"), big+1); !replaced || old != big {
+		t.Errorf("Insert = %#x, %v, want %#x, true", old, replaced, uint64(big))
+	}
+	tbl.Insert(mpp("2001:db8::/32"), 1<<63)
+	tbl.Insert(netip.Prefix{}, 1)
+
+	if v, ok := tbl.Lookup(mpa("10.9.9.9")); !ok || v != big+1 {
+		t.Errorf("Lookup = %#x, %v", v, ok)
+	}
+	if v, ok := tbl.Lookup4Raw(0x0a090909); !ok || v != big+1 {
+		t.Errorf("Lookup4Raw = %#x, %v", v, ok)
+	}
+	a16 := mpa("2001:db8::1").As16()
+	if v, ok := tbl.Lookup6Raw(&a16); !ok || v != 1<<63 {
+		t.Errorf("Lookup6Raw = %#x, %v", v, ok)
+	}
+	if v, ok := tbl.Get(mpp("2001:db8::/32")); !ok || v != 1<<63 {
+		t.Errorf("Get = %#x, %v", v, ok)
+	}
+	if v, ok := tbl.Lookup6HiLo(0x2001_0db8_0000_0000, 1); !ok || v != 1<<63 {
+		t.Errorf("Lookup6HiLo = %#x, %v", v, ok)
+	}
+	if old, replaced := tbl.Insert6HiLo(0x2001_0db8_0000_0000, 0, 32, 1<<63); !replaced || old != 1<<63 {
+		t.Errorf("Insert6HiLo = %#x, %v, want %#x, true", old, replaced, uint64(1<<63))
+	}
+	if _, replaced := tbl.Insert6HiLo(0, 0, 129, 1); replaced || tbl.Size() != 2 {
+		t.Errorf("Insert6HiLo of /129 changed the table")
+	}
+	want := map[netip.Prefix]uint64{mpp("10.0.0.0/8"): big + 1, mpp("2001:db8::/32"): 1 << 63}
+	if got := maps.Collect(tbl.All()); !maps.Equal(got, want) {
+		t.Errorf("All = %v, want %v", got, want)
+	}
+
+	if old, ok := tbl.Delete(mpp("10.0.0.0/8")); !ok || old != big+1 {
+		t.Errorf("Delete = %#x, %v", old, ok)
+	}
+	if _, ok := tbl.Delete(mpp("10.0.0.0/8")); ok {
+		t.Errorf("second Delete found the prefix")
+	}
+	if tbl.Size() != 1 {
+		t.Errorf("Size = %d, want 1", tbl.Size())
+	}
+}
+
+func TestRawTableMatchesTable(t *testing.T) {
+	prng := rand.New(rand.NewPCG(80, 80))
+	raw := NewRaw(WithArena(0))
+	defer raw.Close()
+	tbl := New[uint64]()
+	defer tbl.Close()
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		v := prng.Uint64()
+		raw.Insert(pfx, v)
+		tbl.Insert(pfx, v)
+	}
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		got, gotOK := raw.Lookup(pfx.Addr())
+		want, wantOK := tbl.Lookup(pfx.Addr())
+		if got != want || gotOK != wantOK {
+			t.Fatalf("Lookup(%s) = %#x, %v, want %#x, %v", pfx.Addr(), got, gotOK, want, wantOK)
+		}
+	}
+}
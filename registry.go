@@ -0,0 +1,162 @@
+package zart
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// registry maps the uint32 values stored in the C trie to Go payloads.
+//
+// The trie only ever sees slot numbers, the payloads stay on the Go heap
+// where the garbage collector can reach them. Slots of overwritten or
+// deleted prefixes are zeroed and recycled through a free list.
+//
+// Persistent versions of a table share one registry, shared counts the
+// versions beyond the first. A shared registry never recycles slots since
+// another version may still refer to them.
+//
+// With hit counting enabled hits has a lookup counter per slot. Lookups
+// may run concurrently under a read lock, the counters are only accessed
+// atomically.
+type registry[V any] struct {
+	vals   []V
+	free   []uint32
+	shared int
+	hits   []uint64
+}
+
+// alloc stores v in a free slot and returns the slot number.
+func (r *registry[V]) alloc(v V) uint32 {
+	if n := len(r.free); n > 0 {
+		slot := r.free[n-1]
+		r.free = r.free[:n-1]
+		r.vals[slot] = v
+		return slot
+	}
+	r.vals = append(r.vals, v)
+	if r.hits != nil {
+		r.hits = append(r.hits, 0)
+	}
+	return uint32(len(r.vals) - 1)
+}
+
+// get returns the payload in slot.
+func (r *registry[V]) get(slot uint32) V {
+	return r.vals[slot]
+}
+
+// set replaces the payload in slot.
+func (r *registry[V]) set(slot uint32, v V) {
+	r.vals[slot] = v
+}
+
+// absorb appends all slots of o, used and free, to r and returns the slot
+// number of o's first slot in r. Slot s of o is slot base+s of r.
+func (r *registry[V]) absorb(o *registry[V]) (base uint32) {
+	base = uint32(len(r.vals))
+	r.vals = append(r.vals, o.vals...)
+	for _, slot := range o.free {
+		r.free = append(r.free, base+slot)
+	}
+	if r.hits != nil {
+		r.hits = append(r.hits, make([]uint64, len(o.vals))...)
+		copy(r.hits[base:], o.loadHits())
+	}
+	return base
+}
+
+// clone returns a copy of r with the same slot numbers. Payloads that
+// implement Cloner[V] are deep copied, all others by assignment.
+func (r *registry[V]) clone() *registry[V] {
+	c := &registry[V]{vals: slices.Clone(r.vals), free: slices.Clone(r.free), hits: r.loadHits()}
+
+	// free slots hold the zero value and are not cloned
+	free := make([]bool, len(r.vals))
+	for _, slot := range r.free {
+		free[slot] = true
+	}
+	for i, v := range r.vals {
+		if cl, ok := any(v).(Cloner[V]); ok && !free[i] {
+			c.vals[i] = cl.Clone()
+		}
+	}
+	return c
+}
+
+// release zeroes slot, dropping the reference to its payload, and makes
+// it available for reuse. It is a no-op for a shared registry.
+func (r *registry[V]) release(slot uint32) {
+	if r.shared > 0 {
+		return
+	}
+	var zero V
+	r.vals[slot] = zero
+	r.free = append(r.free, slot)
+	if r.hits != nil {
+		atomic.StoreUint64(&r.hits[slot], 0)
+	}
+}
+
+// len returns the number of slots in use.
+func (r *registry[V]) len() int {
+	return len(r.vals) - len(r.free)
+}
+
+// unshare returns a registry for exclusive use by one version, r itself
+// if it is not shared.
+func (r *registry[V]) unshare() *registry[V] {
+	if r.shared == 0 {
+		return r
+	}
+	r.shared--
+	return &registry[V]{vals: slices.Clone(r.vals), free: slices.Clone(r.free), hits: r.loadHits()}
+}
+
+// reset drops all payloads at once. A shared registry only loses one
+// version.
+func (r *registry[V]) reset() {
+	if r.shared > 0 {
+		r.shared--
+		return
+	}
+	r.vals = nil
+	r.free = nil
+	if r.hits != nil {
+		r.hits = []uint64{}
+	}
+}
+
+// countHits enables hit counting, the counts start at zero.
+func (r *registry[V]) countHits() {
+	if r.hits == nil {
+		r.hits = make([]uint64, len(r.vals))
+	}
+}
+
+// hit counts a lookup that matched the prefix in slot.
+func (r *registry[V]) hit(slot uint32) {
+	atomic.AddUint64(&r.hits[slot], 1)
+}
+
+// moveHits carries the count of slot from over to slot to, for a value
+// replaced by another one. Releasing from resets its count.
+func (r *registry[V]) moveHits(from, to uint32) {
+	atomic.StoreUint64(&r.hits[to], atomic.LoadUint64(&r.hits[from]))
+}
+
+// hitCount returns the number of lookups that matched slot.
+func (r *registry[V]) hitCount(slot uint32) uint64 {
+	return atomic.LoadUint64(&r.hits[slot])
+}
+
+// loadHits returns a copy of the counters, nil if counting is off.
+func (r *registry[V]) loadHits() []uint64 {
+	if r.hits == nil {
+		return nil
+	}
+	c := make([]uint64, len(r.hits))
+	for i := range r.hits {
+		c[i] = atomic.LoadUint64(&r.hits[i])
+	}
+	return c
+}
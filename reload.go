@@ -0,0 +1,108 @@
+package zart
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// ReloadConfig tunes AutoReload, the zero value takes the defaults.
+type ReloadConfig[V any] struct {
+	// Interval is how often the file is checked for a change of its
+	// modification time or size, 1 second.
+	Interval time.Duration
+
+	// Options configure the tables built from the file, as for New.
+	Options []Option
+
+	// Validate, if not nil, checks a table built from the file before it
+	// is published; an error keeps the published table.
+	Validate func(t *Table[V]) error
+
+	// Report, if not nil, is called after every load attempt.
+	Report func(ReloadStats)
+}
+
+// ReloadStats describes one load of the file by AutoReload. Err is nil
+// if the new table was published.
+type ReloadStats struct {
+	Path     string
+	Routes   int
+	Duration time.Duration // to read, build and validate the table
+	Err      error
+}
+
+// AutoReload keeps the table published by a in sync with the routes
+// file at path, in format, until ctx is done. It loads the file once at
+// the start and again whenever its modification time or size changes,
+// building a new table in the calling goroutine; run it with go. A table
+// that loads and passes Validate is published with Swap, so readers move
+// over to it as a whole without waiting, one that fails is closed and the
+// published table kept until the next change of the file.
+func (a *AtomicTable[V]) AutoReload(ctx context.Context, path string, format Format[V], cfg ReloadConfig[V]) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	report := func(stats ReloadStats) {
+		if cfg.Report != nil {
+			cfg.Report(stats)
+		}
+	}
+	var last os.FileInfo
+	missing := false
+	check := func() {
+		fi, err := os.Stat(path)
+		switch {
+		case err != nil:
+			// a missing file is reported once, not on every tick
+			if !missing {
+				missing = true
+				report(ReloadStats{Path: path, Err: err})
+			}
+			return
+		case last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size():
+			return
+		}
+		missing, last = false, fi
+		report(a.reload(path, format, &cfg))
+	}
+
+	check()
+	tick := time.NewTicker(cfg.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			check()
+		}
+	}
+}
+
+// reload builds a table from the file at path and publishes it if it is
+// valid.
+func (a *AtomicTable[V]) reload(path string, format Format[V], cfg *ReloadConfig[V]) ReloadStats {
+	start := time.Now()
+	stats := ReloadStats{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		stats.Err = err
+		return stats
+	}
+	defer f.Close()
+
+	t := New[V](cfg.Options...)
+	stats.Routes, err = t.Load(f, format, nil)
+	if err == nil && cfg.Validate != nil {
+		err = cfg.Validate(t)
+	}
+	stats.Duration = time.Since(start)
+	if err != nil {
+		t.Close()
+		stats.Err = err
+		return stats
+	}
+	a.Swap(t)
+	return stats
+}
@@ -0,0 +1,81 @@
+package zart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAutoReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes")
+	writeRoutes(t, path, "10.0.0.0/8 1\n")
+	a := NewAtomic(New[int]())
+	defer a.Close()
+
+	errEmpty := errors.New("empty")
+	reports := make(chan ReloadStats)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.AutoReload(ctx, path, Lines[int](), ReloadConfig[int]{
+			Interval: 5 * time.Millisecond,
+			Validate: func(t *Table[int]) error {
+				if t.Size() == 0 {
+					return errEmpty
+				}
+				return nil
+			},
+			Report: func(s ReloadStats) { reports <- s },
+		})
+	}()
+	defer func() {
+		cancel()
+		for {
+			select {
+			case <-reports:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if s := <-reports; s.Err != nil || s.Routes != 1 {
+		t.Fatalf("first load = %+v", s)
+	}
+	if v, ok := a.Lookup(mpa("10.1.1.1")); !ok || v != 1 {
+		t.Errorf("Lookup after the first load = %d, %v", v, ok)
+	}
+
+	writeRoutes(t, path, "10.0.0.0/8 2\n192.168.0.0/16 3\n")
+	if s := <-reports; s.Err != nil || s.Routes != 2 {
+		t.Fatalf("reload = %+v", s)
+	}
+	if v, ok := a.Lookup(mpa("192.168.1.1")); !ok || v != 3 {
+		t.Errorf("Lookup after the reload = %d, %v", v, ok)
+	}
+
+	// a table failing validation is not published
+	writeRoutes(t, path, "")
+	if s := <-reports; !errors.Is(s.Err, errEmpty) {
+		t.Fatalf("reload of an empty file = %+v", s)
+	}
+	if v, ok := a.Lookup(mpa("10.1.1.1")); !ok || v != 2 {
+		t.Errorf("Lookup after a rejected reload = %d, %v", v, ok)
+	}
+}
+
+// writeRoutes replaces the file at path by renaming, so that AutoReload
+// never sees it half written.
+func writeRoutes(t *testing.T, path, routes string) {
+	t.Helper()
+	if err := os.WriteFile(path+".tmp", []byte(routes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
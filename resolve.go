@@ -0,0 +1,58 @@
+package zart
+
+import "net/netip"
+
+// Resolvable is implemented by payloads whose next hop can be an address
+// to be looked up again, like the BGP next hops learned without an
+// outgoing interface. NextHop implements it.
+type Resolvable interface {
+	// Resolve returns the address to look up for the next hop, ok is
+	// false for a next hop that is directly connected.
+	Resolve() (addr netip.Addr, ok bool)
+}
+
+// Resolve returns the gateway of a next hop without an interface, which
+// must be resolved against the routes to the gateway.
+func (nh NextHop) Resolve() (addr netip.Addr, ok bool) {
+	v := nh.get()
+	return v.gateway, v.ifIndex == 0 && v.gateway.IsValid()
+}
+
+// LookupResolved is Lookup following recursive next hops: as long as the
+// value found implements Resolvable and reports an address to resolve, it
+// looks that address up in turn and returns the first directly connected
+// value. Values that do not implement Resolvable are returned as they
+// are. ok is false if addr or an address on the way has no route, and for
+// chains longer than the depth of WithResolveDepth, which also ends
+// resolution loops.
+func (t *Table[V]) LookupResolved(addr netip.Addr) (val V, ok bool) {
+	depth := t.opts.resolve
+	if depth == 0 {
+		depth = defaultResolveDepth
+	}
+	val, ok = t.Lookup(addr)
+	for ; ok; depth-- {
+		r, isResolvable := any(val).(Resolvable)
+		if !isResolvable {
+			return val, true
+		}
+		next, recursive := r.Resolve()
+		if !recursive {
+			return val, true
+		}
+		if depth == 0 {
+			break
+		}
+		val, ok = t.Lookup(next)
+	}
+	var zero V
+	return zero, false
+}
+
+// LookupResolved is like Table.LookupResolved, all lookups see the same
+// state of the table.
+func (c *ConcurrentTable[V]) LookupResolved(addr netip.Addr) (val V, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.LookupResolved(addr)
+}
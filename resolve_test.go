@@ -0,0 +1,53 @@
+package zart
+
+import "testing"
+
+func TestLookupResolved(t *testing.T) {
+	tbl := New[NextHop]()
+	defer tbl.Close()
+	connected := MakeNextHop(mpa("10.0.0.1"), 2, 0)
+	// a BGP route via a loopback learned over the IGP
+	tbl.Insert(mpp("203.0.113.0/24"), MakeNextHop(mpa("192.0.2.9"), 0, 0))
+	tbl.Insert(mpp("192.0.2.9/32"), MakeNextHop(mpa("10.0.0.1"), 0, 0))
+	tbl.Insert(mpp("10.0.0.0/30"), connected)
+	// looping and unresolvable next hops
+	tbl.Insert(mpp("198.51.100.0/24"), MakeNextHop(mpa("198.51.100.1"), 0, 0))
+	tbl.Insert(mpp("100.64.0.0/10"), MakeNextHop(mpa("172.16.0.1"), 0, 0))
+
+	tests := []struct {
+		addr string
+		want NextHop
+		ok   bool
+	}{
+		{"203.0.113.5", connected, true},
+		{"192.0.2.9", connected, true},
+		{"10.0.0.2", connected, true},
+		{"198.51.100.7", NextHop{}, false},
+		{"100.64.0.1", NextHop{}, false},
+		{"8.8.8.8", NextHop{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := tbl.LookupResolved(mpa(tt.addr)); got != tt.want || ok != tt.ok {
+			t.Errorf("LookupResolved(%s) = %s, %v, want %s, %v", tt.addr, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	shallow := New[NextHop](WithResolveDepth(1))
+	defer shallow.Close()
+	for pfx, nh := range tbl.All() {
+		shallow.Insert(pfx, nh)
+	}
+	if _, ok := shallow.LookupResolved(mpa("203.0.113.5")); ok {
+		t.Error("resolved a chain of two with depth 1")
+	}
+	if got, ok := shallow.LookupResolved(mpa("192.0.2.9")); !ok || got != connected {
+		t.Errorf("LookupResolved with depth 1 = %s, %v", got, ok)
+	}
+
+	ints := New[int]()
+	defer ints.Close()
+	ints.Insert(mpp("10.0.0.0/8"), 7)
+	if v, ok := ints.LookupResolved(mpa("10.1.1.1")); !ok || v != 7 {
+		t.Errorf("LookupResolved of a plain value = %d, %v", v, ok)
+	}
+}
@@ -0,0 +1,198 @@
+package zart
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Administrative distances of common route sources, as used by most
+// router vendors. Any other distance can be used with RIB.Insert.
+const (
+	DistanceConnected = 0
+	DistanceStatic    = 1
+	DistanceEBGP      = 20
+	DistanceOSPF      = 110
+	DistanceISIS      = 115
+	DistanceRIP       = 120
+	DistanceIBGP      = 200
+)
+
+// Path is a route to a prefix learned from one source. Among the paths of
+// a prefix the one with the lowest Distance is selected, the others are
+// kept as backups.
+//
+// Time and Seq record the provenance of the path beyond its source: when
+// it was installed and the sequence number of the update of the source
+// that carried it, a BGP session's update counter or an RPKI serial, say.
+// The RIB only stores them.
+type Path[V any] struct {
+	Source   string
+	Distance int
+	Value    V
+	Time     time.Time
+	Seq      uint64
+}
+
+// RIB is a routing table in which several sources can install the same
+// prefix, each with its own administrative distance. Lookups match the
+// longest prefix as in Table and return its best path; deleting the best
+// path lets the next one take over.
+//
+// A RIB is a Table of path lists sorted by preference, so a lookup costs
+// the same as in a Table. The lists are replaced, not modified, on every
+// change. Like Table, a RIB is not safe for concurrent use.
+type RIB[V any] struct {
+	t       *Table[[]Path[V]]
+	sources map[string]map[netip.Prefix]struct{} // prefixes by source
+}
+
+// NewRIB returns an empty RIB.
+func NewRIB[V any]() *RIB[V] {
+	return &RIB[V]{t: New[[]Path[V]](), sources: map[string]map[netip.Prefix]struct{}{}}
+}
+
+// Close releases the RIB, see Table.Close.
+func (r *RIB[V]) Close() {
+	r.t.Close()
+}
+
+// Table returns the underlying table of path lists, best path first. Its
+// values must not be modified, nor its prefixes but through the RIB,
+// which indexes them by source.
+func (r *RIB[V]) Table() *Table[[]Path[V]] {
+	return r.t
+}
+
+// Size returns the number of prefixes in the RIB.
+func (r *RIB[V]) Size() int {
+	return r.t.Size()
+}
+
+// Insert installs the path of source to pfx, replacing an earlier path of
+// the same source. Paths with equal distance are preferred in the order
+// they were installed. The path is stamped with the current time.
+func (r *RIB[V]) Insert(pfx netip.Prefix, source string, distance int, val V) {
+	r.InsertPath(pfx, Path[V]{Source: source, Distance: distance, Value: val, Time: time.Now()})
+}
+
+// InsertPath is Insert for a path with its provenance set by the caller.
+func (r *RIB[V]) InsertPath(pfx netip.Prefix, p Path[V]) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked()
+	source, distance := p.Source, p.Distance
+	r.t.Modify(pfx, func(old []Path[V], _ bool) ([]Path[V], bool) {
+		paths := make([]Path[V], 0, len(old)+1)
+		for _, q := range old {
+			if q.Source != source {
+				paths = append(paths, q)
+			}
+		}
+		i, _ := slices.BinarySearchFunc(paths, distance, func(q Path[V], d int) int {
+			if q.Distance <= d {
+				return -1
+			}
+			return 1
+		})
+		return slices.Insert(paths, i, p), false
+	})
+	if r.sources[source] == nil {
+		r.sources[source] = map[netip.Prefix]struct{}{}
+	}
+	r.sources[source][pfx] = struct{}{}
+}
+
+// Delete withdraws the path of source to pfx and reports whether there
+// was one. The prefix is removed with its last path.
+func (r *RIB[V]) Delete(pfx netip.Prefix, source string) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	pfx = pfx.Masked()
+	deleted := false
+	r.t.Modify(pfx, func(old []Path[V], existed bool) ([]Path[V], bool) {
+		i := slices.IndexFunc(old, func(q Path[V]) bool { return q.Source == source })
+		if i < 0 {
+			return old, !existed
+		}
+		deleted = true
+		return slices.Delete(slices.Clone(old), i, i+1), len(old) == 1
+	})
+	if pfxs := r.sources[source]; pfxs != nil {
+		delete(pfxs, pfx)
+		if len(pfxs) == 0 {
+			delete(r.sources, source)
+		}
+	}
+	return deleted
+}
+
+// ReplaceSource makes entries the complete set of routes of source, as
+// after a full resync of a feed: they are installed with distance, the
+// current time and seq, and the paths of source to all other prefixes
+// are withdrawn. The paths of other sources are untouched. It returns the
+// number of paths installed and withdrawn.
+//
+// The new paths are installed before the stale ones are withdrawn, so a
+// prefix the source keeps is never without its path. A RIB shared between
+// goroutines must be locked for the whole call, as for every change;
+// readers then see the routes of source either all old or all new.
+func (r *RIB[V]) ReplaceSource(source string, distance int, seq uint64, entries []RouteEntry[V]) (installed, withdrawn int) {
+	stale := maps.Clone(r.sources[source])
+	now := time.Now()
+	for _, e := range entries {
+		if !e.Prefix.IsValid() {
+			continue
+		}
+		pfx := e.Prefix.Masked()
+		r.InsertPath(pfx, Path[V]{Source: source, Distance: distance, Value: e.Value, Time: now, Seq: seq})
+		delete(stale, pfx)
+		installed++
+	}
+	for pfx := range stale {
+		if r.Delete(pfx, source) {
+			withdrawn++
+		}
+	}
+	return installed, withdrawn
+}
+
+// Sources returns the sources with paths in the RIB, sorted.
+func (r *RIB[V]) Sources() []string {
+	return slices.Sorted(maps.Keys(r.sources))
+}
+
+// SourceSize returns the number of prefixes source has a path to.
+func (r *RIB[V]) SourceSize(source string) int {
+	return len(r.sources[source])
+}
+
+// Paths returns the paths to exactly pfx, best first. The slice must not
+// be modified.
+func (r *RIB[V]) Paths(pfx netip.Prefix) []Path[V] {
+	paths, _ := r.t.Get(pfx)
+	return paths
+}
+
+// Lookup performs a longest-prefix match for addr and returns the best
+// path of the matching prefix.
+func (r *RIB[V]) Lookup(addr netip.Addr) (p Path[V], ok bool) {
+	paths, ok := r.t.Lookup(addr)
+	if !ok {
+		return p, false
+	}
+	return paths[0], true
+}
+
+// LookupPrefix is like Lookup and additionally returns the matching
+// prefix.
+func (r *RIB[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, p Path[V], ok bool) {
+	pfx, paths, ok := r.t.LookupPrefix(addr)
+	if !ok {
+		return pfx, p, false
+	}
+	return pfx, paths[0], true
+}
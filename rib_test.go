@@ -0,0 +1,99 @@
+package zart
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRIB(t *testing.T) {
+	r := NewRIB[string]()
+	defer r.Close()
+
+	pfx := mpp("10.0.0.0/8")
+	r.Insert(pfx, "bgp", DistanceEBGP, "192.0.2.1")
+	r.Insert(pfx, "ospf", DistanceOSPF, "192.0.2.2")
+	r.Insert(pfx, "rip", DistanceOSPF, "192.0.2.3")
+	r.Insert(mpp("10.1.0.0/16"), "ospf", DistanceOSPF, "192.0.2.9")
+
+	if p, ok := r.Lookup(mpa("10.2.0.1")); !ok || p.Source != "bgp" {
+		t.Errorf("Lookup = %+v, %v, want the eBGP path", p, ok)
+	}
+	if pfx, p, _ := r.LookupPrefix(mpa("10.1.0.1")); pfx != mpp("10.1.0.0/16") || p.Value != "192.0.2.9" {
+		t.Errorf("longest match lost to preference: %s %+v", pfx, p)
+	}
+
+	// a static route beats all, reinstalling it replaces the old path
+	r.Insert(pfx, "static", DistanceStatic, "old")
+	r.Insert(pfx, "static", DistanceStatic, "new")
+	var order []string
+	for _, p := range r.Paths(pfx) {
+		order = append(order, p.Source)
+	}
+	if want := []string{"static", "bgp", "ospf", "rip"}; !slices.Equal(order, want) {
+		t.Errorf("paths = %v, want %v", order, want)
+	}
+	if p, _ := r.Lookup(mpa("10.2.0.1")); p.Value != "new" {
+		t.Errorf("Lookup = %+v, want the reinstalled static path", p)
+	}
+
+	// withdrawing the best path makes the backups take over
+	for _, want := range []string{"bgp", "ospf", "rip"} {
+		r.Delete(pfx, order[0])
+		order = order[1:]
+		if p, ok := r.Lookup(mpa("10.2.0.1")); !ok || p.Source != want {
+			t.Errorf("after withdrawal Lookup = %+v, %v, want %s", p, ok, want)
+		}
+	}
+	if r.Delete(pfx, "bgp") {
+		t.Errorf("Delete of a withdrawn path succeeded")
+	}
+	r.Delete(pfx, "rip")
+	if _, ok := r.Lookup(mpa("10.2.0.1")); ok || r.Size() != 1 {
+		t.Errorf("prefix kept after its last path, size %d", r.Size())
+	}
+}
+
+func TestRIBReplaceSource(t *testing.T) {
+	r := NewRIB[string]()
+	defer r.Close()
+
+	r.ReplaceSource("feed-a", DistanceEBGP, 1, []RouteEntry[string]{
+		{Prefix: mpp("10.0.0.0/8"), Value: "a1"},
+		{Prefix: mpp("10.1.0.0/16"), Value: "a1"},
+		{Prefix: mpp("2001:db8::/32"), Value: "a1"},
+	})
+	r.Insert(mpp("10.0.0.0/8"), "static", DistanceStatic, "s")
+	r.Insert(mpp("192.0.2.0/24"), "feed-b", DistanceIBGP, "b")
+
+	installed, withdrawn := r.ReplaceSource("feed-a", DistanceEBGP, 2, []RouteEntry[string]{
+		{Prefix: mpp("10.0.0.0/8"), Value: "a2"},
+		{Prefix: mpp("192.0.2.0/24"), Value: "a2"},
+	})
+	if installed != 2 || withdrawn != 2 {
+		t.Errorf("ReplaceSource = %d installed, %d withdrawn, want 2, 2", installed, withdrawn)
+	}
+	if r.Size() != 2 {
+		t.Errorf("Size = %d after the withdrawals, want 2", r.Size())
+	}
+	paths := r.Paths(mpp("10.0.0.0/8"))
+	if len(paths) != 2 || paths[0].Source != "static" || paths[1].Value != "a2" || paths[1].Seq != 2 || paths[1].Time.IsZero() {
+		t.Errorf("paths of 10.0.0.0/8 = %+v", paths)
+	}
+	if p, _ := r.Lookup(mpa("192.0.2.1")); p.Source != "feed-a" {
+		t.Errorf("Lookup(192.0.2.1) = %+v, want the eBGP path of feed-a", p)
+	}
+	if paths := r.Paths(mpp("192.0.2.0/24")); len(paths) != 2 || paths[1].Source != "feed-b" {
+		t.Errorf("other source touched: %+v", paths)
+	}
+	if got := r.Sources(); !slices.Equal(got, []string{"feed-a", "feed-b", "static"}) {
+		t.Errorf("Sources = %v", got)
+	}
+
+	// an empty update withdraws the source completely
+	if _, withdrawn := r.ReplaceSource("feed-a", DistanceEBGP, 3, nil); withdrawn != 2 || r.SourceSize("feed-a") != 0 {
+		t.Errorf("empty ReplaceSource withdrew %d, %d prefixes left", withdrawn, r.SourceSize("feed-a"))
+	}
+	if r.Size() != 2 || r.SourceSize("feed-b") != 1 {
+		t.Errorf("Size = %d, feed-b has %d prefixes", r.Size(), r.SourceSize("feed-b"))
+	}
+}
@@ -0,0 +1,200 @@
+package routedump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gx14ac/zart"
+)
+
+// BIRD is the format of the text output of BIRD's "show route all", from
+// BIRD 2 as well as BIRD 1.x, where the next hop leads the route line:
+//
+//	Table master4:
+//	10.0.0.0/8           unicast [static1 2024-01-01] * (200)
+//		via 192.0.2.1 on eth0
+//		Type: static univ
+//	                     unicast [bgp1 2024-01-01] (100) [AS65000i]
+//		via 192.0.2.2 on eth1
+//
+// Of the routes to a prefix the best one, marked by *, is used. The
+// attributes of the routes are skipped.
+func BIRD() zart.Format[Route] {
+	return func(r io.Reader) zart.Decoder[Route] { return &birdDecoder{sc: bufio.NewScanner(r)} }
+}
+
+type birdDecoder struct {
+	sc   *bufio.Scanner
+	line int
+
+	// next is the route line read ahead, which ended the previous route
+	next    string
+	hasNext bool
+}
+
+// birdEntry is a route to the prefix of a route line and of the
+// indented alternatives following it.
+type birdEntry struct {
+	proto  string
+	metric uint32
+	best   bool
+	reject bool
+	hops   []NextHop
+}
+
+func (d *birdDecoder) scan() (string, bool) {
+	if d.hasNext {
+		d.hasNext = false
+		return d.next, true
+	}
+	if !d.sc.Scan() {
+		return "", false
+	}
+	d.line++
+	return d.sc.Text(), true
+}
+
+func (d *birdDecoder) Next() (zart.RouteEntry[Route], error) {
+	var (
+		pfx     netip.Prefix
+		entries []birdEntry
+	)
+	for {
+		line, ok := d.scan()
+		if !ok {
+			break
+		}
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			// a route line, or a header like "Table master4:"
+			fields := strings.Fields(line)
+			p, err := parsePrefix(fields[0])
+			if err != nil {
+				continue
+			}
+			if entries != nil {
+				d.next, d.hasNext = line, true
+				break
+			}
+			e, err := parseBirdEntry(strings.TrimPrefix(line, fields[0]))
+			if err != nil {
+				return zart.RouteEntry[Route]{}, fmt.Errorf("%w: line %d: %w", ErrFormat, d.line, err)
+			}
+			pfx, entries = p, []birdEntry{e}
+			continue
+		}
+		if entries == nil {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		switch {
+		case strings.Contains(text, "["):
+			// an alternative route to the prefix
+			e, err := parseBirdEntry(text)
+			if err != nil {
+				return zart.RouteEntry[Route]{}, fmt.Errorf("%w: line %d: %w", ErrFormat, d.line, err)
+			}
+			entries = append(entries, e)
+		case strings.HasPrefix(text, "via ") || strings.HasPrefix(text, "dev "):
+			last := &entries[len(entries)-1]
+			hop, err := parseBirdHop(strings.Fields(text))
+			if err != nil {
+				return zart.RouteEntry[Route]{}, fmt.Errorf("%w: line %d: %w", ErrFormat, d.line, err)
+			}
+			last.hops = append(last.hops, hop)
+		}
+	}
+	if err := d.sc.Err(); err != nil {
+		return zart.RouteEntry[Route]{}, err
+	}
+	if entries == nil {
+		return zart.RouteEntry[Route]{}, io.EOF
+	}
+
+	best := &entries[0]
+	for i := range entries {
+		if entries[i].best {
+			best = &entries[i]
+			break
+		}
+	}
+	rt := Route{Prefix: pfx, Protocol: best.proto, Metric: best.metric}
+	if !best.reject {
+		rt.NextHops = best.hops
+	}
+	return zart.RouteEntry[Route]{Prefix: pfx, Value: rt}, nil
+}
+
+// parseBirdEntry parses a route line after the prefix, like "unicast
+// [bgp1 2024-01-01] * (100/20) [AS65000i]" or, from BIRD 1.x, "via
+// 192.0.2.1 on eth0 [static1 2024-01-01] * (200)".
+func parseBirdEntry(text string) (e birdEntry, err error) {
+	open := strings.IndexByte(text, '[')
+	end := strings.IndexByte(text, ']')
+	if open < 0 || end < open {
+		return e, fmt.Errorf("no protocol in %q", text)
+	}
+	if proto := strings.Fields(text[open+1 : end]); len(proto) > 0 {
+		e.proto = proto[0]
+	}
+
+	head := strings.Fields(text[:open])
+	for i, f := range head {
+		switch f {
+		case "blackhole", "unreachable", "prohibit":
+			e.reject = true
+		case "via", "dev":
+			hop, err := parseBirdHop(head[i:])
+			if err != nil {
+				return e, err
+			}
+			e.hops = append(e.hops, hop)
+		}
+		if len(e.hops) > 0 {
+			break
+		}
+	}
+
+	for _, f := range strings.Fields(text[end+1:]) {
+		switch {
+		case f == "*":
+			e.best = true
+		case strings.HasPrefix(f, "("):
+			pref, _, _ := strings.Cut(strings.Trim(f, "()"), "/")
+			m, err := strconv.ParseUint(pref, 10, 32)
+			if err != nil {
+				return e, fmt.Errorf("preference %q", f)
+			}
+			e.metric = uint32(m)
+		}
+	}
+	return e, nil
+}
+
+// parseBirdHop parses a next hop like "via 192.0.2.1 on eth0 weight 2" or
+// "dev eth0", up to the first field that is not part of it.
+func parseBirdHop(fields []string) (hop NextHop, err error) {
+	for n := 0; n+1 < len(fields); n += 2 {
+		switch arg := fields[n+1]; fields[n] {
+		case "via":
+			if hop.Gateway, err = netip.ParseAddr(arg); err != nil {
+				return hop, fmt.Errorf("gateway %q", arg)
+			}
+		case "on", "dev":
+			hop.Interface = arg
+		case "weight":
+			if hop.Weight, err = strconv.Atoi(arg); err != nil {
+				return hop, fmt.Errorf("weight %q", arg)
+			}
+		default:
+			return hop, nil
+		}
+	}
+	return hop, nil
+}
@@ -0,0 +1,90 @@
+package routedump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// frrRoute is a route of "show ip route json", the listing maps each
+// prefix to its routes.
+type frrRoute struct {
+	Prefix   string       `json:"prefix"`
+	Protocol string       `json:"protocol"`
+	Selected bool         `json:"selected"`
+	Metric   uint32       `json:"metric"`
+	NextHops []frrNextHop `json:"nexthops"`
+}
+
+type frrNextHop struct {
+	IP            string `json:"ip"`
+	InterfaceName string `json:"interfaceName"`
+	Weight        int    `json:"weight"`
+	Unreachable   bool   `json:"unreachable"`
+}
+
+// FRR is the format of the JSON output of FRR's "show ip route json" and
+// "show ipv6 route json". Of the routes to a prefix the selected one is
+// used.
+func FRR() zart.Format[Route] {
+	return func(r io.Reader) zart.Decoder[Route] { return &frrDecoder{dec: json.NewDecoder(r)} }
+}
+
+type frrDecoder struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func (d *frrDecoder) Next() (zart.RouteEntry[Route], error) {
+	if !d.started {
+		if tok, err := d.dec.Token(); err != nil {
+			return zart.RouteEntry[Route]{}, err
+		} else if tok != json.Delim('{') {
+			return zart.RouteEntry[Route]{}, fmt.Errorf("%w: FRR routes must be an object", ErrFormat)
+		}
+		d.started = true
+	}
+	if !d.dec.More() {
+		return zart.RouteEntry[Route]{}, io.EOF
+	}
+	tok, err := d.dec.Token()
+	if err != nil {
+		return zart.RouteEntry[Route]{}, err
+	}
+	key, _ := tok.(string)
+	var routes []frrRoute
+	if err := d.dec.Decode(&routes); err != nil {
+		return zart.RouteEntry[Route]{}, err
+	}
+	if len(routes) == 0 {
+		return zart.RouteEntry[Route]{}, fmt.Errorf("%w: no routes for %q", ErrFormat, key)
+	}
+	best := &routes[0]
+	for i := range routes {
+		if routes[i].Selected {
+			best = &routes[i]
+			break
+		}
+	}
+
+	rt := Route{Protocol: best.Protocol, Metric: best.Metric}
+	if rt.Prefix, err = parsePrefix(key); err != nil {
+		return zart.RouteEntry[Route]{}, fmt.Errorf("%w: prefix %q", ErrFormat, key)
+	}
+	for _, h := range best.NextHops {
+		if h.Unreachable {
+			continue
+		}
+		nh := NextHop{Interface: h.InterfaceName, Weight: h.Weight}
+		if h.IP != "" {
+			if nh.Gateway, err = netip.ParseAddr(h.IP); err != nil {
+				return zart.RouteEntry[Route]{}, fmt.Errorf("%w: next hop %q", ErrFormat, h.IP)
+			}
+		}
+		rt.NextHops = append(rt.NextHops, nh)
+	}
+	return zart.RouteEntry[Route]{Prefix: rt.Prefix, Value: rt}, nil
+}
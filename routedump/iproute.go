@@ -0,0 +1,107 @@
+package routedump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/gx14ac/zart"
+)
+
+// ipRoute is an element of "ip -j route show".
+type ipRoute struct {
+	Type     string      `json:"type"`
+	Dst      string      `json:"dst"`
+	Gateway  string      `json:"gateway"`
+	Dev      string      `json:"dev"`
+	Protocol string      `json:"protocol"`
+	Metric   uint32      `json:"metric"`
+	NextHops []ipNextHop `json:"nexthops"`
+}
+
+type ipNextHop struct {
+	Gateway string `json:"gateway"`
+	Dev     string `json:"dev"`
+	Weight  int    `json:"weight"`
+}
+
+// IPRoute is the format of the JSON output of "ip -j route show" and "ip
+// -6 -j route show". The default route is taken as 0.0.0.0/0 unless its
+// gateway is an IPv6 address; load IPv6 listings without a default
+// gateway with IPRoute6.
+func IPRoute() zart.Format[Route] {
+	return func(r io.Reader) zart.Decoder[Route] { return &ipDecoder{dec: json.NewDecoder(r)} }
+}
+
+// IPRoute6 is IPRoute for "ip -6 -j route show", the default route is
+// ::/0.
+func IPRoute6() zart.Format[Route] {
+	return func(r io.Reader) zart.Decoder[Route] { return &ipDecoder{dec: json.NewDecoder(r), v6: true} }
+}
+
+type ipDecoder struct {
+	dec     *json.Decoder
+	v6      bool
+	started bool
+}
+
+func (d *ipDecoder) Next() (zart.RouteEntry[Route], error) {
+	if !d.started {
+		if tok, err := d.dec.Token(); err != nil {
+			return zart.RouteEntry[Route]{}, err
+		} else if tok != json.Delim('[') {
+			return zart.RouteEntry[Route]{}, fmt.Errorf("%w: ip routes must be an array", ErrFormat)
+		}
+		d.started = true
+	}
+	if !d.dec.More() {
+		return zart.RouteEntry[Route]{}, io.EOF
+	}
+	var ir ipRoute
+	if err := d.dec.Decode(&ir); err != nil {
+		return zart.RouteEntry[Route]{}, err
+	}
+	rt, err := d.route(&ir)
+	return zart.RouteEntry[Route]{Prefix: rt.Prefix, Value: rt}, err
+}
+
+func (d *ipDecoder) route(ir *ipRoute) (Route, error) {
+	rt := Route{Protocol: ir.Protocol, Metric: ir.Metric}
+	hops := ir.NextHops
+	if len(hops) == 0 && (ir.Gateway != "" || ir.Dev != "") {
+		hops = []ipNextHop{{Gateway: ir.Gateway, Dev: ir.Dev}}
+	}
+	for _, h := range hops {
+		nh := NextHop{Interface: h.Dev, Weight: h.Weight}
+		if h.Gateway != "" {
+			gw, err := netip.ParseAddr(h.Gateway)
+			if err != nil {
+				return rt, fmt.Errorf("%w: gateway %q", ErrFormat, h.Gateway)
+			}
+			nh.Gateway = gw
+		}
+		rt.NextHops = append(rt.NextHops, nh)
+	}
+	if ir.Type == "blackhole" || ir.Type == "unreachable" || ir.Type == "prohibit" {
+		rt.NextHops = nil
+	}
+
+	if ir.Dst == "default" {
+		v6 := d.v6
+		if len(rt.NextHops) > 0 && rt.NextHops[0].Gateway.Is6() {
+			v6 = true
+		}
+		rt.Prefix = netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+		if v6 {
+			rt.Prefix = netip.PrefixFrom(netip.IPv6Unspecified(), 0)
+		}
+		return rt, nil
+	}
+	pfx, err := parsePrefix(ir.Dst)
+	if err != nil {
+		return rt, fmt.Errorf("%w: destination %q", ErrFormat, ir.Dst)
+	}
+	rt.Prefix = pfx
+	return rt, nil
+}
@@ -0,0 +1,58 @@
+// Package routedump reads the route listings of routing software into
+// zart tables: the JSON output of Linux "ip -j route show", the text of
+// BIRD's "show route all" and the JSON of FRR's "show ip route json".
+//
+// Each format is a zart.Format for Table.Load:
+//
+//	tbl := zart.New[routedump.Route]()
+//	n, err := tbl.Load(out, routedump.BIRD(), nil)
+//
+// Listings may hold several routes per prefix, from different protocols
+// or with different metrics. The formats report the one the router
+// selected, where the listing tells, and the first otherwise.
+package routedump
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// ErrFormat is returned, wrapped, for listings that do not parse.
+var ErrFormat = errors.New("routedump: malformed listing")
+
+// Route is a route of a listing, the value of the tables loaded.
+type Route struct {
+	Prefix netip.Prefix
+
+	// Protocol is the source of the route as named by the router: the
+	// kernel protocol like "kernel" or "static" for ip, the protocol
+	// instance like "bgp1" for BIRD, the protocol like "ospf" for FRR.
+	Protocol string
+
+	// Metric is the metric of the route for ip and FRR and the preference
+	// for BIRD.
+	Metric uint32
+
+	// NextHops are the next hops, several for multipath routes. None for
+	// blackhole and unreachable routes.
+	NextHops []NextHop
+}
+
+// NextHop is a gateway or an interface a route forwards to.
+type NextHop struct {
+	Gateway   netip.Addr // invalid for directly connected routes
+	Interface string
+	Weight    int // 0 unless given
+}
+
+// parsePrefix parses a destination, a bare address is a host route.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if pfx, err := netip.ParsePrefix(s); err == nil {
+		return pfx, nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,160 @@
+package routedump
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+func load(t *testing.T, listing string, format zart.Format[Route]) *zart.Table[Route] {
+	t.Helper()
+	tbl := zart.New[Route]()
+	if _, err := tbl.Load(strings.NewReader(listing), format, nil); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return tbl
+}
+
+func check(t *testing.T, tbl *zart.Table[Route], want []Route) {
+	t.Helper()
+	if got := tbl.Size(); got != len(want) {
+		t.Errorf("Size = %d, want %d", got, len(want))
+	}
+	for _, w := range want {
+		got, ok := tbl.Get(w.Prefix)
+		if !ok {
+			t.Errorf("%s missing", w.Prefix)
+			continue
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("%s = %+v, want %+v", w.Prefix, got, w)
+		}
+	}
+}
+
+func hop(gw, dev string, weight int) NextHop {
+	h := NextHop{Interface: dev, Weight: weight}
+	if gw != "" {
+		h.Gateway = netip.MustParseAddr(gw)
+	}
+	return h
+}
+
+func TestIPRoute(t *testing.T) {
+	const listing = `[
+{"dst":"default","gateway":"192.0.2.1","dev":"eth0","protocol":"dhcp","metric":100,"flags":[]},
+{"dst":"10.0.0.0/8","nexthops":[{"gateway":"192.0.2.2","dev":"eth0","weight":1,"flags":[]},{"gateway":"192.0.2.3","dev":"eth1","weight":2,"flags":[]}],"protocol":"static","flags":[]},
+{"dst":"192.0.2.0/24","dev":"eth0","protocol":"kernel","scope":"link","prefsrc":"192.0.2.10","flags":[]},
+{"type":"blackhole","dst":"198.51.100.0/24","protocol":"static","flags":[]},
+{"dst":"203.0.113.7","gateway":"192.0.2.1","dev":"eth0","flags":[]}
+]`
+	check(t, load(t, listing, IPRoute()), []Route{
+		{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Protocol: "dhcp", Metric: 100, NextHops: []NextHop{hop("192.0.2.1", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Protocol: "static", NextHops: []NextHop{hop("192.0.2.2", "eth0", 1), hop("192.0.2.3", "eth1", 2)}},
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Protocol: "kernel", NextHops: []NextHop{hop("", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Protocol: "static"},
+		{Prefix: netip.MustParsePrefix("203.0.113.7/32"), NextHops: []NextHop{hop("192.0.2.1", "eth0", 0)}},
+	})
+
+	const listing6 = `[
+{"dst":"default","dev":"wg0","protocol":"static","metric":1024,"flags":[]},
+{"dst":"2001:db8::/32","gateway":"fe80::1","dev":"eth0","protocol":"ra","metric":100,"flags":[]}
+]`
+	check(t, load(t, listing6, IPRoute6()), []Route{
+		{Prefix: netip.MustParsePrefix("::/0"), Protocol: "static", Metric: 1024, NextHops: []NextHop{hop("", "wg0", 0)}},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Protocol: "ra", Metric: 100, NextHops: []NextHop{hop("fe80::1", "eth0", 0)}},
+	})
+}
+
+func TestBIRD(t *testing.T) {
+	const listing = `BIRD 2.0.12 ready.
+Table master4:
+10.0.0.0/8           unicast [static1 2024-01-01] * (200)
+	via 192.0.2.1 on eth0
+	Type: static univ
+192.168.0.0/16       unicast [bgp1 2024-01-01] (100) [AS65001i]
+	via 192.0.2.3 on eth1
+	Type: BGP univ
+	BGP.origin: IGP
+	BGP.as_path: 65001
+                     unicast [bgp2 2024-01-01] * (100) [AS65002i]
+	via 192.0.2.2 on eth0
+	Type: BGP univ
+172.16.0.0/12        unicast [ospf1 2024-01-01] * I (150/20) [10.0.0.1]
+	via 192.0.2.4 on eth0 weight 1
+	via 192.0.2.5 on eth1 weight 3
+	Type: OSPF univ
+198.51.100.0/24      blackhole [static1 2024-01-01] * (200)
+	Type: static univ
+
+Table master6:
+2001:db8::/32        unicast [direct1 2024-01-01] * (240)
+	dev eth0
+	Type: device univ
+`
+	check(t, load(t, listing, BIRD()), []Route{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Protocol: "static1", Metric: 200, NextHops: []NextHop{hop("192.0.2.1", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("192.168.0.0/16"), Protocol: "bgp2", Metric: 100, NextHops: []NextHop{hop("192.0.2.2", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("172.16.0.0/12"), Protocol: "ospf1", Metric: 150, NextHops: []NextHop{hop("192.0.2.4", "eth0", 1), hop("192.0.2.5", "eth1", 3)}},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Protocol: "static1", Metric: 200},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Protocol: "direct1", Metric: 240, NextHops: []NextHop{hop("", "eth0", 0)}},
+	})
+
+	const listing1 = `BIRD 1.6.8 ready.
+10.0.0.0/8         via 192.0.2.1 on eth0 [static1 12:00:00] * (200)
+	Type: static unicast univ
+192.0.2.0/24       dev eth0 [direct1 12:00:00] * (240)
+`
+	check(t, load(t, listing1, BIRD()), []Route{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Protocol: "static1", Metric: 200, NextHops: []NextHop{hop("192.0.2.1", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Protocol: "direct1", Metric: 240, NextHops: []NextHop{hop("", "eth0", 0)}},
+	})
+}
+
+func TestFRR(t *testing.T) {
+	const listing = `{
+  "10.0.0.0/8":[
+    {"prefix":"10.0.0.0/8","protocol":"static","distance":1,"metric":0,"installed":true,
+     "nexthops":[{"ip":"192.0.2.9","afi":"ipv4","interfaceName":"eth1","active":true,"weight":1}]},
+    {"prefix":"10.0.0.0/8","protocol":"ospf","selected":true,"distance":110,"metric":20,"installed":true,
+     "nexthops":[{"ip":"192.0.2.1","afi":"ipv4","interfaceName":"eth0","active":true,"weight":1},
+                 {"ip":"192.0.2.2","afi":"ipv4","interfaceName":"eth1","active":true,"weight":1}]}
+  ],
+  "192.0.2.0/24":[
+    {"prefix":"192.0.2.0/24","protocol":"connected","selected":true,"distance":0,"metric":0,
+     "nexthops":[{"directlyConnected":true,"interfaceName":"eth0","active":true}]}
+  ],
+  "198.51.100.0/24":[
+    {"prefix":"198.51.100.0/24","protocol":"static","selected":true,"distance":1,"metric":0,
+     "nexthops":[{"unreachable":true,"blackhole":true,"active":true}]}
+  ]
+}`
+	check(t, load(t, listing, FRR()), []Route{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Protocol: "ospf", Metric: 20, NextHops: []NextHop{hop("192.0.2.1", "eth0", 1), hop("192.0.2.2", "eth1", 1)}},
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Protocol: "connected", NextHops: []NextHop{hop("", "eth0", 0)}},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Protocol: "static"},
+	})
+}
+
+func TestMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		format  zart.Format[Route]
+		listing string
+	}{
+		{"ip object", IPRoute(), `{"dst":"default"}`},
+		{"ip dst", IPRoute(), `[{"dst":"10.0.0.0/33"}]`},
+		{"bird gateway", BIRD(), "10.0.0.0/8 unicast [static1] * (200)\n\tvia 192.0.2.x on eth0\n"},
+		{"bird protocol", BIRD(), "10.0.0.0/8 unicast * (200)\n"},
+		{"frr prefix", FRR(), `{"10.0.0.0/33":[{"protocol":"static"}]}`},
+	} {
+		_, err := zart.New[Route]().Load(strings.NewReader(tc.listing), tc.format, nil)
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: err = %v, want ErrFormat", tc.name, err)
+		}
+	}
+}
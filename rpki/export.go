@@ -0,0 +1,147 @@
+package rpki
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gx14ac/zart"
+)
+
+// jsonROA is an element of the "roas" array of a JSON export. The origin
+// is a number for rpki-client and a string like "AS13335" for Routinator
+// and StayRTR.
+type jsonROA struct {
+	Prefix    string          `json:"prefix"`
+	MaxLength int             `json:"maxLength"`
+	ASN       json.RawMessage `json:"asn"`
+}
+
+// JSON is the format of the JSON exports of rpki-client, Routinator and
+// the cache files of StayRTR and GoRTR: an object with the ROAs in an
+// array under "roas". The other members of the object are skipped.
+func JSON() zart.Format[ROA] {
+	return func(r io.Reader) zart.Decoder[ROA] { return &jsonDecoder{dec: json.NewDecoder(r)} }
+}
+
+type jsonDecoder struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func (d *jsonDecoder) Next() (zart.RouteEntry[ROA], error) {
+	if !d.started {
+		if err := d.seek(); err != nil {
+			return zart.RouteEntry[ROA]{}, err
+		}
+		d.started = true
+	}
+	if !d.dec.More() {
+		return zart.RouteEntry[ROA]{}, io.EOF
+	}
+	var jr jsonROA
+	if err := d.dec.Decode(&jr); err != nil {
+		return zart.RouteEntry[ROA]{}, err
+	}
+	pfx, err := netip.ParsePrefix(jr.Prefix)
+	if err != nil {
+		return zart.RouteEntry[ROA]{}, fmt.Errorf("%w: prefix %q", ErrFormat, jr.Prefix)
+	}
+	asn, err := parseASN(strings.Trim(string(jr.ASN), `"`))
+	if err != nil {
+		return zart.RouteEntry[ROA]{}, err
+	}
+	roa := ROA{Prefix: pfx, MaxLength: jr.MaxLength, Origin: asn}
+	if roa.MaxLength == 0 {
+		roa.MaxLength = pfx.Bits()
+	}
+	return zart.RouteEntry[ROA]{Prefix: pfx, Value: roa}, nil
+}
+
+// seek reads up to the start of the "roas" array.
+func (d *jsonDecoder) seek() error {
+	if tok, err := d.dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("%w: not a JSON object", ErrFormat)
+	}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != "roas" {
+			var skip json.RawMessage
+			if err := d.dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if tok, err := d.dec.Token(); err != nil {
+			return err
+		} else if tok != json.Delim('[') {
+			return fmt.Errorf("%w: roas must be an array", ErrFormat)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no roas", ErrFormat)
+}
+
+// CSV is the format of the CSV exports of rpki-client and Routinator:
+// a header line, then the origin AS, prefix, max length and further
+// columns like the trust anchor, which are skipped.
+//
+//	ASN,IP Prefix,Max Length,Trust Anchor
+//	AS13335,1.1.1.0/24,24,apnic
+func CSV() zart.Format[ROA] {
+	return func(r io.Reader) zart.Decoder[ROA] {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &csvDecoder{cr: cr}
+	}
+}
+
+type csvDecoder struct {
+	cr     *csv.Reader
+	header bool
+}
+
+func (d *csvDecoder) Next() (e zart.RouteEntry[ROA], err error) {
+	rec, err := d.cr.Read()
+	if err == nil && !d.header {
+		d.header = true
+		rec, err = d.cr.Read()
+	}
+	if err != nil {
+		return e, err
+	}
+	line, _ := d.cr.FieldPos(0)
+	if len(rec) < 3 {
+		return e, fmt.Errorf("%w: line %d: %d columns", ErrFormat, line, len(rec))
+	}
+	if e.Value.Origin, err = parseASN(rec[0]); err != nil {
+		return e, fmt.Errorf("line %d: %w", line, err)
+	}
+	if e.Value.Prefix, err = netip.ParsePrefix(rec[1]); err != nil {
+		return e, fmt.Errorf("%w: line %d: prefix %q", ErrFormat, line, rec[1])
+	}
+	if e.Value.MaxLength, err = strconv.Atoi(rec[2]); err != nil {
+		return e, fmt.Errorf("%w: line %d: max length %q", ErrFormat, line, rec[2])
+	}
+	e.Prefix = e.Value.Prefix
+	return e, nil
+}
+
+// parseASN parses an AS number with or without the AS prefix.
+func parseASN(s string) (uint32, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(s), "AS"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: AS number %q", ErrFormat, s)
+	}
+	return uint32(n), nil
+}
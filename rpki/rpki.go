@@ -0,0 +1,159 @@
+// Package rpki validates the origin of BGP routes against RPKI Route
+// Origin Authorizations, following RFC 6811.
+//
+// A Validator holds the validated ROA payloads, loaded from the JSON or
+// CSV export of a relying party like rpki-client, Routinator or the cache
+// files of StayRTR:
+//
+//	v := rpki.NewValidator()
+//	if _, err := v.Load(f, rpki.JSON()); err != nil {
+//		return err
+//	}
+//	switch v.Validate(pfx, origin) {
+//	case rpki.Invalid:
+//		// drop the route
+//	}
+package rpki
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+
+	"github.com/gx14ac/zart"
+)
+
+// ErrFormat is returned, wrapped, for exports that do not parse.
+var ErrFormat = errors.New("rpki: malformed ROA export")
+
+// ROA is a validated ROA payload: Origin may announce Prefix and its
+// subnets up to MaxLength bits.
+type ROA struct {
+	Prefix    netip.Prefix
+	MaxLength int
+	Origin    uint32
+}
+
+// State is the validation state of a route.
+type State int
+
+// The states of RFC 6811.
+const (
+	NotFound State = iota // no ROA covers the prefix
+	Valid                 // a covering ROA matches the origin and length
+	Invalid               // covering ROAs exist but none matches
+)
+
+func (s State) String() string {
+	switch s {
+	case NotFound:
+		return "not-found"
+	case Valid:
+		return "valid"
+	case Invalid:
+		return "invalid"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// vrp is a ROA without its prefix, the key of its table entry.
+type vrp struct {
+	maxLen uint8
+	origin uint32
+}
+
+// Validator validates routes against a set of ROAs. It is not safe for
+// concurrent modification; load a new Validator and swap it in to follow
+// the updates of the RPKI.
+type Validator struct {
+	t *zart.Table[[]vrp]
+	n int
+}
+
+// NewValidator returns a Validator without ROAs, for which every route
+// is NotFound.
+func NewValidator() *Validator {
+	return &Validator{t: zart.New[[]vrp](zart.WithStrict())}
+}
+
+// Add adds roa. It fails for invalid prefixes, prefixes with host bits
+// set and a MaxLength shorter than the prefix or longer than the address.
+// Duplicates are ignored.
+func (v *Validator) Add(roa ROA) error {
+	pfx := roa.Prefix
+	if err := v.t.Check(pfx); err != nil {
+		return err
+	}
+	if roa.MaxLength < pfx.Bits() || roa.MaxLength > pfx.Addr().BitLen() {
+		return fmt.Errorf("rpki: %s: max length %d out of range", pfx, roa.MaxLength)
+	}
+	p := vrp{maxLen: uint8(roa.MaxLength), origin: roa.Origin}
+	v.t.Modify(pfx, func(vrps []vrp, _ bool) ([]vrp, bool) {
+		if !slices.Contains(vrps, p) {
+			vrps = append(vrps, p)
+			v.n++
+		}
+		return vrps, false
+	})
+	return nil
+}
+
+// Len returns the number of ROAs.
+func (v *Validator) Len() int {
+	return v.n
+}
+
+// Load adds the ROAs read from r in format and returns their number. On
+// error the ROAs read before it are added.
+func (v *Validator) Load(r io.Reader, format zart.Format[ROA]) (n int, err error) {
+	dec := format(r)
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := v.Add(e.Value); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Validate returns the validation state of the route to pfx originated by
+// origin. The candidate ROAs are those covering pfx, found in a single
+// walk up from its longest-prefix match; the route is Valid if one of them
+// is for origin with a MaxLength of at least the length of pfx. ROAs for
+// AS 0 match no route (RFC 7607), nor does origin 0, which callers pass
+// for routes whose origin cannot be determined, like an AS_SET.
+func (v *Validator) Validate(pfx netip.Prefix, origin uint32) State {
+	if !pfx.IsValid() {
+		return NotFound
+	}
+	state := NotFound
+	for _, vrps := range v.t.Supernets(pfx.Masked()) {
+		state = Invalid
+		for _, p := range vrps {
+			if p.origin == origin && origin != 0 && pfx.Bits() <= int(p.maxLen) {
+				return Valid
+			}
+		}
+	}
+	return state
+}
+
+// Covering returns the ROAs covering pfx, from the most to the least
+// specific prefix, for telling why a route is Invalid.
+func (v *Validator) Covering(pfx netip.Prefix) []ROA {
+	var roas []ROA
+	for p, vrps := range v.t.Supernets(pfx.Masked()) {
+		for _, vp := range vrps {
+			roas = append(roas, ROA{Prefix: p, MaxLength: int(vp.maxLen), Origin: vp.origin})
+		}
+	}
+	return roas
+}
@@ -0,0 +1,95 @@
+package rpki
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gx14ac/zart"
+)
+
+const exportJSON = `{
+  "metadata": {"buildtime": "2024-01-01T00:00:00Z", "roas": 5},
+  "roas": [
+    {"asn": 13335, "prefix": "1.1.1.0/24", "maxLength": 24, "ta": "apnic"},
+    {"asn": "AS64500", "prefix": "192.0.2.0/23", "maxLength": 24, "ta": "arin"},
+    {"asn": "AS64501", "prefix": "192.0.2.0/24", "maxLength": 24, "ta": "arin"},
+    {"asn": "AS0", "prefix": "198.51.100.0/24", "maxLength": 32, "ta": "ripe"},
+    {"asn": 64502, "prefix": "2001:db8::/32", "maxLength": 48, "ta": "ripe"}
+  ]
+}`
+
+func TestValidate(t *testing.T) {
+	v := NewValidator()
+	if n, err := v.Load(strings.NewReader(exportJSON), JSON()); err != nil || n != 5 || v.Len() != 5 {
+		t.Fatalf("Load = %d, %v; Len %d", n, err, v.Len())
+	}
+	for _, tc := range []struct {
+		pfx    string
+		origin uint32
+		want   State
+	}{
+		{"1.1.1.0/24", 13335, Valid},
+		{"1.1.1.0/24", 64666, Invalid},
+		{"1.1.1.0/25", 13335, Invalid}, // longer than the max length
+		{"1.1.0.0/16", 13335, NotFound},
+		{"8.8.8.0/24", 15169, NotFound},
+		{"192.0.2.0/24", 64500, Valid}, // either covering ROA may match
+		{"192.0.2.0/24", 64501, Valid},
+		{"192.0.3.0/24", 64501, Invalid},
+		{"192.0.2.0/23", 64501, Invalid},
+		{"198.51.100.0/24", 0, Invalid}, // AS 0 ROAs match nothing
+		{"198.51.100.128/25", 64500, Invalid},
+		{"2001:db8:1::/48", 64502, Valid},
+		{"2001:db8:1::/64", 64502, Invalid},
+		{"2001:db9::/32", 64502, NotFound},
+	} {
+		if got := v.Validate(netip.MustParsePrefix(tc.pfx), tc.origin); got != tc.want {
+			t.Errorf("Validate(%s, AS%d) = %v, want %v", tc.pfx, tc.origin, got, tc.want)
+		}
+	}
+
+	if got := v.Covering(netip.MustParsePrefix("192.0.2.0/24")); len(got) != 2 || got[0].Origin != 64501 {
+		t.Errorf("Covering(192.0.2.0/24) = %v", got)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	v := NewValidator()
+	roa := ROA{Prefix: netip.MustParsePrefix("10.0.0.0/8"), MaxLength: 16, Origin: 64500}
+	for range 2 {
+		if err := v.Add(roa); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if v.Len() != 1 {
+		t.Errorf("Len after a duplicate = %d, want 1", v.Len())
+	}
+	if err := v.Add(ROA{Prefix: netip.MustParsePrefix("10.0.0.1/8"), MaxLength: 8}); !errors.Is(err, zart.ErrHostBits) {
+		t.Errorf("Add with host bits = %v", err)
+	}
+	if err := v.Add(ROA{Prefix: netip.MustParsePrefix("10.0.0.0/8"), MaxLength: 7}); err == nil {
+		t.Errorf("Add with a max length under the prefix length succeeded")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	const export = "ASN,IP Prefix,Max Length,Trust Anchor\nAS13335,1.1.1.0/24,24,apnic\nAS64502,2001:db8::/32,48,ripe\n"
+	v := NewValidator()
+	if n, err := v.Load(strings.NewReader(export), CSV()); err != nil || n != 2 {
+		t.Fatalf("Load = %d, %v", n, err)
+	}
+	if got := v.Validate(netip.MustParsePrefix("2001:db8:ff::/48"), 64502); got != Valid {
+		t.Errorf("Validate = %v, want valid", got)
+	}
+
+	for _, bad := range []string{"ASN,Prefix,Max\nASx,1.1.1.0/24,24\n", "ASN,Prefix,Max\nAS1,1.1.1.0/24\n"} {
+		if _, err := NewValidator().Load(strings.NewReader(bad), CSV()); !errors.Is(err, ErrFormat) {
+			t.Errorf("Load(%q) = %v, want ErrFormat", bad, err)
+		}
+	}
+	if _, err := NewValidator().Load(strings.NewReader(`{"metadata":{}}`), JSON()); !errors.Is(err, ErrFormat) {
+		t.Errorf("Load without roas = %v, want ErrFormat", err)
+	}
+}
@@ -0,0 +1,125 @@
+package zart
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+)
+
+// Sampler draws uniformly random addresses from the address space a table
+// routes, or from the space it does not route, for generating matching
+// and missing traffic in load tests and fuzzers. See Table.Sampler.
+//
+// A Sampler is a snapshot, later modifications of the table are not seen.
+// Like the *rand.Rand it draws from, it is not safe for concurrent use.
+type Sampler struct {
+	r *rand.Rand
+
+	// pools of the routed and unrouted space, indexed by IPv6
+	match, miss [2]pool
+}
+
+// pool is a set of disjoint prefixes to draw from, weighted by their
+// size. cum holds the running total of the sizes, in addresses for IPv4
+// and in /64s for IPv6.
+type pool struct {
+	pfxs []netip.Prefix
+	cum  []float64
+}
+
+// Sampler returns a Sampler for the current contents of the table,
+// drawing from r. It takes time linear in the size of the table.
+func (t *Table[V]) Sampler(r *rand.Rand) *Sampler {
+	s := &Sampler{r: r}
+	top := t.outermost()
+	split := len(top)
+	for i, p := range top {
+		if p.Addr().Is6() {
+			split = i
+			break
+		}
+	}
+
+	v4 := netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+	v6 := netip.PrefixFrom(netip.IPv6Unspecified(), 0)
+	s.match[0] = newPool(top[:split])
+	s.match[1] = newPool(top[split:])
+	s.miss[0] = newPool(gaps(v4, top[:split], nil))
+	s.miss[1] = newPool(gaps(v6, top[split:], nil))
+	return s
+}
+
+// Match returns a random address of the family that the table routes, ok
+// is false if it routes none.
+func (s *Sampler) Match(is4 bool) (addr netip.Addr, ok bool) {
+	return s.match[family(is4)].draw(s.r)
+}
+
+// Miss returns a random address of the family that the table does not
+// route, ok is false if the table routes all of them.
+func (s *Sampler) Miss(is4 bool) (addr netip.Addr, ok bool) {
+	return s.miss[family(is4)].draw(s.r)
+}
+
+func family(is4 bool) int {
+	if is4 {
+		return 0
+	}
+	return 1
+}
+
+func newPool(pfxs []netip.Prefix) pool {
+	p := pool{pfxs: pfxs, cum: make([]float64, len(pfxs))}
+	total := 0.0
+	for i, pfx := range pfxs {
+		hostBits := pfx.Addr().BitLen() - pfx.Bits()
+		if pfx.Addr().Is6() {
+			hostBits -= 64
+		}
+		total += math.Ldexp(1, hostBits)
+		p.cum[i] = total
+	}
+	return p
+}
+
+// draw picks a prefix by its weight and an address in it by randomizing
+// the host bits.
+func (p pool) draw(r *rand.Rand) (netip.Addr, bool) {
+	if len(p.pfxs) == 0 {
+		return netip.Addr{}, false
+	}
+	x := r.Float64() * p.cum[len(p.cum)-1]
+	i, _ := slices.BinarySearch(p.cum, x)
+	pfx := p.pfxs[min(i, len(p.pfxs)-1)]
+
+	a := pfx.Addr().AsSlice()
+	for j := pfx.Bits() / 8; j < len(a); j++ {
+		keep := 0
+		if j == pfx.Bits()/8 {
+			keep = pfx.Bits() % 8
+		}
+		mask := byte(0xff >> keep)
+		a[j] = a[j]&^mask | byte(r.UintN(256))&mask
+	}
+	addr, _ := netip.AddrFromSlice(a)
+	return addr, true
+}
+
+// gaps appends the complement of the sorted disjoint prefixes tops inside
+// block to out, as the largest prefixes not overlapping any of them.
+func gaps(block netip.Prefix, tops, out []netip.Prefix) []netip.Prefix {
+	switch {
+	case len(tops) == 0:
+		return append(out, block)
+	case tops[0] == block:
+		return out
+	}
+	lo := netip.PrefixFrom(block.Addr(), block.Bits()+1)
+	n := 0
+	for n < len(tops) && lo.Overlaps(tops[n]) {
+		n++
+	}
+	out = gaps(lo, tops[:n], out)
+	return gaps(sibling(lo), tops[n:], out)
+}
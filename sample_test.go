@@ -0,0 +1,60 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	prng := rand.New(rand.NewPCG(52, 52))
+	for i, pfx := range randomPrefixes(prng, 500) {
+		tbl.Insert(pfx, i)
+	}
+
+	s := tbl.Sampler(prng)
+	for _, is4 := range []bool{true, false} {
+		for range 2000 {
+			a, ok := s.Match(is4)
+			if !ok || a.Is4() != is4 || !tbl.Contains(a) {
+				t.Fatalf("Match(%v) = %s, %v, which the table does not route", is4, a, ok)
+			}
+			a, ok = s.Miss(is4)
+			if !ok || a.Is4() != is4 || tbl.Contains(a) {
+				t.Fatalf("Miss(%v) = %s, %v, which the table routes", is4, a, ok)
+			}
+		}
+	}
+}
+
+func TestSamplerWeights(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("11.0.0.0/10"), 2)
+	tbl.Insert(netip.PrefixFrom(netip.IPv6Unspecified(), 0), 3)
+
+	s := tbl.Sampler(rand.New(rand.NewPCG(52, 53)))
+	in10 := 0
+	for range 4000 {
+		a, _ := s.Match(true)
+		if mpp("10.0.0.0/8").Contains(a) {
+			in10++
+		}
+	}
+	// 10.0.0.0/8 is four fifths of the routed space
+	if in10 < 3000 || in10 > 3400 {
+		t.Errorf("%d of 4000 matches in 10.0.0.0/8, want about 3200", in10)
+	}
+	if a, ok := s.Miss(false); ok {
+		t.Errorf("Miss(false) = %s with a default route", a)
+	}
+
+	empty := New[int]()
+	defer empty.Close()
+	if a, ok := empty.Sampler(rand.New(rand.NewPCG(1, 1))).Match(true); ok {
+		t.Errorf("Match on an empty table = %s", a)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/netip"
+
+	"google.golang.org/grpc"
+
+	"github.com/gx14ac/zart"
+	"github.com/gx14ac/zart/server/zartpb"
+)
+
+// Client is a typed client for the Routes service, it decodes values
+// with the same Codec as the server.
+type Client[V any] struct {
+	rpc   zartpb.RoutesClient
+	codec Codec[V]
+}
+
+// NewClient returns a Client using the connection cc.
+func NewClient[V any](cc grpc.ClientConnInterface, codec Codec[V]) *Client[V] {
+	return &Client[V]{rpc: zartpb.NewRoutesClient(cc), codec: codec}
+}
+
+// Lookup performs a longest-prefix match for addr on the server.
+func (c *Client[V]) Lookup(ctx context.Context, addr netip.Addr) (pfx netip.Prefix, val V, ok bool, err error) {
+	resp, err := c.rpc.Lookup(ctx, &zartpb.LookupRequest{Addr: addr.String()})
+	if err != nil || !resp.GetFound() {
+		return pfx, val, false, err
+	}
+	pfx, val, err = c.decode(resp.GetRoute())
+	return pfx, val, err == nil, err
+}
+
+// Insert adds or replaces routes on the server in one request.
+func (c *Client[V]) Insert(ctx context.Context, routes ...zart.RouteEntry[V]) error {
+	req := &zartpb.InsertRequest{Routes: make([]*zartpb.Route, len(routes))}
+	for i, e := range routes {
+		b, err := c.codec.Marshal(e.Value)
+		if err != nil {
+			return err
+		}
+		req.Routes[i] = &zartpb.Route{Prefix: e.Prefix.String(), Value: b}
+	}
+	_, err := c.rpc.Insert(ctx, req)
+	return err
+}
+
+// Delete removes prefixes on the server and returns how many were present.
+func (c *Client[V]) Delete(ctx context.Context, pfxs ...netip.Prefix) (int, error) {
+	req := &zartpb.DeleteRequest{Prefixes: make([]string, len(pfxs))}
+	for i, pfx := range pfxs {
+		req.Prefixes[i] = pfx.String()
+	}
+	resp, err := c.rpc.Delete(ctx, req)
+	return int(resp.GetDeleted()), err
+}
+
+// Dump calls fn for every route of the server's table until fn returns
+// false.
+func (c *Client[V]) Dump(ctx context.Context, fn func(netip.Prefix, V) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.rpc.Dump(ctx, &zartpb.DumpRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pfx, val, err := c.decode(r)
+		if err != nil {
+			return err
+		}
+		if !fn(pfx, val) {
+			return nil
+		}
+	}
+}
+
+func (c *Client[V]) decode(r *zartpb.Route) (pfx netip.Prefix, val V, err error) {
+	if pfx, err = netip.ParsePrefix(r.GetPrefix()); err != nil {
+		return pfx, val, err
+	}
+	val, err = c.codec.Unmarshal(r.GetValue())
+	return pfx, val, err
+}
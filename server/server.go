@@ -0,0 +1,227 @@
+// Package server serves a zart table over gRPC, so that services in
+// other languages can query and update it.
+//
+// The service is defined in zartpb/zart.proto, zartpb holds the
+// generated Go code including the client. Values cross the wire as
+// bytes, a Codec converts them from and to the payload type of the
+// table. Client is a typed wrapper around the generated client.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/gx14ac/zart"
+	"github.com/gx14ac/zart/server/zartpb"
+)
+
+// Codec encodes table payloads for the wire.
+type Codec[V any] interface {
+	Marshal(V) ([]byte, error)
+	Unmarshal([]byte) (V, error)
+}
+
+// Bytes is the Codec for []byte payloads, they are sent unchanged.
+type Bytes struct{}
+
+func (Bytes) Marshal(v []byte) ([]byte, error)   { return v, nil }
+func (Bytes) Unmarshal(b []byte) ([]byte, error) { return b, nil }
+
+// JSON is a Codec encoding payloads with encoding/json.
+type JSON[V any] struct{}
+
+func (JSON[V]) Marshal(v V) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON[V]) Unmarshal(b []byte) (v V, err error) {
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// watchBuffer is the number of events queued per watcher. A watcher that
+// falls further behind is disconnected.
+const watchBuffer = 1024
+
+// Server implements zartpb.RoutesServer on top of a Table. It serializes
+// access to the table, which must not be used directly while the server
+// is running.
+type Server[V any] struct {
+	zartpb.UnimplementedRoutesServer
+
+	codec Codec[V]
+
+	mu sync.RWMutex
+	t  *zart.Table[V]
+
+	watchMu  sync.Mutex
+	watchers map[chan *zartpb.RouteEvent]struct{}
+}
+
+// New returns a Server for t.
+func New[V any](t *zart.Table[V], codec Codec[V]) *Server[V] {
+	return &Server[V]{t: t, codec: codec, watchers: map[chan *zartpb.RouteEvent]struct{}{}}
+}
+
+// Register registers the Routes service of s with g.
+func (s *Server[V]) Register(g grpc.ServiceRegistrar) {
+	zartpb.RegisterRoutesServer(g, s)
+}
+
+func (s *Server[V]) Lookup(_ context.Context, req *zartpb.LookupRequest) (*zartpb.LookupResponse, error) {
+	addr, err := netip.ParseAddr(req.GetAddr())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.RLock()
+	pfx, val, ok := s.t.LookupPrefix(addr)
+	s.mu.RUnlock()
+	if !ok {
+		return &zartpb.LookupResponse{}, nil
+	}
+	r, err := s.route(pfx, val)
+	if err != nil {
+		return nil, err
+	}
+	return &zartpb.LookupResponse{Found: true, Route: r}, nil
+}
+
+func (s *Server[V]) Insert(_ context.Context, req *zartpb.InsertRequest) (*zartpb.InsertResponse, error) {
+	// decode everything first, a bad route rejects the whole request
+	entries := make([]zart.RouteEntry[V], len(req.GetRoutes()))
+	for i, r := range req.GetRoutes() {
+		pfx, err := netip.ParsePrefix(r.GetPrefix())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		val, err := s.codec.Unmarshal(r.GetValue())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "route %s: %v", pfx, err)
+		}
+		entries[i] = zart.RouteEntry[V]{Prefix: pfx.Masked(), Value: val}
+	}
+
+	// events are queued under the table lock, so watchers see changes
+	// in the order they were applied
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.t.InsertBatch(entries)
+	for i, r := range req.GetRoutes() {
+		s.notify(&zartpb.RouteEvent{
+			Op:    zartpb.RouteEvent_OP_INSERT,
+			Route: &zartpb.Route{Prefix: entries[i].Prefix.String(), Value: r.GetValue()},
+		})
+	}
+	return &zartpb.InsertResponse{}, nil
+}
+
+func (s *Server[V]) Delete(_ context.Context, req *zartpb.DeleteRequest) (*zartpb.DeleteResponse, error) {
+	pfxs := make([]netip.Prefix, len(req.GetPrefixes()))
+	for i, p := range req.GetPrefixes() {
+		pfx, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		pfxs[i] = pfx.Masked()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for _, pfx := range pfxs {
+		if s.t.Delete(pfx) {
+			deleted++
+			s.notify(&zartpb.RouteEvent{Op: zartpb.RouteEvent_OP_DELETE, Route: &zartpb.Route{Prefix: pfx.String()}})
+		}
+	}
+	return &zartpb.DeleteResponse{Deleted: uint32(deleted)}, nil
+}
+
+// Dump sends a consistent snapshot, the table is only locked while the
+// routes are copied out.
+func (s *Server[V]) Dump(_ *zartpb.DumpRequest, stream zartpb.Routes_DumpServer) error {
+	s.mu.RLock()
+	var routes []zart.RouteEntry[V]
+	for pfx, val := range s.t.All() {
+		routes = append(routes, zart.RouteEntry[V]{Prefix: pfx, Value: val})
+	}
+	s.mu.RUnlock()
+
+	for _, e := range routes {
+		r, err := s.route(e.Prefix, e.Value)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch streams changes until the client goes away. The response header
+// is sent once the watch is in place, clients waiting for it with
+// Header see all later changes. Clients that cannot keep up are
+// disconnected with codes.ResourceExhausted and should dump the table
+// again.
+func (s *Server[V]) Watch(_ *zartpb.WatchRequest, stream zartpb.Routes_WatchServer) error {
+	ch := make(chan *zartpb.RouteEvent, watchBuffer)
+	s.watchMu.Lock()
+	s.watchers[ch] = struct{}{}
+	s.watchMu.Unlock()
+	defer s.unwatch(ch)
+
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher too slow")
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// notify queues ev for all watchers, dropping those with a full queue.
+func (s *Server[V]) notify(ev *zartpb.RouteEvent) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for ch := range s.watchers {
+		select {
+		case ch <- ev:
+		default:
+			delete(s.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *Server[V]) unwatch(ch chan *zartpb.RouteEvent) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if _, ok := s.watchers[ch]; ok {
+		delete(s.watchers, ch)
+		close(ch)
+	}
+}
+
+func (s *Server[V]) route(pfx netip.Prefix, val V) (*zartpb.Route, error) {
+	b, err := s.codec.Marshal(val)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "route %s: %v", pfx, err)
+	}
+	return &zartpb.Route{Prefix: pfx.String(), Value: b}, nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/gx14ac/zart"
+	"github.com/gx14ac/zart/server/zartpb"
+)
+
+type hop struct {
+	Via    string
+	Metric int
+}
+
+func serve(t *testing.T, tbl *zart.Table[hop]) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	New(tbl, JSON[hop]{}).Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestServer(t *testing.T) {
+	tbl := zart.New[hop]()
+	defer tbl.Close()
+	cc := serve(t, tbl)
+	c := NewClient(cc, JSON[hop]{})
+	ctx := context.Background()
+
+	watch, err := zartpb.NewRoutesClient(cc).Watch(ctx, &zartpb.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Insert(ctx,
+		zart.RouteEntry[hop]{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: hop{"192.0.2.1", 10}},
+		zart.RouteEntry[hop]{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: hop{"192.0.2.2", 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pfx, v, ok, err := c.Lookup(ctx, netip.MustParseAddr("10.1.2.3"))
+	if err != nil || !ok || pfx != netip.MustParsePrefix("10.1.0.0/16") || v.Metric != 20 {
+		t.Errorf("Lookup = %s, %+v, %v, %v", pfx, v, ok, err)
+	}
+	if _, _, ok, err := c.Lookup(ctx, netip.MustParseAddr("192.168.1.1")); ok || err != nil {
+		t.Errorf("Lookup of unrouted address = %v, %v", ok, err)
+	}
+
+	n, err := c.Delete(ctx, netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("172.16.0.0/12"))
+	if err != nil || n != 1 {
+		t.Errorf("Delete = %d, %v, want 1", n, err)
+	}
+
+	var dumped []netip.Prefix
+	if err := c.Dump(ctx, func(pfx netip.Prefix, _ hop) bool {
+		dumped = append(dumped, pfx)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dumped) != 1 || dumped[0] != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Dump = %v", dumped)
+	}
+
+	want := []struct {
+		op  zartpb.RouteEvent_Op
+		pfx string
+	}{
+		{zartpb.RouteEvent_OP_INSERT, "10.0.0.0/8"},
+		{zartpb.RouteEvent_OP_INSERT, "10.1.0.0/16"},
+		{zartpb.RouteEvent_OP_DELETE, "10.1.0.0/16"},
+	}
+	for _, w := range want {
+		ev, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.GetOp() != w.op || ev.GetRoute().GetPrefix() != w.pfx {
+			t.Errorf("Watch event = %v %s, want %v %s", ev.GetOp(), ev.GetRoute().GetPrefix(), w.op, w.pfx)
+		}
+	}
+}
+
+func TestServerInvalidArgument(t *testing.T) {
+	tbl := zart.New[hop]()
+	defer tbl.Close()
+	rpc := zartpb.NewRoutesClient(serve(t, tbl))
+	ctx := context.Background()
+
+	_, err := rpc.Insert(ctx, &zartpb.InsertRequest{Routes: []*zartpb.Route{
+		{Prefix: "10.0.0.0/8", Value: []byte(`{}`)},
+		{Prefix: "10.0.0.0/33", Value: []byte(`{}`)},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Insert with bad prefix: %v", err)
+	}
+	if _, ok := tbl.Lookup(netip.MustParseAddr("10.1.1.1")); ok {
+		t.Errorf("rejected Insert modified the table")
+	}
+	if _, err := rpc.Lookup(ctx, &zartpb.LookupRequest{Addr: "nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Lookup with bad address: %v", err)
+	}
+}
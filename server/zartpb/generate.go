@@ -0,0 +1,5 @@
+// Package zartpb holds the protocol buffer messages and gRPC stubs of the
+// Routes service, generated from zart.proto.
+package zartpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative zart.proto
@@ -0,0 +1,199 @@
+//go:build cgo && !purego
+
+package zart
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include
+#cgo LDFLAGS: -L${SRCDIR}/zig-out/lib -lbart
+#cgo noescape bart_pset_size4
+#cgo nocallback bart_pset_size4
+#cgo noescape bart_pset_size6
+#cgo nocallback bart_pset_size6
+#cgo noescape bart_pset_add4
+#cgo nocallback bart_pset_add4
+#cgo noescape bart_pset_add6
+#cgo nocallback bart_pset_add6
+#cgo noescape bart_pset_remove4
+#cgo nocallback bart_pset_remove4
+#cgo noescape bart_pset_remove6
+#cgo nocallback bart_pset_remove6
+#cgo noescape bart_pset_has4
+#cgo nocallback bart_pset_has4
+#cgo noescape bart_pset_has6
+#cgo nocallback bart_pset_has6
+#cgo noescape bart_pset_contains4
+#cgo nocallback bart_pset_contains4
+#cgo noescape bart_pset_contains6
+#cgo nocallback bart_pset_contains6
+#cgo noescape bart_pset_add_bulk
+#cgo nocallback bart_pset_add_bulk
+#cgo noescape bart_pset_remove_bulk
+#cgo nocallback bart_pset_remove_bulk
+#cgo nocallback bart_pset_union
+#cgo noescape bart_pset_common
+#cgo nocallback bart_pset_common
+#cgo noescape bart_pset_stats
+#cgo nocallback bart_pset_stats
+#include "bart.h"
+
+extern int zartWalk(void *ctx, bart_route_t *routes, size_t n);
+*/
+import "C"
+
+import (
+	"runtime"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// setTrie is the handle to a C prefix set, a table without values whose
+// nodes have no value slots. Sets have no persistent versions, so unlike
+// a trie a leaked one is destroyed by its cleanup right away.
+type setTrie struct {
+	ptr     *C.bart_pset_t
+	cleanup runtime.Cleanup
+}
+
+// trackSet wraps ptr, destroying it eventually if it is never closed.
+func trackSet(ptr *C.bart_pset_t) *setTrie {
+	s := &setTrie{ptr: ptr}
+	stack := allocStack()
+	s.cleanup = runtime.AddCleanup(s, func(ptr *C.bart_pset_t) {
+		reportLeak(stack)
+		C.bart_pset_destroy(ptr)
+	}, ptr)
+	return s
+}
+
+// newSetTrie returns an empty C set, allocating from an arena like
+// newTrie if o.arena is set.
+func newSetTrie(o options) *setTrie {
+	var ptr *C.bart_pset_t
+	if o.arena > 0 {
+		ptr = C.bart_pset_create_arena(C.size_t(o.arena - 1))
+	} else {
+		ptr = C.bart_pset_create()
+	}
+	if ptr == nil {
+		panic("zart: bart_pset_create: out of memory")
+	}
+	return trackSet(ptr)
+}
+
+// clone returns a deep copy of the C set.
+func (s *setTrie) clone() *setTrie {
+	ptr := C.bart_pset_clone(s.handle())
+	if ptr == nil {
+		panic("zart: bart_pset_clone: out of memory")
+	}
+	return trackSet(ptr)
+}
+
+func (s *setTrie) close() {
+	if s.ptr == nil {
+		return
+	}
+	s.cleanup.Stop()
+	C.bart_pset_destroy(s.ptr)
+	s.ptr = nil
+}
+
+// handle returns the C set, it panics with ErrClosed once the set is
+// closed like trie.handle.
+func (s *setTrie) handle() *C.bart_pset_t {
+	if s.ptr == nil {
+		panic(ErrClosed)
+	}
+	return s.ptr
+}
+
+func (s *setTrie) size4() int {
+	return int(C.bart_pset_size4(s.handle()))
+}
+
+func (s *setTrie) size6() int {
+	return int(C.bart_pset_size6(s.handle()))
+}
+
+// add4 adds a prefix and reports whether it was missing.
+func (s *setTrie) add4(addr uint32, bits uint8) bool {
+	return C.bart_pset_add4(s.handle(), C.uint32_t(addr), C.uint8_t(bits)) != 0
+}
+
+func (s *setTrie) add6(addr *[16]byte, bits uint8) bool {
+	return C.bart_pset_add6(s.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits)) != 0
+}
+
+// remove4 removes a prefix and reports whether it was present.
+func (s *setTrie) remove4(addr uint32, bits uint8) bool {
+	return C.bart_pset_remove4(s.handle(), C.uint32_t(addr), C.uint8_t(bits)) != 0
+}
+
+func (s *setTrie) remove6(addr *[16]byte, bits uint8) bool {
+	return C.bart_pset_remove6(s.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits)) != 0
+}
+
+func (s *setTrie) has4(addr uint32, bits uint8) bool {
+	return C.bart_pset_has4(s.handle(), C.uint32_t(addr), C.uint8_t(bits)) != 0
+}
+
+func (s *setTrie) has6(addr *[16]byte, bits uint8) bool {
+	return C.bart_pset_has6(s.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits)) != 0
+}
+
+func (s *setTrie) contains4(addr uint32) bool {
+	return C.bart_pset_contains4(s.handle(), C.uint32_t(addr)) != 0
+}
+
+func (s *setTrie) contains6(addr *[16]byte) bool {
+	return C.bart_pset_contains6(s.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0]))) != 0
+}
+
+// insertBulk adds the prefixes of routes with a single cgo call and
+// returns how many were missing, the values are ignored.
+func (s *setTrie) insertBulk(routes []route) int {
+	if len(routes) == 0 {
+		return 0
+	}
+	return int(C.bart_pset_add_bulk(s.handle(), (*C.bart_route_t)(unsafe.Pointer(&routes[0])), C.size_t(len(routes))))
+}
+
+// deleteBulk removes the prefixes of routes with a single cgo call and
+// returns how many were present.
+func (s *setTrie) deleteBulk(routes []route) int {
+	if len(routes) == 0 {
+		return 0
+	}
+	return int(C.bart_pset_remove_bulk(s.handle(), (*C.bart_route_t)(unsafe.Pointer(&routes[0])), C.size_t(len(routes))))
+}
+
+// walkSorted is trie.walkSorted for a set, the routes have value 0.
+func (s *setTrie) walkSorted(fn func(batch []route) bool) {
+	buf := make([]route, batchSize)
+	h := cgo.NewHandle(fn)
+	defer h.Delete()
+	C.bart_pset_walk_sorted(s.handle(), (*C.bart_route_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)),
+		C.bart_walk_fn(C.zartWalk), unsafe.Pointer(&h))
+}
+
+// union adds the prefixes of o to s.
+func (s *setTrie) union(o *setTrie) {
+	C.bart_pset_union(s.handle(), o.handle())
+}
+
+// common stores the prefixes present in both sets in out and returns
+// their total number, like dump.
+func (s *setTrie) common(o *setTrie, out []route) int {
+	var ptr *C.bart_route_t
+	if len(out) > 0 {
+		ptr = (*C.bart_route_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_pset_common(s.handle(), o.handle(), ptr, C.size_t(len(out))))
+}
+
+// stats returns the statistics of both tries of the set, walked in C.
+func (s *setTrie) stats() (v4, v6 FamilyStats) {
+	var cs C.bart_stats_t
+	C.bart_pset_stats(s.handle(), &cs)
+	return familyStats(&cs.v4), familyStats(&cs.v6)
+}
@@ -0,0 +1,166 @@
+//go:build !cgo || purego
+
+package zart
+
+import (
+	"encoding/binary"
+	"runtime"
+
+	"github.com/gx14ac/zart/internal/bart"
+)
+
+// setTrie is the pure-Go counterpart of the C prefix set in set_cgo.go,
+// a trie with struct{} values whose nodes keep no value slots.
+type setTrie struct {
+	t       bart.Trie[struct{}]
+	closed  bool
+	cleanup runtime.Cleanup
+}
+
+// trackSet reports s if it is never closed, like track.
+func trackSet(s *setTrie) *setTrie {
+	if stack := allocStack(); stack != "" {
+		s.cleanup = runtime.AddCleanup(s, reportLeak, stack)
+	}
+	return s
+}
+
+// newSetTrie ignores the arena option like newTrie.
+func newSetTrie(o options) *setTrie {
+	return trackSet(&setTrie{t: bart.Trie[struct{}]{Compress: o.compress}})
+}
+
+func (s *setTrie) clone() *setTrie {
+	return trackSet(&setTrie{t: *s.live().Clone()})
+}
+
+func (s *setTrie) close() {
+	s.cleanup.Stop()
+	s.t = bart.Trie[struct{}]{}
+	s.closed = true
+}
+
+// live returns the trie, it panics with ErrClosed once the set is closed
+// like trie.live.
+func (s *setTrie) live() *bart.Trie[struct{}] {
+	if s.closed {
+		panic(ErrClosed)
+	}
+	return &s.t
+}
+
+func (s *setTrie) size4() int {
+	return s.live().Size4()
+}
+
+func (s *setTrie) size6() int {
+	return s.live().Size6()
+}
+
+func (s *setTrie) add4(addr uint32, bits uint8) bool {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	_, existed := s.live().Insert(a[:], int(bits), struct{}{})
+	return !existed
+}
+
+func (s *setTrie) add6(addr *[16]byte, bits uint8) bool {
+	_, existed := s.live().Insert(addr[:], int(bits), struct{}{})
+	return !existed
+}
+
+func (s *setTrie) remove4(addr uint32, bits uint8) bool {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	_, ok := s.live().Delete(a[:], int(bits))
+	return ok
+}
+
+func (s *setTrie) remove6(addr *[16]byte, bits uint8) bool {
+	_, ok := s.live().Delete(addr[:], int(bits))
+	return ok
+}
+
+func (s *setTrie) has4(addr uint32, bits uint8) bool {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	_, ok := s.live().Get(a[:], int(bits))
+	return ok
+}
+
+func (s *setTrie) has6(addr *[16]byte, bits uint8) bool {
+	_, ok := s.live().Get(addr[:], int(bits))
+	return ok
+}
+
+func (s *setTrie) contains4(addr uint32) bool {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return s.live().Contains(a[:])
+}
+
+func (s *setTrie) contains6(addr *[16]byte) bool {
+	return s.live().Contains(addr[:])
+}
+
+func (s *setTrie) insertBulk(routes []route) int {
+	n := 0
+	for i := range routes {
+		octets := routes[i].addr[:]
+		if routes[i].is4 != 0 {
+			octets = routes[i].addr[:4]
+		}
+		if _, existed := s.live().Insert(octets, int(routes[i].bits), struct{}{}); !existed {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *setTrie) deleteBulk(routes []route) int {
+	n := 0
+	for i := range routes {
+		octets := routes[i].addr[:]
+		if routes[i].is4 != 0 {
+			octets = routes[i].addr[:4]
+		}
+		if _, ok := s.live().Delete(octets, int(routes[i].bits)); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *setTrie) walkSorted(fn func(batch []route) bool) {
+	batches(func(yield func(octets []byte, bits int, val uint64) bool) {
+		s.live().WalkSorted(func(octets []byte, bits int, _ struct{}) bool {
+			return yield(octets, bits, 0)
+		})
+	}, fn)
+}
+
+func (s *setTrie) union(o *setTrie) {
+	o.live().Walk(func(octets []byte, bits int, _ struct{}) bool {
+		s.live().Insert(octets, bits, struct{}{})
+		return true
+	})
+}
+
+func (s *setTrie) common(o *setTrie, out []route) int {
+	n := 0
+	bart.Diff(s.live(), o.live(), false, func(octets []byte, bits int, _, _ struct{}, inS, inO bool) bool {
+		if !inS || !inO {
+			return true
+		}
+		if n < len(out) {
+			out[n] = octetsRoute(octets, bits, 0)
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+func (s *setTrie) stats() (v4, v6 FamilyStats) {
+	return familyStats(s.live().Stats(true)), familyStats(s.live().Stats(false))
+}
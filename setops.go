@@ -0,0 +1,57 @@
+package zart
+
+// Subtract removes from t every prefix that is also in other and returns
+// how many were removed. With a non-nil equal a prefix is only removed
+// if its values in both tables are equal by it, for withdrawing exactly
+// the routes of a withdraw set; a nil equal removes them whatever their
+// values. other is left as it is.
+//
+// The prefixes common to both tables are found in a single walk of both
+// tries side by side that skips the subtrees only one of them has, then
+// removed with a single call into the trie, forgetting their TTLs and
+// tags. A watched table deletes them one by one as by Delete instead.
+func (t *Table[V]) Subtract(other *Table[V], equal func(a, b V) bool) int {
+	t.mutable()
+	items := t.commonItems(other)
+	routes := make([]route, 0, len(items))
+	for _, d := range items {
+		if equal == nil || equal(t.vals.get(d.route.val), other.vals.get(d.theirs)) {
+			routes = append(routes, d.route)
+		}
+	}
+	if t.watch != nil {
+		n := 0
+		for i := range routes {
+			if t.Delete(routes[i].prefix()) {
+				n++
+			}
+		}
+		return n
+	}
+	t.trie.deleteBulk(routes)
+	t.deleted(routes)
+	return len(routes)
+}
+
+// commonItems returns the prefixes present in both t and other, each with
+// the slot of its value in t and in other.
+func (t *Table[V]) commonItems(other *Table[V]) []diffItem {
+	hint := min(t.Size(), other.Size())
+	return fill(hint, func(out []diffItem) int { return t.trie.common(other.trie, out) })
+}
+
+// Intersect returns a new table, configured like t, of the prefixes
+// present in both t and other, each with combine of its value in t and
+// in other. Neither input is changed. The common prefixes come from the
+// walk of Subtract and the result is built in a single bulk insert.
+func (t *Table[V]) Intersect(other *Table[V], combine func(a, b V) V) *Table[V] {
+	x := (&Table[V]{trie: newTrie(t.opts), vals: new(registry[V]), opts: t.opts}).audited()
+	items := t.commonItems(other)
+	routes := make([]route, len(items))
+	for i := range items {
+		routes[i] = items[i].route
+		routes[i].val = x.vals.alloc(combine(t.vals.get(items[i].route.val), other.vals.get(items[i].theirs)))
+	}
+	x.trie.insertBulk(routes, make([]uint32, len(routes)))
+	return x
+}
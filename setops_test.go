@@ -0,0 +1,140 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestSubtract(t *testing.T) {
+	a, b := New[string](), New[string]()
+	defer a.Close()
+	defer b.Close()
+	a.Insert(mpp("10.0.0.0/8"), "x")
+	a.Insert(mpp("10.1.0.0/16"), "y")
+	a.Insert(mpp("10.1.2.3/32"), "h")
+	a.Insert(mpp("2001:db8::/32"), "z")
+	b.Insert(mpp("10.1.0.0/16"), "w")
+	b.Insert(mpp("10.1.2.3/32"), "h")
+	b.Insert(mpp("2001:db8::/32"), "z")
+	b.Insert(mpp("192.168.0.0/24"), "v")
+
+	eq := func(x, y string) bool { return x == y }
+	if n := a.Subtract(b, eq); n != 2 {
+		t.Errorf("Subtract with equal = %d, want 2", n)
+	}
+	if _, ok := a.Get(mpp("10.1.2.3/32")); ok {
+		t.Error("10.1.2.3/32 with an equal value survived")
+	}
+	if v, ok := a.Get(mpp("10.1.0.0/16")); !ok || v != "y" {
+		t.Errorf("10.1.0.0/16 = %q, %v, want the unequal value kept", v, ok)
+	}
+	if n := a.Subtract(b, nil); n != 1 || a.Size() != 1 {
+		t.Errorf("Subtract without equal = %d, size %d, want 1, 1", n, a.Size())
+	}
+	if b.Size() != 4 {
+		t.Errorf("other changed to size %d", b.Size())
+	}
+	if n := b.Subtract(b, nil); n != 4 || b.Size() != 0 {
+		t.Errorf("Subtract of itself = %d, size %d", n, b.Size())
+	}
+}
+
+func TestSubtractRandom(t *testing.T) {
+	prng := rand.New(rand.NewPCG(96, 96))
+	a, b := New[int](), New[int]()
+	defer a.Close()
+	defer b.Close()
+	pfxs := randomPrefixes(prng, 3000)
+	for i, pfx := range pfxs {
+		if i%3 != 0 {
+			a.Insert(pfx, i%4)
+		}
+		if i%2 == 0 {
+			b.Insert(pfx, i%5)
+		}
+	}
+	want := a.Clone()
+	defer want.Close()
+	for pfx, v := range b.All() {
+		if w, ok := want.Get(pfx); ok && w == v {
+			want.Delete(pfx)
+		}
+	}
+	a.Subtract(b, func(x, y int) bool { return x == y })
+	if !a.Equal(want, func(x, y int) bool { return x == y }) {
+		t.Errorf("Subtract left %d prefixes, want %d", a.Size(), want.Size())
+	}
+}
+
+func TestSubtractForgets(t *testing.T) {
+	const feed Tag = "feed"
+	for _, watched := range []bool{false, true} {
+		a, b := New[int](), New[int]()
+		events := 0
+		if watched {
+			a.hook(func(ev Event[int]) {
+				if ev.Kind == EventDelete {
+					events++
+				}
+			})
+		}
+		a.InsertTagged(mpp("10.0.0.0/8"), 1, feed)
+		a.InsertWithTTL(mpp("10.1.0.0/16"), 2, time.Hour)
+		a.Insert(mpp("192.168.0.0/24"), 3)
+		b.Insert(mpp("10.0.0.0/8"), 1)
+		b.Insert(mpp("10.1.0.0/16"), 2)
+
+		if n := a.Subtract(b, nil); n != 2 || a.Size() != 1 {
+			t.Errorf("watched %v: Subtract = %d, size %d", watched, n, a.Size())
+		}
+		if watched && events != 2 {
+			t.Errorf("watched Subtract sent %d delete events, want 2", events)
+		}
+		a.Insert(mpp("10.0.0.0/8"), 4)
+		a.Insert(mpp("10.1.0.0/16"), 5)
+		if len(a.Tags(mpp("10.0.0.0/8"))) != 0 {
+			t.Errorf("watched %v: tags survived Subtract", watched)
+		}
+		if _, ok := a.TTL(mpp("10.1.0.0/16")); ok {
+			t.Errorf("watched %v: TTL survived Subtract", watched)
+		}
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	prng := rand.New(rand.NewPCG(97, 97))
+	a, b := New[int](), New[int]()
+	defer a.Close()
+	defer b.Close()
+	for i, pfx := range randomPrefixes(prng, 3000) {
+		if i%3 != 0 {
+			a.Insert(pfx, i)
+		}
+		if i%2 == 0 {
+			b.Insert(pfx, 10*i)
+		}
+	}
+	x := a.Intersect(b, func(x, y int) int { return x + y })
+	defer x.Close()
+
+	n := 0
+	for pfx, v := range a.All() {
+		w, ok := b.Get(pfx)
+		got, in := x.Get(pfx)
+		if in != ok || (ok && got != v+w) {
+			t.Fatalf("Intersect has %s = %d, %v, want %d, %v", pfx, got, in, v+w, ok)
+		}
+		if ok {
+			n++
+		}
+	}
+	if x.Size() != n {
+		t.Errorf("Intersect size = %d, want %d", x.Size(), n)
+	}
+	if a.Size() == 0 || b.Size() == 0 {
+		t.Fatal("inputs changed")
+	}
+}
@@ -0,0 +1,68 @@
+package zart
+
+// Shape describes the structure of the trie of a table, for diagnosing
+// lookup performance and the effect of compression, see Table.Shape.
+type Shape struct {
+	IPv4, IPv6 FamilyShape
+}
+
+// FamilyShape describes the trie of one address family.
+//
+// PrefixFill and ChildFill are histograms of the nodes by their number n
+// of stored prefixes or children: bucket 0 counts the nodes with n == 0,
+// bucket b > 0 those with 2^(b-1) <= n < 2^b, the last bucket of
+// ChildFill the nodes with all 256 children.
+type FamilyShape struct {
+	// Levels holds the nodes per depth, down to the deepest node. A
+	// lookup visits at most one node per level.
+	Levels []LevelShape
+
+	PrefixFill [9]int
+	ChildFill  [10]int
+}
+
+// LevelShape sums the nodes at one depth of the trie. Children counts
+// all child slots in use, Leaves and Fringes the path compressed ones
+// among them; the pure-Go backend has them with WithPathCompression
+// only.
+type LevelShape struct {
+	Nodes    int
+	Prefixes int
+	Children int
+	Leaves   int
+	Fringes  int
+}
+
+// PrefixFill returns the average share of the 255 prefix slots used by
+// the nodes of the level.
+func (l LevelShape) PrefixFill() float64 {
+	if l.Nodes == 0 {
+		return 0
+	}
+	return float64(l.Prefixes) / float64(255*l.Nodes)
+}
+
+// ChildFill returns the average share of the 256 child slots used by
+// the nodes of the level.
+func (l LevelShape) ChildFill() float64 {
+	if l.Nodes == 0 {
+		return 0
+	}
+	return float64(l.Children) / float64(256*l.Nodes)
+}
+
+// Shape walks the trie and reports its structure. Like Stats it takes
+// time linear in the number of nodes.
+func (t *Table[V]) Shape() Shape {
+	v4, v6 := t.trie.shape()
+	return Shape{IPv4: v4, IPv6: v6}
+}
+
+// trimShape drops the empty levels below the deepest node.
+func trimShape(levels []LevelShape) []LevelShape {
+	n := len(levels)
+	for n > 0 && levels[n-1].Nodes == 0 {
+		n--
+	}
+	return levels[:n:n]
+}
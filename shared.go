@@ -0,0 +1,489 @@
+package zart
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Shared table format, all integers little endian, sections aligned to 8
+// bytes:
+//
+//	magic     "ZARTSHRD"
+//	header    version, node count, value id count, child count,
+//	          payload count, IPv4 root, IPv6 root, IPv4 size, IPv6
+//	          size, padding; uint32 each
+//	nodes     node count times: prefix bitset [4]uint64, child bitset
+//	          [4]uint64, value base uint32, child base uint32
+//	value ids uint32, the payload of every prefix of the nodes in order
+//	children  uint32, the node of every child of the nodes in order
+//	offsets   payload count + 1 times uint32, the payloads' start in blob
+//	blob      the payloads, encoded as for MarshalBinary
+//	checksum  uint32, CRC-32C of everything before it
+//
+// The nodes are a multibit trie of 8-bit strides, like the trie of a
+// Table but without any pointers: a node holds the prefixes of 0 to 7
+// bits within its stride, prefix idx (1<<bits)+(octet>>(8-bits)) of
+// the complete binary tree, and the children for the 256 octets. The
+// prefixes and children are stored in the value id and child sections at
+// the base of the node plus their rank in the bitset, and payloads are
+// deduplicated.
+const (
+	sharedMagic   = "ZARTSHRD"
+	sharedVersion = 1
+
+	sharedHeader = 8 + 10*4
+	sharedNode   = 8*8 + 2*4
+	noNode       = ^uint32(0)
+)
+
+// BuildShared writes the routes of the table to the file at path in a
+// read-only format that OpenShared maps into memory, for sharing one
+// large table, like a geolocation or origin AS table, between the
+// processes of a host without copying it into each of their heaps.
+//
+// The payloads are encoded as for MarshalBinary and must be of a type
+// it accepts. The file is written next to path and renamed into place,
+// processes that have the previous version open keep reading it.
+func (t *Table[V]) BuildShared(path string) error {
+	if err := t.usable(); err != nil {
+		return err
+	}
+	data, err := t.encodeShared()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// encodeShared returns the table in the shared format.
+func (t *Table[V]) encodeShared() ([]byte, error) {
+	var b sharedBuilder
+	ids := map[string]uint32{}
+	var payload []byte
+	roots := [2]*sharedBuildNode{}
+	for pfx, val := range t.All() {
+		var err error
+		if payload, err = appendPayload(payload[:0], val); err != nil {
+			return nil, err
+		}
+		id, ok := ids[string(payload)]
+		if !ok {
+			id = uint32(len(b.offsets))
+			ids[string(payload)] = id
+			b.offsets = append(b.offsets, uint32(len(b.blob)))
+			b.blob = append(b.blob, payload...)
+		}
+		fam := family(pfx.Addr().Is4())
+		if roots[fam] == nil {
+			roots[fam] = &sharedBuildNode{}
+		}
+		roots[fam].insert(pfx, id)
+	}
+	b.offsets = append(b.offsets, uint32(len(b.blob)))
+
+	root4, root6 := b.flatten(roots[0]), b.flatten(roots[1])
+	return b.encode(root4, root6, uint32(t.Size4()), uint32(t.Size6())), nil
+}
+
+// sharedBuildNode is a trie node of BuildShared before flattening.
+type sharedBuildNode struct {
+	pfxs     [256]uint32 // value id + 1 by prefix idx, 0 for none
+	children [256]*sharedBuildNode
+}
+
+func (n *sharedBuildNode) insert(pfx netip.Prefix, id uint32) {
+	addr := pfx.Addr().AsSlice()
+	depth, l := pfx.Bits()/8, pfx.Bits()%8
+	for d := range depth {
+		c := n.children[addr[d]]
+		if c == nil {
+			c = &sharedBuildNode{}
+			n.children[addr[d]] = c
+		}
+		n = c
+	}
+	idx := 1
+	if l > 0 {
+		idx = 1<<l + int(addr[depth]>>(8-l))
+	}
+	n.pfxs[idx] = id + 1
+}
+
+type sharedBuilder struct {
+	nodes    [][8]uint64
+	bases    [][2]uint32
+	valueIDs []uint32
+	children []uint32
+	offsets  []uint32
+	blob     []byte
+}
+
+// flatten appends the subtree of n in breadth-first order and returns
+// the number of its root.
+func (b *sharedBuilder) flatten(n *sharedBuildNode) uint32 {
+	if n == nil {
+		return noNode
+	}
+	root := uint32(len(b.nodes))
+	queue := []*sharedBuildNode{n}
+	b.nodes = append(b.nodes, [8]uint64{})
+	b.bases = append(b.bases, [2]uint32{})
+	for i := 0; i < len(queue); i++ {
+		n, num := queue[i], root+uint32(i)
+		b.bases[num] = [2]uint32{uint32(len(b.valueIDs)), uint32(len(b.children))}
+		for idx, v := range n.pfxs {
+			if v != 0 {
+				b.nodes[num][idx/64] |= 1 << (idx % 64)
+				b.valueIDs = append(b.valueIDs, v-1)
+			}
+		}
+		for octet, c := range n.children {
+			if c != nil {
+				b.nodes[num][4+octet/64] |= 1 << (octet % 64)
+				b.children = append(b.children, root+uint32(len(queue)))
+				queue = append(queue, c)
+				b.nodes = append(b.nodes, [8]uint64{})
+				b.bases = append(b.bases, [2]uint32{})
+			}
+		}
+	}
+	return root
+}
+
+func (b *sharedBuilder) encode(root4, root6, size4, size6 uint32) []byte {
+	le := binary.LittleEndian
+	data := append([]byte(nil), sharedMagic...)
+	for _, v := range []uint32{sharedVersion, uint32(len(b.nodes)), uint32(len(b.valueIDs)), uint32(len(b.children)),
+		uint32(len(b.offsets) - 1), root4, root6, size4, size6, 0} {
+		data = le.AppendUint32(data, v)
+	}
+	for i, n := range b.nodes {
+		for _, w := range n {
+			data = le.AppendUint64(data, w)
+		}
+		data = le.AppendUint32(data, b.bases[i][0])
+		data = le.AppendUint32(data, b.bases[i][1])
+	}
+	for _, sec := range [][]uint32{b.valueIDs, b.children, b.offsets} {
+		for _, v := range sec {
+			data = le.AppendUint32(data, v)
+		}
+		data = pad8(data)
+	}
+	data = append(data, b.blob...)
+	return le.AppendUint32(data, crc32.Checksum(data, castagnoli))
+}
+
+func pad8(b []byte) []byte {
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// SharedTable is a read-only table mapped from a file written by
+// BuildShared. The trie stays in the file mapping, shared with all
+// processes mapping the file, only the distinct payloads are decoded
+// into the heap. It is safe for concurrent use.
+//
+// On systems without mmap the file is read into memory instead.
+type SharedTable[V any] struct {
+	data     []byte
+	unmap    func() error
+	nodes    []byte
+	valueIDs []byte
+	children []byte
+	roots    [2]uint32
+	size     [2]int
+	vals     []V
+}
+
+// OpenShared maps the table in the file at path, written by BuildShared.
+// It fails for files that are truncated or corrupt, with a checksum
+// mismatch, which means it reads the whole file once.
+func OpenShared[V any](path string) (*SharedTable[V], error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &SharedTable[V]{data: data, unmap: unmap}
+	if err := s.parse(); err != nil {
+		unmap()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *SharedTable[V]) parse() error {
+	le := binary.LittleEndian
+	data := s.data
+	if len(data) < sharedHeader+4 || string(data[:8]) != sharedMagic {
+		return errCorrupt
+	}
+	body := data[:len(data)-4]
+	if crc32.Checksum(body, castagnoli) != le.Uint32(data[len(body):]) {
+		return errCorrupt
+	}
+	var hdr [10]uint32
+	for i := range hdr {
+		hdr[i] = le.Uint32(data[8+4*i:])
+	}
+	if hdr[0] != sharedVersion {
+		return fmt.Errorf("zart: shared table version %d not supported", hdr[0])
+	}
+	nodes, nids, nchildren, npayloads := int(hdr[1]), int(hdr[2]), int(hdr[3]), int(hdr[4])
+	s.roots = [2]uint32{hdr[5], hdr[6]}
+	s.size = [2]int{int(hdr[7]), int(hdr[8])}
+
+	rest := body[sharedHeader:]
+	take := func(n int) ([]byte, bool) {
+		n = (n + 7) &^ 7
+		if n > len(rest) || n < 0 {
+			return nil, false
+		}
+		sec := rest[:n]
+		rest = rest[n:]
+		return sec, true
+	}
+	var ok [4]bool
+	var offsets []byte
+	s.nodes, ok[0] = take(nodes * sharedNode)
+	s.valueIDs, ok[1] = take(nids * 4)
+	s.children, ok[2] = take(nchildren * 4)
+	offsets, ok[3] = take((npayloads + 1) * 4)
+	if ok != [4]bool{true, true, true, true} {
+		return errCorrupt
+	}
+	for _, root := range s.roots {
+		if root != noNode && int(root) >= nodes {
+			return errCorrupt
+		}
+	}
+	for i := range nodes {
+		vb, cb := s.bases(uint32(i))
+		if int(vb) > nids || int(cb) > nchildren {
+			return errCorrupt
+		}
+	}
+	for i := range nchildren {
+		if int(le.Uint32(s.children[4*i:])) >= nodes {
+			return errCorrupt
+		}
+	}
+	for i := range nids {
+		if int(le.Uint32(s.valueIDs[4*i:])) >= npayloads {
+			return errCorrupt
+		}
+	}
+
+	s.vals = make([]V, npayloads)
+	for i := range s.vals {
+		start, end := le.Uint32(offsets[4*i:]), le.Uint32(offsets[4*i+4:])
+		if start > end || int(end) > len(rest) {
+			return errCorrupt
+		}
+		if _, err := decodePayload(rest[start:end], &s.vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close unmaps the file. The table must not be used afterwards.
+func (s *SharedTable[V]) Close() error {
+	if s.unmap == nil {
+		return nil
+	}
+	err := s.unmap()
+	s.unmap, s.data, s.nodes, s.valueIDs, s.children = nil, nil, nil, nil, nil
+	return err
+}
+
+// Size returns the number of prefixes.
+func (s *SharedTable[V]) Size() int {
+	return s.size[0] + s.size[1]
+}
+
+// Contains reports whether any prefix matches addr.
+func (s *SharedTable[V]) Contains(addr netip.Addr) bool {
+	_, _, ok := s.lookup(addr)
+	return ok
+}
+
+// Lookup performs a longest-prefix match for addr.
+func (s *SharedTable[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	id, _, ok := s.lookup(addr)
+	if !ok {
+		return val, false
+	}
+	return s.vals[id], true
+}
+
+// LookupPrefix is Lookup also returning the matching prefix.
+func (s *SharedTable[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	id, bits, ok := s.lookup(addr)
+	if !ok {
+		return pfx, val, false
+	}
+	pfx, _ = addr.Prefix(bits)
+	return pfx, s.vals[id], true
+}
+
+// lookup returns the payload id and length of the longest prefix
+// matching addr.
+func (s *SharedTable[V]) lookup(addr netip.Addr) (id uint32, length int, ok bool) {
+	if !addr.IsValid() {
+		return 0, 0, false
+	}
+	addr = addr.WithZone("")
+	a := addr.As16()
+	octets := a[:]
+	if addr.Is4() {
+		octets = octets[12:]
+	}
+	node := s.roots[family(addr.Is4())]
+	if node == noNode {
+		return 0, 0, false
+	}
+	anc := ancestors()
+	for depth := 0; ; depth++ {
+		mask := [4]uint64{1 << 1}
+		if depth < len(octets) {
+			mask = anc[octets[depth]]
+		}
+		// the highest idx is the longest prefix
+		for w := 3; w >= 0; w-- {
+			if m := s.word(node, w) & mask[w]; m != 0 {
+				idx := w*64 + 63 - bits.LeadingZeros64(m)
+				id, length, ok = s.valueID(node, idx), depth*8+bits.Len(uint(idx))-1, true
+				break
+			}
+		}
+		if depth == len(octets) {
+			return id, length, ok
+		}
+		octet := int(octets[depth])
+		if s.word(node, 4+octet/64)&(1<<(octet%64)) == 0 {
+			return id, length, ok
+		}
+		node = s.child(node, octet)
+	}
+}
+
+// entries returns the routes of the table, IPv4 first, for loading them
+// into a Table.
+func (s *SharedTable[V]) entries() []RouteEntry[V] {
+	out := make([]RouteEntry[V], 0, s.Size())
+	for fam, root := range s.roots {
+		if root != noNode {
+			out = s.appendEntries(out, root, [16]byte{}, 0, fam == 0)
+		}
+	}
+	return out
+}
+
+// appendEntries appends the routes of the subtree of node at depth, with
+// the octets of path above it, in the order of the file.
+func (s *SharedTable[V]) appendEntries(out []RouteEntry[V], node uint32, path [16]byte, depth int, is4 bool) []RouteEntry[V] {
+	maxDepth := 16
+	if is4 {
+		maxDepth = 4
+	}
+	vbase, cbase := s.bases(node)
+	k := 0
+	for idx := 1; idx < 256; idx++ {
+		if s.word(node, idx/64)&(1<<(idx%64)) == 0 {
+			continue
+		}
+		l := bits.Len(uint(idx)) - 1
+		p := path
+		if depth < maxDepth {
+			p[depth] = byte(idx-1<<l) << (8 - l)
+		}
+		addr := netip.AddrFrom16(p)
+		if is4 {
+			addr = netip.AddrFrom4([4]byte(p[:4]))
+		}
+		id := binary.LittleEndian.Uint32(s.valueIDs[4*(int(vbase)+k):])
+		out = append(out, RouteEntry[V]{netip.PrefixFrom(addr, depth*8+l), s.vals[id]})
+		k++
+	}
+	// the depth bounds the walk of a corrupt file with a cycle
+	if depth == maxDepth {
+		return out
+	}
+	k = 0
+	for octet := range 256 {
+		if s.word(node, 4+octet/64)&(1<<(octet%64)) == 0 {
+			continue
+		}
+		p := path
+		p[depth] = byte(octet)
+		child := binary.LittleEndian.Uint32(s.children[4*(int(cbase)+k):])
+		out = s.appendEntries(out, child, p, depth+1, is4)
+		k++
+	}
+	return out
+}
+
+// word returns word w of the bitsets of node, the prefix bitset first.
+func (s *SharedTable[V]) word(node uint32, w int) uint64 {
+	return binary.LittleEndian.Uint64(s.nodes[int(node)*sharedNode+8*w:])
+}
+
+func (s *SharedTable[V]) bases(node uint32) (values, children uint32) {
+	b := s.nodes[int(node)*sharedNode+64:]
+	return binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+}
+
+// rank returns the number of bits set in the bitset of 4 words at w0
+// below bit i.
+func (s *SharedTable[V]) rank(node uint32, w0, i int) int {
+	n := 0
+	for w := range i / 64 {
+		n += bits.OnesCount64(s.word(node, w0+w))
+	}
+	return n + bits.OnesCount64(s.word(node, w0+i/64)&(1<<(i%64)-1))
+}
+
+func (s *SharedTable[V]) valueID(node uint32, idx int) uint32 {
+	base, _ := s.bases(node)
+	return binary.LittleEndian.Uint32(s.valueIDs[4*(int(base)+s.rank(node, 0, idx)):])
+}
+
+func (s *SharedTable[V]) child(node uint32, octet int) uint32 {
+	_, base := s.bases(node)
+	return binary.LittleEndian.Uint32(s.children[4*(int(base)+s.rank(node, 4, octet)):])
+}
+
+// ancestors returns for every octet the prefix idxs of 0 to 7 bits
+// covering it.
+var ancestors = sync.OnceValue(func() *[256][4]uint64 {
+	var anc [256][4]uint64
+	for octet := range 256 {
+		for l := range 8 {
+			idx := 1<<l + octet>>(8-l)
+			anc[octet][idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return &anc
+})
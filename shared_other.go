@@ -0,0 +1,14 @@
+//go:build !unix
+
+package zart
+
+import "os"
+
+// mapFile reads the file at path, for systems without mmap.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
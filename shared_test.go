@@ -0,0 +1,106 @@
+package zart
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestShared(t *testing.T) {
+	prng := rand.New(rand.NewPCG(69, 69))
+	tbl := New[string]()
+	for i, pfx := range randomPrefixes(prng, 5000) {
+		tbl.Insert(pfx, "AS"+strconv.Itoa(i%50))
+	}
+	tbl.Insert(mpp("0.0.0.0/0"), "default")
+	tbl.Insert(mpp("15.1.2.3/32"), "host")
+	tbl.Insert(mpp("2001:db8::1/128"), "host6")
+
+	path := filepath.Join(t.TempDir(), "routes.shared")
+	if err := tbl.BuildShared(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenShared[string](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Size() != tbl.Size() {
+		t.Errorf("Size = %d, want %d", s.Size(), tbl.Size())
+	}
+	if len(s.vals) != 53 {
+		t.Errorf("%d payloads, want 53 distinct", len(s.vals))
+	}
+
+	addrs := []netip.Addr{mpa("15.1.2.3"), mpa("2001:db8::1"), mpa("2001:db8::2"), mpa("200.1.1.1")}
+	for range 20000 {
+		pfx := randomPrefixes(prng, 1)[0]
+		a := pfx.Addr().AsSlice()
+		for i := pfx.Bits() / 8; i < len(a); i++ {
+			a[i] = byte(prng.UintN(256))
+		}
+		addr, _ := netip.AddrFromSlice(a)
+		addrs = append(addrs, addr)
+	}
+	for _, addr := range addrs {
+		wantPfx, wantVal, wantOK := tbl.LookupPrefix(addr)
+		pfx, val, ok := s.LookupPrefix(addr)
+		if pfx != wantPfx || val != wantVal || ok != wantOK {
+			t.Fatalf("LookupPrefix(%s) = %s, %q, %v, want %s, %q, %v", addr, pfx, val, ok, wantPfx, wantVal, wantOK)
+		}
+		if s.Contains(addr) != wantOK {
+			t.Fatalf("Contains(%s) = %v", addr, !wantOK)
+		}
+	}
+
+	// an IPv4 only table has no IPv6 routes
+	v4 := New[int]()
+	v4.Insert(mpp("10.0.0.0/8"), 1)
+	if err := v4.BuildShared(path); err != nil {
+		t.Fatal(err)
+	}
+	s4, err := OpenShared[int](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s4.Close()
+	if _, ok := s4.Lookup(mpa("2001:db8::1")); ok {
+		t.Errorf("IPv6 lookup in an IPv4 table matched")
+	}
+	if val, ok := s4.Lookup(mpa("10.1.1.1")); !ok || val != 1 {
+		t.Errorf("Lookup(10.1.1.1) = %d, %v", val, ok)
+	}
+}
+
+func TestSharedCorrupt(t *testing.T) {
+	tbl := New[int]()
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	path := filepath.Join(t.TempDir(), "routes.shared")
+	if err := tbl.BuildShared(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 1
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenShared[int](path); !errors.Is(err, errCorrupt) {
+		t.Errorf("OpenShared of a corrupt file = %v", err)
+	}
+	if _, err := OpenShared[int](filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenShared of a missing file = %v", err)
+	}
+
+	bad := New[map[string]int]()
+	bad.Insert(mpp("10.0.0.0/8"), nil)
+	if err := bad.BuildShared(path); err == nil {
+		t.Errorf("BuildShared of unencodable payloads succeeded")
+	}
+}
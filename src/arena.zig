@@ -0,0 +1,236 @@
+// arena.zig - per-table slab allocator
+//
+// An Arena hands out the memory of one table and its persistent versions
+// from a few large blocks. Small allocations are carved from the blocks
+// in power of two size classes and recycled through a free list per
+// class, so inserts and deletes stop going to malloc for every node.
+// Destroying the arena returns its blocks at once, without walking the
+// trie node by node.
+//
+// An Arena is not thread-safe, like the tables using it.
+
+const std = @import("std");
+const Allocator = std.mem.Allocator;
+const Alignment = std.mem.Alignment;
+
+/// default_block_size is used for a block size of 0.
+pub const default_block_size: usize = 256 * 1024;
+
+/// The size classes are 16 bytes up to max_class, larger allocations go
+/// to the backing allocator one by one.
+const min_class_log2 = 4;
+const num_classes = 9;
+const max_class: usize = 1 << (min_class_log2 + num_classes - 1);
+
+/// Slots are aligned to their size up to max_align, which covers the
+/// cache line aligned bitsets of the nodes.
+const max_align: usize = 64;
+
+pub const Arena = struct {
+    backing: Allocator,
+    block_size: usize,
+
+    /// refs counts the tables using the arena, see retain and release.
+    refs: usize = 1,
+
+    /// bytes is the memory taken from the backing allocator.
+    bytes: usize = 0,
+
+    blocks: ?*Block = null,
+    large: ?*Large = null,
+    free: [num_classes]?*Slot = [_]?*Slot{null} ** num_classes,
+
+    /// the unused rest of the newest block
+    cur: usize = 0,
+    end: usize = 0,
+
+    const Block = struct {
+        next: ?*Block,
+        len: usize,
+    };
+
+    const Slot = struct {
+        next: ?*Slot,
+    };
+
+    const Large = struct {
+        prev: ?*Large,
+        next: ?*Large,
+        len: usize,
+    };
+
+    const block_header = std.mem.alignForward(usize, @sizeOf(Block), max_align);
+
+    /// create returns an arena taking blocks of block_size bytes from
+    /// backing, or the default_block_size for 0.
+    pub fn create(backing: Allocator, block_size: usize) !*Arena {
+        const self = try backing.create(Arena);
+        const size = if (block_size == 0) default_block_size else block_size;
+        self.* = .{
+            .backing = backing,
+            .block_size = @max(size, block_header + max_class),
+        };
+        return self;
+    }
+
+    /// retain adds a table using the arena.
+    pub fn retain(self: *Arena) *Arena {
+        self.refs += 1;
+        return self;
+    }
+
+    /// release drops a table using the arena, the last one frees all
+    /// memory allocated from it.
+    pub fn release(self: *Arena) void {
+        if (self.refs > 1) {
+            self.refs -= 1;
+            return;
+        }
+        var b = self.blocks;
+        while (b) |blk| {
+            b = blk.next;
+            const mem: [*]align(max_align) u8 = @ptrCast(@alignCast(blk));
+            self.backing.free(mem[0..blk.len]);
+        }
+        var l = self.large;
+        while (l) |big| {
+            l = big.next;
+            const mem: [*]align(max_align) u8 = @ptrCast(@alignCast(big));
+            self.backing.free(mem[0..big.len]);
+        }
+        self.backing.destroy(self);
+    }
+
+    pub fn allocator(self: *Arena) Allocator {
+        return .{ .ptr = self, .vtable = &vtable };
+    }
+
+    const vtable = Allocator.VTable{
+        .alloc = alloc,
+        .resize = resize,
+        .remap = remap,
+        .free = free,
+    };
+
+    /// class returns the size class of an allocation, null for the large
+    /// ones.
+    fn class(len: usize, alignment: Alignment) ?usize {
+        const a = alignment.toByteUnits();
+        if (a > max_align) return null;
+        const size = @max(len, a, @as(usize, 1) << min_class_log2);
+        if (size > max_class) return null;
+        return std.math.log2_int_ceil(usize, size) - min_class_log2;
+    }
+
+    fn alloc(ctx: *anyopaque, len: usize, alignment: Alignment, ret_addr: usize) ?[*]u8 {
+        _ = ret_addr;
+        const self: *Arena = @ptrCast(@alignCast(ctx));
+        const c = class(len, alignment) orelse return self.allocLarge(len, alignment);
+        if (self.free[c]) |slot| {
+            self.free[c] = slot.next;
+            return @ptrCast(slot);
+        }
+
+        const size = @as(usize, 1) << @intCast(c + min_class_log2);
+        var start = std.mem.alignForward(usize, self.cur, @min(size, max_align));
+        if (self.blocks == null or start + size > self.end) {
+            const mem = self.backing.alignedAlloc(u8, max_align, self.block_size) catch return null;
+            const blk: *Block = @ptrCast(mem.ptr);
+            blk.* = .{ .next = self.blocks, .len = mem.len };
+            self.blocks = blk;
+            self.bytes += mem.len;
+            self.cur = @intFromPtr(mem.ptr) + block_header;
+            self.end = @intFromPtr(mem.ptr) + mem.len;
+            start = self.cur;
+        }
+        self.cur = start + size;
+        return @ptrFromInt(start);
+    }
+
+    /// largeHeader returns the offset of a large allocation from the start
+    /// of its Large header.
+    fn largeHeader(alignment: Alignment) usize {
+        return std.mem.alignForward(usize, @sizeOf(Large), @max(alignment.toByteUnits(), max_align));
+    }
+
+    fn allocLarge(self: *Arena, len: usize, alignment: Alignment) ?[*]u8 {
+        const hdr = largeHeader(alignment);
+        const a = Alignment.fromByteUnits(@max(alignment.toByteUnits(), max_align));
+        const mem = self.backing.rawAlloc(hdr + len, a, @returnAddress()) orelse return null;
+        const big: *Large = @ptrCast(@alignCast(mem));
+        big.* = .{ .prev = null, .next = self.large, .len = hdr + len };
+        if (self.large) |next| next.prev = big;
+        self.large = big;
+        self.bytes += hdr + len;
+        return mem + hdr;
+    }
+
+    fn resize(ctx: *anyopaque, memory: []u8, alignment: Alignment, new_len: usize, ret_addr: usize) bool {
+        _ = ctx;
+        _ = ret_addr;
+        if (class(memory.len, alignment)) |c| {
+            // in place within the slot of the size class
+            return class(new_len, alignment) == c;
+        }
+        const big: *Large = @ptrFromInt(@intFromPtr(memory.ptr) - largeHeader(alignment));
+        return new_len + largeHeader(alignment) <= big.len and class(new_len, alignment) == null;
+    }
+
+    fn remap(ctx: *anyopaque, memory: []u8, alignment: Alignment, new_len: usize, ret_addr: usize) ?[*]u8 {
+        return if (resize(ctx, memory, alignment, new_len, ret_addr)) memory.ptr else null;
+    }
+
+    fn free(ctx: *anyopaque, memory: []u8, alignment: Alignment, ret_addr: usize) void {
+        _ = ret_addr;
+        const self: *Arena = @ptrCast(@alignCast(ctx));
+        if (class(memory.len, alignment)) |c| {
+            const slot: *Slot = @ptrCast(@alignCast(memory.ptr));
+            slot.next = self.free[c];
+            self.free[c] = slot;
+            return;
+        }
+        const hdr = largeHeader(alignment);
+        const big: *Large = @ptrFromInt(@intFromPtr(memory.ptr) - hdr);
+        if (big.prev) |prev| prev.next = big.next else self.large = big.next;
+        if (big.next) |next| next.prev = big.prev;
+        self.bytes -= big.len;
+        const mem: [*]u8 = @ptrCast(big);
+        const a = Alignment.fromByteUnits(@max(alignment.toByteUnits(), max_align));
+        self.backing.rawFree(mem[0..big.len], a, @returnAddress());
+    }
+};
+
+test "arena recycles slots by size class" {
+    const arena = try Arena.create(std.testing.allocator, 4096);
+    defer arena.release();
+    const a = arena.allocator();
+
+    const x = try a.alloc(u32, 10);
+    const y = try a.alloc(u32, 10);
+    try std.testing.expect(x.ptr != y.ptr);
+    a.free(x);
+    const z = try a.alloc(u32, 9);
+    try std.testing.expectEqual(@intFromPtr(x.ptr), @intFromPtr(z.ptr));
+
+    // aligned and large allocations, freed with the arena
+    const line = try a.alignedAlloc(u8, 64, 100);
+    try std.testing.expectEqual(@as(usize, 0), @intFromPtr(line.ptr) % 64);
+    const big = try a.alloc(u8, 3 * max_class);
+    @memset(big, 0xaa);
+    _ = try a.alloc(u64, 2000);
+    a.free(big);
+
+    // more than a block
+    for (0..1000) |_| _ = try a.create([4]u64);
+    try std.testing.expect(arena.bytes >= 1000 * 32);
+}
+
+test "arena refs" {
+    const arena = try Arena.create(std.testing.allocator, 0);
+    try std.testing.expectEqual(default_block_size, arena.block_size);
+    _ = arena.retain();
+    _ = try arena.allocator().alloc(u8, 100);
+    arena.release();
+    // the last release frees the arena, checked by the testing allocator
+    arena.release();
+}
@@ -1,6 +1,31 @@
 const std = @import("std");
+const builtin = @import("builtin");
 const lookup_tbl = @import("lookup_tbl.zig");
 
+/// runtime_popcnt is whether a build for a baseline x86-64 target, where
+/// @popCount is a sequence of shifts and masks, has to choose the POPCNT
+/// instruction at run time.
+pub const runtime_popcnt = builtin.cpu.arch == .x86_64 and
+    !std.Target.x86.featureSetHas(builtin.cpu.features, .popcnt);
+
+/// hw_popcnt is set through bart_set_cpu_features once the CPU is known to
+/// have POPCNT. It is only read in builds with runtime_popcnt.
+pub var hw_popcnt: bool = false;
+
+/// popCount64 counts the set bits of x, with POPCNT if the target has it
+/// or the CPU was found to have it.
+inline fn popCount64(x: u64) u32 {
+    if (comptime runtime_popcnt) {
+        if (hw_popcnt) {
+            return @intCast(asm ("popcntq %[x], %[ret]"
+                : [ret] "=r" (-> u64),
+                : [x] "r" (x),
+            ));
+        }
+    }
+    return @popCount(x);
+}
+
 /// Go BART compatible BitSet256 implementation
 /// Uses 4 x u64 = 256 bits for cache line optimization
 /// Optimized with CPU bit manipulation instructions (POPCNT, LZCNT, TZCNT)
@@ -84,10 +109,10 @@ pub const BitSet256 = struct {
     pub fn popcnt(self: *const BitSet256) u8 {
         // Manual loop unrolling exactly like Go BART
         var cnt: u32 = 0;
-        cnt += @popCount(self.data[0]);
-        cnt += @popCount(self.data[1]);
-        cnt += @popCount(self.data[2]);
-        cnt += @popCount(self.data[3]);
+        cnt += popCount64(self.data[0]);
+        cnt += popCount64(self.data[1]);
+        cnt += popCount64(self.data[2]);
+        cnt += popCount64(self.data[3]);
         return @as(u8, @intCast(cnt));
     }
 
@@ -98,10 +123,10 @@ pub const BitSet256 = struct {
         // Same as Go BART: rnk += bits.OnesCount64(b[i] & rankMask[idx][i])
         const mask = &rankMask[idx];
         var cnt: u32 = 0;
-        cnt += @popCount(self.data[0] & mask.data[0]);
-        cnt += @popCount(self.data[1] & mask.data[1]);
-        cnt += @popCount(self.data[2] & mask.data[2]);
-        cnt += @popCount(self.data[3] & mask.data[3]);
+        cnt += popCount64(self.data[0] & mask.data[0]);
+        cnt += popCount64(self.data[1] & mask.data[1]);
+        cnt += popCount64(self.data[2] & mask.data[2]);
+        cnt += popCount64(self.data[3] & mask.data[3]);
         return @as(u16, @intCast(cnt));
     }
 
@@ -134,10 +159,10 @@ pub const BitSet256 = struct {
     /// Uses POPCNT instruction with manual loop unrolling
     pub fn intersectionCardinality(self: *const BitSet256, other: *const BitSet256) u8 {
         var cnt: u32 = 0;
-        cnt += @popCount(self.data[0] & other.data[0]);
-        cnt += @popCount(self.data[1] & other.data[1]);
-        cnt += @popCount(self.data[2] & other.data[2]);
-        cnt += @popCount(self.data[3] & other.data[3]);
+        cnt += popCount64(self.data[0] & other.data[0]);
+        cnt += popCount64(self.data[1] & other.data[1]);
+        cnt += popCount64(self.data[2] & other.data[2]);
+        cnt += popCount64(self.data[3] & other.data[3]);
         return @as(u8, @intCast(cnt));
     }
 
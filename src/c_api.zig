@@ -0,0 +1,2110 @@
+// c_api.zig - C ABI exported by libbart.a
+//
+// Every function here is a thin shim over Table(u64), see include/bart.h
+// for the contract. The Go package links against these symbols via cgo.
+//
+// The tables store 64-bit values. The original entrypoints taking and
+// returning uint32_t values are kept for compatibility, they store their
+// values zero extended and return the low 32 bits of any value; the _64
+// entrypoints see the whole value.
+
+const std = @import("std");
+const table_mod = @import("table.zig");
+const node_mod = @import("node.zig");
+const base_index = @import("base_index.zig");
+const bitset = @import("bitset256.zig");
+const builtin = @import("builtin");
+
+const Prefix = node_mod.Prefix;
+const IPAddr = node_mod.IPAddr;
+
+/// Value is the payload stored with every prefix.
+const Value = u64;
+
+/// CTable is the concrete table behind the opaque bart_table_t handle.
+const CTable = table_mod.Table(Value);
+const CNode = node_mod.Node(Value);
+const CChild = node_mod.Child(Value);
+
+/// All tables handed out over the C ABI share the libc allocator,
+/// the caller owns nothing but the opaque pointer.
+const allocator = std.heap.c_allocator;
+
+/// Route mirrors bart_route_t.
+const Route = extern struct {
+    addr: [16]u8,
+    bits: u8,
+    is4: u8,
+    value: u32,
+};
+
+/// Route64 mirrors bart_route64_t.
+const Route64 = extern struct {
+    addr: [16]u8,
+    bits: u8,
+    is4: u8,
+    value: u64,
+};
+
+fn toTable(tbl: *anyopaque) *CTable {
+    return @ptrCast(@alignCast(tbl));
+}
+
+fn toConstTable(tbl: *const anyopaque) *const CTable {
+    return @ptrCast(@alignCast(tbl));
+}
+
+/// addr4 converts a host-order IPv4 integer into an IPAddr.
+fn addr4(addr: u32) IPAddr {
+    var octets: [4]u8 = undefined;
+    std.mem.writeInt(u32, &octets, addr, .big);
+    return IPAddr{ .v4 = octets };
+}
+
+/// addr6 copies 16 network-order bytes into an IPAddr.
+fn addr6(addr: [*]const u8) IPAddr {
+    return IPAddr{ .v6 = addr[0..16].* };
+}
+
+/// addr6hl builds an IPAddr from the high and low 64 bits of an address.
+fn addr6hl(hi: u64, lo: u64) IPAddr {
+    var octets: [16]u8 = undefined;
+    std.mem.writeInt(u64, octets[0..8], hi, .big);
+    std.mem.writeInt(u64, octets[8..16], lo, .big);
+    return IPAddr{ .v6 = octets };
+}
+
+export fn bart_create() ?*anyopaque {
+    const tbl = allocator.create(CTable) catch return null;
+    tbl.* = CTable.init(allocator);
+    return tbl;
+}
+
+export fn bart_create_arena(block_size: usize) ?*anyopaque {
+    return CTable.initArena(allocator, block_size) catch null;
+}
+
+export fn bart_clone(tbl: *const anyopaque) ?*anyopaque {
+    const t = toConstTable(tbl);
+    if (t.arena != null) return t.cloneArena() catch null;
+    const c = allocator.create(CTable) catch return null;
+    c.* = t.clone();
+    return c;
+}
+
+export fn bart_persist(tbl: *anyopaque) ?*anyopaque {
+    return toTable(tbl).persist();
+}
+
+export fn bart_destroy(tbl: ?*anyopaque) void {
+    const t = tbl orelse return;
+    toTable(t).deinitAndDestroy();
+}
+
+export fn bart_size4(tbl: *const anyopaque) usize {
+    return toConstTable(tbl).getSize4();
+}
+
+export fn bart_size6(tbl: *const anyopaque) usize {
+    return toConstTable(tbl).getSize6();
+}
+
+/// FamilyStats mirrors bart_family_stats_t.
+const FamilyStats = extern struct {
+    prefixes: u64 = 0,
+    nodes: u64 = 0,
+    leaves: u64 = 0,
+    fringes: u64 = 0,
+    bytes: u64 = 0,
+    nodes_per_level: [16]u64 = [_]u64{0} ** 16,
+};
+
+/// Stats mirrors bart_stats_t.
+const Stats = extern struct {
+    v4: FamilyStats,
+    v6: FamilyStats,
+};
+
+/// nodeStats adds n and its descendants at depth and below to s, for
+/// the nodes of tables with values of type V.
+fn nodeStats(comptime V: type, n: *const node_mod.Node(V), depth: usize, s: *FamilyStats) void {
+    s.nodes += 1;
+    s.nodes_per_level[depth] += 1;
+    s.bytes += @sizeOf(node_mod.Node(V)) +
+        n.children.items.capacity * @sizeOf(node_mod.Child(V)) +
+        n.prefixes.items.capacity * @sizeOf(V);
+    s.prefixes += n.prefixes.len();
+
+    var buf: [256]u8 = undefined;
+    for (n.children.bitset.asSlice(&buf)) |addr| {
+        switch (n.children.mustGet(addr)) {
+            .node => |c| nodeStats(V, c, depth + 1, s),
+            .leaf => {
+                s.leaves += 1;
+                s.prefixes += 1;
+            },
+            .fringe => {
+                s.fringes += 1;
+                s.prefixes += 1;
+            },
+        }
+    }
+}
+
+export fn bart_stats(tbl: *const anyopaque, out: *Stats) void {
+    const t = toConstTable(tbl);
+    out.* = .{ .v4 = .{}, .v6 = .{} };
+    nodeStats(Value, t.root4, 0, &out.v4);
+    nodeStats(Value, t.root6, 0, &out.v6);
+}
+
+/// LevelShape mirrors bart_level_shape_t.
+const LevelShape = extern struct {
+    nodes: u64 = 0,
+    prefixes: u64 = 0,
+    children: u64 = 0,
+    leaves: u64 = 0,
+    fringes: u64 = 0,
+};
+
+/// Shape mirrors bart_shape_t.
+const Shape = extern struct {
+    levels: [16]LevelShape = [_]LevelShape{.{}} ** 16,
+    prefix_fill: [9]u64 = [_]u64{0} ** 9,
+    child_fill: [10]u64 = [_]u64{0} ** 10,
+};
+
+/// fillBucket returns the histogram bucket of n, its bit length.
+fn fillBucket(n: usize) usize {
+    return @bitSizeOf(usize) - @clz(n);
+}
+
+/// nodeShape adds n and its descendants at depth and below to s.
+fn nodeShape(n: *const CNode, depth: usize, s: *Shape) void {
+    const lvl = &s.levels[depth];
+    const pfxs = n.prefixes.len();
+    const kids = n.children.len();
+    lvl.nodes += 1;
+    lvl.prefixes += pfxs;
+    lvl.children += kids;
+    s.prefix_fill[fillBucket(pfxs)] += 1;
+    s.child_fill[fillBucket(kids)] += 1;
+
+    var buf: [256]u8 = undefined;
+    for (n.children.bitset.asSlice(&buf)) |addr| {
+        switch (n.children.mustGet(addr)) {
+            .node => |c| nodeShape(c, depth + 1, s),
+            .leaf => lvl.leaves += 1,
+            .fringe => lvl.fringes += 1,
+        }
+    }
+}
+
+export fn bart_shape(tbl: *const anyopaque, v4: *Shape, v6: *Shape) void {
+    const t = toConstTable(tbl);
+    v4.* = .{};
+    v6.* = .{};
+    nodeShape(t.root4, 0, v4);
+    nodeShape(t.root6, 0, v6);
+}
+
+/// FsckProblem mirrors bart_fsck_problem_t, the codes are those of
+/// include/bart.h.
+const FsckProblem = extern struct {
+    path: [16]u8 = [_]u8{0} ** 16,
+    depth: u8 = 0,
+    is4: u8 = 0,
+    code: u8,
+    _pad: [5]u8 = [_]u8{0} ** 5,
+    have: u64 = 0,
+    want: u64 = 0,
+};
+
+const fsck_prefix_count = 1;
+const fsck_child_count = 2;
+const fsck_prefix_index = 3;
+const fsck_depth = 4;
+const fsck_empty_node = 5;
+const fsck_leaf = 6;
+const fsck_refs = 7;
+const fsck_size = 8;
+
+/// Fsck collects the problems of one table walk.
+const Fsck = struct {
+    out: []FsckProblem,
+    n: usize = 0,
+    is4: bool,
+
+    fn report(f: *Fsck, path: *const [16]u8, depth: usize, code: u8, have: u64, want: u64) void {
+        if (f.n < f.out.len) {
+            f.out[f.n] = .{
+                .path = path.*,
+                .depth = @intCast(depth),
+                .is4 = @intFromBool(f.is4),
+                .code = code,
+                .have = have,
+                .want = want,
+            };
+        }
+        f.n += 1;
+    }
+
+    /// node checks n at depth, whose position is the first depth octets
+    /// of path, and returns the number of prefixes below it.
+    fn node(f: *Fsck, n: *const CNode, path: *[16]u8, depth: usize) u64 {
+        const max_depth: usize = if (f.is4) 4 else 16;
+        if (depth >= max_depth) {
+            f.report(path, depth, fsck_depth, depth, max_depth - 1);
+            return 0;
+        }
+        if (n.refs == 0) f.report(path, depth, fsck_refs, 0, 1);
+        if (depth > 0 and n.prefixes.len() == 0 and n.children.len() == 0) {
+            f.report(path, depth, fsck_empty_node, 0, 0);
+        }
+
+        var buf: [256]u8 = undefined;
+        const idxs = n.prefixes.bitset.asSlice(&buf);
+        if (idxs.len != n.prefixes.len()) {
+            f.report(path, depth, fsck_prefix_count, idxs.len, n.prefixes.len());
+        }
+        if (idxs.len > 0 and idxs[0] == 0) f.report(path, depth, fsck_prefix_index, 0, 1);
+        var count: u64 = @min(idxs.len, n.prefixes.len());
+
+        const addrs = n.children.bitset.asSlice(&buf);
+        if (addrs.len != n.children.len()) {
+            f.report(path, depth, fsck_child_count, addrs.len, n.children.len());
+            return count;
+        }
+        for (addrs) |addr| {
+            path[depth] = addr;
+            @memset(path[depth + 1 ..], 0);
+            switch (n.children.mustGet(addr)) {
+                .node => |c| count += f.node(c, path, depth + 1),
+                .leaf => |leaf| {
+                    // a leaf sits in the slot of its octet at this depth,
+                    // below the prefixes of the node
+                    const octets = leaf.prefix.addr.asSlice();
+                    const ok = leaf.prefix.addr.is4() == f.is4 and
+                        leaf.prefix.bits >= 8 * depth + 8 and
+                        leaf.prefix.bits <= 8 * octets.len and
+                        std.mem.eql(u8, octets[0 .. depth + 1], path[0 .. depth + 1]);
+                    if (!ok) f.report(path, depth + 1, fsck_leaf, leaf.prefix.bits, 8 * depth + 8);
+                    count += 1;
+                },
+                .fringe => count += 1,
+            }
+        }
+        return count;
+    }
+};
+
+export fn bart_fsck(tbl: *const anyopaque, out: ?[*]FsckProblem, cap: usize) usize {
+    const t = toConstTable(tbl);
+    const buf: []FsckProblem = if (out) |o| o[0..cap] else &.{};
+    var total: usize = 0;
+    for ([_]bool{ true, false }) |is4| {
+        var f = Fsck{ .out = buf[@min(total, cap)..], .is4 = is4 };
+        var path = [_]u8{0} ** 16;
+        const root = if (is4) t.root4 else t.root6;
+        const size: u64 = if (is4) t.size4 else t.size6;
+        const count = f.node(root, &path, 0);
+        if (count != size) f.report(&path, 0, fsck_size, count, size);
+        total += f.n;
+    }
+    return total;
+}
+
+/// TrieItem mirrors bart_trie_item_t.
+const TrieItem = extern struct {
+    node: u32,
+    parent: u32,
+    kind: u8,
+    depth: u8,
+    octet: u8,
+    route: Route,
+};
+
+const item_node = 0;
+const item_prefix = 1;
+const item_leaf = 2;
+const item_fringe = 3;
+
+/// ItemsCtx collects trie items like DumpCtx collects routes.
+const ItemsCtx = struct {
+    out: ?[*]TrieItem,
+    cap: usize,
+    n: usize = 0,
+    next_id: u32 = 1,
+
+    fn add(self: *ItemsCtx, item: TrieItem) void {
+        if (self.out) |out| {
+            if (self.n < self.cap) out[self.n] = item;
+        }
+        self.n += 1;
+    }
+
+    /// walk adds node n, numbered id, with its prefixes and children.
+    fn walk(self: *ItemsCtx, n: *const CNode, id: u32, parent: u32, depth: usize, octet: u8, path: [16]u8, is4: bool) void {
+        const ip = if (is4) IPAddr{ .v4 = path[0..4].* } else IPAddr{ .v6 = path };
+        const d: u8 = @intCast(depth);
+        self.add(.{ .node = id, .parent = parent, .kind = item_node, .depth = d, .octet = octet, .route = toRoute(Prefix.init(&ip, d * 8), 0) });
+
+        var buf: [256]u8 = undefined;
+        for (n.prefixes.bitset.asSlice(&buf)) |idx| {
+            const pfx = node_mod.cidrFromPath(path, depth, is4, idx);
+            self.add(.{ .node = id, .parent = parent, .kind = item_prefix, .depth = d, .octet = 0, .route = toRoute(pfx, n.prefixes.mustGet(idx)) });
+        }
+        for (n.children.bitset.asSlice(&buf)) |addr| {
+            switch (n.children.mustGet(addr)) {
+                .node => |c| {
+                    var next = path;
+                    next[depth] = addr;
+                    const child_id = self.next_id;
+                    self.next_id += 1;
+                    self.walk(c, child_id, id, depth + 1, addr, next, is4);
+                },
+                .leaf => |l| self.add(.{ .node = id, .parent = parent, .kind = item_leaf, .depth = d, .octet = addr, .route = toRoute(l.prefix, l.value) }),
+                .fringe => |f| {
+                    const pfx = CNode.cidrForFringe(path[0..depth], depth, is4, addr);
+                    self.add(.{ .node = id, .parent = parent, .kind = item_fringe, .depth = d, .octet = addr, .route = toRoute(pfx, f.value) });
+                },
+            }
+        }
+    }
+};
+
+export fn bart_trie_items(tbl: *const anyopaque, out: ?[*]TrieItem, cap: usize) usize {
+    const t = toConstTable(tbl);
+    var ctx = ItemsCtx{ .out = out, .cap = cap };
+    const zero = [_]u8{0} ** 16;
+    ctx.next_id = 3;
+    ctx.walk(t.root4, 1, 0, 0, 0, zero, true);
+    ctx.walk(t.root6, 2, 0, 0, 0, zero, false);
+    return ctx.n;
+}
+
+/// traceAddr adds the nodes on the lookup path of ip, numbered from 1,
+/// each followed by its prefixes covering ip, most specific first. The
+/// walk ends with a leaf or fringe in the child slot of ip if there is one.
+fn traceAddr(t: *const CTable, ip: IPAddr, out: ?[*]TrieItem, cap: usize) usize {
+    var ctx = ItemsCtx{ .out = out, .cap = cap };
+    const is4 = ip.is4();
+    const octets = ip.asSlice();
+    var path = [_]u8{0} ** 16;
+    var n: *const CNode = if (is4) t.root4 else t.root6;
+    var id: u32 = 1;
+    for (octets, 0..) |octet, depth| {
+        const d: u8 = @intCast(depth);
+        const at = if (is4) IPAddr{ .v4 = path[0..4].* } else IPAddr{ .v6 = path };
+        ctx.add(.{ .node = id, .parent = id - 1, .kind = item_node, .depth = d, .octet = octet, .route = toRoute(Prefix.init(&at, d * 8), 0) });
+
+        var idx = base_index.hostIdx(octet) >> 1;
+        while (idx > 0) : (idx >>= 1) {
+            const i: u8 = @intCast(idx);
+            if (n.prefixes.get(i)) |v| {
+                ctx.add(.{ .node = id, .parent = id - 1, .kind = item_prefix, .depth = d, .octet = octet, .route = toRoute(node_mod.cidrFromPath(path, depth, is4, i), v) });
+            }
+        }
+
+        const kid = n.children.get(octet) orelse break;
+        switch (kid) {
+            .node => |c| {
+                path[depth] = octet;
+                n = c;
+                id += 1;
+            },
+            .leaf => |l| {
+                ctx.add(.{ .node = id, .parent = id - 1, .kind = item_leaf, .depth = d, .octet = octet, .route = toRoute(l.prefix, l.value) });
+                break;
+            },
+            .fringe => |f| {
+                const pfx = CNode.cidrForFringe(path[0..depth], depth, is4, octet);
+                ctx.add(.{ .node = id, .parent = id - 1, .kind = item_fringe, .depth = d, .octet = octet, .route = toRoute(pfx, f.value) });
+                break;
+            },
+        }
+    }
+    return ctx.n;
+}
+
+export fn bart_lookup_trace4(tbl: *const anyopaque, addr: u32, out: ?[*]TrieItem, cap: usize) usize {
+    return traceAddr(toConstTable(tbl), addr4(addr), out, cap);
+}
+
+export fn bart_lookup_trace6(tbl: *const anyopaque, addr: [*]const u8, out: ?[*]TrieItem, cap: usize) usize {
+    return traceAddr(toConstTable(tbl), addr6(addr), out, cap);
+}
+
+/// insertPfx inserts pfx and reports a previous value for the same prefix,
+/// the Go side needs it to recycle the payload slot of the old value.
+fn insertPfx(t: *CTable, pfx: *const Prefix, value: Value, old: ?*Value) c_int {
+    const prev = t.get(pfx);
+    t.insert(pfx, value);
+    if (prev) |v| {
+        if (old) |o| o.* = v;
+        return 1;
+    }
+    return 0;
+}
+
+/// narrow stores the low 32 bits of an old value for the 32-bit
+/// entrypoints, if there is one.
+fn narrow(rc: c_int, v: Value, out: ?*u32) c_int {
+    if (rc != 0) {
+        if (out) |o| o.* = @truncate(v);
+    }
+    return rc;
+}
+
+export fn bart_insert4(tbl: *anyopaque, addr: u32, bits: u8, value: u32, old: ?*u32) c_int {
+    var prev: Value = 0;
+    return narrow(bart_insert4_64(tbl, addr, bits, value, &prev), prev, old);
+}
+
+export fn bart_insert6(tbl: *anyopaque, addr: [*]const u8, bits: u8, value: u32, old: ?*u32) c_int {
+    var prev: Value = 0;
+    return narrow(bart_insert6_64(tbl, addr, bits, value, &prev), prev, old);
+}
+
+export fn bart_insert4_64(tbl: *anyopaque, addr: u32, bits: u8, value: u64, old: ?*u64) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return insertPfx(toTable(tbl), &pfx, value, old);
+}
+
+export fn bart_insert6_64(tbl: *anyopaque, addr: [*]const u8, bits: u8, value: u64, old: ?*u64) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return insertPfx(toTable(tbl), &pfx, value, old);
+}
+
+export fn bart_insert6_hl_64(tbl: *anyopaque, hi: u64, lo: u64, bits: u8, value: u64, old: ?*u64) c_int {
+    const ip = addr6hl(hi, lo);
+    const pfx = Prefix.init(&ip, bits);
+    return insertPfx(toTable(tbl), &pfx, value, old);
+}
+
+/// getOrInsertPfx inserts pfx only if it is missing, the Go side decides
+/// on the value of a present prefix after seeing it.
+fn getOrInsertPfx(t: *CTable, pfx: *const Prefix, value: u32, old: ?*u32) c_int {
+    if (t.get(pfx)) |v| {
+        if (old) |o| o.* = @truncate(v);
+        return 1;
+    }
+    t.insert(pfx, value);
+    return 0;
+}
+
+export fn bart_get_or_insert4(tbl: *anyopaque, addr: u32, bits: u8, value: u32, old: ?*u32) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return getOrInsertPfx(toTable(tbl), &pfx, value, old);
+}
+
+export fn bart_get_or_insert6(tbl: *anyopaque, addr: [*]const u8, bits: u8, value: u32, old: ?*u32) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return getOrInsertPfx(toTable(tbl), &pfx, value, old);
+}
+
+export fn bart_insert_bulk(tbl: *anyopaque, routes: [*]const Route, n: usize, replaced: ?[*]u32) usize {
+    const t = toTable(tbl);
+    var n_replaced: usize = 0;
+    for (routes[0..n]) |*r| {
+        const pfx = fromRoute(r);
+        var old: Value = 0;
+        if (insertPfx(t, &pfx, r.value, &old) != 0) {
+            if (replaced) |out| out[n_replaced] = @truncate(old);
+            n_replaced += 1;
+        }
+    }
+    return n_replaced;
+}
+
+/// deletePfx removes pfx and reports its value.
+fn deletePfx(t: *CTable, pfx: *const Prefix, old: ?*Value) c_int {
+    const res = t.getAndDelete(pfx);
+    if (!res.ok) return 0;
+    if (old) |o| o.* = res.value;
+    return 1;
+}
+
+export fn bart_delete4(tbl: *anyopaque, addr: u32, bits: u8, old: ?*u32) c_int {
+    var prev: Value = 0;
+    return narrow(bart_delete4_64(tbl, addr, bits, &prev), prev, old);
+}
+
+export fn bart_delete6(tbl: *anyopaque, addr: [*]const u8, bits: u8, old: ?*u32) c_int {
+    var prev: Value = 0;
+    return narrow(bart_delete6_64(tbl, addr, bits, &prev), prev, old);
+}
+
+export fn bart_delete4_64(tbl: *anyopaque, addr: u32, bits: u8, old: ?*u64) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return deletePfx(toTable(tbl), &pfx, old);
+}
+
+export fn bart_delete6_64(tbl: *anyopaque, addr: [*]const u8, bits: u8, old: ?*u64) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return deletePfx(toTable(tbl), &pfx, old);
+}
+
+/// getPfx reports the value stored for exactly pfx.
+fn getPfx(t: *const CTable, pfx: *const Prefix, found: ?*c_int) Value {
+    const v = t.get(pfx);
+    if (found) |f| f.* = @intFromBool(v != null);
+    return v orelse 0;
+}
+
+export fn bart_get4(tbl: *const anyopaque, addr: u32, bits: u8, found: ?*c_int) u32 {
+    return @truncate(bart_get4_64(tbl, addr, bits, found));
+}
+
+export fn bart_get6(tbl: *const anyopaque, addr: [*]const u8, bits: u8, found: ?*c_int) u32 {
+    return @truncate(bart_get6_64(tbl, addr, bits, found));
+}
+
+export fn bart_get4_64(tbl: *const anyopaque, addr: u32, bits: u8, found: ?*c_int) u64 {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return getPfx(toConstTable(tbl), &pfx, found);
+}
+
+export fn bart_get6_64(tbl: *const anyopaque, addr: [*]const u8, bits: u8, found: ?*c_int) u64 {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return getPfx(toConstTable(tbl), &pfx, found);
+}
+
+export fn bart_contains4(tbl: *const anyopaque, addr: u32) c_int {
+    const ip = addr4(addr);
+    return @intFromBool(toConstTable(tbl).contains(&ip));
+}
+
+export fn bart_contains6(tbl: *const anyopaque, addr: [*]const u8) c_int {
+    const ip = addr6(addr);
+    return @intFromBool(toConstTable(tbl).contains(&ip));
+}
+
+export fn bart_lookup4(tbl: *const anyopaque, addr: u32, found: ?*c_int) u32 {
+    return @truncate(bart_lookup4_64(tbl, addr, found));
+}
+
+export fn bart_lookup6(tbl: *const anyopaque, addr: [*]const u8, found: ?*c_int) u32 {
+    return @truncate(bart_lookup6_64(tbl, addr, found));
+}
+
+export fn bart_lookup4_64(tbl: *const anyopaque, addr: u32, found: ?*c_int) u64 {
+    const ip = addr4(addr);
+    const res = toConstTable(tbl).lookup(&ip);
+    if (found) |f| f.* = @intFromBool(res.ok);
+    return if (res.ok) res.value else 0;
+}
+
+export fn bart_lookup6_64(tbl: *const anyopaque, addr: [*]const u8, found: ?*c_int) u64 {
+    const ip = addr6(addr);
+    const res = toConstTable(tbl).lookup(&ip);
+    if (found) |f| f.* = @intFromBool(res.ok);
+    return if (res.ok) res.value else 0;
+}
+
+export fn bart_lookup6_hl(tbl: *const anyopaque, hi: u64, lo: u64, found: ?*c_int) u32 {
+    return @truncate(bart_lookup6_hl_64(tbl, hi, lo, found));
+}
+
+export fn bart_lookup6_hl_64(tbl: *const anyopaque, hi: u64, lo: u64, found: ?*c_int) u64 {
+    const ip = addr6hl(hi, lo);
+    const res = toConstTable(tbl).lookup(&ip);
+    if (found) |f| f.* = @intFromBool(res.ok);
+    return if (res.ok) res.value else 0;
+}
+
+/// lookupPfx reports the value and length of the longest matching prefix.
+fn lookupPfx(t: *const CTable, ip: *const IPAddr, bits: ?*u8, found: ?*c_int) u32 {
+    const res = t.lookup(ip);
+    if (found) |f| f.* = @intFromBool(res.ok);
+    if (!res.ok) return 0;
+    if (bits) |b| b.* = res.prefix.bits;
+    return @truncate(res.value);
+}
+
+export fn bart_lookup_prefix4(tbl: *const anyopaque, addr: u32, bits: ?*u8, found: ?*c_int) u32 {
+    const ip = addr4(addr);
+    return lookupPfx(toConstTable(tbl), &ip, bits, found);
+}
+
+export fn bart_lookup_prefix6(tbl: *const anyopaque, addr: [*]const u8, bits: ?*u8, found: ?*c_int) u32 {
+    const ip = addr6(addr);
+    return lookupPfx(toConstTable(tbl), &ip, bits, found);
+}
+
+export fn bart_lookup_shortest4(tbl: *const anyopaque, addr: u32, bits: ?*u8, found: ?*c_int) u32 {
+    const ip = addr4(addr);
+    return shortestPfx(toConstTable(tbl), &ip, bits, found);
+}
+
+export fn bart_lookup_shortest6(tbl: *const anyopaque, addr: [*]const u8, bits: ?*u8, found: ?*c_int) u32 {
+    const ip = addr6(addr);
+    return shortestPfx(toConstTable(tbl), &ip, bits, found);
+}
+
+/// shortestPfx is lookupPfx for the shortest matching prefix.
+fn shortestPfx(t: *const CTable, ip: *const IPAddr, bits: ?*u8, found: ?*c_int) u32 {
+    const res = t.lookupShortest(ip);
+    if (found) |f| f.* = @intFromBool(res.ok);
+    if (!res.ok) return 0;
+    if (bits) |b| b.* = res.prefix.bits;
+    return @truncate(res.value);
+}
+
+export fn bart_lookup_batch4(tbl: *const anyopaque, addrs: [*]const u32, n: usize, values: [*]u32, found: [*]u8) void {
+    const t = toConstTable(tbl);
+    for (addrs[0..n], values[0..n], found[0..n]) |addr, *v, *f| {
+        const ip = addr4(addr);
+        const res = t.lookup(&ip);
+        v.* = if (res.ok) @truncate(res.value) else 0;
+        f.* = @intFromBool(res.ok);
+    }
+}
+
+export fn bart_lookup_batch6(tbl: *const anyopaque, addrs: [*]const [16]u8, n: usize, values: [*]u32, found: [*]u8) void {
+    const t = toConstTable(tbl);
+    for (addrs[0..n], values[0..n], found[0..n]) |addr, *v, *f| {
+        const ip = IPAddr{ .v6 = addr };
+        const res = t.lookup(&ip);
+        v.* = if (res.ok) @truncate(res.value) else 0;
+        f.* = @intFromBool(res.ok);
+    }
+}
+
+/// fromRoute converts a bart_route_t into a prefix, the value is ignored.
+fn fromRoute(r: *const Route) Prefix {
+    const ip = if (r.is4 != 0)
+        IPAddr{ .v4 = r.addr[0..4].* }
+    else
+        IPAddr{ .v6 = r.addr };
+    return Prefix.init(&ip, r.bits);
+}
+
+/// toRoute converts a prefix from the trie into its C representation.
+fn toRoute(pfx: Prefix, value: Value) Route {
+    var r = Route{ .addr = [_]u8{0} ** 16, .bits = pfx.bits, .is4 = 0, .value = @truncate(value) };
+    switch (pfx.addr) {
+        .v4 => |a| {
+            @memcpy(r.addr[0..4], &a);
+            r.is4 = 1;
+        },
+        .v6 => |a| r.addr = a,
+    }
+    return r;
+}
+
+/// DumpCtx collects routes into a caller supplied buffer and keeps
+/// counting past its end, so the caller learns the required capacity.
+const DumpCtx = struct {
+    out: ?[*]Route,
+    cap: usize,
+    n: usize = 0,
+
+    fn yield(self: *DumpCtx, pfx: Prefix, value: Value) bool {
+        if (self.out) |out| {
+            if (self.n < self.cap) out[self.n] = toRoute(pfx, value);
+        }
+        self.n += 1;
+        return true;
+    }
+};
+
+export fn bart_dump(tbl: *const anyopaque, out: ?[*]Route, cap: usize) usize {
+    var ctx = DumpCtx{ .out = out, .cap = cap };
+    toConstTable(tbl).walk(&ctx);
+    return ctx.n;
+}
+
+/// WalkFn mirrors bart_walk_fn.
+const WalkFn = *const fn (ctx: ?*anyopaque, routes: [*]const Route, n: usize) callconv(.C) c_int;
+
+/// WalkCtx collects routes into buf and hands them to fn whenever it is
+/// full, bart_walk flushes the rest.
+const WalkCtx = struct {
+    buf: [*]Route,
+    cap: usize,
+    func: WalkFn,
+    ctx: ?*anyopaque,
+    n: usize = 0,
+    total: usize = 0,
+    stopped: bool = false,
+
+    fn yield(self: *WalkCtx, pfx: Prefix, value: Value) bool {
+        self.buf[self.n] = toRoute(pfx, value);
+        self.n += 1;
+        return self.n < self.cap or self.flush();
+    }
+
+    fn flush(self: *WalkCtx) bool {
+        if (self.n == 0) return true;
+        self.total += self.n;
+        const n = self.n;
+        self.n = 0;
+        if (self.func(self.ctx, self.buf, n) == 0) self.stopped = true;
+        return !self.stopped;
+    }
+};
+
+export fn bart_walk(tbl: *const anyopaque, buf: [*]Route, cap: usize, func: WalkFn, ctx: ?*anyopaque) usize {
+    if (cap == 0) return 0;
+    var w = WalkCtx{ .buf = buf, .cap = cap, .func = func, .ctx = ctx };
+    toConstTable(tbl).walk(&w);
+    if (!w.stopped) _ = w.flush();
+    return w.total;
+}
+
+export fn bart_walk_sorted(tbl: *const anyopaque, buf: [*]Route, cap: usize, func: WalkFn, ctx: ?*anyopaque) usize {
+    if (cap == 0) return 0;
+    var w = WalkCtx{ .buf = buf, .cap = cap, .func = func, .ctx = ctx };
+    toConstTable(tbl).walkSorted(&w);
+    if (!w.stopped) _ = w.flush();
+    return w.total;
+}
+
+/// PageCtx fills a buffer and stops the walk when it is full.
+const PageCtx = struct {
+    out: [*]Route,
+    cap: usize,
+    n: usize = 0,
+
+    fn yield(self: *PageCtx, pfx: Prefix, value: Value) bool {
+        self.out[self.n] = toRoute(pfx, value);
+        self.n += 1;
+        return self.n < self.cap;
+    }
+};
+
+export fn bart_page(tbl: *const anyopaque, after: ?*const Route, out: [*]Route, cap: usize) usize {
+    if (cap == 0) return 0;
+    var ctx = PageCtx{ .out = out, .cap = cap };
+    if (after) |a| {
+        const pfx = fromRoute(a);
+        if (!pfx.isValid()) return 0;
+        toConstTable(tbl).walkSortedAfter(&pfx, &ctx);
+    } else {
+        toConstTable(tbl).walkSorted(&ctx);
+    }
+    return ctx.n;
+}
+
+/// Dump64Ctx is DumpCtx for bart_route64_t.
+const Dump64Ctx = struct {
+    out: ?[*]Route64,
+    cap: usize,
+    n: usize = 0,
+
+    fn yield(self: *Dump64Ctx, pfx: Prefix, value: Value) bool {
+        if (self.out) |out| {
+            if (self.n < self.cap) {
+                const r = toRoute(pfx, 0);
+                out[self.n] = .{ .addr = r.addr, .bits = r.bits, .is4 = r.is4, .value = value };
+            }
+        }
+        self.n += 1;
+        return true;
+    }
+};
+
+export fn bart_dump64(tbl: *const anyopaque, out: ?[*]Route64, cap: usize) usize {
+    var ctx = Dump64Ctx{ .out = out, .cap = cap };
+    toConstTable(tbl).walk(&ctx);
+    return ctx.n;
+}
+
+export fn bart_supernets(tbl: *const anyopaque, pfx: *const Route, out: [*]Route, cap: usize) usize {
+    const t = toConstTable(tbl);
+    const base = fromRoute(pfx);
+    if (!base.isValid()) return 0;
+
+    var n: usize = 0;
+    var bits: i16 = base.bits;
+    while (bits >= 0) : (bits -= 1) {
+        const b: u8 = @intCast(bits);
+        const addr = base.addr.masked(b);
+        const cand = Prefix.init(&addr, b);
+        if (t.get(&cand)) |v| {
+            if (n < cap) out[n] = toRoute(cand, v);
+            n += 1;
+        }
+    }
+    return n;
+}
+
+export fn bart_subnets(tbl: *const anyopaque, pfx: *const Route, out: ?[*]Route, cap: usize) usize {
+    var ctx = DumpCtx{ .out = out, .cap = cap };
+    const base = fromRoute(pfx);
+    toConstTable(tbl).walkSubnets(&base, &ctx);
+    return ctx.n;
+}
+
+export fn bart_delete_subtree(tbl: *anyopaque, pfx: *const Route, out: ?[*]Route, cap: usize) usize {
+    const t = toTable(tbl);
+    var ctx = DumpCtx{ .out = out, .cap = cap };
+    const base = fromRoute(pfx);
+    t.walkSubnets(&base, &ctx);
+    if (ctx.n > cap) return ctx.n;
+    if (out) |routes| {
+        for (routes[0..ctx.n]) |*r| {
+            const p = fromRoute(r);
+            t.delete(&p);
+        }
+    }
+    return ctx.n;
+}
+
+export fn bart_delete_bulk(tbl: *anyopaque, routes: [*]const Route, n: usize) usize {
+    const t = toTable(tbl);
+    var n_deleted: usize = 0;
+    for (routes[0..n]) |*r| {
+        const pfx = fromRoute(r);
+        if (deletePfx(t, &pfx, null) != 0) n_deleted += 1;
+    }
+    return n_deleted;
+}
+
+export fn bart_overlaps_prefix(tbl: *const anyopaque, pfx: *const Route) c_int {
+    const p = fromRoute(pfx);
+    return @intFromBool(toConstTable(tbl).overlapsPrefix(&p));
+}
+
+export fn bart_overlaps(a: *const anyopaque, b: *const anyopaque) c_int {
+    return @intFromBool(toConstTable(a).overlaps(toConstTable(b)));
+}
+
+/// UnionCtx inserts the routes of the other table into dst, offsetting
+/// their values by base. Prefixes already present in dst are left alone
+/// and reported as conflicts.
+const UnionCtx = struct {
+    dst: *CTable,
+    base: u32,
+    conflicts: ?[*]Route,
+    theirs: ?[*]u32,
+    cap: usize,
+    n: usize = 0,
+
+    fn yield(self: *UnionCtx, pfx: Prefix, value: Value) bool {
+        if (self.dst.get(&pfx)) |ours| {
+            if (self.n < self.cap) {
+                if (self.conflicts) |c| c[self.n] = toRoute(pfx, ours);
+                if (self.theirs) |t| t[self.n] = @truncate(value);
+            }
+            self.n += 1;
+        } else {
+            self.dst.insert(&pfx, @as(Value, self.base) + value);
+        }
+        return true;
+    }
+};
+
+export fn bart_union(tbl: *anyopaque, other: *const anyopaque, base: u32, conflicts: ?[*]Route, theirs: ?[*]u32, cap: usize) usize {
+    var ctx = UnionCtx{ .dst = toTable(tbl), .base = base, .conflicts = conflicts, .theirs = theirs, .cap = cap };
+    toConstTable(other).walk(&ctx);
+    return ctx.n;
+}
+
+/// graftRoot moves the children of src to dst, which has none of them.
+fn graftRoot(dst: *CNode, src: *CNode) void {
+    var buf: [256]u8 = undefined;
+    for (src.children.bitset.asSlice(&buf)) |addr| {
+        _ = dst.children.insertAt(addr, src.children.mustGet(addr));
+    }
+    src.children.clearAll();
+}
+
+export fn bart_graft(tbl: *anyopaque, sub: *anyopaque) c_int {
+    const t = toTable(tbl);
+    const s = toTable(sub);
+    // the nodes keep the allocator they were made with, only tables on
+    // the shared libc allocator can take over each other's nodes
+    if (t.arena != null or s.arena != null) return 0;
+    if (t.root4.refs > 1 or t.root6.refs > 1 or s.root4.refs > 1 or s.root6.refs > 1) return 0;
+    if (s.root4.prefixes.len() != 0 or s.root6.prefixes.len() != 0) return 0;
+    if (t.root4.children.bitset.intersectsAny(&s.root4.children.bitset) or
+        t.root6.children.bitset.intersectsAny(&s.root6.children.bitset)) return 0;
+
+    graftRoot(t.root4, s.root4);
+    graftRoot(t.root6, s.root6);
+    t.size4 += s.size4;
+    t.size6 += s.size6;
+    s.size4 = 0;
+    s.size6 = 0;
+    return 1;
+}
+
+/// DiffItem mirrors bart_diff_t.
+const DiffItem = extern struct {
+    route: Route,
+    theirs: u32,
+    kind: u8,
+};
+
+const diff_removed = 0;
+const diff_added = 1;
+const diff_changed = 2;
+
+/// DiffCtx walks two tries side by side and collects the prefixes that
+/// differ. Where both tries have a node at the same position the nodes
+/// are compared slot by slot; everywhere else the subtree of either side
+/// is walked and its prefixes looked up in the other table, a prefix may
+/// sit in a leaf or fringe on one side and in a node on the other. With
+/// common set only the prefixes present in both tries are collected.
+const DiffCtx = struct {
+    a: *const CTable,
+    b: *const CTable,
+    shared: bool,
+    common: bool = false,
+    out: ?[*]DiffItem,
+    cap: usize,
+    n: usize = 0,
+
+    fn add(self: *DiffCtx, pfx: Prefix, ours: ?Value, theirs: ?Value) void {
+        if (self.common and (ours == null or theirs == null)) return;
+        if (ours != null and theirs != null and self.shared and ours.? == theirs.?) return;
+        const kind: u8 = if (ours == null) diff_added else if (theirs == null) diff_removed else diff_changed;
+        if (self.out) |out| {
+            if (self.n < self.cap) out[self.n] = .{ .route = toRoute(pfx, ours orelse 0), .theirs = @truncate(theirs orelse 0), .kind = kind };
+        }
+        self.n += 1;
+    }
+
+    fn nodes(self: *DiffCtx, a: *const CNode, b: *const CNode, path: [16]u8, depth: usize, is4: bool) void {
+        // with a shared registry equal nodes of persistent versions hold
+        // equal values
+        if (self.shared and a == b) return;
+
+        var buf: [256]u8 = undefined;
+        const pfxs = if (self.common)
+            a.prefixes.bitset.intersection(&b.prefixes.bitset)
+        else
+            a.prefixes.bitset.bitUnion(&b.prefixes.bitset);
+        for (pfxs.asSlice(&buf)) |idx| {
+            self.add(node_mod.cidrFromPath(path, depth, is4, idx), a.prefixes.get(idx), b.prefixes.get(idx));
+        }
+
+        var child_buf: [256]u8 = undefined;
+        const kids = a.children.bitset.bitUnion(&b.children.bitset);
+        for (kids.asSlice(&child_buf)) |addr| {
+            const ca = a.children.get(addr);
+            const cb = b.children.get(addr);
+            if (ca != null and cb != null and ca.? == .node and cb.? == .node) {
+                var next = path;
+                next[depth] = addr;
+                self.nodes(ca.?.node, cb.?.node, next, depth + 1, is4);
+                continue;
+            }
+            if (self.common) {
+                // a slot filled on one side only holds no common prefix
+                if (ca != null and cb != null) {
+                    var side = DiffSide{ .d = self, .ours = true };
+                    walkSlot(ca.?, path, depth, is4, addr, &side);
+                }
+                continue;
+            }
+            if (ca) |c| {
+                var side = DiffSide{ .d = self, .ours = true };
+                walkSlot(c, path, depth, is4, addr, &side);
+            }
+            if (cb) |c| {
+                var side = DiffSide{ .d = self, .ours = false };
+                walkSlot(c, path, depth, is4, addr, &side);
+            }
+        }
+    }
+};
+
+/// DiffSide feeds the prefixes of one side's subtree into a DiffCtx.
+/// Prefixes present in both tables are reported from the side of a only.
+const DiffSide = struct {
+    d: *DiffCtx,
+    ours: bool,
+
+    fn yield(self: *DiffSide, pfx: Prefix, value: Value) bool {
+        if (self.ours) {
+            self.d.add(pfx, value, self.d.b.get(&pfx));
+        } else if (self.d.a.get(&pfx) == null) {
+            self.d.add(pfx, null, value);
+        }
+        return true;
+    }
+};
+
+/// walkSlot walks the child in slot addr of the node at path/depth.
+fn walkSlot(child: CChild, path: [16]u8, depth: usize, is4: bool, addr: u8, ctx: anytype) void {
+    switch (child) {
+        .node => |kid| {
+            var next = path;
+            next[depth] = addr;
+            _ = kid.walkRec(next, depth + 1, is4, ctx);
+        },
+        .leaf => |l| _ = ctx.yield(l.prefix, l.value),
+        .fringe => |f| _ = ctx.yield(CNode.cidrForFringe(path[0..depth], depth, is4, addr), f.value),
+    }
+}
+
+export fn bart_diff(a: *const anyopaque, b: *const anyopaque, shared: c_int, out: ?[*]DiffItem, cap: usize) usize {
+    const ta = toConstTable(a);
+    const tb = toConstTable(b);
+    var ctx = DiffCtx{ .a = ta, .b = tb, .shared = shared != 0, .out = out, .cap = cap };
+    const zero = [_]u8{0} ** 16;
+    ctx.nodes(ta.root4, tb.root4, zero, 0, true);
+    ctx.nodes(ta.root6, tb.root6, zero, 0, false);
+    return ctx.n;
+}
+
+/// bart_common stores up to cap prefixes present in both a and b in out,
+/// as changed items carrying both values, and returns their number.
+export fn bart_common(a: *const anyopaque, b: *const anyopaque, out: ?[*]DiffItem, cap: usize) usize {
+    const ta = toConstTable(a);
+    const tb = toConstTable(b);
+    var ctx = DiffCtx{ .a = ta, .b = tb, .shared = false, .common = true, .out = out, .cap = cap };
+    const zero = [_]u8{0} ** 16;
+    ctx.nodes(ta.root4, tb.root4, zero, 0, true);
+    ctx.nodes(ta.root6, tb.root6, zero, 0, false);
+    return ctx.n;
+}
+
+/// KeyCheck counts the prefixes of one side's subtree and, given the
+/// other table, checks that it has each of them.
+const KeyCheck = struct {
+    other: ?*const CTable,
+    n: usize = 0,
+    ok: bool = true,
+
+    fn yield(self: *KeyCheck, pfx: Prefix, _: Value) bool {
+        self.n += 1;
+        if (self.other) |o| {
+            if (o.get(&pfx) == null) self.ok = false;
+        }
+        return self.ok;
+    }
+};
+
+/// samePrefixes reports whether the nodes at the same position of two
+/// tries hold the same prefixes. A prefix below a child slot can only be
+/// stored in that slot, so the slots in use must match on both sides.
+fn samePrefixes(ta: *const CTable, tb: *const CTable, a: *const CNode, b: *const CNode, path: [16]u8, depth: usize, is4: bool) bool {
+    if (a == b) return true;
+    if (!std.mem.eql(u64, &a.prefixes.bitset.data, &b.prefixes.bitset.data)) return false;
+    if (!std.mem.eql(u64, &a.children.bitset.data, &b.children.bitset.data)) return false;
+
+    var buf: [256]u8 = undefined;
+    for (a.children.bitset.asSlice(&buf)) |addr| {
+        const ca = a.children.mustGet(addr);
+        const cb = b.children.mustGet(addr);
+        if (ca == .node and cb == .node) {
+            var next = path;
+            next[depth] = addr;
+            if (!samePrefixes(ta, tb, ca.node, cb.node, next, depth + 1, is4)) return false;
+            continue;
+        }
+        var ka = KeyCheck{ .other = tb };
+        walkSlot(ca, path, depth, is4, addr, &ka);
+        var kb = KeyCheck{ .other = null };
+        walkSlot(cb, path, depth, is4, addr, &kb);
+        if (!ka.ok or ka.n != kb.n) return false;
+    }
+    return true;
+}
+
+export fn bart_same_prefixes(a: *const anyopaque, b: *const anyopaque) c_int {
+    const ta = toConstTable(a);
+    const tb = toConstTable(b);
+    if (ta.getSize4() != tb.getSize4() or ta.getSize6() != tb.getSize6()) return 0;
+    const zero = [_]u8{0} ** 16;
+    return @intFromBool(samePrefixes(ta, tb, ta.root4, tb.root4, zero, 0, true) and
+        samePrefixes(ta, tb, ta.root6, tb.root6, zero, 0, false));
+}
+
+// Prefix sets: tables of prefixes without values. They are Table(void),
+// whose nodes have no value slots, so a set costs the bitsets and child
+// arrays of its nodes only.
+
+/// SetTable is the concrete table behind the opaque bart_pset_t handle.
+const SetTable = table_mod.Table(void);
+
+fn toSet(set: *anyopaque) *SetTable {
+    return @ptrCast(@alignCast(set));
+}
+
+fn toConstSet(set: *const anyopaque) *const SetTable {
+    return @ptrCast(@alignCast(set));
+}
+
+export fn bart_pset_create() ?*anyopaque {
+    const s = allocator.create(SetTable) catch return null;
+    s.* = SetTable.init(allocator);
+    return s;
+}
+
+export fn bart_pset_create_arena(block_size: usize) ?*anyopaque {
+    return SetTable.initArena(allocator, block_size) catch null;
+}
+
+export fn bart_pset_clone(set: *const anyopaque) ?*anyopaque {
+    const s = toConstSet(set);
+    if (s.arena != null) return s.cloneArena() catch null;
+    const c = allocator.create(SetTable) catch return null;
+    c.* = s.clone();
+    return c;
+}
+
+export fn bart_pset_destroy(set: ?*anyopaque) void {
+    const s = set orelse return;
+    toSet(s).deinitAndDestroy();
+}
+
+export fn bart_pset_size4(set: *const anyopaque) usize {
+    return toConstSet(set).getSize4();
+}
+
+export fn bart_pset_size6(set: *const anyopaque) usize {
+    return toConstSet(set).getSize6();
+}
+
+/// addPfx inserts pfx and reports whether it was missing.
+fn addPfx(s: *SetTable, pfx: *const Prefix) c_int {
+    if (!pfx.isValid() or s.get(pfx) != null) return 0;
+    s.insert(pfx, {});
+    return 1;
+}
+
+/// removePfx deletes pfx and reports whether it was present.
+fn removePfx(s: *SetTable, pfx: *const Prefix) c_int {
+    return @intFromBool(s.getAndDelete(pfx).ok);
+}
+
+export fn bart_pset_add4(set: *anyopaque, addr: u32, bits: u8) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return addPfx(toSet(set), &pfx);
+}
+
+export fn bart_pset_add6(set: *anyopaque, addr: [*]const u8, bits: u8) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return addPfx(toSet(set), &pfx);
+}
+
+export fn bart_pset_remove4(set: *anyopaque, addr: u32, bits: u8) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return removePfx(toSet(set), &pfx);
+}
+
+export fn bart_pset_remove6(set: *anyopaque, addr: [*]const u8, bits: u8) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return removePfx(toSet(set), &pfx);
+}
+
+export fn bart_pset_has4(set: *const anyopaque, addr: u32, bits: u8) c_int {
+    const ip = addr4(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return @intFromBool(toConstSet(set).get(&pfx) != null);
+}
+
+export fn bart_pset_has6(set: *const anyopaque, addr: [*]const u8, bits: u8) c_int {
+    const ip = addr6(addr);
+    const pfx = Prefix.init(&ip, bits);
+    return @intFromBool(toConstSet(set).get(&pfx) != null);
+}
+
+export fn bart_pset_contains4(set: *const anyopaque, addr: u32) c_int {
+    const ip = addr4(addr);
+    return @intFromBool(toConstSet(set).contains(&ip));
+}
+
+export fn bart_pset_contains6(set: *const anyopaque, addr: [*]const u8) c_int {
+    const ip = addr6(addr);
+    return @intFromBool(toConstSet(set).contains(&ip));
+}
+
+export fn bart_pset_add_bulk(set: *anyopaque, routes: [*]const Route, n: usize) usize {
+    const s = toSet(set);
+    var added: usize = 0;
+    for (routes[0..n]) |*r| {
+        const pfx = fromRoute(r);
+        added += @intCast(addPfx(s, &pfx));
+    }
+    return added;
+}
+
+export fn bart_pset_remove_bulk(set: *anyopaque, routes: [*]const Route, n: usize) usize {
+    const s = toSet(set);
+    var removed: usize = 0;
+    for (routes[0..n]) |*r| {
+        const pfx = fromRoute(r);
+        removed += @intCast(removePfx(s, &pfx));
+    }
+    return removed;
+}
+
+/// SetWalkCtx feeds the prefixes of a set into a WalkCtx, with value 0.
+const SetWalkCtx = struct {
+    w: *WalkCtx,
+
+    fn yield(self: *SetWalkCtx, pfx: Prefix, _: void) bool {
+        return self.w.yield(pfx, 0);
+    }
+};
+
+export fn bart_pset_walk_sorted(set: *const anyopaque, buf: [*]Route, cap: usize, func: WalkFn, ctx: ?*anyopaque) usize {
+    if (cap == 0) return 0;
+    var w = WalkCtx{ .buf = buf, .cap = cap, .func = func, .ctx = ctx };
+    var sw = SetWalkCtx{ .w = &w };
+    toConstSet(set).walkSorted(&sw);
+    if (!w.stopped) _ = w.flush();
+    return w.total;
+}
+
+/// SetUnionCtx adds the prefixes of the other set to dst.
+const SetUnionCtx = struct {
+    dst: *SetTable,
+
+    fn yield(self: *SetUnionCtx, pfx: Prefix, _: void) bool {
+        self.dst.insert(&pfx, {});
+        return true;
+    }
+};
+
+export fn bart_pset_union(set: *anyopaque, other: *const anyopaque) void {
+    var ctx = SetUnionCtx{ .dst = toSet(set) };
+    toConstSet(other).walk(&ctx);
+}
+
+/// SetCommonCtx collects the prefixes of one set that the other has too,
+/// counting past the end of out like DumpCtx.
+const SetCommonCtx = struct {
+    other: *const SetTable,
+    out: ?[*]Route,
+    cap: usize,
+    n: usize = 0,
+
+    fn yield(self: *SetCommonCtx, pfx: Prefix, _: void) bool {
+        if (self.other.get(&pfx) == null) return true;
+        if (self.out) |out| {
+            if (self.n < self.cap) out[self.n] = toRoute(pfx, 0);
+        }
+        self.n += 1;
+        return true;
+    }
+};
+
+export fn bart_pset_common(a: *const anyopaque, b: *const anyopaque, out: ?[*]Route, cap: usize) usize {
+    const ta = toConstSet(a);
+    const tb = toConstSet(b);
+    // walk the smaller set and look its prefixes up in the larger one
+    const small, const large = if (ta.size() <= tb.size()) .{ ta, tb } else .{ tb, ta };
+    var ctx = SetCommonCtx{ .other = large, .out = out, .cap = cap };
+    small.walk(&ctx);
+    return ctx.n;
+}
+
+export fn bart_pset_stats(set: *const anyopaque, out: *Stats) void {
+    const s = toConstSet(set);
+    out.* = .{ .v4 = .{}, .v6 = .{} };
+    nodeStats(void, s.root4, 0, &out.v4);
+    nodeStats(void, s.root6, 0, &out.v6);
+}
+
+test "c_api insert and lookup" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    try std.testing.expectEqual(@as(c_int, 0), bart_insert4(tbl, 0x0a000000, 8, 99, null));
+    var old: u32 = 0;
+    try std.testing.expectEqual(@as(c_int, 1), bart_insert4(tbl, 0x0a000000, 8, 100, &old));
+    try std.testing.expectEqual(@as(u32, 99), old);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 100), bart_lookup4(tbl, 0x0a010203, &found));
+    try std.testing.expectEqual(@as(c_int, 1), found);
+
+    _ = bart_lookup4(tbl, 0x08080808, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+
+    const v6 = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12;
+    _ = bart_insert6(tbl, &v6, 32, 600, null);
+    try std.testing.expectEqual(@as(u32, 600), bart_lookup6(tbl, &v6, &found));
+    try std.testing.expectEqual(@as(c_int, 1), found);
+}
+
+test "c_api size" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 2, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 3, null);
+    const v6 = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12;
+    _ = bart_insert6(tbl, &v6, 32, 4, null);
+    try std.testing.expectEqual(@as(usize, 2), bart_size4(tbl));
+    try std.testing.expectEqual(@as(usize, 1), bart_size6(tbl));
+
+    _ = bart_delete4(tbl, 0x0a010000, 16, null);
+    _ = bart_delete4(tbl, 0x0a010000, 16, null);
+    try std.testing.expectEqual(@as(usize, 1), bart_size4(tbl));
+}
+
+test "c_api stats" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 3, null);
+
+    var st: Stats = undefined;
+    bart_stats(tbl, &st);
+    try std.testing.expectEqual(@as(u64, 3), st.v4.prefixes);
+    var per_level: u64 = 0;
+    for (st.v4.nodes_per_level) |n| per_level += n;
+    try std.testing.expectEqual(st.v4.nodes, per_level);
+    try std.testing.expect(st.v4.bytes >= st.v4.nodes * @sizeOf(CNode));
+    try std.testing.expectEqual(@as(u64, 0), st.v6.prefixes);
+    try std.testing.expectEqual(@as(u64, 1), st.v6.nodes);
+}
+
+test "c_api shape" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 3, null);
+
+    var v4: Shape = undefined;
+    var v6: Shape = undefined;
+    bart_shape(tbl, &v4, &v6);
+    var st: Stats = undefined;
+    bart_stats(tbl, &st);
+
+    var nodes: u64 = 0;
+    var stored: u64 = 0;
+    for (v4.levels) |l| {
+        nodes += l.nodes;
+        stored += l.prefixes + l.leaves + l.fringes;
+    }
+    try std.testing.expectEqual(st.v4.nodes, nodes);
+    try std.testing.expectEqual(@as(u64, 3), stored);
+    try std.testing.expectEqual(@as(u64, 1), v6.levels[0].nodes);
+    try std.testing.expectEqual(@as(u64, 1), v6.prefix_fill[0]);
+}
+
+test "c_api trie items" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 3, null);
+
+    const n = bart_trie_items(tbl, null, 0);
+    var items: [32]TrieItem = undefined;
+    try std.testing.expectEqual(n, bart_trie_items(tbl, &items, items.len));
+
+    var nodes: usize = 0;
+    var values: u32 = 0;
+    for (items[0..n]) |it| {
+        if (it.kind == item_node) {
+            nodes += 1;
+        } else {
+            values += it.route.value;
+        }
+    }
+    try std.testing.expectEqual(@as(u32, 1 + 2 + 3), values);
+    try std.testing.expectEqual(@as(u32, 1), items[0].node);
+    try std.testing.expectEqual(@as(u8, item_node), items[n - 1].kind);
+    try std.testing.expectEqual(@as(u32, 2), items[n - 1].node);
+    try std.testing.expect(nodes >= 2);
+}
+
+test "c_api lookup trace" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a100000, 12, 12, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 16, null);
+
+    var items: [16]TrieItem = undefined;
+    const n = bart_lookup_trace4(tbl, 0x0a100203, &items, items.len);
+    try std.testing.expect(n <= items.len);
+    try std.testing.expectEqual(@as(u8, item_node), items[0].kind);
+    try std.testing.expectEqual(@as(u32, 1), items[0].node);
+
+    // the /16 is off the path, the /12 is the most specific candidate
+    var seen12 = false;
+    for (items[0..n]) |it| {
+        try std.testing.expect(it.route.value != 16);
+        if (it.route.value == 12) seen12 = true;
+    }
+    try std.testing.expect(seen12);
+}
+
+test "c_api get" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 8), bart_get4(tbl, 0x0a010203, 8, &found));
+    try std.testing.expectEqual(@as(c_int, 1), found);
+    _ = bart_get4(tbl, 0x0a010000, 16, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+}
+
+test "c_api get or insert" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    var old: u32 = 0;
+    try std.testing.expectEqual(@as(c_int, 0), bart_get_or_insert4(tbl, 0x0a000000, 8, 1, &old));
+    try std.testing.expectEqual(@as(c_int, 1), bart_get_or_insert4(tbl, 0x0a000000, 8, 2, &old));
+    try std.testing.expectEqual(@as(u32, 1), old);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 1), bart_get4(tbl, 0x0a000000, 8, &found));
+}
+
+test "c_api contains" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0xc0000200, 24, 24, null);
+
+    try std.testing.expectEqual(@as(c_int, 1), bart_contains4(tbl, 0x0a010203));
+    try std.testing.expectEqual(@as(c_int, 1), bart_contains4(tbl, 0xc0000201));
+    try std.testing.expectEqual(@as(c_int, 0), bart_contains4(tbl, 0xc0000301));
+    const v6 = [_]u8{0x20} ++ [_]u8{0} ** 15;
+    try std.testing.expectEqual(@as(c_int, 0), bart_contains6(tbl, &v6));
+}
+
+test "c_api delete" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a100000, 12, 12, null);
+
+    var old: u32 = 0;
+    try std.testing.expectEqual(@as(c_int, 1), bart_delete4(tbl, 0x0a100000, 12, &old));
+    try std.testing.expectEqual(@as(u32, 12), old);
+    try std.testing.expectEqual(@as(c_int, 0), bart_delete4(tbl, 0x0a100000, 12, &old));
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup4(tbl, 0x0a100001, &found));
+    try std.testing.expectEqual(@as(c_int, 1), found);
+}
+
+test "c_api insert bulk" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    const routes = [_]Route{
+        .{ .addr = [_]u8{ 10, 0, 0, 0 } ++ [_]u8{0} ** 12, .bits = 8, .is4 = 1, .value = 1 },
+        .{ .addr = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12, .bits = 32, .is4 = 0, .value = 2 },
+        .{ .addr = [_]u8{ 10, 0, 0, 0 } ++ [_]u8{0} ** 12, .bits = 8, .is4 = 1, .value = 3 },
+    };
+    var replaced: [3]u32 = undefined;
+    try std.testing.expectEqual(@as(usize, 1), bart_insert_bulk(tbl, &routes, routes.len, &replaced));
+    try std.testing.expectEqual(@as(u32, 1), replaced[0]);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 3), bart_lookup4(tbl, 0x0a000001, &found));
+}
+
+test "c_api lookup batch" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    const v6 = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12;
+    _ = bart_insert6(tbl, &v6, 32, 32, null);
+
+    const addrs4 = [_]u32{ 0x0a010203, 0x08080808 };
+    var values: [2]u32 = undefined;
+    var found: [2]u8 = undefined;
+    bart_lookup_batch4(tbl, &addrs4, addrs4.len, &values, &found);
+    try std.testing.expectEqualSlices(u32, &.{ 8, 0 }, &values);
+    try std.testing.expectEqualSlices(u8, &.{ 1, 0 }, &found);
+
+    const addrs6 = [_][16]u8{ v6, [_]u8{0xfe} ++ [_]u8{0} ** 15 };
+    bart_lookup_batch6(tbl, &addrs6, addrs6.len, &values, &found);
+    try std.testing.expectEqualSlices(u32, &.{ 32, 0 }, &values);
+    try std.testing.expectEqualSlices(u8, &.{ 1, 0 }, &found);
+}
+
+test "c_api dump" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a800000, 9, 9, null);
+    _ = bart_insert4(tbl, 0xc0a80100, 24, 24, null);
+    const v6 = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12;
+    _ = bart_insert6(tbl, &v6, 32, 32, null);
+
+    try std.testing.expectEqual(@as(usize, 4), bart_dump(tbl, null, 0));
+
+    var routes: [4]Route = undefined;
+    try std.testing.expectEqual(@as(usize, 4), bart_dump(tbl, &routes, routes.len));
+
+    var seen: usize = 0;
+    for (routes) |r| {
+        const want: u32 = r.bits;
+        try std.testing.expectEqual(want, r.value);
+        if (r.bits == 9) {
+            try std.testing.expectEqualSlices(u8, &.{ 10, 128, 0, 0 }, r.addr[0..4]);
+        }
+        seen += 1;
+    }
+    try std.testing.expectEqual(@as(usize, 4), seen);
+}
+
+test "c_api lookup prefix" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a800000, 9, 9, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 24, null);
+
+    var bits: u8 = 0;
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 9), bart_lookup_prefix4(tbl, 0x0a800001, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 9), bits);
+    try std.testing.expectEqual(@as(u32, 24), bart_lookup_prefix4(tbl, 0x0a010203, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 24), bits);
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup_prefix4(tbl, 0x0a020304, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 8), bits);
+
+    _ = bart_lookup_prefix4(tbl, 0x08080808, &bits, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+}
+
+test "c_api supernets" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x00000000, 0, 0, null);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 16, null);
+    _ = bart_insert4(tbl, 0x0a020000, 16, 99, null);
+
+    const query = Route{ .addr = [_]u8{ 10, 1, 2, 0 } ++ [_]u8{0} ** 12, .bits = 24, .is4 = 1, .value = 0 };
+    var out: [129]Route = undefined;
+    const n = bart_supernets(tbl, &query, &out, out.len);
+    try std.testing.expectEqual(@as(usize, 3), n);
+    for (out[0..n], [_]u8{ 16, 8, 0 }) |r, bits| {
+        try std.testing.expectEqual(bits, r.bits);
+        try std.testing.expectEqual(@as(u32, bits), r.value);
+    }
+}
+
+test "c_api subnets" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 16, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 24, null);
+    _ = bart_insert4(tbl, 0x0a020000, 16, 99, null);
+    _ = bart_insert4(tbl, 0x0b000000, 8, 99, null);
+
+    const query = Route{ .addr = [_]u8{ 10, 1, 0, 0 } ++ [_]u8{0} ** 12, .bits = 16, .is4 = 1, .value = 0 };
+    var out: [8]Route = undefined;
+    const n = bart_subnets(tbl, &query, &out, out.len);
+    try std.testing.expectEqual(@as(usize, 2), n);
+    for (out[0..n]) |r| {
+        try std.testing.expectEqual(@as(u32, r.bits), r.value);
+    }
+
+    const all = Route{ .addr = [_]u8{0} ** 16, .bits = 0, .is4 = 1, .value = 0 };
+    try std.testing.expectEqual(@as(usize, 5), bart_subnets(tbl, &all, null, 0));
+}
+
+test "c_api delete subtree" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 16, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 24, null);
+    _ = bart_insert4(tbl, 0x0a020000, 16, 17, null);
+
+    const sub = Route{ .addr = [_]u8{ 10, 1 } ++ [_]u8{0} ** 14, .bits = 16, .is4 = 1, .value = 0 };
+    var out: [1]Route = undefined;
+    try std.testing.expectEqual(@as(usize, 2), bart_delete_subtree(tbl, &sub, &out, out.len));
+    try std.testing.expectEqual(@as(usize, 4), bart_size4(tbl));
+
+    var big: [4]Route = undefined;
+    try std.testing.expectEqual(@as(usize, 2), bart_delete_subtree(tbl, &sub, &big, big.len));
+    try std.testing.expectEqual(@as(usize, 2), bart_size4(tbl));
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup4(tbl, 0x0a010203, &found));
+
+    // routes already gone are not counted
+    const gone = [_]Route{ big[0], Route{ .addr = [_]u8{10} ++ [_]u8{0} ** 15, .bits = 8, .is4 = 1, .value = 0 } };
+    try std.testing.expectEqual(@as(usize, 1), bart_delete_bulk(tbl, &gone, gone.len));
+    try std.testing.expectEqual(@as(usize, 1), bart_size4(tbl));
+}
+
+test "c_api overlaps" {
+    const a = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(a);
+    const b = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(b);
+
+    _ = bart_insert4(a, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(b, 0xc0a80000, 16, 1, null);
+    try std.testing.expectEqual(@as(c_int, 0), bart_overlaps(a, b));
+
+    _ = bart_insert4(b, 0x0a010200, 24, 1, null);
+    try std.testing.expectEqual(@as(c_int, 1), bart_overlaps(a, b));
+
+    const inside = Route{ .addr = [_]u8{ 10, 9, 0, 0 } ++ [_]u8{0} ** 12, .bits = 16, .is4 = 1, .value = 0 };
+    const outside = Route{ .addr = [_]u8{ 11, 0, 0, 0 } ++ [_]u8{0} ** 12, .bits = 8, .is4 = 1, .value = 0 };
+    try std.testing.expectEqual(@as(c_int, 1), bart_overlaps_prefix(a, &inside));
+    try std.testing.expectEqual(@as(c_int, 0), bart_overlaps_prefix(a, &outside));
+}
+
+test "c_api union" {
+    const a = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(a);
+    const b = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(b);
+
+    _ = bart_insert4(a, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(b, 0x0a000000, 8, 2, null);
+    _ = bart_insert4(b, 0xc0a80000, 16, 3, null);
+
+    var conflicts: [2]Route = undefined;
+    var theirs: [2]u32 = undefined;
+    try std.testing.expectEqual(@as(usize, 1), bart_union(a, b, 100, &conflicts, &theirs, 2));
+    try std.testing.expectEqual(@as(u32, 1), conflicts[0].value);
+    try std.testing.expectEqual(@as(u32, 2), theirs[0]);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 1), bart_lookup4(a, 0x0a000001, &found));
+    try std.testing.expectEqual(@as(u32, 103), bart_lookup4(a, 0xc0a80001, &found));
+}
+
+test "c_api clone" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+
+    const c = bart_clone(tbl) orelse return error.OutOfMemory;
+    defer bart_destroy(c);
+    _ = bart_insert4(c, 0x0a000000, 8, 9, null);
+    _ = bart_insert4(c, 0xc0a80000, 16, 16, null);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup4(tbl, 0x0a000001, &found));
+    _ = bart_lookup4(tbl, 0xc0a80001, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+    try std.testing.expectEqual(@as(u32, 9), bart_lookup4(c, 0x0a000001, &found));
+}
+
+test "c_api persist" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null);
+
+    const v1 = bart_persist(tbl) orelse return error.OutOfMemory;
+    _ = bart_insert4(v1, 0x0a010000, 16, 16, null);
+    _ = bart_delete4(tbl, 0x0a000000, 8, null);
+
+    var found: c_int = 0;
+    _ = bart_lookup4(tbl, 0x0a010001, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+    try std.testing.expectEqual(@as(u32, 16), bart_lookup4(v1, 0x0a010001, &found));
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup4(v1, 0x0a020001, &found));
+
+    // versions can be released in any order
+    bart_destroy(v1);
+    try std.testing.expectEqual(@as(c_int, 0), bart_insert4(tbl, 0x0a000000, 8, 1, null));
+}
+
+test "c_api diff" {
+    const a = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(a);
+    const b = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(b);
+
+    _ = bart_insert4(a, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(a, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(a, 0xc0a80100, 24, 3, null);
+    _ = bart_insert4(b, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(b, 0x0a010000, 16, 5, null);
+    _ = bart_insert4(b, 0xac100000, 12, 6, null);
+
+    var out: [4]DiffItem = undefined;
+    try std.testing.expectEqual(@as(usize, 3), bart_diff(a, b, 1, &out, 4));
+    var kinds = [_]usize{0} ** 3;
+    for (out[0..3]) |d| kinds[d.kind] += 1;
+    try std.testing.expectEqual([_]usize{ 1, 1, 1 }, kinds);
+
+    // without a shared registry equal slots are reported too
+    try std.testing.expectEqual(@as(usize, 4), bart_diff(a, b, 0, null, 0));
+    try std.testing.expectEqual(@as(usize, 0), bart_diff(a, a, 1, null, 0));
+}
+
+test "c_api same prefixes" {
+    const a = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(a);
+    const b = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(b);
+
+    _ = bart_insert4(a, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(a, 0x0a010200, 24, 2, null);
+    _ = bart_insert4(b, 0x0a010200, 24, 7, null);
+    _ = bart_insert4(b, 0x0a000000, 8, 8, null);
+    try std.testing.expectEqual(@as(c_int, 1), bart_same_prefixes(a, b));
+
+    _ = bart_insert4(b, 0x0a010300, 24, 9, null);
+    _ = bart_delete4(b, 0x0a010200, 24, null);
+    try std.testing.expectEqual(@as(c_int, 0), bart_same_prefixes(a, b));
+}
+
+test "c_api arena" {
+    const tbl = bart_create_arena(4096) orelse return error.OutOfMemory;
+    var i: u32 = 0;
+    while (i < 2000) : (i += 1) {
+        _ = bart_insert4(tbl, 0x0a000000 | (i << 8), 24, i, null);
+    }
+    const v1 = bart_persist(tbl) orelse return error.OutOfMemory;
+    _ = bart_delete4(v1, 0x0a000000, 24, null);
+    const c = bart_clone(v1) orelse return error.OutOfMemory;
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 1999), bart_lookup4(tbl, 0x0a07cf01, &found));
+    try std.testing.expectEqual(@as(u32, 0), bart_lookup4(tbl, 0x0a000001, &found));
+    try std.testing.expectEqual(@as(c_int, 1), found);
+    _ = bart_lookup4(c, 0x0a000001, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+
+    // the versions share the arena, the clone has its own
+    bart_destroy(tbl);
+    try std.testing.expectEqual(@as(u32, 7), bart_lookup4(v1, 0x0a000701, &found));
+    bart_destroy(v1);
+    try std.testing.expectEqual(@as(usize, 1999), bart_size4(c));
+    bart_destroy(c);
+}
+
+test "c_api 64-bit values" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    const big: u64 = 0x1234_5678_9abc_def0;
+    var old: u64 = 0;
+    try std.testing.expectEqual(@as(c_int, 0), bart_insert4_64(tbl, 0x0a000000, 8, big, &old));
+    try std.testing.expectEqual(@as(c_int, 1), bart_insert4_64(tbl, 0x0a000000, 8, big + 1, &old));
+    try std.testing.expectEqual(big, old);
+    var v6 = [_]u8{0x20, 0x01, 0x0d, 0xb8} ++ [_]u8{0} ** 12;
+    _ = bart_insert6_64(tbl, &v6, 32, big, null);
+
+    var found: c_int = 0;
+    try std.testing.expectEqual(big + 1, bart_lookup4_64(tbl, 0x0a010203, &found));
+    try std.testing.expectEqual(big, bart_get6_64(tbl, &v6, 32, &found));
+    try std.testing.expectEqual(big, bart_lookup6_64(tbl, &v6, &found));
+    try std.testing.expectEqual(big, bart_lookup6_hl_64(tbl, 0x2001_0db8_ffff_0000, 1, &found));
+    try std.testing.expectEqual(@as(u32, 0), bart_lookup6_hl(tbl, 0x2001_0db9_0000_0000, 0, &found));
+    try std.testing.expectEqual(@as(c_int, 0), found);
+    try std.testing.expectEqual(@as(c_int, 1), bart_insert6_hl_64(tbl, 0x2001_0db8_0000_0000, 0, 32, big + 2, &old));
+    try std.testing.expectEqual(big, old);
+
+    // the 32-bit entrypoints see the low half
+    try std.testing.expectEqual(@as(u32, 0x9abc_def1), bart_lookup4(tbl, 0x0a010203, &found));
+    var old32: u32 = 0;
+    try std.testing.expectEqual(@as(c_int, 1), bart_insert4(tbl, 0x0a000000, 8, 7, &old32));
+    try std.testing.expectEqual(@as(u32, 0x9abc_def1), old32);
+    try std.testing.expectEqual(@as(u64, 7), bart_get4_64(tbl, 0x0a000000, 8, &found));
+
+    var routes: [2]Route64 = undefined;
+    try std.testing.expectEqual(@as(usize, 2), bart_dump64(tbl, &routes, routes.len));
+    try std.testing.expectEqual(@as(u64, 7), routes[0].value);
+    try std.testing.expectEqual(big, routes[1].value);
+    try std.testing.expectEqual(@as(c_int, 1), bart_delete6_64(tbl, &v6, 32, &old));
+    try std.testing.expectEqual(big, old);
+    try std.testing.expectEqual(@as(c_int, 0), bart_delete4_64(tbl, 0x0b000000, 8, &old));
+}
+
+test "c_api walk" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    var i: u32 = 0;
+    while (i < 10) : (i += 1) {
+        _ = bart_insert4(tbl, 0x0a000000 | (i << 16), 16, i, null);
+    }
+
+    const Counter = struct {
+        calls: usize = 0,
+        sum: u32 = 0,
+        stop_after: usize,
+
+        fn cb(ctx: ?*anyopaque, routes: [*]const Route, n: usize) callconv(.C) c_int {
+            const self: *@This() = @ptrCast(@alignCast(ctx.?));
+            self.calls += 1;
+            for (routes[0..n]) |r| self.sum += r.value;
+            return @intFromBool(self.calls < self.stop_after);
+        }
+    };
+    var buf: [4]Route = undefined;
+    var all = Counter{ .stop_after = 100 };
+    try std.testing.expectEqual(@as(usize, 10), bart_walk(tbl, &buf, buf.len, Counter.cb, &all));
+    try std.testing.expectEqual(@as(usize, 3), all.calls);
+    try std.testing.expectEqual(@as(u32, 45), all.sum);
+
+    var first = Counter{ .stop_after = 1 };
+    try std.testing.expectEqual(@as(usize, 4), bart_walk(tbl, &buf, buf.len, Counter.cb, &first));
+    try std.testing.expectEqual(@as(usize, 1), first.calls);
+}
+
+test "c_api walk sorted" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    // inserted out of order, with a /8 fringe, a leaf and a node below 10/8
+    const pfxs = [_]struct { addr: u32, bits: u8 }{
+        .{ .addr = 0x0a010000, .bits = 16 },
+        .{ .addr = 0x0a000000, .bits = 8 },
+        .{ .addr = 0x0a010203, .bits = 32 },
+        .{ .addr = 0x00000000, .bits = 0 },
+        .{ .addr = 0x0b000000, .bits = 8 },
+        .{ .addr = 0x0a000000, .bits = 7 },
+        .{ .addr = 0x0a010100, .bits = 24 },
+        .{ .addr = 0x08000000, .bits = 5 },
+    };
+    for (pfxs, 0..) |p, i| _ = bart_insert4(tbl, p.addr, p.bits, @intCast(i), null);
+
+    const Check = struct {
+        prev: ?Route = null,
+        fn cb(ctx: ?*anyopaque, routes: [*]const Route, n: usize) callconv(.C) c_int {
+            const self: *@This() = @ptrCast(@alignCast(ctx.?));
+            for (routes[0..n]) |r| {
+                if (self.prev) |p| {
+                    const order = std.mem.order(u8, &p.addr, &r.addr);
+                    if (order == .gt or (order == .eq and p.bits >= r.bits)) return 0;
+                }
+                self.prev = r;
+            }
+            return 1;
+        }
+    };
+    var buf: [3]Route = undefined;
+    var check = Check{};
+    try std.testing.expectEqual(pfxs.len, bart_walk_sorted(tbl, &buf, buf.len, Check.cb, &check));
+    try std.testing.expectEqual(@as(u8, 8), check.prev.?.bits);
+}
+
+test "c_api page" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    var i: u32 = 0;
+    while (i < 20) : (i += 1) {
+        _ = bart_insert4(tbl, 0x0a000000 | (i << 16), 16, i, null);
+        _ = bart_insert4(tbl, 0x0a000000 | (i << 16) | 0x0100, 24, 100 + i, null);
+    }
+    const v6 = [_]u8{ 0x20, 0x01, 0x0d, 0xb8 } ++ [_]u8{0} ** 12;
+    _ = bart_insert6(tbl, &v6, 32, 999, null);
+
+    // page through in sevens, every page resuming after the last route
+    var out: [7]Route = undefined;
+    var seen: usize = 0;
+    var n = bart_page(tbl, null, &out, out.len);
+    while (n > 0) {
+        seen += n;
+        const last = out[n - 1];
+        n = bart_page(tbl, &last, &out, out.len);
+        if (n > 0) try std.testing.expect(std.mem.order(u8, &last.addr, &out[0].addr) != .gt);
+    }
+    try std.testing.expectEqual(@as(usize, 41), seen);
+
+    // a cursor that is not in the table
+    const cursor = Route{ .addr = [_]u8{ 10, 5, 0, 0 } ++ [_]u8{0} ** 12, .bits = 20, .is4 = 1, .value = 0 };
+    try std.testing.expectEqual(@as(usize, 1), bart_page(tbl, &cursor, &out, 1));
+    try std.testing.expectEqual(@as(u32, 105), out[0].value);
+}
+
+test "c_api lookup shortest" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 8, null); // fringe
+    _ = bart_insert4(tbl, 0x0a010000, 16, 16, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 24, null);
+    _ = bart_insert4(tbl, 0xc0a80100, 24, 124, null); // leaf
+    _ = bart_insert4(tbl, 0xac100000, 12, 12, null);
+    _ = bart_insert4(tbl, 0xac180000, 13, 13, null);
+
+    var bits: u8 = 0;
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 8), bart_lookup_shortest4(tbl, 0x0a010203, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 8), bits);
+    try std.testing.expectEqual(@as(u32, 124), bart_lookup_shortest4(tbl, 0xc0a80101, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 24), bits);
+    try std.testing.expectEqual(@as(u32, 12), bart_lookup_shortest4(tbl, 0xac180001, &bits, &found));
+    _ = bart_lookup_shortest4(tbl, 0xc0a80201, &bits, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+
+    _ = bart_insert4(tbl, 0, 0, 99, null);
+    try std.testing.expectEqual(@as(u32, 99), bart_lookup_shortest4(tbl, 0x0a010203, &bits, &found));
+    try std.testing.expectEqual(@as(u8, 0), bits);
+}
+
+test "c_api common" {
+    const a = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(a);
+    const b = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(b);
+
+    _ = bart_insert4(a, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(a, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(a, 0xc0a80100, 24, 3, null);
+    _ = bart_insert4(b, 0x0a010000, 16, 5, null);
+    _ = bart_insert4(b, 0xc0a80100, 24, 3, null);
+    _ = bart_insert4(b, 0xac100000, 12, 6, null);
+
+    var out: [4]DiffItem = undefined;
+    try std.testing.expectEqual(@as(usize, 2), bart_common(a, b, &out, 4));
+    for (out[0..2]) |d| {
+        try std.testing.expectEqual(@as(u8, diff_changed), d.kind);
+        if (d.route.bits == 16) try std.testing.expectEqual(@as(u32, 5), d.theirs);
+    }
+    try std.testing.expectEqual(@as(usize, 3), bart_common(a, a, null, 0));
+}
+
+const cpu_popcnt: u32 = 1;
+const cpu_bmi2: u32 = 2;
+const cpu_neon: u32 = 4;
+
+export fn bart_cpu_features() u32 {
+    var f: u32 = 0;
+    switch (builtin.cpu.arch) {
+        .x86_64 => {
+            if (std.Target.x86.featureSetHas(builtin.cpu.features, .popcnt)) f |= cpu_popcnt;
+            if (std.Target.x86.featureSetHas(builtin.cpu.features, .bmi2)) f |= cpu_bmi2;
+        },
+        .aarch64 => {
+            if (std.Target.aarch64.featureSetHas(builtin.cpu.features, .neon)) f |= cpu_neon;
+        },
+        else => {},
+    }
+    return f;
+}
+
+export fn bart_set_cpu_features(features: u32) u32 {
+    if (bitset.runtime_popcnt) bitset.hw_popcnt = features & cpu_popcnt != 0;
+    var used = bart_cpu_features();
+    if (bitset.hw_popcnt) used |= cpu_popcnt;
+    return used;
+}
+
+test "c_api cpu features" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    var i: u32 = 0;
+    while (i < 200) : (i += 1) _ = bart_insert4(tbl, i << 20, 12, i, null);
+
+    const static = bart_cpu_features();
+    try std.testing.expectEqual(static, bart_set_cpu_features(0));
+    // features built in stay in use, claiming them again changes nothing
+    const used = bart_set_cpu_features(static);
+    defer _ = bart_set_cpu_features(0);
+    try std.testing.expectEqual(static, used);
+    var found: c_int = 0;
+    i = 0;
+    while (i < 200) : (i += 1) {
+        try std.testing.expectEqual(i, bart_lookup4(tbl, (i << 20) | 1, &found));
+        try std.testing.expect(found != 0);
+    }
+}
+
+test "c_api graft" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    const sub = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(sub);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0, 0, 2, null);
+    _ = bart_insert4(sub, 0x0b010000, 16, 3, null);
+    _ = bart_insert4(sub, 0x0b010200, 24, 4, null);
+
+    try std.testing.expectEqual(@as(c_int, 1), bart_graft(tbl, sub));
+    try std.testing.expectEqual(@as(usize, 4), bart_size4(tbl));
+    try std.testing.expectEqual(@as(usize, 0), bart_size4(sub));
+    var found: c_int = 0;
+    try std.testing.expectEqual(@as(u32, 4), bart_lookup4(tbl, 0x0b010203, &found));
+    _ = bart_lookup4(sub, 0x0b010203, &found);
+    try std.testing.expectEqual(@as(c_int, 0), found);
+
+    // a shared first octet refuses the graft
+    _ = bart_insert4(sub, 0x0a020000, 16, 5, null);
+    try std.testing.expectEqual(@as(c_int, 0), bart_graft(tbl, sub));
+    try std.testing.expectEqual(@as(usize, 1), bart_size4(sub));
+}
+
+test "c_api fsck" {
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(tbl, 0x0a010203, 32, 3, null);
+
+    var out: [4]FsckProblem = undefined;
+    try std.testing.expectEqual(@as(usize, 0), bart_fsck(tbl, &out, out.len));
+
+    // a size counter out of step with the trie is reported
+    toTable(tbl).size4 += 1;
+    defer toTable(tbl).size4 -= 1;
+    try std.testing.expectEqual(@as(usize, 1), bart_fsck(tbl, &out, out.len));
+    try std.testing.expectEqual(@as(u8, fsck_size), out[0].code);
+    try std.testing.expectEqual(@as(u64, 3), out[0].have);
+}
+
+test "c_api pset" {
+    const set = bart_pset_create() orelse return error.OutOfMemory;
+    defer bart_pset_destroy(set);
+    const tbl = bart_create() orelse return error.OutOfMemory;
+    defer bart_destroy(tbl);
+
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_add4(set, 0x0a000000, 8));
+    try std.testing.expectEqual(@as(c_int, 0), bart_pset_add4(set, 0x0a000000, 8));
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_add4(set, 0x0a010000, 16));
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_add4(set, 0x0a010200, 24));
+    _ = bart_insert4(tbl, 0x0a000000, 8, 1, null);
+    _ = bart_insert4(tbl, 0x0a010000, 16, 2, null);
+    _ = bart_insert4(tbl, 0x0a010200, 24, 3, null);
+    try std.testing.expectEqual(@as(usize, 3), bart_pset_size4(set));
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_has4(set, 0x0a010000, 16));
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_contains4(set, 0x0a7f0001));
+    try std.testing.expectEqual(@as(c_int, 0), bart_pset_contains4(set, 0x0b000001));
+
+    // the same prefixes take fewer bytes without the value slots
+    var ss: Stats = undefined;
+    var ts: Stats = undefined;
+    bart_pset_stats(set, &ss);
+    bart_stats(tbl, &ts);
+    try std.testing.expectEqual(ts.v4.nodes, ss.v4.nodes);
+    try std.testing.expectEqual(@as(u64, 3), ss.v4.prefixes);
+    try std.testing.expect(ss.v4.bytes < ts.v4.bytes);
+
+    const c = bart_pset_clone(set) orelse return error.OutOfMemory;
+    defer bart_pset_destroy(c);
+    try std.testing.expectEqual(@as(c_int, 1), bart_pset_remove4(c, 0x0a010000, 16));
+    try std.testing.expectEqual(@as(c_int, 0), bart_pset_remove4(c, 0x0a010000, 16));
+    try std.testing.expectEqual(@as(usize, 3), bart_pset_size4(set));
+
+    var out: [4]Route = undefined;
+    try std.testing.expectEqual(@as(usize, 2), bart_pset_common(set, c, &out, out.len));
+}
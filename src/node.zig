@@ -44,6 +44,11 @@ pub fn Node(comptime V: type) type {
         
         /// allocator for memory management operations
         allocator: std.mem.Allocator,
+
+        /// refs counts the parents and tables holding this node. Nodes are
+        /// shared between persistent versions of a table and copied on
+        /// write while refs > 1, see retain, release and cowCopy.
+        refs: u32 = 1,
         
         pub fn init(allocator: std.mem.Allocator) *Self {
             const node = allocator.create(Node(V)) catch unreachable;
@@ -63,10 +68,7 @@ pub fn Node(comptime V: type) type {
                 if (self.children.isSet(idx)) {
                     const child = self.children.mustGet(idx);
                     switch (child) {
-                        .node => |node_ptr| {
-                            node_ptr.deinit();
-                            node_ptr.allocator.destroy(node_ptr);
-                        },
+                        .node => |node_ptr| node_ptr.release(),
                         else => {},
                     }
                 }
@@ -76,6 +78,51 @@ pub fn Node(comptime V: type) type {
             self.children.deinit();
         }
         
+        /// destroy releases the node, its subtree and the node itself.
+        /// The node must already be unlinked from its parent.
+        pub fn destroy(self: *Self) void {
+            const allocator = self.allocator;
+            self.deinit();
+            allocator.destroy(self);
+        }
+        
+        /// retain adds a reference to a shared node.
+        pub fn retain(self: *Self) *Self {
+            self.refs += 1;
+            return self;
+        }
+
+        /// release drops a reference, the last one destroys the node and
+        /// releases its children.
+        pub fn release(self: *Self) void {
+            if (self.refs > 1) {
+                self.refs -= 1;
+                return;
+            }
+            self.destroy();
+        }
+
+        /// cowCopy returns a private copy of a shared node for a write.
+        /// Prefixes, leaves and fringes are copied, child nodes are shared
+        /// with the original and retained.
+        pub fn cowCopy(self: *const Self) *Self {
+            const new_node = Self.init(self.allocator);
+            new_node.prefixes = self.prefixes.deepCopy(self.allocator, struct {
+                fn cloneFn(val: *const V, _: std.mem.Allocator) V {
+                    return val.*;
+                }
+            }.cloneFn);
+            new_node.children = self.children.deepCopy(self.allocator, struct {
+                fn shareChild(child: *const Child(V), _: std.mem.Allocator) Child(V) {
+                    return switch (child.*) {
+                        .node => |kid| Child(V){ .node = kid.retain() },
+                        else => child.*,
+                    };
+                }
+            }.shareChild);
+            return new_node;
+        }
+
         /// isEmpty returns true if node has neither prefixes nor children
         pub fn isEmpty(self: *const Self) bool {
             return self.prefixes.len() == 0 and self.children.len() == 0;
@@ -144,56 +191,6 @@ pub fn Node(comptime V: type) type {
             return new_node;
         }
 
-        /// insertAtDepthPersist: Go実装と同じ動作の不変なinsert
-        pub fn insertAtDepthPersist(self: *Self, pfx: *const Prefix, val: V, depth: usize, allocator: std.mem.Allocator) bool {
-            const ip = &pfx.addr;
-            const bits = pfx.bits;
-            const octets = ip.asSlice();
-            const max_depth = base_index.maxDepthAndLastBits(bits).max_depth;
-            const last_bits = base_index.maxDepthAndLastBits(bits).last_bits;
-            var current_depth = depth;
-            var current_node = self;
-            
-            while (current_depth < max_depth) : (current_depth += 1) {
-                var octet: u8 = 0;
-                if (current_depth < octets.len) {
-                    octet = octets[current_depth];
-                }
-                if (!current_node.children.isSet(octet)) {
-                    const new_node = Self.newNode(allocator);
-                    const child = Child(V){ .node = new_node };
-                    _ = (&current_node.children).replaceAt(octet, child);
-                }
-                const kid = current_node.children.mustGet(octet);
-                switch (kid) {
-                    .node => |node| {
-                        // 子ノードをクローンして置き換え
-                        const cloned_node = node.cloneFlat(allocator);
-                        if (current_node.children.replaceAt(octet, Child(V){ .node = cloned_node })) |old_child| {
-                            switch (old_child) {
-                                .node => |old_node| {
-                                    old_node.deinit();
-                                    old_node.allocator.destroy(old_node);
-                                },
-                                else => {},
-                            }
-                        }
-                        current_node = cloned_node;
-                    },
-                    else => unreachable,
-                }
-            }
-            
-            const prefix_byte_idx: usize = if (bits > 8) (bits / 8) - 1 else 0;
-            var octet_val: u8 = 0;
-            if (octets.len > prefix_byte_idx) {
-                octet_val = octets[prefix_byte_idx];
-            }
-            const idx = base_index.pfxToIdx256(octet_val, last_bits);
-            const was_new_insert = current_node.prefixes.insertAt(idx, val);
-            return !was_new_insert; // 既存を更新した場合はtrue、新規挿入の場合はfalse
-        }
-        
         /// lpmTest determines longest prefix match existence for the specified index.
         /// Utilizes precomputed lookup tables for optimal performance characteristics.
         pub fn lpmTest(self: *const Self, idx: usize) bool {
@@ -242,7 +239,8 @@ pub fn Node(comptime V: type) type {
                     octet = octets[current_depth];
                 }
                 if (!current_node.children.isSet(octet)) {
-                    break;
+                    // The path ends above max_depth, pfx can't be stored deeper
+                    return null;
                 }
                 const kid = current_node.children.mustGet(octet);
                 switch (kid) {
@@ -269,12 +267,9 @@ pub fn Node(comptime V: type) type {
                 }
             }
             
-            // Terminal case: look in prefixes
-            const prefix_byte_idx: usize = if (bits > 8) (bits / 8) - 1 else 0;
-            var octet_val: u8 = 0;
-            if (octets.len > prefix_byte_idx) {
-                octet_val = octets[prefix_byte_idx];
-            }
+            // Terminal case: look in prefixes, the index is computed from the
+            // octet at max_depth, exactly as insertAtDepth does
+            const octet_val: u8 = if (max_depth < octets.len) octets[max_depth] else 0;
             const idx = base_index.pfxToIdx256(octet_val, last_bits);
             if (current_node.prefixes.isSet(idx)) {
                 return current_node.prefixes.mustGet(idx);
@@ -282,64 +277,66 @@ pub fn Node(comptime V: type) type {
             return null;
         }
         
+        /// delete removes pfx and returns its value.
+        /// Nodes left empty (or with a single path-compressible child) on
+        /// the way back up are purged, so deleted routes release their memory.
         pub fn delete(self: *Self, pfx: *const Prefix) ?V {
             const masked_pfx = pfx.masked();
             const ip = &masked_pfx.addr;
             const bits = masked_pfx.bits;
+            const is4 = ip.is4();
             const octets = ip.asSlice();
             const max_depth = base_index.maxDepthAndLastBits(bits).max_depth;
             const last_bits = base_index.maxDepthAndLastBits(bits).last_bits;
+            
+            // Stack of the traversed nodes for purgeAndCompress
+            var stack: [16]*Self = undefined;
             var current_depth: usize = 0;
             var current_node = self;
             
             // Forward traversal - like Go BART insertAtDepth
-            while (current_depth < max_depth) : (current_depth += 1) {
-                var octet: u8 = 0;
-                if (current_depth < octets.len) {
-                    octet = octets[current_depth];
+            while (current_depth < octets.len) : (current_depth += 1) {
+                const octet = octets[current_depth];
+                
+                // Terminal case: delete from prefixes
+                if (current_depth == max_depth) {
+                    const idx = base_index.pfxToIdx256(octet, last_bits);
+                    const value = current_node.prefixes.deleteAt(idx) orelse return null;
+                    current_node.purgeAndCompress(stack[0..current_depth], octets, is4);
+                    return value;
                 }
+                
                 if (!current_node.children.isSet(octet)) {
                     return null;
                 }
                 const kid = current_node.children.mustGet(octet);
                 switch (kid) {
                     .node => |node| {
+                        stack[current_depth] = current_node;
                         current_node = node;
                     },
                     .leaf => |leaf| {
-                        // Check if this leaf matches our prefix exactly
-                        if (leaf.prefix.eql(masked_pfx)) {
-                            const value = leaf.value;
-                            // Delete the leaf by removing it from children
-                            _ = current_node.children.deleteAt(octet);
-                            return value;
-                        }
                         // Leaf doesn't match, nothing to delete
-                        return null;
+                        if (!leaf.prefix.eql(masked_pfx)) {
+                            return null;
+                        }
+                        _ = current_node.children.deleteAt(octet);
+                        current_node.purgeAndCompress(stack[0..current_depth], octets, is4);
+                        return leaf.value;
                     },
                     .fringe => |fringe| {
-                        // Check if current depth+1 matches our prefix bits
-                        const fringe_bits = @as(u8, @intCast((current_depth + 1) * 8));
-                        if (fringe_bits == bits) {
-                            const value = fringe.value;
-                            // Delete the fringe by removing it from children
-                            _ = current_node.children.deleteAt(octet);
-                            return value;
-                        }
                         // Fringe doesn't match, nothing to delete
-                        return null;
+                        if (!base_index.isFringe(current_depth, bits)) {
+                            return null;
+                        }
+                        _ = current_node.children.deleteAt(octet);
+                        current_node.purgeAndCompress(stack[0..current_depth], octets, is4);
+                        return fringe.value;
                     },
                 }
             }
             
-            // Terminal case: delete from prefixes
-            const prefix_byte_idx: usize = if (bits > 8) (bits / 8) - 1 else 0;
-            var octet_val: u8 = 0;
-            if (octets.len > prefix_byte_idx) {
-                octet_val = octets[prefix_byte_idx];
-            }
-            const idx = base_index.pfxToIdx256(octet_val, last_bits);
-            return current_node.prefixes.deleteAt(idx);
+            return null;
         }
         
         /// lookup performs longest prefix matching for the given IP address
@@ -841,6 +838,137 @@ pub fn Node(comptime V: type) type {
             return true;
         }
 
+        /// walkRec: allRec with a caller supplied context instead of a bare
+        /// function pointer. ctx must provide `fn yield(ctx, Prefix, V) bool`,
+        /// this is what the C ABI uses to collect routes into a buffer.
+        pub fn walkRec(self: *const Self, path: StridePath, depth: usize, is4: bool, ctx: anytype) bool {
+            var buf: [256]u8 = undefined;
+            for (self.prefixes.bitset.asSlice(&buf)) |idx| {
+                if (!ctx.yield(cidrFromPath(path, depth, is4, idx), self.prefixes.mustGet(idx))) {
+                    return false;
+                }
+            }
+
+            var child_buf: [256]u8 = undefined;
+            for (self.children.bitset.asSlice(&child_buf)) |addr| {
+                if (!self.walkChild(path, depth, is4, addr, ctx)) return false;
+            }
+            return true;
+        }
+
+        /// walkCovered: walkRec restricted to the prefixes and children
+        /// covered by octet/last_bits of this node, the last stride of a
+        /// subnets query.
+        pub fn walkCovered(self: *const Self, path: StridePath, depth: usize, is4: bool, octet: u8, last_bits: u8, ctx: anytype) bool {
+            const start = base_index.pfxToIdx256(octet, last_bits);
+            var buf: [256]u8 = undefined;
+            for (self.prefixes.bitset.asSlice(&buf)) |idx| {
+                // idx is covered if start is one of its ancestors
+                var i = idx;
+                while (i > start) i >>= 1;
+                if (i != start) continue;
+
+                if (!ctx.yield(cidrFromPath(path, depth, is4, idx), self.prefixes.mustGet(idx))) {
+                    return false;
+                }
+            }
+
+            const first = octet & base_index.netMask(last_bits);
+            const last = first | ~base_index.netMask(last_bits);
+            var child_buf: [256]u8 = undefined;
+            for (self.children.bitset.asSlice(&child_buf)) |addr| {
+                if (addr < first or addr > last) continue;
+                if (!self.walkChild(path, depth, is4, addr, ctx)) return false;
+            }
+            return true;
+        }
+
+        /// walkSortedRec: walkRec in CIDR order, by address and then by
+        /// length. The prefixes of the node are sorted in a stack buffer
+        /// and merged with the children, a child comes before the prefixes
+        /// starting at a higher octet.
+        ///
+        /// With after set only the prefixes following it are yielded. path
+        /// then holds its first depth octets: the children below its octet
+        /// at depth are skipped and only the one at it is descended with
+        /// after, the others follow it entirely.
+        pub fn walkSortedRec(self: *const Self, path: StridePath, depth: usize, is4: bool, after: ?*const Prefix, ctx: anytype) bool {
+            var buf: [256]u8 = undefined;
+            const indices = self.prefixes.bitset.asSlice(&buf);
+            std.sort.insertion(u8, indices, {}, struct {
+                fn lessThan(_: void, a: u8, b: u8) bool {
+                    return cmpIndexRank(a, b) < 0;
+                }
+            }.lessThan);
+
+            var child_buf: [256]u8 = undefined;
+            const addrs = self.children.bitset.asSlice(&child_buf);
+            var cursor: usize = 0;
+            if (after) |a| {
+                const octets = a.addr.asSlice();
+                if (depth < octets.len) {
+                    while (cursor < addrs.len and addrs[cursor] < octets[depth]) cursor += 1;
+                }
+            }
+            for (indices) |idx| {
+                const octet = (base_index.idxToPfx256(idx) catch continue).octet;
+                while (cursor < addrs.len and addrs[cursor] < octet) : (cursor += 1) {
+                    if (!self.walkSortedChild(path, depth, is4, addrs[cursor], after, ctx)) return false;
+                }
+                const cidr = cidrFromPath(path, depth, is4, idx);
+                if (after != null and !follows(cidr, after.?.*)) continue;
+                if (!ctx.yield(cidr, self.prefixes.mustGet(idx))) {
+                    return false;
+                }
+            }
+            while (cursor < addrs.len) : (cursor += 1) {
+                if (!self.walkSortedChild(path, depth, is4, addrs[cursor], after, ctx)) return false;
+            }
+            return true;
+        }
+
+        /// walkSortedChild is walkChild descending with walkSortedRec.
+        fn walkSortedChild(self: *const Self, path: StridePath, depth: usize, is4: bool, addr: u8, after: ?*const Prefix, ctx: anytype) bool {
+            var below: ?*const Prefix = null;
+            if (after) |a| {
+                const octets = a.addr.asSlice();
+                if (depth < octets.len and octets[depth] == addr) below = a;
+            }
+            switch (self.children.mustGet(addr)) {
+                .node => |kid| {
+                    var new_path = path;
+                    if (depth < new_path.len) {
+                        new_path[depth] = addr;
+                    }
+                    return kid.walkSortedRec(new_path, depth + 1, is4, below, ctx);
+                },
+                .leaf => |leaf| {
+                    if (below != null and !follows(leaf.prefix, below.?.*)) return true;
+                    return ctx.yield(leaf.prefix, leaf.value);
+                },
+                .fringe => |fringe| {
+                    const pfx = cidrForFringe(path[0..depth], depth, is4, addr);
+                    if (below != null and !follows(pfx, below.?.*)) return true;
+                    return ctx.yield(pfx, fringe.value);
+                },
+            }
+        }
+
+        /// walkChild yields everything below the child at addr.
+        fn walkChild(self: *const Self, path: StridePath, depth: usize, is4: bool, addr: u8, ctx: anytype) bool {
+            switch (self.children.mustGet(addr)) {
+                .node => |kid| {
+                    var new_path = path;
+                    if (depth < new_path.len) {
+                        new_path[depth] = addr;
+                    }
+                    return kid.walkRec(new_path, depth + 1, is4, ctx);
+                },
+                .leaf => |leaf| return ctx.yield(leaf.prefix, leaf.value),
+                .fringe => |fringe| return ctx.yield(cidrForFringe(path[0..depth], depth, is4, addr), fringe.value),
+            }
+        }
+
         /// dumpListRec: Go実装互換の階層構造リスト生成
         pub fn dumpListRec(self: *const Self, allocator: std.mem.Allocator, parent_idx: u8, path: [16]u8, depth: usize, is4: bool) ![]DumpListNode(V) {
             // Go実装: recursion stop condition
@@ -1873,7 +2001,7 @@ pub fn Node(comptime V: type) type {
         /// get prefix back from octets path, depth, IP version and last octet.
         /// The prefix of a fringe is solely defined by the position in the trie.
         /// Go実装のcidrForFringeを移植
-        fn cidrForFringe(octets: []const u8, depth: usize, is4: bool, last_octet: u8) Prefix {
+        pub fn cidrForFringe(octets: []const u8, depth: usize, is4: bool, last_octet: u8) Prefix {
             var path: [16]u8 = std.mem.zeroes([16]u8);
             
             // copy existing path
@@ -1900,8 +2028,6 @@ pub fn Node(comptime V: type) type {
 
 
 
-        /// TODO: purgeAndCompressのエラー修正（cidrForFringeFromStack -> cidrForFringe）
-        
         /// purgeAndCompress: Go実装のpurgeAndCompressメソッドを移植
         /// 空ノードの削除と単一要素ノードの圧縮を行う
         pub fn purgeAndCompress(self: *Self, stack: []*Self, octets: []const u8, is4: bool) void {
@@ -1919,6 +2045,7 @@ pub fn Node(comptime V: type) type {
                 if (current_node.isEmpty()) {
                     // Go実装: just delete this empty node from parent
                     _ = parent.children.deleteAt(octet);
+                    current_node.destroy();
                 } else if (pfx_count == 0 and child_count == 1) {
                     // Go実装: single child compression logic
                     var child_addrs_buf: [256]u8 = undefined;
@@ -1940,6 +2067,7 @@ pub fn Node(comptime V: type) type {
                                 
                                 // ... (re)insert the leaf at parents depth
                                 _ = parent.insertAtDepthForCompress(&leaf.prefix, leaf.value, @intCast(depth));
+                                current_node.destroy();
                             },
                             .fringe => |fringe| {
                                 // Go実装: just one fringe, delete this node and reinsert the fringe as leaf above
@@ -1954,6 +2082,7 @@ pub fn Node(comptime V: type) type {
                                 
                                 // ... (re)reinsert prefix/value at parents depth
                                 _ = parent.insertAtDepthForCompress(&fringe_pfx, fringe.value, @intCast(depth));
+                                current_node.destroy();
                             },
                         }
                     }
@@ -2560,6 +2689,16 @@ pub fn cmpIndexRank(a: u8, b: u8) i32 {
 
 /// cidrFromPath: ストライドパス、深度、インデックスからプレフィックスを復元
 /// Go実装のcidrFromPathに相当
+/// follows reports whether p comes after a in CIDR order, both prefixes
+/// being of the same address family.
+fn follows(p: Prefix, a: Prefix) bool {
+    return switch (std.mem.order(u8, p.addr.asSlice(), a.addr.asSlice())) {
+        .gt => true,
+        .lt => false,
+        .eq => p.bits > a.bits,
+    };
+}
+
 pub fn cidrFromPath(path: StridePath, depth: usize, is4: bool, idx: u8) Prefix {
     const pfx_info = base_index.idxToPfx256(idx) catch {
         // エラーの場合は無効なプレフィックスを返す
@@ -2568,17 +2707,10 @@ pub fn cidrFromPath(path: StridePath, depth: usize, is4: bool, idx: u8) Prefix {
 
     var addr_path = path;
     
-    // 現在の深度のオクテットにプレフィックス情報を適用
+    // 現在の深度のオクテットはインデックスだけで決まる
+    // (pathはこの深度ではまだ設定されていない)
     if (depth < addr_path.len) {
-        // 既存のパス情報を保持し、プレフィックス部分のみマスク
-        const current_octet = addr_path[depth];
-        const pfx_mask = if (pfx_info.pfx_len == 0) 
-            @as(u8, 0)  // デフォルトルート
-        else 
-            @as(u8, 0xff) << @as(u3, @intCast(8 - pfx_info.pfx_len));
-        
-        // プレフィックス部分とマスクを組み合わせ
-        addr_path[depth] = (current_octet & pfx_mask) | (pfx_info.octet & (~pfx_mask));
+        addr_path[depth] = pfx_info.octet;
     }
 
     // プレフィックス範囲外のバイトをクリア
@@ -43,6 +43,7 @@ pub fn NodePool(comptime V: type) type {
                     node_ptr.prefixes.deinit();
                     node_ptr.children = sparse_array256.Array256(node.Child(V)).init(self.allocator);
                     node_ptr.prefixes = sparse_array256.Array256(V).init(self.allocator);
+                    node_ptr.refs = 1;
                     self.pool_hits += 1;
                     return node_ptr;
                 }
@@ -31,6 +31,7 @@ const Child = node.Child;
 const LeafNode = node.LeafNode;
 const FringeNode = node.FringeNode;
 const base_index = @import("base_index.zig");
+const Arena = @import("arena.zig").Arena;
 
 /// Table is an IPv4 and IPv6 routing table with payload V.
 /// The zero value is ready to use.
@@ -47,6 +48,14 @@ pub fn Table(comptime V: type) type {
         
         // Memory pool for high-performance node allocation
         node_pool: ?*NodePool(V),
+
+        /// shared is set once nodes may be shared with another table via
+        /// persist, writes then copy shared nodes on their path first.
+        shared: bool = false,
+
+        /// arena, if set, owns the memory of the table and of all its
+        /// persistent versions, see initArena.
+        arena: ?*Arena = null,
         
         pub fn init(allocator: std.mem.Allocator) Self {
             return Self{
@@ -60,10 +69,9 @@ pub fn Table(comptime V: type) type {
         }
         
         pub fn deinit(self: *Self) void {
-            self.root4.deinit();
-            self.allocator.destroy(self.root4);
-            self.root6.deinit();
-            self.allocator.destroy(self.root6);
+            // roots and nodes may be shared with persistent versions
+            self.root4.release();
+            self.root6.release();
             
             // Cleanup node pool
             if (self.node_pool) |pool| {
@@ -72,9 +80,35 @@ pub fn Table(comptime V: type) type {
             }
         }
         
+        /// initArena returns a table allocating its nodes from a new arena
+        /// taking blocks of block_size bytes from the backing allocator,
+        /// 0 for the default size. It must be released with
+        /// deinitAndDestroy, which frees the arena with the last version
+        /// of the table in one go.
+        pub fn initArena(backing: std.mem.Allocator, block_size: usize) !*Self {
+            const arena = try Arena.create(backing, block_size);
+            const allocator = arena.allocator();
+            const self = allocator.create(Self) catch |err| {
+                arena.release();
+                return err;
+            };
+            self.* = Self.init(allocator);
+            self.arena = arena;
+            return self;
+        }
+
         /// deinitAndDestroy: insertPersist等で作成されたテーブルを完全にクリーンアップ
         pub fn deinitAndDestroy(self: *Self) void {
             const allocator = self.allocator;
+            if (self.arena) |arena| {
+                // the last version frees the nodes with the arena blocks
+                if (arena.refs > 1) {
+                    self.deinit();
+                    allocator.destroy(self);
+                }
+                arena.release();
+                return;
+            }
             self.deinit();
             allocator.destroy(self);
         }
@@ -97,6 +131,7 @@ pub fn Table(comptime V: type) type {
             }
             const canonical_pfx = pfx.masked();
             const is4 = canonical_pfx.addr.is4();
+            if (self.shared) self.privatize(&canonical_pfx);
             var n: *Node(V) = self.rootNodeByVersion(is4);
             
             // Phase 3: fast_allocator使用で高速化
@@ -128,6 +163,7 @@ pub fn Table(comptime V: type) type {
             }
             const canonical_pfx = pfx.masked();
             const is4 = canonical_pfx.addr.is4();
+            if (self.shared) self.privatize(&canonical_pfx);
             const n = self.rootNodeByVersion(is4);
             if (n.delete(&canonical_pfx)) |val| {
                 self.sizeUpdate(is4, -1);
@@ -226,6 +262,42 @@ pub fn Table(comptime V: type) type {
             return node.LookupResult(V){ .prefix = undefined, .value = undefined, .ok = false };
         }
         
+        /// lookupShortest is lookup for the least specific prefix covering
+        /// addr. It walks down the path of addr and returns the first
+        /// prefix it meets, without backtracking.
+        pub fn lookupShortest(self: *const Self, addr: *const IPAddr) node.LookupResult(V) {
+            var n = self.rootNodeByVersionConst(addr.is4());
+            for (addr.asSlice(), 0..) |octet, depth| {
+                if (n.prefixes.len() != 0) {
+                    var bits: u8 = 0;
+                    while (bits < 8) : (bits += 1) {
+                        const idx = base_index.pfxToIdx256(octet, bits);
+                        if (n.prefixes.isSet(idx)) {
+                            const pfx_bits: u8 = @intCast(depth * 8 + bits);
+                            const masked = addr.masked(pfx_bits);
+                            return .{ .prefix = Prefix.init(&masked, pfx_bits), .value = n.prefixes.mustGet(idx), .ok = true };
+                        }
+                    }
+                }
+                const kid = n.children.get(octet) orelse break;
+                switch (kid) {
+                    .node => |k| n = k,
+                    .fringe => |fringe| {
+                        const pfx_bits: u8 = @intCast((depth + 1) * 8);
+                        const masked = addr.masked(pfx_bits);
+                        return .{ .prefix = Prefix.init(&masked, pfx_bits), .value = fringe.value, .ok = true };
+                    },
+                    .leaf => |leaf| {
+                        if (leaf.prefix.containsAddr(addr.*)) {
+                            return .{ .prefix = leaf.prefix, .value = leaf.value, .ok = true };
+                        }
+                        break;
+                    },
+                }
+            }
+            return .{ .prefix = undefined, .value = undefined, .ok = false };
+        }
+
         /// LookupPrefix performs a longest prefix match for the given prefix.
         pub fn lookupPrefix(self: *const Self, pfx: *const Prefix) node.LookupResult(V) {
             if (!pfx.isValid()) {
@@ -271,6 +343,21 @@ pub fn Table(comptime V: type) type {
             };
             return new_table;
         }
+
+        /// cloneArena is clone for an arena table, the copy gets an arena
+        /// of its own with the same block size, as it is independent of
+        /// self.
+        pub fn cloneArena(self: *const Self) !*Self {
+            const src = self.arena orelse unreachable;
+            const c = try initArena(src.backing, src.block_size);
+            c.root4.release();
+            c.root6.release();
+            c.root4 = self.root4.cloneRec(c.allocator);
+            c.root6 = self.root6.cloneRec(c.allocator);
+            c.size4 = self.size4;
+            c.size6 = self.size6;
+            return c;
+        }
         
         /// sizeUpdate updates the size counter for the given IP version.
         fn sizeUpdate(self: *Self, is4: bool, delta: i32) void {
@@ -451,220 +538,95 @@ pub fn Table(comptime V: type) type {
             }
         }
 
-        /// InsertPersist: 元のテーブルを変更せずに新しいテーブルを返す（Go実装と同じ動作）
-        pub fn insertPersist(self: *const Self, pfx: *const Prefix, val: V) *Self {
-            if (!pfx.isValid()) {
-                return @constCast(self);
-            }
-            
-            const canonical_pfx = pfx.masked();
-            const is4 = canonical_pfx.addr.is4();
-            
+        /// persist returns a new table sharing all nodes with self in O(1).
+        /// Both tables stay mutable: the first write to a shared node copies
+        /// it (see privatize), so neither table sees the changes of the
+        /// other. The new table must be released with deinitAndDestroy.
+        pub fn persist(self: *const Self) *Self {
+            // the receiver has to copy on write from now on as well
+            @constCast(self).shared = true;
+
             const new_table = self.allocator.create(Self) catch unreachable;
             new_table.* = Self{
                 .allocator = self.allocator,
+                .root4 = self.root4.retain(),
+                .root6 = self.root6.retain(),
                 .size4 = self.size4,
                 .size6 = self.size6,
-                .root4 = undefined, // 後でセット
-                .root6 = undefined, // 後でセット
+                .node_pool = null,
+                .shared = true,
+                .arena = if (self.arena) |arena| arena.retain() else null,
             };
-            
-            // 挿入パスのルートをdeep clone
-            if (is4) {
-                new_table.root4 = self.rootNodeByVersionConst(is4).cloneRec(self.allocator);
-                new_table.root6 = self.root6.cloneRec(self.allocator); // 変更なしでもクローン
-            } else {
-                new_table.root4 = self.root4.cloneRec(self.allocator); // 変更なしでもクローン
-                new_table.root6 = self.rootNodeByVersionConst(is4).cloneRec(self.allocator);
-            }
-            const root = new_table.rootNodeByVersion(is4);
-            
-            // 挿入パスに沿ってノードをクローン
-            const insert_result = root.insertAtDepthPersist(&canonical_pfx, val, 0, self.allocator);
-            
-            if (insert_result) {
-                // プレフィックスが存在した、サイズ増加なし
-                return new_table;
-            }
-            
-            // 真の挿入、サイズ更新
-            new_table.sizeUpdate(is4, 1);
             return new_table;
         }
 
-        /// UpdatePersist: 不変な更新操作（Go実装と同じ動作）
-        pub fn updatePersist(self: *const Self, pfx: *const Prefix, cb: fn (V, bool) V) struct { table: *Self, value: V } {
-            const zero: V = undefined;
-            if (!pfx.isValid()) {
-                return .{ .table = self, .value = zero };
+        /// privatize copies the shared nodes on the path of pfx, so that a
+        /// following insert or delete of pfx only touches nodes owned by
+        /// self. Nodes off the path stay shared.
+        fn privatize(self: *Self, pfx: *const Prefix) void {
+            const root = if (pfx.addr.is4()) &self.root4 else &self.root6;
+            if (root.*.refs > 1) {
+                const copy = root.*.cowCopy();
+                root.*.release();
+                root.* = copy;
             }
-            
-            // 正規化されたプレフィックス
-            const canonical_pfx = pfx.masked();
-            const ip = &canonical_pfx.addr;
-            const is4 = ip.is4();
-            const bits = canonical_pfx.bits;
-            
-            const new_table = self.allocator.create(Self) catch unreachable;
-            new_table.* = Self{
-                .allocator = self.allocator,
-                .size4 = self.size4,
-                .size6 = self.size6,
-                .root4 = undefined, // 後でセット
-                .root6 = undefined, // 後でセット
-            };
-            
-            // 挿入パスのルートをdeep clone
-            if (is4) {
-                new_table.root4 = self.rootNodeByVersionConst(is4).cloneRec(self.allocator);
-                new_table.root6 = self.root6.cloneRec(self.allocator); // 変更なしでもクローン
-            } else {
-                new_table.root4 = self.root4.cloneRec(self.allocator); // 変更なしでもクローン
-                new_table.root6 = self.rootNodeByVersionConst(is4).cloneRec(self.allocator);
-            }
-            const root = new_table.rootNodeByVersion(is4);
-            
-            const max_depth = base_index.maxDepthAndLastBits(bits).max_depth;
-            const last_bits = base_index.maxDepthAndLastBits(bits).last_bits;
-            const octets = ip.asSlice();
-            
-            // 適切なトライノードを見つけてプレフィックスを更新
+
+            const octets = pfx.addr.asSlice();
+            const max_depth = base_index.maxDepthAndLastBits(pfx.bits).max_depth;
+            var n = root.*;
             var depth: usize = 0;
-            var current_node = root;
-            while (depth < octets.len) : (depth += 1) {
-                const octet = octets[depth];
-                
-                // プレフィックスの最後のオクテット、ノードにプレフィックスを更新/挿入
-                if (depth == max_depth) {
-                    const idx = base_index.pfxToIdx256(octet, last_bits);
-                    const result = current_node.prefixes.updateAt(idx, cb);
-                    if (!result.was_present) {
-                        new_table.sizeUpdate(is4, 1);
-                    }
-                    return .{ .table = new_table, .value = result.new_value };
-                }
-                
-                const addr = octet;
-                
-                // 最後のオクテットまでタイトループで降下
-                if (!current_node.children.isSet(addr)) {
-                    // プレフィックスをパス圧縮として挿入
-                    const new_val = cb(zero, false);
-                    if (node.isFringe(depth, bits)) {
-                        _ = current_node.children.replaceAt(addr, Child(V){ .fringe = FringeNode(V).init(new_val) });
-                    } else {
-                        _ = current_node.children.replaceAt(addr, Child(V){ .leaf = LeafNode(V).init(canonical_pfx, new_val) });
-                    }
-                    
-                    new_table.sizeUpdate(is4, 1);
-                    return .{ .table = new_table, .value = new_val };
-                }
-                
-                const kid = current_node.children.mustGet(addr);
+            while (depth < max_depth and depth < octets.len) : (depth += 1) {
+                const kid = n.children.get(octets[depth]) orelse return;
                 switch (kid) {
-                    .node => |node_ptr| {
-                        // 次のレベルに進む
-                        const cloned_kid = node_ptr.cloneFlat(current_node.allocator);
-                        // 古いノードをクリーンアップ
-                        if (current_node.children.replaceAt(addr, Child(V){ .node = cloned_kid })) |old_child| {
-                            switch (old_child) {
-                                .node => |old_node_ptr| {
-                                    old_node_ptr.deinit();
-                                    old_node_ptr.allocator.destroy(old_node_ptr);
-                                },
-                                else => {},
-                            }
+                    .node => |k| {
+                        if (k.refs > 1) {
+                            const copy = k.cowCopy();
+                            _ = n.children.replaceAt(octets[depth], Child(V){ .node = copy });
+                            k.release();
+                            n = copy;
+                        } else {
+                            n = k;
                         }
-                        current_node = cloned_kid;
-                        continue; // 次のトライレベルに降下
-                    },
-                    .leaf => |leaf| {
-                        const cloned_leaf = leaf.cloneLeaf();
-                        
-                        // プレフィックスが等しい場合、既存の値を更新
-                        if (cloned_leaf.prefix.eql(canonical_pfx)) {
-                            const new_val = cb(cloned_leaf.value, true);
-                            _ = current_node.children.replaceAt(addr, Child(V){ .leaf = LeafNode(V).init(canonical_pfx, new_val) });
-                            return .{ .table = new_table, .value = new_val };
-                        }
-                        
-                        // 新しいノードを作成 - OPTIMIZED
-                        // リーフを下に押し下げ
-                        // 現在のリーフ位置（addr）に新しい子を挿入
-                        // 降下し、nを新しい子で置き換え
-                        const new_node = Node(V).createFastNode(current_node.allocator);
-                        _ = new_node.insertAtDepth(&cloned_leaf.prefix, cloned_leaf.value, depth + 1, current_node.allocator);
-                        
-                        _ = current_node.children.replaceAt(addr, Child(V){ .node = new_node });
-                        current_node = new_node;
-                    },
-                    .fringe => |fringe| {
-                        const cloned_fringe = fringe.cloneFringe();
-                        
-                        // pfxがフリンジの場合、既存の値を更新
-                        if (node.isFringe(depth, bits)) {
-                            const new_val = cb(cloned_fringe.value, true);
-                            _ = current_node.children.replaceAt(addr, Child(V){ .fringe = FringeNode(V).init(new_val) });
-                            return .{ .table = new_table, .value = new_val };
-                        }
-                        
-                        // 新しいノードを作成 - OPTIMIZED
-                        // フリンジを下に押し下げ、デフォルトルート（idx=1）になる
-                        // 現在のリーフ位置（addr）に新しい子を挿入
-                        // 降下し、nを新しい子で置き換え
-                        const new_node = Node(V).createFastNode(current_node.allocator);
-                        _ = new_node.prefixes.insertAt(1, cloned_fringe.value);
-                        
-                        _ = current_node.children.replaceAt(addr, Child(V){ .node = new_node });
-                        current_node = new_node;
                     },
+                    // leaves and fringes are values, copied with their parent
+                    else => return,
                 }
             }
-            
-            unreachable; // 到達不可能
         }
 
-        /// DeletePersist: 不変な削除操作（Go実装と同じ動作）
+        /// InsertPersist is like insert but the receiver isn't modified.
+        /// The returned table shares all untouched nodes with the receiver.
+        pub fn insertPersist(self: *const Self, pfx: *const Prefix, val: V) *Self {
+            const new_table = self.persist();
+            new_table.insert(pfx, val);
+            return new_table;
+        }
+
+        /// UpdatePersist is like an update via cb but the receiver isn't
+        /// modified. cb gets the current value and whether it exists.
+        pub fn updatePersist(self: *const Self, pfx: *const Prefix, cb: fn (V, bool) V) struct { table: *Self, value: V } {
+            const new_table = self.persist();
+            if (!pfx.isValid()) {
+                return .{ .table = new_table, .value = undefined };
+            }
+            const old = new_table.get(pfx);
+            const value = cb(old orelse undefined, old != null);
+            new_table.insert(pfx, value);
+            return .{ .table = new_table, .value = value };
+        }
+
+        /// DeletePersist is like delete but the receiver isn't modified.
         pub fn deletePersist(self: *const Self, pfx: *const Prefix) *Self {
             const result = self.getAndDeletePersist(pfx);
             return result.table;
         }
 
-        /// GetAndDeletePersist: 不変な削除操作（Go実装と同じ動作）
+        /// GetAndDeletePersist is like getAndDelete but the receiver isn't
+        /// modified.
         pub fn getAndDeletePersist(self: *const Self, pfx: *const Prefix) struct { table: *Self, value: V, ok: bool } {
-            const zero: V = undefined;
-            if (!pfx.isValid()) {
-                return .{ .table = self, .value = zero, .ok = false };
-            }
-            
-            const canonical_pfx = pfx.masked();
-            const is4 = canonical_pfx.addr.is4();
-            
-            const new_table = self.allocator.create(Self) catch unreachable;
-            new_table.* = Self{
-                .allocator = self.allocator,
-                .size4 = self.size4,
-                .size6 = self.size6,
-                .root4 = undefined, // 後でセット
-                .root6 = undefined, // 後でセット
-            };
-            
-            // 削除パスのルートをクローン
-            if (is4) {
-                new_table.root4 = self.rootNodeByVersionConst(is4).cloneFlat(self.allocator);
-                new_table.root6 = self.root6.cloneFlat(self.allocator); // 変更なしでもクローン
-            } else {
-                new_table.root4 = self.root4.cloneFlat(self.allocator); // 変更なしでもクローン
-                new_table.root6 = self.rootNodeByVersionConst(is4).cloneFlat(self.allocator);
-            }
-            const root = new_table.rootNodeByVersion(is4);
-            
-            if (root.delete(&canonical_pfx)) |val| {
-                new_table.sizeUpdate(is4, -1);
-                return .{ .table = new_table, .value = val, .ok = true };
-            }
-            
-            return .{ .table = new_table, .value = zero, .ok = false };
+            const new_table = self.persist();
+            const res = new_table.getAndDelete(pfx);
+            return .{ .table = new_table, .value = res.value, .ok = res.ok };
         }
 
         /// OverlapsPrefix reports whether any IP in pfx is matched by a route in the table or vice versa
@@ -950,6 +912,78 @@ pub fn Table(comptime V: type) type {
             _ = self.root6.allRecSorted(path, 0, false, yield);
         }
 
+        /// walk enumerates all prefixes, IPv4 before IPv6, until ctx.yield
+        /// returns false. Unlike allWithCallback the callback gets a context,
+        /// see Node.walkRec.
+        pub fn walk(self: *const Self, ctx: anytype) void {
+            const path = std.mem.zeroes(node.StridePath);
+            _ = self.root4.walkRec(path, 0, true, ctx) and
+                self.root6.walkRec(path, 0, false, ctx);
+        }
+
+        /// walkSorted is walk in CIDR order, IPv4 before IPv6 and within a
+        /// family by address and then by length. See Node.walkSortedRec.
+        pub fn walkSorted(self: *const Self, ctx: anytype) void {
+            const path = std.mem.zeroes(node.StridePath);
+            _ = self.root4.walkSortedRec(path, 0, true, null, ctx) and
+                self.root6.walkSortedRec(path, 0, false, null, ctx);
+        }
+
+        /// walkSortedAfter is walkSorted starting after pfx, which need not
+        /// be in the table. The subtrees before pfx are skipped, so resuming
+        /// a walk costs a descent along pfx and not a walk up to it.
+        pub fn walkSortedAfter(self: *const Self, pfx: *const Prefix, ctx: anytype) void {
+            const path = std.mem.zeroes(node.StridePath);
+            const after = pfx.masked();
+            if (after.addr.is4()) {
+                _ = self.root4.walkSortedRec(path, 0, true, &after, ctx) and
+                    self.root6.walkSortedRec(path, 0, false, null, ctx);
+            } else {
+                _ = self.root6.walkSortedRec(path, 0, false, &after, ctx);
+            }
+        }
+
+        /// walkSubnets enumerates all prefixes covered by pfx, including pfx
+        /// itself, until ctx.yield returns false. See walk for ctx.
+        pub fn walkSubnets(self: *const Self, pfx: *const Prefix, ctx: anytype) void {
+            if (!pfx.isValid()) return;
+            const canonical = pfx.masked();
+            const is4 = canonical.addr.is4();
+            const octets = canonical.addr.asSlice();
+            const md = base_index.maxDepthAndLastBits(canonical.bits);
+
+            // descend to the node of the last stride of pfx
+            var n = self.rootNodeByVersionConst(is4);
+            var path = std.mem.zeroes(node.StridePath);
+            var depth: usize = 0;
+            while (depth < md.max_depth) : (depth += 1) {
+                const octet = octets[depth];
+                const kid = n.children.get(octet) orelse return;
+                switch (kid) {
+                    .node => |k| {
+                        path[depth] = octet;
+                        n = k;
+                    },
+                    .leaf => |leaf| {
+                        // a path compressed leaf is the only prefix below octet
+                        if (leaf.prefix.bits >= canonical.bits and canonical.containsAddr(leaf.prefix.addr)) {
+                            _ = ctx.yield(leaf.prefix, leaf.value);
+                        }
+                        return;
+                    },
+                    .fringe => |fringe| {
+                        // a fringe above the last stride is pfx itself
+                        if ((depth + 1) * 8 == canonical.bits) {
+                            _ = ctx.yield(canonical, fringe.value);
+                        }
+                        return;
+                    },
+                }
+            }
+            if (depth >= octets.len) return;
+            _ = n.walkCovered(path, depth, is4, octets[depth], md.last_bits, ctx);
+        }
+
         /// Contains performs a route lookup for IP and returns true if any route matched.
         /// Direct port of Go BART's Contains implementation
         pub fn contains(self: *const Self, addr: *const IPAddr) bool {
@@ -1047,6 +1081,65 @@ fn cloneOrCopy(comptime V: type, value: V) V {
     return value;
 }
 
+test "Table persist copies on write" {
+    const allocator = std.testing.allocator;
+    var tbl = Table(u32).init(allocator);
+    defer tbl.deinit();
+
+    const a = IPAddr{ .v4 = .{ 10, 0, 0, 0 } };
+    const b = IPAddr{ .v4 = .{ 10, 1, 0, 0 } };
+    const c = IPAddr{ .v4 = .{ 10, 1, 2, 0 } };
+    const pfx8 = Prefix.init(&a, 8);
+    const pfx16 = Prefix.init(&b, 16);
+    const pfx24 = Prefix.init(&c, 24);
+    tbl.insert(&pfx8, 8);
+    tbl.insert(&pfx16, 16);
+
+    const v1 = tbl.insertPersist(&pfx24, 24);
+    defer v1.deinitAndDestroy();
+    const v2 = v1.deletePersist(&pfx16);
+    defer v2.deinitAndDestroy();
+
+    // the original is still writable and doesn't leak into the versions
+    tbl.insert(&pfx8, 99);
+
+    const probe = IPAddr{ .v4 = .{ 10, 1, 2, 3 } };
+    try std.testing.expectEqual(@as(u32, 16), tbl.lookup(&probe).value);
+    try std.testing.expectEqual(@as(u32, 24), v1.lookup(&probe).value);
+    try std.testing.expectEqual(@as(u32, 24), v2.lookup(&probe).value);
+    try std.testing.expectEqual(@as(u32, 99), tbl.get(&pfx8).?);
+    try std.testing.expectEqual(@as(u32, 8), v2.get(&pfx8).?);
+    try std.testing.expect(v2.get(&pfx16) == null);
+    try std.testing.expect(v1.get(&pfx16) != null);
+}
+
+test "Table delete purges emptied nodes" {
+    const allocator = std.testing.allocator;
+    var table = Table(u32).init(allocator);
+    defer table.deinit();
+
+    // /12 and /20 are stored inside nodes below the /8, not path-compressed
+    const pfx8 = Prefix.init(&IPAddr{ .v4 = .{ 10, 0, 0, 0 } }, 8);
+    const pfx12 = Prefix.init(&IPAddr{ .v4 = .{ 10, 16, 0, 0 } }, 12);
+    const pfx20 = Prefix.init(&IPAddr{ .v4 = .{ 10, 16, 16, 0 } }, 20);
+    table.insert(&pfx8, 8);
+    table.insert(&pfx12, 12);
+    table.insert(&pfx20, 20);
+
+    try std.testing.expectEqual(@as(u32, 12), table.get(&pfx12).?);
+    try std.testing.expectEqual(@as(u32, 20), table.get(&pfx20).?);
+
+    try std.testing.expectEqual(@as(u32, 20), table.getAndDelete(&pfx20).value);
+    try std.testing.expect(table.get(&pfx20) == null);
+    try std.testing.expectEqual(@as(u32, 12), table.getAndDelete(&pfx12).value);
+    try std.testing.expect(!table.getAndDelete(&pfx12).ok);
+    try std.testing.expectEqual(@as(u32, 8), table.getAndDelete(&pfx8).value);
+
+    // std.testing.allocator reports the nodes if they were not freed
+    try std.testing.expectEqual(@as(usize, 0), table.size());
+    try std.testing.expect(table.root4.isEmpty());
+}
+
 test "Table lookupPrefixLPM basic" {
     const allocator = std.testing.allocator;
     var table = Table(u32).init(allocator);
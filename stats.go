@@ -0,0 +1,55 @@
+package zart
+
+// Stats describes the memory use and shape of the trie of a table.
+type Stats struct {
+	IPv4, IPv6 FamilyStats
+}
+
+// FamilyStats describes the trie of one address family.
+//
+// Prefixes are stored in trie nodes or, path compressed, as leaves and
+// fringes in place of a child node. The pure-Go backend only compresses
+// paths with WithPathCompression. Nodes shared with persistent versions
+// of the table are counted in every version.
+type FamilyStats struct {
+	Prefixes int
+	Nodes    int
+	Leaves   int
+	Fringes  int
+
+	// Bytes is the memory held by the nodes as allocated by the trie,
+	// without allocator overhead. Payloads on the Go side are not
+	// included.
+	Bytes int
+
+	// NodesPerLevel holds the number of nodes per depth, down to the
+	// deepest node. Depth d is the stride of address bits 8d to 8d+7.
+	NodesPerLevel []int
+}
+
+// Bytes returns the memory held by both tries.
+func (s Stats) Bytes() int {
+	return s.IPv4.Bytes + s.IPv6.Bytes
+}
+
+// Nodes returns the number of nodes of both tries.
+func (s Stats) Nodes() int {
+	return s.IPv4.Nodes + s.IPv6.Nodes
+}
+
+// Stats walks the trie and reports its statistics, it takes time linear
+// in the number of nodes. Use Size for the prefix counts alone.
+func (t *Table[V]) Stats() Stats {
+	v4, v6 := t.trie.stats()
+	return Stats{IPv4: v4, IPv6: v6}
+}
+
+// trimLevels returns the node counts per depth without the empty levels
+// below the deepest node.
+func trimLevels(levels []int) []int {
+	n := len(levels)
+	for n > 0 && levels[n-1] == 0 {
+		n--
+	}
+	return levels[:n:n]
+}
@@ -0,0 +1,138 @@
+package zart
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	empty := tbl.Stats()
+	if empty.IPv4.Prefixes != 0 || empty.IPv6.Prefixes != 0 {
+		t.Errorf("empty table: %+v", empty)
+	}
+
+	for i := range 1000 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24), i)
+	}
+	tbl.Insert(mpp("2001:db8::/32"), 0)
+	tbl.Insert(mpp("2001:db8:1:2::/64"), 0)
+	tbl.Insert(mpp("2001:db8:1:2::1/128"), 0)
+
+	s := tbl.Stats()
+	for _, f := range []struct {
+		name string
+		fs   FamilyStats
+		size int
+	}{{"IPv4", s.IPv4, tbl.Size4()}, {"IPv6", s.IPv6, tbl.Size6()}} {
+		if f.fs.Prefixes != f.size {
+			t.Errorf("%s: %d prefixes, want %d", f.name, f.fs.Prefixes, f.size)
+		}
+		sum := 0
+		for _, n := range f.fs.NodesPerLevel {
+			sum += n
+		}
+		if sum != f.fs.Nodes || f.fs.NodesPerLevel[0] != 1 {
+			t.Errorf("%s: nodes per level %v, want a root and %d in total", f.name, f.fs.NodesPerLevel, f.fs.Nodes)
+		}
+		if f.fs.Leaves+f.fs.Fringes > f.fs.Prefixes {
+			t.Errorf("%s: %d leaves and %d fringes for %d prefixes", f.name, f.fs.Leaves, f.fs.Fringes, f.fs.Prefixes)
+		}
+	}
+	if len(s.IPv4.NodesPerLevel) > 4 {
+		t.Errorf("IPv4 trie deeper than 4 levels: %v", s.IPv4.NodesPerLevel)
+	}
+	if s.Bytes() <= empty.Bytes() || s.Nodes() != s.IPv4.Nodes+s.IPv6.Nodes {
+		t.Errorf("Bytes %d (empty %d), Nodes %d", s.Bytes(), empty.Bytes(), s.Nodes())
+	}
+}
+
+func TestShape(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+	for i := range 256 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i), 0, 0}), 16), i)
+	}
+	tbl.Insert(mpp("2001:db8::/32"), 0)
+	tbl.Insert(mpp("2001:db8::1/128"), 0)
+
+	sh, st := tbl.Shape(), tbl.Stats()
+	for _, f := range []struct {
+		name  string
+		shape FamilyShape
+		stats FamilyStats
+	}{{"IPv4", sh.IPv4, st.IPv4}, {"IPv6", sh.IPv6, st.IPv6}} {
+		nodes, stored, hist := 0, 0, 0
+		for d, l := range f.shape.Levels {
+			if l.Nodes != f.stats.NodesPerLevel[d] {
+				t.Errorf("%s level %d: %d nodes, Stats reports %d", f.name, d, l.Nodes, f.stats.NodesPerLevel[d])
+			}
+			if l.PrefixFill() < 0 || l.PrefixFill() > 1 || l.ChildFill() < 0 || l.ChildFill() > 1 {
+				t.Errorf("%s level %d: fill %f, %f", f.name, d, l.PrefixFill(), l.ChildFill())
+			}
+			nodes += l.Nodes
+			stored += l.Prefixes + l.Leaves + l.Fringes
+		}
+		for _, n := range f.shape.ChildFill {
+			hist += n
+		}
+		if nodes != f.stats.Nodes || stored != f.stats.Prefixes || hist != nodes {
+			t.Errorf("%s: %d nodes, %d prefixes, %d in ChildFill; Stats: %d nodes, %d prefixes",
+				f.name, nodes, stored, hist, f.stats.Nodes, f.stats.Prefixes)
+		}
+	}
+
+	// 10.0.0.0/8 has a child for every second octet
+	if sh.IPv4.Levels[1].Children != 256 || sh.IPv4.ChildFill[9] != 1 {
+		t.Errorf("IPv4 level 1: %+v, ChildFill %v", sh.IPv4.Levels[1], sh.IPv4.ChildFill)
+	}
+}
+
+func TestPathCompression(t *testing.T) {
+	plain, comp := New[int](), New[int](WithPathCompression())
+	defer plain.Close()
+	defer comp.Close()
+
+	var pfxs []netip.Prefix
+	for i := range 500 {
+		a := mpa("2001:db8::").As16()
+		a[4], a[5], a[7] = byte(i>>8), byte(i), byte(i*7)
+		pfxs = append(pfxs, netip.PrefixFrom(netip.AddrFrom16(a), 48+16*(i%2)).Masked())
+	}
+	for i, pfx := range pfxs {
+		plain.Insert(pfx, i)
+		comp.Insert(pfx, i)
+	}
+	// a route covering the others, both ways of a pushed down leaf
+	comp.Insert(mpp("2001:db8::/32"), -1)
+	comp.Delete(mpp("2001:db8::/32"))
+
+	ps, cs := plain.Stats().IPv6, comp.Stats().IPv6
+	if cs.Prefixes != len(pfxs) || cs.Leaves+cs.Fringes == 0 || cs.Nodes > ps.Nodes {
+		t.Errorf("compressed %+v, plain %+v", cs, ps)
+	}
+	leaves, fringes := 0, 0
+	for _, l := range comp.Shape().IPv6.Levels {
+		leaves += l.Leaves
+		fringes += l.Fringes
+	}
+	if leaves != cs.Leaves || fringes != cs.Fringes {
+		t.Errorf("Shape counts %d leaves, %d fringes, Stats %d, %d", leaves, fringes, cs.Leaves, cs.Fringes)
+	}
+
+	for i, pfx := range pfxs {
+		if v, ok := comp.Lookup(pfx.Addr()); !ok || v != i {
+			t.Fatalf("Lookup(%s) = %d, %v, want %d", pfx.Addr(), v, ok, i)
+		}
+	}
+	c := comp.Clone()
+	defer c.Close()
+	if !c.Equal(plain, func(a, b int) bool { return a == b }) {
+		t.Error("clone of the compressed table differs")
+	}
+	if err := comp.Fsck(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,442 @@
+package zart
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+)
+
+// Store directory layout:
+//
+//	snapshot  the table as written by MarshalBinary, or by BuildShared
+//	          for a lazy store
+//	log       the changes since the snapshot, one record each
+//
+// A log record is a uvarint body length, the body and the CRC-32C of the
+// body as a big endian uint32. The body is an op byte, '+' for an insert
+// or update and '-' for a delete, the prefix encoded as in a snapshot
+// and, for '+', the payload. Replaying the log on the snapshot it was
+// started after, or on a later one, yields the table it was written for.
+const (
+	storeSnapshot = "snapshot"
+	storeLog      = "log"
+
+	logInsert = '+'
+	logDelete = '-'
+
+	defaultSnapshotEvery = 100_000
+)
+
+// StoreConfig tunes a Store, the zero value takes the defaults.
+type StoreConfig struct {
+	// SnapshotEvery is the number of logged changes after which
+	// Insert and Delete write a new snapshot and truncate the log,
+	// 100000. A negative value leaves snapshots to Snapshot.
+	SnapshotEvery int
+
+	// Sync makes Insert and Delete flush the log to stable storage
+	// before returning. Otherwise it is flushed by Sync, Snapshot and
+	// Close, and the changes since are lost when the host crashes.
+	Sync bool
+
+	// Lazy writes the snapshots in the format of BuildShared and opens
+	// them like OpenShared, mapping the file instead of loading it.
+	// Lookup and Contains are answered from the mapping as long as the
+	// log is empty, the table is built from it on the first call of
+	// Table, Insert, Delete or Snapshot, or of a lookup with changes
+	// logged since the snapshot.
+	Lazy bool
+}
+
+// Store is a Table kept on disk in a directory, as a snapshot and an
+// append-only log of the changes made since. Every change of the table
+// is written to the log as it is applied to the trie, whichever method
+// makes it, except the wholesale replacement by UnmarshalBinary.
+// Reopening the directory bulk loads the snapshot and replays the log,
+// which ends at the first torn or corrupt record: one cut short by a
+// crash is dropped. With StoreConfig.Lazy the snapshot is mapped instead
+// and loaded on first use, so reopening takes the time to map it and
+// verify its checksum.
+//
+// The payloads are encoded as for MarshalBinary and must be of a type
+// it accepts. Like Table, a Store is not safe for concurrent use.
+type Store[V any] struct {
+	t    *Table[V] // nil until a lazy store is loaded
+	dir  string
+	cfg  StoreConfig
+	opts []Option
+
+	// snap is the mapped snapshot of a lazy store and pending the log
+	// records to replay on it, until the table is built.
+	snap    *SharedTable[V]
+	pending []logRecord[V]
+	closed  bool
+
+	log *os.File
+	w   *bufio.Writer
+	n   int // records in the log
+	rec []byte
+	err error // the first error writing the log
+}
+
+// OpenStore opens the store in dir, creating the directory if needed.
+// opts configure the table as for New.
+func OpenStore[V any](dir string, cfg StoreConfig, opts ...Option) (*Store[V], error) {
+	if cfg.SnapshotEvery == 0 {
+		cfg.SnapshotEvery = defaultSnapshotEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store[V]{dir: dir, cfg: cfg, opts: opts}
+	if err := s.load(); err != nil {
+		if s.t != nil {
+			s.t.Close()
+		}
+		if s.snap != nil {
+			s.snap.Close()
+		}
+		return nil, err
+	}
+	if s.t != nil {
+		s.t.hook(s.append)
+	} else if !cfg.Lazy {
+		s.table()
+	}
+	return s, nil
+}
+
+// load reads the snapshot and replays the log into the table, leaving
+// the log open for appending after its last complete record. A snapshot
+// in the shared format is kept mapped in snap, the log for it in
+// pending.
+func (s *Store[V]) load() error {
+	path := filepath.Join(s.dir, storeSnapshot)
+	data, unmap, err := mapFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.t = New[V](s.opts...)
+	case err != nil:
+		return err
+	case bytes.HasPrefix(data, []byte(sharedMagic)):
+		s.snap = &SharedTable[V]{data: data, unmap: unmap}
+		if err := s.snap.parse(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		s.t = New[V](s.opts...)
+		err := s.t.UnmarshalBinary(data)
+		unmap()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, storeLog), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	var off int64
+	for {
+		rec, n, err := s.next(r)
+		if err != nil {
+			break
+		}
+		if s.t != nil {
+			rec.apply(s.t)
+		} else {
+			s.pending = append(s.pending, rec)
+		}
+		off += int64(n)
+		s.n++
+	}
+	if err := f.Truncate(off); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.log, s.w = f, bufio.NewWriter(f)
+	return nil
+}
+
+// logRecord is a decoded log record.
+type logRecord[V any] struct {
+	del bool
+	pfx netip.Prefix
+	val V
+}
+
+func (r *logRecord[V]) apply(t *Table[V]) {
+	if r.del {
+		t.Delete(r.pfx)
+	} else {
+		t.Insert(r.pfx, r.val)
+	}
+}
+
+// next decodes the next log record of r and returns it with its size. A
+// truncated or corrupt record ends the log.
+func (s *Store[V]) next(r *bufio.Reader) (logRecord[V], int, error) {
+	var lr logRecord[V]
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return lr, 0, err
+	}
+	if size > 1<<30 {
+		return lr, 0, errCorrupt
+	}
+	rec := make([]byte, size+4)
+	if _, err := io.ReadFull(r, rec); err != nil {
+		return lr, 0, err
+	}
+	body := rec[:size]
+	if binary.BigEndian.Uint32(rec[size:]) != crc32.Checksum(body, castagnoli) {
+		return lr, 0, errCorrupt
+	}
+	if len(body) == 0 {
+		return lr, 0, errCorrupt
+	}
+	var rt route
+	b, err := decodeRoute(body[1:], &rt)
+	if err != nil {
+		return lr, 0, err
+	}
+	lr.pfx = rt.prefix()
+	switch body[0] {
+	case logInsert:
+		if b, err = decodePayload(b, &lr.val); err != nil {
+			return lr, 0, err
+		}
+		if len(b) != 0 {
+			return lr, 0, errCorrupt
+		}
+	case logDelete:
+		lr.del = true
+	default:
+		return lr, 0, errCorrupt
+	}
+	return lr, uvarintLen(size) + len(rec), nil
+}
+
+// table returns the table, building a lazy store's from the mapped
+// snapshot and the pending log records first.
+func (s *Store[V]) table() *Table[V] {
+	if s.t != nil {
+		return s.t
+	}
+	t := New[V](s.opts...)
+	t.InsertBatch(s.snap.entries())
+	s.snap.Close()
+	s.snap = nil
+	for i := range s.pending {
+		s.pending[i].apply(t)
+	}
+	s.pending = nil
+	t.hook(s.append)
+	s.t = t
+	return t
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+// append writes the record of ev to the log, it is the hook of the
+// table.
+func (s *Store[V]) append(ev Event[V]) {
+	if s.err != nil {
+		return
+	}
+	op, val := byte(logInsert), ev.New
+	if ev.Kind == EventDelete {
+		op = logDelete
+	}
+	rt := makeRoute(ev.Prefix, 0)
+	body := appendRoute(append(s.rec[:0], op), &rt)
+	if op == logInsert {
+		var err error
+		if body, err = appendPayload(body, val); err != nil {
+			s.err = err
+			return
+		}
+	}
+	s.rec = body
+
+	var head [binary.MaxVarintLen64]byte
+	_, s.err = s.w.Write(head[:binary.PutUvarint(head[:], uint64(len(body)))])
+	if s.err == nil {
+		_, s.err = s.w.Write(body)
+	}
+	if s.err == nil {
+		s.err = binary.Write(s.w, binary.BigEndian, crc32.Checksum(body, castagnoli))
+	}
+	s.n++
+}
+
+// Table returns the table of the store, loading a lazy one. It may be
+// read and modified directly, modifications are logged, but must not be
+// closed.
+func (s *Store[V]) Table() *Table[V] {
+	return s.table()
+}
+
+// Lookup is Table.Lookup. A lazy store answers it from the mapped
+// snapshot while no changes are logged since.
+func (s *Store[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	if s.snap != nil && len(s.pending) == 0 {
+		return s.snap.Lookup(addr)
+	}
+	return s.table().Lookup(addr)
+}
+
+// Contains is Table.Contains, answered like Lookup.
+func (s *Store[V]) Contains(addr netip.Addr) bool {
+	if s.snap != nil && len(s.pending) == 0 {
+		return s.snap.Contains(addr)
+	}
+	return s.table().Contains(addr)
+}
+
+// Insert is Table.Insert, it returns the first error writing the log.
+func (s *Store[V]) Insert(pfx netip.Prefix, val V) error {
+	s.table().Insert(pfx, val)
+	return s.commit()
+}
+
+// Delete is Table.Delete, err is the first error writing the log.
+func (s *Store[V]) Delete(pfx netip.Prefix) (ok bool, err error) {
+	ok = s.table().Delete(pfx)
+	return ok, s.commit()
+}
+
+// commit ends a change: it flushes the log with Sync and writes a
+// snapshot once the log is long enough.
+func (s *Store[V]) commit() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.cfg.SnapshotEvery > 0 && s.n >= s.cfg.SnapshotEvery {
+		return s.Snapshot()
+	}
+	if s.cfg.Sync {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Err returns the first error writing the log. After it the store logs
+// nothing more, the table keeps its changes in memory only.
+func (s *Store[V]) Err() error {
+	return s.err
+}
+
+// Sync flushes the log to stable storage.
+func (s *Store[V]) Sync() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.err = s.w.Flush(); s.err != nil {
+		return s.err
+	}
+	s.err = s.log.Sync()
+	return s.err
+}
+
+// Snapshot writes a new snapshot of the table and truncates the log. The
+// snapshot is written next to the old one and renamed into place, a
+// crash leaves either the old snapshot with the complete log or the new
+// one.
+func (s *Store[V]) Snapshot() error {
+	if err := s.Sync(); err != nil {
+		return err
+	}
+	var data []byte
+	var err error
+	if s.cfg.Lazy {
+		data, err = s.table().encodeShared()
+	} else {
+		data, err = s.table().MarshalBinary()
+	}
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, storeSnapshot)
+	tmp, err := os.CreateTemp(s.dir, storeSnapshot+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if s.err = s.log.Truncate(0); s.err != nil {
+		return s.err
+	}
+	if _, s.err = s.log.Seek(0, io.SeekStart); s.err != nil {
+		return s.err
+	}
+	s.w.Reset(s.log)
+	s.n = 0
+	return nil
+}
+
+// syncDir flushes a rename in dir to stable storage where the system
+// supports syncing a directory.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// Close flushes the log, closes it and the table. It returns the first
+// error writing the log.
+func (s *Store[V]) Close() error {
+	if s.closed {
+		return s.err
+	}
+	s.closed = true
+	err := s.Sync()
+	if cerr := s.log.Close(); err == nil {
+		err = cerr
+	}
+	if s.snap != nil {
+		s.snap.Close()
+	}
+	if s.t != nil {
+		s.t.Close()
+	}
+	return err
+}
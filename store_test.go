@@ -0,0 +1,201 @@
+package zart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore[string](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Insert(mpp("10.0.0.0/8"), "a")
+	s.Insert(mpp("10.1.0.0/16"), "b")
+	s.Insert(mpp("2001:db8::/32"), "c")
+	if ok, err := s.Delete(mpp("10.1.0.0/16")); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v", ok, err)
+	}
+	// changes made on the table directly are logged too
+	s.Table().Insert(mpp("192.168.0.0/24"), "d")
+	s.Table().DeleteSubtree(mpp("2001:db8::/32"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore[string](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"10.0.0.0/8": "a", "192.168.0.0/24": "d"}
+	checkStore(t, s, want)
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, storeLog)); err != nil || fi.Size() != 0 {
+		t.Fatalf("log after Snapshot: %v, %v", fi, err)
+	}
+	s.Insert(mpp("10.0.0.0/8"), "e")
+	want["10.0.0.0/8"] = "e"
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore[string](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	checkStore(t, s, want)
+}
+
+func TestStoreTornLog(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore[int](dir, StoreConfig{Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Insert(mpp("10.0.0.0/8"), 1)
+	s.Insert(mpp("10.1.0.0/16"), 2)
+	s.Close()
+
+	// cut the last record short as a crash while appending would
+	path := filepath.Join(dir, storeLog)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, fi.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore[int](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Table().Size() != 1 {
+		t.Errorf("size after a torn record = %d, want 1", s.Table().Size())
+	}
+	s.Insert(mpp("10.2.0.0/16"), 3)
+	s.Close()
+
+	s, err = OpenStore[int](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Table().Size() != 2 {
+		t.Errorf("size after appending past the torn record = %d, want 2", s.Table().Size())
+	}
+}
+
+func TestStoreSnapshotEvery(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore[int](dir, StoreConfig{SnapshotEvery: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 25 {
+		if err := s.Insert(mpp("10.0.0.0/8"), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.n != 5 {
+		t.Errorf("%d records in the log, want 5", s.n)
+	}
+	s.Close()
+
+	s, err = OpenStore[int](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, _ := s.Table().Get(mpp("10.0.0.0/8")); v != 24 {
+		t.Errorf("value after reopening = %d, want 24", v)
+	}
+}
+
+func TestStoreLazy(t *testing.T) {
+	dir := t.TempDir()
+	cfg := StoreConfig{Lazy: true}
+	s, err := OpenStore[string](dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{}
+	for _, pfx := range []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/23", "10.1.2.3/32", "::/0", "2001:db8::/32", "2001:db8::1/128"} {
+		s.Insert(mpp(pfx), pfx)
+		want[pfx] = pfx
+	}
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// opened from the mapping, the table is built on first use
+	s, err = OpenStore[string](dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.t != nil {
+		t.Fatalf("lazy store loaded the table on open")
+	}
+	if v, ok := s.Lookup(mpa("10.1.3.1")); !ok || v != "10.1.2.0/23" {
+		t.Errorf("Lookup from the mapping = %q, %v", v, ok)
+	}
+	if !s.Contains(mpa("2001:db8::2")) || s.t != nil {
+		t.Errorf("Contains from the mapping failed or loaded the table")
+	}
+	checkStore(t, s, want)
+	s.Insert(mpp("192.168.0.0/16"), "x")
+	want["192.168.0.0/16"] = "x"
+	s.Close()
+
+	// the log is kept for the table until it is built
+	s, err = OpenStore[string](dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Lookup(mpa("192.168.1.1")); !ok || v != "x" {
+		t.Errorf("Lookup of a logged change = %q, %v", v, ok)
+	}
+	checkStore(t, s, want)
+	s.Close()
+
+	// a store opened eagerly reads the lazy snapshot too
+	s, err = OpenStore[string](dir, StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.t == nil {
+		t.Fatalf("store did not load the table on open")
+	}
+	checkStore(t, s, want)
+}
+
+func TestStoreUnsupportedPayload(t *testing.T) {
+	s, err := OpenStore[map[int]int](t.TempDir(), StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Insert(mpp("10.0.0.0/8"), nil); err == nil {
+		t.Error("Insert of an unsupported payload logged")
+	}
+}
+
+func checkStore(t *testing.T, s *Store[string], want map[string]string) {
+	t.Helper()
+	if s.Table().Size() != len(want) {
+		t.Errorf("size = %d, want %d", s.Table().Size(), len(want))
+	}
+	for pfx, v := range want {
+		if got, ok := s.Table().Get(mpp(pfx)); !ok || got != v {
+			t.Errorf("Get(%s) = %q, %v, want %q", pfx, got, ok, v)
+		}
+	}
+}
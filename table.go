@@ -0,0 +1,486 @@
+// Package zart is a Go binding for ZART, a Zig implementation of the
+// BART (Balanced Routing Table) multibit trie for IPv4 and IPv6
+// longest-prefix matching.
+//
+// The trie lives in libbart.a, built from the Zig sources with
+//
+//	zig build -Doptimize=ReleaseFast
+//
+// and is linked into the Go program through the C ABI in include/bart.h.
+// Builds with the purego tag, or with cgo disabled, use a pure-Go port of
+// the same trie instead; the API is identical.
+//
+// All keys are net/netip types. They are converted into the C key
+// layout on the stack, a lookup does not allocate.
+//
+// The C trie stores a 32-bit value per prefix. Table[V] keeps the actual
+// payloads on the Go side in a slot registry and stores only the slot
+// number in C, so any Go type can be used as payload.
+package zart
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"slices"
+)
+
+// Table is an IPv4 and IPv6 routing table with payload V.
+//
+// A Table must be created with New and released with Close, the memory
+// of the underlying trie is not managed by the Go garbage collector. A
+// table that is never closed is released by a cleanup once it becomes
+// unreachable, as a safety net; see TrackLeaks for finding such tables.
+// A Table is not safe for concurrent use, see ConcurrentTable.
+type Table[V any] struct {
+	trie  *trie
+	vals  *registry[V]
+	watch *watchers[V]    // nil until Watch is called
+	ttl   *expiries[V]    // nil until InsertWithTTL or OnExpire is called
+	tags  *tagSet         // nil until a prefix is tagged
+	cps   *checkpoints[V] // nil until Checkpoint is called
+	mem   *memBudget      // nil until the first insert with WithMaxMemory
+
+	opts   options
+	closed bool
+
+	// changes counts the modifications of the table, a LookupCache
+	// drops its entries when it moves
+	changes uint64
+}
+
+// Cloner is implemented by payloads that must be deep copied when a
+// table is cloned, Clone returns the copy.
+type Cloner[V any] interface {
+	Clone() V
+}
+
+// New returns an empty routing table configured by opts.
+func New[V any](opts ...Option) *Table[V] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	t := &Table[V]{trie: newTrie(o), vals: new(registry[V]), opts: o}
+	return t.audited()
+}
+
+// Clone returns an independent copy of the table, both tables can be
+// modified and closed separately. The trie is copied in C without a
+// round trip through Go, payloads implementing Cloner[V] are cloned,
+// all others are copied by assignment.
+func (t *Table[V]) Clone() *Table[V] {
+	c := &Table[V]{trie: t.trie.clone(), vals: t.vals.clone(), tags: t.tags.clone(), opts: t.opts}
+	return c.audited()
+}
+
+// Close releases the underlying trie and all payloads, payloads shared
+// with persistent versions are kept until the last one is closed.
+// Closing a table again does nothing, all other methods fail with
+// ErrClosed afterwards.
+func (t *Table[V]) Close() {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	t.release()
+}
+
+// release drops the trie and payloads of the table and the deadlines and
+// tags of its prefixes.
+func (t *Table[V]) release() {
+	t.changes++
+	t.releaseCheckpoints()
+	t.trie.close()
+	t.vals.reset()
+	if t.ttl != nil {
+		t.ttl.reset()
+	}
+	t.tags = nil
+	if t.watch != nil {
+		t.watch.closeAll()
+	}
+}
+
+// Size returns the number of prefixes in the table. The counters are
+// maintained by the trie on every change, Size, Size4 and Size6 take
+// constant time.
+func (t *Table[V]) Size() int {
+	return t.trie.size4() + t.trie.size6()
+}
+
+// Size4 returns the number of IPv4 prefixes in the table.
+func (t *Table[V]) Size4() int {
+	return t.trie.size4()
+}
+
+// Size6 returns the number of IPv6 prefixes in the table.
+func (t *Table[V]) Size6() int {
+	return t.trie.size6()
+}
+
+// Insert adds pfx to the table with value val. An existing value for the
+// same prefix is overwritten. Host bits of pfx are masked off, invalid
+// prefixes and new ones over the memory limit are ignored; see TryInsert
+// for an error instead.
+func (t *Table[V]) Insert(pfx netip.Prefix, val V) {
+	t.insert(pfx, val)
+}
+
+// insert is Insert reporting whether pfx was stored, it is false for the
+// prefixes Insert ignores.
+func (t *Table[V]) insert(pfx netip.Prefix, val V) bool {
+	t.mutable()
+	if !t.accepts(pfx) || !t.room(pfx) {
+		return false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	slot := t.vals.alloc(val)
+	t.changes++
+
+	var old uint32
+	var existed bool
+	if addr.Is4() {
+		a4 := addr.As4()
+		old, existed = t.trie.insert4(binary.BigEndian.Uint32(a4[:]), bits, slot)
+	} else {
+		a16 := addr.As16()
+		old, existed = t.trie.insert6(&a16, bits, slot)
+	}
+	if existed {
+		t.count(EventUpdate, 1)
+	} else {
+		t.count(EventInsert, 1)
+	}
+	if t.watch != nil {
+		ev := Event[V]{Kind: EventInsert, Prefix: pfx.Masked(), New: val}
+		if existed {
+			ev.Kind, ev.Old = EventUpdate, t.vals.get(old)
+		}
+		t.watch.notify(ev)
+	}
+	if existed {
+		if t.vals.hits != nil {
+			t.vals.moveHits(old, slot)
+		}
+		t.vals.release(old)
+	}
+	if t.ttl != nil {
+		t.ttl.forget(pfx.Masked())
+	}
+	return true
+}
+
+// Delete removes pfx from the table and reports whether it was present.
+// The payload slot of the removed prefix is released.
+func (t *Table[V]) Delete(pfx netip.Prefix) bool {
+	if !pfx.IsValid() {
+		return false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+
+	var old uint32
+	var ok bool
+	if addr.Is4() {
+		a4 := addr.As4()
+		old, ok = t.trie.delete4(binary.BigEndian.Uint32(a4[:]), bits)
+	} else {
+		a16 := addr.As16()
+		old, ok = t.trie.delete6(&a16, bits)
+	}
+	if ok {
+		t.changes++
+		t.count(EventDelete, 1)
+		if t.watch != nil {
+			t.watch.notify(Event[V]{Kind: EventDelete, Prefix: pfx.Masked(), Old: t.vals.get(old)})
+		}
+		t.vals.release(old)
+		if t.ttl != nil {
+			t.ttl.forget(pfx.Masked())
+		}
+		if t.tags != nil {
+			t.tags.forget(pfx.Masked())
+		}
+	}
+	return ok
+}
+
+// DeleteSubtree removes pfx and every prefix covered by it and returns
+// the number of prefixes removed. The routes are collected and removed in
+// one call into the trie, a second one is only needed if there are more
+// than a few dozen of them.
+func (t *Table[V]) DeleteSubtree(pfx netip.Prefix) int {
+	if !pfx.IsValid() {
+		return 0
+	}
+	q := makeRoute(pfx.Masked(), 0)
+	routes := fill(64, func(out []route) int { return t.trie.deleteSubtree(&q, out) })
+	t.deleted(routes)
+	return len(routes)
+}
+
+// DeleteIf removes every prefix for which fn returns true and returns
+// their number, as when pruning the stale routes of a feed. fn sees the
+// prefixes in the order of All, in batches of one walk of the trie, and
+// must not modify the table. The chosen prefixes are then removed with a
+// single call into the trie rather than a Delete each.
+func (t *Table[V]) DeleteIf(fn func(pfx netip.Prefix, val V) bool) int {
+	var doomed []route
+	t.trie.walk(func(batch []route) bool {
+		for i := range batch {
+			if fn(batch[i].prefix(), t.vals.get(batch[i].val)) {
+				doomed = append(doomed, batch[i])
+			}
+		}
+		return true
+	})
+	t.trie.deleteBulk(doomed)
+	t.deleted(doomed)
+	return len(doomed)
+}
+
+// deleted does the bookkeeping for routes removed from the trie at once:
+// it notifies the watchers, releases the payloads and forgets the TTLs
+// and tags.
+func (t *Table[V]) deleted(routes []route) {
+	if len(routes) > 0 {
+		t.changes++
+		t.count(EventDelete, len(routes))
+	}
+	for i := range routes {
+		if t.watch != nil {
+			t.watch.notify(Event[V]{Kind: EventDelete, Prefix: routes[i].prefix(), Old: t.vals.get(routes[i].val)})
+		}
+		t.vals.release(routes[i].val)
+		if t.ttl != nil {
+			t.ttl.forget(routes[i].prefix())
+		}
+		if t.tags != nil {
+			t.tags.forget(routes[i].prefix())
+		}
+	}
+}
+
+// InsertCovering inserts pfx with value val like Insert and removes every
+// more specific prefix it covers, as when a summary replaces the routes
+// it aggregates. It returns the number of prefixes removed; a value
+// already stored for pfx itself is overwritten, not counted. If Insert
+// ignores pfx, over the memory limit say, nothing is removed.
+//
+// The covered prefixes are found by one walk below pfx and removed with
+// a single call into the trie.
+func (t *Table[V]) InsertCovering(pfx netip.Prefix, val V) int {
+	if !t.insert(pfx, val) {
+		return 0
+	}
+	pfx = pfx.Masked()
+	q := makeRoute(pfx, 0)
+	routes := fill(64, func(out []route) int { return t.trie.subnets(&q, out) })
+	routes = slices.DeleteFunc(routes, func(r route) bool { return r.prefix() == pfx })
+	t.trie.deleteBulk(routes)
+	t.deleted(routes)
+	return len(routes)
+}
+
+// Get returns the value stored for exactly pfx, without a longest-prefix
+// match; ok is false if pfx is not in the table. Host bits of pfx are
+// ignored as for Insert.
+func (t *Table[V]) Get(pfx netip.Prefix) (val V, ok bool) {
+	slot, ok := t.slot(pfx)
+	if !ok {
+		return val, false
+	}
+	return t.vals.get(slot), true
+}
+
+// slot returns the payload slot of exactly pfx.
+func (t *Table[V]) slot(pfx netip.Prefix) (slot uint32, ok bool) {
+	if !pfx.IsValid() {
+		return 0, false
+	}
+	addr, bits := pfx.Addr(), uint8(pfx.Bits())
+	if addr.Is4() {
+		a4 := addr.As4()
+		return t.trie.get4(binary.BigEndian.Uint32(a4[:]), bits)
+	}
+	a16 := addr.As16()
+	return t.trie.get6(&a16, bits)
+}
+
+// Lookup performs a longest-prefix match for addr and returns the value of
+// the matching prefix, ok is false if no prefix matched. IPv4-mapped
+// addresses are looked up as IPv6 unless the table was created
+// WithMapped(MappedAsIPv4).
+func (t *Table[V]) Lookup(addr netip.Addr) (val V, ok bool) {
+	if lt, on := t.timer(); on {
+		val, ok = t.lookup(addr)
+		lt.stop(t.key(addr).Is4(), ok)
+		return val, ok
+	}
+	return t.lookup(addr)
+}
+
+func (t *Table[V]) lookup(addr netip.Addr) (val V, ok bool) {
+	addr = t.key(addr)
+	var slot uint32
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		slot, ok = t.trie.lookup4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		slot, ok = t.trie.lookup6(&a16)
+	}
+	if !ok {
+		return val, false
+	}
+	return t.payload(slot), true
+}
+
+// Lookup4Raw is Lookup for an IPv4 address given as an integer, the first
+// octet in the most significant byte as binary.BigEndian.Uint32 reads it
+// from a packet header: 10.0.0.1 is 0x0a000001. Unlike building a
+// netip.Addr per packet it is free of allocations and conversions, the
+// call allocates nothing and lets nothing escape to the heap.
+func (t *Table[V]) Lookup4Raw(addr uint32) (val V, ok bool) {
+	slot, ok := t.trie.lookup4(addr)
+	if !ok {
+		return val, false
+	}
+	return t.payload(slot), true
+}
+
+// Lookup6Raw is Lookup for an IPv6 address given as 16 bytes in network
+// order, with the guarantees of Lookup4Raw: addr does not escape and may
+// point into a packet buffer. The bytes are looked up as they are, the
+// MappedPolicy of the table does not apply.
+func (t *Table[V]) Lookup6Raw(addr *[16]byte) (val V, ok bool) {
+	slot, ok := t.trie.lookup6(addr)
+	if !ok {
+		return val, false
+	}
+	return t.payload(slot), true
+}
+
+// Lookup6HiLo is Lookup6Raw for an IPv6 address given as its high and
+// low 64 bits, hi holding the first 8 bytes as binary.BigEndian.Uint64
+// reads them. The halves are passed to the trie by value, for callers
+// that decode addresses into registers and would otherwise store them
+// into an array only to hand out its address.
+func (t *Table[V]) Lookup6HiLo(hi, lo uint64) (val V, ok bool) {
+	slot, ok := t.trie.lookup6HiLo(hi, lo)
+	if !ok {
+		return val, false
+	}
+	return t.payload(slot), true
+}
+
+// Insert6HiLo is Insert for the IPv6 prefix with the address halves hi,
+// lo of Lookup6HiLo and bits. The watchers and the other bookkeeping of
+// Insert need the netip.Prefix, so unlike the lookup it is a convenience
+// rather than a shortcut; RawTable.Insert6HiLo goes straight to the trie.
+func (t *Table[V]) Insert6HiLo(hi, lo uint64, bits int, val V) {
+	t.Insert(netip.PrefixFrom(netip.AddrFrom16(addr6HiLo(hi, lo)), bits), val)
+}
+
+// addr6HiLo returns the 16 bytes of the address with the halves hi, lo.
+func addr6HiLo(hi, lo uint64) [16]byte {
+	var a [16]byte
+	binary.BigEndian.PutUint64(a[:8], hi)
+	binary.BigEndian.PutUint64(a[8:], lo)
+	return a
+}
+
+// payload returns the value in the slot found by a lookup and counts the
+// hit.
+func (t *Table[V]) payload(slot uint32) V {
+	if t.vals.hits != nil {
+		t.vals.hit(slot)
+	}
+	return t.vals.get(slot)
+}
+
+// key applies the MappedPolicy of the table to a lookup address.
+func (t *Table[V]) key(addr netip.Addr) netip.Addr {
+	if t.opts.mapped == MappedAsIPv4 {
+		return addr.Unmap()
+	}
+	return addr
+}
+
+// Contains reports whether any prefix in the table covers addr. It is
+// cheaper than Lookup: the walk stops at the first covering prefix and
+// no payload is fetched.
+func (t *Table[V]) Contains(addr netip.Addr) bool {
+	addr = t.key(addr)
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		return t.trie.contains4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		return t.trie.contains6(&a16)
+	}
+	return false
+}
+
+// LookupPrefix is like Lookup and additionally returns the matching
+// prefix, so callers can tell which route was selected for addr.
+func (t *Table[V]) LookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	if lt, on := t.timer(); on {
+		pfx, val, ok = t.lookupPrefix(addr)
+		lt.stop(t.key(addr).Is4(), ok)
+		return pfx, val, ok
+	}
+	return t.lookupPrefix(addr)
+}
+
+func (t *Table[V]) lookupPrefix(addr netip.Addr) (pfx netip.Prefix, val V, ok bool) {
+	addr = t.key(addr)
+	var slot uint32
+	var bits uint8
+	switch {
+	case addr.Is4():
+		a4 := addr.As4()
+		slot, bits, ok = t.trie.lookupPrefix4(binary.BigEndian.Uint32(a4[:]))
+	case addr.IsValid():
+		a16 := addr.As16()
+		slot, bits, ok = t.trie.lookupPrefix6(&a16)
+	}
+	if !ok {
+		return pfx, val, false
+	}
+	if t.vals.hits != nil {
+		t.vals.hit(slot)
+	}
+	pfx, _ = addr.Prefix(int(bits))
+	return pfx, t.vals.get(slot), true
+}
+
+// InsertPersist is like Insert but returns a new table with the change and
+// leaves t unmodified. The new table shares all unchanged trie nodes with
+// t, only the nodes on the path to pfx are copied.
+//
+// Both tables stay valid and writable, further changes to either one are
+// not visible in the other. Each must be closed with Close. Versions share
+// their payloads, overwritten and deleted payloads are only dropped once
+// all versions are closed. Versions must not be used concurrently.
+func (t *Table[V]) InsertPersist(pfx netip.Prefix, val V) *Table[V] {
+	p := t.persist()
+	p.Insert(pfx, val)
+	return p
+}
+
+// DeletePersist is like Delete but returns a new table without pfx and
+// leaves t unmodified, see InsertPersist.
+func (t *Table[V]) DeletePersist(pfx netip.Prefix) *Table[V] {
+	p := t.persist()
+	p.Delete(pfx)
+	return p
+}
+
+// persist returns a version of t that shares trie nodes and payloads.
+func (t *Table[V]) persist() *Table[V] {
+	t.vals.shared++
+	p := &Table[V]{trie: t.trie.persist(), vals: t.vals, opts: t.opts}
+	return p.audited()
+}
@@ -0,0 +1,141 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+)
+
+// Table2D is a classification table of rules matching a source and a
+// destination prefix, as used by firewalls and policy routing. A lookup
+// returns the rule with the longest destination prefix covering the
+// destination address among those whose source prefix covers the source
+// address, with the longest source prefix among these.
+//
+// A Table2D is a trie of tries: a Table of destination prefixes, each
+// holding a Table of the source prefixes of its rules. A lookup walks the
+// destination prefixes covering the address from the most specific one
+// and does a longest-prefix match of the source in each, until one
+// matches. Like Table, a Table2D is not safe for concurrent use.
+type Table2D[V any] struct {
+	t *Table[*Table[V]]
+	n int
+}
+
+// NewTable2D returns an empty Table2D.
+func NewTable2D[V any]() *Table2D[V] {
+	return &Table2D[V]{t: New[*Table[V]]()}
+}
+
+// Close releases the tables of all destination prefixes and the table of
+// destinations. Closing a table again does nothing.
+func (t *Table2D[V]) Close() {
+	if t.t.closed {
+		return
+	}
+	for _, src := range t.t.All() {
+		src.Close()
+	}
+	t.t.Close()
+}
+
+// Size returns the number of rules in the table.
+func (t *Table2D[V]) Size() int {
+	return t.n
+}
+
+// Insert adds the rule for traffic from src to dst with value val,
+// overwriting the value of an existing rule for the same prefixes. Rules
+// with an invalid prefix are ignored.
+func (t *Table2D[V]) Insert(src, dst netip.Prefix, val V) {
+	if !src.IsValid() || !dst.IsValid() {
+		return
+	}
+	srcs, ok := t.t.Get(dst)
+	if !ok {
+		srcs = New[V]()
+		t.t.Insert(dst, srcs)
+	}
+	if _, ok := srcs.Get(src); !ok {
+		t.n++
+	}
+	srcs.Insert(src, val)
+}
+
+// Delete removes the rule for src and dst and reports whether it was in
+// the table.
+func (t *Table2D[V]) Delete(src, dst netip.Prefix) bool {
+	srcs, ok := t.t.Get(dst)
+	if !ok || !srcs.Delete(src) {
+		return false
+	}
+	t.n--
+	if srcs.Size() == 0 {
+		t.t.Delete(dst)
+		srcs.Close()
+	}
+	return true
+}
+
+// Get returns the value of the rule for exactly src and dst.
+func (t *Table2D[V]) Get(src, dst netip.Prefix) (val V, ok bool) {
+	srcs, ok := t.t.Get(dst)
+	if !ok {
+		return val, false
+	}
+	return srcs.Get(src)
+}
+
+// Lookup returns the value of the rule matching traffic from src to dst,
+// see Table2D.
+func (t *Table2D[V]) Lookup(src, dst netip.Addr) (val V, ok bool) {
+	_, _, val, ok = t.LookupRule(src, dst)
+	return val, ok
+}
+
+// LookupRule is Lookup also returning the prefixes of the matching rule.
+func (t *Table2D[V]) LookupRule(src, dst netip.Addr) (srcPfx, dstPfx netip.Prefix, val V, ok bool) {
+	if !src.IsValid() {
+		return srcPfx, dstPfx, val, false
+	}
+	for pfx, srcs := range t.t.LookupAll(dst) {
+		if spfx, v, found := srcs.LookupPrefix(src); found {
+			return spfx, pfx, v, true
+		}
+	}
+	return srcPfx, dstPfx, val, false
+}
+
+// LookupAll returns an iterator over all rules matching traffic from src
+// to dst as pairs of source and destination prefix with their values, in
+// the order of preference of Lookup: by destination and for each
+// destination by source, from the most to the least specific prefix. The
+// first rule yielded is the one returned by Lookup.
+func (t *Table2D[V]) LookupAll(src, dst netip.Addr) iter.Seq2[[2]netip.Prefix, V] {
+	return func(yield func([2]netip.Prefix, V) bool) {
+		if !src.IsValid() {
+			return
+		}
+		for dpfx, srcs := range t.t.LookupAll(dst) {
+			for spfx, val := range srcs.LookupAll(src) {
+				if !yield([2]netip.Prefix{spfx, dpfx}, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// All returns an iterator over the rules of the table as pairs of source
+// and destination prefix with their values, by destination in the order
+// of All and for each destination by source.
+func (t *Table2D[V]) All() iter.Seq2[[2]netip.Prefix, V] {
+	return func(yield func([2]netip.Prefix, V) bool) {
+		for dst, srcs := range t.t.All() {
+			for src, val := range srcs.All() {
+				if !yield([2]netip.Prefix{src, dst}, val) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestTable2D(t *testing.T) {
+	tbl := NewTable2D[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("0.0.0.0/0"), mpp("0.0.0.0/0"), "default")
+	tbl.Insert(mpp("10.0.0.0/8"), mpp("192.168.0.0/16"), "lan")
+	tbl.Insert(mpp("10.1.0.0/16"), mpp("192.168.1.0/24"), "office")
+	tbl.Insert(mpp("172.16.0.0/12"), mpp("192.168.1.0/24"), "vpn")
+	tbl.Insert(mpp("2001:db8::/32"), mpp("::/0"), "v6")
+
+	tests := []struct {
+		src, dst string
+		want     string
+		wantOK   bool
+	}{
+		{"10.1.2.3", "192.168.1.1", "office", true},
+		{"172.16.9.9", "192.168.1.1", "vpn", true},
+		// no source matches under 192.168.1.0/24, backtrack to /16
+		{"10.2.0.1", "192.168.1.1", "lan", true},
+		// nor under /16, on to the default rule
+		{"8.8.8.8", "192.168.1.1", "default", true},
+		{"10.2.0.1", "192.168.2.1", "lan", true},
+		{"2001:db8::1", "2001:db8:1::1", "v6", true},
+		{"2001:db9::1", "2001:db8:1::1", "", false},
+		{"10.1.2.3", "2001:db8::1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tbl.Lookup(mpa(tt.src), mpa(tt.dst))
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Lookup(%s, %s) = %q, %v, want %q, %v", tt.src, tt.dst, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	src, dst, _, _ := tbl.LookupRule(mpa("10.2.0.1"), mpa("192.168.1.1"))
+	if src != mpp("10.0.0.0/8") || dst != mpp("192.168.0.0/16") {
+		t.Errorf("LookupRule = %s, %s", src, dst)
+	}
+
+	if tbl.Size() != 5 {
+		t.Fatalf("Size = %d, want 5", tbl.Size())
+	}
+	tbl.Insert(mpp("10.1.0.0/16"), mpp("192.168.1.0/24"), "office2")
+	if v, _ := tbl.Get(mpp("10.1.0.0/16"), mpp("192.168.1.0/24")); v != "office2" || tbl.Size() != 5 {
+		t.Errorf("overwrite: Get = %q, Size = %d", v, tbl.Size())
+	}
+	if !tbl.Delete(mpp("10.1.0.0/16"), mpp("192.168.1.0/24")) || tbl.Delete(mpp("10.1.0.0/16"), mpp("192.168.1.0/24")) {
+		t.Error("Delete did not report the rule once")
+	}
+	tbl.Delete(mpp("172.16.0.0/12"), mpp("192.168.1.0/24"))
+	if _, ok := tbl.t.Get(mpp("192.168.1.0/24")); ok {
+		t.Error("destination without rules kept")
+	}
+	n := 0
+	for range tbl.All() {
+		n++
+	}
+	if n != 3 || tbl.Size() != 3 {
+		t.Errorf("All yielded %d rules, Size = %d, want 3", n, tbl.Size())
+	}
+}
+
+func TestTable2DMatchesBruteForce(t *testing.T) {
+	prng := rand.New(rand.NewPCG(86, 86))
+	type rule struct{ src, dst netip.Prefix }
+	rules := map[rule]int{}
+	tbl := NewTable2D[int]()
+	defer tbl.Close()
+	randPfx := func() netip.Prefix {
+		a := netip.AddrFrom4([4]byte{10, byte(prng.IntN(4)), byte(prng.IntN(4)), 0})
+		return netip.PrefixFrom(a, 8+prng.IntN(17)).Masked()
+	}
+	for i := range 300 {
+		r := rule{randPfx(), randPfx()}
+		rules[r] = i
+		tbl.Insert(r.src, r.dst, i)
+	}
+
+	for range 1000 {
+		src := netip.AddrFrom4([4]byte{10, byte(prng.IntN(4)), byte(prng.IntN(4)), 1})
+		dst := netip.AddrFrom4([4]byte{10, byte(prng.IntN(4)), byte(prng.IntN(4)), 1})
+		var best rule
+		want, wantOK := 0, false
+		for r, v := range rules {
+			if !r.src.Contains(src) || !r.dst.Contains(dst) {
+				continue
+			}
+			if !wantOK || r.dst.Bits() > best.dst.Bits() || r.dst.Bits() == best.dst.Bits() && r.src.Bits() > best.src.Bits() {
+				best, want, wantOK = r, v, true
+			}
+		}
+		if got, ok := tbl.Lookup(src, dst); got != want || ok != wantOK {
+			t.Fatalf("Lookup(%s, %s) = %d, %v, want %d, %v", src, dst, got, ok, want, wantOK)
+		}
+	}
+}
+
+func TestTable2DLookupAll(t *testing.T) {
+	tbl := NewTable2D[int]()
+	defer tbl.Close()
+	tbl.Insert(mpp("0.0.0.0/0"), mpp("0.0.0.0/0"), 0)
+	tbl.Insert(mpp("10.0.0.0/8"), mpp("0.0.0.0/0"), 1)
+	tbl.Insert(mpp("10.0.0.0/8"), mpp("192.168.0.0/16"), 2)
+	tbl.Insert(mpp("10.1.0.0/16"), mpp("192.168.0.0/16"), 3)
+	tbl.Insert(mpp("11.0.0.0/8"), mpp("192.168.0.0/16"), 4)
+
+	var got []int
+	for _, v := range tbl.LookupAll(mpa("10.1.1.1"), mpa("192.168.1.1")) {
+		got = append(got, v)
+	}
+	if want := []int{3, 2, 1, 0}; !slices.Equal(got, want) {
+		t.Errorf("LookupAll = %v, want %v", got, want)
+	}
+}
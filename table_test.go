@@ -0,0 +1,445 @@
+package zart
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+var mpp = netip.MustParsePrefix
+
+var mpa = netip.MustParseAddr
+
+func TestTableInsertLookup(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+
+	tbl.Insert(mpp("0.0.0.0/0"), "default")
+	tbl.Insert(mpp("10.0.0.0/8"), "ten")
+	tbl.Insert(mpp("10.16.0.0/12"), "ten-sixteen")
+	tbl.Insert(mpp("192.168.1.0/24"), "lan")
+	tbl.Insert(mpp("192.168.1.1/32"), "gw")
+	tbl.Insert(mpp("2001:db8::/32"), "doc")
+	tbl.Insert(mpp("2001:db8::1/128"), "doc-host")
+
+	tests := []struct {
+		addr string
+		want string
+		ok   bool
+	}{
+		{"10.1.2.3", "ten", true},
+		{"10.16.0.1", "ten-sixteen", true},
+		{"10.31.255.255", "ten-sixteen", true},
+		{"10.32.0.0", "ten", true},
+		{"192.168.1.1", "gw", true},
+		{"192.168.1.2", "lan", true},
+		{"8.8.8.8", "default", true},
+		{"2001:db8::1", "doc-host", true},
+		{"2001:db8::2", "doc", true},
+		{"2001:db9::", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tbl.Lookup(mpa(tt.addr))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%s) = %q, %v, want %q, %v", tt.addr, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := tbl.Lookup(netip.Addr{}); ok {
+		t.Error("Lookup(invalid addr) matched")
+	}
+}
+
+func TestTableInsertMasksHostBits(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	tbl.Insert(mpp("192.168.1.55/24"), 1)
+	if got, ok := tbl.Lookup(mpa("192.168.1.200")); !ok || got != 1 {
+		t.Errorf("Lookup = %d, %v, want 1, true", got, ok)
+	}
+}
+
+func TestTableDelete(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	tbl.Insert(mpp("10.0.0.0/8"), 8)
+	tbl.Insert(mpp("10.16.0.0/12"), 12)
+
+	if !tbl.Delete(mpp("10.16.0.0/12")) {
+		t.Fatal("Delete(10.16.0.0/12) = false, want true")
+	}
+	if tbl.Delete(mpp("10.16.0.0/12")) {
+		t.Fatal("second Delete(10.16.0.0/12) = true, want false")
+	}
+	if tbl.Delete(netip.Prefix{}) {
+		t.Fatal("Delete(invalid prefix) = true, want false")
+	}
+	if got, _ := tbl.Lookup(mpa("10.16.0.1")); got != 8 {
+		t.Errorf("Lookup after Delete = %d, want 8", got)
+	}
+}
+
+func TestTableSize(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	tbl.InsertBatch([]RouteEntry[int]{
+		{mpp("10.0.0.0/8"), 1},
+		{mpp("10.1.2.3/8"), 2},
+		{mpp("192.168.0.0/16"), 3},
+		{mpp("2001:db8::/32"), 4},
+	})
+	tbl.Delete(mpp("192.168.0.0/16"))
+	tbl.Delete(mpp("192.168.0.0/16"))
+	if tbl.Size() != 2 || tbl.Size4() != 1 || tbl.Size6() != 1 {
+		t.Errorf("Size, Size4, Size6 = %d, %d, %d, want 2, 1, 1", tbl.Size(), tbl.Size4(), tbl.Size6())
+	}
+
+	p := tbl.InsertPersist(mpp("2001:db8:1::/48"), 5)
+	defer p.Close()
+	o := New[int]()
+	defer o.Close()
+	o.Insert(mpp("10.0.0.0/8"), 6)
+	o.Insert(mpp("172.16.0.0/12"), 7)
+	tbl.Union(o, nil)
+	if tbl.Size() != 3 || p.Size6() != 2 || p.Size4() != 1 {
+		t.Errorf("after Union and InsertPersist: Size %d, persisted Size4 %d Size6 %d, want 3, 1, 2",
+			tbl.Size(), p.Size4(), p.Size6())
+	}
+}
+
+func TestTableRecyclesSlots(t *testing.T) {
+	tbl := New[*int]()
+	defer tbl.Close()
+
+	pfx := mpp("10.0.0.0/8")
+	for i := 0; i < 100; i++ {
+		v := i
+		tbl.Insert(pfx, &v)
+	}
+	if n := len(tbl.vals.vals); n > 2 {
+		t.Errorf("registry grew to %d slots for a single prefix", n)
+	}
+
+	tbl.Delete(pfx)
+	for i, v := range tbl.vals.vals {
+		if v != nil {
+			t.Errorf("released slot %d still references its payload", i)
+		}
+	}
+}
+
+func TestTableLookupPrefix(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	for i, pfx := range []string{"10.0.0.0/8", "10.128.0.0/9", "10.1.2.0/24", "10.1.2.3/32", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), i)
+	}
+
+	tests := []struct {
+		addr string
+		pfx  string
+		val  int
+	}{
+		{"10.2.3.4", "10.0.0.0/8", 0},
+		{"10.200.0.1", "10.128.0.0/9", 1},
+		{"10.1.2.200", "10.1.2.0/24", 2},
+		{"10.1.2.3", "10.1.2.3/32", 3},
+		{"2001:db8:1::1", "2001:db8::/32", 4},
+	}
+	for _, tt := range tests {
+		pfx, val, ok := tbl.LookupPrefix(mpa(tt.addr))
+		if !ok || pfx != mpp(tt.pfx) || val != tt.val {
+			t.Errorf("LookupPrefix(%s) = %s, %d, %v, want %s, %d, true", tt.addr, pfx, val, ok, tt.pfx, tt.val)
+		}
+	}
+
+	if pfx, _, ok := tbl.LookupPrefix(mpa("192.0.2.1")); ok {
+		t.Errorf("LookupPrefix(192.0.2.1) matched %s", pfx)
+	}
+}
+
+func TestTableDeleteSubtree(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	pfxs := []string{"0.0.0.0/0", "10.0.0.0/8", "100.64.0.0/10", "100.64.0.0/16", "100.100.1.0/24", "100.127.255.255/32", "100.128.0.0/9", "2001:db8::/32"}
+	for i, pfx := range pfxs {
+		tbl.Insert(mpp(pfx), i)
+	}
+	for i := range 200 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{100, 65, byte(i), 0}), 24), 100+i)
+	}
+
+	if n := tbl.DeleteSubtree(mpp("100.64.0.0/10")); n != 204 {
+		t.Errorf("DeleteSubtree(100.64.0.0/10) = %d, want 204", n)
+	}
+	if tbl.Size() != 4 || tbl.vals.len() != 4 {
+		t.Errorf("after DeleteSubtree: size %d, %d slots, want 4", tbl.Size(), tbl.vals.len())
+	}
+	for _, pfx := range []string{"0.0.0.0/0", "10.0.0.0/8", "100.128.0.0/9", "2001:db8::/32"} {
+		if _, ok := tbl.Get(mpp(pfx)); !ok {
+			t.Errorf("%s outside the subtree was removed", pfx)
+		}
+	}
+	if pfx, _, _ := tbl.LookupPrefix(mpa("100.65.1.1")); pfx != mpp("0.0.0.0/0") {
+		t.Errorf("LookupPrefix in the removed subtree = %s", pfx)
+	}
+
+	if n := tbl.DeleteSubtree(mpp("192.168.0.0/16")); n != 0 {
+		t.Errorf("DeleteSubtree of an empty range = %d", n)
+	}
+	if n := tbl.DeleteSubtree(mpp("::/0")); n != 1 || tbl.Size6() != 0 {
+		t.Errorf("DeleteSubtree(::/0) = %d, %d IPv6 prefixes left", n, tbl.Size6())
+	}
+}
+
+func TestTableDeleteIf(t *testing.T) {
+	prng := rand.New(rand.NewPCG(113, 113))
+	tbl := New[int]()
+	defer tbl.Close()
+	want := map[netip.Prefix]int{}
+	for i, pfx := range randomPrefixes(prng, 3000) {
+		tbl.Insert(pfx, i)
+		want[pfx.Masked()] = i
+	}
+	var events int
+	tbl.hook(func(ev Event[int]) {
+		if ev.Kind == EventDelete && ev.Old%3 == 0 {
+			events++
+		}
+	})
+
+	n := tbl.DeleteIf(func(pfx netip.Prefix, v int) bool { return v%3 == 0 })
+	removed := 0
+	for pfx, v := range want {
+		if v%3 == 0 {
+			delete(want, pfx)
+			removed++
+		}
+	}
+	if n != removed || events != removed {
+		t.Errorf("DeleteIf = %d with %d events, want %d", n, events, removed)
+	}
+	if tbl.Size() != len(want) || tbl.vals.len() != len(want) {
+		t.Errorf("after DeleteIf: size %d, %d slots, want %d", tbl.Size(), tbl.vals.len(), len(want))
+	}
+	for pfx, v := range want {
+		if got, ok := tbl.Get(pfx); !ok || got != v {
+			t.Fatalf("Get(%s) = %d, %v, want %d", pfx, got, ok, v)
+		}
+	}
+	if n := tbl.DeleteIf(func(netip.Prefix, int) bool { return false }); n != 0 {
+		t.Errorf("DeleteIf matching nothing = %d", n)
+	}
+}
+
+func TestTableInsertCovering(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	tbl.Insert(mpp("10.0.0.0/8"), 1)
+	tbl.Insert(mpp("10.1.0.0/16"), 2)
+	for i := range 300 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 1, byte(i), byte(i >> 8)}), 24), i)
+	}
+	tbl.Insert(mpp("10.2.0.0/24"), 3)
+
+	if n := tbl.InsertCovering(mpp("10.1.7.7/16"), 9); n != 256 {
+		t.Errorf("InsertCovering(10.1.0.0/16) = %d, want 256", n)
+	}
+	if tbl.Size() != 3 || tbl.vals.len() != 3 {
+		t.Errorf("after InsertCovering: size %d, %d slots, want 3", tbl.Size(), tbl.vals.len())
+	}
+	if v, ok := tbl.Get(mpp("10.1.0.0/16")); !ok || v != 9 {
+		t.Errorf("Get(10.1.0.0/16) = %d, %v, want 9", v, ok)
+	}
+	for _, pfx := range []string{"10.0.0.0/8", "10.2.0.0/24"} {
+		if _, ok := tbl.Get(mpp(pfx)); !ok {
+			t.Errorf("%s outside the covered range was removed", pfx)
+		}
+	}
+	if n := tbl.InsertCovering(mpp("192.168.0.0/16"), 4); n != 0 || tbl.Size() != 4 {
+		t.Errorf("InsertCovering of an empty range = %d, size %d", n, tbl.Size())
+	}
+}
+
+func TestTableInsertCoveringMemoryLimit(t *testing.T) {
+	tbl := New[int](WithMaxMemory(1))
+	defer tbl.Close()
+	if n := tbl.InsertCovering(mpp("11.0.0.0/8"), 1); n != 0 || tbl.Size() != 0 {
+		t.Fatalf("InsertCovering over the limit = %d, size %d", n, tbl.Size())
+	}
+
+	// fill a table to its limit, the summary is refused and the routes stay
+	ref := New[int]()
+	defer ref.Close()
+	for i := range 100 {
+		ref.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{11, byte(i), 0, 0}), 24), i)
+	}
+	tbl = New[int](WithMaxMemory(ref.Stats().Bytes()))
+	defer tbl.Close()
+	for i := range 200 {
+		tbl.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{11, byte(i), 0, 0}), 24), i)
+	}
+	size := tbl.Size()
+	if _, ok := tbl.Get(mpp("11.0.0.0/8")); ok || size >= 200 {
+		t.Fatalf("limit not reached, size %d", size)
+	}
+	if n := tbl.InsertCovering(mpp("11.0.0.0/8"), -1); n != 0 || tbl.Size() != size {
+		t.Errorf("refused InsertCovering removed %d, size %d, want %d", n, tbl.Size(), size)
+	}
+	if _, ok := tbl.Get(mpp("11.0.0.0/8")); ok {
+		t.Errorf("summary inserted over the limit")
+	}
+}
+
+func TestTableGet(t *testing.T) {
+	tbl := New[int]()
+	defer tbl.Close()
+
+	for i, pfx := range []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/24", "10.1.2.3/32", "2001:db8::/32"} {
+		tbl.Insert(mpp(pfx), i)
+	}
+
+	tests := []struct {
+		pfx string
+		val int
+		ok  bool
+	}{
+		{"0.0.0.0/0", 0, true},
+		{"10.0.0.0/8", 1, true},
+		{"10.9.9.9/8", 1, true}, // host bits are ignored
+		{"10.0.0.0/9", 0, false},
+		{"10.1.0.0/16", 0, false},
+		{"10.1.2.0/24", 2, true},
+		{"10.1.2.3/32", 3, true},
+		{"10.1.2.4/32", 0, false},
+		{"2001:db8::/32", 4, true},
+		{"2001:db8::/48", 0, false},
+		{"::/0", 0, false},
+	}
+	for _, tt := range tests {
+		if val, ok := tbl.Get(mpp(tt.pfx)); val != tt.val || ok != tt.ok {
+			t.Errorf("Get(%s) = %d, %v, want %d, %v", tt.pfx, val, ok, tt.val, tt.ok)
+		}
+	}
+	if _, ok := tbl.Get(netip.Prefix{}); ok {
+		t.Errorf("Get of the zero Prefix succeeded")
+	}
+}
+
+type clonedPayload struct{ n *int }
+
+func (p clonedPayload) Clone() clonedPayload {
+	n := *p.n
+	return clonedPayload{&n}
+}
+
+func TestTableClone(t *testing.T) {
+	tbl := New[clonedPayload]()
+	defer tbl.Close()
+
+	one := 1
+	tbl.Insert(mpp("10.0.0.0/8"), clonedPayload{&one})
+	tbl.Insert(mpp("2001:db8::/32"), clonedPayload{&one})
+	tbl.Delete(mpp("2001:db8::/32"))
+
+	clone := tbl.Clone()
+	defer clone.Close()
+
+	two := 2
+	clone.Insert(mpp("192.168.0.0/16"), clonedPayload{&two})
+	clone.Delete(mpp("10.0.0.0/8"))
+
+	if v, ok := tbl.Lookup(mpa("10.1.1.1")); !ok || *v.n != 1 {
+		t.Errorf("original lost 10.0.0.0/8 after deleting it from the clone")
+	}
+	if _, ok := tbl.Lookup(mpa("192.168.1.1")); ok {
+		t.Errorf("insert into the clone is visible in the original")
+	}
+
+	c2 := tbl.Clone()
+	defer c2.Close()
+	v, _ := c2.Lookup(mpa("10.1.1.1"))
+	*v.n = 3
+	if one != 1 {
+		t.Errorf("Cloner payload was not deep copied")
+	}
+}
+
+func TestTableContains(t *testing.T) {
+	prng := rand.New(rand.NewPCG(41, 41))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 1000) {
+		tbl.Insert(pfx, i)
+	}
+	tbl.Insert(mpp("192.0.2.1/32"), -1)
+
+	addrs := []netip.Addr{mpa("192.0.2.1"), mpa("192.0.2.2"), {}}
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		addrs = append(addrs, pfx.Addr())
+	}
+	for _, addr := range addrs {
+		_, want := tbl.Lookup(addr)
+		if got := tbl.Contains(addr); got != want {
+			t.Fatalf("Contains(%s) = %v, Lookup found %v", addr, got, want)
+		}
+	}
+}
+
+func TestTableLookupRaw(t *testing.T) {
+	prng := rand.New(rand.NewPCG(78, 78))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 1000) {
+		tbl.Insert(pfx, i)
+	}
+	tbl.Insert(mpp("::ffff:0:0/96"), -1)
+
+	addrs := []netip.Addr{mpa("::ffff:10.0.0.1")}
+	for _, pfx := range randomPrefixes(prng, 2000) {
+		addrs = append(addrs, pfx.Addr())
+	}
+	for _, addr := range addrs {
+		wantVal, wantOK := tbl.Lookup(addr)
+		var val int
+		var ok bool
+		if addr.Is4() {
+			a4 := addr.As4()
+			val, ok = tbl.Lookup4Raw(binary.BigEndian.Uint32(a4[:]))
+		} else {
+			a16 := addr.As16()
+			val, ok = tbl.Lookup6Raw(&a16)
+			hv, hok := tbl.Lookup6HiLo(binary.BigEndian.Uint64(a16[:8]), binary.BigEndian.Uint64(a16[8:]))
+			if hv != val || hok != ok {
+				t.Fatalf("Lookup6HiLo(%s) = %d, %v, Lookup6Raw %d, %v", addr, hv, hok, val, ok)
+			}
+		}
+		if val != wantVal || ok != wantOK {
+			t.Fatalf("raw lookup of %s = %d, %v, want %d, %v", addr, val, ok, wantVal, wantOK)
+		}
+	}
+
+	tbl.Insert6HiLo(0x2001_0db8_0000_0000, 0, 32, -2)
+	if v, _ := tbl.Get(mpp("2001:db8::/32")); v != -2 {
+		t.Errorf("Insert6HiLo stored %d, want -2", v)
+	}
+
+	a16 := addrs[0].As16()
+	allocs := testing.AllocsPerRun(100, func() {
+		tbl.Lookup4Raw(0x0a000001)
+		tbl.Lookup6Raw(&a16)
+		tbl.Lookup6HiLo(0x2001_0db8_0000_0000, 1)
+	})
+	if allocs != 0 {
+		t.Errorf("raw lookups allocate %v times", allocs)
+	}
+}
@@ -0,0 +1,165 @@
+package zart
+
+import (
+	"iter"
+	"maps"
+	"net/netip"
+	"slices"
+)
+
+// Tag labels routes for managing them in bulk, e.g. by their origin:
+//
+//	const (
+//		Static Tag = "static"
+//		Feed   Tag = "feed"
+//	)
+//
+// Numeric labels are formatted into a string, strconv.Itoa will do.
+type Tag string
+
+// tagSet holds the tags of the prefixes in both directions.
+type tagSet struct {
+	byPfx map[netip.Prefix][]Tag
+	byTag map[Tag]map[netip.Prefix]struct{}
+}
+
+func (t *Table[V]) tagSet() *tagSet {
+	if t.tags == nil {
+		t.tags = &tagSet{byPfx: map[netip.Prefix][]Tag{}, byTag: map[Tag]map[netip.Prefix]struct{}{}}
+	}
+	return t.tags
+}
+
+// InsertTagged is like Insert and sets the tags of pfx, replacing those
+// it had. The tags stay with the prefix across inserts without tags until
+// it is deleted. Clone copies the tags, the persistent inserts don't.
+func (t *Table[V]) InsertTagged(pfx netip.Prefix, val V, tags ...Tag) {
+	if !pfx.IsValid() {
+		return
+	}
+	t.Insert(pfx, val)
+	t.tagSet().set(pfx.Masked(), tags)
+}
+
+// SetTags replaces the tags of pfx, no tags remove them. It reports
+// whether pfx is in the table, the tags of missing prefixes are left
+// alone.
+func (t *Table[V]) SetTags(pfx netip.Prefix, tags ...Tag) bool {
+	t.mutable()
+	if _, ok := t.slot(pfx); !ok {
+		return false
+	}
+	t.tagSet().set(pfx.Masked(), tags)
+	return true
+}
+
+// Tags returns the tags of pfx.
+func (t *Table[V]) Tags(pfx netip.Prefix) []Tag {
+	if t.tags == nil || !pfx.IsValid() {
+		return nil
+	}
+	return slices.Clone(t.tags.byPfx[pfx.Masked()])
+}
+
+// DeleteByTag deletes all prefixes tagged with tag and returns their
+// number.
+func (t *Table[V]) DeleteByTag(tag Tag) int {
+	if t.tags == nil {
+		return 0
+	}
+	n := 0
+	for _, pfx := range slices.Collect(maps.Keys(t.tags.byTag[tag])) {
+		if t.Delete(pfx) {
+			n++
+		}
+	}
+	return n
+}
+
+// AllWithTag returns an iterator over the prefixes tagged with tag and
+// their values, in CIDR order. It takes time proportional to the number
+// of tagged prefixes, not to the size of the table. The table must not be
+// modified during the iteration.
+func (t *Table[V]) AllWithTag(tag Tag) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		if t.tags == nil {
+			return
+		}
+		pfxs := slices.SortedFunc(maps.Keys(t.tags.byTag[tag]), comparePrefix)
+		for _, pfx := range pfxs {
+			if val, ok := t.Get(pfx); ok && !yield(pfx, val) {
+				return
+			}
+		}
+	}
+}
+
+// AllTagged is All restricted to the prefixes whose tags pass filter,
+// filter gets nil for untagged prefixes.
+func (t *Table[V]) AllTagged(filter func(tags []Tag) bool) iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		for pfx, val := range t.All() {
+			var tags []Tag
+			if t.tags != nil {
+				tags = t.tags.byPfx[pfx]
+			}
+			if filter(tags) && !yield(pfx, val) {
+				return
+			}
+		}
+	}
+}
+
+func (s *tagSet) set(pfx netip.Prefix, tags []Tag) {
+	s.forget(pfx)
+	if len(tags) == 0 {
+		return
+	}
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	s.byPfx[pfx] = tags
+	for _, tag := range tags {
+		set := s.byTag[tag]
+		if set == nil {
+			set = map[netip.Prefix]struct{}{}
+			s.byTag[tag] = set
+		}
+		set[pfx] = struct{}{}
+	}
+}
+
+// forget drops the tags of pfx, called by the modifications removing it.
+func (s *tagSet) forget(pfx netip.Prefix) {
+	for _, tag := range s.byPfx[pfx] {
+		set := s.byTag[tag]
+		delete(set, pfx)
+		if len(set) == 0 {
+			delete(s.byTag, tag)
+		}
+	}
+	delete(s.byPfx, pfx)
+}
+
+func (s *tagSet) clone() *tagSet {
+	if s == nil {
+		return nil
+	}
+	c := &tagSet{byPfx: maps.Clone(s.byPfx), byTag: make(map[Tag]map[netip.Prefix]struct{}, len(s.byTag))}
+	for tag, set := range s.byTag {
+		c.byTag[tag] = maps.Clone(set)
+	}
+	return c
+}
+
+// InsertTagged is like Table.InsertTagged.
+func (c *ConcurrentTable[V]) InsertTagged(pfx netip.Prefix, val V, tags ...Tag) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.InsertTagged(pfx, val, tags...)
+}
+
+// DeleteByTag is like Table.DeleteByTag.
+func (c *ConcurrentTable[V]) DeleteByTag(tag Tag) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.DeleteByTag(tag)
+}
@@ -0,0 +1,62 @@
+package zart
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	const static, feed, pinned Tag = "static", "feed", "pinned"
+	tbl := New[int]()
+	defer tbl.Close()
+	tbl.InsertTagged(mpp("10.0.0.0/8"), 1, static)
+	tbl.InsertTagged(mpp("10.1.0.0/16"), 2, feed)
+	tbl.InsertTagged(mpp("192.168.0.0/24"), 3, feed, pinned, feed)
+	tbl.InsertTagged(mpp("2001:db8::/32"), 4, feed)
+	tbl.Insert(mpp("172.16.0.0/12"), 5)
+
+	if got := tbl.Tags(mpp("192.168.0.0/24")); !slices.Equal(got, []Tag{feed, pinned}) {
+		t.Errorf("Tags = %v, want the sorted tags without duplicates", got)
+	}
+
+	tbl.Insert(mpp("10.1.0.0/16"), 6) // keeps the tags
+	var got []string
+	for pfx, val := range tbl.AllWithTag(feed) {
+		got = append(got, pfx.String())
+		if v, _ := tbl.Get(pfx); v != val {
+			t.Errorf("AllWithTag yields %s = %d, Get %d", pfx, val, v)
+		}
+	}
+	if want := []string{"10.1.0.0/16", "192.168.0.0/24", "2001:db8::/32"}; !slices.Equal(got, want) {
+		t.Errorf("AllWithTag(feed) = %v, want %v", got, want)
+	}
+
+	untagged := 0
+	for range tbl.AllTagged(func(tags []Tag) bool { return len(tags) == 0 }) {
+		untagged++
+	}
+	if untagged != 1 {
+		t.Errorf("AllTagged found %d untagged prefixes, want 1", untagged)
+	}
+
+	tbl.SetTags(mpp("192.168.0.0/24"), pinned)
+	if n := tbl.DeleteByTag(feed); n != 2 || tbl.Size() != 3 {
+		t.Errorf("DeleteByTag(feed) = %d, size %d", n, tbl.Size())
+	}
+	if tbl.SetTags(mpp("10.1.0.0/16"), feed) {
+		t.Error("SetTags on a deleted prefix")
+	}
+
+	c := tbl.Clone()
+	defer c.Close()
+	tbl.DeleteSubtree(mpp("10.0.0.0/8"))
+	if tags := tbl.Tags(mpp("10.0.0.0/8")); tags != nil {
+		t.Errorf("DeleteSubtree kept the tags %v", tags)
+	}
+	if n := c.DeleteByTag(static); n != 1 {
+		t.Errorf("DeleteByTag on a clone = %d, want 1", n)
+	}
+	if _, ok := c.Get(mpp("192.168.0.0/24")); !ok || len(c.Tags(mpp("192.168.0.0/24"))) != 1 {
+		t.Error("clone lost a tagged prefix")
+	}
+}
@@ -0,0 +1,61 @@
+package zart
+
+import "time"
+
+// Telemetry receives the measurements of a table created with
+// WithTelemetry; package otel reports them to OpenTelemetry. The methods
+// are called synchronously by the goroutines using the table, lookups
+// concurrently under the read lock of a ConcurrentTable, and must be
+// safe for concurrent use and cheap.
+type Telemetry interface {
+	// Lookup records a Lookup or LookupPrefix that took d.
+	Lookup(d time.Duration, hit bool)
+
+	// Change records n prefixes inserted, updated or deleted by one
+	// call.
+	Change(kind EventKind, n int)
+
+	// Start is called at the start of a bulk operation op: "load",
+	// "load_parallel", "insert_batch", "unmarshal" or "commit" of a
+	// transaction. It returns the function called at its end with the
+	// number of routes the operation handled and its error.
+	Start(op string) (end func(n int, err error))
+}
+
+// WithTelemetry makes the table report lookups, changes and bulk
+// operations to tel. Without it the table measures nothing, the lookups
+// don't even read the clock. Tables made from the table, like clones and
+// persistent versions, report to tel as well.
+func WithTelemetry(tel Telemetry) Option {
+	return func(o *options) { o.tel = tel }
+}
+
+// count reports n changes of kind to the telemetry of the table.
+func (t *Table[V]) count(kind EventKind, n int) {
+	if t.opts.tel != nil && n > 0 {
+		t.opts.tel.Change(kind, n)
+	}
+}
+
+// start reports the start of bulk operation op to the telemetry and the
+// audit logger of the table, the returned function reports its end; both
+// do nothing without either.
+func (t *Table[V]) start(op string) func(n int, err error) {
+	tel, audit := t.opts.tel, t.opts.audit
+	if tel == nil && audit == nil {
+		return func(int, error) {}
+	}
+	var end func(int, error)
+	if tel != nil {
+		end = tel.Start(op)
+	}
+	begin := time.Now()
+	return func(n int, err error) {
+		if end != nil {
+			end(n, err)
+		}
+		if audit != nil {
+			t.auditOp(op, begin, n, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package zart
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTelemetry counts what a table reports.
+type fakeTelemetry struct {
+	mu      sync.Mutex
+	lookups map[bool]int
+	changes map[EventKind]int
+	ops     []string
+	errs    []error
+}
+
+func newFakeTelemetry() *fakeTelemetry {
+	return &fakeTelemetry{lookups: map[bool]int{}, changes: map[EventKind]int{}}
+}
+
+func (f *fakeTelemetry) Lookup(_ time.Duration, hit bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups[hit]++
+}
+
+func (f *fakeTelemetry) Change(kind EventKind, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changes[kind] += n
+}
+
+func (f *fakeTelemetry) Start(op string) func(int, error) {
+	return func(n int, err error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.ops = append(f.ops, op)
+		f.errs = append(f.errs, err)
+	}
+}
+
+func TestTelemetry(t *testing.T) {
+	tel := newFakeTelemetry()
+	tbl := New[int](WithTelemetry(tel))
+	defer tbl.Close()
+
+	tbl.InsertBatch([]RouteEntry[int]{{mpp("10.0.0.0/8"), 1}, {mpp("10.1.0.0/16"), 2}, {mpp("10.0.0.0/8"), 3}})
+	tbl.Insert(mpp("10.1.0.0/16"), 4)
+	tbl.Modify(mpp("192.168.0.0/16"), func(int, bool) (int, bool) { return 5, false })
+	tbl.Delete(mpp("10.1.0.0/16"))
+	tbl.Delete(mpp("172.16.0.0/12"))
+	tbl.DeleteSubtree(mpp("0.0.0.0/0"))
+	tbl.Lookup(mpa("10.1.1.1"))
+	tbl.LookupPrefix(mpa("10.1.1.1"))
+
+	want := map[EventKind]int{EventInsert: 3, EventUpdate: 2, EventDelete: 3}
+	for k, n := range want {
+		if tel.changes[k] != n {
+			t.Errorf("%v changes = %d, want %d", k, tel.changes[k], n)
+		}
+	}
+	if tel.lookups[false] != 2 {
+		t.Errorf("lookups = %v, want 2 misses", tel.lookups)
+	}
+
+	_, err := tbl.Load(strings.NewReader("10.0.0.0/8 1\nbogus\n"), Lines[int](), nil)
+	if got := strings.Join(tel.ops, " "); got != "insert_batch insert_batch load" {
+		t.Errorf("operations = %s", got)
+	}
+	if last := tel.errs[len(tel.errs)-1]; err == nil || !errors.Is(last, err) {
+		t.Errorf("load ended with %v, want %v", last, err)
+	}
+}
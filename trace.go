@@ -0,0 +1,113 @@
+package zart
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Trace explains a longest-prefix match, see LookupTrace.
+type Trace[V any] struct {
+	Addr  netip.Addr
+	Steps []TraceStep[V]
+
+	// Prefix and Value are the result of the lookup, as returned by
+	// LookupPrefix, OK is false if no prefix matched.
+	Prefix netip.Prefix
+	Value  V
+	OK     bool
+}
+
+// TraceStep is a trie node visited by a lookup.
+type TraceStep[V any] struct {
+	// Depth is the trie level of the node, Path the address range it
+	// covers, Depth*8 bits of the address.
+	Depth int
+	Path  netip.Prefix
+
+	// Candidates are the prefixes stored in the node that cover the
+	// address, most specific first.
+	Candidates []RouteEntry[V]
+
+	// Leaf is the path compressed prefix in the child slot of the
+	// address, the lookup ends with it. It only matches if it covers the
+	// address, otherwise the lookup falls back to the candidates.
+	Leaf *RouteEntry[V]
+}
+
+// LookupTrace performs a longest-prefix match for addr like LookupPrefix
+// and records how the result was found: every trie node on the path of
+// addr with the prefixes it holds that cover addr. The match is the most
+// specific of them, a covering leaf at the end of the path or else the
+// first candidate of the deepest node that has one.
+//
+// Tracing allocates and is meant for debugging, not for the data path.
+func (t *Table[V]) LookupTrace(addr netip.Addr) Trace[V] {
+	tr := Trace[V]{Addr: addr}
+	addr = t.key(addr)
+	if !addr.IsValid() {
+		return tr
+	}
+	var items []trieItem
+	if addr.Is4() {
+		a4 := addr.As4()
+		items = fill(8, func(out []trieItem) int { return t.trie.trace4(binary.BigEndian.Uint32(a4[:]), out) })
+	} else {
+		a16 := addr.As16()
+		items = fill(32, func(out []trieItem) int { return t.trie.trace6(&a16, out) })
+	}
+
+	for _, it := range items {
+		e := RouteEntry[V]{Prefix: it.route.prefix()}
+		if it.kind != itemNode {
+			e.Value = t.vals.get(it.route.val)
+		}
+		switch it.kind {
+		case itemNode:
+			tr.Steps = append(tr.Steps, TraceStep[V]{Depth: int(it.depth), Path: e.Prefix})
+		case itemPrefix:
+			s := &tr.Steps[len(tr.Steps)-1]
+			s.Candidates = append(s.Candidates, e)
+		case itemLeaf, itemFringe:
+			tr.Steps[len(tr.Steps)-1].Leaf = &e
+		}
+	}
+
+	for i := len(tr.Steps) - 1; i >= 0 && !tr.OK; i-- {
+		s := tr.Steps[i]
+		switch {
+		case s.Leaf != nil && s.Leaf.Prefix.Contains(addr):
+			tr.Prefix, tr.Value, tr.OK = s.Leaf.Prefix, s.Leaf.Value, true
+		case len(s.Candidates) > 0:
+			tr.Prefix, tr.Value, tr.OK = s.Candidates[0].Prefix, s.Candidates[0].Value, true
+		}
+	}
+	return tr
+}
+
+// String renders the trace one node per line, each followed by its
+// candidates, and the match at the end. Values are formatted with %v.
+func (tr Trace[V]) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "lookup %s\n", tr.Addr)
+	for _, s := range tr.Steps {
+		fmt.Fprintf(&b, "  depth %d node %s\n", s.Depth, s.Path)
+		for _, c := range s.Candidates {
+			fmt.Fprintf(&b, "    candidate %s = %v\n", c.Prefix, c.Value)
+		}
+		if s.Leaf != nil {
+			covers := "covers"
+			if !s.Leaf.Prefix.Contains(tr.Addr) {
+				covers = "does not cover"
+			}
+			fmt.Fprintf(&b, "    leaf %s = %v, %s %s\n", s.Leaf.Prefix, s.Leaf.Value, covers, tr.Addr)
+		}
+	}
+	if tr.OK {
+		fmt.Fprintf(&b, "match %s = %v\n", tr.Prefix, tr.Value)
+	} else {
+		b.WriteString("no match\n")
+	}
+	return b.String()
+}
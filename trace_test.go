@@ -0,0 +1,78 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestLookupTrace(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	tbl.Insert(mpp("10.0.0.0/8"), "eight")
+	tbl.Insert(mpp("10.0.0.0/12"), "twelve")
+	tbl.Insert(mpp("10.1.0.0/16"), "sixteen")
+
+	addr := netip.MustParseAddr("10.1.2.3")
+	tr := tbl.LookupTrace(addr)
+	if !tr.OK || tr.Prefix != mpp("10.1.0.0/16") || tr.Value != "sixteen" {
+		t.Fatalf("LookupTrace(%s) = %s %q %v", addr, tr.Prefix, tr.Value, tr.OK)
+	}
+	if len(tr.Steps) == 0 || tr.Steps[0].Depth != 0 || tr.Steps[0].Path != mpp("0.0.0.0/0") {
+		t.Fatalf("trace does not start at the IPv4 root: %+v", tr.Steps)
+	}
+
+	addr = netip.MustParseAddr("10.8.0.1")
+	tr = tbl.LookupTrace(addr)
+	var considered []netip.Prefix
+	for _, s := range tr.Steps {
+		for _, c := range s.Candidates {
+			considered = append(considered, c.Prefix)
+		}
+	}
+	if !tr.OK || tr.Prefix != mpp("10.0.0.0/12") {
+		t.Errorf("LookupTrace(%s) matched %s, want 10.0.0.0/12", addr, tr.Prefix)
+	}
+	for _, pfx := range considered {
+		if pfx == mpp("10.1.0.0/16") {
+			t.Errorf("10.1.0.0/16 does not cover %s but was a candidate", addr)
+		}
+	}
+	if s := tr.String(); !strings.HasPrefix(s, "lookup 10.8.0.1\n") || !strings.HasSuffix(s, "match 10.0.0.0/12 = twelve\n") {
+		t.Errorf("String() =\n%s", s)
+	}
+
+	if tr := tbl.LookupTrace(netip.MustParseAddr("192.0.2.1")); tr.OK || len(tr.Steps) == 0 {
+		t.Errorf("LookupTrace of an unrouted address = %+v", tr)
+	}
+	if tr := tbl.LookupTrace(netip.Addr{}); tr.OK || len(tr.Steps) != 0 {
+		t.Errorf("LookupTrace of the zero Addr = %+v", tr)
+	}
+}
+
+func TestLookupTraceMatchesLookupPrefix(t *testing.T) {
+	prng := rand.New(rand.NewPCG(36, 36))
+
+	tbl := New[int]()
+	defer tbl.Close()
+	for i, pfx := range randomPrefixes(prng, 2000) {
+		tbl.Insert(pfx, i)
+	}
+
+	for _, pfx := range randomPrefixes(prng, 1000) {
+		addr := pfx.Addr()
+		tr := tbl.LookupTrace(addr)
+		pfx, val, ok := tbl.LookupPrefix(addr)
+		if tr.OK != ok || tr.Prefix != pfx || tr.Value != val {
+			t.Fatalf("LookupTrace(%s) = %s %d %v, LookupPrefix = %s %d %v\n%s", addr, tr.Prefix, tr.Value, tr.OK, pfx, val, ok, tr)
+		}
+		for _, s := range tr.Steps {
+			for _, c := range s.Candidates {
+				if !c.Prefix.Contains(addr) {
+					t.Fatalf("candidate %s does not cover %s", c.Prefix, addr)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,727 @@
+//go:build cgo && !purego
+
+package zart
+
+// The trie is Zig, not C, and can't be compiled by cgo itself.
+//go:generate zig build -Doptimize=ReleaseFast
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include
+#cgo LDFLAGS: -L${SRCDIR}/zig-out/lib -lbart
+#cgo noescape bart_size4
+#cgo nocallback bart_size4
+#cgo noescape bart_size6
+#cgo nocallback bart_size6
+#cgo noescape bart_stats
+#cgo nocallback bart_stats
+#cgo noescape bart_shape
+#cgo nocallback bart_shape
+#cgo noescape bart_fsck
+#cgo nocallback bart_fsck
+#cgo noescape bart_graft
+#cgo nocallback bart_graft
+#cgo noescape bart_trie_items
+#cgo nocallback bart_trie_items
+#cgo noescape bart_lookup_trace4
+#cgo nocallback bart_lookup_trace4
+#cgo noescape bart_lookup_trace6
+#cgo nocallback bart_lookup_trace6
+#cgo noescape bart_insert4
+#cgo nocallback bart_insert4
+#cgo noescape bart_insert4_64
+#cgo nocallback bart_insert4_64
+#cgo noescape bart_insert6
+#cgo nocallback bart_insert6
+#cgo noescape bart_insert6_64
+#cgo nocallback bart_insert6_64
+#cgo noescape bart_insert6_hl_64
+#cgo nocallback bart_insert6_hl_64
+#cgo noescape bart_get_or_insert4
+#cgo nocallback bart_get_or_insert4
+#cgo noescape bart_get_or_insert6
+#cgo nocallback bart_get_or_insert6
+#cgo noescape bart_insert_bulk
+#cgo nocallback bart_insert_bulk
+#cgo noescape bart_delete_bulk
+#cgo nocallback bart_delete_bulk
+#cgo noescape bart_delete4
+#cgo nocallback bart_delete4
+#cgo noescape bart_delete4_64
+#cgo nocallback bart_delete4_64
+#cgo noescape bart_delete6
+#cgo nocallback bart_delete6
+#cgo noescape bart_delete6_64
+#cgo nocallback bart_delete6_64
+#cgo noescape bart_get4
+#cgo nocallback bart_get4
+#cgo noescape bart_get4_64
+#cgo nocallback bart_get4_64
+#cgo noescape bart_get6
+#cgo nocallback bart_get6
+#cgo noescape bart_get6_64
+#cgo nocallback bart_get6_64
+#cgo noescape bart_contains4
+#cgo nocallback bart_contains4
+#cgo noescape bart_contains6
+#cgo nocallback bart_contains6
+#cgo noescape bart_lookup4
+#cgo nocallback bart_lookup4
+#cgo noescape bart_lookup4_64
+#cgo nocallback bart_lookup4_64
+#cgo noescape bart_lookup6
+#cgo nocallback bart_lookup6
+#cgo noescape bart_lookup6_64
+#cgo nocallback bart_lookup6_64
+#cgo noescape bart_lookup6_hl
+#cgo nocallback bart_lookup6_hl
+#cgo noescape bart_lookup6_hl_64
+#cgo nocallback bart_lookup6_hl_64
+#cgo noescape bart_lookup_prefix4
+#cgo nocallback bart_lookup_prefix4
+#cgo noescape bart_lookup_prefix6
+#cgo nocallback bart_lookup_prefix6
+#cgo noescape bart_lookup_shortest4
+#cgo nocallback bart_lookup_shortest4
+#cgo noescape bart_lookup_shortest6
+#cgo nocallback bart_lookup_shortest6
+#cgo noescape bart_lookup_batch4
+#cgo nocallback bart_lookup_batch4
+#cgo noescape bart_lookup_batch6
+#cgo nocallback bart_lookup_batch6
+#cgo noescape bart_dump
+#cgo nocallback bart_dump
+#cgo noescape bart_dump64
+#cgo nocallback bart_dump64
+#cgo noescape bart_supernets
+#cgo nocallback bart_supernets
+#cgo noescape bart_page
+#cgo nocallback bart_page
+#cgo noescape bart_subnets
+#cgo nocallback bart_subnets
+#cgo noescape bart_delete_subtree
+#cgo nocallback bart_delete_subtree
+#cgo noescape bart_overlaps_prefix
+#cgo nocallback bart_overlaps_prefix
+#cgo nocallback bart_overlaps
+#cgo noescape bart_union
+#cgo nocallback bart_union
+#cgo noescape bart_diff
+#cgo nocallback bart_diff
+#cgo noescape bart_common
+#cgo nocallback bart_set_cpu_features
+#cgo nocallback bart_common
+#cgo nocallback bart_same_prefixes
+#include "bart.h"
+
+extern int zartWalk(void *ctx, bart_route_t *routes, size_t n);
+*/
+import "C"
+
+import (
+	"runtime"
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+// The noescape/nocallback directives above let the compiler keep the
+// key arrays and result flags passed to C on the Go stack.
+
+// route must have exactly the layout of bart_route_t, route64 that of
+// bart_route64_t, trieItem that of bart_trie_item_t and diffItem that of
+// bart_diff_t.
+var (
+	_ [unsafe.Sizeof(route{}) - C.sizeof_bart_route_t]byte
+	_ [C.sizeof_bart_route_t - unsafe.Sizeof(route{})]byte
+	_ [unsafe.Sizeof(route64{}) - C.sizeof_bart_route64_t]byte
+	_ [C.sizeof_bart_route64_t - unsafe.Sizeof(route64{})]byte
+	_ [unsafe.Sizeof(trieItem{}) - C.sizeof_bart_trie_item_t]byte
+	_ [C.sizeof_bart_trie_item_t - unsafe.Sizeof(trieItem{})]byte
+	_ [unsafe.Sizeof(diffItem{}) - C.sizeof_bart_diff_t]byte
+	_ [C.sizeof_bart_diff_t - unsafe.Sizeof(diffItem{})]byte
+)
+
+// trie is the handle to a C routing table from libbart.a.
+//
+// IPv4 keys are host-order integers, IPv6 keys 16 bytes in network
+// order, exactly as expected by include/bart.h.
+type trie struct {
+	ptr     *C.bart_table_t
+	grp     *trieGroup
+	frozen  bool
+	cleanup runtime.Cleanup
+}
+
+// trieGroup is a C table and its persistent versions, which share nodes
+// with non-atomic reference counts. The garbage collector runs the cleanup
+// of a leaked version concurrently with the users of the others, so it
+// must not destroy the leaked table while they are alive: it queues it in
+// dead instead, for the next version closed to destroy. The last version
+// of the group destroys the table itself.
+type trieGroup struct {
+	mu   sync.Mutex
+	live int
+	dead []*C.bart_table_t
+}
+
+// track wraps ptr of grp, destroying it eventually if it is never closed.
+func track(ptr *C.bart_table_t, grp *trieGroup) *trie {
+	t := &trie{ptr: ptr, grp: grp}
+	stack := allocStack()
+	t.cleanup = runtime.AddCleanup(t, func(ptr *C.bart_table_t) {
+		reportLeak(stack)
+		grp.mu.Lock()
+		defer grp.mu.Unlock()
+		grp.live--
+		grp.dead = append(grp.dead, ptr)
+		if grp.live == 0 {
+			grp.drain()
+		}
+	}, ptr)
+	return t
+}
+
+// drain destroys the leaked versions, with grp.mu held.
+func (g *trieGroup) drain() {
+	for _, ptr := range g.dead {
+		C.bart_destroy(ptr)
+	}
+	g.dead = nil
+}
+
+// newTrie returns an empty C table, allocating from an arena of
+// o.arena-1 byte blocks if it is set, see WithArena. The C trie always
+// compresses paths.
+func newTrie(o options) *trie {
+	var ptr *C.bart_table_t
+	if o.arena > 0 {
+		ptr = C.bart_create_arena(C.size_t(o.arena - 1))
+	} else {
+		ptr = C.bart_create()
+	}
+	if ptr == nil {
+		panic("zart: bart_create: out of memory")
+	}
+	return track(ptr, &trieGroup{live: 1})
+}
+
+// clone returns a deep copy of the C table.
+func (t *trie) clone() *trie {
+	ptr := C.bart_clone(t.handle())
+	if ptr == nil {
+		panic("zart: bart_clone: out of memory")
+	}
+	return track(ptr, &trieGroup{live: 1})
+}
+
+// persist returns a copy-on-write version of the C table.
+func (t *trie) persist() *trie {
+	t.grp.mu.Lock()
+	t.grp.live++
+	t.grp.mu.Unlock()
+	return track(C.bart_persist(t.handle()), t.grp)
+}
+
+func (t *trie) close() {
+	if t.ptr == nil {
+		return
+	}
+	t.cleanup.Stop()
+	C.bart_destroy(t.ptr)
+	t.ptr = nil
+
+	t.grp.mu.Lock()
+	defer t.grp.mu.Unlock()
+	t.grp.live--
+	t.grp.drain()
+}
+
+// handle returns the C table, it panics with ErrClosed once the table is
+// closed instead of handing a dangling pointer to C.
+func (t *trie) handle() *C.bart_table_t {
+	if t.ptr == nil {
+		panic(ErrClosed)
+	}
+	return t.ptr
+}
+
+// writable is handle for the calls modifying the trie, it panics with
+// ErrFrozen once the trie is frozen.
+func (t *trie) writable() *C.bart_table_t {
+	if t.frozen {
+		panic(ErrFrozen)
+	}
+	return t.handle()
+}
+
+// insert4 inserts or overwrites a prefix, it returns the overwritten value.
+func (t *trie) insert4(addr uint32, bits uint8, val uint32) (old uint32, existed bool) {
+	var prev C.uint32_t
+	rc := C.bart_insert4(t.writable(), C.uint32_t(addr), C.uint8_t(bits), C.uint32_t(val), &prev)
+	return uint32(prev), rc != 0
+}
+
+// insert6 inserts or overwrites a prefix, it returns the overwritten value.
+func (t *trie) insert6(addr *[16]byte, bits uint8, val uint32) (old uint32, existed bool) {
+	var prev C.uint32_t
+	rc := C.bart_insert6(t.writable(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), C.uint32_t(val), &prev)
+	return uint32(prev), rc != 0
+}
+
+// insertWide4 is insert4 with a whole 64-bit value.
+func (t *trie) insertWide4(addr uint32, bits uint8, val uint64) (old uint64, existed bool) {
+	var prev C.uint64_t
+	rc := C.bart_insert4_64(t.writable(), C.uint32_t(addr), C.uint8_t(bits), C.uint64_t(val), &prev)
+	return uint64(prev), rc != 0
+}
+
+// insertWide6 is insert6 with a whole 64-bit value.
+func (t *trie) insertWide6(addr *[16]byte, bits uint8, val uint64) (old uint64, existed bool) {
+	var prev C.uint64_t
+	rc := C.bart_insert6_64(t.writable(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), C.uint64_t(val), &prev)
+	return uint64(prev), rc != 0
+}
+
+// insertWide6HiLo is insertWide6 for an address passed as its two halves.
+func (t *trie) insertWide6HiLo(hi, lo uint64, bits uint8, val uint64) (old uint64, existed bool) {
+	var prev C.uint64_t
+	rc := C.bart_insert6_hl_64(t.writable(), C.uint64_t(hi), C.uint64_t(lo), C.uint8_t(bits), C.uint64_t(val), &prev)
+	return uint64(prev), rc != 0
+}
+
+// getOrInsert4 inserts a prefix unless it is present, it returns the
+// value of a present prefix.
+func (t *trie) getOrInsert4(addr uint32, bits uint8, val uint32) (old uint32, existed bool) {
+	var prev C.uint32_t
+	rc := C.bart_get_or_insert4(t.writable(), C.uint32_t(addr), C.uint8_t(bits), C.uint32_t(val), &prev)
+	return uint32(prev), rc != 0
+}
+
+// getOrInsert6 inserts a prefix unless it is present, it returns the
+// value of a present prefix.
+func (t *trie) getOrInsert6(addr *[16]byte, bits uint8, val uint32) (old uint32, existed bool) {
+	var prev C.uint32_t
+	rc := C.bart_get_or_insert6(t.writable(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), C.uint32_t(val), &prev)
+	return uint32(prev), rc != 0
+}
+
+// insertBulk inserts routes with a single cgo call, the values of
+// overwritten prefixes are stored in replaced, which must have room for
+// len(routes) values.
+func (t *trie) insertBulk(routes []route, replaced []uint32) int {
+	if len(routes) == 0 {
+		return 0
+	}
+	n := C.bart_insert_bulk(t.writable(),
+		(*C.bart_route_t)(unsafe.Pointer(&routes[0])), C.size_t(len(routes)),
+		(*C.uint32_t)(unsafe.Pointer(&replaced[0])))
+	return int(n)
+}
+
+// delete4 removes a prefix, it returns the value of the removed prefix.
+func (t *trie) delete4(addr uint32, bits uint8) (old uint32, ok bool) {
+	var prev C.uint32_t
+	rc := C.bart_delete4(t.writable(), C.uint32_t(addr), C.uint8_t(bits), &prev)
+	return uint32(prev), rc != 0
+}
+
+// delete6 removes a prefix, it returns the value of the removed prefix.
+func (t *trie) delete6(addr *[16]byte, bits uint8) (old uint32, ok bool) {
+	var prev C.uint32_t
+	rc := C.bart_delete6(t.writable(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), &prev)
+	return uint32(prev), rc != 0
+}
+
+func (t *trie) deleteWide4(addr uint32, bits uint8) (old uint64, ok bool) {
+	var prev C.uint64_t
+	rc := C.bart_delete4_64(t.writable(), C.uint32_t(addr), C.uint8_t(bits), &prev)
+	return uint64(prev), rc != 0
+}
+
+func (t *trie) deleteWide6(addr *[16]byte, bits uint8) (old uint64, ok bool) {
+	var prev C.uint64_t
+	rc := C.bart_delete6_64(t.writable(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), &prev)
+	return uint64(prev), rc != 0
+}
+
+// get4 returns the value stored for exactly addr/bits.
+func (t *trie) get4(addr uint32, bits uint8) (uint32, bool) {
+	var found C.int
+	val := C.bart_get4(t.handle(), C.uint32_t(addr), C.uint8_t(bits), &found)
+	return uint32(val), found != 0
+}
+
+// get6 returns the value stored for exactly addr/bits.
+func (t *trie) get6(addr *[16]byte, bits uint8) (uint32, bool) {
+	var found C.int
+	val := C.bart_get6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), &found)
+	return uint32(val), found != 0
+}
+
+func (t *trie) getWide4(addr uint32, bits uint8) (uint64, bool) {
+	var found C.int
+	val := C.bart_get4_64(t.handle(), C.uint32_t(addr), C.uint8_t(bits), &found)
+	return uint64(val), found != 0
+}
+
+func (t *trie) getWide6(addr *[16]byte, bits uint8) (uint64, bool) {
+	var found C.int
+	val := C.bart_get6_64(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), C.uint8_t(bits), &found)
+	return uint64(val), found != 0
+}
+
+func (t *trie) contains4(addr uint32) bool {
+	return C.bart_contains4(t.handle(), C.uint32_t(addr)) != 0
+}
+
+func (t *trie) contains6(addr *[16]byte) bool {
+	return C.bart_contains6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0]))) != 0
+}
+
+func (t *trie) lookup4(addr uint32) (uint32, bool) {
+	var found C.int
+	val := C.bart_lookup4(t.handle(), C.uint32_t(addr), &found)
+	return uint32(val), found != 0
+}
+
+func (t *trie) lookup6(addr *[16]byte) (uint32, bool) {
+	var found C.int
+	val := C.bart_lookup6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), &found)
+	return uint32(val), found != 0
+}
+
+func (t *trie) lookupWide4(addr uint32) (uint64, bool) {
+	var found C.int
+	val := C.bart_lookup4_64(t.handle(), C.uint32_t(addr), &found)
+	return uint64(val), found != 0
+}
+
+func (t *trie) lookupWide6(addr *[16]byte) (uint64, bool) {
+	var found C.int
+	val := C.bart_lookup6_64(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), &found)
+	return uint64(val), found != 0
+}
+
+// lookup6HiLo is lookup6 for an address passed as its two halves.
+func (t *trie) lookup6HiLo(hi, lo uint64) (uint32, bool) {
+	var found C.int
+	val := C.bart_lookup6_hl(t.handle(), C.uint64_t(hi), C.uint64_t(lo), &found)
+	return uint32(val), found != 0
+}
+
+func (t *trie) lookupWide6HiLo(hi, lo uint64) (uint64, bool) {
+	var found C.int
+	val := C.bart_lookup6_hl_64(t.handle(), C.uint64_t(hi), C.uint64_t(lo), &found)
+	return uint64(val), found != 0
+}
+
+// lookupPrefix4 is lookup4 that also returns the length of the match.
+func (t *trie) lookupPrefix4(addr uint32) (val uint32, bits uint8, ok bool) {
+	var found C.int
+	var b C.uint8_t
+	v := C.bart_lookup_prefix4(t.handle(), C.uint32_t(addr), &b, &found)
+	return uint32(v), uint8(b), found != 0
+}
+
+// lookupPrefix6 is lookup6 that also returns the length of the match.
+func (t *trie) lookupPrefix6(addr *[16]byte) (val uint32, bits uint8, ok bool) {
+	var found C.int
+	var b C.uint8_t
+	v := C.bart_lookup_prefix6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), &b, &found)
+	return uint32(v), uint8(b), found != 0
+}
+
+// lookupShortest4 is lookupPrefix4 for the shortest matching prefix.
+func (t *trie) lookupShortest4(addr uint32) (val uint32, bits uint8, ok bool) {
+	var found C.int
+	var b C.uint8_t
+	v := C.bart_lookup_shortest4(t.handle(), C.uint32_t(addr), &b, &found)
+	return uint32(v), uint8(b), found != 0
+}
+
+// lookupShortest6 is lookupPrefix6 for the shortest matching prefix.
+func (t *trie) lookupShortest6(addr *[16]byte) (val uint32, bits uint8, ok bool) {
+	var found C.int
+	var b C.uint8_t
+	v := C.bart_lookup_shortest6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), &b, &found)
+	return uint32(v), uint8(b), found != 0
+}
+
+// lookupBatch4 performs len(addrs) lookups with a single cgo call,
+// vals and found must be at least as long as addrs.
+func (t *trie) lookupBatch4(addrs []uint32, vals []uint32, found []uint8) {
+	if len(addrs) == 0 {
+		return
+	}
+	C.bart_lookup_batch4(t.handle(),
+		(*C.uint32_t)(unsafe.Pointer(&addrs[0])), C.size_t(len(addrs)),
+		(*C.uint32_t)(unsafe.Pointer(&vals[0])), (*C.uint8_t)(unsafe.Pointer(&found[0])))
+}
+
+// lookupBatch6 is the IPv6 counterpart of lookupBatch4.
+func (t *trie) lookupBatch6(addrs [][16]byte, vals []uint32, found []uint8) {
+	if len(addrs) == 0 {
+		return
+	}
+	C.bart_lookup_batch6(t.handle(),
+		(*[16]C.uint8_t)(unsafe.Pointer(&addrs[0])), C.size_t(len(addrs)),
+		(*C.uint32_t)(unsafe.Pointer(&vals[0])), (*C.uint8_t)(unsafe.Pointer(&found[0])))
+}
+
+// size4 returns the number of IPv4 prefixes, counted in C.
+func (t *trie) size4() int {
+	return int(C.bart_size4(t.handle()))
+}
+
+// size6 returns the number of IPv6 prefixes, counted in C.
+func (t *trie) size6() int {
+	return int(C.bart_size6(t.handle()))
+}
+
+// stats returns the statistics of both tries, walked in C.
+func (t *trie) stats() (v4, v6 FamilyStats) {
+	var cs C.bart_stats_t
+	C.bart_stats(t.handle(), &cs)
+	return familyStats(&cs.v4), familyStats(&cs.v6)
+}
+
+func familyStats(cs *C.bart_family_stats_t) FamilyStats {
+	levels := make([]int, len(cs.nodes_per_level))
+	for i, n := range cs.nodes_per_level {
+		levels[i] = int(n)
+	}
+	return FamilyStats{
+		Prefixes:      int(cs.prefixes),
+		Nodes:         int(cs.nodes),
+		Leaves:        int(cs.leaves),
+		Fringes:       int(cs.fringes),
+		Bytes:         int(cs.bytes),
+		NodesPerLevel: trimLevels(levels),
+	}
+}
+
+// shape returns the shape of both tries, walked in C.
+func (t *trie) shape() (v4, v6 FamilyShape) {
+	var c4, c6 C.bart_shape_t
+	C.bart_shape(t.handle(), &c4, &c6)
+	return familyShape(&c4), familyShape(&c6)
+}
+
+// fsck checks the C trie, it stores up to len(out) problems and returns
+// their total number.
+func (t *trie) fsck(out []fsckProblem) int {
+	var ptr *C.bart_fsck_problem_t
+	if len(out) > 0 {
+		ptr = (*C.bart_fsck_problem_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_fsck(t.handle(), ptr, C.size_t(len(out))))
+}
+
+func familyShape(cs *C.bart_shape_t) FamilyShape {
+	var s FamilyShape
+	levels := make([]LevelShape, len(cs.levels))
+	for i, l := range cs.levels {
+		levels[i] = LevelShape{
+			Nodes:    int(l.nodes),
+			Prefixes: int(l.prefixes),
+			Children: int(l.children),
+			Leaves:   int(l.leaves),
+			Fringes:  int(l.fringes),
+		}
+	}
+	s.Levels = trimShape(levels)
+	for i, n := range cs.prefix_fill {
+		s.PrefixFill[i] = int(n)
+	}
+	for i, n := range cs.child_fill {
+		s.ChildFill[i] = int(n)
+	}
+	return s
+}
+
+// items stores the structural dump of the trie in out and returns the
+// total number of items, like dump.
+func (t *trie) items(out []trieItem) int {
+	var ptr *C.bart_trie_item_t
+	if len(out) > 0 {
+		ptr = (*C.bart_trie_item_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_trie_items(t.handle(), ptr, C.size_t(len(out))))
+}
+
+// trace4 stores the items of the lookup path of addr in out and returns
+// their total number, like items.
+func (t *trie) trace4(addr uint32, out []trieItem) int {
+	var ptr *C.bart_trie_item_t
+	if len(out) > 0 {
+		ptr = (*C.bart_trie_item_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_lookup_trace4(t.handle(), C.uint32_t(addr), ptr, C.size_t(len(out))))
+}
+
+// trace6 is trace4 for IPv6.
+func (t *trie) trace6(addr *[16]byte, out []trieItem) int {
+	var ptr *C.bart_trie_item_t
+	if len(out) > 0 {
+		ptr = (*C.bart_trie_item_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_lookup_trace6(t.handle(), (*C.uint8_t)(unsafe.Pointer(&addr[0])), ptr, C.size_t(len(out))))
+}
+
+// dump stores the routes of the trie in out and returns the total number
+// of routes, which is larger than len(out) if out was too small.
+func (t *trie) dump(out []route) int {
+	var ptr *C.bart_route_t
+	if len(out) > 0 {
+		ptr = (*C.bart_route_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_dump(t.handle(), ptr, C.size_t(len(out))))
+}
+
+// walk calls fn with the routes of the trie in the order of dump, in
+// batches of up to batchSize routes, until fn returns false. The batch
+// is only valid during the call. The walk runs in C and calls back into
+// Go once per batch, fn must not modify the trie.
+func (t *trie) walk(fn func(batch []route) bool) {
+	buf := make([]route, batchSize)
+	h := cgo.NewHandle(fn)
+	defer h.Delete()
+	C.bart_walk(t.handle(), (*C.bart_route_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)),
+		C.bart_walk_fn(C.zartWalk), unsafe.Pointer(&h))
+}
+
+// walkSorted is walk in CIDR order, see bart_walk_sorted.
+func (t *trie) walkSorted(fn func(batch []route) bool) {
+	buf := make([]route, batchSize)
+	h := cgo.NewHandle(fn)
+	defer h.Delete()
+	C.bart_walk_sorted(t.handle(), (*C.bart_route_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)),
+		C.bart_walk_fn(C.zartWalk), unsafe.Pointer(&h))
+}
+
+//export zartWalk
+func zartWalk(ctx unsafe.Pointer, routes *C.bart_route_t, n C.size_t) C.int {
+	fn := (*(*cgo.Handle)(ctx)).Value().(func([]route) bool)
+	if fn(unsafe.Slice((*route)(unsafe.Pointer(routes)), int(n))) {
+		return 1
+	}
+	return 0
+}
+
+// dumpWide is dump with the whole 64-bit values.
+func (t *trie) dumpWide(out []route64) int {
+	var ptr *C.bart_route64_t
+	if len(out) > 0 {
+		ptr = (*C.bart_route64_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_dump64(t.handle(), ptr, C.size_t(len(out))))
+}
+
+// supernets stores the routes covering pfx in out, most specific first,
+// and returns their total number.
+func (t *trie) supernets(pfx *route, out []route) int {
+	return int(C.bart_supernets(t.handle(),
+		(*C.bart_route_t)(unsafe.Pointer(pfx)),
+		(*C.bart_route_t)(unsafe.Pointer(&out[0])), C.size_t(len(out))))
+}
+
+// page stores the routes following after in the order of walkSorted in
+// out, from the first one if after is nil, and returns their number.
+func (t *trie) page(after *route, out []route) int {
+	if len(out) == 0 {
+		return 0
+	}
+	return int(C.bart_page(t.handle(), (*C.bart_route_t)(unsafe.Pointer(after)),
+		(*C.bart_route_t)(unsafe.Pointer(&out[0])), C.size_t(len(out))))
+}
+
+// subnets stores the routes covered by pfx in out and returns their
+// total number, which is larger than len(out) if out was too small.
+func (t *trie) subnets(pfx *route, out []route) int {
+	var ptr *C.bart_route_t
+	if len(out) > 0 {
+		ptr = (*C.bart_route_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_subnets(t.handle(), (*C.bart_route_t)(unsafe.Pointer(pfx)), ptr, C.size_t(len(out))))
+}
+
+// deleteSubtree removes the routes covered by pfx and stores them in
+// out. It returns their total number, nothing is removed if that is
+// larger than len(out).
+func (t *trie) deleteSubtree(pfx *route, out []route) int {
+	var ptr *C.bart_route_t
+	if len(out) > 0 {
+		ptr = (*C.bart_route_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_delete_subtree(t.writable(), (*C.bart_route_t)(unsafe.Pointer(pfx)), ptr, C.size_t(len(out))))
+}
+
+// deleteBulk removes routes with a single cgo call and returns the
+// number of them that were present.
+func (t *trie) deleteBulk(routes []route) int {
+	if len(routes) == 0 {
+		return 0
+	}
+	return int(C.bart_delete_bulk(t.writable(), (*C.bart_route_t)(unsafe.Pointer(&routes[0])), C.size_t(len(routes))))
+}
+
+func (t *trie) overlapsPrefix(pfx *route) bool {
+	return C.bart_overlaps_prefix(t.handle(), (*C.bart_route_t)(unsafe.Pointer(pfx))) != 0
+}
+
+func (t *trie) overlaps(o *trie) bool {
+	return C.bart_overlaps(t.handle(), o.handle()) != 0
+}
+
+// union inserts the routes of o into t with their values offset by base.
+// Prefixes in both tries keep t's value and are reported in conflicts and
+// theirs, which need room for all routes of o.
+func (t *trie) union(o *trie, base uint32, conflicts []route, theirs []uint32) int {
+	var cptr *C.bart_route_t
+	var tptr *C.uint32_t
+	if len(conflicts) > 0 {
+		cptr = (*C.bart_route_t)(unsafe.Pointer(&conflicts[0]))
+		tptr = (*C.uint32_t)(unsafe.Pointer(&theirs[0]))
+	}
+	return int(C.bart_union(t.writable(), o.handle(), C.uint32_t(base), cptr, tptr, C.size_t(len(conflicts))))
+}
+
+// graft moves the subtries of o into t, see bart_graft.
+func (t *trie) graft(o *trie) bool {
+	return C.bart_graft(t.writable(), o.handle()) != 0
+}
+
+// diff stores the differences between t and o in out and returns their
+// total number, like dump. shared tells that both tries index one
+// registry, see bart_diff.
+func (t *trie) diff(o *trie, shared bool, out []diffItem) int {
+	var ptr *C.bart_diff_t
+	if len(out) > 0 {
+		ptr = (*C.bart_diff_t)(unsafe.Pointer(&out[0]))
+	}
+	var sh C.int
+	if shared {
+		sh = 1
+	}
+	return int(C.bart_diff(t.handle(), o.handle(), sh, ptr, C.size_t(len(out))))
+}
+
+// common stores the prefixes present in both tries in out, see
+// bart_common.
+func (t *trie) common(o *trie, out []diffItem) int {
+	var ptr *C.bart_diff_t
+	if len(out) > 0 {
+		ptr = (*C.bart_diff_t)(unsafe.Pointer(&out[0]))
+	}
+	return int(C.bart_common(t.handle(), o.handle(), ptr, C.size_t(len(out))))
+}
+
+// setCPUFeatures passes the CPU features to the C library and returns
+// the ones it uses, see bart_set_cpu_features.
+func setCPUFeatures(features uint32) uint32 {
+	return uint32(C.bart_set_cpu_features(C.uint32_t(features)))
+}
+
+func (t *trie) samePrefixes(o *trie) bool {
+	return C.bart_same_prefixes(t.handle(), o.handle()) != 0
+}
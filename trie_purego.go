@@ -0,0 +1,576 @@
+//go:build !cgo || purego
+
+package zart
+
+import (
+	"encoding/binary"
+	"runtime"
+	"slices"
+
+	"github.com/gx14ac/zart/internal/bart"
+)
+
+// trie is the pure-Go counterpart of the cgo handle in trie_cgo.go,
+// selected with the purego build tag or when cgo is disabled.
+// It has the same method set and semantics as the C table, down to the
+// 64-bit values of which the 32-bit methods see the low half.
+type trie struct {
+	t       bart.Trie[uint64]
+	closed  bool
+	frozen  bool
+	cleanup runtime.Cleanup
+}
+
+// track reports t if it is never closed, the garbage collector frees the
+// nodes in any case.
+func track(t *trie) *trie {
+	if stack := allocStack(); stack != "" {
+		t.cleanup = runtime.AddCleanup(t, reportLeak, stack)
+	}
+	return t
+}
+
+// newTrie ignores the arena option, the Go heap allocates the nodes.
+func newTrie(o options) *trie {
+	return track(&trie{t: bart.Trie[uint64]{Compress: o.compress}})
+}
+
+func (t *trie) clone() *trie {
+	return track(&trie{t: *t.live().Clone()})
+}
+
+func (t *trie) persist() *trie {
+	return track(&trie{t: *t.live().Persist()})
+}
+
+func (t *trie) close() {
+	t.cleanup.Stop()
+	t.t = bart.Trie[uint64]{}
+	t.closed = true
+}
+
+// live returns the trie, it panics with ErrClosed once the table is
+// closed like the C table does.
+func (t *trie) live() *bart.Trie[uint64] {
+	if t.closed {
+		panic(ErrClosed)
+	}
+	return &t.t
+}
+
+// writable is live for the methods modifying the trie, it panics with
+// ErrFrozen once the trie is frozen.
+func (t *trie) writable() *bart.Trie[uint64] {
+	if t.frozen {
+		panic(ErrFrozen)
+	}
+	return t.live()
+}
+
+func (t *trie) insert4(addr uint32, bits uint8, val uint32) (old uint32, existed bool) {
+	prev, existed := t.insertWide4(addr, bits, uint64(val))
+	return uint32(prev), existed
+}
+
+func (t *trie) insert6(addr *[16]byte, bits uint8, val uint32) (old uint32, existed bool) {
+	prev, existed := t.insertWide6(addr, bits, uint64(val))
+	return uint32(prev), existed
+}
+
+func (t *trie) insertWide4(addr uint32, bits uint8, val uint64) (old uint64, existed bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.writable().Insert(a[:], int(bits), val)
+}
+
+func (t *trie) insertWide6(addr *[16]byte, bits uint8, val uint64) (old uint64, existed bool) {
+	return t.writable().Insert(addr[:], int(bits), val)
+}
+
+func (t *trie) insertWide6HiLo(hi, lo uint64, bits uint8, val uint64) (old uint64, existed bool) {
+	a := addr6HiLo(hi, lo)
+	return t.writable().Insert(a[:], int(bits), val)
+}
+
+func (t *trie) getOrInsert4(addr uint32, bits uint8, val uint32) (old uint32, existed bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.getOrInsert(a[:], bits, val)
+}
+
+func (t *trie) getOrInsert6(addr *[16]byte, bits uint8, val uint32) (old uint32, existed bool) {
+	return t.getOrInsert(addr[:], bits, val)
+}
+
+func (t *trie) getOrInsert(octets []byte, bits uint8, val uint32) (old uint32, existed bool) {
+	prev, existed := t.writable().Get(octets, int(bits))
+	if !existed {
+		t.writable().Insert(octets, int(bits), uint64(val))
+	}
+	return uint32(prev), existed
+}
+
+func (t *trie) insertBulk(routes []route, replaced []uint32) int {
+	n := 0
+	for i := range routes {
+		r := &routes[i]
+		octets := r.addr[:]
+		if r.is4 != 0 {
+			octets = r.addr[:4]
+		}
+		if old, existed := t.writable().Insert(octets, int(r.bits), uint64(r.val)); existed {
+			replaced[n] = uint32(old)
+			n++
+		}
+	}
+	return n
+}
+
+func (t *trie) delete4(addr uint32, bits uint8) (old uint32, ok bool) {
+	prev, ok := t.deleteWide4(addr, bits)
+	return uint32(prev), ok
+}
+
+func (t *trie) delete6(addr *[16]byte, bits uint8) (old uint32, ok bool) {
+	prev, ok := t.deleteWide6(addr, bits)
+	return uint32(prev), ok
+}
+
+func (t *trie) deleteWide4(addr uint32, bits uint8) (old uint64, ok bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.writable().Delete(a[:], int(bits))
+}
+
+func (t *trie) deleteWide6(addr *[16]byte, bits uint8) (old uint64, ok bool) {
+	return t.writable().Delete(addr[:], int(bits))
+}
+
+func (t *trie) get4(addr uint32, bits uint8) (uint32, bool) {
+	val, ok := t.getWide4(addr, bits)
+	return uint32(val), ok
+}
+
+func (t *trie) get6(addr *[16]byte, bits uint8) (uint32, bool) {
+	val, ok := t.getWide6(addr, bits)
+	return uint32(val), ok
+}
+
+func (t *trie) getWide4(addr uint32, bits uint8) (uint64, bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.live().Get(a[:], int(bits))
+}
+
+func (t *trie) getWide6(addr *[16]byte, bits uint8) (uint64, bool) {
+	return t.live().Get(addr[:], int(bits))
+}
+
+func (t *trie) contains4(addr uint32) bool {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.live().Contains(a[:])
+}
+
+func (t *trie) contains6(addr *[16]byte) bool {
+	return t.live().Contains(addr[:])
+}
+
+func (t *trie) lookup4(addr uint32) (uint32, bool) {
+	val, ok := t.lookupWide4(addr)
+	return uint32(val), ok
+}
+
+func (t *trie) lookup6(addr *[16]byte) (uint32, bool) {
+	val, ok := t.lookupWide6(addr)
+	return uint32(val), ok
+}
+
+func (t *trie) lookupWide4(addr uint32) (uint64, bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.live().Lookup(a[:])
+}
+
+func (t *trie) lookupWide6(addr *[16]byte) (uint64, bool) {
+	return t.live().Lookup(addr[:])
+}
+
+func (t *trie) lookup6HiLo(hi, lo uint64) (uint32, bool) {
+	val, ok := t.lookupWide6HiLo(hi, lo)
+	return uint32(val), ok
+}
+
+func (t *trie) lookupWide6HiLo(hi, lo uint64) (uint64, bool) {
+	a := addr6HiLo(hi, lo)
+	return t.live().Lookup(a[:])
+}
+
+func (t *trie) lookupPrefix4(addr uint32) (val uint32, bits uint8, ok bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	b, v, ok := t.live().LookupPrefix(a[:])
+	return uint32(v), uint8(b), ok
+}
+
+func (t *trie) lookupPrefix6(addr *[16]byte) (val uint32, bits uint8, ok bool) {
+	b, v, ok := t.live().LookupPrefix(addr[:])
+	return uint32(v), uint8(b), ok
+}
+
+func (t *trie) lookupShortest4(addr uint32) (val uint32, bits uint8, ok bool) {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	b, v, ok := t.live().LookupShortest(a[:])
+	return uint32(v), uint8(b), ok
+}
+
+func (t *trie) lookupShortest6(addr *[16]byte) (val uint32, bits uint8, ok bool) {
+	b, v, ok := t.live().LookupShortest(addr[:])
+	return uint32(v), uint8(b), ok
+}
+
+func (t *trie) lookupBatch4(addrs []uint32, vals []uint32, found []uint8) {
+	for i, addr := range addrs {
+		val, ok := t.lookup4(addr)
+		vals[i], found[i] = val, boolToUint8(ok)
+	}
+}
+
+func (t *trie) lookupBatch6(addrs [][16]byte, vals []uint32, found []uint8) {
+	for i := range addrs {
+		val, ok := t.lookup6(&addrs[i])
+		vals[i], found[i] = val, boolToUint8(ok)
+	}
+}
+
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (t *trie) size4() int {
+	return t.live().Size4()
+}
+
+func (t *trie) size6() int {
+	return t.live().Size6()
+}
+
+func (t *trie) stats() (v4, v6 FamilyStats) {
+	return familyStats(t.live().Stats(true)), familyStats(t.live().Stats(false))
+}
+
+func familyStats(s bart.Stats) FamilyStats {
+	return FamilyStats{
+		Prefixes:      s.Prefixes,
+		Nodes:         s.Nodes,
+		Leaves:        s.Leaves,
+		Fringes:       s.Fringes,
+		Bytes:         s.Bytes,
+		NodesPerLevel: trimLevels(slices.Clone(s.Levels[:])),
+	}
+}
+
+func (t *trie) shape() (v4, v6 FamilyShape) {
+	return familyShape(t.live().Shape(true)), familyShape(t.live().Shape(false))
+}
+
+func familyShape(bs bart.Shape) FamilyShape {
+	s := FamilyShape{PrefixFill: bs.PrefixFill, ChildFill: bs.ChildFill}
+	levels := make([]LevelShape, len(bs.Levels))
+	for i, l := range bs.Levels {
+		levels[i] = LevelShape{Nodes: l.Nodes, Prefixes: l.Prefixes, Children: l.Children, Leaves: l.Leaves, Fringes: l.Fringes}
+	}
+	s.Levels = trimShape(levels)
+	return s
+}
+
+func (t *trie) items(out []trieItem) int {
+	n := 0
+	t.live().Items(func(it bart.Item[uint64]) {
+		if n < len(out) {
+			out[n] = toTrieItem(it)
+		}
+		n++
+	})
+	return n
+}
+
+// toTrieItem converts an item of the Go trie into the C layout.
+func toTrieItem(it bart.Item[uint64]) trieItem {
+	kind := uint8(itemPrefix)
+	switch {
+	case it.IsNode:
+		kind = itemNode
+	case it.Fringe:
+		kind = itemFringe
+	case it.Leaf:
+		kind = itemLeaf
+	}
+	return trieItem{
+		node: uint32(it.Node), parent: uint32(it.Parent),
+		kind: kind, depth: uint8(it.Depth), octet: it.Octet,
+		route: octetsRoute(it.Octets, it.Bits, it.Val),
+	}
+}
+
+func (t *trie) trace4(addr uint32, out []trieItem) int {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], addr)
+	return t.trace(a[:], out)
+}
+
+func (t *trie) trace6(addr *[16]byte, out []trieItem) int {
+	return t.trace(addr[:], out)
+}
+
+func (t *trie) trace(octets []byte, out []trieItem) int {
+	n := 0
+	t.live().Trace(octets, func(it bart.Item[uint64]) {
+		if n < len(out) {
+			out[n] = toTrieItem(it)
+		}
+		n++
+	})
+	return n
+}
+
+func (t *trie) dump(out []route) int {
+	n := 0
+	t.live().Walk(collect(out, &n))
+	return n
+}
+
+func (t *trie) walk(fn func(batch []route) bool) {
+	batches(t.live().Walk, fn)
+}
+
+func (t *trie) walkSorted(fn func(batch []route) bool) {
+	batches(t.live().WalkSorted, fn)
+}
+
+func (t *trie) page(after *route, out []route) int {
+	n := 0
+	fill := func(octets []byte, bits int, val uint64) bool {
+		out[n] = octetsRoute(octets, bits, val)
+		n++
+		return n < len(out)
+	}
+	switch {
+	case len(out) == 0:
+	case after == nil:
+		t.live().WalkSorted(fill)
+	case after.is4 != 0:
+		t.live().WalkSortedAfter(after.addr[:4], int(after.bits), fill)
+	default:
+		t.live().WalkSortedAfter(after.addr[:], int(after.bits), fill)
+	}
+	return n
+}
+
+// batches passes the routes of walk to fn in batches of up to batchSize,
+// like bart_walk.
+func batches(walk func(func(octets []byte, bits int, val uint64) bool), fn func(batch []route) bool) {
+	buf := make([]route, 0, batchSize)
+	walk(func(octets []byte, bits int, val uint64) bool {
+		if buf = append(buf, octetsRoute(octets, bits, val)); len(buf) < cap(buf) {
+			return true
+		}
+		more := fn(buf)
+		buf = buf[:0]
+		return more
+	})
+	if len(buf) > 0 {
+		fn(buf)
+	}
+}
+
+func (t *trie) dumpWide(out []route64) int {
+	n := 0
+	t.live().Walk(func(octets []byte, bits int, val uint64) bool {
+		if n < len(out) {
+			r := octetsRoute(octets, bits, 0)
+			out[n] = route64{addr: r.addr, bits: r.bits, is4: r.is4, val: val}
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+func (t *trie) subnets(pfx *route, out []route) int {
+	octets := pfx.addr[:]
+	if pfx.is4 != 0 {
+		octets = pfx.addr[:4]
+	}
+	n := 0
+	t.live().Subnets(octets, int(pfx.bits), collect(out, &n))
+	return n
+}
+
+func (t *trie) deleteSubtree(pfx *route, out []route) int {
+	n := t.subnets(pfx, out)
+	if n > len(out) {
+		return n
+	}
+	for i := range out[:n] {
+		octets := out[i].addr[:]
+		if out[i].is4 != 0 {
+			octets = out[i].addr[:4]
+		}
+		t.writable().Delete(octets, int(out[i].bits))
+	}
+	return n
+}
+
+func (t *trie) deleteBulk(routes []route) int {
+	n := 0
+	for i := range routes {
+		octets := routes[i].addr[:]
+		if routes[i].is4 != 0 {
+			octets = routes[i].addr[:4]
+		}
+		if _, ok := t.writable().Delete(octets, int(routes[i].bits)); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// collect returns a walk callback that stores routes in out and counts
+// all of them in *n, with the semantics of bart_dump.
+func collect(out []route, n *int) func(octets []byte, bits int, val uint64) bool {
+	return func(octets []byte, bits int, val uint64) bool {
+		if *n < len(out) {
+			out[*n] = octetsRoute(octets, bits, val)
+		}
+		*n++
+		return true
+	}
+}
+
+// octetsRoute builds a route from a prefix as passed by the walk callbacks.
+func octetsRoute(octets []byte, bits int, val uint64) route {
+	r := route{bits: uint8(bits), val: uint32(val)}
+	copy(r.addr[:], octets)
+	if len(octets) == 4 {
+		r.is4 = 1
+	}
+	return r
+}
+
+func (t *trie) supernets(pfx *route, out []route) int {
+	octets := pfx.addr[:]
+	if pfx.is4 != 0 {
+		octets = pfx.addr[:4]
+	}
+	n := 0
+	t.live().Supernets(octets, int(pfx.bits), func(bits int, val uint64) bool {
+		if n < len(out) {
+			r := route{bits: uint8(bits), is4: pfx.is4, val: uint32(val)}
+			copy(r.addr[:], octets)
+			out[n] = r
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+func (t *trie) overlapsPrefix(pfx *route) bool {
+	octets := pfx.addr[:]
+	if pfx.is4 != 0 {
+		octets = pfx.addr[:4]
+	}
+	return t.live().OverlapsPrefix(octets, int(pfx.bits))
+}
+
+func (t *trie) overlaps(o *trie) bool {
+	return t.live().Overlaps(o.live())
+}
+
+func (t *trie) diff(o *trie, shared bool, out []diffItem) int {
+	n := 0
+	bart.Diff(t.live(), o.live(), shared, func(octets []byte, bits int, ours, theirs uint64, inT, inO bool) bool {
+		if n < len(out) {
+			d := diffItem{route: octetsRoute(octets, bits, ours), theirs: uint32(theirs), kind: diffChanged}
+			switch {
+			case !inO:
+				d.kind = diffRemoved
+			case !inT:
+				d.kind = diffAdded
+			}
+			out[n] = d
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+func (t *trie) common(o *trie, out []diffItem) int {
+	n := 0
+	bart.Diff(t.live(), o.live(), false, func(octets []byte, bits int, ours, theirs uint64, inT, inO bool) bool {
+		if !inT || !inO {
+			return true
+		}
+		if n < len(out) {
+			out[n] = diffItem{route: octetsRoute(octets, bits, ours), theirs: uint32(theirs), kind: diffChanged}
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// setCPUFeatures selects nothing: math/bits, which the pure-Go trie
+// counts its bits with, uses POPCNT wherever the CPU has it.
+func setCPUFeatures(features uint32) uint32 {
+	return features & cpuPOPCNT
+}
+
+func (t *trie) samePrefixes(o *trie) bool {
+	return bart.SamePrefixes(t.live(), o.live())
+}
+
+func (t *trie) graft(o *trie) bool {
+	return t.writable().Graft(o.live())
+}
+
+func (t *trie) union(o *trie, base uint32, conflicts []route, theirs []uint32) int {
+	n := 0
+	o.live().Walk(func(octets []byte, bits int, val uint64) bool {
+		ours, existed := t.writable().Insert(octets, bits, uint64(base)+val)
+		if !existed {
+			return true
+		}
+		t.writable().Insert(octets, bits, ours)
+		if n < len(conflicts) {
+			conflicts[n] = octetsRoute(octets, bits, ours)
+			theirs[n] = uint32(val)
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+func (t *trie) fsck(out []fsckProblem) int {
+	n := 0
+	for _, is4 := range []bool{true, false} {
+		t.live().Fsck(is4, func(p bart.Problem) {
+			if n < len(out) {
+				// the codes of the Go trie are those of the C trie
+				out[n] = fsckProblem{path: p.Path, depth: uint8(p.Depth), code: uint8(p.Code), have: uint64(p.Have), want: uint64(p.Want)}
+				if is4 {
+					out[n].is4 = 1
+				}
+			}
+			n++
+		})
+	}
+	return n
+}
@@ -0,0 +1,149 @@
+package zart
+
+import (
+	"container/heap"
+	"context"
+	"net/netip"
+	"time"
+)
+
+// expiries are the deadlines of the prefixes inserted with
+// InsertWithTTL. The heap may hold outdated deadlines of prefixes that
+// were deleted, reinserted or given a new TTL since, the map has the
+// current ones.
+type expiries[V any] struct {
+	deadline map[netip.Prefix]time.Time
+	queue    deadlineHeap
+	onExpire func(pfx netip.Prefix, val V)
+}
+
+type deadline struct {
+	at  time.Time
+	pfx netip.Prefix
+}
+
+type deadlineHeap []deadline
+
+func (h deadlineHeap) Len() int           { return len(h) }
+func (h deadlineHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h deadlineHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *deadlineHeap) Push(x any)        { *h = append(*h, x.(deadline)) }
+
+func (h *deadlineHeap) Pop() any {
+	old := *h
+	d := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return d
+}
+
+func (t *Table[V]) expiries() *expiries[V] {
+	if t.ttl == nil {
+		t.ttl = &expiries[V]{deadline: map[netip.Prefix]time.Time{}}
+	}
+	return t.ttl
+}
+
+// InsertWithTTL is like Insert, and the prefix expires after ttl. Expired
+// prefixes are removed by Expire, which InsertWithTTL calls itself, so a
+// table that keeps receiving entries ages out the old ones on its own. An
+// idle table needs Expire to be called, for a ConcurrentTable see
+// ExpireEvery. Until then lookups still see expired prefixes.
+//
+// Inserting or deleting the prefix otherwise drops its deadline, Modify
+// keeps it. For a prefix that Insert ignores InsertWithTTL changes
+// nothing. Deadlines are not copied by Clone or the persistent inserts.
+func (t *Table[V]) InsertWithTTL(pfx netip.Prefix, val V, ttl time.Duration) {
+	if !pfx.IsValid() {
+		return
+	}
+	now := time.Now()
+	t.Expire(now)
+	if !t.insert(pfx, val) {
+		return
+	}
+
+	e := t.expiries()
+	pfx = pfx.Masked()
+	at := now.Add(ttl)
+	e.deadline[pfx] = at
+	heap.Push(&e.queue, deadline{at, pfx})
+}
+
+// TTL returns the time left until pfx expires, ok is false if pfx was not
+// inserted with InsertWithTTL or is not in the table.
+func (t *Table[V]) TTL(pfx netip.Prefix) (ttl time.Duration, ok bool) {
+	if t.ttl == nil {
+		return 0, false
+	}
+	at, ok := t.ttl.deadline[pfx.Masked()]
+	if !ok {
+		return 0, false
+	}
+	return max(time.Until(at), 0), true
+}
+
+// OnExpire sets a function called for every prefix removed by Expire,
+// after it was removed. fn must not modify the table.
+func (t *Table[V]) OnExpire(fn func(pfx netip.Prefix, val V)) {
+	t.expiries().onExpire = fn
+}
+
+// Expire removes all prefixes whose TTL ran out at now and returns their
+// number.
+func (t *Table[V]) Expire(now time.Time) int {
+	e := t.ttl
+	if e == nil {
+		return 0
+	}
+	n := 0
+	for len(e.queue) > 0 && !e.queue[0].at.After(now) {
+		d := heap.Pop(&e.queue).(deadline)
+		if at, ok := e.deadline[d.pfx]; !ok || !at.Equal(d.at) {
+			continue
+		}
+		val, _ := t.Get(d.pfx)
+		t.Delete(d.pfx) // drops the deadline
+		n++
+		if e.onExpire != nil {
+			e.onExpire(d.pfx, val)
+		}
+	}
+	return n
+}
+
+// forget drops the deadline of pfx, called by the modifications that
+// make a prefix permanent or remove it.
+func (e *expiries[V]) forget(pfx netip.Prefix) {
+	delete(e.deadline, pfx)
+}
+
+// reset drops all deadlines, the callback set with OnExpire stays.
+func (e *expiries[V]) reset() {
+	clear(e.deadline)
+	e.queue = nil
+}
+
+// InsertWithTTL is like Table.InsertWithTTL.
+func (c *ConcurrentTable[V]) InsertWithTTL(pfx netip.Prefix, val V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t.InsertWithTTL(pfx, val, ttl)
+}
+
+// ExpireEvery calls Expire every interval until ctx is done, in the
+// calling goroutine; run it with go. The callback set with OnExpire runs
+// with the table locked.
+func (c *ConcurrentTable[V]) ExpireEvery(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			c.mu.Lock()
+			c.t.Expire(now)
+			c.mu.Unlock()
+		}
+	}
+}
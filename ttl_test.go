@@ -0,0 +1,111 @@
+package zart
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestInsertWithTTL(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+
+	var expired []netip.Prefix
+	tbl.OnExpire(func(pfx netip.Prefix, val string) {
+		if val != pfx.String() {
+			t.Errorf("OnExpire(%s) got value %q", pfx, val)
+		}
+		expired = append(expired, pfx)
+	})
+
+	tbl.Insert(mpp("10.0.0.0/8"), "10.0.0.0/8")
+	tbl.InsertWithTTL(mpp("10.1.0.0/16"), "10.1.0.0/16", time.Hour)
+	tbl.InsertWithTTL(mpp("10.2.0.0/16"), "10.2.0.0/16", time.Hour)
+	tbl.InsertWithTTL(mpp("10.3.0.0/16"), "10.3.0.0/16", time.Hour)
+	tbl.InsertWithTTL(mpp("10.4.0.0/16"), "10.4.0.0/16", 3*time.Hour)
+
+	tbl.Insert(mpp("10.2.0.0/16"), "10.2.0.0/16") // now permanent
+	tbl.Delete(mpp("10.3.0.0/16"))
+
+	if ttl, ok := tbl.TTL(mpp("10.1.0.0/16")); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL = %v, %v", ttl, ok)
+	}
+	if _, ok := tbl.TTL(mpp("10.2.0.0/16")); ok {
+		t.Errorf("Insert kept the deadline")
+	}
+
+	if n := tbl.Expire(time.Now()); n != 0 {
+		t.Errorf("Expire before the deadline removed %d", n)
+	}
+	if n := tbl.Expire(time.Now().Add(2 * time.Hour)); n != 1 || len(expired) != 1 || expired[0] != mpp("10.1.0.0/16") {
+		t.Errorf("Expire = %d, expired %v, want 10.1.0.0/16", n, expired)
+	}
+	if v, _ := tbl.Lookup(mpa("10.1.1.1")); v != "10.0.0.0/8" {
+		t.Errorf("expired prefix still matches: %q", v)
+	}
+	if tbl.Size() != 3 {
+		t.Errorf("size %d after Expire, want 3", tbl.Size())
+	}
+}
+
+func TestInsertWithTTLIgnored(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opt  Option
+		perm netip.Prefix // inserted before, if valid
+		pfx  netip.Prefix // ignored by Insert
+	}{
+		{"strict over a route", WithStrict(), mpp("10.0.0.0/8"), mpp("10.0.0.1/8")},
+		{"strict", WithStrict(), netip.Prefix{}, mpp("192.0.2.1/24")},
+		{"memory limit", WithMaxMemory(1), netip.Prefix{}, mpp("192.0.2.0/24")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tbl := New[int](tc.opt)
+			defer tbl.Close()
+			tbl.OnExpire(func(pfx netip.Prefix, val int) {
+				t.Errorf("OnExpire(%s, %d) after an ignored insert", pfx, val)
+			})
+			if tc.perm.IsValid() {
+				tbl.Insert(tc.perm, 1)
+			}
+			tbl.InsertWithTTL(tc.pfx, 2, time.Hour)
+			if _, ok := tbl.TTL(tc.pfx); ok {
+				t.Errorf("ignored insert of %s has a TTL", tc.pfx)
+			}
+			if n := tbl.Expire(time.Now().Add(2 * time.Hour)); n != 0 {
+				t.Errorf("Expire removed %d", n)
+			}
+			if !tc.perm.IsValid() {
+				if n := tbl.Size(); n != 0 {
+					t.Errorf("size %d, want 0", n)
+				}
+			} else if v, ok := tbl.Get(tc.perm); !ok || v != 1 {
+				t.Errorf("permanent route = %d, %v", v, ok)
+			}
+		})
+	}
+}
+
+func TestExpireEvery(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+
+	c.InsertWithTTL(mpp("192.0.2.0/24"), 1, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.ExpireEvery(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Contains(mpa("192.0.2.1")) {
+		if time.Now().After(deadline) {
+			t.Fatal("entry did not expire")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
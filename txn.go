@@ -0,0 +1,91 @@
+package zart
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// ErrTxDone is returned by Commit and Rollback of a transaction that has
+// already been committed or rolled back.
+var ErrTxDone = errors.New("zart: transaction has already been committed or rolled back")
+
+// Txn stages inserts and deletes for a ConcurrentTable. Nothing changes
+// until Commit, which applies all staged operations under one write
+// lock, so readers see the table either before or after the transaction.
+// A Txn is not safe for concurrent use.
+type Txn[V any] struct {
+	c    *ConcurrentTable[V]
+	ops  []txnOp[V]
+	done bool
+}
+
+// txnOp is a staged operation, a delete if del is set.
+type txnOp[V any] struct {
+	pfx netip.Prefix
+	val V
+	del bool
+}
+
+// Begin starts a transaction on c. Transactions don't isolate from each
+// other: operations are applied in commit order, later commits win.
+func (c *ConcurrentTable[V]) Begin() *Txn[V] {
+	return &Txn[V]{c: c}
+}
+
+// Insert stages an insert of pfx with value val.
+func (tx *Txn[V]) Insert(pfx netip.Prefix, val V) {
+	tx.stage(txnOp[V]{pfx: pfx, val: val})
+}
+
+// Delete stages the removal of pfx.
+func (tx *Txn[V]) Delete(pfx netip.Prefix) {
+	tx.stage(txnOp[V]{pfx: pfx, del: true})
+}
+
+// Len returns the number of staged operations.
+func (tx *Txn[V]) Len() int {
+	return len(tx.ops)
+}
+
+func (tx *Txn[V]) stage(op txnOp[V]) {
+	if tx.done {
+		panic(ErrTxDone)
+	}
+	tx.ops = append(tx.ops, op)
+}
+
+// Commit applies the staged operations in order. Runs of inserts are
+// applied as one InsertBatch.
+func (tx *Txn[V]) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	var batch []RouteEntry[V]
+	tx.c.Update(func(t *Table[V]) {
+		defer t.start("commit")(len(tx.ops), nil)
+		for _, op := range tx.ops {
+			if !op.del {
+				batch = append(batch, RouteEntry[V]{op.pfx, op.val})
+				continue
+			}
+			t.InsertBatch(batch)
+			batch = batch[:0]
+			t.Delete(op.pfx)
+		}
+		t.InsertBatch(batch)
+	})
+	tx.ops = nil
+	return nil
+}
+
+// Rollback discards the staged operations.
+func (tx *Txn[V]) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.ops = nil
+	return nil
+}
@@ -0,0 +1,106 @@
+package zart
+
+import (
+	"maps"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTxnCommit(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+	c.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+
+	tx := c.Begin()
+	tx.Insert(netip.MustParsePrefix("10.1.0.0/16"), 2)
+	tx.Delete(netip.MustParsePrefix("10.0.0.0/8"))
+	tx.Insert(netip.MustParsePrefix("10.0.0.0/8"), 3)
+	tx.Delete(netip.MustParsePrefix("10.1.0.0/16"))
+	tx.Insert(netip.MustParsePrefix("192.168.0.0/16"), 4)
+	if tx.Len() != 5 {
+		t.Errorf("Len = %d, want 5", tx.Len())
+	}
+	if _, ok := c.Lookup(netip.MustParseAddr("192.168.1.1")); ok {
+		t.Errorf("staged insert visible before Commit")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[netip.Prefix]int{
+		netip.MustParsePrefix("10.0.0.0/8"):     3,
+		netip.MustParsePrefix("192.168.0.0/16"): 4,
+	}
+	got := map[netip.Prefix]int{}
+	for pfx, val := range c.All() {
+		got[pfx] = val
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("after Commit: %v, want %v", got, want)
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("second Commit = %v, want ErrTxDone", err)
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+
+	tx := c.Begin()
+	tx.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Lookup(netip.MustParseAddr("10.1.1.1")); ok {
+		t.Errorf("rolled back insert applied")
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Commit after Rollback = %v, want ErrTxDone", err)
+	}
+	defer func() {
+		if recover() != ErrTxDone {
+			t.Errorf("Insert on a finished transaction did not panic with ErrTxDone")
+		}
+	}()
+	tx.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+}
+
+// TestTxnAtomic moves a route between two prefixes in transactions while
+// readers check that exactly one of them is present.
+func TestTxnAtomic(t *testing.T) {
+	a, b := netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("172.16.0.0/12")
+	c := NewConcurrent[int]()
+	defer c.Close()
+	c.Insert(a, 0)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			c.Read(func(tbl *Table[int]) {
+				_, okA := tbl.Lookup(a.Addr())
+				_, okB := tbl.Lookup(b.Addr())
+				if okA == okB {
+					t.Errorf("reader saw a half applied transaction: %v %v", okA, okB)
+				}
+			})
+		}
+	}()
+	for i := range 500 {
+		from, to := a, b
+		if i%2 == 1 {
+			from, to = b, a
+		}
+		tx := c.Begin()
+		tx.Delete(from)
+		tx.Insert(to, i)
+		tx.Commit()
+	}
+	stop.Store(true)
+	wg.Wait()
+}
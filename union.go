@@ -0,0 +1,70 @@
+package zart
+
+import "net/netip"
+
+// Union merges all prefixes of o into t. For prefixes present in both
+// tables resolve is called with the value in t as a and the value in o as
+// b, its result is stored in t. With a nil resolve the value of o wins.
+//
+// The merge runs in a single call into the trie; only the conflicting
+// prefixes come back to Go for resolve. Payloads are copied shallowly, o
+// is not modified. A watched table inserts the prefixes of o one by one
+// instead, to report every change.
+func (t *Table[V]) Union(o *Table[V], resolve func(pfx netip.Prefix, a, b V) V) {
+	t.mutable()
+	if t.watch != nil {
+		t.unionWatched(o, resolve)
+		return
+	}
+
+	// resolved values are stored in place, other versions must not see them
+	t.vals = t.vals.unshare()
+	t.changes++
+
+	if o == t {
+		// every prefix conflicts with itself
+		if resolve != nil {
+			for _, r := range t.routes() {
+				v := t.vals.get(r.val)
+				t.vals.set(r.val, resolve(r.prefix(), v, v))
+			}
+		}
+		return
+	}
+
+	// o's payloads move into t's registry, the trie values are offset
+	// so they keep pointing at them
+	n := o.Size()
+	base := t.vals.absorb(o.vals)
+	conflicts := make([]route, n)
+	theirs := make([]uint32, n)
+
+	k := t.trie.union(o.trie, base, conflicts, theirs)
+	for i := range conflicts[:k] {
+		ours, in := conflicts[i].val, base+theirs[i]
+		b := t.vals.get(in)
+		if resolve != nil {
+			b = resolve(conflicts[i].prefix(), t.vals.get(ours), b)
+		}
+		t.vals.set(ours, b)
+		t.vals.release(in)
+	}
+	t.count(EventInsert, n-k)
+	t.count(EventUpdate, k)
+}
+
+// unionWatched is Union through Insert, which reports the changes. The
+// resolved values are computed before the first insert, so resolve sees
+// t as it was, as with a self-union in the trie.
+func (t *Table[V]) unionWatched(o *Table[V], resolve func(pfx netip.Prefix, a, b V) V) {
+	var entries []RouteEntry[V]
+	for pfx, b := range o.All() {
+		if resolve != nil {
+			if a, ok := t.Get(pfx); ok {
+				b = resolve(pfx, a, b)
+			}
+		}
+		entries = append(entries, RouteEntry[V]{pfx, b})
+	}
+	t.InsertBatch(entries)
+}
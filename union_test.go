@@ -0,0 +1,82 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	prng := rand.New(rand.NewPCG(14, 14))
+
+	a, b := New[int](), New[int]()
+	defer a.Close()
+	defer b.Close()
+
+	want := map[netip.Prefix]int{}
+	for _, pfx := range randomPrefixes(prng, 1500) {
+		a.Insert(pfx, 1)
+		want[pfx] = 1
+	}
+	inB := map[netip.Prefix]bool{}
+	for _, pfx := range randomPrefixes(prng, 1500) {
+		b.Insert(pfx, 10)
+		inB[pfx] = true
+	}
+	for pfx := range inB {
+		want[pfx] += 10
+	}
+
+	var resolved int
+	a.Union(b, func(_ netip.Prefix, x, y int) int {
+		resolved++
+		return x + y
+	})
+
+	got := map[netip.Prefix]int{}
+	for pfx, val := range a.All() {
+		got[pfx] = val
+	}
+	if len(got) != len(want) {
+		t.Fatalf("union has %d prefixes, want %d", len(got), len(want))
+	}
+	conflicts := 0
+	for pfx, val := range want {
+		if got[pfx] != val {
+			t.Fatalf("union: %s = %d, want %d", pfx, got[pfx], val)
+		}
+		if val == 11 {
+			conflicts++
+		}
+	}
+	if resolved != conflicts {
+		t.Errorf("resolve called %d times, want %d", resolved, conflicts)
+	}
+	if live := a.vals.len(); live != len(want) {
+		t.Errorf("%d live slots after union, want %d", live, len(want))
+	}
+
+	// b is unchanged
+	if n := len(fill(0, b.trie.dump)); n != len(inB) {
+		t.Errorf("other table has %d prefixes after union, want %d", n, len(inB))
+	}
+}
+
+func TestUnionNilResolve(t *testing.T) {
+	a, b := New[string](), New[string]()
+	defer a.Close()
+	defer b.Close()
+
+	a.Insert(mpp("10.0.0.0/8"), "a")
+	b.Insert(mpp("10.0.0.0/8"), "b")
+	a.Union(b, nil)
+
+	if got, _ := a.Lookup(mpa("10.0.0.1")); got != "b" {
+		t.Errorf("Union(nil resolve) kept %q, want the value of other", got)
+	}
+
+	a.Union(a, func(_ netip.Prefix, x, y string) string { return x + y })
+	if got, _ := a.Lookup(mpa("10.0.0.1")); got != "bb" {
+		t.Errorf("self union = %q, want %q", got, "bb")
+	}
+}
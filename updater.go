@@ -0,0 +1,162 @@
+package zart
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// UpdaterConfig tunes an Updater, the zero value takes the defaults.
+type UpdaterConfig struct {
+	// Window is how long Run collects updates before applying them,
+	// 100 milliseconds.
+	Window time.Duration
+
+	// MaxPending is the number of pending prefixes at which Run applies
+	// the updates before the window is over, 65536.
+	MaxPending int
+}
+
+// UpdaterStats are the counters of an Updater.
+type UpdaterStats struct {
+	Received  uint64 // announcements and withdrawals
+	Coalesced uint64 // updates superseded by a later one for the prefix
+	Applied   uint64 // prefixes inserted or deleted
+	Batches   uint64 // transactions committed
+}
+
+// update is the pending update of a prefix, a withdrawal if withdraw is
+// set.
+type update[V any] struct {
+	val      V
+	withdraw bool
+}
+
+// Updater feeds a ConcurrentTable from a high-rate stream of route
+// announcements and withdrawals, like a full BGP feed. Updates are
+// collected per prefix, a later update of a prefix replaces the pending
+// one, and applied in one transaction per window: readers see the table
+// move from window to window, with every prefix at its latest state, and
+// a flapping prefix costs one change per window however often it flaps.
+//
+// Announce and Withdraw may be called from any number of goroutines
+// while Run applies the updates in another.
+type Updater[V any] struct {
+	c   *ConcurrentTable[V]
+	cfg UpdaterConfig
+
+	mu      sync.Mutex
+	pending map[netip.Prefix]update[V]
+	stats   UpdaterStats
+	full    chan struct{} // signalled when MaxPending is reached
+}
+
+// NewUpdater returns an Updater applying its updates to c.
+func NewUpdater[V any](c *ConcurrentTable[V], cfg UpdaterConfig) *Updater[V] {
+	if cfg.Window <= 0 {
+		cfg.Window = 100 * time.Millisecond
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 65536
+	}
+	return &Updater[V]{c: c, cfg: cfg, pending: map[netip.Prefix]update[V]{}, full: make(chan struct{}, 1)}
+}
+
+// Announce queues an insert of pfx with value val. Invalid prefixes are
+// ignored.
+func (u *Updater[V]) Announce(pfx netip.Prefix, val V) {
+	u.queue(pfx, update[V]{val: val})
+}
+
+// Withdraw queues the removal of pfx.
+func (u *Updater[V]) Withdraw(pfx netip.Prefix) {
+	u.queue(pfx, update[V]{withdraw: true})
+}
+
+func (u *Updater[V]) queue(pfx netip.Prefix, up update[V]) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.stats.Received++
+	if _, ok := u.pending[pfx]; ok {
+		u.stats.Coalesced++
+	}
+	u.pending[pfx] = up
+	if len(u.pending) >= u.cfg.MaxPending {
+		select {
+		case u.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Pending returns the number of prefixes with a pending update.
+func (u *Updater[V]) Pending() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.pending)
+}
+
+// Stats returns the counters of the Updater.
+func (u *Updater[V]) Stats() UpdaterStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.stats
+}
+
+// Flush applies the pending updates in one transaction and returns
+// their number. The announcements go first, so they are applied as one
+// batch insert.
+func (u *Updater[V]) Flush() int {
+	u.mu.Lock()
+	pending := u.pending
+	if len(pending) == 0 {
+		u.mu.Unlock()
+		return 0
+	}
+	u.pending = make(map[netip.Prefix]update[V], len(pending))
+	u.mu.Unlock()
+
+	tx := u.c.Begin()
+	for pfx, up := range pending {
+		if !up.withdraw {
+			tx.Insert(pfx, up.val)
+		}
+	}
+	for pfx, up := range pending {
+		if up.withdraw {
+			tx.Delete(pfx)
+		}
+	}
+	tx.Commit()
+
+	u.mu.Lock()
+	u.stats.Applied += uint64(len(pending))
+	u.stats.Batches++
+	u.mu.Unlock()
+	return len(pending)
+}
+
+// Run applies the pending updates at the end of every window, or as soon
+// as MaxPending prefixes are pending, until ctx is done; run it with go.
+// The updates still pending when ctx is done are applied before it
+// returns.
+func (u *Updater[V]) Run(ctx context.Context) {
+	tick := time.NewTicker(u.cfg.Window)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			u.Flush()
+			return
+		case <-tick.C:
+			u.Flush()
+		case <-u.full:
+			u.Flush()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package zart
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdaterCoalesce(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+	u := NewUpdater(c, UpdaterConfig{})
+
+	u.Announce(mpp("10.0.0.0/8"), 1)
+	u.Announce(mpp("10.0.0.0/8"), 2)
+	u.Announce(mpp("10.1.0.0/16"), 3)
+	u.Withdraw(mpp("10.1.0.0/16"))
+	u.Withdraw(mpp("192.168.0.0/16"))
+	u.Announce(mpp("192.168.0.0/16"), 4)
+	if n := u.Pending(); n != 3 {
+		t.Errorf("Pending = %d, want 3", n)
+	}
+	if _, ok := c.Get(mpp("10.0.0.0/8")); ok {
+		t.Error("updates applied before Flush")
+	}
+
+	if n := u.Flush(); n != 3 {
+		t.Errorf("Flush = %d, want 3", n)
+	}
+	if v, _ := c.Get(mpp("10.0.0.0/8")); v != 2 {
+		t.Errorf("10.0.0.0/8 = %d, want the last announcement", v)
+	}
+	if _, ok := c.Get(mpp("10.1.0.0/16")); ok {
+		t.Error("withdrawn prefix inserted")
+	}
+	if v, _ := c.Get(mpp("192.168.0.0/16")); v != 4 {
+		t.Errorf("192.168.0.0/16 = %d, want 4", v)
+	}
+	want := UpdaterStats{Received: 6, Coalesced: 3, Applied: 3, Batches: 1}
+	if s := u.Stats(); s != want {
+		t.Errorf("Stats = %+v, want %+v", s, want)
+	}
+	if n := u.Flush(); n != 0 {
+		t.Errorf("second Flush = %d", n)
+	}
+}
+
+func TestUpdaterRun(t *testing.T) {
+	c := NewConcurrent[int]()
+	defer c.Close()
+	u := NewUpdater(c, UpdaterConfig{Window: time.Millisecond, MaxPending: 100})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		u.Run(ctx)
+		close(done)
+	}()
+
+	// the feeders only withdraw, the final state of a prefix is set by
+	// the last loop: announced with its index if even, withdrawn if odd
+	pfxs := randomPrefixes(rand.New(rand.NewPCG(105, 105)), 1000)
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, pfx := range pfxs {
+				if (i+w)%4 == 0 {
+					u.Withdraw(pfx)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	want := map[netip.Prefix]int{}
+	for i, pfx := range pfxs {
+		if i%2 == 0 {
+			u.Announce(pfx, i)
+			want[pfx] = i
+		} else {
+			u.Withdraw(pfx)
+			delete(want, pfx)
+		}
+	}
+	cancel()
+	<-done
+
+	if u.Pending() != 0 {
+		t.Errorf("%d updates left after Run", u.Pending())
+	}
+	for _, pfx := range pfxs {
+		v, ok := c.Get(pfx)
+		w, in := want[pfx]
+		if ok != in || v != w {
+			t.Fatalf("%s = %d, %v, want %d, %v", pfx, v, ok, w, in)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package zart
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// ValueIndex is a reverse index of a table from values to the prefixes
+// mapped to them, e.g. all routes pointing at a next hop, for rerouting
+// them when the next hop fails.
+//
+// The index follows every change of the table as it happens, like a
+// watcher that cannot fall behind; the table reports its changes one by
+// one from then on, which makes InsertBatch and Union insert prefix by
+// prefix. UnmarshalBinary replaces the table without reporting changes,
+// call Rebuild after it. Tables made from t with Clone or InsertPersist
+// are not indexed. The index needs the same synchronization as the table.
+type ValueIndex[V comparable] struct {
+	t    *Table[V]
+	pfxs map[V]map[netip.Prefix]struct{}
+}
+
+// NewValueIndex indexes the current contents of t and keeps the index up
+// to date with its changes.
+func NewValueIndex[V comparable](t *Table[V]) *ValueIndex[V] {
+	x := &ValueIndex[V]{t: t}
+	x.Rebuild()
+	t.hook(x.update)
+	return x
+}
+
+// Rebuild indexes the table from scratch.
+func (x *ValueIndex[V]) Rebuild() {
+	x.pfxs = map[V]map[netip.Prefix]struct{}{}
+	for pfx, val := range x.t.All() {
+		x.add(val, pfx)
+	}
+}
+
+// PrefixesWithValue returns the prefixes mapped to val, in CIDR order.
+func (x *ValueIndex[V]) PrefixesWithValue(val V) []netip.Prefix {
+	set := x.pfxs[val]
+	pfxs := make([]netip.Prefix, 0, len(set))
+	for pfx := range set {
+		pfxs = append(pfxs, pfx)
+	}
+	slices.SortFunc(pfxs, comparePrefix)
+	return pfxs
+}
+
+// Count returns the number of prefixes mapped to val.
+func (x *ValueIndex[V]) Count(val V) int {
+	return len(x.pfxs[val])
+}
+
+// Values returns the number of distinct values in the table.
+func (x *ValueIndex[V]) Values() int {
+	return len(x.pfxs)
+}
+
+func (x *ValueIndex[V]) update(ev Event[V]) {
+	switch ev.Kind {
+	case EventInsert:
+		x.add(ev.New, ev.Prefix)
+	case EventUpdate:
+		x.remove(ev.Old, ev.Prefix)
+		x.add(ev.New, ev.Prefix)
+	case EventDelete:
+		x.remove(ev.Old, ev.Prefix)
+	}
+}
+
+func (x *ValueIndex[V]) add(val V, pfx netip.Prefix) {
+	set := x.pfxs[val]
+	if set == nil {
+		set = map[netip.Prefix]struct{}{}
+		x.pfxs[val] = set
+	}
+	set[pfx] = struct{}{}
+}
+
+func (x *ValueIndex[V]) remove(val V, pfx netip.Prefix) {
+	set := x.pfxs[val]
+	delete(set, pfx)
+	if len(set) == 0 {
+		delete(x.pfxs, val)
+	}
+}
@@ -0,0 +1,50 @@
+package zart
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestValueIndex(t *testing.T) {
+	prng := rand.New(rand.NewPCG(55, 55))
+	tbl := New[int]()
+	defer tbl.Close()
+	pfxs := randomPrefixes(prng, 1000)
+	for _, pfx := range pfxs[:500] {
+		tbl.Insert(pfx, prng.IntN(8))
+	}
+
+	x := NewValueIndex(tbl)
+	for i, pfx := range pfxs {
+		switch i % 4 {
+		case 0:
+			tbl.Delete(pfx)
+		case 1:
+			tbl.Modify(pfx, func(old int, _ bool) (int, bool) { return (old + 1) % 8, false })
+		default:
+			tbl.Insert(pfx, prng.IntN(8))
+		}
+	}
+	tbl.DeleteSubtree(mpp("3.0.0.0/8"))
+	tbl.InsertBatch([]RouteEntry[int]{{mpp("192.0.2.0/24"), 3}, {mpp("198.51.100.0/24"), 3}})
+
+	want := map[int][]netip.Prefix{}
+	for pfx, val := range tbl.All() {
+		want[val] = append(want[val], pfx)
+	}
+	if x.Values() != len(want) {
+		t.Errorf("Values = %d, want %d", x.Values(), len(want))
+	}
+	for val := range 8 {
+		slices.SortFunc(want[val], comparePrefix)
+		got := x.PrefixesWithValue(val)
+		if !slices.Equal(got, want[val]) || x.Count(val) != len(want[val]) {
+			t.Errorf("PrefixesWithValue(%d) has %d prefixes, want %d", val, len(got), len(want[val]))
+		}
+	}
+	if got := x.PrefixesWithValue(42); len(got) != 0 {
+		t.Errorf("PrefixesWithValue of a missing value = %v", got)
+	}
+}
@@ -0,0 +1,246 @@
+package zart
+
+import (
+	"iter"
+	"net/netip"
+	"slices"
+)
+
+// TableSet manages the routing tables of many VRFs keyed by name or ID,
+// and leaks selected routes between them.
+//
+// A leak copies the routes of one VRF accepted by a filter into another
+// one, first when it is established and then on every Insert and Delete
+// through the set. Only routes inserted into a VRF directly are leaked,
+// leaked routes are not passed on, so leaks in both directions cannot
+// loop. A route inserted directly into a VRF takes precedence over a
+// leaked route for the same prefix.
+//
+// The tables must be modified through the set for the leaks to follow,
+// the tables returned by Table are for lookups. A TableSet is not safe
+// for concurrent use.
+type TableSet[K comparable, V any] struct {
+	vrfs  map[K]*vrf[K, V]
+	leaks []*leak[K, V]
+}
+
+// vrf is a table of a TableSet with the origins of its leaked routes.
+type vrf[K comparable, V any] struct {
+	t      *Table[V]
+	leaked map[netip.Prefix]K
+}
+
+// leak is a leak established with TableSet.Leak.
+type leak[K comparable, V any] struct {
+	from, to K
+	filter   func(pfx netip.Prefix, val V) bool
+}
+
+// NewTableSet returns an empty TableSet.
+func NewTableSet[K comparable, V any]() *TableSet[K, V] {
+	return &TableSet[K, V]{vrfs: map[K]*vrf[K, V]{}}
+}
+
+// Close closes all tables of the set.
+func (s *TableSet[K, V]) Close() {
+	for _, v := range s.vrfs {
+		v.t.Close()
+	}
+	clear(s.vrfs)
+	s.leaks = nil
+}
+
+// Table returns the table of VRF k, an empty one is created for a new k.
+func (s *TableSet[K, V]) Table(k K) *Table[V] {
+	return s.vrf(k).t
+}
+
+func (s *TableSet[K, V]) vrf(k K) *vrf[K, V] {
+	v, ok := s.vrfs[k]
+	if !ok {
+		v = &vrf[K, V]{t: New[V](), leaked: map[netip.Prefix]K{}}
+		s.vrfs[k] = v
+	}
+	return v
+}
+
+// All returns an iterator over the VRFs of the set and their tables, in
+// no particular order.
+func (s *TableSet[K, V]) All() iter.Seq2[K, *Table[V]] {
+	return func(yield func(K, *Table[V]) bool) {
+		for k, v := range s.vrfs {
+			if !yield(k, v.t) {
+				return
+			}
+		}
+	}
+}
+
+// Remove closes the table of VRF k, ends all leaks from and to it and
+// withdraws the routes leaked from it. It reports whether k existed.
+func (s *TableSet[K, V]) Remove(k K) bool {
+	v, ok := s.vrfs[k]
+	if !ok {
+		return false
+	}
+	s.leaks = slices.DeleteFunc(s.leaks, func(l *leak[K, V]) bool { return l.from == k || l.to == k })
+	for _, o := range s.vrfs {
+		for pfx, from := range o.leaked {
+			if from == k {
+				o.t.Delete(pfx)
+				delete(o.leaked, pfx)
+			}
+		}
+	}
+	v.t.Close()
+	delete(s.vrfs, k)
+	return true
+}
+
+// Lookup performs a longest-prefix match for addr in VRF k.
+func (s *TableSet[K, V]) Lookup(k K, addr netip.Addr) (val V, ok bool) {
+	v, found := s.vrfs[k]
+	if !found {
+		return val, false
+	}
+	return v.t.Lookup(addr)
+}
+
+// Insert adds pfx with val to VRF k and leaks it to the VRFs importing it.
+func (s *TableSet[K, V]) Insert(k K, pfx netip.Prefix, val V) {
+	if !pfx.IsValid() {
+		return
+	}
+	pfx = pfx.Masked()
+	v := s.vrf(k)
+	v.t.Insert(pfx, val)
+	delete(v.leaked, pfx)
+
+	// a new value may no longer pass the filter of an earlier leak
+	for _, l := range s.leaks {
+		if l.from != k {
+			continue
+		}
+		if s.accepted(k, l.to, pfx, val) {
+			s.leakRoute(l, pfx, val)
+		} else {
+			s.withdraw(l.to, k, pfx)
+		}
+	}
+}
+
+// Delete removes pfx from VRF k, with the routes leaked from it, and
+// reports whether it was present.
+func (s *TableSet[K, V]) Delete(k K, pfx netip.Prefix) bool {
+	v, ok := s.vrfs[k]
+	if !ok || !pfx.IsValid() {
+		return false
+	}
+	pfx = pfx.Masked()
+	if !v.t.Delete(pfx) {
+		return false
+	}
+	delete(v.leaked, pfx)
+
+	for _, l := range s.leaks {
+		if l.from == k {
+			s.withdraw(l.to, k, pfx)
+		}
+	}
+	return true
+}
+
+// Leak leaks the routes of VRF from accepted by filter into VRF to, the
+// routes currently in from right away, later ones as they are inserted.
+// A nil filter accepts every route, see also LeakPrefixes. Leaking a VRF
+// into itself has no effect.
+//
+// The returned function ends the leak and withdraws the routes it leaked,
+// unless another leak between the same VRFs still accepts them.
+func (s *TableSet[K, V]) Leak(from, to K, filter func(pfx netip.Prefix, val V) bool) (stop func()) {
+	if filter == nil {
+		filter = func(netip.Prefix, V) bool { return true }
+	}
+	if from == to {
+		return func() {}
+	}
+	l := &leak[K, V]{from: from, to: to, filter: filter}
+	s.leaks = append(s.leaks, l)
+
+	src := s.vrf(from)
+	s.vrf(to)
+	for pfx, val := range src.t.All() {
+		if _, leaked := src.leaked[pfx]; !leaked && filter(pfx, val) {
+			s.leakRoute(l, pfx, val)
+		}
+	}
+
+	return func() {
+		i := slices.Index(s.leaks, l)
+		if i < 0 {
+			return
+		}
+		s.leaks = slices.Delete(s.leaks, i, i+1)
+		dst, ok := s.vrfs[to]
+		if !ok {
+			return
+		}
+		for pfx, origin := range dst.leaked {
+			if origin != from {
+				continue
+			}
+			if val, ok := src.t.Get(pfx); !ok || !s.accepted(from, to, pfx, val) {
+				s.withdraw(to, from, pfx)
+			}
+		}
+	}
+}
+
+// LeakPrefixes returns a Leak filter accepting the routes covered by any
+// of pfxs.
+func LeakPrefixes[V any](pfxs ...netip.Prefix) func(pfx netip.Prefix, val V) bool {
+	return func(pfx netip.Prefix, _ V) bool {
+		for _, p := range pfxs {
+			if p.Bits() <= pfx.Bits() && p.Contains(pfx.Addr()) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// leakRoute copies a route along l, unless the destination has its own
+// route for pfx.
+func (s *TableSet[K, V]) leakRoute(l *leak[K, V], pfx netip.Prefix, val V) {
+	dst := s.vrfs[l.to]
+	if _, own := dst.t.Get(pfx); own {
+		if _, leaked := dst.leaked[pfx]; !leaked {
+			return
+		}
+	}
+	dst.t.Insert(pfx, val)
+	dst.leaked[pfx] = l.from
+}
+
+// withdraw removes pfx from VRF to if it was leaked there from VRF from.
+func (s *TableSet[K, V]) withdraw(to, from K, pfx netip.Prefix) {
+	dst, ok := s.vrfs[to]
+	if !ok {
+		return
+	}
+	if origin, leaked := dst.leaked[pfx]; leaked && origin == from {
+		dst.t.Delete(pfx)
+		delete(dst.leaked, pfx)
+	}
+}
+
+// accepted reports whether any leak from VRF from to VRF to accepts the
+// route.
+func (s *TableSet[K, V]) accepted(from, to K, pfx netip.Prefix, val V) bool {
+	for _, l := range s.leaks {
+		if l.from == from && l.to == to && l.filter(pfx, val) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package zart
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTableSetLeak(t *testing.T) {
+	s := NewTableSet[string, string]()
+	defer s.Close()
+
+	s.Insert("shared", mpp("192.0.2.0/24"), "dns")
+	s.Insert("shared", mpp("198.51.100.0/24"), "internal")
+	s.Insert("red", mpp("10.0.0.0/8"), "red-lan")
+	s.Insert("blue", mpp("10.0.0.0/8"), "blue-lan")
+
+	stopRed := s.Leak("shared", "red", LeakPrefixes[string](mpp("192.0.2.0/24")))
+	s.Leak("shared", "blue", nil)
+	s.Leak("red", "shared", nil)
+
+	if v, ok := s.Lookup("red", mpa("192.0.2.53")); !ok || v != "dns" {
+		t.Errorf("red does not see the leaked shared route: %q, %v", v, ok)
+	}
+	if _, ok := s.Lookup("red", mpa("198.51.100.1")); ok {
+		t.Errorf("route outside the filter was leaked")
+	}
+	if v, _ := s.Lookup("blue", mpa("10.1.1.1")); v != "blue-lan" {
+		t.Errorf("tenant tables are not separate: %q", v)
+	}
+	if v, _ := s.Lookup("shared", mpa("10.1.1.1")); v != "red-lan" {
+		t.Errorf("red route not leaked into shared: %q", v)
+	}
+	// leaked routes are not passed on
+	if v, _ := s.Lookup("blue", mpa("10.1.1.1")); v != "blue-lan" {
+		t.Errorf("route leaked over two hops: %q", v)
+	}
+
+	// later inserts and deletes follow the leak
+	s.Insert("shared", mpp("192.0.2.128/25"), "dns-2")
+	if v, _ := s.Lookup("red", mpa("192.0.2.200")); v != "dns-2" {
+		t.Errorf("later insert not leaked: %q", v)
+	}
+	s.Delete("shared", mpp("192.0.2.128/25"))
+	if v, _ := s.Lookup("red", mpa("192.0.2.200")); v != "dns" {
+		t.Errorf("delete not leaked: %q", v)
+	}
+
+	// an own route wins over a leaked one
+	s.Insert("blue", mpp("198.51.100.0/24"), "blue-own")
+	s.Insert("shared", mpp("198.51.100.0/24"), "internal-2")
+	if v, _ := s.Lookup("blue", mpa("198.51.100.1")); v != "blue-own" {
+		t.Errorf("leak overwrote an own route: %q", v)
+	}
+
+	s.Leak("blue", "red", func(_ netip.Prefix, v string) bool { return v != "private" })
+	s.Insert("blue", mpp("203.0.113.0/24"), "public")
+	s.Insert("blue", mpp("203.0.113.0/24"), "private")
+	if _, ok := s.Lookup("red", mpa("203.0.113.1")); ok {
+		t.Errorf("route still leaked after its new value was filtered out")
+	}
+
+	stopRed()
+	if _, ok := s.Lookup("red", mpa("192.0.2.53")); ok {
+		t.Errorf("stopped leak left its routes")
+	}
+
+	s.Remove("red")
+	if _, ok := s.Lookup("shared", mpa("10.1.1.1")); ok {
+		t.Errorf("routes leaked from a removed VRF are still present")
+	}
+	n := 0
+	for range s.All() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("%d VRFs after Remove, want 2", n)
+	}
+}
@@ -0,0 +1,130 @@
+package zart
+
+import (
+	"context"
+	"net/netip"
+	"strconv"
+	"sync"
+)
+
+// EventKind is the kind of change reported by an Event.
+type EventKind uint8
+
+const (
+	EventInsert EventKind = iota + 1 // a new prefix, Old is the zero value
+	EventUpdate                      // the value of an existing prefix was replaced
+	EventDelete                      // a prefix was removed, New is the zero value
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Event is a change of a table, Prefix is masked.
+type Event[V any] struct {
+	Kind     EventKind
+	Prefix   netip.Prefix
+	Old, New V
+}
+
+// watchBuffer is the number of events queued per watcher.
+const watchBuffer = 1024
+
+// watchers are the channels returned by Watch, events are queued on
+// them by the goroutine modifying the table. hooks are called with every
+// event before it is queued, they keep the indexes built on the table up
+// to date.
+type watchers[V any] struct {
+	mu    sync.Mutex
+	chans map[chan Event[V]]struct{}
+	hooks []func(Event[V])
+}
+
+// Watch returns a channel receiving an Event for every change of the
+// table, in the order of the changes. The channel is closed once ctx is
+// done or the table is closed. Events are queued by the goroutine
+// modifying the table, a watcher falling more than 1024 events behind
+// has its channel closed early; tell this apart from cancellation by
+// ctx.Err() and resync from All if needed.
+//
+// Watch must not be called concurrently with modifications of the
+// table. Watchers belong to t, tables made from it with Clone or
+// InsertPersist don't inherit them.
+func (t *Table[V]) Watch(ctx context.Context) <-chan Event[V] {
+	w := t.watchers()
+	ch := make(chan Event[V], watchBuffer)
+	w.mu.Lock()
+	w.chans[ch] = struct{}{}
+	w.mu.Unlock()
+
+	context.AfterFunc(ctx, func() { w.remove(ch) })
+	return ch
+}
+
+// Watch is like Table.Watch.
+func (c *ConcurrentTable[V]) Watch(ctx context.Context) <-chan Event[V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Watch(ctx)
+}
+
+func (t *Table[V]) watchers() *watchers[V] {
+	if t.watch == nil {
+		t.watch = &watchers[V]{chans: map[chan Event[V]]struct{}{}}
+	}
+	return t.watch
+}
+
+// hook adds fn to the functions called synchronously for every change
+// of the table.
+func (t *Table[V]) hook(fn func(Event[V])) {
+	w := t.watchers()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, fn)
+}
+
+// notify calls the hooks with ev and queues it for all watchers, dropping
+// those with a full queue.
+func (w *watchers[V]) notify(ev Event[V]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, fn := range w.hooks {
+		fn(ev)
+	}
+	for ch := range w.chans {
+		select {
+		case ch <- ev:
+		default:
+			delete(w.chans, ch)
+			close(ch)
+		}
+	}
+}
+
+func (w *watchers[V]) remove(ch chan Event[V]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.chans[ch]; ok {
+		delete(w.chans, ch)
+		close(ch)
+	}
+}
+
+// closeAll closes all watcher channels.
+func (w *watchers[V]) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.chans {
+		delete(w.chans, ch)
+		close(ch)
+	}
+}
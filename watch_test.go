@@ -0,0 +1,93 @@
+package zart
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+func recvEvents[V any](t *testing.T, ch <-chan Event[V], n int) []Event[V] {
+	t.Helper()
+	evs := make([]Event[V], 0, n)
+	for range n {
+		select {
+		case ev := <-ch:
+			evs = append(evs, ev)
+		default:
+			t.Fatalf("got %d events, want %d", len(evs), n)
+		}
+	}
+	return evs
+}
+
+func TestWatch(t *testing.T) {
+	tbl := New[string]()
+	defer tbl.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := tbl.Watch(ctx)
+
+	p8, p16 := netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.1.0.0/16")
+	tbl.Insert(netip.MustParsePrefix("10.1.2.3/8"), "a")
+	tbl.Insert(p8, "b")
+	tbl.InsertBatch([]RouteEntry[string]{{p16, "c"}, {p8, "d"}})
+	tbl.Delete(p16)
+	tbl.Delete(p16)
+
+	o := New[string]()
+	defer o.Close()
+	o.Insert(p8, "e")
+	tbl.Union(o, func(_ netip.Prefix, a, b string) string { return a + b })
+
+	want := []Event[string]{
+		{EventInsert, p8, "", "a"},
+		{EventUpdate, p8, "a", "b"},
+		{EventInsert, p16, "", "c"},
+		{EventUpdate, p8, "b", "d"},
+		{EventDelete, p16, "c", ""},
+		{EventUpdate, p8, "d", "de"},
+	}
+	got := recvEvents(t, ch, len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// the channel is closed shortly after cancel, events in between
+	// may still arrive
+	cancel()
+	tbl.Insert(p16, "f")
+	for range ch {
+	}
+}
+
+func TestWatchOverflowAndClose(t *testing.T) {
+	tbl := New[int]()
+	slow := tbl.Watch(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	live := tbl.Watch(ctx)
+
+	for i := range watchBuffer + 1 {
+		tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), i)
+		<-live
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != watchBuffer {
+		t.Errorf("slow watcher got %d events before its channel closed, want %d", n, watchBuffer)
+	}
+
+	tbl.Close()
+	if _, ok := <-live; ok {
+		t.Errorf("watcher channel open after Close")
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Close canceled the context")
+	}
+	if EventDelete.String() != "delete" || EventKind(9).String() != "EventKind(9)" {
+		t.Errorf("EventKind.String = %s, %s", EventDelete, EventKind(9))
+	}
+}